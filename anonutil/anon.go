@@ -0,0 +1,221 @@
+// Package anonutil anonymizes rows of production data with rules per
+// table and column eg. masking emails, faking names and nullifying ssns,
+// so realistic but safe datasets can be exported to staging
+package anonutil
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+// Rule returns anonymized value of a column
+// hash is keyed hash of value so rules can derive fake values that are
+// the same for equal values, keeping joins and unique columns intact
+// Rules are not called for nil values
+type Rule func(value interface{}, hash uint64) interface{}
+
+// TableRules are rules of a table keyed by column name as returned from
+// database
+// Columns without rule are left as is
+type TableRules map[string]Rule
+
+// Anonymizer applies rules of tables to rows
+type Anonymizer struct {
+	secret []byte
+	rules  map[string]TableRules
+}
+
+// NewAnonymizer returns *Anonymizer with rules keyed by table name
+// secret is key used to hash values so fake values can't be reversed
+// by hashing known values
+// If secret is empty, a random secret is used, so fake values are only
+// the same within a single Anonymizer
+func NewAnonymizer(secret []byte, rules map[string]TableRules) (*Anonymizer, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Anonymizer{
+		secret: secret,
+		rules:  rules,
+	}, nil
+}
+
+// Row anonymizes values of row in place based on rules of table
+func (a *Anonymizer) Row(table string, row map[string]interface{}) {
+	for column, rule := range a.rules[table] {
+		if val, ok := row[column]; ok {
+			row[column] = a.apply(rule, val)
+		}
+	}
+}
+
+// Rows anonymizes every row in place based on rules of table
+func (a *Anonymizer) Rows(table string, rows []map[string]interface{}) {
+	for _, row := range rows {
+		a.Row(table, row)
+	}
+}
+
+// RowerToMaps scans every row of rower, eg. results of a query against
+// table, keyed by column name and anonymizes them based on rules of table
+// The returned rows can be exported with apiutil#Negotiate or reportutil
+func (a *Anonymizer) RowerToMaps(table string, rower httputil.Rower) ([]map[string]interface{}, error) {
+	rows, err := queryutil.RowerToMaps(rower, queryutil.RowerMapConfig{
+		Naming: queryutil.AsIsNaming,
+		Bytes:  queryutil.BytesAsString,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.Rows(table, rows)
+	return rows, nil
+}
+
+// CopyConfig is config struct used for Anonymizer#CopyTable
+type CopyConfig struct {
+	// DBType is type of destination database used to bind query
+	// placeholders
+	// Default is postgres
+	DBType string
+
+	// BatchSize is number of rows inserted per statement
+	// Default is 100
+	BatchSize int
+}
+
+func (c *CopyConfig) setDefaults() {
+	if c.DBType == "" {
+		c.DBType = dbutil.Postgres
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+}
+
+// CopyTable copies every row of table from src to the table of the
+// same name in dest, anonymizing rows based on rules of table
+// dest should usually be a transaction so a failed copy can be rolled
+// back
+// Returns number of rows copied
+func (a *Anonymizer) CopyTable(src httputil.Querier, dest httputil.XODB, table string, config CopyConfig) (int, error) {
+	config.setDefaults()
+
+	if !dbutil.ValidIdentifier(table) {
+		return 0, fmt.Errorf("anonutil: invalid table name '%s'", table)
+	}
+
+	rower, err := src.Query(fmt.Sprintf("select * from %s", table))
+
+	if err != nil {
+		return 0, err
+	}
+
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range columns {
+		if !dbutil.ValidIdentifier(c) {
+			return 0, fmt.Errorf("anonutil: invalid column name '%s'", c)
+		}
+	}
+
+	rules := a.rules[table]
+	batch := make([]interface{}, 0, len(columns)*config.BatchSize)
+	copied := 0
+
+	for rower.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return copied, err
+		}
+
+		for i, c := range columns {
+			if rule, ok := rules[c]; ok {
+				values[i] = a.apply(rule, values[i])
+			}
+		}
+
+		batch = append(batch, values...)
+
+		if len(batch) == cap(batch) {
+			if err = insertBatch(dest, config.DBType, table, columns, batch); err != nil {
+				return copied, err
+			}
+
+			copied += config.BatchSize
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err = insertBatch(dest, config.DBType, table, columns, batch); err != nil {
+			return copied, err
+		}
+
+		copied += len(batch) / len(columns)
+	}
+
+	return copied, nil
+}
+
+func insertBatch(db httputil.XODB, dbType, table string, columns []string, args []interface{}) error {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	rows := make([]string, len(args)/len(columns))
+
+	for i := range rows {
+		rows[i] = placeholders
+	}
+
+	query := sqlx.Rebind(
+		sqlx.BindType(dbType),
+		fmt.Sprintf(
+			"insert into %s (%s) values %s",
+			table,
+			strings.Join(columns, ", "),
+			strings.Join(rows, ", "),
+		),
+	)
+
+	_, err := db.Exec(query, args...)
+	return err
+}
+
+func (a *Anonymizer) apply(rule Rule, val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	if b, ok := val.([]byte); ok {
+		val = string(b)
+	}
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(fmt.Sprint(val)))
+
+	return rule(val, binary.BigEndian.Uint64(mac.Sum(nil)))
+}
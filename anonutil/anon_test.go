@@ -0,0 +1,97 @@
+package anonutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+var testRules = map[string]TableRules{
+	"users": {
+		"email": MaskEmail,
+		"name":  FakeFullName,
+		"ssn":   Nullify,
+		"phone": Mask(4),
+	},
+}
+
+func TestAnonymizerRowerToMaps(t *testing.T) {
+	a, err := NewAnonymizer([]byte("secret"), testRules)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	rows, err := a.RowerToMaps("users", dbtest.NewRows("id", "email", "name", "ssn", "phone").
+		AddRow(int64(1), []byte("foo@company.com"), "Foo Bar", "123-45-6789", "5555551234").
+		AddRow(int64(2), "foo@company.com", nil, "987-65-4321", "5551"),
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	first, second := rows[0], rows[1]
+
+	if first["id"] != int64(1) {
+		t.Errorf("columns without rules should be left as is; got %v", first["id"])
+	}
+	if email := first["email"].(string); !strings.HasSuffix(email, "@example.com") || email != second["email"] {
+		t.Errorf("equal emails should be masked the same; got %v and %v", email, second["email"])
+	}
+	if first["name"] == "Foo Bar" || second["name"] != nil {
+		t.Errorf("name should be faked and nil left as is; got %v and %v", first["name"], second["name"])
+	}
+	if first["ssn"] != nil {
+		t.Errorf("ssn should be nullified; got %v", first["ssn"])
+	}
+	if first["phone"] != "******1234" || second["phone"] != "5551" {
+		t.Errorf("got phones %v and %v", first["phone"], second["phone"])
+	}
+
+	other, _ := NewAnonymizer([]byte("other"), testRules)
+	row := map[string]interface{}{"email": "foo@company.com"}
+	other.Row("users", row)
+
+	if row["email"] == first["email"] {
+		t.Errorf("different secrets should give different values")
+	}
+}
+
+func TestAnonymizerCopyTable(t *testing.T) {
+	src := dbtest.NewExpectDB(t)
+	src.QueryMatcher = dbtest.QueryMatcherEqual
+	src.ExpectQuery("select * from users").
+		WillReturnRows(dbtest.NewRows("id", "ssn").
+			AddRow(int64(1), "123-45-6789").
+			AddRow(int64(2), "987-65-4321").
+			AddRow(int64(3), nil),
+		)
+
+	dest := dbtest.NewExpectDB(t)
+	dest.QueryMatcher = dbtest.QueryMatcherEqual
+	dest.ExpectExec("insert into users (id, ssn) values ($1, $2), ($3, $4)").
+		WithArgs(int64(1), nil, int64(2), nil).
+		WillReturnResult(dbtest.NewResult(0, 2))
+	dest.ExpectExec("insert into users (id, ssn) values ($1, $2)").
+		WithArgs(int64(3), nil).
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	a, _ := NewAnonymizer(nil, testRules)
+	copied, err := a.CopyTable(src, dest, "users", CopyConfig{BatchSize: 2})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if copied != 3 {
+		t.Errorf("got %d copied; want 3", copied)
+	}
+	if err = dest.ExpectationsWereMet(); err != nil {
+		t.Error(err.Error())
+	}
+
+	if _, err = a.CopyTable(src, dest, "users; drop table users", CopyConfig{}); err == nil {
+		t.Errorf("should return error for invalid table")
+	}
+}
@@ -0,0 +1,79 @@
+package anonutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+var firstNames = []string{
+	"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael",
+	"Linda", "William", "Elizabeth", "David", "Barbara", "Richard", "Susan",
+	"Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen",
+}
+
+var lastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller",
+	"Davis", "Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez",
+	"Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+}
+
+// Nullify is Rule that replaces values with nil eg. for ssns
+func Nullify(value interface{}, hash uint64) interface{} {
+	return nil
+}
+
+// Fixed returns Rule that replaces values with v
+func Fixed(v interface{}) Rule {
+	return func(value interface{}, hash uint64) interface{} {
+		return v
+	}
+}
+
+// MaskEmail is Rule that replaces emails with fake emails at
+// example.com that are unique per email
+func MaskEmail(value interface{}, hash uint64) interface{} {
+	return fmt.Sprintf("user-%016x@example.com", hash)
+}
+
+// FakeFirstName is Rule that replaces values with fake first names
+func FakeFirstName(value interface{}, hash uint64) interface{} {
+	return firstNames[hash%uint64(len(firstNames))]
+}
+
+// FakeLastName is Rule that replaces values with fake last names
+func FakeLastName(value interface{}, hash uint64) interface{} {
+	return lastNames[hash%uint64(len(lastNames))]
+}
+
+// FakeFullName is Rule that replaces values with fake first and last names
+func FakeFullName(value interface{}, hash uint64) interface{} {
+	return fmt.Sprintf(
+		"%s %s",
+		FakeFirstName(value, hash),
+		lastNames[(hash>>32)%uint64(len(lastNames))],
+	)
+}
+
+// Hash is Rule that replaces values with hex of their hash eg. for
+// usernames and other unique identifiers
+func Hash(value interface{}, hash uint64) interface{} {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Mask returns Rule that replaces every character but the last keep
+// characters with "*" eg. Mask(4) for card and phone numbers
+func Mask(keep int) Rule {
+	if keep < 0 {
+		keep = 0
+	}
+
+	return func(value interface{}, hash uint64) interface{} {
+		runes := []rune(fmt.Sprint(value))
+
+		if keep >= len(runes) {
+			return string(runes)
+		}
+
+		return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+	}
+}
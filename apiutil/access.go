@@ -0,0 +1,233 @@
+package apiutil
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	forbiddenAccessTxt = "Forbidden to access object"
+)
+
+var (
+	// AccessControlCtxKey is key used to store *AccessControl within
+	// request context by AccessControl#MiddlewareFunc
+	AccessControlCtxKey = MiddlewareKey{KeyName: "accessControl"}
+
+	// ErrNoAccessPolicy is returned from CanAccess if no AccessPolicy is
+	// registered for object type
+	ErrNoAccessPolicy = errors.New("apiutil: no access policy registered for object type")
+
+	// ErrNoAccessControl is returned from CanAccess if
+	// AccessControl#MiddlewareFunc was not used for request
+	ErrNoAccessControl = errors.New("apiutil: access control is not set for request")
+)
+
+// AccessPolicy determines whether user of request can perform action on
+// object with objectID
+type AccessPolicy interface {
+	CanAccess(r *http.Request, db httputil.Querier, objectID interface{}, action string) (bool, error)
+}
+
+// AccessPolicyFunc is function that implements AccessPolicy
+type AccessPolicyFunc func(r *http.Request, db httputil.Querier, objectID interface{}, action string) (bool, error)
+
+// CanAccess calls f
+func (f AccessPolicyFunc) CanAccess(r *http.Request, db httputil.Querier, objectID interface{}, action string) (bool, error) {
+	return f(r, db, objectID, action)
+}
+
+// SQLAccessPolicy is AccessPolicy that allows access if Query returns a
+// row eg.
+//
+//	SQLAccessPolicy{Query: "select 1 from invoice where id = ? and owner_id = ?"}
+type SQLAccessPolicy struct {
+	// Query is query with "?" placeholders that returns a row if user is
+	// allowed access - Required
+	Query string
+
+	// DBType is the type of database Query is rebound for eg. Postgres
+	// Default is dbutil#Postgres
+	DBType string
+
+	// Args returns arguments of Query
+	// Default returns objectID and id of user of request
+	Args func(r *http.Request, objectID interface{}, action string) []interface{}
+
+	// Actions, if set, are the only actions policy applies to where
+	// other actions are denied
+	Actions []string
+}
+
+// CanAccess implements AccessPolicy
+func (s SQLAccessPolicy) CanAccess(r *http.Request, db httputil.Querier, objectID interface{}, action string) (bool, error) {
+	if len(s.Actions) > 0 && !hasString(s.Actions, action) {
+		return false, nil
+	}
+
+	dbType := s.DBType
+
+	if dbType == "" {
+		dbType = dbutil.Postgres
+	}
+
+	var args []interface{}
+
+	if s.Args != nil {
+		args = s.Args(r, objectID, action)
+	} else {
+		args = []interface{}{objectID, GetUserID(r)}
+	}
+
+	var found int
+
+	err := db.QueryRow(sqlx.Rebind(sqlx.BindType(dbType), s.Query), args...).Scan(&found)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "apiutil: sql access policy")
+	}
+
+	return true, nil
+}
+
+// AccessControlConfig is config struct used for AccessControl
+type AccessControlConfig struct {
+	// ForbiddenResponse is config used to respond to user if they're not
+	// allowed to access object
+	//
+	// Default status value is http.StatusForbidden
+	// Default response value is []byte("Forbidden to access object")
+	ForbiddenResponse HTTPResponseConfig
+
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// AccessControl is registry of AccessPolicy keyed by object type used to
+// check whether users can access individual objects instead of each
+// handler writing its own ownership checks
+//
+// MiddlewareFunc stores AccessControl within request context so
+// CanAccess can be called from handlers, and Require returns middleware
+// that responds with 403 before handler is reached
+type AccessControl struct {
+	db       httputil.DBInterfaceV2
+	config   AccessControlConfig
+	mu       sync.RWMutex
+	policies map[string]AccessPolicy
+}
+
+// NewAccessControl returns *AccessControl without policies
+// db is used for policies unless DBHandler has set database of request
+func NewAccessControl(db httputil.DBInterfaceV2, config AccessControlConfig) *AccessControl {
+	setHTTPResponseDefaults(&config.ForbiddenResponse, http.StatusForbidden, []byte(forbiddenAccessTxt))
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &AccessControl{
+		db:       db,
+		config:   config,
+		policies: make(map[string]AccessPolicy),
+	}
+}
+
+// Register sets policy of objectType, replacing existing policy
+func (a *AccessControl) Register(objectType string, policy AccessPolicy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.policies[objectType] = policy
+}
+
+// CanAccess returns whether user of request can perform action on object
+// of objectType with objectID
+// Returns ErrNoAccessPolicy if no policy is registered for objectType
+func (a *AccessControl) CanAccess(r *http.Request, objectType string, objectID interface{}, action string) (bool, error) {
+	a.mu.RLock()
+	policy, ok := a.policies[objectType]
+	a.mu.RUnlock()
+
+	if !ok {
+		return false, errors.Wrap(ErrNoAccessPolicy, objectType)
+	}
+
+	var db httputil.Querier = a.db
+
+	if reqDB := GetDB(r); reqDB != nil {
+		db = reqDB
+	}
+
+	return policy.CanAccess(r, db, objectID, action)
+}
+
+// MiddlewareFunc stores AccessControl within request context for
+// CanAccess
+func (a *AccessControl) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), AccessControlCtxKey, a)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require returns middleware that responds with
+// AccessControlConfig#ForbiddenResponse if user of request can't perform
+// action on object of objectType with id of router variable idParam
+func (a *AccessControl) Require(objectType, idParam, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := mux.Vars(r)[idParam]
+			allowed, err := a.CanAccess(r, objectType, id, action)
+
+			if err != nil {
+				httputil.Logger.Errorf("apiutil: access control of %s %s err: %s", objectType, id, err.Error())
+				writeHTTPResponse(w, a.config.ServerErrResponse)
+				return
+			}
+			if !allowed {
+				writeHTTPResponse(w, a.config.ForbiddenResponse)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CanAccess returns whether user of request can perform action on object
+// of objectType with objectID using AccessControl set by
+// AccessControl#MiddlewareFunc
+// Returns ErrNoAccessControl if AccessControl is not set
+func CanAccess(r *http.Request, objectType string, objectID interface{}, action string) (bool, error) {
+	a, ok := r.Context().Value(AccessControlCtxKey).(*AccessControl)
+
+	if !ok {
+		return false, ErrNoAccessControl
+	}
+
+	return a.CanAccess(r, objectType, objectID, action)
+}
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestAccessControl(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	ac := NewAccessControl(db, AccessControlConfig{})
+
+	ac.Register("invoice", SQLAccessPolicy{
+		Query:   "select 1 from invoice where id = ? and owner_id = ?",
+		Actions: []string{"read", "write"},
+	})
+	ac.Register("report", AccessPolicyFunc(func(r *http.Request, db httputil.Querier, objectID interface{}, action string) (bool, error) {
+		return false, errors.New(generalErr)
+	}))
+
+	var reached bool
+
+	router := mux.NewRouter()
+	router.Use(ac.MiddlewareFunc)
+	router.Handle("/invoice/{id}", ac.Require("invoice", "id", "write")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+		}),
+	))
+	router.Handle("/report/{id}", ac.Require("report", "id", "read")(mockHandler))
+	router.Handle("/other/{id}", ac.Require("other", "id", "read")(mockHandler))
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, nil)
+		req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, middlewareUser{ID: "1"}))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	query := `select 1 from invoice where id = \$1 and owner_id = \$2`
+
+	db.ExpectQuery(query).
+		WithArgs("10", "1").
+		WillReturnRows(dbtest.NewRows("found").AddRow(1))
+
+	if rr := serve("/invoice/10"); rr.Code != http.StatusOK || !reached {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	reached = false
+	db.ExpectQuery(query).
+		WithArgs("11", "1").
+		WillReturnRows(dbtest.NewRows("found"))
+
+	if rr := serve("/invoice/11"); rr.Code != http.StatusForbidden || reached {
+		t.Errorf(statusErrTxt, http.StatusForbidden, rr.Code)
+	}
+
+	if rr := serve("/report/1"); rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+	if rr := serve("/other/1"); rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+
+	// Actions not listed by policy are denied without query
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req = req.WithContext(context.WithValue(req.Context(), AccessControlCtxKey, ac))
+
+	if allowed, err := CanAccess(req, "invoice", 10, "delete"); err != nil || allowed {
+		t.Errorf("should deny delete; got %v and %v", allowed, err)
+	}
+
+	if _, err := CanAccess(httptest.NewRequest(http.MethodGet, "/url", nil), "invoice", 10, "read"); err != ErrNoAccessControl {
+		t.Errorf("should return ErrNoAccessControl; got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni"
+)
+
+// FromNegroni converts negroni style middleware into standard library
+// style middleware which can be used with routers like chi or gorilla
+func FromNegroni(fn negroni.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fn(w, r, next.ServeHTTP)
+		})
+	}
+}
+
+// ToNegroni converts standard library style middleware, like the
+// MiddlewareFunc of AuthHandler, GroupHandler and RoutingHandler,
+// into negroni style middleware
+func ToNegroni(mw func(http.Handler) http.Handler) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		mw(next).ServeHTTP(w, r)
+	}
+}
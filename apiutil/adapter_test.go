@@ -0,0 +1,53 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegroniAdapters(t *testing.T) {
+	var calls []string
+
+	negroniMW := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		calls = append(calls, "negroni")
+		next(w, r)
+	}
+	stdMW := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "std")
+			next.ServeHTTP(w, r)
+		})
+	}
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rr := httptest.NewRecorder()
+	FromNegroni(negroniMW)(final).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusTeapot)
+	}
+
+	rr = httptest.NewRecorder()
+	ToNegroni(stdMW)(rr, httptest.NewRequest(http.MethodGet, "/", nil), final)
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusTeapot)
+	}
+
+	expected := []string{"negroni", "handler", "std", "handler"}
+
+	if len(calls) != len(expected) {
+		t.Fatalf("got calls %v; want %v", calls, expected)
+	}
+
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("got calls %v; want %v", calls, expected)
+			break
+		}
+	}
+}
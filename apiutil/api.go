@@ -2,20 +2,22 @@ package apiutil
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/ctxutil"
+	"github.com/TravisS25/httputil/dbutil"
 
 	"github.com/TravisS25/httputil/mailutil"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/securecookie"
-	"github.com/gorilla/sessions"
 	"github.com/pkg/errors"
 	"github.com/urfave/negroni"
 )
@@ -31,6 +33,16 @@ const (
 	jsonErrTxt         = "json err: %s"
 )
 
+const (
+	// StaleVersionResponse is body sent with 409 status by
+	// HasStaleVersionError
+	StaleVersionResponse = `{"error": "Resource was modified by another request, reload and try again"}`
+
+	// QueryTimeoutResponse is body sent with 504 status by
+	// HasQueryTimeoutError
+	QueryTimeoutResponse = `{"error": "Request took too long to process, try narrowing your query"}`
+)
+
 var (
 	// NonSafeOperations is slice of http methods that are not safe
 	NonSafeOperations = []string{http.MethodPost, http.MethodPut, http.MethodDelete}
@@ -70,16 +82,17 @@ func SetToken(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-CSRF-Token", csrf.Token(r))
 }
 
-// CheckError simply prints given error in verbose to stdout
+// CheckError logs given error in verbose with httputil#Logger
 func CheckError(err error, customMessage string) {
 	err = errors.Wrap(err, customMessage)
-	fmt.Printf("%+v\n", err)
+	httputil.Logger.Errorf("%+v", err)
 }
 
 // ServerError takes given err along with customMessage and writes back to client
-// then logs the error given the logFile
+// then logs the error given the logFile and sends it to httputil#Reporter
 func ServerError(w http.ResponseWriter, err error, customMessage string) {
 	CheckError(err, "Server Err:")
+	httputil.CaptureError(context.Background(), err, map[string]string{"source": "server_error"})
 	w.WriteHeader(http.StatusInternalServerError)
 
 	if customMessage != "" {
@@ -104,7 +117,11 @@ func HasServerError(w http.ResponseWriter, err error, customMessage string) bool
 // HasFormErrors determines if err is nil and if it is, convert it to json form
 // with which form fields have errors and send to client with 406 error
 // If err is not nil, returns true else false
+//
+// Deprecated: Use HasError, which handles every error consistently
 func HasFormErrors(w http.ResponseWriter, err error) bool {
+	httputil.Deprecated("apiutil.HasFormErrors", "apiutil.HasError")
+
 	if err != nil {
 		CheckError(err, "Form Err:")
 		payload, ok := err.(validation.Errors)
@@ -123,6 +140,7 @@ func HasFormErrors(w http.ResponseWriter, err error) bool {
 
 // SendPayload is a wrapper for converting the payload map parameter into json and
 // sending to the client
+// Use Negotiate to respond in the format requested by the Accept header
 func SendPayload(w http.ResponseWriter, payload interface{}) {
 	jsonString, err := json.Marshal(payload)
 
@@ -137,20 +155,58 @@ func SendPayload(w http.ResponseWriter, payload interface{}) {
 
 // GetUser returns a user if set in userctx, else returns nil
 func GetUser(r *http.Request) []byte {
-	if r.Context().Value(UserCtxKey) == nil {
-		return nil
-	}
-
-	return r.Context().Value(UserCtxKey).([]byte)
+	return ctxutil.UserJSONFrom(r.Context())
 }
 
 // GetMiddlewareUser returns a user's email if set in userctx, else returns nil
 func GetMiddlewareUser(r *http.Request) *middlewareUser {
-	if r.Context().Value(MiddlewareUserCtxKey) == nil {
+	if user, ok := ctxutil.UserFrom(r.Context()); ok {
+		return &user
+	}
+
+	return nil
+}
+
+// GetUserID returns id of user set by AuthHandler or
+// Middleware#AuthMiddleware, else returns empty string
+func GetUserID(r *http.Request) string {
+	user, _ := ctxutil.UserFrom(r.Context())
+	return user.ID
+}
+
+// IsImpersonating returns whether user of request is being impersonated
+func IsImpersonating(r *http.Request) bool {
+	_, ok := ctxutil.ImpersonatorFrom(r.Context())
+	return ok
+}
+
+// GetActorID returns id of the real user of request, which is the
+// impersonator while impersonating, else the same as GetUserID
+// This should be used for audit logs
+func GetActorID(r *http.Request) string {
+	if actor, ok := ctxutil.ImpersonatorFrom(r.Context()); ok {
+		return actor.ID
+	}
+
+	return GetUserID(r)
+}
+
+// GetGroupNames returns names of groups set by GroupHandler or
+// Middleware#GroupMiddleware, else returns nil
+func GetGroupNames(r *http.Request) []string {
+	groups := ctxutil.GroupsFrom(r.Context())
+
+	if groups == nil {
 		return nil
 	}
 
-	return r.Context().Value(MiddlewareUserCtxKey).(*middlewareUser)
+	names := make([]string, 0, len(groups))
+
+	for k := range groups {
+		names = append(names, k)
+	}
+
+	return names
 }
 
 // HasBodyError checks if the "Body" field of the request parameter is nil or not
@@ -181,10 +237,62 @@ func HasDecodeError(w http.ResponseWriter, err error) bool {
 	return false
 }
 
+// HasStaleVersionError determines if err is dbutil#StaleVersionError
+// and if it is, writes StaleVersionResponse with 409 status and returns true
+// Else return false
+//
+// Deprecated: Use HasError, which handles every error consistently
+func HasStaleVersionError(w http.ResponseWriter, err error) bool {
+	httputil.Deprecated("apiutil.HasStaleVersionError", "apiutil.HasError")
+	return hasStaleVersionError(w, err)
+}
+
+func hasStaleVersionError(w http.ResponseWriter, err error) bool {
+	if _, ok := errors.Cause(err).(*dbutil.StaleVersionError); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(StaleVersionResponse))
+		return true
+	}
+
+	return false
+}
+
+// HasQueryTimeoutError determines if err is dbutil#ErrQueryTimeout
+// and if it is, writes QueryTimeoutResponse with 504 status and returns true
+// Else return false
+//
+// Deprecated: Use HasError, which handles every error consistently
+func HasQueryTimeoutError(w http.ResponseWriter, err error) bool {
+	httputil.Deprecated("apiutil.HasQueryTimeoutError", "apiutil.HasError")
+	return hasQueryTimeoutError(w, err)
+}
+
+func hasQueryTimeoutError(w http.ResponseWriter, err error) bool {
+	if errors.Cause(err) == dbutil.ErrQueryTimeout {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(QueryTimeoutResponse))
+		return true
+	}
+
+	return false
+}
+
 // HasQueryError is wrapper for determining if err equals "sql.ErrNoRows"
 // If it does, we write to client with not found message, 404 status and return true
+// If err is dbutil#StaleVersionError, HasStaleVersionError is used
+// If err is dbutil#ErrQueryTimeout, HasQueryTimeoutError is used
 // Else return false
+//
+// Deprecated: Use HasError, which handles every error consistently
 func HasQueryError(w http.ResponseWriter, err error, notFoundMessage string) bool {
+	httputil.Deprecated("apiutil.HasQueryError", "apiutil.HasError")
+
+	if hasStaleVersionError(w, err) || hasQueryTimeoutError(w, err) {
+		return true
+	}
+
 	if err == sql.ErrNoRows {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(notFoundMessage))
@@ -199,7 +307,13 @@ func HasQueryError(w http.ResponseWriter, err error, notFoundMessage string) boo
 	return false
 }
 
+// HasQueryOrServerError is the same as HasQueryError but writes
+// serverErrorMessage for server errors
+//
+// Deprecated: Use HasError, which handles every error consistently
 func HasQueryOrServerError(w http.ResponseWriter, err error, notFoundMessage, serverErrorMessage string) bool {
+	httputil.Deprecated("apiutil.HasQueryOrServerError", "apiutil.HasError")
+
 	if err == sql.ErrNoRows {
 		CheckError(err, "")
 		w.WriteHeader(http.StatusNotFound)
@@ -215,53 +329,29 @@ func HasQueryOrServerError(w http.ResponseWriter, err error, notFoundMessage, se
 	return false
 }
 
-// LogoutUser deletes user session based on session object passed along with userSession parameter
-// If userSession is empty string, then string "user" will be used to delete from session object
-func LogoutUser(w http.ResponseWriter, r *http.Request, sessionStore sessions.Store, userSession string) error {
-	if r.Context().Value(UserCtxKey) != nil {
-		var session *sessions.Session
-		var err error
-
-		if userSession == "" {
-			session, err = sessionStore.Get(r, "user")
-		} else {
-			session, err = sessionStore.Get(r, userSession)
-		}
-
-		if err != nil {
-			return err
-		}
-
-		session.Options = &sessions.Options{
-			MaxAge: -1,
-		}
-		session.Save(r, w)
-	}
-
-	return nil
-}
-
 // GetUserGroups is wrapper for to returning group string slice from context of request
 // If there is no groupctx, returns nil
 func GetUserGroups(r *http.Request) map[string]bool {
-	if r.Context().Value(GroupCtxKey) != nil {
-		return r.Context().Value(GroupCtxKey).(map[string]bool)
-	}
-
-	return nil
+	return ctxutil.GroupsFrom(r.Context())
 }
 
+// GetUserGroupsI returns names of groups of user as []interface{} to be
+// used as args of query
+// If there is no groupctx, returns nil
 func GetUserGroupsI(r *http.Request) []interface{} {
-	if r.Context().Value(GroupCtxKey) != nil {
-		list := r.Context().Value(GroupCtxKey).([]string)
-		args := make([]interface{}, 0)
-		for _, v := range list {
-			args = append(args, v)
-		}
-		return args
+	names := GetGroupNames(r)
+
+	if names == nil {
+		return nil
 	}
 
-	return nil
+	args := make([]interface{}, 0, len(names))
+
+	for _, v := range names {
+		args = append(args, v)
+	}
+
+	return args
 }
 
 // HasGroup is a wrapper for finding if given groups names is in
@@ -270,7 +360,7 @@ func GetUserGroupsI(r *http.Request) []interface{} {
 // The search is based on OR logic so if any one of the given strings
 // is found, function will return true
 func HasGroup(r *http.Request, searchGroups ...string) bool {
-	groupMap := r.Context().Value(GroupCtxKey).(map[string]bool)
+	groupMap := ctxutil.GroupsFrom(r.Context())
 
 	for _, searchGroup := range searchGroups {
 		if _, ok := groupMap[searchGroup]; ok {
@@ -297,7 +387,11 @@ func HasGroup(r *http.Request, searchGroups ...string) bool {
 // 		to only display that library(s) in the message.  This is just
 //		to help reduce the clutter of a stacktrace that you don't
 //		care about
+//
+// Deprecated: Use RecoveryHandler, which sends panics to httputil#Reporter
 func PanicHandlerFunc(to []string, from, subject string, subSearchStrings []string, mail mailutil.SendMessage) func(*negroni.PanicInformation) {
+	httputil.Deprecated("apiutil.PanicHandlerFunc", "apiutil.RecoveryHandler")
+
 	return func(info *negroni.PanicInformation) {
 		var stack string
 		ss := strings.Fields(info.StackAsString())
@@ -366,17 +460,6 @@ func GetJSONBuffer(item interface{}) bytes.Buffer {
 	return buffer
 }
 
-// SetSecureCookie is used to set a cookie from a session
-// The code used is copied pasted from the RedisStore#Save function from the redis store library
-func SetSecureCookie(w http.ResponseWriter, session *sessions.Session, keyPairs ...[]byte) error {
-	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, securecookie.CodecsFromPairs(keyPairs...)...)
-	if err != nil {
-		return err
-	}
-	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
-	return nil
-}
-
 // func GetRouterExpressionPaths(r *mux.Router, paths map[int]string) (map[int]string, error) {
 // 	pathExps := make(map[int]string, len(paths))
 
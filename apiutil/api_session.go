@@ -0,0 +1,52 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+// LogoutUser deletes user session based on session object passed along with userSession parameter
+// If userSession is empty string, then string "user" will be used to delete from session object
+// Use LogoutHandler for a complete logout endpoint that also deletes
+// session from database and clears cookies
+func LogoutUser(w http.ResponseWriter, r *http.Request, sessionStore sessions.Store, userSession string) error {
+	if ctxutil.UserJSONFrom(r.Context()) != nil {
+		var session *sessions.Session
+		var err error
+
+		if userSession == "" {
+			session, err = sessionStore.Get(r, "user")
+		} else {
+			session, err = sessionStore.Get(r, userSession)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		session.Options = &sessions.Options{
+			MaxAge: -1,
+		}
+		session.Save(r, w)
+	}
+
+	return nil
+}
+
+// SetSecureCookie is used to set a cookie from a session
+// The code used is copied pasted from the RedisStore#Save function from the redis store library
+func SetSecureCookie(w http.ResponseWriter, session *sessions.Session, keyPairs ...[]byte) error {
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, securecookie.CodecsFromPairs(keyPairs...)...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
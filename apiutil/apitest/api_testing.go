@@ -18,6 +18,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	"github.com/TravisS25/httputil"
 )
 
@@ -32,6 +34,9 @@ const (
 
 const (
 	IDParam = "{id:[0-9]+}"
+
+	// UUIDParam is route param for uuid ids
+	UUIDParam = "{id:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}}"
 )
 
 const (
@@ -43,6 +48,9 @@ const (
 	int64FilteredIDResult
 	intObjectIDResult
 	int64ObjectIDResult
+	uuidArrayIDResult
+	uuidFilteredIDResult
+	uuidObjectIDResult
 )
 
 var (
@@ -89,6 +97,23 @@ type TestCase struct {
 	File io.Reader
 	// Handler is the request handler that you which to test
 	Handler http.Handler
+	// RouteTemplate, if set, registers Handler on a new gorilla router with
+	// the template eg. "/api/user/" + IDParam, and the request is dispatched
+	// through the router so path variables are parsed like they would be
+	// in production
+	RouteTemplate string
+	// Router, if set and RouteTemplate is not, is used to dispatch
+	// the request instead of Handler
+	// This is useful to test against the application's actual router
+	Router *mux.Router
+	// ExpectedHeaders are headers the response must have
+	// How values are compared is determined by HeaderMatchMode
+	ExpectedHeaders map[string]string
+	// HeaderMatchMode determines how ExpectedHeaders values are compared
+	// Default is HeaderMatchExact
+	HeaderMatchMode HeaderMatchMode
+	// ExpectedCookies are cookies the response must, or must not, set
+	ExpectedCookies []ExpectedCookie
 	// ValidResponse allows user to take in response from api end
 	// and determine if the given response is the expected one
 	// ValidResponse func(bodyResponse io.Reader) (bool, error)
@@ -119,6 +144,15 @@ type filteredInt64ID struct {
 	Count int       `json:"count"`
 }
 
+type uuidID struct {
+	ID string `json:"id"`
+}
+
+type filteredUUIDID struct {
+	Data  []uuidID `json:"data"`
+	Count int      `json:"count"`
+}
+
 type Response struct {
 	ExpectedResult       interface{}
 	ValidateResponseFunc func(bodyResponse io.Reader, expectedResult interface{}) error
@@ -150,7 +184,7 @@ func RunTestCasesV2(t *testing.T, deferFunc func() error, testCases []TestCase)
 						err := deferFunc()
 
 						if err != nil {
-							fmt.Printf(err.Error())
+							httputil.Debugf("apitest: defer func err: %s", err.Error())
 						}
 					}
 				}
@@ -211,7 +245,7 @@ func RunTestCasesV2(t *testing.T, deferFunc func() error, testCases []TestCase)
 			// Init recorder that will be written to based on the status
 			// we get from created request
 			rr := httptest.NewRecorder()
-			testCase.Handler.ServeHTTP(rr, req)
+			testCaseHandler(testCase).ServeHTTP(rr, req)
 
 			// If status is not what was expected, print error
 			if status := rr.Code; status != testCase.ExpectedStatus {
@@ -228,6 +262,10 @@ func RunTestCasesV2(t *testing.T, deferFunc func() error, testCases []TestCase)
 				}
 			}
 
+			for _, headerErr := range validateHeaders(rr.Result(), testCase) {
+				v.Error(headerErr)
+			}
+
 			if testCase.ValidateResponse.ValidateResponseFunc != nil {
 				err = testCase.ValidateResponse.ValidateResponseFunc(
 					rr.Body,
@@ -235,13 +273,13 @@ func RunTestCasesV2(t *testing.T, deferFunc func() error, testCases []TestCase)
 				)
 
 				if err != nil {
-					v.Errorf(err.Error() + "\n")
+					v.Errorf("%s\n", err.Error())
 				}
 			}
 
 			if testCase.PostResponseValidation != nil {
 				if err = testCase.PostResponseValidation(); err != nil {
-					v.Errorf(err.Error() + "\n")
+					v.Errorf("%s\n", err.Error())
 				}
 			}
 
@@ -250,11 +288,29 @@ func RunTestCasesV2(t *testing.T, deferFunc func() error, testCases []TestCase)
 	}
 }
 
+// testCaseHandler returns the handler the request of testCase
+// should be dispatched through
+func testCaseHandler(testCase TestCase) http.Handler {
+	if testCase.RouteTemplate != "" {
+		router := mux.NewRouter()
+		router.Handle(testCase.RouteTemplate, testCase.Handler)
+		return router
+	}
+
+	if testCase.Router != nil {
+		return testCase.Router
+	}
+
+	return testCase.Handler
+}
+
 // RunTestCases takes the given list of TestCase structs and loops through
 // and applies tests based on each TestCase struct config
 //
-// Deprecated for RunTestCasesV2
+// Deprecated: Use RunTestCasesV2, which runs deferFunc after every test case
 func RunTestCases(t *testing.T, testCases []TestCase) {
+	httputil.Deprecated("apitest.RunTestCases", "apitest.RunTestCasesV2")
+
 	for _, testCase := range testCases {
 		t.Run(testCase.TestName, func(v *testing.T) {
 			var req *http.Request
@@ -289,7 +345,7 @@ func RunTestCases(t *testing.T, testCases []TestCase) {
 			// Init recorder that will be written to based on the status
 			// we get from created request
 			rr := httptest.NewRecorder()
-			testCase.Handler.ServeHTTP(rr, req)
+			testCaseHandler(testCase).ServeHTTP(rr, req)
 
 			// If status is not what was expected, print error
 			if status := rr.Code; status != testCase.ExpectedStatus {
@@ -313,14 +369,14 @@ func RunTestCases(t *testing.T, testCases []TestCase) {
 				)
 
 				if err != nil {
-					v.Errorf(err.Error() + "\n")
+					v.Errorf("%s\n", err.Error())
 					httputil.CheckError(err, "")
 				}
 			}
 
 			if testCase.PostResponseValidation != nil {
 				if err = testCase.PostResponseValidation(); err != nil {
-					v.Errorf(err.Error() + "\n")
+					v.Errorf("%s\n", err.Error())
 					httputil.CheckError(err, "")
 				}
 			}
@@ -550,6 +606,58 @@ func validateIDResponse(bodyResponse io.Reader, result int, expectedResults inte
 			)
 			return errors.New(errorMessage)
 		}
+	case uuidArrayIDResult:
+		expectedIDs, ok := expectedResults.([]string)
+
+		if !ok {
+			return errors.New("apitesting: Expected result should be []string")
+		}
+
+		var responseResults []uuidID
+		err := SetJSONFromResponse(bodyResponse, &responseResults)
+
+		if err != nil {
+			return err
+		}
+
+		return validateUUIDs(responseResults, expectedIDs)
+	case uuidFilteredIDResult:
+		expectedIDs, ok := expectedResults.([]string)
+
+		if !ok {
+			return errors.New("apitesting: Expected result should be []string")
+		}
+
+		var responseResults filteredUUIDID
+		err := SetJSONFromResponse(bodyResponse, &responseResults)
+
+		if err != nil {
+			return err
+		}
+
+		return validateUUIDs(responseResults.Data, expectedIDs)
+	case uuidObjectIDResult:
+		expectedID, ok := expectedResults.(string)
+
+		if !ok {
+			return errors.New("apitesting: Expected result should be string")
+		}
+
+		var responseResults uuidID
+		err := SetJSONFromResponse(bodyResponse, &responseResults)
+
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(responseResults.ID, expectedID) {
+			errorMessage := fmt.Sprintf(
+				ResponseErrorMessage,
+				responseResults.ID,
+				expectedID,
+			)
+			return errors.New(errorMessage)
+		}
 	case intMapIDResult:
 		expectedMap, ok := expectedResults.(map[string]interface{})
 
@@ -846,6 +954,31 @@ func validateIDResponse(bodyResponse io.Reader, result int, expectedResults inte
 	return nil
 }
 
+// validateUUIDs compares uuids case insensitively as databases may
+// return them in a different case than they were inserted
+func validateUUIDs(responseResults []uuidID, expectedIDs []string) error {
+	if len(responseResults) != len(expectedIDs) {
+		return fmt.Errorf(ResponseErrorMessage, responseResults, expectedIDs)
+	}
+
+	for _, m := range expectedIDs {
+		foundResult := false
+
+		for _, v := range responseResults {
+			if strings.EqualFold(m, v.ID) {
+				foundResult = true
+				break
+			}
+		}
+
+		if !foundResult {
+			return fmt.Errorf(ResponseErrorMessage, responseResults, expectedIDs)
+		}
+	}
+
+	return nil
+}
+
 func ValidateFilteredIntArrayResponse(bodyResponse io.Reader, expectedResult interface{}) error {
 	return validateIDResponse(bodyResponse, intFilteredIDResult, expectedResult)
 }
@@ -878,6 +1011,24 @@ func ValidateInt64ObjectResponse(bodyResponse io.Reader, expectedResult interfac
 	return validateIDResponse(bodyResponse, int64ObjectIDResult, expectedResult)
 }
 
+// ValidateUUIDArrayResponse validates response is json array of objects
+// with "id" uuids matching expectedResult, which should be []string
+func ValidateUUIDArrayResponse(bodyResponse io.Reader, expectedResult interface{}) error {
+	return validateIDResponse(bodyResponse, uuidArrayIDResult, expectedResult)
+}
+
+// ValidateFilteredUUIDArrayResponse is the same as ValidateUUIDArrayResponse
+// but for filtered responses with "data" and "count"
+func ValidateFilteredUUIDArrayResponse(bodyResponse io.Reader, expectedResult interface{}) error {
+	return validateIDResponse(bodyResponse, uuidFilteredIDResult, expectedResult)
+}
+
+// ValidateUUIDObjectResponse validates response is json object with "id"
+// uuid matching expectedResult, which should be string
+func ValidateUUIDObjectResponse(bodyResponse io.Reader, expectedResult interface{}) error {
+	return validateIDResponse(bodyResponse, uuidObjectIDResult, expectedResult)
+}
+
 func ValidateStringResponse(bodyResponse io.Reader, expectedResult interface{}) error {
 	response, err := ioutil.ReadAll(bodyResponse)
 
@@ -905,7 +1056,7 @@ func SetJSONFromResponse(bodyResponse io.Reader, item interface{}) error {
 		return err
 	}
 
-	fmt.Printf("response: %s", string(response))
+	httputil.Debugf("apitest: response: %s", string(response))
 
 	err = json.Unmarshal(response, &item)
 
@@ -2,13 +2,48 @@ package apitest
 
 import (
 	"bytes"
+	"net/http"
 	"testing"
+
+	"github.com/gorilla/mux"
 )
 
 func ExampleRunTestCases() {
 
 }
 
+func TestRunTestCasesRouteTemplate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mux.Vars(r)["id"]))
+	})
+
+	router := mux.NewRouter()
+	router.Handle("/api/item/{id}", handler)
+
+	testCases := []TestCase{
+		{
+			TestName:       "RouteTemplate",
+			Method:         http.MethodGet,
+			RequestURL:     "/api/user/5",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "5",
+			Handler:        handler,
+			RouteTemplate:  "/api/user/{id}",
+		},
+		{
+			TestName:       "Router",
+			Method:         http.MethodGet,
+			RequestURL:     "/api/item/6",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "6",
+			Router:         router,
+		},
+	}
+
+	RunTestCases(t, testCases)
+	RunTestCasesV2(t, nil, testCases)
+}
+
 func TestNewFileUploadRequest(t *testing.T) {
 	req, _ := NewFileUploadRequest(
 		"/url",
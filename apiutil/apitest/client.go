@@ -0,0 +1,89 @@
+package apitest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+)
+
+// Client is a http client for Server that keeps cookies between
+// requests and sends the csrf token on unsafe requests
+type Client struct {
+	*http.Client
+
+	// BaseURL is prepended to paths passed to Client#Request
+	BaseURL string
+
+	token string
+}
+
+// Request creates and sends request to path with the json encoding
+// of form as the body, if not nil
+func (c *Client) Request(method, path string, form interface{}) (*http.Response, error) {
+	var body io.Reader
+
+	if form != nil {
+		buf := httputil.GetJSONBuffer(form)
+		body = &buf
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if form != nil {
+		req.Header.Set("Content-Type", httputil.ContentTypeJSON)
+	}
+
+	return c.Do(req)
+}
+
+// Do sends req, fetching a csrf token first if req is an unsafe
+// request and no token has been received yet
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		if c.token == "" {
+			if err := c.fetchToken(); err != nil {
+				return nil, err
+			}
+		}
+
+		req.Header.Set(TokenHeader, c.token)
+	}
+
+	res, err := c.Client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token := res.Header.Get(TokenHeader); token != "" {
+		c.token = token
+	}
+
+	return res, nil
+}
+
+func (c *Client) fetchToken() error {
+	res, err := c.Client.Get(strings.TrimRight(c.BaseURL, "/") + "/")
+
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+	c.token = res.Header.Get(TokenHeader)
+
+	if c.token == "" {
+		return errors.New("apitest: server did not return csrf token")
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// UpdateGoldenFlag is the test flag used to regenerate golden files
+	// eg. "go test ./... -update"
+	UpdateGoldenFlag = "update"
+
+	// IgnoredValue replaces the value of ignored fields so golden files
+	// still record that the field exists
+	IgnoredValue = "<ignored>"
+)
+
+func init() {
+	// Another package within the test binary may have already
+	// registered the flag which is fine as only the value is needed
+	if flag.Lookup(UpdateGoldenFlag) == nil {
+		flag.Bool(UpdateGoldenFlag, false, "regenerate golden files")
+	}
+}
+
+// GoldenConfig is config struct used in conjunction with ValidateGoldenResponse
+type GoldenConfig struct {
+	// IgnoreFields are fields whose values will not be compared, such as
+	// timestamps or generated ids
+	// An entry can be a key name which matches at any depth eg. "created_at"
+	// or a dotted path from the root eg. "data.id", where array
+	// indexes are left out of the path
+	IgnoreFields []string
+
+	// SortArrays sorts every array before comparing so results with
+	// no guaranteed order can be compared
+	SortArrays bool
+}
+
+// ValidateGoldenResponse returns function to be used as
+// Response#ValidateResponseFunc that compares the json response body
+// against the golden file at path after normalizing both with config
+//
+// If the test binary is run with the "-update" flag, the golden file is
+// written with the normalized response instead
+//
+// The expectedResult argument of the returned function is ignored
+func ValidateGoldenResponse(path string, config GoldenConfig) func(io.Reader, interface{}) error {
+	ignore := make(map[string]bool, len(config.IgnoreFields))
+
+	for _, v := range config.IgnoreFields {
+		ignore[v] = true
+	}
+
+	return func(bodyResponse io.Reader, expectedResult interface{}) error {
+		body, err := ioutil.ReadAll(bodyResponse)
+
+		if err != nil {
+			return err
+		}
+
+		actual, err := normalizeGoldenJSON(body, ignore, config.SortArrays)
+
+		if err != nil {
+			return fmt.Errorf("apitest: response is not valid json: %s", err.Error())
+		}
+
+		if shouldUpdateGolden() {
+			if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			return ioutil.WriteFile(path, actual, 0644)
+		}
+
+		golden, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf(
+					"apitest: golden file '%s' does not exist, run tests with -%s to create",
+					path,
+					UpdateGoldenFlag,
+				)
+			}
+
+			return err
+		}
+
+		expected, err := normalizeGoldenJSON(golden, ignore, config.SortArrays)
+
+		if err != nil {
+			return fmt.Errorf("apitest: golden file '%s' is not valid json: %s", path, err.Error())
+		}
+
+		if !bytes.Equal(actual, expected) {
+			return fmt.Errorf(
+				"apitest: response does not match golden file '%s'\n got: %s\n want: %s",
+				path,
+				actual,
+				expected,
+			)
+		}
+
+		return nil
+	}
+}
+
+func shouldUpdateGolden() bool {
+	f := flag.Lookup(UpdateGoldenFlag)
+	return f != nil && f.Value.String() == "true"
+}
+
+func normalizeGoldenJSON(source []byte, ignore map[string]bool, sortArrays bool) ([]byte, error) {
+	var val interface{}
+
+	if err := json.Unmarshal(source, &val); err != nil {
+		return nil, err
+	}
+
+	val = normalizeGoldenValue(val, "", ignore, sortArrays)
+	normalized, err := json.MarshalIndent(val, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(normalized, '\n'), nil
+}
+
+func normalizeGoldenValue(val interface{}, path string, ignore map[string]bool, sortArrays bool) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			itemPath := k
+
+			if path != "" {
+				itemPath = path + "." + k
+			}
+
+			if ignore[k] || ignore[itemPath] {
+				v[k] = IgnoredValue
+			} else {
+				v[k] = normalizeGoldenValue(item, itemPath, ignore, sortArrays)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeGoldenValue(item, path, ignore, sortArrays)
+		}
+
+		if sortArrays {
+			keys := make([]string, len(v))
+
+			for i, item := range v {
+				b, _ := json.Marshal(item)
+				keys[i] = string(b)
+			}
+
+			sort.Sort(goldenArray{keys: keys, values: v})
+		}
+	}
+
+	return val
+}
+
+type goldenArray struct {
+	keys   []string
+	values []interface{}
+}
+
+func (g goldenArray) Len() int {
+	return len(g.keys)
+}
+
+func (g goldenArray) Less(i, j int) bool {
+	return g.keys[i] < g.keys[j]
+}
+
+func (g goldenArray) Swap(i, j int) {
+	g.keys[i], g.keys[j] = g.keys[j], g.keys[i]
+	g.values[i], g.values[j] = g.values[j], g.values[i]
+}
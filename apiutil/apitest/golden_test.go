@@ -0,0 +1,50 @@
+package apitest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateGoldenResponse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "users.json")
+	golden := `{"data": [{"id": 2, "name": "bar"}, {"id": 1, "name": "foo"}], "created_at": "2020-01-01"}`
+
+	if err = ioutil.WriteFile(path, []byte(golden), 0644); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	validate := ValidateGoldenResponse(path, GoldenConfig{
+		IgnoreFields: []string{"created_at"},
+		SortArrays:   true,
+	})
+
+	body := `{"created_at": "2021-05-05", "data": [{"name": "foo", "id": 1}, {"name": "bar", "id": 2}]}`
+
+	if err = validate(strings.NewReader(body), nil); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	body = `{"created_at": "2021-05-05", "data": [{"name": "foo", "id": 1}]}`
+
+	if err = validate(strings.NewReader(body), nil); err == nil {
+		t.Errorf("should have error for mismatched response")
+	}
+
+	validate = ValidateGoldenResponse(path, GoldenConfig{IgnoreFields: []string{"data.id"}})
+	body = `{"created_at": "2020-01-01", "data": [{"id": 20, "name": "bar"}, {"id": 10, "name": "foo"}]}`
+
+	if err = validate(strings.NewReader(body), nil); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// HeaderMatchMode determines how TestCase#ExpectedHeaders values
+// are compared against response headers
+type HeaderMatchMode int
+
+const (
+	// HeaderMatchExact requires header value to equal expected value
+	HeaderMatchExact HeaderMatchMode = iota
+
+	// HeaderMatchRegex treats expected value as regular expression
+	// the header value must match
+	HeaderMatchRegex
+
+	// HeaderMatchPresent only requires the header to be set
+	HeaderMatchPresent
+)
+
+// ExpectedCookie is used in TestCase#ExpectedCookies to assert on
+// cookies set by the response
+// Pointer fields are only checked if set; apitest.True and apitest.False
+// can be used for the bool fields
+type ExpectedCookie struct {
+	// Name is the name of the cookie
+	Name string
+
+	// Absent asserts the cookie is not set by response
+	Absent bool
+
+	// Value is expected value of cookie
+	Value *string
+
+	Secure   *bool
+	HttpOnly *bool
+	MaxAge   *int
+}
+
+// validateHeaders checks headers and cookies of res against the
+// expected headers and cookies of testCase
+func validateHeaders(res *http.Response, testCase TestCase) []error {
+	var errs []error
+
+	for k, expected := range testCase.ExpectedHeaders {
+		values, ok := res.Header[http.CanonicalHeaderKey(k)]
+
+		if !ok || len(values) == 0 {
+			errs = append(errs, fmt.Errorf("header '%s' not set", k))
+			continue
+		}
+
+		actual := values[0]
+
+		switch testCase.HeaderMatchMode {
+		case HeaderMatchRegex:
+			exp, err := regexp.Compile(expected)
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("header '%s' has invalid regex: %s", k, err.Error()))
+			} else if !exp.MatchString(actual) {
+				errs = append(errs, fmt.Errorf("header '%s' value '%s' does not match '%s'", k, actual, expected))
+			}
+		case HeaderMatchPresent:
+		default:
+			if actual != expected {
+				errs = append(errs, fmt.Errorf("got header '%s' value '%s'; want '%s'", k, actual, expected))
+			}
+		}
+	}
+
+	cookies := make(map[string]*http.Cookie)
+
+	for _, c := range res.Cookies() {
+		cookies[c.Name] = c
+	}
+
+	for _, expected := range testCase.ExpectedCookies {
+		c, ok := cookies[expected.Name]
+
+		if expected.Absent {
+			if ok {
+				errs = append(errs, fmt.Errorf("cookie '%s' should not be set", expected.Name))
+			}
+
+			continue
+		}
+
+		if !ok {
+			errs = append(errs, fmt.Errorf("cookie '%s' not set", expected.Name))
+			continue
+		}
+
+		if expected.Value != nil && c.Value != *expected.Value {
+			errs = append(errs, fmt.Errorf("got cookie '%s' value '%s'; want '%s'", c.Name, c.Value, *expected.Value))
+		}
+		if expected.Secure != nil && c.Secure != *expected.Secure {
+			errs = append(errs, fmt.Errorf("got cookie '%s' secure %t; want %t", c.Name, c.Secure, *expected.Secure))
+		}
+		if expected.HttpOnly != nil && c.HttpOnly != *expected.HttpOnly {
+			errs = append(errs, fmt.Errorf("got cookie '%s' http only %t; want %t", c.Name, c.HttpOnly, *expected.HttpOnly))
+		}
+		if expected.MaxAge != nil && c.MaxAge != *expected.MaxAge {
+			errs = append(errs, fmt.Errorf("got cookie '%s' max age %d; want %d", c.Name, c.MaxAge, *expected.MaxAge))
+		}
+	}
+
+	return errs
+}
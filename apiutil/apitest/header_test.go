@@ -0,0 +1,39 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateHeaders(t *testing.T) {
+	maxAge := 3600
+	rr := httptest.NewRecorder()
+	rr.Header().Set("X-CSRF-Token", "abc123")
+	http.SetCookie(rr, &http.Cookie{Name: "user", Value: "foo", HttpOnly: true, MaxAge: maxAge})
+
+	testCase := TestCase{
+		ExpectedHeaders: map[string]string{"x-csrf-token": "^[a-z0-9]+$"},
+		HeaderMatchMode: HeaderMatchRegex,
+		ExpectedCookies: []ExpectedCookie{
+			{Name: "user", HttpOnly: &True, Secure: &False, MaxAge: &maxAge},
+			{Name: "csrf", Absent: true},
+		},
+	}
+
+	if errs := validateHeaders(rr.Result(), testCase); len(errs) != 0 {
+		t.Errorf("should not have errors; got %v", errs)
+	}
+
+	testCase = TestCase{
+		ExpectedHeaders: map[string]string{"X-CSRF-Token": "xyz", "Location": "/"},
+		ExpectedCookies: []ExpectedCookie{
+			{Name: "user", Secure: &True},
+			{Name: "session"},
+		},
+	}
+
+	if errs := validateHeaders(rr.Result(), testCase); len(errs) != 4 {
+		t.Errorf("should have 4 errors; got %v", errs)
+	}
+}
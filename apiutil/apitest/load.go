@@ -0,0 +1,271 @@
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LoadConfig is config struct used in conjunction with RunLoadTest
+type LoadConfig struct {
+	// Concurrency is number of workers sending requests at once
+	// Default is 10
+	Concurrency int
+
+	// Duration is how long to keep sending requests
+	// If not set, Requests is used instead
+	Duration time.Duration
+
+	// Requests is total number of requests to send when Duration is not set
+	// Default is 100
+	Requests int
+}
+
+// LoadReport is returned from RunLoadTest with the results of the run
+type LoadReport struct {
+	Requests int
+	Failures int
+	Elapsed  time.Duration
+	Min      time.Duration
+	Max      time.Duration
+	Mean     time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+
+	// StatusCodes is count of each status code returned
+	StatusCodes map[int]int
+}
+
+// RequestsPerSecond returns throughput of the run
+func (l LoadReport) RequestsPerSecond() float64 {
+	if l.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(l.Requests) / l.Elapsed.Seconds()
+}
+
+func (l LoadReport) String() string {
+	return fmt.Sprintf(
+		"requests: %d, failures: %d, req/s: %.2f, min: %s, mean: %s, p50: %s, p90: %s, p99: %s, max: %s",
+		l.Requests,
+		l.Failures,
+		l.RequestsPerSecond(),
+		l.Min,
+		l.Mean,
+		l.P50,
+		l.P90,
+		l.P99,
+		l.Max,
+	)
+}
+
+// RunTestCasesConcurrently runs every test case n times at once to help
+// find race conditions in handlers, and should be run with "-race"
+//
+// Only status and ExpectedBody are checked as ValidateResponse and
+// PostResponseValidation may not be safe to call concurrently
+// TestCase#File is not sent as a reader can only be read once
+func RunTestCasesConcurrently(t *testing.T, n int, testCases []TestCase) {
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.TestName, func(v *testing.T) {
+			var wg sync.WaitGroup
+
+			errs := make(chan error, n)
+
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					if _, err := serveTestCase(testCase); err != nil {
+						errs <- err
+					}
+				}()
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				v.Error(err)
+			}
+		})
+	}
+}
+
+// RunLoadTest sends the test cases, round robin, to their handlers with the
+// concurrency of config and returns a report of latencies
+//
+// Any response with a different status than TestCase#ExpectedStatus
+// is counted as a failure
+//
+// RunLoadTest can be used within a benchmark, in which case the
+// report is also attached to the benchmark as metrics
+func RunLoadTest(tb testing.TB, config LoadConfig, testCases []TestCase) LoadReport {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 10
+	}
+	if config.Duration <= 0 && config.Requests <= 0 {
+		config.Requests = 100
+	}
+
+	if len(testCases) == 0 {
+		tb.Fatal("apitest: at least one test case is required")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var latencies []time.Duration
+
+	report := LoadReport{StatusCodes: make(map[int]int)}
+	jobs := make(chan TestCase)
+	start := time.Now()
+
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for testCase := range jobs {
+				reqStart := time.Now()
+				code, err := serveTestCase(testCase)
+				latency := time.Since(reqStart)
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				report.StatusCodes[code]++
+
+				if err != nil {
+					report.Failures++
+				}
+
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; ; i++ {
+		if config.Duration > 0 {
+			if time.Since(start) >= config.Duration {
+				break
+			}
+		} else if i >= config.Requests {
+			break
+		}
+
+		jobs <- testCases[i%len(testCases)]
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	report.Requests = len(latencies)
+	setLoadLatencies(&report, latencies)
+
+	if b, ok := tb.(*testing.B); ok {
+		b.ReportMetric(report.RequestsPerSecond(), "req/s")
+		b.ReportMetric(float64(report.P99.Nanoseconds()), "p99-ns")
+		b.ReportMetric(float64(report.Failures), "failures")
+	}
+
+	return report
+}
+
+func setLoadLatencies(report *LoadReport, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	var total time.Duration
+
+	for _, v := range latencies {
+		total += v
+	}
+
+	percentile := func(p float64) time.Duration {
+		idx := int(p*float64(len(latencies))+0.5) - 1
+
+		if idx < 0 {
+			idx = 0
+		}
+
+		return latencies[idx]
+	}
+
+	report.Min = latencies[0]
+	report.Max = latencies[len(latencies)-1]
+	report.Mean = total / time.Duration(len(latencies))
+	report.P50 = percentile(0.50)
+	report.P90 = percentile(0.90)
+	report.P99 = percentile(0.99)
+}
+
+// serveTestCase sends request of testCase to its handler and returns the
+// status code along with error if the status or body was not expected
+func serveTestCase(testCase TestCase) (int, error) {
+	var body bytes.Buffer
+
+	if testCase.Form != nil {
+		if err := json.NewEncoder(&body).Encode(testCase.Form); err != nil {
+			return 0, err
+		}
+	} else if testCase.URLValues != nil {
+		body.WriteString(testCase.URLValues.Encode())
+	}
+
+	req, err := http.NewRequest(testCase.Method, testCase.RequestURL, &body)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for k, v := range testCase.Header {
+		req.Header[k] = v
+	}
+
+	if testCase.ContextValues != nil {
+		ctx := req.Context()
+
+		for key, value := range testCase.ContextValues {
+			ctx = context.WithValue(ctx, key, value)
+		}
+
+		req = req.WithContext(ctx)
+	}
+
+	rr := httptest.NewRecorder()
+	testCaseHandler(testCase).ServeHTTP(rr, req)
+
+	if rr.Code != testCase.ExpectedStatus {
+		return rr.Code, fmt.Errorf(
+			"got status %d; want %d\nbody response: %s",
+			rr.Code,
+			testCase.ExpectedStatus,
+			rr.Body.String(),
+		)
+	}
+
+	if testCase.ExpectedBody != "" && rr.Body.String() != testCase.ExpectedBody {
+		return rr.Code, fmt.Errorf("got body %s; want %s", rr.Body.String(), testCase.ExpectedBody)
+	}
+
+	return rr.Code, nil
+}
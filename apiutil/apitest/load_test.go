@@ -0,0 +1,42 @@
+package apitest
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunLoadTest(t *testing.T) {
+	var count int64
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&count, 1)%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	})
+
+	report := RunLoadTest(t, LoadConfig{Concurrency: 4, Requests: 50}, []TestCase{
+		{
+			Method:         http.MethodGet,
+			RequestURL:     "/url",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "ok",
+			Handler:        handler,
+		},
+	})
+
+	if report.Requests != 50 {
+		t.Errorf("should have 50 requests; got %d", report.Requests)
+	}
+
+	if report.Failures != 5 {
+		t.Errorf("should have 5 failures; got %d", report.Failures)
+	}
+
+	if report.Min > report.P50 || report.P50 > report.P99 || report.P99 > report.Max {
+		t.Errorf("latencies should be ordered; got %s", report.String())
+	}
+}
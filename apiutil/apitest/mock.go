@@ -0,0 +1,282 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+// MockFailure is how MockEndpoint fails instead of responding
+type MockFailure int
+
+const (
+	// MockFailureNone responds normally
+	MockFailureNone MockFailure = iota
+
+	// MockFailureConnection closes the connection without responding so
+	// the client gets a network error
+	MockFailureConnection
+
+	// MockFailureTimeout never responds, blocking until the client gives
+	// up on the request
+	MockFailureTimeout
+)
+
+// MockEndpoint is canned endpoint of a third party api served by
+// MockServer
+type MockEndpoint struct {
+	// Method is method of requests matched eg. http.MethodGet
+	// Default matches any method
+	Method string
+
+	// Path is path of requests matched eg. "/v1/users"
+	// Path ending in "*" matches any path starting with the rest of it
+	Path string
+
+	// Match, if set, is additional check requests must pass to be
+	// matched eg. checking body or headers
+	Match func(r *http.Request, body []byte) bool
+
+	// Status is status of response
+	// Default is 200
+	Status int
+
+	// Header is header of response
+	Header http.Header
+
+	// Body is body of response where []byte and string are written as
+	// is and anything else is written as json
+	Body interface{}
+
+	// Latency is how long to wait before responding
+	Latency time.Duration
+
+	// Failure is how endpoint fails instead of responding
+	// Default is MockFailureNone
+	Failure MockFailure
+
+	// Times is max number of requests the endpoint responds to, after
+	// which requests are matched against the next endpoints, so failures
+	// can be followed by successes eg. to test retries
+	// Default is 0 which responds to every request
+	Times int
+}
+
+// MockRequest is request received by MockServer
+type MockRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+type mockEndpoint struct {
+	MockEndpoint
+	requests []MockRequest
+}
+
+// MockServer is a running httptest.Server that serves canned
+// MockEndpoints so handlers calling third party apis can be tested
+// without them
+//
+// Requests that don't match any endpoint respond 501 and fail
+// AssertExpectations
+type MockServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	endpoints []*mockEndpoint
+	unmatched []MockRequest
+}
+
+// NewMockServer returns running *MockServer serving endpoints that is
+// closed when tb finishes
+func NewMockServer(tb testing.TB, endpoints ...MockEndpoint) *MockServer {
+	m := &MockServer{}
+
+	for _, e := range endpoints {
+		m.Handle(e)
+	}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	tb.Cleanup(m.Server.Close)
+
+	return m
+}
+
+// Handle adds endpoint, which is matched after the existing endpoints
+func (m *MockServer) Handle(endpoint MockEndpoint) *MockServer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.endpoints = append(m.endpoints, &mockEndpoint{MockEndpoint: endpoint})
+	return m
+}
+
+// Requests returns requests received by endpoints of method and path, in
+// the order they were received
+// method and path must be the same as the MockEndpoint they were
+// declared with
+func (m *MockServer) Requests(method, path string) []MockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var requests []MockRequest
+
+	for _, e := range m.endpoints {
+		if e.Method == method && e.Path == path {
+			requests = append(requests, e.requests...)
+		}
+	}
+
+	return requests
+}
+
+// Calls returns number of requests received by endpoints of method and
+// path, see Requests
+func (m *MockServer) Calls(method, path string) int {
+	return len(m.Requests(method, path))
+}
+
+// AssertCalled fails tb if endpoints of method and path weren't called
+// exactly n times
+func (m *MockServer) AssertCalled(tb testing.TB, method, path string, n int) {
+	tb.Helper()
+
+	if calls := m.Calls(method, path); calls != n {
+		tb.Errorf("apitest: %s %s should be called %d times; got %d", method, path, n, calls)
+	}
+}
+
+// AssertExpectations fails tb if any endpoint wasn't called or any
+// request didn't match an endpoint
+func (m *MockServer) AssertExpectations(tb testing.TB) {
+	tb.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.endpoints {
+		if len(e.requests) == 0 {
+			tb.Errorf("apitest: %s %s was not called", e.Method, e.Path)
+		}
+	}
+	for _, r := range m.unmatched {
+		tb.Errorf("apitest: unexpected request %s %s", r.Method, r.Path)
+	}
+}
+
+func (m *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	req := MockRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	endpoint, ok := m.match(r, req)
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("apitest: no mock endpoint for %s %s", r.Method, r.URL.Path), http.StatusNotImplemented)
+		return
+	}
+
+	if endpoint.Latency > 0 {
+		select {
+		case <-time.After(endpoint.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch endpoint.Failure {
+	case MockFailureConnection:
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+
+		panic(http.ErrAbortHandler)
+	case MockFailureTimeout:
+		<-r.Context().Done()
+		return
+	}
+
+	for key, values := range endpoint.Header {
+		w.Header()[key] = values
+	}
+
+	var resBody []byte
+
+	switch v := endpoint.Body.(type) {
+	case nil:
+	case []byte:
+		resBody = v
+	case string:
+		resBody = []byte(v)
+	default:
+		resBody, _ = json.Marshal(v)
+
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+		}
+	}
+
+	status := endpoint.Status
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	w.Write(resBody)
+}
+
+// match returns the first endpoint matching r and records req on it
+func (m *MockServer) match(r *http.Request, req MockRequest) (MockEndpoint, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.endpoints {
+		if e.Times > 0 && len(e.requests) >= e.Times {
+			continue
+		}
+		if e.Method != "" && e.Method != r.Method {
+			continue
+		}
+		if !mockPathMatches(e.Path, r.URL.Path) {
+			continue
+		}
+		if e.Match != nil && !e.Match(r, req.Body) {
+			continue
+		}
+
+		e.requests = append(e.requests, req)
+		return e.MockEndpoint, true
+	}
+
+	m.unmatched = append(m.unmatched, req)
+	return MockEndpoint{}, false
+}
+
+func mockPathMatches(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == path
+}
@@ -0,0 +1,130 @@
+package apitest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
+
+func TestMockServer(t *testing.T) {
+	m := NewMockServer(
+		t,
+		MockEndpoint{
+			Method: http.MethodGet,
+			Path:   "/v1/users/*",
+			Status: http.StatusServiceUnavailable,
+			Times:  1,
+		},
+		MockEndpoint{
+			Method: http.MethodGet,
+			Path:   "/v1/users/*",
+			Body:   map[string]string{"id": "1"},
+		},
+		MockEndpoint{
+			Method: http.MethodPost,
+			Path:   "/v1/users",
+			Match: func(r *http.Request, body []byte) bool {
+				return strings.Contains(string(body), "foo")
+			},
+			Status: http.StatusCreated,
+			Body:   "created",
+		},
+	)
+
+	for _, expected := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		res, err := http.Get(m.URL + "/v1/users/1")
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != expected {
+			t.Errorf("should respond %d; got %d", expected, res.StatusCode)
+		}
+		if expected == http.StatusOK && strings.TrimSpace(string(body)) != `{"id":"1"}` {
+			t.Errorf("got body %s", body)
+		}
+	}
+
+	res, err := http.Post(m.URL+"/v1/users", "text/plain", strings.NewReader("foo"))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("should respond 201; got %d", res.StatusCode)
+	}
+
+	res, err = http.Post(m.URL+"/v1/users", "text/plain", strings.NewReader("bar"))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Errorf("unmatched request should respond 501; got %d", res.StatusCode)
+	}
+
+	m.AssertCalled(t, http.MethodGet, "/v1/users/*", 2)
+	m.AssertCalled(t, http.MethodPost, "/v1/users", 1)
+
+	if requests := m.Requests(http.MethodPost, "/v1/users"); len(requests) != 1 || string(requests[0].Body) != "foo" {
+		t.Errorf("should record body; got %v", requests)
+	}
+
+	ft := &fakeTB{TB: t}
+	m.AssertExpectations(ft)
+
+	if !ft.failed {
+		t.Errorf("unmatched request should fail expectations")
+	}
+}
+
+func TestMockServerFailures(t *testing.T) {
+	m := NewMockServer(
+		t,
+		MockEndpoint{Path: "/reset", Failure: MockFailureConnection},
+		MockEndpoint{Path: "/timeout", Failure: MockFailureTimeout},
+		MockEndpoint{Path: "/slow", Latency: time.Millisecond * 50},
+	)
+	client := &http.Client{Timeout: time.Millisecond * 20}
+
+	for _, path := range []string{"/reset", "/timeout", "/slow"} {
+		if res, err := client.Get(m.URL + path); err == nil {
+			res.Body.Close()
+			t.Errorf("%s should fail", path)
+		}
+	}
+
+	client.Timeout = time.Second
+
+	res, err := client.Get(m.URL + "/slow")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	res.Body.Close()
+	m.AssertExpectations(t)
+}
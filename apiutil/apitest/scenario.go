@@ -0,0 +1,288 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	scenarioVarExp = regexp.MustCompile(`{{\s*([A-Za-z0-9_\-]+)\s*}}`)
+)
+
+// ScenarioVars are the values extracted from previous steps of a ScenarioCase
+type ScenarioVars map[string]interface{}
+
+// ScenarioStep is a single request within a ScenarioCase
+//
+// RequestURL, Header values and string values within Form can reference
+// values extracted by previous steps with "{{name}}" eg. "/api/user/{{userID}}"
+// If a string within Form is only a reference, it is replaced with the
+// extracted value as is, keeping its json type
+type ScenarioStep struct {
+	// StepName is name of step used for sub test
+	StepName string
+	// Method is http method used for request
+	Method string
+	// RequestURL is the url of the request
+	RequestURL string
+	// ExpectedStatus is http response code expected from request
+	ExpectedStatus int
+	// Header is for adding custom header to request
+	Header http.Header
+	// Form is encoded as json and used as body of request
+	Form interface{}
+	// Extract is map of variable name to json path of the value to
+	// extract from the response body eg. "data.0.id" or "$.user.id"
+	// Extracted values can be referenced by every step after
+	Extract map[string]string
+	// ValidateResponse is used to validate response body
+	ValidateResponse Response
+	// PostResponseValidation is called after step with the values
+	// extracted so far
+	PostResponseValidation func(vars ScenarioVars) error
+}
+
+// ScenarioCase is config struct used in conjunction with RunScenarioCases
+// for testing ordered multi step flows such as create, fetch, update
+// then verify
+type ScenarioCase struct {
+	// TestName is name of the scenario
+	TestName string
+	// Handler is the request handler every step is sent to
+	Handler http.Handler
+	// Steps are run in order and the scenario stops at the first failed step
+	Steps []ScenarioStep
+}
+
+// RunScenarioCases runs every scenario as a sub test and every step of
+// the scenario as a sub test of the scenario
+func RunScenarioCases(t *testing.T, scenarios []ScenarioCase) {
+	for _, scenario := range scenarios {
+		scenario := scenario
+
+		t.Run(scenario.TestName, func(s *testing.T) {
+			vars := make(ScenarioVars)
+
+			for i, step := range scenario.Steps {
+				step := step
+				name := step.StepName
+
+				if name == "" {
+					name = fmt.Sprintf("step%d", i+1)
+				}
+
+				passed := s.Run(name, func(v *testing.T) {
+					runScenarioStep(v, scenario.Handler, step, vars)
+				})
+
+				if !passed {
+					s.Fatalf("step '%s' failed, skipping remaining steps", name)
+				}
+			}
+		})
+	}
+}
+
+func runScenarioStep(t *testing.T, handler http.Handler, step ScenarioStep, vars ScenarioVars) {
+	var body bytes.Buffer
+
+	requestURL, err := replaceScenarioVars(step.RequestURL, vars)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step.Form != nil {
+		form, err := replaceScenarioForm(step.Form, vars)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = json.NewEncoder(&body).Encode(form); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(step.Method, requestURL, &body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, values := range step.Header {
+		for _, val := range values {
+			if val, err = replaceScenarioVars(val, vars); err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Add(k, val)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	resBody := rr.Body.Bytes()
+
+	if rr.Code != step.ExpectedStatus {
+		t.Fatalf("got status %d; want %d\nbody response: %s\n", rr.Code, step.ExpectedStatus, resBody)
+	}
+
+	if step.ValidateResponse.ValidateResponseFunc != nil {
+		err = step.ValidateResponse.ValidateResponseFunc(
+			bytes.NewReader(resBody),
+			step.ValidateResponse.ExpectedResult,
+		)
+
+		if err != nil {
+			t.Errorf("%s\n", err.Error())
+		}
+	}
+
+	for name, path := range step.Extract {
+		val, err := ExtractJSONPath(resBody, path)
+
+		if err != nil {
+			t.Fatalf("could not extract '%s': %s", name, err.Error())
+		}
+
+		vars[name] = val
+	}
+
+	if step.PostResponseValidation != nil {
+		if err = step.PostResponseValidation(vars); err != nil {
+			t.Errorf("%s\n", err.Error())
+		}
+	}
+}
+
+// ExtractJSONPath returns the value at path within json source
+// Path is dot separated keys and array indexes with an optional
+// leading "$" eg. "$.data[0].id" or "data.0.id"
+func ExtractJSONPath(source []byte, path string) (interface{}, error) {
+	var val interface{}
+
+	if err := json.Unmarshal(source, &val); err != nil {
+		return nil, err
+	}
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	if path == "" {
+		return val, nil
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			item, ok := v[part]
+
+			if !ok {
+				return nil, fmt.Errorf("apitest: key '%s' not found for path '%s'", part, path)
+			}
+
+			val = item
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("apitest: invalid index '%s' for path '%s'", part, path)
+			}
+
+			val = v[idx]
+		default:
+			return nil, fmt.Errorf("apitest: can't traverse '%s' for path '%s'", part, path)
+		}
+	}
+
+	return val, nil
+}
+
+func scenarioVarString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+func replaceScenarioVars(s string, vars ScenarioVars) (string, error) {
+	var err error
+
+	replaced := scenarioVarExp.ReplaceAllStringFunc(s, func(match string) string {
+		name := scenarioVarExp.FindStringSubmatch(match)[1]
+		val, ok := vars[name]
+
+		if !ok {
+			err = fmt.Errorf("apitest: scenario variable '%s' has not been extracted", name)
+			return match
+		}
+
+		return scenarioVarString(val)
+	})
+
+	return replaced, err
+}
+
+func replaceScenarioForm(form interface{}, vars ScenarioVars) (interface{}, error) {
+	var val interface{}
+
+	source, err := json.Marshal(form)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(source, &val); err != nil {
+		return nil, err
+	}
+
+	return replaceScenarioValue(val, vars)
+}
+
+func replaceScenarioValue(val interface{}, vars ScenarioVars) (interface{}, error) {
+	var err error
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if v[k], err = replaceScenarioValue(item, vars); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if v[i], err = replaceScenarioValue(item, vars); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		if matches := scenarioVarExp.FindStringSubmatch(v); matches != nil && matches[0] == v {
+			item, ok := vars[matches[1]]
+
+			if !ok {
+				return nil, fmt.Errorf("apitest: scenario variable '%s' has not been extracted", matches[1])
+			}
+
+			return item, nil
+		}
+
+		return replaceScenarioVars(v, vars)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,80 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	source := []byte(`{"data": [{"id": 1, "user": {"email": "foo@example.com"}}]}`)
+
+	val, err := ExtractJSONPath(source, "$.data[0].user.email")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if val != "foo@example.com" {
+		t.Errorf("should have 'foo@example.com'; got %v", val)
+	}
+
+	if _, err = ExtractJSONPath(source, "data.1.id"); err == nil {
+		t.Errorf("should have error for out of range index")
+	}
+}
+
+func TestRunScenarioCases(t *testing.T) {
+	items := make(map[string]interface{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var form map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&form)
+			items["7"] = form
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 7}`))
+		case http.MethodGet:
+			item, ok := items[strings.TrimPrefix(r.URL.Path, "/item/")]
+
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			json.NewEncoder(w).Encode(item)
+		}
+	})
+
+	RunScenarioCases(t, []ScenarioCase{
+		{
+			TestName: "create then fetch",
+			Handler:  handler,
+			Steps: []ScenarioStep{
+				{
+					Method:         http.MethodPost,
+					RequestURL:     "/item",
+					Form:           map[string]interface{}{"name": "foo"},
+					ExpectedStatus: http.StatusCreated,
+					Extract:        map[string]string{"id": "id"},
+				},
+				{
+					Method:         http.MethodGet,
+					RequestURL:     "/item/{{id}}",
+					ExpectedStatus: http.StatusOK,
+					Extract:        map[string]string{"name": "name"},
+					PostResponseValidation: func(vars ScenarioVars) error {
+						if vars["name"] != "foo" {
+							return fmt.Errorf("should have name 'foo'; got %v", vars["name"])
+						}
+
+						return nil
+					},
+				},
+			},
+		},
+	})
+}
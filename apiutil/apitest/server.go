@@ -0,0 +1,217 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apitest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/gorilla/csrf"
+	"github.com/gorilla/securecookie"
+)
+
+const (
+	// DefaultSessionName is session name used by Server if
+	// ServerConfig#SessionConfig is not set
+	DefaultSessionName = "user"
+)
+
+// ServerConfig is config struct used in conjunction with NewServer
+type ServerConfig struct {
+	// Handler is the handler, usually a router, that will be wrapped
+	// by the auth, group and routing middleware
+	Handler http.Handler
+
+	// DB is passed to the middleware and query functions
+	DB httputil.DBInterfaceV2
+
+	// SessionConfig is the session name and keys used by the auth middleware
+	// Default session name is DefaultSessionName and default user key
+	// is the same as the session name
+	SessionConfig cacheutil.SessionConfig
+
+	// PathRegex is passed to apiutil.RoutingHandler and determines the
+	// key used to look up allowed urls
+	// Default is the path of the request url
+	PathRegex httputil.PathRegex
+
+	// AnonURLs are urls that can be accessed without being logged in
+	AnonURLs map[string]bool
+
+	// QueryForUser, QueryForGroups and QueryForRouting are used by the
+	// middleware when a value is not found in the session store or cache
+	// Default for each returns sql.ErrNoRows
+	QueryForUser    apiutil.QueryDB
+	QueryForGroups  apiutil.QueryDB
+	QueryForRouting apiutil.QueryDB
+
+	// CSRFKey, if set, will wrap the server with gorilla/csrf and set the
+	// csrf token header on every response
+	CSRFKey []byte
+}
+
+// TestUser is a user that can be logged in through Server#Login
+type TestUser struct {
+	ID     string
+	Email  string
+	Groups map[string]bool
+	URLs   map[string]bool
+}
+
+// Server is a running httptest.Server with the full middleware stack
+// backed by in-memory session and cache stores
+type Server struct {
+	*httptest.Server
+
+	SessionStore *cachetest.MemorySessionStore
+	Cache        *cachetest.MemoryCache
+
+	config ServerConfig
+}
+
+// NewServer starts and returns *Server
+// Server#Close should be deferred to shut down the server
+func NewServer(config ServerConfig) *Server {
+	if config.SessionConfig.SessionName == "" {
+		config.SessionConfig.SessionName = DefaultSessionName
+	}
+	if config.SessionConfig.Keys.UserKey == "" {
+		config.SessionConfig.Keys.UserKey = config.SessionConfig.SessionName
+	}
+	if config.PathRegex == nil {
+		config.PathRegex = func(r *http.Request) (string, error) {
+			return r.URL.Path, nil
+		}
+	}
+	if config.QueryForUser == nil {
+		config.QueryForUser = noRowsQuery
+	}
+	if config.QueryForGroups == nil {
+		config.QueryForGroups = noRowsQuery
+	}
+	if config.QueryForRouting == nil {
+		config.QueryForRouting = noRowsQuery
+	}
+	if config.Handler == nil {
+		config.Handler = http.NotFoundHandler()
+	}
+
+	s := &Server{
+		SessionStore: cachetest.NewMemorySessionStore(securecookie.GenerateRandomKey(32)),
+		Cache:        cachetest.NewMemoryCache(),
+		config:       config,
+	}
+
+	authHandler := apiutil.NewAuthHandler(
+		config.DB,
+		config.QueryForUser,
+		apiutil.AuthHandlerConfig{
+			SessionStore:  s.SessionStore,
+			SessionConfig: config.SessionConfig,
+		},
+	)
+	groupHandler := apiutil.NewGroupHandler(
+		config.DB,
+		config.QueryForGroups,
+		apiutil.GroupHandlerConfig{
+			CacheStore: s.Cache,
+		},
+	)
+	routingHandler := apiutil.NewRoutingHandler(
+		config.DB,
+		config.QueryForRouting,
+		config.PathRegex,
+		config.AnonURLs,
+		apiutil.RoutingHandlerConfig{
+			CacheStore: s.Cache,
+		},
+	)
+
+	handler := authHandler.MiddlewareFunc(
+		groupHandler.MiddlewareFunc(
+			routingHandler.MiddlewareFunc(config.Handler),
+		),
+	)
+
+	if config.CSRFKey != nil {
+		next := handler
+		handler = csrf.Protect(config.CSRFKey, csrf.Secure(false))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				apiutil.SetToken(w, r)
+				next.ServeHTTP(w, r)
+			}),
+		)
+	}
+
+	s.Server = httptest.NewServer(handler)
+	return s
+}
+
+// Login stores a session and the groups and urls of user the same way
+// a login handler would and returns the session cookie
+func (s *Server) Login(user TestUser) (*http.Cookie, error) {
+	userBytes, err := json.Marshal(map[string]string{
+		"id":    user.ID,
+		"email": user.Email,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Groups != nil {
+		if err = s.Cache.SetErr(fmt.Sprintf(apiutil.GroupKey, user.Email), user.Groups, 0); err != nil {
+			return nil, err
+		}
+	}
+	if user.URLs != nil {
+		if err = s.Cache.SetErr(fmt.Sprintf(apiutil.URLKey, user.Email), user.URLs, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.SessionStore.CreateSession(
+		s.config.SessionConfig.SessionName,
+		map[interface{}]interface{}{
+			s.config.SessionConfig.Keys.UserKey: s.config.SessionConfig.EncodeValue(userBytes),
+		},
+	)
+}
+
+// NewClient returns *Client for the server with no session
+func (s *Server) NewClient() *Client {
+	jar, _ := cookiejar.New(nil)
+
+	return &Client{
+		Client:  &http.Client{Jar: jar},
+		BaseURL: s.URL,
+	}
+}
+
+// LoginClient logs in user and returns *Client with the session cookie set
+func (s *Server) LoginClient(user TestUser) (*Client, error) {
+	cookie, err := s.Login(user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.NewClient()
+	u, _ := url.Parse(s.URL)
+	client.Jar.SetCookies(u, []*http.Cookie{cookie})
+	return client, nil
+}
+
+func noRowsQuery(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+	return nil, sql.ErrNoRows
+}
@@ -0,0 +1,282 @@
+package apitest
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/xeipuuli/gojsonschema"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ValidateJSONSchema returns function to be used as
+// Response#ValidateResponseFunc that validates the response body
+// against the given json schema document
+//
+// The expectedResult argument of the returned function is ignored
+func ValidateJSONSchema(schema []byte) func(io.Reader, interface{}) error {
+	return validateJSONSchema(gojsonschema.NewBytesLoader(schema))
+}
+
+// ValidateJSONSchemaFile is the same as ValidateJSONSchema except the
+// schema is read from the file at path
+func ValidateJSONSchemaFile(path string) func(io.Reader, interface{}) error {
+	return validateJSONSchema(gojsonschema.NewReferenceLoader("file://" + path))
+}
+
+func validateJSONSchema(schemaLoader gojsonschema.JSONLoader) func(io.Reader, interface{}) error {
+	return func(bodyResponse io.Reader, expectedResult interface{}) error {
+		body, err := ioutil.ReadAll(bodyResponse)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewBytesLoader(body))
+
+		if err != nil {
+			return fmt.Errorf("apitest: %s", err.Error())
+		}
+
+		if result.Valid() {
+			return nil
+		}
+
+		msgs := make([]string, 0, len(result.Errors()))
+
+		for _, v := range result.Errors() {
+			msgs = append(msgs, v.String())
+		}
+
+		return errors.New("apitest: response does not match schema:\n - " + strings.Join(msgs, "\n - "))
+	}
+}
+
+// ValidateStructResponse checks that the json response body has the same
+// shape as expectedResult, which should be a struct, pointer to struct
+// or slice of either
+//
+// Every key within the response must match the json tag of a field, and
+// the json type of each value must be decodable into the field's type
+// Only shape is checked, not values, so it can be used for responses
+// whose values vary between runs
+//
+// Fields missing from the response are not reported as they may
+// be omitted with "omitempty"
+func ValidateStructResponse(bodyResponse io.Reader, expectedResult interface{}) error {
+	var val interface{}
+
+	if expectedResult == nil {
+		return errors.New("apitest: expected result must be struct")
+	}
+
+	body, err := ioutil.ReadAll(bodyResponse)
+
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(body, &val); err != nil {
+		return fmt.Errorf("apitest: response is not valid json: %s", err.Error())
+	}
+
+	var msgs []string
+	checkJSONShape(val, reflect.TypeOf(expectedResult), "$", &msgs)
+
+	if len(msgs) > 0 {
+		return errors.New("apitest: response does not match struct:\n - " + strings.Join(msgs, "\n - "))
+	}
+
+	return nil
+}
+
+func checkJSONShape(val interface{}, typ reflect.Type, path string, msgs *[]string) {
+	for typ.Kind() == reflect.Ptr {
+		if val == nil {
+			return
+		}
+
+		typ = typ.Elem()
+	}
+
+	// Types that decode themselves can accept any json so only
+	// their existence can be checked
+	if reflect.PtrTo(typ).Implements(jsonUnmarshalerType) ||
+		reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return
+	}
+
+	mismatch := func(expected string) {
+		*msgs = append(*msgs, fmt.Sprintf("%s: expected %s; got %s", path, expected, jsonTypeName(val)))
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.Struct:
+		obj, ok := val.(map[string]interface{})
+
+		if !ok {
+			mismatch("object")
+			return
+		}
+
+		fields := make(map[string]jsonField)
+		collectJSONFields(typ, fields)
+
+		for k, item := range obj {
+			field, ok := fields[k]
+
+			if !ok {
+				*msgs = append(*msgs, fmt.Sprintf("%s.%s: unknown field", path, k))
+				continue
+			}
+
+			if field.asString {
+				if _, ok = item.(string); !ok && item != nil {
+					*msgs = append(*msgs, fmt.Sprintf(
+						"%s.%s: expected string; got %s",
+						path,
+						k,
+						jsonTypeName(item),
+					))
+				}
+
+				continue
+			}
+
+			checkJSONShape(item, field.typ, path+"."+k, msgs)
+		}
+	case reflect.Map:
+		if val == nil {
+			return
+		}
+
+		obj, ok := val.(map[string]interface{})
+
+		if !ok {
+			mismatch("object")
+			return
+		}
+
+		for k, item := range obj {
+			checkJSONShape(item, typ.Elem(), path+"."+k, msgs)
+		}
+	case reflect.Slice, reflect.Array:
+		if val == nil && typ.Kind() == reflect.Slice {
+			return
+		}
+
+		// []byte is encoded as base64 string
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if _, ok := val.(string); !ok {
+				mismatch("string")
+			}
+
+			return
+		}
+
+		arr, ok := val.([]interface{})
+
+		if !ok {
+			mismatch("array")
+			return
+		}
+
+		for i, item := range arr {
+			checkJSONShape(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i), msgs)
+		}
+	case reflect.String:
+		if _, ok := val.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := val.(bool); !ok {
+			mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, ok := val.(float64); !ok {
+			mismatch("number")
+		}
+	}
+}
+
+type jsonField struct {
+	typ      reflect.Type
+	asString bool
+}
+
+// collectJSONFields adds the json name of every field of typ to fields,
+// including fields of embedded structs the same as encoding/json
+func collectJSONFields(typ reflect.Type, fields map[string]jsonField) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		fieldType := field.Type
+
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct {
+				collectJSONFields(fieldType, fields)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = jsonField{
+			typ:      field.Type,
+			asString: strings.Contains(opts, "string"),
+		}
+	}
+}
+
+func jsonTypeName(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+
+	return fmt.Sprintf("%T", val)
+}
@@ -0,0 +1,49 @@
+package apitest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateStructResponse(t *testing.T) {
+	type base struct {
+		ID int64 `json:"id,string"`
+	}
+
+	type user struct {
+		base
+		Email     string            `json:"email"`
+		Age       *int              `json:"age"`
+		Tags      []string          `json:"tags"`
+		Meta      map[string]int    `json:"meta"`
+		CreatedAt time.Time         `json:"created_at"`
+		Ignored   string            `json:"-"`
+		Extra     map[string]string `json:"extra,omitempty"`
+	}
+
+	body := `{"id": "1", "email": "foo@example.com", "age": null, "tags": ["a"], "meta": {"a": 1}, "created_at": "2020-01-01T00:00:00Z"}`
+
+	if err := ValidateStructResponse(strings.NewReader(body), user{}); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	body = `[{"id": "1", "email": "foo@example.com"}]`
+
+	if err := ValidateStructResponse(strings.NewReader(body), []*user{}); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	body = `{"id": 1, "email": 2, "tags": "a", "unknown": true}`
+	err := ValidateStructResponse(strings.NewReader(body), user{})
+
+	if err == nil {
+		t.Fatalf("should have error")
+	}
+
+	for _, v := range []string{"$.id", "$.email", "$.tags", "$.unknown: unknown field"} {
+		if !strings.Contains(err.Error(), v) {
+			t.Errorf("error should contain '%s'; got %s", v, err.Error())
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package apiutil
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/diffutil"
+	"github.com/TravisS25/httputil/muxutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// DefaultAuditTable is table used to store audit entries if
+	// AuditConfig#TableName is not set
+	DefaultAuditTable = "audit_log"
+
+	// AuditActionCreate is action of audit entries of post requests
+	AuditActionCreate = "create"
+
+	// AuditActionUpdate is action of audit entries of put and patch
+	// requests
+	AuditActionUpdate = "update"
+
+	// AuditActionDelete is action of audit entries of delete requests
+	AuditActionDelete = "delete"
+)
+
+const (
+	auditPostgresTableQuery = `
+	create table if not exists %[1]s (
+		id bigserial primary key,
+		actor_id varchar(64),
+		impersonator_id varchar(64),
+		action varchar(32) not null,
+		entity_type varchar(128) not null,
+		entity_id varchar(64),
+		ip varchar(45),
+		payload text,
+		created_at timestamp not null default current_timestamp
+	);
+	create index if not exists %[1]s_actor_idx on %[1]s (actor_id, created_at);
+	create index if not exists %[1]s_entity_idx on %[1]s (entity_type, entity_id, created_at);
+	create index if not exists %[1]s_created_idx on %[1]s (created_at)`
+
+	auditMysqlTableQuery = `
+	create table if not exists %[1]s (
+		id bigint not null auto_increment primary key,
+		actor_id varchar(64),
+		impersonator_id varchar(64),
+		action varchar(32) not null,
+		entity_type varchar(128) not null,
+		entity_id varchar(64),
+		ip varchar(45),
+		payload text,
+		created_at timestamp not null default current_timestamp,
+		index %[1]s_actor_idx (actor_id, created_at),
+		index %[1]s_entity_idx (entity_type, entity_id, created_at),
+		index %[1]s_created_idx (created_at)
+	)`
+
+	auditColumns = "id, actor_id, impersonator_id, action, entity_type, entity_id, ip, payload, created_at"
+)
+
+var (
+	// AuditFields are fields of audit table that can be filtered and
+	// sorted by AuditSearchHandler
+	AuditFields = map[string]queryutil.FieldConfig{
+		"id":             auditField("id"),
+		"actorID":        auditField("actor_id"),
+		"impersonatorID": auditField("impersonator_id"),
+		"action":         auditField("action"),
+		"entityType":     auditField("entity_type"),
+		"entityID":       auditField("entity_id"),
+		"ip":             auditField("ip"),
+		"createdAt":      auditField("created_at"),
+	}
+)
+
+// AuditEntry is a single action of a user stored in audit table
+type AuditEntry struct {
+	// ActorID is id of user of request, which is the impersonated user
+	// while impersonating
+	ActorID string `json:"actorID" db:"actor_id"`
+
+	// ImpersonatorID is id of user impersonating ActorID, if any, see
+	// GetActorID
+	ImpersonatorID string `json:"impersonatorID" db:"impersonator_id"`
+
+	// Action is what was done eg. AuditActionCreate
+	Action string `json:"action" db:"action"`
+
+	// EntityType is type of entity acted on eg. "invoice"
+	EntityType string `json:"entityType" db:"entity_type"`
+
+	// EntityID is id of entity acted on, if any
+	EntityID string `json:"entityID" db:"entity_id"`
+
+	IP      string `json:"ip" db:"ip"`
+	Payload string `json:"payload" db:"payload"`
+
+	// CreatedAt is set to current time by InsertAudit if zero
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AuditConfig is config struct used for storing and searching audit
+// entries
+type AuditConfig struct {
+	// TableName is table used to store audit entries
+	// Default is DefaultAuditTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding and table creation
+	// Default is Postgres
+	DBType string
+
+	// Entity returns entity type and id of request for AuditLogger
+	// Default uses name of route matched by mux, else its path template,
+	// as entity type and "id" router variable as entity id
+	Entity func(r *http.Request) (entityType, entityID string)
+}
+
+func (a *AuditConfig) setDefaults() {
+	if a.TableName == "" {
+		a.TableName = DefaultAuditTable
+	}
+	if a.DBType == "" {
+		a.DBType = dbutil.Postgres
+	}
+	if a.Entity == nil {
+		a.Entity = routeEntity
+	}
+}
+
+// CreateAuditTable creates audit table, along with indexes on actor,
+// entity and time, if it does not exist
+func CreateAuditTable(db httputil.XODB, config AuditConfig) error {
+	config.setDefaults()
+
+	if config.DBType == dbutil.Mysql {
+		_, err := db.Exec(fmt.Sprintf(auditMysqlTableQuery, config.TableName))
+		return err
+	}
+
+	for _, query := range strings.Split(fmt.Sprintf(auditPostgresTableQuery, config.TableName), ";") {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertAudit stores entry in audit table
+func InsertAudit(db httputil.XODB, config AuditConfig, entry AuditEntry) error {
+	config.setDefaults()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := db.Exec(
+		sqlx.Rebind(sqlx.BindType(config.DBType), fmt.Sprintf(
+			"insert into %s (actor_id, impersonator_id, action, entity_type, entity_id, ip, payload, created_at) "+
+				"values (?, ?, ?, ?, ?, ?, ?, ?)",
+			config.TableName,
+		)),
+		nullString(entry.ActorID),
+		nullString(entry.ImpersonatorID),
+		entry.Action,
+		entry.EntityType,
+		nullString(entry.EntityID),
+		nullString(entry.IP),
+		entry.Payload,
+		entry.CreatedAt,
+	)
+
+	return err
+}
+
+// AuditLogger implements InsertLogger, and can be used as
+// Middleware#LogInserter, by storing every request as AuditEntry
+type AuditLogger struct {
+	config AuditConfig
+}
+
+// NewAuditLogger returns *AuditLogger
+func NewAuditLogger(config AuditConfig) *AuditLogger {
+	config.setDefaults()
+	return &AuditLogger{config: config}
+}
+
+// InsertLog implements InsertLogger
+func (a *AuditLogger) InsertLog(r *http.Request, payload string, db httputil.DBInterface) error {
+	return InsertAudit(db, a.config, a.Entry(r, payload))
+}
+
+// LogInserter implements Middleware#LogInserter
+func (a *AuditLogger) LogInserter(w http.ResponseWriter, r *http.Request, payload []byte, db httputil.DBInterface) error {
+	return a.InsertLog(r, string(payload), db)
+}
+
+// InsertDiff stores request as AuditEntry with before and after values
+// of changes, from diffutil#Compare, as payload instead of raw payload
+// of request
+func (a *AuditLogger) InsertDiff(r *http.Request, db httputil.DBInterface, changes diffutil.Changes) error {
+	return InsertAudit(db, a.config, a.Entry(r, changes.JSON()))
+}
+
+// Entry returns AuditEntry of request with payload
+func (a *AuditLogger) Entry(r *http.Request, payload string) AuditEntry {
+	entityType, entityID := a.config.Entity(r)
+	entry := AuditEntry{
+		ActorID:    GetUserID(r),
+		Action:     auditAction(r.Method),
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         ClientIP(r),
+		Payload:    payload,
+	}
+
+	if IsImpersonating(r) {
+		entry.ImpersonatorID = GetActorID(r)
+	}
+
+	return entry
+}
+
+// AuditSearchHandler is endpoint that lets admins search audit entries
+// with the same filter, sort and pagination query params as
+// Resource#List, using AuditFields
+// Entries are sorted newest first by default
+type AuditSearchHandler struct {
+	resource *Resource
+}
+
+// NewAuditSearchHandler returns *AuditSearchHandler
+// queryConf can be used to limit results eg. with
+// queryutil#QueryConfig#TakeLimit
+func NewAuditSearchHandler(db httputil.DBInterfaceV2, config AuditConfig, queryConf queryutil.QueryConfig) *AuditSearchHandler {
+	config.setDefaults()
+
+	if queryConf.SQLBindVar == nil {
+		bindVar := sqlx.BindType(config.DBType)
+		queryConf.SQLBindVar = &bindVar
+	}
+	if queryConf.Dialect == "" {
+		queryConf.Dialect = config.DBType
+	}
+	if queryConf.DefaultSorts == nil {
+		queryConf.DefaultSorts = []queryutil.Sort{{Field: "createdAt", Dir: "desc"}}
+	}
+	if queryConf.TiebreakerColumn == "" {
+		queryConf.TiebreakerColumn = "id"
+	}
+
+	return &AuditSearchHandler{
+		resource: NewResource(db, ResourceConfig{
+			Table:      config.TableName,
+			ListQuery:  fmt.Sprintf("select %s from %s", auditColumns, config.TableName),
+			CountQuery: fmt.Sprintf("select count(*) from %s", config.TableName),
+			Fields:     AuditFields,
+			QueryConf:  queryConf,
+		}),
+	}
+}
+
+func (a *AuditSearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.resource.List(w, r)
+}
+
+func auditField(column string) queryutil.FieldConfig {
+	return queryutil.FieldConfig{
+		DBField:       column,
+		OperationConf: queryutil.OperationConfig{CanFilterBy: true, CanSortBy: true},
+	}
+}
+
+func auditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return AuditActionCreate
+	case http.MethodPut, http.MethodPatch:
+		return AuditActionUpdate
+	case http.MethodDelete:
+		return AuditActionDelete
+	}
+
+	return strings.ToLower(method)
+}
+
+func routeEntity(r *http.Request) (string, string) {
+	return muxutil.RouteLabel(r), mux.Vars(r)["id"]
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
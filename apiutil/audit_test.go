@@ -0,0 +1,76 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestCreateAuditTable(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec("create table if not exists audit_log").WillReturnResult(dbtest.NewResult(0, 0))
+	db.ExpectExec("create index if not exists audit_log_actor_idx").WillReturnResult(dbtest.NewResult(0, 0))
+	db.ExpectExec("create index if not exists audit_log_entity_idx").WillReturnResult(dbtest.NewResult(0, 0))
+	db.ExpectExec("create index if not exists audit_log_created_idx").WillReturnResult(dbtest.NewResult(0, 0))
+
+	if err := CreateAuditTable(db, AuditConfig{}); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	db.ExpectExec("(?s)create table if not exists audit_log.*index audit_log_actor_idx").WillReturnResult(dbtest.NewResult(0, 0))
+
+	if err := CreateAuditTable(db, AuditConfig{DBType: dbutil.Mysql}); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+}
+
+func TestAuditLogger(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	logger := NewAuditLogger(AuditConfig{})
+
+	db.ExpectExec(`insert into audit_log \(actor_id, impersonator_id, action, entity_type, entity_id, ip, payload, created_at\) values \(\$1`).
+		WithArgs("2", "1", AuditActionUpdate, "invoice", "10", "1.2.3.4", `{"total":1}`, dbtest.AnyArg()).
+		WillReturnResult(dbtest.NewResult(1, 1))
+	db.ExpectExec(`insert into audit_log`).
+		WithArgs("1", nil, AuditActionDelete, "/api/item/{id}", "5", "1.2.3.4", "", dbtest.AnyArg()).
+		WillReturnResult(dbtest.NewResult(2, 1))
+
+	router := mux.NewRouter()
+	router.Handle("/api/invoice/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := logger.InsertLog(r, `{"total":1}`, db); err != nil {
+			t.Errorf("should not return error; got %s", err.Error())
+		}
+	})).Name("invoice")
+	router.Handle("/api/item/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := logger.LogInserter(w, r, nil, db); err != nil {
+			t.Errorf("should not return error; got %s", err.Error())
+		}
+	}))
+
+	// Impersonated user 2 is actor and impersonator is recorded
+	req := httptest.NewRequest(http.MethodPut, "/api/invoice/10", strings.NewReader(`{"total":1}`))
+	req.RemoteAddr = "1.2.3.4:1234"
+	ctx := context.WithValue(req.Context(), MiddlewareUserCtxKey, middlewareUser{ID: "2"})
+	ctx = context.WithValue(ctx, ImpersonatorCtxKey, middlewareUser{ID: "1"})
+	router.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/item/5", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, middlewareUser{ID: "1"}))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+}
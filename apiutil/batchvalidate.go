@@ -0,0 +1,127 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+)
+
+// BatchValidateConfig is config struct used for BatchValidateHandler
+type BatchValidateConfig struct {
+	// Validator validates each row of request, where body of request
+	// passed to Validator is the json of the row - Required
+	// This is usually the same as BulkHandlerConfig#Validator of the
+	// endpoint rows are later committed to
+	Validator ResourceValidator
+
+	// Instance, if set, returns the existing row a row edit applies to,
+	// which is passed to Validator as instance so partial rows can be
+	// validated as updates
+	// Returning nil instance validates row as a create
+	Instance func(r *http.Request, index int, row json.RawMessage) (interface{}, error)
+
+	// MaxItems is max number of rows of request
+	// Default value is 1000
+	MaxItems int
+}
+
+// BatchValidateResult is result of a single row of a batch validation
+type BatchValidateResult struct {
+	// Index is index of row within request
+	Index int `json:"index"`
+
+	Valid bool `json:"valid"`
+
+	// Error is why row is invalid, if it is, where errors of fields
+	// are within Error#Fields
+	Error *httputil.Error `json:"error,omitempty"`
+}
+
+// BatchValidateResponse is json sent by BatchValidateHandler
+type BatchValidateResponse struct {
+	Results []BatchValidateResult `json:"results"`
+	Valid   int                   `json:"valid"`
+	Invalid int                   `json:"invalid"`
+}
+
+// BatchValidateHandler is endpoint that validates rows edited inline
+// within a grid without persisting them, so frontends can show errors
+// of every row before committing edits eg. through BulkHandler
+//
+// Body of request is a json array of rows, which are each validated
+// with BatchValidateConfig#Validator, and BatchValidateResponse is sent
+// with the result of every row
+// Status is 200 if every row is valid, else 406
+type BatchValidateHandler struct {
+	config BatchValidateConfig
+}
+
+// NewBatchValidateHandler returns *BatchValidateHandler
+func NewBatchValidateHandler(config BatchValidateConfig) *BatchValidateHandler {
+	if config.MaxItems <= 0 {
+		config.MaxItems = 1000
+	}
+
+	return &BatchValidateHandler{config: config}
+}
+
+func (b *BatchValidateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var raws []json.RawMessage
+
+	if HasBodyError(w, r) {
+		return
+	}
+	if HasDecodeError(w, DecodeBody(r, &raws)) {
+		return
+	}
+
+	if len(raws) == 0 {
+		WriteError(w, httputil.Invalid(bulkEmptyTxt, nil))
+		return
+	}
+	if len(raws) > b.config.MaxItems {
+		WriteError(w, httputil.Invalid(fmt.Sprintf(bulkTooLargeTxt, b.config.MaxItems), nil))
+		return
+	}
+
+	res := BatchValidateResponse{Results: make([]BatchValidateResult, len(raws))}
+
+	for i, raw := range raws {
+		res.Results[i].Index = i
+
+		if err := b.validate(r, i, raw); err != nil {
+			res.Results[i].Error = bulkError(err)
+			res.Invalid++
+			continue
+		}
+
+		res.Results[i].Valid = true
+		res.Valid++
+	}
+
+	status := http.StatusOK
+
+	if res.Invalid > 0 {
+		status = http.StatusNotAcceptable
+	}
+
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.WriteHeader(status)
+	SendPayload(w, res)
+}
+
+func (b *BatchValidateHandler) validate(r *http.Request, index int, raw json.RawMessage) error {
+	var instance interface{}
+	var err error
+
+	if b.config.Instance != nil {
+		if instance, err = b.config.Instance(r, index, raw); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.config.Validator.Validate(bulkItemRequest(r, raw), instance)
+	return err
+}
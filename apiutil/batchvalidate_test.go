@@ -0,0 +1,62 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+)
+
+func TestBatchValidateHandler(t *testing.T) {
+	serve := func(config BatchValidateConfig, body string) (*httptest.ResponseRecorder, BatchValidateResponse) {
+		var res BatchValidateResponse
+
+		config.Validator = bulkTestValidator{}
+		rr := httptest.NewRecorder()
+		NewBatchValidateHandler(config).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(body)))
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		return rr, res
+	}
+
+	rr, res := serve(BatchValidateConfig{}, `[{"name": "a"}, {"name": "b"}]`)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if res.Valid != 2 || res.Invalid != 0 {
+		t.Errorf("should validate every row; got %+v", res)
+	}
+
+	rr, res = serve(BatchValidateConfig{}, `[{"name": "a"}, {"name": ""}, {"name": "c"}]`)
+
+	if rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+	if res.Valid != 2 || res.Invalid != 1 || !res.Results[2].Valid {
+		t.Errorf("should validate rows independently; got %+v", res)
+	}
+	if res.Results[1].Error == nil || res.Results[1].Error.Fields["name"] != "Required" {
+		t.Errorf("should return field errors of invalid row; got %+v", res.Results[1])
+	}
+
+	rr, res = serve(BatchValidateConfig{
+		Instance: func(r *http.Request, index int, row json.RawMessage) (interface{}, error) {
+			if index == 1 {
+				return nil, httputil.NotFound(notFoundTxt)
+			}
+
+			return nil, nil
+		},
+	}, `[{"name": "a"}, {"name": "b"}]`)
+
+	if res.Valid != 1 || res.Results[1].Error == nil || res.Results[1].Error.Code != httputil.CodeNotFound {
+		t.Errorf("should return error of instance; got %+v", res)
+	}
+
+	if rr, _ = serve(BatchValidateConfig{MaxItems: 1}, `[{"name": "a"}, {"name": "b"}]`); rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+}
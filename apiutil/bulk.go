@@ -0,0 +1,273 @@
+package apiutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+)
+
+// BulkMode determines how BulkHandler handles items that fail
+type BulkMode int
+
+const (
+	// BulkAllOrNothing applies every item within one transaction, where
+	// no item is applied if any item fails
+	BulkAllOrNothing BulkMode = iota
+
+	// BulkBestEffort applies every item that is valid and executes
+	// successfully, where items are executed in chunks of
+	// BulkHandlerConfig#ChunkSize with a transaction each
+	BulkBestEffort
+)
+
+const (
+	bulkEmptyTxt      = "Request must have at least one item"
+	bulkTooLargeTxt   = "Request has more than %d items"
+	bulkNotAppliedTxt = "Not applied as another item failed"
+)
+
+// BulkItemResult is result of a single item of a bulk request
+type BulkItemResult struct {
+	// Index is index of item within request
+	Index int `json:"index"`
+
+	Success bool `json:"success"`
+
+	// ID is value returned from BulkHandlerConfig#Exec, if any
+	ID interface{} `json:"id,omitempty"`
+
+	// Error is why item failed, if it did
+	Error *httputil.Error `json:"error,omitempty"`
+}
+
+// BulkResponse is json sent by BulkHandler
+type BulkResponse struct {
+	Results   []BulkItemResult `json:"results"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+}
+
+// BulkHandlerConfig is config struct used for BulkHandler
+type BulkHandlerConfig struct {
+	// Validator validates each item of request, where body of request
+	// passed to Validator is the json of the item - Required
+	Validator ResourceValidator
+
+	// Exec creates, updates or deletes form returned from Validator
+	// within tx and returns value sent as BulkItemResult#ID - Required
+	Exec func(tx httputil.Tx, r *http.Request, index int, form interface{}) (interface{}, error)
+
+	// Mode is how items that fail are handled
+	// Default is BulkAllOrNothing
+	Mode BulkMode
+
+	// ChunkSize is number of items executed within one transaction for
+	// BulkBestEffort
+	// Default value is 100
+	ChunkSize int
+
+	// MaxItems is max number of items of request
+	// Default value is 1000
+	MaxItems int
+}
+
+// BulkHandler is endpoint for bulk create, update and delete requests
+// whose body is a json array of items
+//
+// Every item is validated with BulkHandlerConfig#Validator and executed
+// with BulkHandlerConfig#Exec, and BulkResponse is sent with the result
+// of every item
+//
+// Status is 200 if every item succeeded, else:
+//   - BulkAllOrNothing is 406 if any item is invalid, else status of the
+//     error of the item that failed to execute
+//   - BulkBestEffort is 207
+type BulkHandler struct {
+	db     httputil.DBInterfaceV2
+	config BulkHandlerConfig
+}
+
+// NewBulkHandler returns *BulkHandler
+func NewBulkHandler(db httputil.DBInterfaceV2, config BulkHandlerConfig) *BulkHandler {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = 100
+	}
+	if config.MaxItems <= 0 {
+		config.MaxItems = 1000
+	}
+
+	return &BulkHandler{
+		db:     db,
+		config: config,
+	}
+}
+
+type bulkItem struct {
+	index int
+	form  interface{}
+}
+
+func (b *BulkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var raws []json.RawMessage
+
+	if HasBodyError(w, r) {
+		return
+	}
+	if HasDecodeError(w, DecodeBody(r, &raws)) {
+		return
+	}
+
+	if len(raws) == 0 {
+		WriteError(w, httputil.Invalid(bulkEmptyTxt, nil))
+		return
+	}
+	if len(raws) > b.config.MaxItems {
+		WriteError(w, httputil.Invalid(fmt.Sprintf(bulkTooLargeTxt, b.config.MaxItems), nil))
+		return
+	}
+
+	res := BulkResponse{Results: make([]BulkItemResult, len(raws))}
+	items := make([]bulkItem, 0, len(raws))
+
+	for i, raw := range raws {
+		res.Results[i].Index = i
+		form, err := b.config.Validator.Validate(bulkItemRequest(r, raw), nil)
+
+		if err != nil {
+			res.Results[i].Error = bulkError(err)
+			continue
+		}
+
+		items = append(items, bulkItem{index: i, form: form})
+	}
+
+	status := http.StatusOK
+
+	if b.config.Mode == BulkBestEffort {
+		for start := 0; start < len(items); start += b.config.ChunkSize {
+			end := start + b.config.ChunkSize
+
+			if end > len(items) {
+				end = len(items)
+			}
+
+			b.execChunk(r, items[start:end], res.Results)
+		}
+	} else if len(items) < len(raws) {
+		status = http.StatusNotAcceptable
+		markNotApplied(items, res.Results)
+	} else if failed := b.execAll(r, items, res.Results); failed >= 0 {
+		status = res.Results[failed].Error.Status
+		markNotApplied(items, res.Results)
+	}
+
+	for _, result := range res.Results {
+		if result.Success {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+	}
+
+	if res.Failed > 0 && b.config.Mode == BulkBestEffort {
+		status = http.StatusMultiStatus
+	}
+
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.WriteHeader(status)
+	SendPayload(w, res)
+}
+
+// execAll executes every item within one transaction
+// Returns index of item that failed, in which case transaction is rolled
+// back, else -1
+// Errors of the transaction itself are set as error of first item
+func (b *BulkHandler) execAll(r *http.Request, items []bulkItem, results []BulkItemResult) int {
+	tx, err := b.db.Begin()
+
+	if err != nil {
+		results[items[0].index].Error = bulkError(err)
+		return items[0].index
+	}
+
+	for _, item := range items {
+		if err = b.exec(tx, r, item, results); err != nil {
+			tx.Rollback()
+			return item.index
+		}
+	}
+
+	if err = b.db.Commit(tx); err != nil {
+		results[items[0].index].Error = bulkError(err)
+		return items[0].index
+	}
+
+	return -1
+}
+
+// execChunk executes items within one transaction
+// If any item fails, transaction is rolled back and every item is
+// executed again within its own transaction so only failing items are
+// not applied, which means BulkHandlerConfig#Exec can be called twice
+// for an item
+func (b *BulkHandler) execChunk(r *http.Request, items []bulkItem, results []BulkItemResult) {
+	if b.execAll(r, items, results) < 0 || len(items) == 1 {
+		return
+	}
+
+	for _, item := range items {
+		results[item.index] = BulkItemResult{Index: item.index}
+		b.execAll(r, []bulkItem{item}, results)
+	}
+}
+
+func (b *BulkHandler) exec(tx httputil.Tx, r *http.Request, item bulkItem, results []BulkItemResult) error {
+	id, err := b.config.Exec(tx, r, item.index, item.form)
+
+	if err != nil {
+		results[item.index].Error = bulkError(err)
+		return err
+	}
+
+	results[item.index].ID = id
+	results[item.index].Success = true
+	return nil
+}
+
+// markNotApplied marks valid items as failed once transaction of
+// BulkAllOrNothing is not committed
+func markNotApplied(items []bulkItem, results []BulkItemResult) {
+	for _, item := range items {
+		results[item.index].Success = false
+		results[item.index].ID = nil
+
+		if results[item.index].Error == nil {
+			results[item.index].Error = httputil.Conflict(bulkNotAppliedTxt)
+		}
+	}
+}
+
+// bulkItemRequest returns copy of r whose body is raw so item can be
+// validated with ResourceValidator
+func bulkItemRequest(r *http.Request, raw json.RawMessage) *http.Request {
+	itemReq := r.WithContext(r.Context())
+	itemReq.Body = ioutil.NopCloser(bytes.NewReader(raw))
+	itemReq.ContentLength = int64(len(raw))
+	return itemReq
+}
+
+// bulkError converts err to *httputil.Error of item, logging server
+// errors as they're not written by WriteError
+func bulkError(err error) *httputil.Error {
+	e := ToError(err)
+
+	if e.Status >= http.StatusInternalServerError {
+		httputil.Logger.Errorf("apiutil: bulk item err: %s", err.Error())
+	}
+
+	return e
+}
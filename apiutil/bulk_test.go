@@ -0,0 +1,141 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+type bulkTestValidator struct{}
+
+func (bulkTestValidator) Validate(req *http.Request, instance interface{}) (interface{}, error) {
+	var form struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(req.Body).Decode(&form); err != nil {
+		return nil, err
+	}
+	if form.Name == "" {
+		return nil, httputil.Invalid(InvalidFormMessage, map[string]string{"name": "Required"})
+	}
+
+	return form.Name, nil
+}
+
+func TestBulkHandler(t *testing.T) {
+	query := `insert into item`
+	exec := func(tx httputil.Tx, r *http.Request, index int, form interface{}) (interface{}, error) {
+		if _, err := tx.Exec("insert into item (name) values (?)", form); err != nil {
+			return nil, err
+		}
+
+		return form, nil
+	}
+
+	serve := func(db *dbtest.ExpectDB, config BulkHandlerConfig, body string) (*httptest.ResponseRecorder, BulkResponse) {
+		var res BulkResponse
+
+		config.Validator = bulkTestValidator{}
+		config.Exec = exec
+
+		rr := httptest.NewRecorder()
+		NewBulkHandler(db, config).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(body)))
+		json.Unmarshal(rr.Body.Bytes(), &res)
+
+		if err := db.ExpectationsWereMet(); err != nil {
+			t.Errorf("should meet expectations; got %s", err.Error())
+		}
+
+		return rr, res
+	}
+
+	// All or nothing
+	db := dbtest.NewExpectDB(t)
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("a").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(query).WithArgs("b").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	rr, res := serve(db, BulkHandlerConfig{}, `[{"name": "a"}, {"name": "b"}]`)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if res.Succeeded != 2 || res.Results[1].ID != "b" {
+		t.Errorf("should apply every item; got %+v", res)
+	}
+
+	rr, res = serve(dbtest.NewExpectDB(t), BulkHandlerConfig{}, `[{"name": "a"}, {"name": ""}]`)
+
+	if rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+	if res.Failed != 2 || res.Results[1].Error.Fields["name"] == "" || res.Results[0].Error.Code != httputil.CodeConflict {
+		t.Errorf("should not apply any item; got %+v", res)
+	}
+
+	db = dbtest.NewExpectDB(t)
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("a").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(query).WithArgs("b").WillReturnError(httputil.Conflict("Duplicate"))
+	db.ExpectRollback()
+
+	rr, res = serve(db, BulkHandlerConfig{}, `[{"name": "a"}, {"name": "b"}]`)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf(statusErrTxt, http.StatusConflict, rr.Code)
+	}
+	if res.Failed != 2 || res.Results[0].Success || res.Results[1].Error.Message != "Duplicate" {
+		t.Errorf("should not apply any item; got %+v", res)
+	}
+
+	// Best effort, where failed chunk is retried item by item
+	db = dbtest.NewExpectDB(t)
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("a").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(query).WithArgs("b").WillReturnError(errors.New(generalErr))
+	db.ExpectRollback()
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("a").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("b").WillReturnError(errors.New(generalErr))
+	db.ExpectRollback()
+	db.ExpectBegin()
+	db.ExpectExec(query).WithArgs("d").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	rr, res = serve(
+		db,
+		BulkHandlerConfig{Mode: BulkBestEffort, ChunkSize: 2},
+		`[{"name": "a"}, {"name": "b"}, {"name": ""}, {"name": "d"}]`,
+	)
+
+	if rr.Code != http.StatusMultiStatus {
+		t.Errorf(statusErrTxt, http.StatusMultiStatus, rr.Code)
+	}
+	if res.Succeeded != 2 || res.Failed != 2 {
+		t.Errorf("should apply 2 items; got %+v", res)
+	}
+	if !res.Results[0].Success || res.Results[1].Error.Code != httputil.CodeInternal {
+		t.Errorf("should only fail item 1 with server error; got %+v", res.Results)
+	}
+	if res.Results[2].Error.Code != httputil.CodeInvalid || !res.Results[3].Success {
+		t.Errorf("should fail invalid item 2 and apply item 3; got %+v", res.Results)
+	}
+
+	if rr, _ = serve(dbtest.NewExpectDB(t), BulkHandlerConfig{MaxItems: 1}, `[{"name": "a"}, {"name": "b"}]`); rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+	if rr, _ = serve(dbtest.NewExpectDB(t), BulkHandlerConfig{}, `[]`); rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+}
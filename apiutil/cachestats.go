@@ -0,0 +1,38 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// CacheStatsPath is default path CacheStatsHandler should be registered to
+const CacheStatsPath = "/metrics/cache"
+
+type cacheStatsPayload struct {
+	cacheutil.CacheStats
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// CacheStatsHandler returns handler that writes stats of every cache
+// keyed by name eg. "group" for cache used by GroupHandler and "routing"
+// for cache used by RoutingHandler
+//
+// Caches should be created with cacheutil#NewClientCacheWithStats or
+// wrapped with cacheutil#NewInstrumentedCache
+func CacheStatsHandler(caches map[string]cacheutil.StatsProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := make(map[string]cacheStatsPayload, len(caches))
+
+		for name, cache := range caches {
+			stats := cache.Stats()
+			payload[name] = cacheStatsPayload{
+				CacheStats: stats,
+				HitRatio:   stats.HitRatio(),
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, payload)
+	}
+}
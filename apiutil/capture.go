@@ -0,0 +1,170 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// DefaultMaxCaptureBytes is default ResponseCaptureConfig#MaxBodyBytes
+	DefaultMaxCaptureBytes = 64 * 1024
+)
+
+var (
+	// ResponseCaptureCtxKey is key used to store *ResponseCapture within
+	// request context by ResponseCaptureHandler
+	ResponseCaptureCtxKey = MiddlewareKey{KeyName: "responseCapture"}
+)
+
+// ResponseCaptureConfig is config struct used for ResponseCaptureHandler
+type ResponseCaptureConfig struct {
+	// MinStatus is min status of responses whose body is captured
+	// Default value is http.StatusInternalServerError
+	MinStatus int
+
+	// MaxBodyBytes is max number of bytes of body that are captured
+	// where the rest of body is still written to client
+	// Default value is DefaultMaxCaptureBytes
+	MaxBodyBytes int
+
+	// OnCapture is called with every captured response once next
+	// handler returns
+	// Default logs status and body of response with httputil#Logger
+	OnCapture func(r *http.Request, capture *ResponseCapture)
+}
+
+// ResponseCapture is response captured by ResponseCaptureHandler
+type ResponseCapture struct {
+	// Status is status of response
+	Status int
+
+	// Body is body of response up to ResponseCaptureConfig#MaxBodyBytes
+	Body []byte
+
+	// Truncated is whether body was larger than
+	// ResponseCaptureConfig#MaxBodyBytes
+	Truncated bool
+}
+
+// Captured returns whether response status was high enough for its
+// body to be captured
+func (rc *ResponseCapture) Captured() bool {
+	return rc.Body != nil
+}
+
+// ResponseCaptureHandler tees bodies of failed responses, up to a size
+// limit, so what was actually sent to the client can be logged when
+// diagnosing production errors
+//
+// *ResponseCapture is stored within request context so middleware that
+// comes after this one, like Middleware#LogEntryMiddleware or an
+// AuditLogger, can include it with GetResponseCapture once its next
+// handler returns
+type ResponseCaptureHandler struct {
+	config ResponseCaptureConfig
+}
+
+// NewResponseCaptureHandler returns *ResponseCaptureHandler
+func NewResponseCaptureHandler(config ResponseCaptureConfig) *ResponseCaptureHandler {
+	if config.MinStatus == 0 {
+		config.MinStatus = http.StatusInternalServerError
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxCaptureBytes
+	}
+	if config.OnCapture == nil {
+		config.OnCapture = logResponseCapture
+	}
+
+	return &ResponseCaptureHandler{config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (rc *ResponseCaptureHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &captureRecorder{
+			ResponseWriter: w,
+			capture:        &ResponseCapture{Status: http.StatusOK},
+			config:         rc.config,
+		}
+		r = r.WithContext(context.WithValue(r.Context(), ResponseCaptureCtxKey, rec.capture))
+
+		next.ServeHTTP(rec, r)
+
+		if rec.capture.Captured() {
+			rc.config.OnCapture(r, rec.capture)
+		}
+	})
+}
+
+// GetResponseCapture returns *ResponseCapture of request set by
+// ResponseCaptureHandler, which is only complete once handler that
+// writes response returns
+// Returns nil if ResponseCaptureHandler was not used for request
+func GetResponseCapture(r *http.Request) *ResponseCapture {
+	capture, _ := r.Context().Value(ResponseCaptureCtxKey).(*ResponseCapture)
+	return capture
+}
+
+func logResponseCapture(r *http.Request, capture *ResponseCapture) {
+	httputil.Logger.WithFields(logrus.Fields{
+		"method":    r.Method,
+		"url":       r.URL.String(),
+		"status":    capture.Status,
+		"body":      string(capture.Body),
+		"truncated": capture.Truncated,
+	}).Error("response captured")
+}
+
+// captureRecorder buffers body of response, if status is at least
+// ResponseCaptureConfig#MinStatus, while still writing it to client
+type captureRecorder struct {
+	http.ResponseWriter
+	capture     *ResponseCapture
+	config      ResponseCaptureConfig
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *captureRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.writeHeader(status)
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *captureRecorder) writeHeader(status int) {
+	rec.wroteHeader = true
+	rec.capture.Status = status
+
+	if status >= rec.config.MinStatus {
+		rec.capture.Body = []byte{}
+	}
+}
+
+func (rec *captureRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.writeHeader(http.StatusOK)
+	}
+
+	if rec.capture.Captured() && !rec.capture.Truncated {
+		remaining := rec.config.MaxBodyBytes - rec.body.Len()
+
+		if len(b) > remaining {
+			rec.body.Write(b[:remaining])
+			rec.capture.Truncated = true
+		} else {
+			rec.body.Write(b)
+		}
+
+		rec.capture.Body = rec.body.Bytes()
+	}
+
+	return rec.ResponseWriter.Write(b)
+}
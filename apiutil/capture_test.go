@@ -0,0 +1,49 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseCaptureHandler(t *testing.T) {
+	var captured *ResponseCapture
+	var fromCtx *ResponseCapture
+
+	status := http.StatusOK
+	handler := NewResponseCaptureHandler(ResponseCaptureConfig{
+		MaxBodyBytes: 5,
+		OnCapture: func(r *http.Request, capture *ResponseCapture) {
+			captured = capture
+		},
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromCtx = GetResponseCapture(r)
+		w.WriteHeader(status)
+		w.Write([]byte("server error"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if captured != nil {
+		t.Errorf("should not capture successful response")
+	}
+
+	status = http.StatusInternalServerError
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Body.String() != "server error" {
+		t.Errorf("should write whole body to client; got %s", rr.Body.String())
+	}
+	if captured == nil || captured != fromCtx {
+		t.Fatalf("should capture response stored within context")
+	}
+	if captured.Status != status || string(captured.Body) != "serve" || !captured.Truncated {
+		t.Errorf("should capture truncated body; got %d %s", captured.Status, captured.Body)
+	}
+	if !strings.HasPrefix(rr.Body.String(), string(captured.Body)) {
+		t.Errorf("captured body should be start of response")
+	}
+}
@@ -0,0 +1,256 @@
+package apiutil
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+const (
+	forbiddenIPTxt = "Forbidden to access url from address"
+)
+
+var (
+	// ClientIPCtxKey is key used to store resolved client ip within
+	// request context by ClientIPHandler
+	ClientIPCtxKey = ctxutil.ClientIPKey
+)
+
+// IPRouteRule is allow and deny lists applied to every request path
+// that starts with PathPrefix
+type IPRouteRule struct {
+	// PathPrefix is prefix of request path rule applies to, eg. "/admin"
+	PathPrefix string
+
+	// Allow is list of CIDRs or ips allowed to access route
+	// If empty, every ip not within Deny is allowed
+	Allow []string
+
+	// Deny is list of CIDRs or ips not allowed to access route
+	// Deny takes precedence over Allow
+	Deny []string
+}
+
+// ClientIPHandlerConfig is config struct used for ClientIPHandler
+type ClientIPHandlerConfig struct {
+	// TrustedProxies is list of CIDRs or ips of proxies whose
+	// X-Forwarded-For and X-Real-IP headers are trusted
+	// If empty, headers are never trusted and the remote address
+	// of the request is used
+	TrustedProxies []string
+
+	// Allow and Deny are applied to every request
+	// See IPRouteRule for how they are applied
+	Allow []string
+	Deny  []string
+
+	// RouteRules are applied, along with Allow and Deny, to every
+	// request whose path matches the rule
+	RouteRules []IPRouteRule
+
+	// ForbiddenResponse is config used to respond to user if the
+	// client ip is not allowed
+	//
+	// Default status value is http.StatusForbidden
+	// Default response value is []byte("Forbidden to access url from address")
+	ForbiddenResponse HTTPResponseConfig
+}
+
+type ipRule struct {
+	pathPrefix string
+	allow      []*net.IPNet
+	deny       []*net.IPNet
+}
+
+// ClientIPHandler resolves the client ip of requests, honoring headers
+// set by trusted proxies, and stores it within request context
+// under ClientIPCtxKey to be used for things like logging and
+// rate limiting
+// Optionally it will also enforce allow and deny lists
+type ClientIPHandler struct {
+	trustedProxies []*net.IPNet
+	rules          []ipRule
+	config         ClientIPHandlerConfig
+}
+
+// NewClientIPHandler returns *ClientIPHandler
+// Returns error if any of the CIDRs or ips within config are invalid
+func NewClientIPHandler(config ClientIPHandlerConfig) (*ClientIPHandler, error) {
+	var err error
+
+	c := &ClientIPHandler{config: config}
+
+	if c.trustedProxies, err = parseCIDRs(config.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	rules := make([]IPRouteRule, 0, len(config.RouteRules)+1)
+
+	if len(config.Allow) > 0 || len(config.Deny) > 0 {
+		rules = append(rules, IPRouteRule{Allow: config.Allow, Deny: config.Deny})
+	}
+
+	rules = append(rules, config.RouteRules...)
+
+	for _, v := range rules {
+		rule := ipRule{pathPrefix: v.PathPrefix}
+
+		if rule.allow, err = parseCIDRs(v.Allow); err != nil {
+			return nil, err
+		}
+		if rule.deny, err = parseCIDRs(v.Deny); err != nil {
+			return nil, err
+		}
+
+		c.rules = append(c.rules, rule)
+	}
+
+	setHTTPResponseDefaults(&c.config.ForbiddenResponse, http.StatusForbidden, []byte(forbiddenIPTxt))
+	return c, nil
+}
+
+func (c *ClientIPHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ipStr := c.ResolveIP(r)
+		ip := net.ParseIP(ipStr)
+
+		for _, rule := range c.rules {
+			if !strings.HasPrefix(r.URL.Path, rule.pathPrefix) {
+				continue
+			}
+
+			if ip == nil || ipInNets(ip, rule.deny) || (len(rule.allow) > 0 && !ipInNets(ip, rule.allow)) {
+				w.WriteHeader(*c.config.ForbiddenResponse.HTTPStatus)
+				w.Write(c.config.ForbiddenResponse.HTTPResponse)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctxutil.WithClientIP(r.Context(), ipStr)))
+	})
+}
+
+// ResolveIP returns the client ip of r
+//
+// If the remote address of r is a trusted proxy, X-Forwarded-For is
+// walked from right to left and the first address that is not a
+// trusted proxy is returned
+// If a hop that can't be parsed is reached first, eg. "unknown", the
+// remote address of r is returned
+// If X-Forwarded-For is not set, X-Real-IP is used
+func (c *ClientIPHandler) ResolveIP(r *http.Request) string {
+	remoteIP := remoteAddrIP(r.RemoteAddr)
+
+	if !c.isTrusted(remoteIP) {
+		return remoteIP
+	}
+
+	if forwarded := r.Header[http.CanonicalHeaderKey("X-Forwarded-For")]; len(forwarded) > 0 {
+		var hops []string
+
+		for _, v := range forwarded {
+			for _, hop := range strings.Split(v, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			// Hops left of one that can't be parsed can't be trusted
+			// as they could have been set by the client
+			if net.ParseIP(hops[i]) == nil {
+				return remoteIP
+			}
+			if !c.isTrusted(hops[i]) {
+				return hops[i]
+			}
+		}
+
+		// Every hop is trusted so the left most is the client
+		if len(hops) > 0 {
+			return hops[0]
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return remoteIP
+}
+
+func (c *ClientIPHandler) isTrusted(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	return ip != nil && ipInNets(ip, c.trustedProxies)
+}
+
+// ClientIP returns client ip stored within context of r by
+// ClientIPHandler, falling back to the remote address of r
+func ClientIP(r *http.Request) string {
+	if ip := ctxutil.ClientIPFrom(r.Context()); ip != "" {
+		return ip
+	}
+
+	return remoteAddrIP(r.RemoteAddr)
+}
+
+func remoteAddrIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
+
+// parseCIDRs parses list of CIDRs where plain ips are treated
+// as a single address
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, v := range cidrs {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+
+			if ip == nil {
+				return nil, errors.Errorf("apiutil: invalid ip '%s'", v)
+			}
+
+			bits := 128
+
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(v)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "apiutil: invalid cidr '%s'", v)
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPHandler(t *testing.T) {
+	handler, err := NewClientIPHandler(ClientIPHandlerConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Deny:           []string{"203.0.113.66"},
+		RouteRules: []IPRouteRule{
+			{PathPrefix: "/admin", Allow: []string{"192.168.1.0/24"}},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	var clientIP string
+
+	h := handler.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP = ClientIP(r)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		forwardedFor   string
+		realIP         string
+		expectedStatus int
+		expectedIP     string
+	}{
+		{"direct", "/", "198.51.100.1:1234", "", "", http.StatusOK, "198.51.100.1"},
+		{"untrusted proxy header ignored", "/", "198.51.100.1:1234", "1.1.1.1", "", http.StatusOK, "198.51.100.1"},
+		{"trusted proxy", "/", "10.0.0.1:1234", "1.1.1.1, 198.51.100.2, 10.0.0.2", "", http.StatusOK, "198.51.100.2"},
+		{"real ip", "/", "10.0.0.1:1234", "", "198.51.100.3", http.StatusOK, "198.51.100.3"},
+		{"denied", "/", "10.0.0.1:1234", "203.0.113.66", "", http.StatusForbidden, ""},
+		{"admin allowed", "/admin/users", "10.0.0.1:1234", "192.168.1.20", "", http.StatusOK, "192.168.1.20"},
+		{"admin forbidden", "/admin/users", "198.51.100.1:1234", "", "", http.StatusForbidden, ""},
+		{"unparseable hop", "/", "10.0.0.1:1234", "1.1.1.1, 198.51.100.2:443, 10.0.0.2", "", http.StatusOK, "10.0.0.1"},
+		{"unparseable hop spoofed admin", "/admin/users", "10.0.0.1:1234", "192.168.1.20, unknown, 10.0.0.2", "", http.StatusForbidden, ""},
+		{"every hop trusted", "/", "10.0.0.1:1234", "10.0.0.3, 10.0.0.2", "", http.StatusOK, "10.0.0.3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			clientIP = ""
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.RemoteAddr = test.remoteAddr
+
+			if test.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+			if test.realIP != "" {
+				req.Header.Set("X-Real-IP", test.realIP)
+			}
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != test.expectedStatus {
+				v.Errorf("got status %d; want %d", rr.Code, test.expectedStatus)
+			}
+			if clientIP != test.expectedIP {
+				v.Errorf("got ip %s; want %s", clientIP, test.expectedIP)
+			}
+		})
+	}
+
+	if _, err = NewClientIPHandler(ClientIPHandlerConfig{Allow: []string{"foo"}}); err == nil {
+		t.Errorf("should have error for invalid ip")
+	}
+}
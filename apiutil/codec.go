@@ -0,0 +1,128 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/TravisS25/httputil/formutil"
+)
+
+const (
+	// MsgpackMediaType is media type of MessagePack bodies
+	MsgpackMediaType = "application/msgpack"
+
+	// ProtobufMediaType is media type of Protocol Buffers bodies
+	ProtobufMediaType = "application/x-protobuf"
+)
+
+// Decoder reads body from r into v in the format of the media type it
+// was registered with through RegisterDecoder
+type Decoder func(r io.Reader, v interface{}) error
+
+var (
+	decoderMu sync.RWMutex
+	decoders  = map[string]Decoder{
+		"application/json":      decodeJSON,
+		MsgpackMediaType:        decodeMsgpack,
+		"application/x-msgpack": decodeMsgpack,
+		ProtobufMediaType:       decodeProtobuf,
+	}
+)
+
+func init() {
+	encoders[MsgpackMediaType] = encodeMsgpack
+	encoders["application/x-msgpack"] = encodeMsgpack
+	encoders[ProtobufMediaType] = encodeProtobuf
+	encoderSupports[ProtobufMediaType] = func(payload interface{}) bool {
+		_, ok := payload.(proto.Message)
+		return ok
+	}
+
+	// formutil doesn't import apiutil so use its content negotiation
+	// and language matching once apiutil is imported
+	formutil.DecodeBody = DecodeBody
+	formutil.MatchLanguage = func(r *http.Request, langs []string) string {
+		return MatchLanguage([]string{LanguageFromRequest(r)}, langs)
+	}
+}
+
+// RegisterDecoder registers decoder for mediaType to be used by
+// DecodeBody, replacing any existing decoder of mediaType
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	decoderMu.Lock()
+	defer decoderMu.Unlock()
+	decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// DecodeBody decodes body of r into v based on the Content-Type header
+// of r, defaulting to json if not set or not registered
+//
+// Along with json, MessagePack is supported where json struct tags are
+// used, along with Protocol Buffers when v is a proto.Message
+func DecodeBody(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	decoderMu.RLock()
+	decoder, ok := decoders[strings.ToLower(mediaType)]
+	decoderMu.RUnlock()
+
+	if !ok {
+		decoder = decodeJSON
+	}
+
+	return decoder(r.Body, v)
+}
+
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+func decodeMsgpack(r io.Reader, v interface{}) error {
+	return msgpack.NewDecoder(r).UseJSONTag(true).Decode(v)
+}
+
+func encodeMsgpack(w io.Writer, payload interface{}) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).Encode(payload)
+}
+
+func decodeProtobuf(r io.Reader, v interface{}) error {
+	msg, ok := v.(proto.Message)
+
+	if !ok {
+		return fmt.Errorf("apiutil: %T is not a proto.Message", v)
+	}
+
+	b, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(b, msg)
+}
+
+func encodeProtobuf(w io.Writer, payload interface{}) error {
+	msg, ok := payload.(proto.Message)
+
+	if !ok {
+		return fmt.Errorf("apiutil: %T is not a proto.Message", payload)
+	}
+
+	b, err := proto.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,75 @@
+package apiutil
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+func TestDecodeBody(t *testing.T) {
+	type form struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	var buf bytes.Buffer
+
+	if err := msgpack.NewEncoder(&buf).UseJSONTag(true).Encode(form{Name: "foo", Age: 10}); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"json", "application/json", []byte(`{"name": "foo", "age": 10}`)},
+		{"default", "", []byte(`{"name": "foo", "age": 10}`)},
+		{"msgpack", MsgpackMediaType, buf.Bytes()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+
+			var f form
+
+			if err := DecodeBody(req, &f); err != nil {
+				v.Fatalf("should not have error; got %s", err.Error())
+			}
+
+			if f.Name != "foo" || f.Age != 10 {
+				v.Errorf("got form %+v", f)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", MsgpackMediaType+", application/json;q=0.5")
+	rr := httptest.NewRecorder()
+	Negotiate(rr, req, form{Name: "foo", Age: 10})
+
+	if rr.Header().Get("Content-Type") != MsgpackMediaType {
+		t.Errorf("got content type %s; want %s", rr.Header().Get("Content-Type"), MsgpackMediaType)
+	}
+
+	var f form
+
+	if err := msgpack.NewDecoder(rr.Body).UseJSONTag(true).Decode(&f); err != nil || f.Name != "foo" {
+		t.Errorf("should decode msgpack response; got %+v", f)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", ProtobufMediaType)
+	rr = httptest.NewRecorder()
+	Negotiate(rr, req, f)
+
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("non proto payload should fall back to json; got %s", rr.Header().Get("Content-Type"))
+	}
+}
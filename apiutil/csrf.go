@@ -0,0 +1,61 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/gorilla/csrf"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	missingCSRFTxt = "CSRF protection is not configured"
+)
+
+// CSRFTokenResponse is json sent by CSRFTokenHandler
+type CSRFTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// CSRFTokenHandlerConfig is config struct used for CSRFTokenHandler
+type CSRFTokenHandlerConfig struct {
+	// MissingTokenResponse is config used to respond to user if there is
+	// no csrf token, which happens when handler is not wrapped with
+	// csrf.Protect
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("CSRF protection is not configured")
+	MissingTokenResponse HTTPResponseConfig
+}
+
+// CSRFTokenHandler is endpoint that sends a fresh csrf token, both as
+// the X-CSRF-Token header and as CSRFTokenResponse, so single page apps
+// can refresh their token eg. after login
+// Handler must be wrapped with csrf.Protect
+type CSRFTokenHandler struct {
+	config CSRFTokenHandlerConfig
+}
+
+// NewCSRFTokenHandler returns *CSRFTokenHandler
+func NewCSRFTokenHandler(config CSRFTokenHandlerConfig) *CSRFTokenHandler {
+	setHTTPResponseDefaults(&config.MissingTokenResponse, http.StatusInternalServerError, []byte(missingCSRFTxt))
+
+	return &CSRFTokenHandler{
+		config: config,
+	}
+}
+
+func (c *CSRFTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := csrf.Token(r)
+
+	if token == "" {
+		httputil.Logger.Errorf("csrf token handler: %s", missingCSRFTxt)
+		writeHTTPResponse(w, c.config.MissingTokenResponse)
+		return
+	}
+
+	w.Header().Set("X-CSRF-Token", token)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	SendPayload(w, CSRFTokenResponse{Token: token})
+}
@@ -0,0 +1,41 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/csrf"
+)
+
+func TestCSRFTokenHandler(t *testing.T) {
+	h := NewCSRFTokenHandler(CSRFTokenHandlerConfig{})
+
+	rr := httptest.NewRecorder()
+	csrf.Protect([]byte("01234567890123456789012345678901"), csrf.Secure(false))(h).ServeHTTP(
+		rr,
+		httptest.NewRequest(http.MethodGet, "/csrf", nil),
+	)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	var res CSRFTokenResponse
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if res.Token == "" || res.Token != rr.Header().Get("X-CSRF-Token") {
+		t.Errorf("should send token in body and header; got %s and %s", res.Token, rr.Header().Get("X-CSRF-Token"))
+	}
+
+	// Handler that isn't wrapped with csrf.Protect has no token
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+var (
+	// DBCtxKey is key used to store current database connection
+	// within request context by DBHandler
+	DBCtxKey = MiddlewareKey{KeyName: "db"}
+)
+
+// DBHandler attaches the current database connection of a
+// dbutil#DBProvider to every request so handlers can retrieve it
+// with GetDB
+//
+// Passing the connection from GetDB to dbutil#HasDBError swaps the
+// connection of the provider on failure so subsequent requests use
+// the recovered connection
+type DBHandler struct {
+	provider *dbutil.DBProvider
+}
+
+// NewDBHandler returns *DBHandler
+func NewDBHandler(provider *dbutil.DBProvider) *DBHandler {
+	return &DBHandler{provider: provider}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (d *DBHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), DBCtxKey, d.provider.DB())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetDB returns database connection set by DBHandler, else nil
+func GetDB(r *http.Request) httputil.DBInterfaceV2 {
+	db, _ := r.Context().Value(DBCtxKey).(httputil.DBInterfaceV2)
+	return db
+}
@@ -0,0 +1,157 @@
+package apiutil
+
+import (
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/muxutil"
+	"github.com/TravisS25/httputil/resilutil"
+)
+
+const (
+	degradedTxt = "Service unavailable"
+)
+
+// DegradationConfig is config struct used for Degradation
+type DegradationConfig struct {
+	// FailOpenRoutes are path templates of low risk routes, eg. public
+	// reads, that continue without user, groups or url checks while
+	// dependencies of middleware are failing
+	// Every other route fails closed with Response
+	FailOpenRoutes map[string]bool
+
+	// PathRegex returns path template of request matched against
+	// FailOpenRoutes
+	// Default is muxutil#PathRegex
+	PathRegex httputil.PathRegex
+
+	// Response is config used to respond to user if route fails closed
+	//
+	// Default status value is http.StatusServiceUnavailable
+	// Default response value is []byte("Service unavailable")
+	Response HTTPResponseConfig
+
+	// BreakerConfig is config of breaker of database queries of
+	// middleware so queries fail fast while database keeps failing
+	// Default name is "middleware-db" and neither sql.ErrNoRows nor
+	// cookie errors count as failure
+	BreakerConfig resilutil.BreakerConfig
+
+	// OnDegrade, if set, is called every time a request is served
+	// degraded by handler eg. "group", with whether it failed open,
+	// eg. to record metrics
+	OnDegrade func(handler string, r *http.Request, failOpen bool)
+}
+
+// DegradationStats are counts of requests served degraded
+type DegradationStats struct {
+	// Degraded is whether breaker of database queries isn't closed
+	Degraded bool `json:"degraded"`
+
+	FailedOpen   int64 `json:"failedOpen"`
+	FailedClosed int64 `json:"failedClosed"`
+}
+
+// Degradation is policy of AuthHandler, GroupHandler and
+// RoutingHandler for when both their cache, if any, and database are
+// failing, where instead of responding with server error, requests
+// either continue as if user had no session, groups or urls (fail
+// open) or are rejected (fail closed) by route
+//
+// The same *Degradation should be shared by handlers so they share
+// the status of database
+type Degradation struct {
+	// Counts are first so they're aligned for atomic on 32 bit platforms
+	failedOpen   int64
+	failedClosed int64
+
+	config  DegradationConfig
+	breaker *resilutil.Breaker
+}
+
+// NewDegradation returns *Degradation
+func NewDegradation(config DegradationConfig) *Degradation {
+	if config.PathRegex == nil {
+		config.PathRegex = muxutil.PathRegex
+	}
+	if config.BreakerConfig.Name == "" {
+		config.BreakerConfig.Name = "middleware-db"
+	}
+	if config.BreakerConfig.IsFailure == nil {
+		config.BreakerConfig.IsFailure = func(err error) bool {
+			if _, ok := err.(securecookie.Error); ok {
+				return false
+			}
+
+			return err != nil && err != sql.ErrNoRows
+		}
+	}
+
+	setHTTPResponseDefaults(&config.Response, http.StatusServiceUnavailable, []byte(degradedTxt))
+
+	return &Degradation{
+		config:  config,
+		breaker: resilutil.NewBreaker(config.BreakerConfig),
+	}
+}
+
+// Degraded returns whether database queries of middleware keep
+// failing, which can be used as status flag eg. of health checks
+func (d *Degradation) Degraded() bool {
+	return d.breaker.State() != resilutil.StateClosed
+}
+
+// Stats returns counts of requests served degraded
+func (d *Degradation) Stats() DegradationStats {
+	return DegradationStats{
+		Degraded:     d.Degraded(),
+		FailedOpen:   atomic.LoadInt64(&d.failedOpen),
+		FailedClosed: atomic.LoadInt64(&d.failedClosed),
+	}
+}
+
+// do calls query through breaker
+// If d is nil, query is called directly
+func (d *Degradation) do(query func() error) error {
+	if d == nil {
+		return query()
+	}
+
+	return d.breaker.Do(query)
+}
+
+// serve serves request of handler whose dependencies are failing by
+// policy of d, either with next or Response
+// Returns false without serving request if d is nil so handler responds
+// with its server error as before
+func (d *Degradation) serve(handler string, w http.ResponseWriter, r *http.Request, next http.Handler) bool {
+	if d == nil {
+		return false
+	}
+
+	pathExp, err := d.config.PathRegex(r)
+	failOpen := err == nil && d.config.FailOpenRoutes[pathExp]
+
+	if failOpen {
+		atomic.AddInt64(&d.failedOpen, 1)
+	} else {
+		atomic.AddInt64(&d.failedClosed, 1)
+	}
+
+	if d.config.OnDegrade != nil {
+		d.config.OnDegrade(handler, r, failOpen)
+	}
+
+	if failOpen {
+		next.ServeHTTP(w, r)
+		return true
+	}
+
+	w.WriteHeader(*d.config.Response.HTTPStatus)
+	w.Write(d.config.Response.HTTPResponse)
+	return true
+}
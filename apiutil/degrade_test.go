@@ -0,0 +1,81 @@
+package apiutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/resilutil"
+)
+
+func TestDegradation(t *testing.T) {
+	queries := 0
+	degraded := map[bool]int{}
+
+	mockCache := &cachetest.MockCache{
+		MGetFunc: func(keys ...string) ([][]byte, error) {
+			return nil, errors.New(generalErr)
+		},
+		GetFunc: func(key string) ([]byte, error) {
+			return nil, errors.New(generalErr)
+		},
+	}
+	queryDB := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		queries++
+		return nil, errors.New(generalErr)
+	}
+	pathRegex := func(r *http.Request) (string, error) {
+		return r.URL.Path, nil
+	}
+
+	degradation := NewDegradation(DegradationConfig{
+		FailOpenRoutes: map[string]bool{"/public": true},
+		PathRegex:      pathRegex,
+		BreakerConfig:  resilutil.BreakerConfig{FailureThreshold: 2},
+		OnDegrade: func(handler string, r *http.Request, failOpen bool) {
+			degraded[failOpen]++
+		},
+	})
+
+	groupHandler := NewGroupHandler(nil, queryDB, GroupHandlerConfig{CacheStore: mockCache, Degradation: degradation})
+	routingHandler := NewRoutingHandler(nil, queryDB, pathRegex, nil, RoutingHandlerConfig{CacheStore: mockCache, Degradation: degradation})
+	h := groupHandler.MiddlewareFunc(routingHandler.MiddlewareFunc(mockHandler))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("/public"); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if rr := serve("/admin"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf(statusErrTxt, http.StatusServiceUnavailable, rr.Code)
+	}
+	if !degradation.Degraded() {
+		t.Errorf("should be degraded after consecutive failures")
+	}
+
+	// Database isn't queried while breaker is open
+	queries = 0
+
+	if rr := serve("/public"); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if queries != 0 {
+		t.Errorf("should not query database while degraded; got %d queries", queries)
+	}
+
+	stats := degradation.Stats()
+
+	if stats.FailedOpen != 4 || stats.FailedClosed != 1 || degraded[true] != 4 || degraded[false] != 1 {
+		t.Errorf("got stats %+v and hooks %v", stats, degraded)
+	}
+}
@@ -0,0 +1,214 @@
+package apiutil
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// DiagnosticsPath is default DiagnosticsConfig#PathPrefix
+	DiagnosticsPath = "/admin/diagnostics"
+)
+
+// DBStatsProvider is implemented by databases that have connection pool
+// statistics like dbutil#DB
+type DBStatsProvider interface {
+	Stats() sql.DBStats
+}
+
+// DiagnosticsConfig is config struct used for RegisterDiagnostics
+type DiagnosticsConfig struct {
+	// PathPrefix is path every diagnostics endpoint is under
+	// Default value is DiagnosticsPath
+	PathPrefix string
+
+	// Groups are groups, set by GroupHandler, allowed to reach endpoints
+	// Default value is []string{DefaultAdminGroup}
+	Groups []string
+
+	// DB, if set, is database whose pool stats are served at "/db"
+	DB DBStatsProvider
+
+	// Caches, if set, are caches whose stats are served at "/cache"
+	// See CacheStatsHandler
+	Caches map[string]cacheutil.StatsProvider
+
+	// Settings, if set, is settings whose active profile is served
+	// at "/config"
+	Settings *confutil.Settings
+
+	// SchemaDB, if set, is postgres or cockroach database whose schema
+	// is served at "/schema", see dbutil#Inspect
+	SchemaDB httputil.Querier
+
+	// Migrate, if set along with SchemaDB, is config whose SchemaFile
+	// SchemaDB is compared against at "/schema/drift", see
+	// dbutil#DetectDrift
+	Migrate *dbutil.MigrateConfig
+
+	// ForbiddenResponse is config used to respond to user if they're not
+	// within Groups
+	//
+	// Default status value is http.StatusForbidden
+	// Default response value is []byte("Forbidden to access url")
+	ForbiddenResponse HTTPResponseConfig
+}
+
+// RegisterDiagnostics registers diagnostics endpoints on router under
+// DiagnosticsConfig#PathPrefix and returns their subrouter
+//
+//	/pprof/          index of goroutine, heap and other pprof profiles
+//	/db              connection pool stats of DiagnosticsConfig#DB
+//	/cache           stats of DiagnosticsConfig#Caches
+//	/config          active profile of DiagnosticsConfig#Settings
+//	/failovers       recent failover events, see dbutil#RecentFailovers
+//	/deprecations    calls of deprecated functions, see httputil#DeprecationUsages
+//	/schema          tables, columns and indexes of DiagnosticsConfig#SchemaDB
+//	/schema/drift    changes of DiagnosticsConfig#SchemaDB made outside of migrations
+//
+// Endpoints are only reachable by users within DiagnosticsConfig#Groups
+// and, if routing is not nil, go through RoutingHandler#MiddlewareFunc
+// so the paths must also be allowed for the user like any other url
+// GroupHandler should come before this on router
+func RegisterDiagnostics(router *mux.Router, routing *RoutingHandler, config DiagnosticsConfig) *mux.Router {
+	if config.PathPrefix == "" {
+		config.PathPrefix = DiagnosticsPath
+	}
+	if len(config.Groups) == 0 {
+		config.Groups = []string{DefaultAdminGroup}
+	}
+
+	setHTTPResponseDefaults(&config.ForbiddenResponse, http.StatusForbidden, []byte(forbiddenURLTxt))
+
+	prefix := strings.TrimRight(config.PathPrefix, "/")
+	sub := router.PathPrefix(prefix).Subrouter()
+
+	if routing != nil {
+		sub.Use(routing.MiddlewareFunc)
+	}
+
+	sub.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasAnyGroup(r, config.Groups) {
+				writeHTTPResponse(w, config.ForbiddenResponse)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	// pprof.Index serves profiles by trimming "/debug/pprof/" from path
+	// so path is rewritten for profiles under prefix
+	sub.PathPrefix("/pprof/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix+"/pprof/")
+
+		switch name {
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		case "":
+			pprof.Index(w, r)
+		default:
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+	})
+
+	sub.HandleFunc("/db", func(w http.ResponseWriter, r *http.Request) {
+		if config.DB == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, config.DB.Stats())
+	}).Methods(http.MethodGet)
+
+	sub.HandleFunc("/cache", CacheStatsHandler(config.Caches)).Methods(http.MethodGet)
+
+	sub.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		profile := ""
+
+		if config.Settings != nil {
+			profile = config.Settings.ActiveProfile
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, map[string]string{"profile": profile})
+	}).Methods(http.MethodGet)
+
+	sub.HandleFunc("/failovers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, dbutil.RecentFailovers())
+	}).Methods(http.MethodGet)
+
+	sub.HandleFunc("/deprecations", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, httputil.DeprecationUsages())
+	}).Methods(http.MethodGet)
+
+	sub.HandleFunc("/schema", func(w http.ResponseWriter, r *http.Request) {
+		if config.SchemaDB == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		schema, err := dbutil.Inspect(config.SchemaDB)
+
+		if HasError(w, err) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, schema)
+	}).Methods(http.MethodGet)
+
+	sub.HandleFunc("/schema/drift", func(w http.ResponseWriter, r *http.Request) {
+		if config.SchemaDB == nil || config.Migrate == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		changes, err := dbutil.DetectDrift(config.SchemaDB, *config.Migrate)
+
+		if HasError(w, err) {
+			return
+		}
+		if changes == nil {
+			changes = []dbutil.SchemaChange{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		SendPayload(w, changes)
+	}).Methods(http.MethodGet)
+
+	return sub
+}
+
+// hasAnyGroup returns whether user of request is within any of groups
+// set by GroupHandler
+func hasAnyGroup(r *http.Request, groups []string) bool {
+	for _, g := range GetGroupNames(r) {
+		for _, v := range groups {
+			if g == v {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package apiutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+type diagnosticsDB struct{}
+
+func (diagnosticsDB) Stats() sql.DBStats {
+	return sql.DBStats{OpenConnections: 3}
+}
+
+func TestRegisterDiagnostics(t *testing.T) {
+	router := mux.NewRouter()
+	RegisterDiagnostics(router, nil, DiagnosticsConfig{
+		DB:       diagnosticsDB{},
+		Settings: &confutil.Settings{ActiveProfile: confutil.ProfileStaging},
+	})
+
+	serve := func(path string, groups map[string]bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, DiagnosticsPath+path, nil)
+
+		if groups != nil {
+			req = req.WithContext(context.WithValue(req.Context(), GroupCtxKey, groups))
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("/db", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("should forbid users without groups; got %d", rr.Code)
+	}
+	if rr := serve("/db", map[string]bool{"Editor": true}); rr.Code != http.StatusForbidden {
+		t.Errorf("should forbid users not within admin group; got %d", rr.Code)
+	}
+
+	admin := map[string]bool{DefaultAdminGroup: true}
+	rr := serve("/db", admin)
+
+	var stats sql.DBStats
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil || stats.OpenConnections != 3 {
+		t.Errorf("should serve db stats; got %s", rr.Body.String())
+	}
+
+	if rr = serve("/config", admin); rr.Body.String() != `{"profile":"staging"}` {
+		t.Errorf("should serve active profile; got %s", rr.Body.String())
+	}
+	if rr = serve("/pprof/goroutine", admin); rr.Code != http.StatusOK {
+		t.Errorf("should serve goroutine profile; got %d", rr.Code)
+	}
+	if rr = serve("/failovers", admin); rr.Code != http.StatusOK {
+		t.Errorf("should serve failover events; got %d", rr.Code)
+	}
+	if rr = serve("/deprecations", admin); rr.Code != http.StatusOK {
+		t.Errorf("should serve deprecation usages; got %d", rr.Code)
+	}
+}
@@ -0,0 +1,130 @@
+package apiutil
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// InvalidFormMessage is message of error written by WriteError for
+	// validation.Errors
+	InvalidFormMessage = "Invalid form"
+
+	// StaleVersionMessage is message of error written by WriteError for
+	// dbutil#StaleVersionError
+	StaleVersionMessage = "Resource was modified by another request, reload and try again"
+
+	// QueryTimeoutMessage is message of error written by WriteError for
+	// dbutil#ErrQueryTimeout
+	QueryTimeoutMessage = "Request took too long to process, try narrowing your query"
+)
+
+// ToError converts err to *httputil.Error which determines the status
+// and body written by WriteError
+//
+// If err is or wraps *httputil.Error, it's returned as is, else:
+//   - validation.Errors are httputil#Invalid with the error of every field
+//   - queryutil filter, sort, group and slice errors are httputil#Invalid
+//   - queryutil#PaginationError is httputil#BadRequest
+//   - sql.ErrNoRows is httputil#NotFound
+//   - dbutil#StaleVersionError is httputil#Conflict
+//   - dbutil#ErrQueryTimeout is 504 with httputil#CodeTimeout
+//   - everything else is 500 with httputil#CodeInternal
+//
+// Returns nil if err is nil
+func ToError(err error) *httputil.Error {
+	if err == nil {
+		return nil
+	}
+	if e := httputil.AsError(err); e != nil {
+		return e
+	}
+
+	cause := errors.Cause(err)
+
+	switch v := cause.(type) {
+	case validation.Errors:
+		fields := make(map[string]string, len(v))
+
+		for k, fieldErr := range v {
+			if fieldErr != nil {
+				fields[k] = fieldErr.Error()
+			}
+		}
+
+		return httputil.Invalid(InvalidFormMessage, fields).Wrap(err)
+	case *queryutil.FilterError, *queryutil.SortError, *queryutil.GroupError, *queryutil.SliceError,
+		*queryutil.FieldsError:
+		return httputil.Invalid(cause.Error(), nil).Wrap(err)
+	case *queryutil.PaginationError, *queryutil.SyntaxError:
+		return httputil.BadRequest(cause.Error()).Wrap(err)
+	case *dbutil.StaleVersionError:
+		return httputil.Conflict(StaleVersionMessage).Wrap(err)
+	}
+
+	switch cause {
+	case sql.ErrNoRows:
+		return httputil.NotFound(notFoundTxt).Wrap(err)
+	case dbutil.ErrQueryTimeout:
+		return &httputil.Error{
+			Code:    httputil.CodeTimeout,
+			Message: QueryTimeoutMessage,
+			Status:  http.StatusGatewayTimeout,
+			Err:     err,
+		}
+	}
+
+	return &httputil.Error{
+		Code:    httputil.CodeInternal,
+		Message: ErrServerMessage.Error(),
+		Status:  http.StatusInternalServerError,
+		Err:     err,
+	}
+}
+
+// WriteError writes err as json, eg. {"code": "not_found", "message":
+// "Not found"}, with status based on ToError
+// Server errors are logged and sent to httputil#Reporter and their
+// underlying error is never sent to clients
+// Does nothing if err is nil
+func WriteError(w http.ResponseWriter, err error) {
+	e := ToError(err)
+
+	if e == nil {
+		return
+	}
+
+	status := e.Status
+
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		CheckError(err, "Server Err:")
+		httputil.CaptureError(context.Background(), err, map[string]string{"source": "server_error"})
+	}
+
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.WriteHeader(status)
+	SendPayload(w, e)
+}
+
+// HasError is wrapper for WriteError that returns if err is not nil
+// This is the single error pipeline that should be used in place of
+// the HasXxxError helpers
+func HasError(w http.ResponseWriter, err error) bool {
+	if err != nil {
+		WriteError(w, err)
+		return true
+	}
+
+	return false
+}
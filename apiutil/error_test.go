@@ -0,0 +1,73 @@
+package apiutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	pkgerrors "github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		fields map[string]string
+	}{
+		{"domain", httputil.Unauthorized("Login required"), http.StatusUnauthorized, httputil.CodeUnauthorized, nil},
+		{"wrappedDomain", pkgerrors.Wrap(httputil.Conflict("Email taken").Wrap(sql.ErrNoRows), "create user"), http.StatusConflict, httputil.CodeConflict, nil},
+		{"validation", validation.Errors{"email": errors.New("required")}, http.StatusNotAcceptable, httputil.CodeInvalid, map[string]string{"email": "required"}},
+		{"noRows", pkgerrors.Wrap(sql.ErrNoRows, ""), http.StatusNotFound, httputil.CodeNotFound, nil},
+		{"pagination", pkgerrors.WithStack(&queryutil.PaginationError{Param: "skip", Value: "-5", Reason: queryutil.ErrPaginationNegative}), http.StatusBadRequest, httputil.CodeBadRequest, nil},
+		{"staleVersion", &dbutil.StaleVersionError{Table: "user", ID: 1}, http.StatusConflict, httputil.CodeConflict, nil},
+		{"timeout", dbutil.ErrQueryTimeout, http.StatusGatewayTimeout, httputil.CodeTimeout, nil},
+		{"internal", errors.New("connection refused"), http.StatusInternalServerError, httputil.CodeInternal, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body httputil.Error
+
+			rr := httptest.NewRecorder()
+
+			if !HasError(rr, test.err) {
+				t.Fatalf("should return true")
+			}
+			if rr.Code != test.status {
+				t.Errorf("got status %d; want %d", rr.Code, test.status)
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("should not return error; got %s", err.Error())
+			}
+			if body.Code != test.code {
+				t.Errorf("got code %s; want %s", body.Code, test.code)
+			}
+			if fmt.Sprint(body.Fields) != fmt.Sprint(test.fields) {
+				t.Errorf("got fields %v; want %v", body.Fields, test.fields)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+
+	if HasError(rr, nil) || rr.Body.Len() != 0 {
+		t.Errorf("should not write nil error")
+	}
+
+	WriteError(rr, errors.New("password=secret"))
+
+	if rr.Body.String() != `{"code":"internal","message":"`+ErrServerMessage.Error()+`"}` {
+		t.Errorf("should not expose underlying error; got %s", rr.Body.String())
+	}
+}
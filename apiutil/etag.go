@@ -0,0 +1,79 @@
+package apiutil
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// VersionETag returns weak etag of r built from the versions of the
+// tables of setups, see cacheutil#TableVersions, along with the query
+// and user of r so filtered and user scoped lists get their own etags
+func VersionETag(r *http.Request, cache cacheutil.CacheStore, setups ...cacheutil.CacheSetup) (string, error) {
+	versions, err := cacheutil.TableVersions(cache, setups...)
+
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(strings.Join(versions, ",")))
+	hash.Write([]byte("|" + r.URL.Path + "?" + r.URL.RawQuery))
+	hash.Write([]byte("|" + GetUserID(r)))
+
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
+
+// HasNotModified sets ETag header to VersionETag of r and, if it
+// matches If-None-Match header of r, writes 304 and returns true
+// Else return false
+//
+// This lets clients poll list endpoints cheaply as nothing is queried
+// until a table of setups is written to, which must bump its version
+// with cacheutil#BumpVersions
+// If versions can't be retrieved from cache, the error is logged and
+// false is returned so the request is served as normal
+func HasNotModified(w http.ResponseWriter, r *http.Request, cache cacheutil.CacheStore, setups ...cacheutil.CacheSetup) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	etag, err := VersionETag(r, cache, setups...)
+
+	if err != nil {
+		httputil.Logger.Errorf("apiutil: etag err: %s", err.Error())
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares header with etag using weak comparison
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
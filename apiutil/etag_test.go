@@ -0,0 +1,47 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestHasNotModified(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	users := cacheutil.CacheSetup{StringVal: "users"}
+
+	serve := func(url, ifNoneMatch string) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		rr := httptest.NewRecorder()
+		notModified := HasNotModified(rr, req, cache, users)
+		return rr, notModified
+	}
+
+	rr, notModified := serve("/users?take=10", "")
+	etag := rr.Header().Get("ETag")
+
+	if notModified || etag == "" {
+		t.Fatalf("should set etag without 304; got %v and %s", notModified, etag)
+	}
+
+	if rr, notModified = serve("/users?take=10", etag); !notModified || rr.Code != http.StatusNotModified {
+		t.Errorf(statusErrTxt, http.StatusNotModified, rr.Code)
+	}
+	if _, notModified = serve("/users?take=20", etag); notModified {
+		t.Errorf("should not match etag of other query")
+	}
+
+	cacheutil.BumpVersions(cache, users)
+
+	if rr, notModified = serve("/users?take=10", etag); notModified || rr.Header().Get("ETag") == etag {
+		t.Errorf("should change etag once version is bumped")
+	}
+}
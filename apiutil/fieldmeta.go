@@ -0,0 +1,110 @@
+package apiutil
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+// FieldMeta describes field of list endpoint to clients
+type FieldMeta struct {
+	Name       string   `json:"name"`
+	Label      string   `json:"label,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	Filterable bool     `json:"filterable"`
+	Sortable   bool     `json:"sortable"`
+	Groupable  bool     `json:"groupable"`
+	Selectable bool     `json:"selectable"`
+	Operators  []string `json:"operators,omitempty"`
+}
+
+// FieldMetaParams are names of query params of list endpoint
+type FieldMetaParams struct {
+	Filter string `json:"filter"`
+	Sort   string `json:"sort"`
+	Take   string `json:"take"`
+	Skip   string `json:"skip"`
+	Group  string `json:"group"`
+	Fields string `json:"fields"`
+}
+
+// FieldMetaResponse is json sent by FieldMetaHandler
+type FieldMetaResponse struct {
+	Fields []FieldMeta     `json:"fields"`
+	Params FieldMetaParams `json:"params"`
+}
+
+// FieldMetaConfig is config struct used for FieldMetaHandler
+type FieldMetaConfig struct {
+	// Resource, if set, will determine the fields and params
+	Resource *Resource
+
+	// Fields and ParamConf are described if Resource is not set
+	Fields    map[string]queryutil.FieldConfig
+	ParamConf queryutil.ParamConfig
+}
+
+// NewFieldMetaResponse returns description of fields and params of
+// config where fields are sorted by name
+func NewFieldMetaResponse(config FieldMetaConfig) FieldMetaResponse {
+	fields := config.Fields
+	paramConf := config.ParamConf
+
+	if config.Resource != nil {
+		fields = config.Resource.config.Fields
+		paramConf = config.Resource.config.ParamConf
+	}
+
+	paramName := func(name *string, defaultName string) string {
+		if name != nil {
+			return *name
+		}
+
+		return defaultName
+	}
+
+	res := FieldMetaResponse{
+		Fields: make([]FieldMeta, 0, len(fields)),
+		Params: FieldMetaParams{
+			Filter: paramName(paramConf.Filter, "filters"),
+			Sort:   paramName(paramConf.Sort, "sorts"),
+			Take:   paramName(paramConf.Take, "take"),
+			Skip:   paramName(paramConf.Skip, "skip"),
+			Group:  paramName(paramConf.Group, "groups"),
+			Fields: paramName(paramConf.Fields, "fields"),
+		},
+	}
+
+	for k, v := range fields {
+		operators := v.Operators()
+
+		res.Fields = append(res.Fields, FieldMeta{
+			Name:       k,
+			Label:      v.Label,
+			Type:       v.Type,
+			Filterable: len(operators) > 0,
+			Sortable:   v.OperationConf.CanSortBy,
+			Groupable:  v.OperationConf.CanGroupBy && !v.Window,
+			Selectable: v.OperationConf.CanSelect,
+			Operators:  operators,
+		})
+	}
+
+	sort.Slice(res.Fields, func(i, j int) bool {
+		return res.Fields[i].Name < res.Fields[j].Name
+	})
+
+	return res
+}
+
+// FieldMetaHandler returns handler that writes FieldMetaResponse of
+// config so frontends can render filter builders and grids from the
+// same whitelist the server enforces
+func FieldMetaHandler(config FieldMetaConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+		SendPayload(w, NewFieldMetaResponse(config))
+	}
+}
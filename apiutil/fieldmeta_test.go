@@ -0,0 +1,64 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+func TestFieldMetaHandler(t *testing.T) {
+	filterParam := "q"
+	handler := FieldMetaHandler(FieldMetaConfig{
+		Fields: map[string]queryutil.FieldConfig{
+			"name": {
+				DBField:       "user.name",
+				Label:         "Name",
+				Type:          queryutil.FieldTypeString,
+				OperationConf: queryutil.OperationConfig{CanFilterBy: true, CanSortBy: true, CanSelect: true},
+			},
+			"active": {
+				DBField:       "user.active",
+				Type:          queryutil.FieldTypeBoolean,
+				OperationConf: queryutil.OperationConfig{CanFilterBy: true, CanGroupBy: true},
+			},
+			"rank": {
+				DBField:       "row_number() over (order by user.score desc)",
+				Window:        true,
+				OperationConf: queryutil.OperationConfig{CanFilterBy: true, CanSortBy: true, CanGroupBy: true},
+			},
+		},
+		ParamConf: queryutil.ParamConfig{Filter: &filterParam},
+	})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/users/meta", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	var res FieldMetaResponse
+
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatalf("should decode response; got %s", err.Error())
+	}
+
+	expected := []FieldMeta{
+		{Name: "active", Type: "boolean", Filterable: true, Groupable: true, Operators: []string{"eq", "isnotnull", "isnull", "neq"}},
+		{Name: "name", Label: "Name", Type: "string", Filterable: true, Sortable: true, Selectable: true, Operators: []string{
+			"contains", "doesnotcontain", "endswith", "eq", "isempty", "isnotempty", "isnotnull", "isnull", "neq", "startswith",
+		}},
+		{Name: "rank", Sortable: true},
+	}
+
+	if !reflect.DeepEqual(res.Fields, expected) {
+		t.Errorf("unexpected fields; got %+v", res.Fields)
+	}
+	if res.Params.Filter != "q" || res.Params.Take != "take" || res.Params.Fields != "fields" {
+		t.Errorf("unexpected params; got %+v", res.Params)
+	}
+}
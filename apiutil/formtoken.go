@@ -0,0 +1,232 @@
+package apiutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+const (
+	// FormTokenHeader is request header SPAs send form token in
+	FormTokenHeader = "X-Form-Token"
+
+	// FormTokenField is form field server rendered forms send form
+	// token in, see FormTokenHandler#TemplateField
+	FormTokenField = "form_token"
+
+	// FormTokenKey is used as a key when storing issued form tokens
+	// in cache, where first %s is user and second is token
+	FormTokenKey = "form-token-%s-%s"
+
+	// FormTokenUsedKey is used as a key when marking form tokens as
+	// submitted in cache, where first %s is user and second is token
+	FormTokenUsedKey = "form-token-used-%s-%s"
+
+	formTokenInvalidTxt = "Form token is missing or expired"
+	formTokenReplayTxt  = "Form was already submitted"
+)
+
+// FormTokenResponse is json sent by FormTokenHandler
+type FormTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// FormTokenConfig is config struct used for FormTokenHandler
+type FormTokenConfig struct {
+	// TTL is how long issued tokens can be submitted for
+	// Default value is 1 hour
+	TTL time.Duration
+
+	// Methods are http methods that require a form token
+	// Default value is POST
+	Methods []string
+
+	// InvalidResponse is config used to respond to user if token is
+	// missing, expired or wasn't issued to user
+	//
+	// Default status value is http.StatusBadRequest
+	// Default response value is []byte("Form token is missing or expired")
+	InvalidResponse HTTPResponseConfig
+
+	// ReplayResponse is config used to respond to user if token was
+	// already submitted eg. on double click of submit button
+	//
+	// Default status value is http.StatusConflict
+	// Default response value is []byte("Form was already submitted")
+	ReplayResponse HTTPResponseConfig
+}
+
+// FormTokenHandler protects form posts from duplicate submits by
+// issuing one time tokens that are rejected once submitted, which
+// unlike IdempotencyHandler doesn't need clients to generate keys
+//
+// Tokens are issued with Issue, eg. through TemplateField for forms
+// within csrf protected pages, or with ServeHTTP for SPAs, and are
+// scoped to the user if AuthHandler comes before this middleware
+// MiddlewareFunc then requires a token in the X-Form-Token header or
+// form_token form field and lets only its first submit through
+// Tokens of submits that respond with a server error can be submitted
+// again
+type FormTokenHandler struct {
+	cacheStore cacheutil.CacheStore
+	config     FormTokenConfig
+}
+
+// NewFormTokenHandler returns *FormTokenHandler
+func NewFormTokenHandler(cacheStore cacheutil.CacheStore, config FormTokenConfig) *FormTokenHandler {
+	if config.TTL <= 0 {
+		config.TTL = time.Hour
+	}
+	if config.Methods == nil {
+		config.Methods = []string{http.MethodPost}
+	}
+
+	setHTTPResponseDefaults(&config.InvalidResponse, http.StatusBadRequest, []byte(formTokenInvalidTxt))
+	setHTTPResponseDefaults(&config.ReplayResponse, http.StatusConflict, []byte(formTokenReplayTxt))
+
+	return &FormTokenHandler{
+		cacheStore: cacheStore,
+		config:     config,
+	}
+}
+
+// Issue issues new form token for user of r
+func (f *FormTokenHandler) Issue(r *http.Request) (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(b)
+
+	if err := f.cacheStore.SetErr(fmt.Sprintf(FormTokenKey, idempotencyUser(r), token), "1", f.config.TTL); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// TemplateField issues new form token for user of r and returns it
+// as hidden input to be embedded within form
+func (f *FormTokenHandler) TemplateField(r *http.Request) (template.HTML, error) {
+	token, err := f.Issue(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(fmt.Sprintf(
+		`<input type="hidden" name="%s" value="%s">`,
+		FormTokenField,
+		template.HTMLEscapeString(token),
+	)), nil
+}
+
+// ServeHTTP is endpoint that issues new form token, both as the
+// X-Form-Token header and as FormTokenResponse, for SPAs
+func (f *FormTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, err := f.Issue(r)
+
+	if HasServerError(w, err, "") {
+		return
+	}
+
+	w.Header().Set(FormTokenHeader, token)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	SendPayload(w, FormTokenResponse{Token: token})
+}
+
+func (f *FormTokenHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !f.hasMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(FormTokenHeader)
+
+		if token == "" {
+			token = r.PostFormValue(FormTokenField)
+		}
+
+		user := idempotencyUser(r)
+		issued := false
+
+		if token != "" {
+			var err error
+
+			// Cache returns ErrCacheNil for tokens that were never issued
+			if issued, err = f.cacheStore.HasKey(fmt.Sprintf(FormTokenKey, user, token)); err == cacheutil.ErrCacheNil {
+				issued = false
+			} else if HasServerError(w, err, "") {
+				return
+			}
+		}
+
+		if !issued {
+			writeHTTPResponse(w, f.config.InvalidResponse)
+			return
+		}
+
+		// SetNX is used so only the first of concurrent submits of the
+		// same token gets through
+		usedKey := fmt.Sprintf(FormTokenUsedKey, user, token)
+		set, err := f.cacheStore.SetNX(usedKey, "1", f.config.TTL)
+
+		if HasServerError(w, err, "") {
+			return
+		}
+
+		if !set {
+			writeHTTPResponse(w, f.config.ReplayResponse)
+			return
+		}
+
+		rec := &formTokenRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			f.cacheStore.Del(usedKey)
+		}
+	})
+}
+
+func (f *FormTokenHandler) hasMethod(method string) bool {
+	for _, m := range f.config.Methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formTokenRecorder records status of response while still writing
+// it to the client
+type formTokenRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *formTokenRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *formTokenRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,113 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestFormTokenHandler(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+	formToken := NewFormTokenHandler(cachetest.NewMemoryCache(), FormTokenConfig{})
+	handler := formToken.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	rr := httptest.NewRecorder()
+	formToken.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/form-token", nil))
+
+	var res FormTokenResponse
+
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil || res.Token == "" {
+		t.Fatalf("should issue token; got %v", err)
+	}
+	if rr.Header().Get(FormTokenHeader) != res.Token {
+		t.Errorf("should set token header")
+	}
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/item", nil)
+
+		if token != "" {
+			req.Header.Set(FormTokenHeader, token)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send(res.Token); code != http.StatusCreated {
+		t.Errorf(statusErrTxt, http.StatusCreated, code)
+	}
+	if code := send(res.Token); code != http.StatusConflict {
+		t.Errorf(statusErrTxt, http.StatusConflict, code)
+	}
+	if code := send(""); code != http.StatusBadRequest {
+		t.Errorf(statusErrTxt, http.StatusBadRequest, code)
+	}
+	if code := send("unknown"); code != http.StatusBadRequest {
+		t.Errorf(statusErrTxt, http.StatusBadRequest, code)
+	}
+	if calls != 1 {
+		t.Errorf("should only let first submit through; got %d calls", calls)
+	}
+
+	field, err := formToken.TemplateField(httptest.NewRequest(http.MethodGet, "/form", nil))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	token := strings.TrimSuffix(strings.TrimPrefix(string(field), `<input type="hidden" name="form_token" value="`), `">`)
+	status = http.StatusInternalServerError
+
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/item", strings.NewReader(url.Values{FormTokenField: {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := post(); code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, code)
+	}
+
+	status = http.StatusCreated
+
+	if code := post(); code != http.StatusCreated {
+		t.Errorf("should allow resubmit after server error; "+statusErrTxt, http.StatusCreated, code)
+	}
+	if code := post(); code != http.StatusConflict {
+		t.Errorf(statusErrTxt, http.StatusConflict, code)
+	}
+}
+
+func TestFormTokenHandlerCacheErrors(t *testing.T) {
+	mockCache := &cachetest.MockCache{
+		HasKeyFunc: func(key string) (bool, error) {
+			return false, errors.New("connection refused")
+		},
+	}
+	handler := NewFormTokenHandler(mockCache, FormTokenConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/item", nil)
+	req.Header.Set(FormTokenHeader, "abc")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// HealthPath is default path HealthHandler should be registered to
+	HealthPath = "/health"
+
+	healthOK   = "ok"
+	healthDown = "down"
+)
+
+// HealthConfig is config struct used for HealthHandler
+type HealthConfig struct {
+	// Pingers are stores checked by HealthHandler keyed by name
+	// eg. "db", "cache" and "sessions"
+	// See cacheutil#CachePinger and cacheutil#SessionPinger to check
+	// caches and session stores that don't implement httputil#Pinger
+	Pingers map[string]httputil.Pinger
+
+	// Timeout is how long every store has to respond to ping before
+	// it's considered down
+	// Default is 5 seconds
+	Timeout time.Duration
+
+	// OnDown, if set, is called with error of every store that is
+	// down eg. to log or alert
+	OnDown func(name string, err error)
+}
+
+// HealthStatus is status of a store checked by HealthHandler
+type HealthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthPayload is payload written by HealthHandler
+type HealthPayload struct {
+	// Status is "ok" if every store is "ok", else "down"
+	Status string                  `json:"status"`
+	Checks map[string]HealthStatus `json:"checks"`
+}
+
+// HealthHandler returns handler that pings every store of
+// HealthConfig#Pingers concurrently and writes HealthPayload with
+// http.StatusOK if every store responded, else
+// http.StatusServiceUnavailable
+func HealthHandler(config HealthConfig) http.HandlerFunc {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second * 5
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), config.Timeout)
+		defer cancel()
+
+		payload := HealthPayload{
+			Status: healthOK,
+			Checks: make(map[string]HealthStatus, len(config.Pingers)),
+		}
+
+		for name, err := range httputil.PingAll(ctx, config.Pingers) {
+			if err == nil {
+				payload.Checks[name] = HealthStatus{Status: healthOK}
+				continue
+			}
+
+			if config.OnDown != nil {
+				config.OnDown(name, err)
+			}
+
+			payload.Status = healthDown
+			payload.Checks[name] = HealthStatus{Status: healthDown, Error: err.Error()}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if payload.Status != healthOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		SendPayload(w, payload)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestHealthHandler(t *testing.T) {
+	var down []string
+
+	pingers := map[string]httputil.Pinger{
+		"cache":    cacheutil.CachePinger(cachetest.NewMemoryCache()),
+		"sessions": cacheutil.SessionPinger(cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))),
+	}
+	h := HealthHandler(HealthConfig{
+		Pingers: pingers,
+		Timeout: time.Millisecond * 50,
+		OnDown: func(name string, err error) {
+			down = append(down, name)
+		},
+	})
+
+	check := func(status int) HealthPayload {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+
+		if rr.Code != status {
+			t.Errorf(statusErrTxt, status, rr.Code)
+		}
+
+		var payload HealthPayload
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		return payload
+	}
+
+	if payload := check(http.StatusOK); payload.Status != healthOK || len(payload.Checks) != 2 {
+		t.Errorf("every store should be ok; got %+v", payload)
+	}
+
+	pingers["db"] = httputil.PingerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	pingers["storage"] = httputil.PingerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	payload := check(http.StatusServiceUnavailable)
+
+	if payload.Status != healthDown || payload.Checks["cache"].Status != healthOK {
+		t.Errorf("should be down with cache ok; got %+v", payload)
+	}
+	if payload.Checks["db"].Error != "connection refused" {
+		t.Errorf("should return error of db; got %+v", payload.Checks["db"])
+	}
+	if payload.Checks["storage"].Status != healthDown {
+		t.Errorf("storage should time out; got %+v", payload.Checks["storage"])
+	}
+	if len(down) != 2 {
+		t.Errorf("should call OnDown for every store down; got %v", down)
+	}
+}
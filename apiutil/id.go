@@ -0,0 +1,52 @@
+package apiutil
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// IDResponse is json object of single id, which is encoded as string
+// eg. {"id": "1"} so ids such as cockroach ids don't lose precision as
+// javascript numbers
+type IDResponse struct {
+	ID int64 `json:"id,string"`
+}
+
+// IDStrings converts ids to their string encoding
+func IDStrings(ids []int64) []string {
+	vals := make([]string, 0, len(ids))
+
+	for _, v := range ids {
+		vals = append(vals, strconv.FormatInt(v, 10))
+	}
+
+	return vals
+}
+
+// SendID sends id as json object eg. {"id": "1"}
+func SendID(w http.ResponseWriter, id int64) {
+	SendPayload(w, IDResponse{ID: id})
+}
+
+// SendIDs sends ids as json array of objects eg. [{"id": "1"}, {"id": "2"}]
+func SendIDs(w http.ResponseWriter, ids []int64) {
+	payload := make([]IDResponse, 0, len(ids))
+
+	for _, v := range ids {
+		payload = append(payload, IDResponse{ID: v})
+	}
+
+	SendPayload(w, payload)
+}
+
+// SendIDMap sends ids as json object with string values
+// eg. {"customer": "1"}
+func SendIDMap(w http.ResponseWriter, ids map[string]int64) {
+	payload := make(map[string]string, len(ids))
+
+	for k, v := range ids {
+		payload[k] = strconv.FormatInt(v, 10)
+	}
+
+	SendPayload(w, payload)
+}
@@ -0,0 +1,39 @@
+package apiutil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     func(rr *httptest.ResponseRecorder)
+		expected string
+	}{
+		{
+			name:     "id",
+			send:     func(rr *httptest.ResponseRecorder) { SendID(rr, 9007199254740993) },
+			expected: `{"id":"9007199254740993"}`,
+		},
+		{
+			name:     "ids",
+			send:     func(rr *httptest.ResponseRecorder) { SendIDs(rr, []int64{1, 2}) },
+			expected: `[{"id":"1"},{"id":"2"}]`,
+		},
+		{
+			name:     "id map",
+			send:     func(rr *httptest.ResponseRecorder) { SendIDMap(rr, map[string]int64{"customer": 1}) },
+			expected: `{"customer":"1"}`,
+		},
+	}
+
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		test.send(rr)
+
+		if rr.Body.String() != test.expected {
+			t.Errorf("%s: should send %s; got %s\n", test.name, test.expected, rr.Body.String())
+		}
+	}
+}
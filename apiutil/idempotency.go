@@ -0,0 +1,247 @@
+package apiutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+)
+
+const (
+	// IdempotencyKeyHeader is request header clients set to make
+	// retries of unsafe requests safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on responses that were replayed
+	// from cache
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// IdempotencyKey is used as a key when storing responses in cache
+	IdempotencyKey = "idempotency-%s-%s-%s-%s"
+
+	idempotencyInProgressTxt = "Request with same idempotency key is in progress"
+	idempotencyMismatchTxt   = "Idempotency key was used with a different request body"
+)
+
+// IdempotencyHandlerConfig is config struct used for IdempotencyHandler
+type IdempotencyHandlerConfig struct {
+	// TTL is how long responses are stored for
+	// Default value is 24 hours
+	TTL time.Duration
+
+	// Methods are http methods the Idempotency-Key header is honored for
+	// Default value is POST and PUT
+	Methods []string
+
+	// ConflictResponse is config used to respond to user if a request
+	// with the same key is still being processed
+	//
+	// Default status value is http.StatusConflict
+	// Default response value is []byte("Request with same idempotency key is in progress")
+	ConflictResponse HTTPResponseConfig
+
+	// MismatchResponse is config used to respond to user if the key
+	// was already used with a different request body
+	//
+	// Default status value is http.StatusUnprocessableEntity
+	// Default response value is []byte("Idempotency key was used with a different request body")
+	MismatchResponse HTTPResponseConfig
+}
+
+// idempotentResponse is stored in cache for every request with an
+// Idempotency-Key header
+type idempotentResponse struct {
+	InProgress  bool        `json:"in_progress"`
+	RequestHash string      `json:"request_hash"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// IdempotencyHandler stores the first response of requests with an
+// Idempotency-Key header and replays it for retries with the same key,
+// preventing things like duplicate inserts when clients retry after
+// network failures
+//
+// Keys are scoped to the user, if AuthHandler comes before this
+// middleware, along with the method and path of the request
+// Server errors are not stored so the request can be retried
+type IdempotencyHandler struct {
+	cacheStore cacheutil.CacheStore
+	config     IdempotencyHandlerConfig
+}
+
+// NewIdempotencyHandler returns *IdempotencyHandler
+func NewIdempotencyHandler(cacheStore cacheutil.CacheStore, config IdempotencyHandlerConfig) *IdempotencyHandler {
+	if config.TTL <= 0 {
+		config.TTL = time.Hour * 24
+	}
+	if config.Methods == nil {
+		config.Methods = []string{http.MethodPost, http.MethodPut}
+	}
+
+	setHTTPResponseDefaults(&config.ConflictResponse, http.StatusConflict, []byte(idempotencyInProgressTxt))
+	setHTTPResponseDefaults(&config.MismatchResponse, http.StatusUnprocessableEntity, []byte(idempotencyMismatchTxt))
+
+	return &IdempotencyHandler{
+		cacheStore: cacheStore,
+		config:     config,
+	}
+}
+
+func (i *IdempotencyHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+
+		if idempotencyKey == "" || !i.hasMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var body []byte
+		var err error
+
+		if r.Body != nil {
+			if body, err = ioutil.ReadAll(r.Body); HasServerError(w, err, "") {
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		}
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+		key := fmt.Sprintf(IdempotencyKey, idempotencyUser(r), r.Method, r.URL.Path, idempotencyKey)
+
+		if cached, err := i.cacheStore.Get(key); err == nil {
+			var stored idempotentResponse
+
+			if err = json.Unmarshal(cached, &stored); err == nil {
+				switch {
+				case stored.RequestHash != requestHash:
+					w.WriteHeader(*i.config.MismatchResponse.HTTPStatus)
+					w.Write(i.config.MismatchResponse.HTTPResponse)
+				case stored.InProgress:
+					w.WriteHeader(*i.config.ConflictResponse.HTTPStatus)
+					w.Write(i.config.ConflictResponse.HTTPResponse)
+				default:
+					for k, v := range stored.Header {
+						w.Header()[k] = v
+					}
+
+					w.Header().Set(IdempotentReplayedHeader, "true")
+					w.WriteHeader(stored.Status)
+					w.Write(stored.Body)
+				}
+
+				return
+			}
+		}
+
+		// SetNX is used so only one of concurrent requests with the same
+		// key is processed
+		payload, err := json.Marshal(idempotentResponse{InProgress: true, RequestHash: requestHash})
+
+		if HasServerError(w, err, "") {
+			return
+		}
+
+		set, err := i.cacheStore.SetNX(key, payload, i.config.TTL)
+
+		if HasServerError(w, err, "") {
+			return
+		}
+
+		if !set {
+			w.WriteHeader(*i.config.ConflictResponse.HTTPStatus)
+			w.Write(i.config.ConflictResponse.HTTPResponse)
+			return
+		}
+
+		// Key is removed if next panics so retries don't conflict until
+		// TTL expires, then the panic is passed on to RecoveryHandler
+		defer func() {
+			if rv := recover(); rv != nil {
+				i.cacheStore.Del(key)
+				panic(rv)
+			}
+		}()
+
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			i.cacheStore.Del(key)
+			return
+		}
+
+		err = i.store(key, idempotentResponse{
+			RequestHash: requestHash,
+			Status:      rec.status,
+			Header:      w.Header(),
+			Body:        rec.body.Bytes(),
+		})
+
+		// Response is already written so remove in progress flag
+		// allowing retries instead of conflicting until TTL expires
+		if err != nil {
+			confutil.CheckError(err, "")
+			i.cacheStore.Del(key)
+		}
+	})
+}
+
+func (i *IdempotencyHandler) store(key string, res idempotentResponse) error {
+	payload, err := json.Marshal(res)
+
+	if err != nil {
+		return err
+	}
+
+	return i.cacheStore.SetErr(key, payload, i.config.TTL)
+}
+
+func (i *IdempotencyHandler) hasMethod(method string) bool {
+	for _, v := range i.config.Methods {
+		if v == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+func idempotencyUser(r *http.Request) string {
+	return GetUserID(r)
+}
+
+// idempotencyRecorder records status and body of response while
+// still writing it to the client
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
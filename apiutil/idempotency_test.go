@@ -0,0 +1,152 @@
+package apiutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestIdempotencyHandler(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyHandler(cachetest.NewMemoryCache(), IdempotencyHandlerConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Location", "/api/item/1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1}`))
+		}),
+	)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/item", strings.NewReader(body))
+
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("abc", `{"name": "foo"}`)
+
+	if rr.Code != http.StatusCreated || rr.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("first request should not be replayed; got status %d", rr.Code)
+	}
+
+	rr = send("abc", `{"name": "foo"}`)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusCreated)
+	}
+	if rr.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry should be replayed")
+	}
+	if rr.Header().Get("Location") != "/api/item/1" {
+		t.Errorf("replay should have stored headers")
+	}
+	if rr.Body.String() != `{"id": 1}` {
+		t.Errorf("got body %s; want stored body", rr.Body.String())
+	}
+
+	if rr = send("abc", `{"name": "bar"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	send("", `{"name": "foo"}`)
+	send("def", `{"name": "foo"}`)
+
+	if calls != 3 {
+		t.Errorf("got %d handler calls; want 3", calls)
+	}
+}
+
+func TestIdempotencyHandlerCacheErrors(t *testing.T) {
+	calls := 0
+	mockCache := &cachetest.MockCache{
+		GetFunc: func(key string) ([]byte, error) {
+			return nil, cacheutil.ErrCacheNil
+		},
+		SetNXFunc: func(key string, value interface{}, expiration time.Duration) (bool, error) {
+			return false, nil
+		},
+	}
+	handler := NewIdempotencyHandler(mockCache, IdempotencyHandlerConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}),
+	)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/item", strings.NewReader(`{"name": "foo"}`))
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send(); rr.Code != http.StatusConflict {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusConflict)
+	}
+
+	mockCache.SetNXFunc = func(key string, value interface{}, expiration time.Duration) (bool, error) {
+		return false, errors.New("connection refused")
+	}
+
+	if rr := send(); rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusInternalServerError)
+	}
+	if calls != 0 {
+		t.Errorf("got %d handler calls; want 0", calls)
+	}
+}
+
+func TestIdempotencyHandlerPanic(t *testing.T) {
+	calls := 0
+	handler := NewIdempotencyHandler(cachetest.NewMemoryCache(), IdempotencyHandlerConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			if calls == 1 {
+				panic("handler failed")
+			}
+
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	send := func() (rr *httptest.ResponseRecorder, rv interface{}) {
+		defer func() {
+			rv = recover()
+		}()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/item", strings.NewReader(`{"name": "foo"}`))
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr, nil
+	}
+
+	if _, rv := send(); rv != "handler failed" {
+		t.Fatalf("should re-panic with handler panic; got %v", rv)
+	}
+
+	rr, rv := send()
+
+	if rv != nil {
+		t.Fatalf("should not panic; got %v", rv)
+	}
+	if rr.Code != http.StatusCreated {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusCreated)
+	}
+	if calls != 2 {
+		t.Errorf("got %d handler calls; want 2", calls)
+	}
+}
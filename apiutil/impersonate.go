@@ -0,0 +1,402 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+const (
+	// ImpersonatedByHeader is response header set to id of the real user
+	// while they're impersonating another user, which clients can use to
+	// show a banner
+	ImpersonatedByHeader = "X-Impersonated-By"
+
+	// DefaultImpersonationKey is session key impersonated user is stored
+	// under if ImpersonationConfig#SessionKey is not set
+	DefaultImpersonationKey = "impersonate"
+
+	forbiddenImpersonateTxt = "Not allowed to impersonate user"
+	userNotFoundTxt         = "User not found"
+)
+
+// ImpersonateForm is form decoded from body of requests to
+// ImpersonationHandler#Start
+type ImpersonateForm struct {
+	ID string `json:"id"`
+}
+
+// ImpersonationEvent is passed to ImpersonationConfig#AuditLog whenever
+// impersonation starts or stops
+type ImpersonationEvent struct {
+	// ActorID is id of the real user
+	ActorID string
+
+	// UserID is id of impersonated user
+	UserID string
+
+	Started bool
+	IP      string
+	Time    time.Time
+}
+
+// ImpersonationConfig is config struct used for ImpersonationHandler
+type ImpersonationConfig struct {
+	// SessionStore is store impersonated user is stored in, which should
+	// be the same as AuthHandlerConfig#SessionStore
+	SessionStore cacheutil.SessionStore
+
+	// SessionConfig is session name and keys, which should be the same as
+	// AuthHandlerConfig#SessionConfig
+	SessionConfig cacheutil.SessionConfig
+
+	// SessionKey is session key impersonated user is stored under
+	// Default is DefaultImpersonationKey
+	SessionKey string
+
+	// AdminGroups are groups allowed to impersonate users
+	// Default is []string{DefaultAdminGroup}
+	AdminGroups []string
+
+	// QueryGroups returns groups of the real user with id, which are
+	// checked against AdminGroups on every request while impersonating
+	// so impersonation ends once the real user is no longer an admin
+	// Default uses groups within context of request, which requires a
+	// GroupHandler to be placed before MiddlewareFunc
+	QueryGroups func(db httputil.Querier, id string) (map[string]bool, error)
+
+	// Expiration is how long impersonation lasts before it's ended by
+	// MiddlewareFunc
+	// Default is 0, which means impersonation doesn't expire
+	Expiration time.Duration
+
+	// Clock is what time of ImpersonationEvent and Expiration is
+	// taken from
+	// Default is httputil#RealClock
+	Clock httputil.Clock
+
+	// QueryUser returns json of user with id, in the same format as the
+	// user returned from AuthHandler, which should have "id" and "email"
+	// fields
+	// Should return sql.ErrNoRows if user doesn't exist
+	QueryUser func(db httputil.Querier, id string) ([]byte, error)
+
+	// AuditLog is called whenever impersonation starts or stops
+	// Default logs events with httputil#Logger
+	AuditLog func(r *http.Request, event ImpersonationEvent)
+
+	// ForbiddenResponse is config used to respond to user if they're not
+	// allowed to impersonate
+	//
+	// Default status value is http.StatusForbidden
+	// Default response value is []byte("Not allowed to impersonate user")
+	ForbiddenResponse HTTPResponseConfig
+
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// ImpersonationHandler lets users of ImpersonationConfig#AdminGroups
+// impersonate other users
+//
+// MiddlewareFunc should be placed after AuthHandler and before
+// GroupHandler so the impersonated user replaces the real user within
+// context, along with their groups, while the real user is stored under
+// ImpersonatorCtxKey for audit logs
+// ImpersonationConfig#QueryGroups should be set so MiddlewareFunc can
+// check the real user is still an admin without their groups in context
+// Start and Stop are endpoints that start and stop impersonation, where
+// Start should be routed after GroupHandler so groups of the real user
+// can be checked
+type ImpersonationHandler struct {
+	db     httputil.DBInterfaceV2
+	config ImpersonationConfig
+}
+
+// NewImpersonationHandler returns *ImpersonationHandler
+func NewImpersonationHandler(db httputil.DBInterfaceV2, config ImpersonationConfig) *ImpersonationHandler {
+	if config.SessionKey == "" {
+		config.SessionKey = DefaultImpersonationKey
+	}
+	if len(config.AdminGroups) == 0 {
+		config.AdminGroups = []string{DefaultAdminGroup}
+	}
+	if config.AuditLog == nil {
+		config.AuditLog = logImpersonationEvent
+	}
+	if config.Clock == nil {
+		config.Clock = httputil.RealClock
+	}
+
+	setHTTPResponseDefaults(&config.ForbiddenResponse, http.StatusForbidden, []byte(forbiddenImpersonateTxt))
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &ImpersonationHandler{
+		db:     db,
+		config: config,
+	}
+}
+
+// MiddlewareFunc replaces user within context with impersonated user,
+// if any, and sets ImpersonatedByHeader
+//
+// Impersonation is ended if it has expired or the real user is no
+// longer within ImpersonationConfig#AdminGroups
+func (i *ImpersonationHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor, ok := ctxutil.UserFrom(r.Context())
+
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, err := i.config.SessionStore.Get(r, i.config.SessionConfig.SessionName)
+
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		val, ok := session.Values[i.config.SessionKey].([]byte)
+
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user middlewareUser
+
+		userBytes, _, err := i.config.SessionConfig.DecodeValue(val)
+
+		if err == nil {
+			err = json.Unmarshal(userBytes, &user)
+		}
+
+		if err != nil {
+			httputil.Logger.Errorf("invalid impersonated user in session: %s", err.Error())
+			delete(session.Values, i.config.SessionKey)
+			delete(session.Values, i.startedKey())
+			session.Save(r, w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		isAdmin, err := i.isActorAdmin(r, actor.ID)
+
+		if err != nil {
+			httputil.Logger.Errorf("impersonate query for groups err: %s", err.Error())
+			writeHTTPResponse(w, i.config.ServerErrResponse)
+			return
+		}
+
+		if !isAdmin || i.isExpired(session.Values[i.startedKey()]) {
+			if !i.setSessionValue(w, r, nil) {
+				return
+			}
+
+			i.config.AuditLog(r, ImpersonationEvent{
+				ActorID: actor.ID,
+				UserID:  user.ID,
+				IP:      ClientIP(r),
+				Time:    i.config.Clock.Now(),
+			})
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := ctxutil.WithUserJSON(r.Context(), userBytes)
+		ctx = ctxutil.WithUser(ctx, user)
+		ctx = ctxutil.WithImpersonator(ctx, actor)
+
+		w.Header().Set(ImpersonatedByHeader, actor.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Start starts impersonation of user with id of ImpersonateForm and
+// sends the impersonated user as response
+func (i *ImpersonationHandler) Start(w http.ResponseWriter, r *http.Request) {
+	var form ImpersonateForm
+
+	actor, ok := ctxutil.UserFrom(r.Context())
+
+	if !ok || IsImpersonating(r) || !i.isAdmin(r) {
+		writeHTTPResponse(w, i.config.ForbiddenResponse)
+		return
+	}
+
+	if HasBodyError(w, r) {
+		return
+	}
+	if HasDecodeError(w, DecodeBody(r, &form)) {
+		return
+	}
+
+	if form.ID == "" || form.ID == actor.ID {
+		writeHTTPResponse(w, i.config.ForbiddenResponse)
+		return
+	}
+
+	userBytes, err := i.config.QueryUser(i.db, form.ID)
+
+	if err == sql.ErrNoRows {
+		WriteError(w, httputil.NotFound(userNotFoundTxt))
+		return
+	}
+	if err != nil {
+		httputil.Logger.Errorf("impersonate query for user err: %s", err.Error())
+		writeHTTPResponse(w, i.config.ServerErrResponse)
+		return
+	}
+
+	if !i.setSessionValue(w, r, i.config.SessionConfig.EncodeValue(userBytes)) {
+		return
+	}
+
+	i.config.AuditLog(r, ImpersonationEvent{
+		ActorID: actor.ID,
+		UserID:  form.ID,
+		Started: true,
+		IP:      ClientIP(r),
+		Time:    i.config.Clock.Now(),
+	})
+
+	w.Header().Set(ImpersonatedByHeader, actor.ID)
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.Write(userBytes)
+}
+
+// Stop stops impersonation, if any, and sends status 200
+func (i *ImpersonationHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	actor, ok := ctxutil.ImpersonatorFrom(r.Context())
+
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !i.setSessionValue(w, r, nil) {
+		return
+	}
+
+	i.config.AuditLog(r, ImpersonationEvent{
+		ActorID: actor.ID,
+		UserID:  GetUserID(r),
+		IP:      ClientIP(r),
+		Time:    i.config.Clock.Now(),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (i *ImpersonationHandler) isAdmin(r *http.Request) bool {
+	return i.hasAdminGroup(GetUserGroups(r))
+}
+
+// isActorAdmin returns whether real user with id is still within
+// ImpersonationConfig#AdminGroups
+func (i *ImpersonationHandler) isActorAdmin(r *http.Request, id string) (bool, error) {
+	if i.config.QueryGroups == nil {
+		return i.isAdmin(r), nil
+	}
+
+	groups, err := i.config.QueryGroups(i.db, id)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return i.hasAdminGroup(groups), nil
+}
+
+func (i *ImpersonationHandler) hasAdminGroup(groups map[string]bool) bool {
+	for _, v := range i.config.AdminGroups {
+		if groups[v] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExpired returns whether impersonation started at unix time started
+// has lasted longer than ImpersonationConfig#Expiration
+func (i *ImpersonationHandler) isExpired(started interface{}) bool {
+	if i.config.Expiration <= 0 {
+		return false
+	}
+
+	unix, ok := started.(int64)
+
+	if !ok {
+		return true
+	}
+
+	return i.config.Clock.Now().Sub(time.Unix(unix, 0)) > i.config.Expiration
+}
+
+// startedKey is session key the unix time impersonation started at is
+// stored under
+func (i *ImpersonationHandler) startedKey() string {
+	return i.config.SessionKey + "_started"
+}
+
+// setSessionValue stores val under ImpersonationConfig#SessionKey,
+// along with the time impersonation started, deleting both if val is nil
+// Returns false if response has already been written
+func (i *ImpersonationHandler) setSessionValue(w http.ResponseWriter, r *http.Request, val []byte) bool {
+	session, err := i.config.SessionStore.Get(r, i.config.SessionConfig.SessionName)
+
+	if err == nil {
+		if val == nil {
+			delete(session.Values, i.config.SessionKey)
+			delete(session.Values, i.startedKey())
+		} else {
+			session.Values[i.config.SessionKey] = val
+			session.Values[i.startedKey()] = i.config.Clock.Now().Unix()
+		}
+
+		err = session.Save(r, w)
+	}
+
+	if err != nil {
+		httputil.Logger.Errorf("impersonate session err: %s", err.Error())
+		writeHTTPResponse(w, i.config.ServerErrResponse)
+		return false
+	}
+
+	return true
+}
+
+func logImpersonationEvent(r *http.Request, event ImpersonationEvent) {
+	msg := "impersonation stopped"
+
+	if event.Started {
+		msg = "impersonation started"
+	}
+
+	httputil.Logger.WithFields(logrus.Fields{
+		"actor_id": event.ActorID,
+		"user_id":  event.UserID,
+		"ip":       event.IP,
+	}).Info(msg)
+}
@@ -0,0 +1,203 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestImpersonationHandler(t *testing.T) {
+	store := cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))
+	sessionConfig := cacheutil.SessionConfig{
+		SessionName: cookieName,
+		Keys:        cacheutil.SessionKeys{UserKey: cookieName},
+	}
+
+	var events []ImpersonationEvent
+
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := httputil.NewMockClock(now)
+	actorGroups := map[string]bool{DefaultAdminGroup: true}
+
+	h := NewImpersonationHandler(nil, ImpersonationConfig{
+		SessionStore:  store,
+		SessionConfig: sessionConfig,
+		QueryUser: func(db httputil.Querier, id string) ([]byte, error) {
+			if id != "2" {
+				return nil, sql.ErrNoRows
+			}
+
+			return []byte(`{"id":"2","email":"user@email.com"}`), nil
+		},
+		QueryGroups: func(db httputil.Querier, id string) (map[string]bool, error) {
+			if id != "1" {
+				return nil, sql.ErrNoRows
+			}
+
+			return actorGroups, nil
+		},
+		AuditLog: func(r *http.Request, event ImpersonationEvent) {
+			events = append(events, event)
+		},
+		Expiration: time.Hour,
+		Clock:      clock,
+	})
+
+	cookie, err := store.CreateSession(cookieName, map[interface{}]interface{}{
+		cookieName: sessionConfig.EncodeValue([]byte(`{"id":"1","email":"admin@email.com"}`)),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	newRequest := func(method, body string, groups map[string]bool) *http.Request {
+		req := httptest.NewRequest(method, "/url", strings.NewReader(body))
+		req.AddCookie(cookie)
+
+		ctx := context.WithValue(req.Context(), MiddlewareUserCtxKey, middlewareUser{ID: "1", Email: "admin@email.com"})
+		ctx = context.WithValue(ctx, UserCtxKey, []byte(`{"id":"1","email":"admin@email.com"}`))
+
+		if groups != nil {
+			ctx = context.WithValue(ctx, GroupCtxKey, groups)
+		}
+
+		return req.WithContext(ctx)
+	}
+
+	start := func(body string, groups map[string]bool) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.Start(rr, newRequest(http.MethodPost, body, groups))
+		return rr
+	}
+
+	admin := map[string]bool{DefaultAdminGroup: true}
+
+	if rr := start(`{"id":"2"}`, map[string]bool{"User": true}); rr.Code != http.StatusForbidden {
+		t.Errorf(statusErrTxt, http.StatusForbidden, rr.Code)
+	}
+	if rr := start(`{"id":"1"}`, admin); rr.Code != http.StatusForbidden {
+		t.Errorf(statusErrTxt, http.StatusForbidden, rr.Code)
+	}
+	if rr := start(`{"id":"3"}`, admin); rr.Code != http.StatusNotFound {
+		t.Errorf(statusErrTxt, http.StatusNotFound, rr.Code)
+	}
+
+	rr := start(`{"id":"2"}`, admin)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if rr.Header().Get(ImpersonatedByHeader) != "1" {
+		t.Errorf("should set %s header to 1; got %s", ImpersonatedByHeader, rr.Header().Get(ImpersonatedByHeader))
+	}
+	if len(events) != 1 || !events[0].Started || events[0].ActorID != "1" || events[0].UserID != "2" || !events[0].Time.Equal(now) {
+		t.Errorf("should log start of impersonation; got %+v", events)
+	}
+
+	var userID, actorID string
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsImpersonating(r) {
+			t.Errorf("should be impersonating")
+		}
+
+		userID = GetUserID(r)
+		actorID = GetActorID(r)
+		h.Stop(w, r)
+	})
+
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(next).ServeHTTP(rr, newRequest(http.MethodPost, "", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if userID != "2" || actorID != "1" {
+		t.Errorf("user should be 2 and actor should be 1; got %s and %s", userID, actorID)
+	}
+	if rr.Header().Get(ImpersonatedByHeader) != "1" {
+		t.Errorf("should set %s header to 1; got %s", ImpersonatedByHeader, rr.Header().Get(ImpersonatedByHeader))
+	}
+	if len(events) != 2 || events[1].Started || events[1].UserID != "2" {
+		t.Errorf("should log stop of impersonation; got %+v", events)
+	}
+
+	// Impersonation is removed from session once stopped
+	next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsImpersonating(r) {
+			t.Errorf("should not be impersonating")
+		}
+		if GetActorID(r) != "1" {
+			t.Errorf("actor should be 1; got %s", GetActorID(r))
+		}
+	})
+
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(next).ServeHTTP(rr, newRequest(http.MethodGet, "", nil))
+
+	if rr.Header().Get(ImpersonatedByHeader) != "" {
+		t.Errorf("should not set %s header", ImpersonatedByHeader)
+	}
+
+	notImpersonating := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsImpersonating(r) {
+			t.Errorf("should not be impersonating")
+		}
+		if GetUserID(r) != "1" {
+			t.Errorf("user should be 1; got %s", GetUserID(r))
+		}
+	})
+
+	// Impersonation ends once the real user is no longer an admin
+	if rr = start(`{"id":"2"}`, admin); rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	actorGroups = map[string]bool{"User": true}
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(notImpersonating).ServeHTTP(rr, newRequest(http.MethodGet, "", nil))
+
+	if rr.Header().Get(ImpersonatedByHeader) != "" {
+		t.Errorf("should not set %s header", ImpersonatedByHeader)
+	}
+	if len(events) != 4 || events[3].Started || events[3].UserID != "2" {
+		t.Errorf("should log end of impersonation; got %+v", events)
+	}
+
+	actorGroups = admin
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(notImpersonating).ServeHTTP(rr, newRequest(http.MethodGet, "", nil))
+
+	// Impersonation ends once expired
+	if rr = start(`{"id":"2"}`, admin); rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	clock.Add(time.Minute * 59)
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsImpersonating(r) {
+			t.Errorf("should be impersonating before expiration")
+		}
+	})).ServeHTTP(rr, newRequest(http.MethodGet, "", nil))
+
+	clock.Add(time.Minute * 2)
+	rr = httptest.NewRecorder()
+	h.MiddlewareFunc(notImpersonating).ServeHTTP(rr, newRequest(http.MethodGet, "", nil))
+
+	if len(events) != 6 || events[5].Started || !events[5].Time.Equal(now.Add(time.Minute*61)) {
+		t.Errorf("should log end of expired impersonation; got %+v", events)
+	}
+}
@@ -0,0 +1,317 @@
+package apiutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+const (
+	// DefaultInviteTable is table used to store invite tokens if
+	// InviteConfig#TableName is not set
+	DefaultInviteTable = "invite_token"
+
+	// InviteTokenParam is query param token is added to within
+	// InviteConfig#LinkURL
+	InviteTokenParam = "token"
+
+	// InvitePurposeInvite is purpose of tokens inviting a new user, who
+	// sets their password when accepting the invite
+	InvitePurposeInvite = "invite"
+
+	// InvitePurposeVerify is purpose of tokens verifying email of an
+	// existing user
+	InvitePurposeVerify = "verify"
+
+	invalidInviteTxt = "Invalid or expired link"
+	passwordShortTxt = "Must be at least %d characters"
+)
+
+const (
+	invitePostgresTableQuery = `
+	create table if not exists %s (
+		token_hash varchar(64) not null primary key,
+		email varchar(255) not null,
+		purpose varchar(32) not null,
+		user_id varchar(64),
+		expires_at timestamp not null,
+		used_at timestamp,
+		created_at timestamp not null default current_timestamp
+	)`
+
+	inviteMysqlTableQuery = `
+	create table if not exists %s (
+		token_hash varchar(64) not null primary key,
+		email varchar(255) not null,
+		purpose varchar(32) not null,
+		user_id varchar(64),
+		expires_at timestamp not null,
+		used_at timestamp null,
+		created_at timestamp not null default current_timestamp
+	)`
+)
+
+var (
+	// ErrInvalidInviteToken is returned from ConsumeInvite when token
+	// doesn't exist, was already used or is for a different purpose
+	ErrInvalidInviteToken = errors.New("apiutil: invalid invite token")
+
+	// ErrExpiredInviteToken is returned from ConsumeInvite when token
+	// has expired
+	ErrExpiredInviteToken = errors.New("apiutil: expired invite token")
+
+	// DefaultInviteTemplate is email template used if
+	// InviteConfig#Template is not set
+	DefaultInviteTemplate = template.Must(template.New("invite").Parse(
+		`<p>Follow the link below to continue, which expires {{.ExpiresAt.Format "Jan 2, 2006 3:04 PM MST"}}</p>` +
+			`<p><a href="{{.Link}}">{{.Link}}</a></p>`,
+	))
+)
+
+// Invite is invite or email verification stored for a token
+type Invite struct {
+	// Email is address the token is sent to
+	Email string
+
+	// Purpose is what the token is used for
+	// Default is InvitePurposeInvite
+	Purpose string
+
+	// UserID is id of existing user, if any eg. for email verification
+	UserID string
+
+	// ExpiresAt is when token expires, which is set by CreateInvite
+	ExpiresAt time.Time
+}
+
+// InviteEmail is data InviteConfig#Template is executed with
+type InviteEmail struct {
+	Invite
+	Link string
+}
+
+// InviteConfig is config struct used for creating, sending and
+// consuming invite tokens
+type InviteConfig struct {
+	// TableName is table used to store invite tokens
+	// Default is DefaultInviteTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding and table creation
+	// Default is Postgres
+	DBType string
+
+	// Expiry is how long tokens are valid for
+	// Default is 72 hours
+	Expiry time.Duration
+
+	// LinkURL is url sent in email, which token is added to as
+	// InviteTokenParam eg. "https://example.com/invite"
+	LinkURL string
+
+	// From is address emails are sent from
+	From string
+
+	// Subject is subject of emails
+	Subject string
+
+	// Template is html template of email, which is executed with
+	// InviteEmail
+	// Default is DefaultInviteTemplate
+	Template *template.Template
+
+	// Messenger sends emails
+	Messenger mailutil.SendMessage
+}
+
+func (i *InviteConfig) setDefaults() {
+	if i.TableName == "" {
+		i.TableName = DefaultInviteTable
+	}
+	if i.DBType == "" {
+		i.DBType = dbutil.Postgres
+	}
+	if i.Expiry <= 0 {
+		i.Expiry = time.Hour * 72
+	}
+	if i.Template == nil {
+		i.Template = DefaultInviteTemplate
+	}
+}
+
+func (i *InviteConfig) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(i.DBType), fmt.Sprintf(query, i.TableName))
+}
+
+// CreateInviteTable creates invite table if it does not exist
+func CreateInviteTable(db httputil.XODB, config InviteConfig) error {
+	config.setDefaults()
+	query := invitePostgresTableQuery
+
+	if config.DBType == dbutil.Mysql {
+		query = inviteMysqlTableQuery
+	}
+
+	_, err := db.Exec(fmt.Sprintf(query, config.TableName))
+	return err
+}
+
+// CreateInvite stores invite and returns its token, which expires after
+// InviteConfig#Expiry
+// Only a hash of the token is stored so tokens can't be recovered from
+// database
+func CreateInvite(db httputil.XODB, config InviteConfig, invite Invite) (string, error) {
+	config.setDefaults()
+	token, _, err := createInvite(db, config, invite)
+	return token, err
+}
+
+// SendInvite creates invite with CreateInvite and emails link with
+// token to Invite#Email using InviteConfig#Messenger
+// Returns token
+func SendInvite(db httputil.XODB, config InviteConfig, invite Invite) (string, error) {
+	config.setDefaults()
+
+	if config.Messenger == nil {
+		return "", errors.New("apiutil: InviteConfig#Messenger is required to send invites")
+	}
+
+	link, err := url.Parse(config.LinkURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	token, invite, err := createInvite(db, config, invite)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set(InviteTokenParam, token)
+	link.RawQuery = query.Encode()
+
+	var buf bytes.Buffer
+
+	if err = config.Template.Execute(&buf, InviteEmail{Invite: invite, Link: link.String()}); err != nil {
+		return "", err
+	}
+
+	err = mailutil.SendEmail(
+		[]string{invite.Email},
+		config.From,
+		config.Subject,
+		nil,
+		buf.Bytes(),
+		config.Messenger,
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// createInvite returns token of stored invite along with invite with
+// defaults set
+func createInvite(db httputil.XODB, config InviteConfig, invite Invite) (string, Invite, error) {
+	if invite.Purpose == "" {
+		invite.Purpose = InvitePurposeInvite
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	invite.ExpiresAt = time.Now().UTC().Add(config.Expiry)
+
+	var userID interface{}
+
+	if invite.UserID != "" {
+		userID = invite.UserID
+	}
+
+	_, err := db.Exec(
+		config.rebind("insert into %s (token_hash, email, purpose, user_id, expires_at) values (?, ?, ?, ?, ?)"),
+		hashInviteToken(token),
+		invite.Email,
+		invite.Purpose,
+		userID,
+		invite.ExpiresAt,
+	)
+
+	if err != nil {
+		return "", Invite{}, err
+	}
+
+	return token, invite, nil
+}
+
+// ConsumeInvite marks invite of token as used and returns it, so a
+// token can only be used once
+// Returns ErrInvalidInviteToken if token doesn't exist, was already used
+// or its purpose isn't purpose, and ErrExpiredInviteToken if it expired
+func ConsumeInvite(db httputil.XODB, config InviteConfig, token, purpose string) (Invite, error) {
+	var invite Invite
+	var userID sql.NullString
+	var usedAt *time.Time
+
+	config.setDefaults()
+	tokenHash := hashInviteToken(token)
+
+	err := db.QueryRow(
+		config.rebind("select email, purpose, user_id, expires_at, used_at from %s where token_hash = ?"),
+		tokenHash,
+	).Scan(&invite.Email, &invite.Purpose, &userID, &invite.ExpiresAt, &usedAt)
+
+	if err == sql.ErrNoRows {
+		return Invite{}, ErrInvalidInviteToken
+	}
+	if err != nil {
+		return Invite{}, err
+	}
+
+	invite.UserID = userID.String
+
+	if usedAt != nil || invite.Purpose != purpose {
+		return Invite{}, ErrInvalidInviteToken
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return Invite{}, ErrExpiredInviteToken
+	}
+
+	res, err := db.Exec(
+		config.rebind("update %s set used_at = current_timestamp where token_hash = ? and used_at is null"),
+		tokenHash,
+	)
+
+	if err != nil {
+		return Invite{}, err
+	}
+
+	// Token was used by concurrent request
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return Invite{}, ErrInvalidInviteToken
+	}
+
+	return invite, nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/authutil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// AcceptInviteForm is form decoded from body of requests to
+// AcceptInviteHandler
+type AcceptInviteForm struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// AcceptInviteHandlerConfig is config struct used for AcceptInviteHandler
+type AcceptInviteHandlerConfig struct {
+	InviteConfig InviteConfig
+
+	// Purpose is purpose of tokens accepted by handler
+	// Password is required for InvitePurposeInvite and ignored otherwise
+	// Default is InvitePurposeInvite
+	Purpose string
+
+	// MinPasswordLength is min length of password
+	// Default is 8
+	MinPasswordLength int
+
+	// Accept is called within the same transaction the invite is consumed
+	// in and should create the invited user, or set their password or
+	// mark their email as verified, returning user for their session
+	// passwordHash is bcrypt hash of password, which is empty if password
+	// isn't required for Purpose
+	Accept func(tx httputil.Tx, invite Invite, passwordHash string) (LoginUser, error)
+
+	// SessionStore is store session of user is created in
+	// If nil, no session is created and user is only sent as response
+	SessionStore cacheutil.SessionStore
+
+	// SessionConfig is session name and keys, which should be the same as
+	// AuthHandlerConfig#SessionConfig
+	SessionConfig cacheutil.SessionConfig
+
+	// InsertSession is optional function that's called after session
+	// is created, which can be used to store session in database
+	InsertSession func(db httputil.DBInterfaceV2, r *http.Request, userID, sessionID string) error
+
+	// InvalidTokenResponse is config used to respond to user if token is
+	// invalid, used or expired
+	//
+	// Default status value is http.StatusGone
+	// Default response value is []byte("Invalid or expired link")
+	InvalidTokenResponse HTTPResponseConfig
+}
+
+// AcceptInviteHandler is endpoint that decodes AcceptInviteForm,
+// consumes its token, calls AcceptInviteHandlerConfig#Accept with the
+// invite and hash of password, creates session and sends user as
+// response
+type AcceptInviteHandler struct {
+	db     httputil.DBInterfaceV2
+	config AcceptInviteHandlerConfig
+}
+
+// NewAcceptInviteHandler returns *AcceptInviteHandler
+func NewAcceptInviteHandler(db httputil.DBInterfaceV2, config AcceptInviteHandlerConfig) *AcceptInviteHandler {
+	if config.Purpose == "" {
+		config.Purpose = InvitePurposeInvite
+	}
+	if config.MinPasswordLength <= 0 {
+		config.MinPasswordLength = 8
+	}
+
+	setHTTPResponseDefaults(&config.InvalidTokenResponse, http.StatusGone, []byte(invalidInviteTxt))
+
+	return &AcceptInviteHandler{
+		db:     db,
+		config: config,
+	}
+}
+
+func (a *AcceptInviteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var form AcceptInviteForm
+	var passwordHash string
+	var err error
+
+	if HasBodyError(w, r) {
+		return
+	}
+	if HasDecodeError(w, DecodeBody(r, &form)) {
+		return
+	}
+
+	if form.Token == "" {
+		writeHTTPResponse(w, a.config.InvalidTokenResponse)
+		return
+	}
+
+	if a.config.Purpose == InvitePurposeInvite {
+		if len(form.Password) < a.config.MinPasswordLength {
+			WriteError(w, httputil.Invalid(InvalidFormMessage, map[string]string{
+				"password": fmt.Sprintf(passwordShortTxt, a.config.MinPasswordLength),
+			}))
+			return
+		}
+
+		if passwordHash, err = authutil.HashPassword(form.Password); HasError(w, err) {
+			return
+		}
+	}
+
+	tx, err := a.db.Begin()
+
+	if HasError(w, err) {
+		return
+	}
+
+	invite, err := ConsumeInvite(tx, a.config.InviteConfig, form.Token, a.config.Purpose)
+
+	if err != nil {
+		tx.Rollback()
+
+		if err == ErrInvalidInviteToken || err == ErrExpiredInviteToken {
+			writeHTTPResponse(w, a.config.InvalidTokenResponse)
+		} else {
+			WriteError(w, err)
+		}
+
+		return
+	}
+
+	user, err := a.config.Accept(tx, invite, passwordHash)
+
+	if err != nil {
+		tx.Rollback()
+		WriteError(w, err)
+		return
+	}
+
+	if HasError(w, a.db.Commit(tx)) {
+		return
+	}
+
+	if a.config.SessionStore != nil {
+		sessionID, err := newUserSession(w, r, a.config.SessionStore, a.config.SessionConfig, user)
+
+		if err == nil && a.config.InsertSession != nil {
+			err = a.config.InsertSession(a.db, r, user.ID, sessionID)
+		}
+
+		// Invite is already accepted so user can still log in normally
+		if err != nil {
+			httputil.Logger.Errorf("accept invite create session err: %s", err.Error())
+		}
+	}
+
+	SetToken(w, r)
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.Write(user.Payload)
+}
@@ -0,0 +1,84 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/authutil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestAcceptInviteHandler(t *testing.T) {
+	store := cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))
+	db := dbtest.NewExpectDB(t)
+
+	var acceptedHash string
+
+	h := NewAcceptInviteHandler(db, AcceptInviteHandlerConfig{
+		Accept: func(tx httputil.Tx, invite Invite, passwordHash string) (LoginUser, error) {
+			acceptedHash = passwordHash
+			return LoginUser{ID: "1", Payload: []byte(`{"id":"1","email":"` + invite.Email + `"}`)}, nil
+		},
+		SessionStore: store,
+		SessionConfig: cacheutil.SessionConfig{
+			SessionName: cookieName,
+			Keys:        cacheutil.SessionKeys{UserKey: cookieName},
+		},
+	})
+
+	accept := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/invite", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	if rr := accept(`{"token": "valid", "password": "short"}`); rr.Code != http.StatusNotAcceptable {
+		t.Errorf(statusErrTxt, http.StatusNotAcceptable, rr.Code)
+	}
+
+	db.ExpectBegin()
+	db.ExpectQuery("select email, purpose, user_id, expires_at, used_at from invite_token").
+		WithArgs(hashInviteToken("valid")).
+		WillReturnRows(
+			dbtest.NewRows("email", "purpose", "user_id", "expires_at", "used_at").
+				AddRow("foo@example.com", InvitePurposeInvite, nil, time.Now().Add(time.Hour), nil),
+		)
+	db.ExpectExec("update invite_token set used_at").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	rr := accept(`{"token": "valid", "password": "password"}`)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if rr.Body.String() != `{"id":"1","email":"foo@example.com"}` {
+		t.Errorf("should send user; got %s", rr.Body.String())
+	}
+	if err := authutil.CheckPassword(acceptedHash, "password"); err != nil {
+		t.Errorf("should pass hash of password to Accept; got %s", err.Error())
+	}
+	if len(rr.Result().Cookies()) == 0 {
+		t.Errorf("should create session")
+	}
+
+	db.ExpectBegin()
+	db.ExpectQuery("select email, purpose, user_id, expires_at, used_at from invite_token").
+		WillReturnRows(dbtest.NewRows("email", "purpose", "user_id", "expires_at", "used_at"))
+	db.ExpectRollback()
+
+	if rr = accept(`{"token": "used", "password": "password"}`); rr.Code != http.StatusGone {
+		t.Errorf(statusErrTxt, http.StatusGone, rr.Code)
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,111 @@
+package apiutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+type inviteMessenger struct {
+	messages []*mailutil.Message
+}
+
+func (i *inviteMessenger) Send(msg *mailutil.Message) error {
+	i.messages = append(i.messages, msg)
+	return nil
+}
+
+func TestSendInvite(t *testing.T) {
+	messenger := &inviteMessenger{}
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec(`insert into invite_token \(token_hash, email, purpose, user_id, expires_at\) values \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs(dbtest.AnyArg(), "foo@example.com", InvitePurposeInvite, nil, dbtest.AnyArg()).
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	token, err := SendInvite(db, InviteConfig{
+		LinkURL:   "https://example.com/invite",
+		From:      "noreply@example.com",
+		Subject:   "You're invited",
+		Messenger: messenger,
+	}, Invite{Email: "foo@example.com"})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if len(messenger.messages) != 1 {
+		t.Fatalf("should send 1 email; got %d", len(messenger.messages))
+	}
+
+	msg := messenger.messages[0]
+
+	if msg.GetHeaders()["To"][0] != "foo@example.com" {
+		t.Errorf("should send email to foo@example.com; got %v", msg.GetHeaders()["To"])
+	}
+	if !strings.Contains(msg.GetMessage(), "https://example.com/invite?token="+token) {
+		t.Errorf("should send link with token; got %s", msg.GetMessage())
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsumeInvite(t *testing.T) {
+	selectQuery := "select email, purpose, user_id, expires_at, used_at from invite_token"
+	columns := []string{"email", "purpose", "user_id", "expires_at", "used_at"}
+	future := time.Now().Add(time.Hour)
+
+	db := dbtest.NewExpectDB(t)
+	db.ExpectQuery(selectQuery).
+		WithArgs(hashInviteToken("valid")).
+		WillReturnRows(dbtest.NewRows(columns...).AddRow("foo@example.com", InvitePurposeVerify, "1", future, nil))
+	db.ExpectExec("update invite_token set used_at").
+		WithArgs(hashInviteToken("valid")).
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	invite, err := ConsumeInvite(db, InviteConfig{}, "valid", InvitePurposeVerify)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if invite.Email != "foo@example.com" || invite.UserID != "1" {
+		t.Errorf("should return invite; got %+v", invite)
+	}
+
+	tests := []struct {
+		name     string
+		rows     *dbtest.Rows
+		expected error
+	}{
+		{
+			name:     "missing",
+			rows:     dbtest.NewRows(columns...),
+			expected: ErrInvalidInviteToken,
+		},
+		{
+			name:     "used",
+			rows:     dbtest.NewRows(columns...).AddRow("foo@example.com", InvitePurposeVerify, nil, future, time.Now()),
+			expected: ErrInvalidInviteToken,
+		},
+		{
+			name:     "purpose",
+			rows:     dbtest.NewRows(columns...).AddRow("foo@example.com", InvitePurposeInvite, nil, future, nil),
+			expected: ErrInvalidInviteToken,
+		},
+		{
+			name:     "expired",
+			rows:     dbtest.NewRows(columns...).AddRow("foo@example.com", InvitePurposeVerify, nil, time.Now().Add(-time.Hour), nil),
+			expected: ErrExpiredInviteToken,
+		},
+	}
+
+	for _, test := range tests {
+		db.ExpectQuery(selectQuery).WillReturnRows(test.rows)
+
+		if _, err = ConsumeInvite(db, InviteConfig{}, "token", InvitePurposeVerify); err != test.expected {
+			t.Errorf("%s: should return %v; got %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package apiutil
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+const (
+	// DefaultLanguage is language returned from LanguageFromRequest when
+	// request has no language
+	DefaultLanguage = "en"
+)
+
+var (
+	// LanguageCtxKey is key used to store language of request within
+	// request context by LanguageHandler
+	LanguageCtxKey = ctxutil.LanguageKey
+)
+
+// LanguageHandlerConfig is config struct used for LanguageHandler
+type LanguageHandlerConfig struct {
+	// Supported are language tags, like "en" or "pt-BR", responses can
+	// be localized in where the first is used when none match
+	// Default value is []string{DefaultLanguage}
+	Supported []string
+
+	// UserLanguage, if set, returns preferred language of user of
+	// request, eg. from their profile, which is used over the
+	// Accept-Language header if supported
+	// Should return empty string if user has no preference
+	UserLanguage func(r *http.Request) string
+}
+
+// LanguageHandler resolves the best supported language of every
+// request, from the user's profile or Accept-Language header, and
+// stores it within request context so it's resolved the same way
+// everywhere with LanguageFromRequest
+type LanguageHandler struct {
+	config LanguageHandlerConfig
+}
+
+// NewLanguageHandler returns *LanguageHandler
+func NewLanguageHandler(config LanguageHandlerConfig) *LanguageHandler {
+	if len(config.Supported) == 0 {
+		config.Supported = []string{DefaultLanguage}
+	}
+
+	return &LanguageHandler{config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (l *LanguageHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := ""
+
+		if l.config.UserLanguage != nil {
+			lang = MatchLanguage([]string{l.config.UserLanguage(r)}, l.config.Supported)
+		}
+		if lang == "" {
+			lang = MatchLanguage(ParseAcceptLanguage(r.Header.Get("Accept-Language")), l.config.Supported)
+		}
+		if lang == "" {
+			lang = l.config.Supported[0]
+		}
+
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(ctxutil.WithLanguage(r.Context(), lang)))
+	})
+}
+
+// LanguageFromRequest returns language of request set by
+// LanguageHandler
+// If LanguageHandler was not used, the most preferred language of the
+// Accept-Language header is returned, else DefaultLanguage
+func LanguageFromRequest(r *http.Request) string {
+	if lang := ctxutil.LanguageFrom(r.Context()); lang != "" {
+		return lang
+	}
+
+	if langs := ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(langs) > 0 {
+		return langs[0]
+	}
+
+	return DefaultLanguage
+}
+
+// ParseAcceptLanguage returns language tags of Accept-Language header
+// ordered by their quality, most preferred first
+// Wildcards and tags with a quality of 0 are excluded
+func ParseAcceptLanguage(header string) []string {
+	type langRange struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []langRange
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, langRange{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	tags := make([]string, 0, len(ranges))
+
+	for _, v := range ranges {
+		tags = append(tags, v.tag)
+	}
+
+	return tags
+}
+
+// MatchLanguage returns the first of supported that matches tags, in
+// order of tags, else empty string
+// Tags match case insensitively, either exactly or by their base
+// language, so "en-US" matches "en" and "en" matches "en-GB"
+func MatchLanguage(tags []string, supported []string) string {
+	for _, tag := range tags {
+		if tag == "" {
+			continue
+		}
+
+		for _, s := range supported {
+			if strings.EqualFold(tag, s) {
+				return s
+			}
+		}
+
+		base := baseLanguage(tag)
+
+		for _, s := range supported {
+			if strings.EqualFold(base, baseLanguage(s)) {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+func baseLanguage(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		return tag[:i]
+	}
+
+	return tag
+}
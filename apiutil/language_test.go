@@ -0,0 +1,61 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tags := ParseAcceptLanguage("fr;q=0.5, en-US, de;q=0, *;q=0.1, pt-BR;q=0.8")
+
+	if !reflect.DeepEqual(tags, []string{"en-US", "pt-BR", "fr"}) {
+		t.Errorf("should order tags by quality; got %v", tags)
+	}
+}
+
+func TestLanguageHandler(t *testing.T) {
+	var lang string
+
+	userLang := ""
+	handler := NewLanguageHandler(LanguageHandlerConfig{
+		Supported:    []string{"en", "pt-BR", "fr"},
+		UserLanguage: func(r *http.Request) string { return userLang },
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = LanguageFromRequest(r)
+	}))
+
+	tests := []struct {
+		accept   string
+		userLang string
+		expected string
+	}{
+		{accept: "pt, en;q=0.5", expected: "pt-BR"},
+		{accept: "de, fr-CA;q=0.9", expected: "fr"},
+		{accept: "de", expected: "en"},
+		{accept: "pt", userLang: "fr", expected: "fr"},
+		{accept: "pt", userLang: "de", expected: "pt-BR"},
+	}
+
+	for _, test := range tests {
+		userLang = test.userLang
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", test.accept)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if lang != test.expected {
+			t.Errorf("accept %s and user %s: got %s; want %s", test.accept, test.userLang, lang, test.expected)
+		}
+		if rr.Header().Get("Content-Language") != test.expected {
+			t.Errorf("should set Content-Language; got %s", rr.Header().Get("Content-Language"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if lang = LanguageFromRequest(req); lang != DefaultLanguage {
+		t.Errorf("should return default language; got %s", lang)
+	}
+}
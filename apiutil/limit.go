@@ -0,0 +1,349 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	requestTooLargeTxt = "Request body too large"
+	requestTimeoutTxt  = "Request timeout"
+
+	// DefaultMaxBodyBytes is default max size of request body
+	// used by LimitHandler
+	DefaultMaxBodyBytes int64 = 1 << 20
+)
+
+var (
+	// ErrBodyTooLarge is returned when reading request body that is
+	// larger than the limit set by LimitHandler
+	ErrBodyTooLarge = errors.New("apiutil: request body too large")
+
+	// ErrReadTimeout is returned when reading request body after
+	// LimitHandlerConfig#ReadTimeout has passed
+	ErrReadTimeout = errors.New("apiutil: request body read timeout")
+)
+
+// LimitHandlerConfig is config struct used for LimitHandler
+type LimitHandlerConfig struct {
+	// MaxBodyBytes is max size of request body for every route
+	// not within RouteMaxBodyBytes
+	// A negative value disables the limit
+	//
+	// Default value is DefaultMaxBodyBytes
+	MaxBodyBytes int64
+
+	// RouteMaxBodyBytes overrides MaxBodyBytes per route, eg. for upload
+	// routes, where key is path template returned from the PathRegex
+	// given to NewLimitHandler or the request path if PathRegex is nil
+	RouteMaxBodyBytes map[string]int64
+
+	// ReadTimeout is max time from the start of request to read the
+	// request body
+	// Reads after the timeout will return ErrReadTimeout
+	ReadTimeout time.Duration
+
+	// WriteTimeout is max time for next handler to write its response
+	// If exceeded, TimeoutResponse is written instead and anything
+	// written by next handler is discarded
+	WriteTimeout time.Duration
+
+	// TooLargeResponse is config used to respond to user if request
+	// body is larger than limit
+	//
+	// Default status value is http.StatusRequestEntityTooLarge
+	// Default response value is []byte("Request body too large")
+	TooLargeResponse HTTPResponseConfig
+
+	// TimeoutResponse is config used to respond to user if request
+	// exceeded ReadTimeout or WriteTimeout
+	//
+	// Default status value is http.StatusRequestTimeout
+	// Default response value is []byte("Request timeout")
+	TimeoutResponse HTTPResponseConfig
+}
+
+// LimitHandler limits size of request bodies and the time to handle
+// requests to protect things like formutil#CheckBodyAndDecode and
+// Middleware#LogEntryMiddleware, which read the whole body, from abuse
+//
+// If next handler reads past the limit of the body, TooLargeResponse is
+// written in place of whatever response next handler tries to write
+type LimitHandler struct {
+	pathRegex httputil.PathRegex
+	config    LimitHandlerConfig
+}
+
+// NewLimitHandler returns *LimitHandler
+// pathRegex is used to look up LimitHandlerConfig#RouteMaxBodyBytes
+// and can be nil
+func NewLimitHandler(pathRegex httputil.PathRegex, config LimitHandlerConfig) *LimitHandler {
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	setHTTPResponseDefaults(&config.TooLargeResponse, http.StatusRequestEntityTooLarge, []byte(requestTooLargeTxt))
+	setHTTPResponseDefaults(&config.TimeoutResponse, http.StatusRequestTimeout, []byte(requestTimeoutTxt))
+
+	return &LimitHandler{
+		pathRegex: pathRegex,
+		config:    config,
+	}
+}
+
+func (l *LimitHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maxBytes := l.maxBodyBytes(r)
+
+		if maxBytes >= 0 && r.ContentLength > maxBytes {
+			w.WriteHeader(*l.config.TooLargeResponse.HTTPStatus)
+			w.Write(l.config.TooLargeResponse.HTTPResponse)
+			return
+		}
+
+		lw := &limitResponseWriter{
+			ResponseWriter: w,
+			header:         make(http.Header),
+			config:         l.config,
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			lr := &limitReader{
+				ReadCloser: r.Body,
+				remaining:  maxBytes,
+				writer:     lw,
+			}
+
+			if l.config.ReadTimeout > 0 {
+				lr.deadline = time.Now().Add(l.config.ReadTimeout)
+			}
+
+			r.Body = lr
+		}
+
+		if l.config.WriteTimeout <= 0 {
+			lw.direct = true
+			next.ServeHTTP(lw, r)
+			lw.finish()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), l.config.WriteTimeout)
+		defer cancel()
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+
+			next.ServeHTTP(lw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			lw.mu.Lock()
+			defer lw.mu.Unlock()
+			lw.flush()
+		case <-ctx.Done():
+			lw.mu.Lock()
+			defer lw.mu.Unlock()
+			lw.timedOut = true
+
+			if !lw.wroteHeader || lw.status != *l.config.TooLargeResponse.HTTPStatus {
+				w.WriteHeader(*l.config.TimeoutResponse.HTTPStatus)
+				w.Write(l.config.TimeoutResponse.HTTPResponse)
+				return
+			}
+
+			lw.flush()
+		}
+	})
+}
+
+func (l *LimitHandler) maxBodyBytes(r *http.Request) int64 {
+	if len(l.config.RouteMaxBodyBytes) == 0 {
+		return l.config.MaxBodyBytes
+	}
+
+	route := r.URL.Path
+
+	if l.pathRegex != nil {
+		if pathExp, err := l.pathRegex(r); err == nil {
+			route = pathExp
+		}
+	}
+
+	if maxBytes, ok := l.config.RouteMaxBodyBytes[route]; ok {
+		return maxBytes
+	}
+
+	return l.config.MaxBodyBytes
+}
+
+// limitReader returns ErrBodyTooLarge once more than remaining bytes
+// are read and ErrReadTimeout once deadline has passed
+type limitReader struct {
+	io.ReadCloser
+	remaining int64
+	deadline  time.Time
+	writer    *limitResponseWriter
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if !lr.deadline.IsZero() && time.Now().After(lr.deadline) {
+		lr.writer.setOverride(lr.writer.config.TimeoutResponse)
+		return 0, ErrReadTimeout
+	}
+
+	if lr.remaining < 0 {
+		return lr.ReadCloser.Read(p)
+	}
+
+	// Read one byte past the limit to know if body is too large
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+
+	n, err := lr.ReadCloser.Read(p)
+
+	if int64(n) > lr.remaining {
+		n = int(lr.remaining)
+		lr.remaining = 0
+		lr.writer.setOverride(lr.writer.config.TooLargeResponse)
+		return n, ErrBodyTooLarge
+	}
+
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+// limitResponseWriter buffers the response of next handler when there
+// is a write timeout and replaces the response when the body was too
+// large or read timed out
+type limitResponseWriter struct {
+	http.ResponseWriter
+
+	mu          sync.Mutex
+	config      LimitHandlerConfig
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+	direct      bool
+	override    *HTTPResponseConfig
+}
+
+func (lw *limitResponseWriter) setOverride(config HTTPResponseConfig) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.override == nil && !lw.wroteHeader {
+		lw.override = &config
+		lw.writeHeader(*config.HTTPStatus)
+		lw.write(config.HTTPResponse)
+	}
+}
+
+func (lw *limitResponseWriter) Header() http.Header {
+	if lw.direct {
+		return lw.ResponseWriter.Header()
+	}
+
+	return lw.header
+}
+
+func (lw *limitResponseWriter) WriteHeader(status int) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.timedOut || lw.override != nil {
+		return
+	}
+
+	lw.writeHeader(status)
+}
+
+func (lw *limitResponseWriter) Write(b []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	// Pretend writes of next handler succeed as response was replaced
+	if lw.override != nil {
+		return len(b), nil
+	}
+
+	if !lw.wroteHeader {
+		lw.writeHeader(http.StatusOK)
+	}
+
+	return lw.write(b)
+}
+
+func (lw *limitResponseWriter) writeHeader(status int) {
+	if lw.wroteHeader {
+		return
+	}
+
+	lw.wroteHeader = true
+	lw.status = status
+
+	if lw.direct {
+		lw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (lw *limitResponseWriter) write(b []byte) (int, error) {
+	if lw.direct {
+		return lw.ResponseWriter.Write(b)
+	}
+
+	return lw.buf.Write(b)
+}
+
+// finish writes default status if next handler wrote nothing
+func (lw *limitResponseWriter) finish() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if !lw.wroteHeader {
+		lw.writeHeader(http.StatusOK)
+	}
+}
+
+// flush copies buffered response to underlying writer
+// Must be called with mu held
+func (lw *limitResponseWriter) flush() {
+	dst := lw.ResponseWriter.Header()
+
+	for k, v := range lw.header {
+		dst[k] = v
+	}
+
+	if !lw.wroteHeader {
+		lw.status = http.StatusOK
+	}
+
+	lw.ResponseWriter.WriteHeader(lw.status)
+	lw.ResponseWriter.Write(lw.buf.Bytes())
+}
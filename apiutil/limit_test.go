@@ -0,0 +1,88 @@
+package apiutil
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitHandler(t *testing.T) {
+	readHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		w.Write([]byte("ok"))
+	})
+	slowHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+
+		w.Write([]byte("slow"))
+	})
+
+	limit := NewLimitHandler(nil, LimitHandlerConfig{
+		MaxBodyBytes:      10,
+		RouteMaxBodyBytes: map[string]int64{"/upload": 100},
+	})
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		path           string
+		body           string
+		unknownLength  bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"under limit", limit.MiddlewareFunc(readHandler), "/", "small", false, http.StatusOK, "ok"},
+		{"content length over limit", limit.MiddlewareFunc(readHandler), "/", strings.Repeat("a", 11), false, http.StatusRequestEntityTooLarge, requestTooLargeTxt},
+		{"read over limit", limit.MiddlewareFunc(readHandler), "/", strings.Repeat("a", 11), true, http.StatusRequestEntityTooLarge, requestTooLargeTxt},
+		{"route override", limit.MiddlewareFunc(readHandler), "/upload", strings.Repeat("a", 50), false, http.StatusOK, "ok"},
+		{
+			"write timeout",
+			NewLimitHandler(nil, LimitHandlerConfig{WriteTimeout: 10 * time.Millisecond}).MiddlewareFunc(slowHandler),
+			"/",
+			"",
+			false,
+			http.StatusRequestTimeout,
+			requestTimeoutTxt,
+		},
+		{
+			"buffered within timeout",
+			NewLimitHandler(nil, LimitHandlerConfig{WriteTimeout: time.Second}).MiddlewareFunc(readHandler),
+			"/",
+			"small",
+			false,
+			http.StatusOK,
+			"ok",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+
+			if test.unknownLength {
+				req.ContentLength = -1
+			}
+
+			rr := httptest.NewRecorder()
+			test.handler.ServeHTTP(rr, req)
+
+			if rr.Code != test.expectedStatus {
+				v.Errorf("got status %d; want %d", rr.Code, test.expectedStatus)
+			}
+			if rr.Body.String() != test.expectedBody {
+				v.Errorf("got body %s; want %s", rr.Body.String(), test.expectedBody)
+			}
+		})
+	}
+}
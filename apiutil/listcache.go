@@ -0,0 +1,375 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// ListCacheKeyFormat is format of key of materialized page, where
+	// first %s is ListCacheConfig#Name, second is ListCacheView#Name
+	// and %d is index of page
+	ListCacheKeyFormat = "listcache:%s:%s:%d"
+
+	// ListCacheChannelFormat is format of pubsub channel of list cache,
+	// where %s is ListCacheConfig#Name
+	ListCacheChannelFormat = "listcache:%s"
+)
+
+var (
+	// ErrListCacheName is returned from NewListCache when
+	// ListCacheConfig#Name isn't set
+	ErrListCacheName = errors.New("apiutil: list cache name is required")
+)
+
+// ListCacheView is a common combination of list query params, eg.
+// filters of a default dashboard, whose pages are materialized
+type ListCacheView struct {
+	// Name identifies view within cache keys and write notifications
+	Name string
+
+	// Query is query params of view, excluding take and skip, which
+	// requests must match exactly to be served from cache
+	Query url.Values
+}
+
+// ListCacheConfig is config struct used for ListCache
+type ListCacheConfig struct {
+	// Name is prefix of cache keys and pubsub channel, which must be
+	// unique per list endpoint - Required
+	Name string
+
+	// CacheStore stores pre-serialized pages - Required
+	CacheStore cacheutil.CacheStore
+
+	// PubSub, if set, is used to publish write notifications from
+	// Notify so views are refreshed by every instance of app
+	// If not set, views are only refreshed by instance calling Notify
+	PubSub cacheutil.PubSub
+
+	// Views are materialized views of list endpoint
+	Views []ListCacheView
+
+	// Pages is number of pages materialized per view
+	// Default is 1
+	Pages int
+
+	// Take is size of materialized pages, where requests of any other
+	// take are served live
+	// Default is queryutil#DefaultTakeLimit
+	Take int
+
+	// TakeParam and SkipParam are names of take and skip query params
+	// Default is "take" and "skip"
+	TakeParam string
+	SkipParam string
+
+	// Path is path of requests used to render pages on refresh, which
+	// is used for eg. pagination links
+	// Default is "/"
+	Path string
+
+	// Expiration is how long pages are cached
+	// Default is 0 which doesn't expire pages
+	Expiration time.Duration
+}
+
+// listCachePage is materialized page as stored in cache
+type listCachePage struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// ListCache serves hot list endpoints, eg. Resource#List, from pages
+// materialized per common filter combination, see ListCacheView,
+// where requests that don't match a materialized page, or miss the
+// cache, are served live by the list handler
+// On a cache miss, the page is also rendered the same way Refresh does
+// and stored so the next request hits
+//
+// Writes should call Notify, eg. from ResourceHooks#AfterCreate, with
+// names of views they affect so only those views are refreshed, where
+// pages are replaced in place so requests never miss while refreshing
+//
+// Only lists whose results don't depend on the requesting user should
+// be cached as pages are shared by every user
+type ListCache struct {
+	handler http.Handler
+	config  ListCacheConfig
+	close   func() error
+}
+
+// NewListCache returns *ListCache serving list endpoint handler
+// If ListCacheConfig#PubSub is set, cache subscribes to its channel
+// until Close is called
+func NewListCache(handler http.Handler, config ListCacheConfig) (*ListCache, error) {
+	if config.Name == "" {
+		return nil, ErrListCacheName
+	}
+	if config.Pages <= 0 {
+		config.Pages = 1
+	}
+	if config.Take <= 0 {
+		config.Take = queryutil.DefaultTakeLimit
+	}
+	if config.TakeParam == "" {
+		config.TakeParam = "take"
+	}
+	if config.SkipParam == "" {
+		config.SkipParam = "skip"
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+
+	lc := &ListCache{handler: handler, config: config}
+
+	if config.PubSub != nil {
+		closeFn, err := config.PubSub.Subscribe(lc.channel(), func(message string) {
+			var views []string
+
+			if message != "" {
+				views = strings.Split(message, ",")
+			}
+
+			if err := lc.Refresh(views...); err != nil {
+				httputil.Logger.Errorf("apiutil: list cache refresh err: %s", err.Error())
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		lc.close = closeFn
+	}
+
+	return lc, nil
+}
+
+func (lc *ListCache) channel() string {
+	return fmt.Sprintf(ListCacheChannelFormat, lc.config.Name)
+}
+
+func (lc *ListCache) key(view string, page int) string {
+	return fmt.Sprintf(ListCacheKeyFormat, lc.config.Name, view, page)
+}
+
+// Notify notifies cache of a write affecting views, where no views
+// means every view, which are then refreshed by every subscribed
+// instance, or by this one if ListCacheConfig#PubSub isn't set
+func (lc *ListCache) Notify(views ...string) error {
+	if lc.config.PubSub != nil {
+		return lc.config.PubSub.Publish(lc.channel(), strings.Join(views, ","))
+	}
+
+	return lc.Refresh(views...)
+}
+
+// Refresh renders every page of views, or of every view if none are
+// passed, with the list handler and replaces their cached pages
+// Views that aren't configured are ignored
+func (lc *ListCache) Refresh(views ...string) error {
+	names := make(map[string]bool, len(views))
+
+	for _, v := range views {
+		names[v] = true
+	}
+
+	for _, view := range lc.config.Views {
+		if len(names) > 0 && !names[view.Name] {
+			continue
+		}
+
+		pages := make(map[string]interface{}, lc.config.Pages)
+
+		for i := 0; i < lc.config.Pages; i++ {
+			page, ok, err := lc.render(view, i)
+
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+
+			pages[lc.key(view.Name, i)] = page
+		}
+
+		if len(pages) == 0 {
+			continue
+		}
+
+		if err := lc.config.CacheStore.MSet(pages, lc.config.Expiration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// render renders page of view with the list handler and returns it
+// encoded, or false if it didn't respond with 200
+func (lc *ListCache) render(view ListCacheView, page int) ([]byte, bool, error) {
+	query := url.Values{}
+
+	for k, v := range view.Query {
+		query[k] = v
+	}
+
+	query.Set(lc.config.TakeParam, strconv.Itoa(lc.config.Take))
+	query.Set(lc.config.SkipParam, strconv.Itoa(page*lc.config.Take))
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, lc.config.Path+"?"+query.Encode(), nil)
+
+	if err != nil {
+		return nil, false, errors.Wrap(err, "")
+	}
+
+	rec := &listCacheRecorder{header: http.Header{}, status: http.StatusOK}
+	lc.handler.ServeHTTP(rec, r)
+
+	if rec.status != http.StatusOK {
+		return nil, false, nil
+	}
+
+	b, err := json.Marshal(listCachePage{Header: rec.header, Body: rec.body.Bytes()})
+	return b, err == nil, err
+}
+
+// match returns view and index of materialized page r requests, or
+// false if r doesn't request one
+func (lc *ListCache) match(r *http.Request) (ListCacheView, int, bool) {
+	if r.Method != http.MethodGet {
+		return ListCacheView{}, 0, false
+	}
+
+	query := r.URL.Query()
+	take, skip := lc.config.Take, 0
+
+	if v := query.Get(lc.config.TakeParam); v != "" {
+		var err error
+
+		if take, err = strconv.Atoi(v); err != nil {
+			return ListCacheView{}, 0, false
+		}
+	}
+	if v := query.Get(lc.config.SkipParam); v != "" {
+		var err error
+
+		if skip, err = strconv.Atoi(v); err != nil {
+			return ListCacheView{}, 0, false
+		}
+	}
+
+	if take != lc.config.Take || skip < 0 || skip%take != 0 || skip/take >= lc.config.Pages {
+		return ListCacheView{}, 0, false
+	}
+
+	query.Del(lc.config.TakeParam)
+	query.Del(lc.config.SkipParam)
+
+	for _, view := range lc.config.Views {
+		if reflect.DeepEqual(query, view.Query) || (len(query) == 0 && len(view.Query) == 0) {
+			return view, skip / take, true
+		}
+	}
+
+	return ListCacheView{}, 0, false
+}
+
+// ServeHTTP serves r from its materialized page if it has one, else
+// with the list handler
+func (lc *ListCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	view, index, ok := lc.match(r)
+
+	if !ok {
+		lc.handler.ServeHTTP(w, r)
+		return
+	}
+
+	key := lc.key(view.Name, index)
+
+	if b, err := lc.config.CacheStore.Get(key); err == nil {
+		var page listCachePage
+
+		if err = json.Unmarshal(b, &page); err == nil {
+			for k, v := range page.Header {
+				w.Header()[k] = v
+			}
+
+			if etag := page.Header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			w.Write(page.Body)
+			return
+		}
+	} else if err != cacheutil.ErrCacheNil {
+		httputil.Logger.Errorf("apiutil: list cache get err: %s", err.Error())
+	}
+
+	lc.handler.ServeHTTP(w, r)
+
+	// Page is rendered apart from r as response to r may depend on
+	// its context, headers or take and skip values, which must never
+	// be served to other users
+	page, ok, err := lc.render(view, index)
+
+	if err == nil && ok {
+		err = lc.config.CacheStore.SetErr(key, page, lc.config.Expiration)
+	}
+	if err != nil {
+		httputil.Logger.Errorf("apiutil: list cache set err: %s", err.Error())
+	}
+}
+
+// Close unsubscribes cache from ListCacheConfig#PubSub
+func (lc *ListCache) Close() error {
+	if lc.close == nil {
+		return nil
+	}
+
+	return lc.close()
+}
+
+// listCacheRecorder holds header, status and body of response rendered
+// by list handler in memory
+type listCacheRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *listCacheRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *listCacheRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *listCacheRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
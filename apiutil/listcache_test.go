@@ -0,0 +1,101 @@
+package apiutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestListCache(t *testing.T) {
+	calls := 0
+	version := 1
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"version":%d,"skip":"%s","status":"%s"}`, version, r.FormValue("skip"), r.FormValue("status"))
+	})
+
+	cache := cachetest.NewMemoryCache()
+	pubsub := cachetest.NewMemoryPubSub()
+	lc, err := NewListCache(handler, ListCacheConfig{
+		Name:       "orders",
+		CacheStore: cache,
+		PubSub:     pubsub,
+		Take:       10,
+		Pages:      2,
+		Views: []ListCacheView{
+			{Name: "all"},
+			{Name: "open", Query: url.Values{"status": []string{"open"}}},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	defer lc.Close()
+
+	serve := func(target string) string {
+		rr := httptest.NewRecorder()
+		lc.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+		}
+		if rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("should keep headers; got %v", rr.Header())
+		}
+
+		return rr.Body.String()
+	}
+
+	if err = lc.Refresh(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if calls != 4 {
+		t.Fatalf("should render every page of every view; got %d calls", calls)
+	}
+
+	if body := serve("/orders?status=open&skip=10"); body != `{"version":1,"skip":"10","status":"open"}` || calls != 4 {
+		t.Errorf("should serve materialized page; got %s with %d calls", body, calls)
+	}
+
+	serve("/orders?status=closed")
+	serve("/orders?skip=20")
+	serve("/orders?take=5")
+
+	if calls != 7 {
+		t.Errorf("should serve requests without materialized page live; got %d calls", calls)
+	}
+
+	version = 2
+
+	if err = lc.Notify("open"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if calls != 9 {
+		t.Errorf("should only refresh notified view; got %d calls", calls)
+	}
+	if body := serve("/orders?status=open"); body != `{"version":2,"skip":"0","status":"open"}` {
+		t.Errorf("should serve refreshed page; got %s", body)
+	}
+	if body := serve("/orders"); body != `{"version":1,"skip":"0","status":""}` {
+		t.Errorf("should not refresh other views; got %s", body)
+	}
+
+	cache.Del("listcache:orders:all:0")
+
+	serve("/orders")
+
+	if body := serve("/orders"); body != `{"version":2,"skip":"0","status":""}` || calls != 11 {
+		t.Errorf("should store page rendered on miss; got %s with %d calls", body, calls)
+	}
+
+	if _, err = NewListCache(handler, ListCacheConfig{}); err != ErrListCacheName {
+		t.Errorf("should require name; got %v", err)
+	}
+}
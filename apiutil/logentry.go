@@ -0,0 +1,212 @@
+package apiutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultRedactValue is value redacted fields are replaced with
+	DefaultRedactValue = "***"
+)
+
+// LogEntryConfig is config used by Middleware#LogEntryMiddleware to
+// determine what of the request body is passed to Middleware#LogInserter
+type LogEntryConfig struct {
+	// RedactFields are fields of json or url encoded bodies that will
+	// have their values replaced with RedactValue
+	// Entries can either be a key name, which is matched at any depth,
+	// or a dotted path from the root like "user.password"
+	// Matching is case insensitive
+	RedactFields []string
+
+	// RedactValue is value redacted fields are replaced with
+	// Default value is DefaultRedactValue
+	RedactValue string
+
+	// MaxPayloadBytes is max size of body that is captured
+	// Bodies larger than this are not captured and are summarized instead
+	// as the body can't be redacted reliably
+	// The request body passed to next handler is not affected
+	// Default value is no limit
+	MaxPayloadBytes int64
+
+	// ExcludeContentTypes are media types, like "multipart/form-data",
+	// whose bodies are not captured
+	// Default value is []string{"multipart/form-data"}
+	ExcludeContentTypes []string
+
+	// SummarizeExcluded will log a json summary of the content type and
+	// length for excluded and oversized bodies instead of an empty payload
+	SummarizeExcluded bool
+}
+
+// payloadSummary is logged in place of bodies that are not captured
+type payloadSummary struct {
+	ContentType   string `json:"content_type"`
+	ContentLength int64  `json:"content_length"`
+	Truncated     bool   `json:"truncated,omitempty"`
+}
+
+// captureLogPayload reads body of r for logging based on config while
+// leaving the body intact for the next handler
+func captureLogPayload(r *http.Request, config LogEntryConfig) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	excludeTypes := config.ExcludeContentTypes
+
+	if excludeTypes == nil {
+		excludeTypes = []string{"multipart/form-data"}
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	for _, v := range excludeTypes {
+		if strings.EqualFold(mediaType, v) {
+			return summarizePayload(config, payloadSummary{
+				ContentType:   mediaType,
+				ContentLength: r.ContentLength,
+			})
+		}
+	}
+
+	var payload []byte
+
+	if config.MaxPayloadBytes > 0 {
+		payload, _ = ioutil.ReadAll(io.LimitReader(r.Body, config.MaxPayloadBytes+1))
+		r.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(payload), r.Body),
+			Closer: r.Body,
+		}
+
+		if int64(len(payload)) > config.MaxPayloadBytes {
+			return summarizePayload(config, payloadSummary{
+				ContentType:   mediaType,
+				ContentLength: r.ContentLength,
+				Truncated:     true,
+			})
+		}
+	} else {
+		payload, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(payload))
+	}
+
+	if len(config.RedactFields) == 0 {
+		return payload
+	}
+
+	redactValue := config.RedactValue
+
+	if redactValue == "" {
+		redactValue = DefaultRedactValue
+	}
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		return RedactFormPayload(payload, config.RedactFields, redactValue)
+	}
+
+	return RedactJSONPayload(payload, config.RedactFields, redactValue)
+}
+
+func summarizePayload(config LogEntryConfig, summary payloadSummary) []byte {
+	if !config.SummarizeExcluded {
+		return nil
+	}
+
+	payload, _ := json.Marshal(summary)
+	return payload
+}
+
+// RedactJSONPayload replaces values of fields within json payload with
+// redactValue where fields are either key names or dotted paths
+// If payload is not valid json, payload is returned as is
+func RedactJSONPayload(payload []byte, fields []string, redactValue string) []byte {
+	var val interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	if err := dec.Decode(&val); err != nil {
+		return payload
+	}
+
+	redacted, err := json.Marshal(redactJSONValue(val, "", redactFieldSet(fields), redactValue))
+
+	if err != nil {
+		return payload
+	}
+
+	return redacted
+}
+
+// RedactFormPayload replaces values of fields within url encoded
+// payload with redactValue
+// If payload can't be parsed, payload is returned as is
+func RedactFormPayload(payload []byte, fields []string, redactValue string) []byte {
+	values, err := url.ParseQuery(string(payload))
+
+	if err != nil {
+		return payload
+	}
+
+	fieldSet := redactFieldSet(fields)
+
+	for k, v := range values {
+		if fieldSet[strings.ToLower(k)] {
+			for i := range v {
+				v[i] = redactValue
+			}
+		}
+	}
+
+	return []byte(values.Encode())
+}
+
+func redactFieldSet(fields []string) map[string]bool {
+	fieldSet := make(map[string]bool, len(fields))
+
+	for _, v := range fields {
+		fieldSet[strings.ToLower(v)] = true
+	}
+
+	return fieldSet
+}
+
+func redactJSONValue(val interface{}, path string, fields map[string]bool, redactValue string) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			itemPath := strings.ToLower(k)
+
+			if path != "" {
+				itemPath = path + "." + itemPath
+			}
+
+			if fields[strings.ToLower(k)] || fields[itemPath] {
+				v[k] = redactValue
+			} else {
+				v[k] = redactJSONValue(item, itemPath, fields, redactValue)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSONValue(item, path, fields, redactValue)
+		}
+	}
+
+	return val
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,84 @@
+package apiutil
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureLogPayload(t *testing.T) {
+	config := LogEntryConfig{
+		RedactFields:      []string{"password", "user.ssn"},
+		MaxPayloadBytes:   100,
+		SummarizeExcluded: true,
+	}
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		expectedPayload string
+	}{
+		{
+			"json",
+			"application/json",
+			`{"email":"foo@example.com","Password":"secret","user":{"ssn":"123","name":"foo"},"ssn":"456"}`,
+			`{"Password":"***","email":"foo@example.com","ssn":"456","user":{"name":"foo","ssn":"***"}}`,
+		},
+		{
+			"nested array",
+			"application/json",
+			`[{"password":"secret","id":1}]`,
+			`[{"id":1,"password":"***"}]`,
+		},
+		{
+			"form",
+			"application/x-www-form-urlencoded",
+			"email=foo&password=secret",
+			"email=foo&password=%2A%2A%2A",
+		},
+		{
+			"multipart",
+			"multipart/form-data; boundary=foo",
+			"--foo--",
+			`{"content_type":"multipart/form-data","content_length":7}`,
+		},
+		{
+			"too large",
+			"application/json",
+			`{"data":"` + strings.Repeat("a", 100) + `"}`,
+			`{"content_type":"application/json","content_length":111,"truncated":true}`,
+		},
+		{
+			"invalid json",
+			"application/json",
+			`{"password":`,
+			`{"password":`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+
+			payload := captureLogPayload(req, config)
+
+			if string(payload) != test.expectedPayload {
+				v.Errorf("got payload %s; want %s", payload, test.expectedPayload)
+			}
+
+			body, err := ioutil.ReadAll(req.Body)
+
+			if err != nil {
+				v.Fatalf("should not have error; got %s", err.Error())
+			}
+
+			if string(body) != test.body {
+				v.Errorf("body should be intact; got %s", body)
+			}
+		})
+	}
+}
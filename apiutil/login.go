@@ -0,0 +1,391 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/authutil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+)
+
+const (
+	// LoginAttemptsKey is used as a key when storing number of failed
+	// login attempts of a username in cache
+	LoginAttemptsKey = "%s-login-attempts"
+
+	// LoginIPAttemptsKey is used as a key when storing number of login
+	// attempts of a client ip in cache
+	LoginIPAttemptsKey = "%s-login-ip-attempts"
+
+	invalidCredentialsTxt = "Invalid username or password"
+	lockedOutTxt          = "Too many failed login attempts, try again later"
+)
+
+// LoginCredentials is form decoded from body of login requests
+type LoginCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginUser is user returned from LoginHandlerConfig#QueryUser
+type LoginUser struct {
+	// ID is id of user
+	ID string
+
+	// PasswordHash is bcrypt hash of password of user
+	PasswordHash string
+
+	// Payload is json of user that's stored in session and sent as
+	// response, which should have "id" and "email" fields so it can be
+	// read by AuthHandler
+	Payload []byte
+}
+
+// LoginEvent is passed to LoginHandlerConfig#AuditLog for every login
+// attempt
+type LoginEvent struct {
+	Username  string
+	UserID    string
+	IP        string
+	Success   bool
+	LockedOut bool
+	Time      time.Time
+}
+
+// LoginHandlerConfig is config struct used for LoginHandler
+type LoginHandlerConfig struct {
+	// QueryUser returns user with username along with their password
+	// hash
+	// Should return sql.ErrNoRows if user doesn't exist
+	QueryUser func(db httputil.Querier, username string) (LoginUser, error)
+
+	// SessionStore is store session of user is created in
+	SessionStore cacheutil.SessionStore
+
+	// SessionConfig is session name and keys, which should be the same as
+	// AuthHandlerConfig#SessionConfig
+	SessionConfig cacheutil.SessionConfig
+
+	// InsertSession is optional function that's called after session
+	// is created, which can be used to store session in database
+	InsertSession func(db httputil.DBInterfaceV2, r *http.Request, userID, sessionID string) error
+
+	// Cache is used to track failed login attempts
+	// If nil, attempts are not limited
+	Cache cacheutil.CacheStore
+
+	// MaxAttempts is number of failed attempts of a username before it's
+	// locked out for LockoutDuration
+	// Default value is 5
+	MaxAttempts int
+
+	// MaxIPAttempts is number of attempts of a client ip, successful or
+	// not, within LockoutDuration before it's rate limited
+	// Default value is 20
+	MaxIPAttempts int
+
+	// LockoutDuration is how long usernames and client ips are locked out
+	// for and how long failed attempts are counted for
+	// Default value is 15 minutes
+	LockoutDuration time.Duration
+
+	// Clock is what time of LoginEvent is taken from
+	// Lockouts expire with keys of Cache so tests can expire them with
+	// the clock of the cache eg. cachetest#MemoryCache#SetClock
+	// Default is httputil#RealClock
+	Clock httputil.Clock
+
+	// VerifyPassword compares password against hash of user
+	// Default is authutil#CheckPassword
+	VerifyPassword func(hash, password string) error
+
+	// AuditLog is called for every login attempt
+	// Default logs attempts with httputil#Logger
+	AuditLog func(r *http.Request, event LoginEvent)
+
+	// InvalidCredentialsResponse is config used to respond to user if
+	// credentials are missing or don't match
+	//
+	// Default status value is http.StatusUnauthorized
+	// Default response value is []byte("Invalid username or password")
+	InvalidCredentialsResponse HTTPResponseConfig
+
+	// LockedOutResponse is config used to respond to user if username or
+	// client ip is locked out
+	// Retry-After header is set to remaining time of lockout
+	//
+	// Default status value is http.StatusTooManyRequests
+	// Default response value is []byte("Too many failed login attempts, try again later")
+	LockedOutResponse HTTPResponseConfig
+
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// LoginHandler is login endpoint that decodes LoginCredentials, locks out
+// usernames and client ips after too many attempts, verifies password of
+// user, creates session, sets csrf token and sends user as response
+type LoginHandler struct {
+	db     httputil.DBInterfaceV2
+	config LoginHandlerConfig
+}
+
+// NewLoginHandler returns *LoginHandler
+func NewLoginHandler(db httputil.DBInterfaceV2, config LoginHandlerConfig) *LoginHandler {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.MaxIPAttempts <= 0 {
+		config.MaxIPAttempts = 20
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = time.Minute * 15
+	}
+	if config.VerifyPassword == nil {
+		config.VerifyPassword = authutil.CheckPassword
+	}
+	if config.AuditLog == nil {
+		config.AuditLog = logLoginEvent
+	}
+	if config.Clock == nil {
+		config.Clock = httputil.RealClock
+	}
+
+	setHTTPResponseDefaults(&config.InvalidCredentialsResponse, http.StatusUnauthorized, []byte(invalidCredentialsTxt))
+	setHTTPResponseDefaults(&config.LockedOutResponse, http.StatusTooManyRequests, []byte(lockedOutTxt))
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &LoginHandler{
+		db:     db,
+		config: config,
+	}
+}
+
+func (l *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var creds LoginCredentials
+
+	if HasBodyError(w, r) {
+		return
+	}
+	if HasDecodeError(w, DecodeBody(r, &creds)) {
+		return
+	}
+
+	event := LoginEvent{
+		Username: strings.ToLower(strings.TrimSpace(creds.Username)),
+		IP:       ClientIP(r),
+		Time:     l.config.Clock.Now(),
+	}
+
+	if event.Username == "" || creds.Password == "" {
+		l.config.AuditLog(r, event)
+		writeHTTPResponse(w, l.config.InvalidCredentialsResponse)
+		return
+	}
+
+	if locked, retryAfter := l.lockedOut(event); locked {
+		event.LockedOut = true
+		l.config.AuditLog(r, event)
+		setRetryAfter(w, retryAfter)
+		writeHTTPResponse(w, l.config.LockedOutResponse)
+		return
+	}
+
+	user, err := l.config.QueryUser(l.db, event.Username)
+
+	if err != nil && err != sql.ErrNoRows {
+		httputil.Logger.Errorf("login query for user err: %s", err.Error())
+		writeHTTPResponse(w, l.config.ServerErrResponse)
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		// Password is still verified against a dummy hash so response
+		// time doesn't reveal whether username exists
+		l.config.VerifyPassword(confutil.HashPassword, creds.Password)
+	} else {
+		event.UserID = user.ID
+		err = l.config.VerifyPassword(user.PasswordHash, creds.Password)
+	}
+
+	if err != nil {
+		if err != sql.ErrNoRows && err != authutil.ErrInvalidPassword {
+			httputil.Logger.Errorf("login verify password err: %s", err.Error())
+		}
+
+		l.failedAttempt(event)
+		l.config.AuditLog(r, event)
+		writeHTTPResponse(w, l.config.InvalidCredentialsResponse)
+		return
+	}
+
+	if err = l.createSession(w, r, user); err != nil {
+		httputil.Logger.Errorf("login create session err: %s", err.Error())
+		writeHTTPResponse(w, l.config.ServerErrResponse)
+		return
+	}
+
+	if l.config.Cache != nil {
+		l.config.Cache.Del(fmt.Sprintf(LoginAttemptsKey, event.Username))
+	}
+
+	event.Success = true
+	l.config.AuditLog(r, event)
+
+	SetToken(w, r)
+	w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+	w.Write(user.Payload)
+}
+
+// lockedOut returns whether username or client ip of event is locked
+// out, counting attempt of client ip, along with remaining time of
+// lockout
+// Attempts are not limited if cache is down
+func (l *LoginHandler) lockedOut(event LoginEvent) (bool, time.Duration) {
+	if l.config.Cache == nil {
+		return false, 0
+	}
+
+	lockoutKey := fmt.Sprintf(confutil.LockoutKey, event.Username)
+
+	if locked, err := l.config.Cache.HasKey(lockoutKey); err == nil && locked {
+		return true, l.retryAfter(lockoutKey)
+	}
+
+	if event.IP != "" {
+		ipKey := fmt.Sprintf(LoginIPAttemptsKey, event.IP)
+
+		if l.incrAttempts(ipKey) > l.config.MaxIPAttempts {
+			return true, l.retryAfter(ipKey)
+		}
+	}
+
+	return false, 0
+}
+
+// retryAfter returns remaining time to live of lockout key, falling
+// back to LoginHandlerConfig#LockoutDuration if it can't be determined
+func (l *LoginHandler) retryAfter(key string) time.Duration {
+	if ttl, err := l.config.Cache.TTL(key); err == nil && ttl > 0 {
+		return ttl
+	}
+
+	return l.config.LockoutDuration
+}
+
+// setRetryAfter sets Retry-After header to d, rounded up to seconds so
+// clients never retry before d has passed
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int((d + time.Second - 1) / time.Second)
+
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// failedAttempt counts failed attempt of username of event, locking it
+// out once LoginHandlerConfig#MaxAttempts is reached
+func (l *LoginHandler) failedAttempt(event LoginEvent) {
+	if l.config.Cache == nil {
+		return
+	}
+
+	if l.incrAttempts(fmt.Sprintf(LoginAttemptsKey, event.Username)) >= l.config.MaxAttempts {
+		l.config.Cache.Set(fmt.Sprintf(confutil.LockoutKey, event.Username), true, l.config.LockoutDuration)
+		l.config.Cache.Del(fmt.Sprintf(LoginAttemptsKey, event.Username))
+	}
+}
+
+// incrAttempts increments attempts stored under key and returns the new
+// count
+// CacheStore doesn't support atomic increments so concurrent attempts
+// can be undercounted, which is acceptable for limiting brute force
+func (l *LoginHandler) incrAttempts(key string) int {
+	attempts := 0
+
+	if b, err := l.config.Cache.Get(key); err == nil {
+		attempts, _ = strconv.Atoi(string(b))
+	}
+
+	attempts++
+	l.config.Cache.Set(key, attempts, l.config.LockoutDuration)
+	return attempts
+}
+
+// createSession stores payload of user in new session and calls
+// LoginHandlerConfig#InsertSession if set
+func (l *LoginHandler) createSession(w http.ResponseWriter, r *http.Request, user LoginUser) error {
+	sessionID, err := newUserSession(w, r, l.config.SessionStore, l.config.SessionConfig, user)
+
+	if err != nil {
+		return err
+	}
+
+	if l.config.InsertSession != nil {
+		return l.config.InsertSession(l.db, r, user.ID, sessionID)
+	}
+
+	return nil
+}
+
+// newUserSession stores payload of user in new session, discarding any
+// session sent with request to prevent session fixation
+// Returns id of new session
+func newUserSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	store cacheutil.SessionStore,
+	sessionConfig cacheutil.SessionConfig,
+	user LoginUser,
+) (string, error) {
+	session, err := store.New(r, sessionConfig.SessionName)
+
+	if session == nil {
+		return "", err
+	}
+
+	session.ID = ""
+	session.IsNew = true
+	session.Values = map[interface{}]interface{}{
+		sessionConfig.Keys.UserKey: sessionConfig.EncodeValue(user.Payload),
+	}
+
+	if err = session.Save(r, w); err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+func logLoginEvent(r *http.Request, event LoginEvent) {
+	entry := httputil.Logger.WithFields(logrus.Fields{
+		"username":   event.Username,
+		"user_id":    event.UserID,
+		"ip":         event.IP,
+		"success":    event.Success,
+		"locked_out": event.LockedOut,
+	})
+
+	if event.Success {
+		entry.Info("login")
+	} else {
+		entry.Warn("login failed")
+	}
+}
@@ -0,0 +1,156 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/authutil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestLoginHandler(t *testing.T) {
+	hash, err := authutil.HashPassword("secret")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	store := cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))
+	sessionConfig := cacheutil.SessionConfig{
+		SessionName: cookieName,
+		Keys:        cacheutil.SessionKeys{UserKey: cookieName},
+	}
+
+	var events []LoginEvent
+	var insertedSession string
+
+	start := time.Date(2019, time.March, 4, 12, 0, 0, 0, time.UTC)
+	clock := httputil.NewMockClock(start)
+	cache := cachetest.NewMemoryCache()
+	cache.SetClock(clock)
+
+	h := NewLoginHandler(nil, LoginHandlerConfig{
+		QueryUser: func(db httputil.Querier, username string) (LoginUser, error) {
+			if username != "foo@example.com" {
+				return LoginUser{}, sql.ErrNoRows
+			}
+
+			return LoginUser{
+				ID:           "1",
+				PasswordHash: hash,
+				Payload:      []byte(`{"id":"1","email":"foo@example.com"}`),
+			}, nil
+		},
+		SessionStore:  store,
+		SessionConfig: sessionConfig,
+		InsertSession: func(db httputil.DBInterfaceV2, r *http.Request, userID, sessionID string) error {
+			insertedSession = sessionID
+			return nil
+		},
+		Cache:       cache,
+		Clock:       clock,
+		MaxAttempts: 2,
+		AuditLog: func(r *http.Request, event LoginEvent) {
+			events = append(events, event)
+		},
+	})
+
+	login := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	rr := login(`{"username": " Foo@example.com ", "password": "secret"}`)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if rr.Body.String() != `{"id":"1","email":"foo@example.com"}` {
+		t.Errorf("should send user payload; got %s", rr.Body.String())
+	}
+	if insertedSession == "" {
+		t.Errorf("should insert session")
+	}
+
+	// Session created by login is read by AuthHandler
+	var ctxUser middlewareUser
+
+	auth := NewAuthHandler(nil, nil, AuthHandlerConfig{
+		SessionStore:  store,
+		SessionConfig: sessionConfig,
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u := r.Context().Value(MiddlewareUserCtxKey); u != nil {
+			ctxUser = u.(middlewareUser)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	auth.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ctxUser.ID != "1" {
+		t.Errorf("user id should be 1; got %s", ctxUser.ID)
+	}
+
+	if rr = login(`{"username": "foo@example.com", "password": ""}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf(statusErrTxt, http.StatusUnauthorized, rr.Code)
+	}
+	if rr = login(`{"username": "bar@example.com", "password": "secret"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf(statusErrTxt, http.StatusUnauthorized, rr.Code)
+	}
+
+	// Username is locked out after MaxAttempts even with right password
+	for i := 0; i < 2; i++ {
+		if rr = login(`{"username": "foo@example.com", "password": "wrong"}`); rr.Code != http.StatusUnauthorized {
+			t.Errorf(statusErrTxt, http.StatusUnauthorized, rr.Code)
+		}
+	}
+
+	if rr = login(`{"username": "foo@example.com", "password": "secret"}`); rr.Code != http.StatusTooManyRequests {
+		t.Errorf(statusErrTxt, http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "900" {
+		t.Errorf("should retry after 900 seconds; got %s", rr.Header().Get("Retry-After"))
+	}
+
+	// Retry-After counts down with clock of cache
+	clock.Add(time.Minute*10 + time.Millisecond*500)
+
+	if rr = login(`{"username": "foo@example.com", "password": "secret"}`); rr.Header().Get("Retry-After") != "300" {
+		t.Errorf("should retry after 300 seconds; got %s", rr.Header().Get("Retry-After"))
+	}
+
+	if len(events) != 7 {
+		t.Fatalf("should log 7 events; got %d", len(events))
+	}
+	if !events[0].Success || events[0].UserID != "1" || events[0].Username != "foo@example.com" {
+		t.Errorf("first event should be successful login; got %+v", events[0])
+	}
+	if !events[5].LockedOut {
+		t.Errorf("event should be locked out; got %+v", events[5])
+	}
+	if !events[5].Time.Equal(start) {
+		t.Errorf("event time should be time of clock; got %s", events[5].Time)
+	}
+
+	// Lockout expires after LockoutDuration
+	clock.Add(time.Minute * 6)
+
+	if rr = login(`{"username": "foo@example.com", "password": "secret"}`); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+}
@@ -0,0 +1,178 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+const (
+	loggedOutTxt = "Logged out"
+)
+
+// LogoutEvent is passed to LogoutHandlerConfig#AuditLog for every logout
+type LogoutEvent struct {
+	UserID    string
+	SessionID string
+	IP        string
+	Time      time.Time
+}
+
+// LogoutHandlerConfig is config struct used for LogoutHandler
+type LogoutHandlerConfig struct {
+	// SessionStore is store session of user is deleted from
+	SessionStore cacheutil.SessionStore
+
+	// SessionConfig is session name and keys, which should be the same as
+	// AuthHandlerConfig#SessionConfig
+	SessionConfig cacheutil.SessionConfig
+
+	// DeleteSession is optional function that's called before session is
+	// deleted from SessionStore, which can be used to delete session
+	// stored in database by LoginHandlerConfig#InsertSession
+	DeleteSession func(db httputil.DBInterfaceV2, r *http.Request, userID, sessionID string) error
+
+	// ClearCookies are names of cookies, along with the session cookie,
+	// that are cleared eg. csrf cookie
+	ClearCookies []string
+
+	// AuditLog is called for every logout of a logged in user
+	// Default logs logouts with httputil#Logger
+	AuditLog func(r *http.Request, event LogoutEvent)
+
+	// LogoutResponse is config used to respond to user once logged out
+	//
+	// Default status value is http.StatusOK
+	// Default response value is []byte("Logged out")
+	LogoutResponse HTTPResponseConfig
+
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// LogoutHandler is logout endpoint that deletes session of user from
+// SessionStore and database and clears session cookies
+// Requests without a valid session are still responded to as logged out
+type LogoutHandler struct {
+	db     httputil.DBInterfaceV2
+	config LogoutHandlerConfig
+}
+
+// NewLogoutHandler returns *LogoutHandler
+func NewLogoutHandler(db httputil.DBInterfaceV2, config LogoutHandlerConfig) *LogoutHandler {
+	if config.AuditLog == nil {
+		config.AuditLog = logLogoutEvent
+	}
+
+	setHTTPResponseDefaults(&config.LogoutResponse, http.StatusOK, []byte(loggedOutTxt))
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &LogoutHandler{
+		db:     db,
+		config: config,
+	}
+}
+
+func (l *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session, err := l.config.SessionStore.Get(r, l.config.SessionConfig.SessionName)
+
+	if err != nil && !isCookieDecodeError(err) {
+		httputil.Logger.Errorf("logout get session err: %s", err.Error())
+		writeHTTPResponse(w, l.config.ServerErrResponse)
+		return
+	}
+
+	if err == nil && !session.IsNew {
+		event := LogoutEvent{
+			UserID:    l.sessionUserID(r, session.Values[l.config.SessionConfig.Keys.UserKey]),
+			SessionID: session.ID,
+			IP:        ClientIP(r),
+			Time:      time.Now(),
+		}
+
+		if l.config.DeleteSession != nil {
+			if err = l.config.DeleteSession(l.db, r, event.UserID, session.ID); err != nil {
+				httputil.Logger.Errorf("logout delete session err: %s", err.Error())
+				writeHTTPResponse(w, l.config.ServerErrResponse)
+				return
+			}
+		}
+
+		opts := sessions.Options{Path: "/"}
+
+		if session.Options != nil {
+			opts = *session.Options
+		}
+
+		opts.MaxAge = -1
+		session.Options = &opts
+
+		if err = session.Save(r, w); err != nil {
+			httputil.Logger.Errorf("logout save session err: %s", err.Error())
+			writeHTTPResponse(w, l.config.ServerErrResponse)
+			return
+		}
+
+		l.config.AuditLog(r, event)
+	} else {
+		clearCookie(w, l.config.SessionConfig.SessionName)
+	}
+
+	for _, name := range l.config.ClearCookies {
+		clearCookie(w, name)
+	}
+
+	writeHTTPResponse(w, l.config.LogoutResponse)
+}
+
+// sessionUserID returns id of user stored in session, falling back to
+// user set by AuthHandler
+func (l *LogoutHandler) sessionUserID(r *http.Request, val interface{}) string {
+	var user middlewareUser
+
+	if b, ok := val.([]byte); ok {
+		if data, _, err := l.config.SessionConfig.DecodeValue(b); err == nil {
+			if err = json.Unmarshal(data, &user); err == nil {
+				return user.ID
+			}
+		}
+	}
+
+	return GetUserID(r)
+}
+
+func isCookieDecodeError(err error) bool {
+	cookieErr, ok := err.(securecookie.Error)
+	return ok && cookieErr.IsDecode()
+}
+
+func clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:    name,
+		Value:   "",
+		Path:    "/",
+		MaxAge:  -1,
+		Expires: time.Unix(1, 0),
+	})
+}
+
+func logLogoutEvent(r *http.Request, event LogoutEvent) {
+	httputil.Logger.WithFields(logrus.Fields{
+		"user_id": event.UserID,
+		"ip":      event.IP,
+	}).Info("logout")
+}
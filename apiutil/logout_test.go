@@ -0,0 +1,101 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestLogoutHandler(t *testing.T) {
+	store := cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))
+	sessionConfig := cacheutil.SessionConfig{
+		SessionName: cookieName,
+		Keys:        cacheutil.SessionKeys{UserKey: cookieName},
+	}
+
+	var events []LogoutEvent
+	var deletedUserID string
+
+	h := NewLogoutHandler(nil, LogoutHandlerConfig{
+		SessionStore:  store,
+		SessionConfig: sessionConfig,
+		DeleteSession: func(db httputil.DBInterfaceV2, r *http.Request, userID, sessionID string) error {
+			deletedUserID = userID
+			return nil
+		},
+		ClearCookies: []string{"_gorilla_csrf"},
+		AuditLog: func(r *http.Request, event LogoutEvent) {
+			events = append(events, event)
+		},
+	})
+
+	cookie, err := store.CreateSession(cookieName, map[interface{}]interface{}{
+		cookieName: sessionConfig.EncodeValue([]byte(`{"id":"1"}`)),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	logout := func(c *http.Cookie) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+
+		if c != nil {
+			req.AddCookie(c)
+		}
+
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := logout(cookie)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if deletedUserID != "1" {
+		t.Errorf("should delete session of user 1; got %s", deletedUserID)
+	}
+	if len(events) != 1 || events[0].UserID != "1" {
+		t.Errorf("should log logout of user 1; got %+v", events)
+	}
+
+	cleared := map[string]bool{}
+
+	for _, c := range rr.Result().Cookies() {
+		if c.MaxAge < 0 {
+			cleared[c.Name] = true
+		}
+	}
+
+	if !cleared[cookieName] || !cleared["_gorilla_csrf"] {
+		t.Errorf("should clear session and csrf cookies; got %v", cleared)
+	}
+
+	// Session is deleted from store
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.AddCookie(cookie)
+
+	if session, _ := store.New(req, cookieName); !session.IsNew {
+		t.Errorf("session should be deleted from store")
+	}
+
+	// Requests without session are still logged out
+	if rr = logout(nil); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if rr = logout(&http.Cookie{Name: cookieName, Value: "invalid"}); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if len(events) != 1 {
+		t.Errorf("should only log logouts of logged in users; got %d", len(events))
+	}
+}
@@ -0,0 +1,129 @@
+package apiutil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+const (
+	// MaintenanceKey is key used to store maintenance flag in cache
+	MaintenanceKey = "maintenance"
+
+	maintenanceTxt = `{"error": "Service is down for maintenance"}`
+)
+
+// EnableMaintenance turns on maintenance mode for every MaintenanceHandler
+// using cache, until DisableMaintenance is called
+// retryAfter is sent with the Retry-After header and can be 0 to use
+// the default of the handler
+// Returns error if flag could not be set
+func EnableMaintenance(cache cacheutil.CacheStore, retryAfter time.Duration) error {
+	return cache.SetErr(MaintenanceKey, strconv.Itoa(int(retryAfter.Seconds())), 0)
+}
+
+// DisableMaintenance turns off maintenance mode set by EnableMaintenance
+func DisableMaintenance(cache cacheutil.CacheStore) {
+	cache.Del(MaintenanceKey)
+}
+
+// MaintenanceHandlerConfig is config struct used for MaintenanceHandler
+type MaintenanceHandlerConfig struct {
+	// CacheStore is checked on every request for the flag set by
+	// EnableMaintenance
+	CacheStore cacheutil.CacheStore
+
+	// Enabled turns on maintenance mode regardless of cache
+	Enabled bool
+
+	// ExemptURLs are path prefixes that are still served during
+	// maintenance, eg. health checks or admin routes
+	ExemptURLs []string
+
+	// RetryAfter is default value of the Retry-After header
+	// Default value is 5 minutes
+	RetryAfter time.Duration
+
+	// ContentType is content type of MaintenanceResponse
+	// Default value is "application/json"
+	ContentType string
+
+	// MaintenanceResponse is config used to respond to user during
+	// maintenance
+	//
+	// Default status value is http.StatusServiceUnavailable
+	// Default response value is []byte(`{"error": "Service is down for maintenance"}`)
+	MaintenanceResponse HTTPResponseConfig
+}
+
+// MaintenanceHandler responds to every non exempt request with
+// MaintenanceResponse while maintenance mode is enabled
+type MaintenanceHandler struct {
+	config MaintenanceHandlerConfig
+}
+
+// NewMaintenanceHandler returns *MaintenanceHandler
+func NewMaintenanceHandler(config MaintenanceHandlerConfig) *MaintenanceHandler {
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Minute * 5
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+
+	setHTTPResponseDefaults(&config.MaintenanceResponse, http.StatusServiceUnavailable, []byte(maintenanceTxt))
+
+	return &MaintenanceHandler{config: config}
+}
+
+func (m *MaintenanceHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enabled, retryAfter := m.maintenance()
+
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, v := range m.config.ExemptURLs {
+			if strings.HasPrefix(r.URL.Path, v) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", m.config.ContentType)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		w.WriteHeader(*m.config.MaintenanceResponse.HTTPStatus)
+		w.Write(m.config.MaintenanceResponse.HTTPResponse)
+	})
+}
+
+// maintenance returns whether maintenance mode is enabled along with
+// the retry after duration
+// If cache returns an error other than cacheutil.ErrCacheNil,
+// requests are let through so a cache outage doesn't take down the api
+func (m *MaintenanceHandler) maintenance() (bool, time.Duration) {
+	if m.config.Enabled {
+		return true, m.config.RetryAfter
+	}
+
+	if m.config.CacheStore == nil {
+		return false, 0
+	}
+
+	val, err := m.config.CacheStore.Get(MaintenanceKey)
+
+	if err != nil {
+		return false, 0
+	}
+
+	if seconds, err := strconv.Atoi(string(val)); err == nil && seconds > 0 {
+		return true, time.Duration(seconds) * time.Second
+	}
+
+	return true, m.config.RetryAfter
+}
@@ -0,0 +1,52 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestMaintenanceHandler(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	handler := NewMaintenanceHandler(MaintenanceHandlerConfig{
+		CacheStore: cache,
+		ExemptURLs: []string{"/health"},
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	if rr := serve("/api"); rr.Code != http.StatusOK {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	EnableMaintenance(cache, time.Minute)
+
+	rr := serve("/api")
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("got retry after %s; want 60", rr.Header().Get("Retry-After"))
+	}
+	if rr.Body.String() != maintenanceTxt {
+		t.Errorf("got body %s; want %s", rr.Body.String(), maintenanceTxt)
+	}
+
+	if rr = serve("/health"); rr.Code != http.StatusOK {
+		t.Errorf("exempt url should be served; got status %d", rr.Code)
+	}
+
+	DisableMaintenance(cache)
+
+	if rr = serve("/api"); rr.Code != http.StatusOK {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+}
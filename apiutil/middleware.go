@@ -1,24 +1,18 @@
 package apiutil
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
-
-	"github.com/go-redis/redis"
-	"github.com/urfave/negroni"
-
-	"github.com/gorilla/securecookie"
-	"github.com/gorilla/sessions"
+	"time"
 
 	"github.com/TravisS25/httputil"
 
 	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/ctxutil"
+	"github.com/TravisS25/httputil/muxutil"
 )
 
 const (
@@ -41,12 +35,25 @@ const (
 
 	// URLKey is used as a key when pulling a user's allowed urls from cache
 	URLKey = "%s-urls"
+
+	// DefaultAdminGroup is group of admins, which is the default group
+	// allowed to impersonate users and reach diagnostics endpoints
+	DefaultAdminGroup = "Admin"
 )
 
 var (
-	UserCtxKey           = MiddlewareKey{KeyName: "user"}
-	GroupCtxKey          = MiddlewareKey{KeyName: "groupName"}
-	MiddlewareUserCtxKey = MiddlewareKey{KeyName: "middlewareUser"}
+	UserCtxKey           = ctxutil.UserJSONKey
+	GroupCtxKey          = ctxutil.GroupsKey
+	MiddlewareUserCtxKey = ctxutil.UserKey
+
+	// ImpersonatorCtxKey is key used to store the real user, as
+	// middlewareUser, within request context while they're impersonating
+	// another user
+	ImpersonatorCtxKey = ctxutil.ImpersonatorKey
+
+	// cachedURLsCtxKey holds a user's urls fetched from cache by
+	// GroupHandler so RoutingHandler doesn't fetch them again
+	cachedURLsCtxKey = MiddlewareKey{KeyName: "cachedURLs"}
 )
 
 // HTTPResponseConfig is used to give default header and response
@@ -59,14 +66,11 @@ type HTTPResponseConfig struct {
 	HTTPResponse []byte
 }
 
-type MiddlewareKey struct {
-	KeyName string
-}
+// MiddlewareKey is type of context keys set by middleware, which is
+// shared with every other package through ctxutil
+type MiddlewareKey = ctxutil.Key
 
-type middlewareUser struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-}
+type middlewareUser = ctxutil.User
 
 // InsertLogger is interface that allows to log user's actions of
 // post, put, or delete request
@@ -86,540 +90,9 @@ func setHTTPResponseDefaults(config *HTTPResponseConfig, defaultStatus int, defa
 	}
 }
 
-type Middleware struct {
-	CacheStore   cacheutil.CacheStore
-	SessionStore cacheutil.SessionStore
-	DB           httputil.DBInterface
-	LogInserter  func(res http.ResponseWriter, req *http.Request, payload []byte, db httputil.DBInterface) error
-	QueryDB      func(res *http.Request, db httputil.DBInterface, queryType int) ([]byte, error)
-	AnonRouting  []string
-
-	SessionKeys *cacheutil.SessionConfig
-}
-
-// LogEntryMiddleware is used for logging a user modifying actions such as put, post, and delete
-func (m *Middleware) LogEntryMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	var payload []byte
-	var err error
-	rw := negroni.NewResponseWriter(w)
-
-	if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" {
-		if r.Body != nil {
-			payload, _ = ioutil.ReadAll(r.Body)
-			r.Body = ioutil.NopCloser(bytes.NewBuffer(payload))
-		}
-	}
-
-	next(rw, r)
-
-	if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" {
-		if rw.Status() == 0 || rw.Status() == 200 {
-			err = m.LogInserter(w, r, payload, m.DB)
-
-			if HasServerError(w, err, "") {
-				return
-			}
-		}
-	}
-}
-
-// AuthMiddleware is middleware used to check for authenication of incoming requests
-// If there is a session for a user for current request, we add this to the context of the request
-// If you plan on using other middleware of this middleware class, your unmarshaled user
-// must have the same fields as middlewareUser struct
-//
-// Middleware#SessionStore and Middleware#UserSessionName must be set in order to use
-// Optionally if Middleware#DB and Middleware#UserSessionFunc is also set, it will resort
-// to a database backend if cache fails if you are storing session related things in a database
-// Middleware#UserSessionFunc should return json format of user in bytes
-func (m *Middleware) AuthMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	var middlewareUser middlewareUser
-	var session *sessions.Session
-	var err error
-
-	if m.SessionKeys == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	session, err = m.SessionStore.Get(r, m.SessionKeys.SessionName)
-
-	if err != nil {
-		fmt.Printf("no session err: %s\n", err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// If session is considered new, that means
-	// either current user is truly not logged in or cache was/is down
-	if session.IsNew {
-		// fmt.Printf("new session\n")
-
-		// First we determine if user is sending a cookie with our user cookie key
-		// If they are, try retrieving from db if Middleware#QueryDB is set
-		if _, err := r.Cookie(m.SessionKeys.SessionName); err == nil {
-			fmt.Printf("has cookie but not found in store\n")
-			if m.DB != nil && m.QueryDB != nil {
-				fmt.Printf("auth middleware db\n")
-				userBytes, err := m.QueryDB(r, m.DB, UserQuery)
-
-				if err != nil {
-					switch err.(type) {
-					case securecookie.Error:
-						w.WriteHeader(http.StatusBadRequest)
-					default:
-						if err == sql.ErrNoRows {
-							w.WriteHeader(http.StatusBadRequest)
-						} else {
-							w.WriteHeader(http.StatusInternalServerError)
-						}
-					}
-					return
-				}
-
-				err = json.Unmarshal(userBytes, &middlewareUser)
-
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write([]byte(err.Error()))
-					return
-				}
-
-				// Here we test to see if our session backend is responsive
-				// If it is, that means current user logged in while cache was down
-				// and was using the database to grab their sessions but since session
-				// backend is back up, we can grab current user's session from
-				// database and set it to session backend and use that instead of database
-				// for future requests
-				if _, err = m.SessionStore.Ping(); err == nil {
-					fmt.Printf("ping successful\n")
-					sessionIDBytes, err := m.QueryDB(r, m.DB, SessionQuery)
-
-					if err != nil {
-						if err == sql.ErrNoRows {
-							fmt.Printf("auth middleware db no row found\n")
-							next(w, r)
-							return
-						}
-
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-
-					fmt.Printf("session bytes: %s\n", sessionIDBytes)
-
-					session, _ = m.SessionStore.New(r, m.SessionKeys.SessionName)
-					session.ID = string(sessionIDBytes)
-					fmt.Printf("session id: %s\n", session.ID)
-					session.Values[m.SessionKeys.Keys.UserKey] = userBytes
-					session.Save(r, w)
-					fmt.Printf("set session into store \n")
-				}
-
-				ctx := context.WithValue(r.Context(), UserCtxKey, userBytes)
-				ctxWithEmail := context.WithValue(ctx, MiddlewareUserCtxKey, middlewareUser)
-				next(w, r.WithContext(ctxWithEmail))
-			} else {
-				next(w, r)
-			}
-		} else {
-			// fmt.Printf("new session, no cookie\n")
-			next(w, r)
-		}
-	} else {
-		if val, ok := session.Values[m.SessionKeys.Keys.UserKey]; ok {
-			userBytes := val.([]byte)
-
-			err := json.Unmarshal(val.([]byte), &middlewareUser)
-
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-
-			ctx := context.WithValue(r.Context(), UserCtxKey, userBytes)
-			ctxWithEmail := context.WithValue(ctx, MiddlewareUserCtxKey, middlewareUser)
-			next(w, r.WithContext(ctxWithEmail))
-		} else {
-			next(w, r)
-		}
-	}
-}
-
-// GroupMiddleware is middleware used to add current user's groups (if logged in) to the context
-// of the request
-// User session struct must have same fields as middlewarewUser
-//
-// Middleware#CacheStore must be set in order to use
-// Middleware#AuthMiddleware must come before this middleware
-func (m *Middleware) GroupMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	if r.Context().Value(MiddlewareUserCtxKey) != nil {
-		var groupArray []string
-
-		user := r.Context().Value(MiddlewareUserCtxKey).(middlewareUser)
-		groups := fmt.Sprintf(GroupKey, user.Email)
-		groupBytes, err := m.CacheStore.Get(groups)
-
-		if err != nil {
-			if err != redis.Nil {
-				if m.DB != nil && m.QueryDB != nil {
-					fmt.Printf("group middleware db\n")
-					groupBytes, err = m.QueryDB(r, m.DB, GroupQuery)
-
-					if err != nil {
-						if err == sql.ErrNoRows {
-							fmt.Printf("group middleware db no row found\n")
-							next(w, r)
-							return
-						}
-
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-				} else {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-			} else {
-				next(w, r)
-				return
-			}
-		}
-
-		json.Unmarshal(groupBytes, &groupArray)
-		ctx := context.WithValue(r.Context(), GroupCtxKey, groupArray)
-
-		next(w, r.WithContext(ctx))
-	} else {
-		next(w, r)
-	}
-}
-
-// RoutingMiddleware is middleware used to indicate whether an incoming request is
-// authorized to go to certain urls based on authentication of a user's groups
-// The groups should come from cache and be deserialzed into an array of strings
-// and if current requested url matches any of the urls, they are allowed forward,
-// if not, we 404
-//
-// Middleware#CacheStore and Middleware#AnonRouting must be set to use
-// Middleware#AuthMiddleware and Middleware#GroupMiddleware must be before this middleware
-func (m *Middleware) RoutingMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	rootPath := "/"
-	path := r.URL.Path
-	allowedPath := false
-
-	if r.Method != http.MethodOptions {
-		if r.Context().Value(MiddlewareUserCtxKey) != nil {
-			user := r.Context().Value(MiddlewareUserCtxKey).(middlewareUser)
-			key := fmt.Sprintf(URLKey, user.Email)
-			urlBytes, err := m.CacheStore.Get(key)
-
-			if err != nil {
-				if err != redis.Nil {
-					if m.DB != nil && m.QueryDB != nil {
-						fmt.Printf("routing middleware db\n")
-						urlBytes, err = m.QueryDB(r, m.DB, RoutingQuery)
-
-						if err != nil {
-							if err == sql.ErrNoRows {
-								fmt.Printf("routing middleware db no row found\n")
-								next(w, r)
-								return
-							}
-
-							w.WriteHeader(http.StatusInternalServerError)
-							return
-						}
-					} else {
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-				} else {
-					next(w, r)
-					return
-				}
-			}
-
-			var urls []string
-			err = json.Unmarshal(urlBytes, &urls)
-
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
-			}
-
-			if path == rootPath {
-				allowedPath = true
-			}
-
-			for _, url := range urls {
-				if strings.Contains(path, url) && url != rootPath {
-					allowedPath = true
-					break
-				}
-			}
-
-		} else {
-			if path == rootPath {
-				allowedPath = true
-			} else {
-				for _, url := range m.AnonRouting {
-					if strings.Contains(path, url) && url != rootPath {
-						allowedPath = true
-						break
-					}
-				}
-			}
-		}
-
-		if !allowedPath {
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte("Not authorized to access url"))
-			return
-		}
-
-	}
-
-	next(w, r)
-}
-
-// -----------------------------------------------------------
-
-// AuthHandlerConfig is used as config struct for AuthHandler
-// These settings are not required but if user wants to use things
-// like a different session store besides a database, these should
-// be set
-type AuthHandlerConfig struct {
-	// SessionStore is used to implement a backend to store sessions
-	// besides a database like file system or in-memory database
-	// i.e. Redis
-	SessionStore cacheutil.SessionStore
-
-	// SessionKeys is just an arbitrary set of common key names to store
-	// in a session values
-	SessionConfig cacheutil.SessionConfig
-
-	// QueryForSession is used for inserting a session value from a database
-	// to the entity that implements SessionStore
-	// This is used in the case where a person logs in while the entity that
-	// implements SessionStore is down and must query session from database
-	//
-	// If this is set, the implementing function should return the session id
-	// from a database which will then be set to SessionStore if/when it comes back up
-	//
-	// This is bascially a recovery method if implementing SessionStore ever
-	// goes down or some how gets its values flushed
-	QueryForSession func(w http.ResponseWriter, db httputil.Querier, userID string) (sessionID string, err error)
-
-	// DecodeCookieErrResponse is config used to respond to user if decoding
-	// a cookie is invalid
-	// This usually happens when a user sends an invalid cookie on request
-	//
-	// Default status value is http.StatusBadRequest
-	// Default response value is []byte("Invalid cookie")
-	DecodeCookieErrResponse HTTPResponseConfig
-
-	// ServerErrResponse is config used to respond to user if some type
-	// of server error occurs
-	//
-	// Default status value is http.StatusInternalServerError
-	// Default response value is []byte("Server error")
-	ServerErrResponse HTTPResponseConfig
-
-	// NoRowsErrResponse is config used to respond to user if the returned
-	// error result of AuthHandler#queryForUser is sql.ErrNoRows
-	// This should be returned if there are no results when trying to grab
-	// a user from the database
-	//
-	// Default status value is http.StatusInternalServerError
-	// Default response value is []byte("User Not Found")
-	//NoRowsErrResponse HTTPResponseConfig
-}
-
-type AuthHandler struct {
-	db           httputil.DBInterfaceV2
-	queryForUser QueryDB
-	config       AuthHandlerConfig
-}
-
-func NewAuthHandler(
-	db httputil.DBInterfaceV2,
-	queryForUser QueryDB,
-	config AuthHandlerConfig,
-) *AuthHandler {
-	return &AuthHandler{
-		db:           db,
-		queryForUser: queryForUser,
-		config:       config,
-	}
-}
-
-func (a *AuthHandler) MiddlewareFunc(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var userBytes []byte
-		var middlewareUser middlewareUser
-		var session *sessions.Session
-		var err error
-
-		// Setting up default values from passed configs if none are set
-		setHTTPResponseDefaults(&a.config.DecodeCookieErrResponse, http.StatusBadRequest, []byte(invalidCookieTxt))
-		setHTTPResponseDefaults(&a.config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
-
-		setUser := func() error {
-			userBytes, err = a.queryForUser(w, r, a.db)
-
-			if err != nil {
-				isFatalErr := true
-
-				switch err.(type) {
-				case securecookie.Error:
-					cookieErr := err.(securecookie.Error)
-
-					if cookieErr.IsDecode() {
-						isFatalErr = false
-						w.WriteHeader(*a.config.DecodeCookieErrResponse.HTTPStatus)
-						w.Write(a.config.DecodeCookieErrResponse.HTTPResponse)
-					}
-
-					w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-					w.Write(a.config.ServerErrResponse.HTTPResponse)
-				default:
-					if err == sql.ErrNoRows {
-						isFatalErr = false
-						next.ServeHTTP(w, r)
-						//return err
-					} else {
-						w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-						w.Write(a.config.ServerErrResponse.HTTPResponse)
-					}
-				}
-
-				if isFatalErr {
-					httputil.Logger.Errorf("query for user err: %s", err.Error())
-				}
-
-				return err
-			}
-
-			err = json.Unmarshal(userBytes, &middlewareUser)
-
-			if err != nil {
-				w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-				w.Write(a.config.ServerErrResponse.HTTPResponse)
-				return err
-			}
-
-			return nil
-		}
-
-		// If user sets SessionStore, then we try retrieving session from implemented
-		// SessionStore which usually is a file system or in-memory database i.e. Redis
-		if a.config.SessionStore != nil {
-			session, err = a.config.SessionStore.Get(r, a.config.SessionConfig.SessionName)
-
-			if err != nil {
-				w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-				w.Write(a.config.ServerErrResponse.HTTPResponse)
-				return
-			}
-
-			// If session is considered new, that means
-			// either current user is truly not logged in or cache was/is down
-			if session.IsNew {
-				//fmt.Printf("new session\n")
-
-				// First we determine if user is sending a cookie with our user cookie key
-				// If they are, try retrieving from db if AuthHandler#queryForUser is set
-				// Else, continue to next handler
-				if _, err = r.Cookie(a.config.SessionConfig.SessionName); err == nil {
-					//fmt.Printf("has cookie but not found in store\n")
-					if err = setUser(); err != nil {
-						fmt.Printf("within user\n")
-						return
-					}
-
-					// Here we test to see if our session backend is responsive
-					// If it is, that means current user logged in while cache was down
-					// and was using the database to grab their sessions but since session
-					// backend is back up, we can grab current user's session from
-					// database and set it to session backend and use that instead of database
-					// for future requests
-					if _, err = a.config.SessionStore.Ping(); err == nil && a.config.QueryForSession != nil {
-						//fmt.Printf("ping successful\n")
-						sessionStr, err := a.config.QueryForSession(w, a.db, middlewareUser.ID)
-
-						if err != nil {
-							if err == sql.ErrNoRows {
-								fmt.Printf("auth middleware db no row found\n")
-								next.ServeHTTP(w, r)
-								return
-							}
-
-							fmt.Printf("within query session\n")
-
-							w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-							w.Write(a.config.ServerErrResponse.HTTPResponse)
-							return
-						}
-
-						fmt.Printf("session bytes: %s\n", sessionStr)
-
-						session, err = a.config.SessionStore.New(r, a.config.SessionConfig.SessionName)
-
-						if err != nil {
-							fmt.Printf("within new session\n")
-							w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-							w.Write(a.config.ServerErrResponse.HTTPResponse)
-							return
-						}
-
-						session.ID = sessionStr
-						fmt.Printf("session id: %s\n", session.ID)
-						session.Values[a.config.SessionConfig.Keys.UserKey] = userBytes
-						session.Save(r, w)
-					}
-
-					//setCtxAndServe()
-				} else {
-					//fmt.Printf("new session, no cookie\n")
-					next.ServeHTTP(w, r)
-					return
-				}
-			} else {
-				//fmt.Printf("not new session")
-				if val, ok := session.Values[a.config.SessionConfig.Keys.UserKey]; ok {
-					//fmt.Printf("found in session")
-					userBytes = val.([]byte)
-					err := json.Unmarshal(userBytes, &middlewareUser)
-
-					if err != nil {
-						httputil.Logger.Errorf("invalid json from session: %s", err.Error())
-						w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
-						w.Write(a.config.ServerErrResponse.HTTPResponse)
-						return
-					}
-				} else {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-		} else {
-			if err = setUser(); err != nil {
-				return
-			}
-		}
-
-		ctx := context.WithValue(r.Context(), UserCtxKey, userBytes)
-		ctxWithEmail := context.WithValue(ctx, MiddlewareUserCtxKey, middlewareUser)
-		next.ServeHTTP(w, r.WithContext(ctxWithEmail))
-	})
-}
-
-// setConfig is really only here for testing purposes
-func (a *AuthHandler) setConfig(config AuthHandlerConfig) {
-	a.config = config
+func writeHTTPResponse(w http.ResponseWriter, config HTTPResponseConfig) {
+	w.WriteHeader(*config.HTTPStatus)
+	w.Write(config.HTTPResponse)
 }
 
 // GroupHandlerConfig is config struct used for GroupHandler
@@ -636,18 +109,35 @@ type GroupHandlerConfig struct {
 	// CacheStore must be initialized to use this
 	IgnoreCacheNil bool
 
+	// PubSub is used to subscribe to PublishPermissionChange
+	// notifications, after which groups of user are queried from
+	// database and refreshed in cache on their next request
+	// CacheStore must be initialized to use this
+	PubSub cacheutil.PubSub
+
+	// CacheExpiration is expiration of groups refreshed in cache after
+	// a PublishPermissionChange notification
+	// If 0, groups never expire
+	CacheExpiration time.Duration
+
 	// ServerErrResponse is config used to respond to user if some type
 	// of server error occurs
 	//
 	// Default status value is http.StatusInternalServerError
 	// Default response value is []byte("Server error")
 	ServerErrResponse HTTPResponseConfig
+
+	// Degradation determines response while both CacheStore, if set,
+	// and database are failing, see Degradation
+	// Default is nil which responds with ServerErrResponse
+	Degradation *Degradation
 }
 
 type GroupHandler struct {
 	config         GroupHandlerConfig
 	db             httputil.DBInterfaceV2
 	queryForGroups QueryDB
+	stale          *staleUsers
 }
 
 func NewGroupHandler(
@@ -655,36 +145,55 @@ func NewGroupHandler(
 	queryForGroups QueryDB,
 	config GroupHandlerConfig,
 ) *GroupHandler {
-	return &GroupHandler{
+	g := &GroupHandler{
 		config:         config,
 		db:             db,
 		queryForGroups: queryForGroups,
 	}
+
+	if config.CacheStore != nil {
+		g.stale = subscribeStaleUsers(config.PubSub)
+	}
+
+	return g
+}
+
+// Close unsubscribes handler from PublishPermissionChange notifications
+func (g *GroupHandler) Close() error {
+	if g.stale == nil {
+		return nil
+	}
+
+	return g.stale.close()
 }
 
 func (g *GroupHandler) MiddlewareFunc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user := r.Context().Value(MiddlewareUserCtxKey)
-
-		if user != nil {
+		if user, ok := ctxutil.UserFrom(r.Context()); ok {
 			var groupMap map[string]bool
 			var err error
 			var groupBytes []byte
 
 			// Setting up default values from passed configs if none are set
 			setHTTPResponseDefaults(&g.config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
-			user := user.(middlewareUser)
 			groups := fmt.Sprintf(GroupKey, user.Email)
 
 			setGroupFromDB := func() error {
-				fmt.Printf("group middlware query db\n")
-				groupBytes, err = g.queryForGroups(w, r, g.db)
+				httputil.Debugf("apiutil: group middlware query db")
+				err = g.config.Degradation.do(func() error {
+					var queryErr error
+					groupBytes, queryErr = g.queryForGroups(w, r, g.db)
+					return queryErr
+				})
 
 				if err != nil {
 					if err == sql.ErrNoRows {
 						next.ServeHTTP(w, r)
 						return err
 					}
+					if g.config.Degradation.serve("group", w, r, next) {
+						return err
+					}
 
 					w.WriteHeader(*g.config.ServerErrResponse.HTTPStatus)
 					w.Write(g.config.ServerErrResponse.HTTPResponse)
@@ -704,10 +213,31 @@ func (g *GroupHandler) MiddlewareFunc(next http.Handler) http.Handler {
 				return nil
 			}
 
-			// If cache is set, try to get group info from cache
+			// If groups of user have changed, query from db and refresh
+			// cache
+			// Else if cache is set, try to get group info from cache
 			// Else query from db
-			if g.config.CacheStore != nil {
-				groupBytes, err = g.config.CacheStore.Get(groups)
+			if g.stale.take(user.Email) {
+				if err = setGroupFromDB(); err != nil {
+					return
+				}
+
+				g.config.CacheStore.Set(groups, groupBytes, g.config.CacheExpiration)
+			} else if g.config.CacheStore != nil {
+				var values [][]byte
+
+				// Groups and urls are fetched in one round trip and urls
+				// are added to context for RoutingHandler
+				values, err = g.config.CacheStore.MGet(groups, fmt.Sprintf(URLKey, user.Email))
+
+				if err == nil {
+					if groupBytes = values[0]; groupBytes == nil {
+						err = cacheutil.ErrCacheNil
+					}
+					if values[1] != nil {
+						r = r.WithContext(context.WithValue(r.Context(), cachedURLsCtxKey, values[1]))
+					}
+				}
 
 				if err != nil {
 					// If err occurs and is not a nil err,
@@ -744,8 +274,7 @@ func (g *GroupHandler) MiddlewareFunc(next http.Handler) http.Handler {
 				}
 			}
 
-			ctx := context.WithValue(r.Context(), GroupCtxKey, groupMap)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(ctxutil.WithGroups(r.Context(), groupMap)))
 		} else {
 			next.ServeHTTP(w, r)
 		}
@@ -762,6 +291,17 @@ type RoutingHandlerConfig struct {
 	// CacheStore must be initialized to use this
 	IgnoreCacheNil bool
 
+	// PubSub is used to subscribe to PublishPermissionChange
+	// notifications, after which urls of user are queried from database
+	// and refreshed in cache on their next request
+	// CacheStore must be initialized to use this
+	PubSub cacheutil.PubSub
+
+	// CacheExpiration is expiration of urls refreshed in cache after
+	// a PublishPermissionChange notification
+	// If 0, urls never expire
+	CacheExpiration time.Duration
+
 	// ServerErrResponse is config used to respond to user if some type
 	// of server error occurs
 	//
@@ -769,6 +309,11 @@ type RoutingHandlerConfig struct {
 	// Default response value is []byte("Server Error")
 	ServerErrResponse HTTPResponseConfig
 
+	// Degradation determines response while both CacheStore, if set,
+	// and database are failing, see Degradation
+	// Default is nil which responds with ServerErrResponse
+	Degradation *Degradation
+
 	// UnauthorizedErrResponse is config used to respond to user if none
 	// of the nonUserURLs keys or queried urls match the apis
 	// a user is allowed to access
@@ -784,8 +329,12 @@ type RoutingHandler struct {
 	pathRegex   httputil.PathRegex
 	nonUserURLs map[string]bool
 	config      RoutingHandlerConfig
+	stale       *staleUsers
 }
 
+// NewRoutingHandler returns *RoutingHandler
+// If pathRegex is nil, muxutil#PathRegex is used, see package chiutil
+// for routers of go-chi/chi
 func NewRoutingHandler(
 	db httputil.DBInterfaceV2,
 	queryDB QueryDB,
@@ -793,13 +342,32 @@ func NewRoutingHandler(
 	nonUserURLs map[string]bool,
 	config RoutingHandlerConfig,
 ) *RoutingHandler {
-	return &RoutingHandler{
+	if pathRegex == nil {
+		pathRegex = muxutil.PathRegex
+	}
+
+	routing := &RoutingHandler{
 		db:          db,
 		queryDB:     queryDB,
 		pathRegex:   pathRegex,
 		nonUserURLs: nonUserURLs,
 		config:      config,
 	}
+
+	if config.CacheStore != nil {
+		routing.stale = subscribeStaleUsers(config.PubSub)
+	}
+
+	return routing
+}
+
+// Close unsubscribes handler from PublishPermissionChange notifications
+func (routing *RoutingHandler) Close() error {
+	if routing.stale == nil {
+		return nil
+	}
+
+	return routing.stale.close()
 }
 
 func (routing *RoutingHandler) MiddlewareFunc(next http.Handler) http.Handler {
@@ -815,7 +383,11 @@ func (routing *RoutingHandler) MiddlewareFunc(next http.Handler) http.Handler {
 
 			// Queries from db and sets the bytes returned to url map
 			setURLsFromDB := func() error {
-				urlBytes, err = routing.queryDB(w, r, routing.db)
+				err = routing.config.Degradation.do(func() error {
+					var queryErr error
+					urlBytes, queryErr = routing.queryDB(w, r, routing.db)
+					return queryErr
+				})
 
 				if err != nil {
 					if err == sql.ErrNoRows {
@@ -823,6 +395,9 @@ func (routing *RoutingHandler) MiddlewareFunc(next http.Handler) http.Handler {
 						w.Write(routing.config.ForbiddenURLErrResponse.HTTPResponse)
 						return err
 					}
+					if routing.config.Degradation.serve("routing", w, r, next) {
+						return err
+					}
 
 					w.WriteHeader(*routing.config.ServerErrResponse.HTTPStatus)
 					w.Write(routing.config.ServerErrResponse.HTTPResponse)
@@ -849,15 +424,28 @@ func (routing *RoutingHandler) MiddlewareFunc(next http.Handler) http.Handler {
 			}
 
 			allowedPath := false
-			user := r.Context().Value(MiddlewareUserCtxKey)
-
-			if user != nil {
+			if user, ok := ctxutil.UserFrom(r.Context()); ok {
 				//fmt.Printf("routing user\n")
-				user := user.(middlewareUser)
 				key := fmt.Sprintf(URLKey, user.Email)
 
-				if routing.config.CacheStore != nil {
-					urlBytes, err = routing.config.CacheStore.Get(key)
+				// If urls of user have changed, query from db and
+				// refresh cache
+				if routing.stale.take(user.Email) {
+					if err = setURLsFromDB(); err != nil {
+						return
+					}
+
+					routing.config.CacheStore.Set(key, urlBytes, routing.config.CacheExpiration)
+
+					if _, ok := urls[pathExp]; ok {
+						allowedPath = true
+					}
+				} else if routing.config.CacheStore != nil {
+					if cached, ok := r.Context().Value(cachedURLsCtxKey).([]byte); ok {
+						urlBytes = cached
+					} else {
+						urlBytes, err = routing.config.CacheStore.Get(key)
+					}
 
 					if err != nil {
 						if err != cacheutil.ErrCacheNil {
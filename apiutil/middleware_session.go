@@ -0,0 +1,644 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/urfave/negroni"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+type Middleware struct {
+	CacheStore   cacheutil.CacheStore
+	SessionStore cacheutil.SessionStore
+	DB           httputil.DBInterface
+	LogInserter  func(res http.ResponseWriter, req *http.Request, payload []byte, db httputil.DBInterface) error
+	QueryDB      func(res *http.Request, db httputil.DBInterface, queryType int) ([]byte, error)
+	AnonRouting  []string
+
+	SessionKeys *cacheutil.SessionConfig
+
+	// LogEntryConf determines what of the request body is passed
+	// to LogInserter by LogEntryMiddleware
+	LogEntryConf LogEntryConfig
+}
+
+// LogEntryMiddleware is used for logging a user modifying actions such as put, post, and delete
+func (m *Middleware) LogEntryMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var payload []byte
+	var err error
+	rw := negroni.NewResponseWriter(w)
+
+	if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" {
+		payload = captureLogPayload(r, m.LogEntryConf)
+	}
+
+	next(rw, r)
+
+	if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" {
+		if rw.Status() == 0 || rw.Status() == 200 {
+			err = m.LogInserter(w, r, payload, m.DB)
+
+			if HasServerError(w, err, "") {
+				return
+			}
+		}
+	}
+}
+
+// AuthMiddleware is middleware used to check for authenication of incoming requests
+// If there is a session for a user for current request, we add this to the context of the request
+// If you plan on using other middleware of this middleware class, your unmarshaled user
+// must have the same fields as middlewareUser struct
+//
+// Middleware#SessionStore and Middleware#UserSessionName must be set in order to use
+// Optionally if Middleware#DB and Middleware#UserSessionFunc is also set, it will resort
+// to a database backend if cache fails if you are storing session related things in a database
+// Middleware#UserSessionFunc should return json format of user in bytes
+func (m *Middleware) AuthMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var middlewareUser middlewareUser
+	var session *sessions.Session
+	var err error
+
+	if m.SessionKeys == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	session, err = m.SessionStore.Get(r, m.SessionKeys.SessionName)
+
+	if err != nil {
+		httputil.Debugf("apiutil: no session err: %s", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// If session is considered new, that means
+	// either current user is truly not logged in or cache was/is down
+	if session.IsNew {
+		// fmt.Printf("new session\n")
+
+		// First we determine if user is sending a cookie with our user cookie key
+		// If they are, try retrieving from db if Middleware#QueryDB is set
+		if _, err := r.Cookie(m.SessionKeys.SessionName); err == nil {
+			httputil.Debugf("apiutil: has cookie but not found in store")
+			if m.DB != nil && m.QueryDB != nil {
+				httputil.Debugf("apiutil: auth middleware db")
+				userBytes, err := m.QueryDB(r, m.DB, UserQuery)
+
+				if err != nil {
+					switch err.(type) {
+					case securecookie.Error:
+						w.WriteHeader(http.StatusBadRequest)
+					default:
+						if err == sql.ErrNoRows {
+							w.WriteHeader(http.StatusBadRequest)
+						} else {
+							w.WriteHeader(http.StatusInternalServerError)
+						}
+					}
+					return
+				}
+
+				err = json.Unmarshal(userBytes, &middlewareUser)
+
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(err.Error()))
+					return
+				}
+
+				// Here we test to see if our session backend is responsive
+				// If it is, that means current user logged in while cache was down
+				// and was using the database to grab their sessions but since session
+				// backend is back up, we can grab current user's session from
+				// database and set it to session backend and use that instead of database
+				// for future requests
+				if _, err = m.SessionStore.Ping(); err == nil {
+					httputil.Debugf("apiutil: ping successful")
+					sessionIDBytes, err := m.QueryDB(r, m.DB, SessionQuery)
+
+					if err != nil {
+						if err == sql.ErrNoRows {
+							httputil.Debugf("apiutil: auth middleware db no row found")
+							next(w, r)
+							return
+						}
+
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+
+					httputil.Debugf("apiutil: session bytes: %s", sessionIDBytes)
+
+					session, _ = m.SessionStore.New(r, m.SessionKeys.SessionName)
+					session.ID = string(sessionIDBytes)
+					httputil.Debugf("apiutil: session id: %s", session.ID)
+					session.Values[m.SessionKeys.Keys.UserKey] = m.SessionKeys.EncodeValue(userBytes)
+					session.Save(r, w)
+					httputil.Debugf("apiutil: set session into store")
+				}
+
+				ctx := ctxutil.WithUserJSON(r.Context(), userBytes)
+				next(w, r.WithContext(ctxutil.WithUser(ctx, middlewareUser)))
+			} else {
+				next(w, r)
+			}
+		} else {
+			// fmt.Printf("new session, no cookie\n")
+			next(w, r)
+		}
+	} else {
+		if val, ok := session.Values[m.SessionKeys.Keys.UserKey]; ok {
+			userBytes, _, err := m.SessionKeys.DecodeValue(val.([]byte))
+
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			err = json.Unmarshal(userBytes, &middlewareUser)
+
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			ctx := ctxutil.WithUserJSON(r.Context(), userBytes)
+			next(w, r.WithContext(ctxutil.WithUser(ctx, middlewareUser)))
+		} else {
+			next(w, r)
+		}
+	}
+}
+
+// GroupMiddleware is middleware used to add current user's groups (if logged in) to the context
+// of the request
+// User session struct must have same fields as middlewarewUser
+//
+// Middleware#CacheStore must be set in order to use
+// Middleware#AuthMiddleware must come before this middleware
+func (m *Middleware) GroupMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if user, ok := ctxutil.UserFrom(r.Context()); ok {
+		var groupArray []string
+
+		groups := fmt.Sprintf(GroupKey, user.Email)
+		groupBytes, err := m.CacheStore.Get(groups)
+
+		if err != nil {
+			if err != redis.Nil {
+				if m.DB != nil && m.QueryDB != nil {
+					httputil.Debugf("apiutil: group middleware db")
+					groupBytes, err = m.QueryDB(r, m.DB, GroupQuery)
+
+					if err != nil {
+						if err == sql.ErrNoRows {
+							httputil.Debugf("apiutil: group middleware db no row found")
+							next(w, r)
+							return
+						}
+
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			} else {
+				next(w, r)
+				return
+			}
+		}
+
+		json.Unmarshal(groupBytes, &groupArray)
+		groupMap := make(map[string]bool, len(groupArray))
+
+		for _, v := range groupArray {
+			groupMap[v] = true
+		}
+
+		ctx := ctxutil.WithGroups(r.Context(), groupMap)
+
+		next(w, r.WithContext(ctx))
+	} else {
+		next(w, r)
+	}
+}
+
+// RoutingMiddleware is middleware used to indicate whether an incoming request is
+// authorized to go to certain urls based on authentication of a user's groups
+// The groups should come from cache and be deserialzed into an array of strings
+// and if current requested url matches any of the urls, they are allowed forward,
+// if not, we 404
+//
+// Middleware#CacheStore and Middleware#AnonRouting must be set to use
+// Middleware#AuthMiddleware and Middleware#GroupMiddleware must be before this middleware
+func (m *Middleware) RoutingMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	rootPath := "/"
+	path := r.URL.Path
+	allowedPath := false
+
+	if r.Method != http.MethodOptions {
+		if user, ok := ctxutil.UserFrom(r.Context()); ok {
+			key := fmt.Sprintf(URLKey, user.Email)
+			urlBytes, err := m.CacheStore.Get(key)
+
+			if err != nil {
+				if err != redis.Nil {
+					if m.DB != nil && m.QueryDB != nil {
+						httputil.Debugf("apiutil: routing middleware db")
+						urlBytes, err = m.QueryDB(r, m.DB, RoutingQuery)
+
+						if err != nil {
+							if err == sql.ErrNoRows {
+								httputil.Debugf("apiutil: routing middleware db no row found")
+								next(w, r)
+								return
+							}
+
+							w.WriteHeader(http.StatusInternalServerError)
+							return
+						}
+					} else {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+				} else {
+					next(w, r)
+					return
+				}
+			}
+
+			var urls []string
+			err = json.Unmarshal(urlBytes, &urls)
+
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			if path == rootPath {
+				allowedPath = true
+			}
+
+			for _, url := range urls {
+				if strings.Contains(path, url) && url != rootPath {
+					allowedPath = true
+					break
+				}
+			}
+
+		} else {
+			if path == rootPath {
+				allowedPath = true
+			} else {
+				for _, url := range m.AnonRouting {
+					if strings.Contains(path, url) && url != rootPath {
+						allowedPath = true
+						break
+					}
+				}
+			}
+		}
+
+		if !allowedPath {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Not authorized to access url"))
+			return
+		}
+
+	}
+
+	next(w, r)
+}
+
+// LogEntryMiddlewareFunc is standard library version of
+// Middleware#LogEntryMiddleware
+func (m *Middleware) LogEntryMiddlewareFunc(next http.Handler) http.Handler {
+	return FromNegroni(m.LogEntryMiddleware)(next)
+}
+
+// AuthMiddlewareFunc is standard library version of
+// Middleware#AuthMiddleware
+func (m *Middleware) AuthMiddlewareFunc(next http.Handler) http.Handler {
+	return FromNegroni(m.AuthMiddleware)(next)
+}
+
+// GroupMiddlewareFunc is standard library version of
+// Middleware#GroupMiddleware
+func (m *Middleware) GroupMiddlewareFunc(next http.Handler) http.Handler {
+	return FromNegroni(m.GroupMiddleware)(next)
+}
+
+// RoutingMiddlewareFunc is standard library version of
+// Middleware#RoutingMiddleware
+func (m *Middleware) RoutingMiddlewareFunc(next http.Handler) http.Handler {
+	return FromNegroni(m.RoutingMiddleware)(next)
+}
+
+// -----------------------------------------------------------
+
+// AuthHandlerConfig is used as config struct for AuthHandler
+// These settings are not required but if user wants to use things
+// like a different session store besides a database, these should
+// be set
+type AuthHandlerConfig struct {
+	// SessionStore is used to implement a backend to store sessions
+	// besides a database like file system or in-memory database
+	// i.e. Redis
+	SessionStore cacheutil.SessionStore
+
+	// SessionKeys is just an arbitrary set of common key names to store
+	// in a session values
+	SessionConfig cacheutil.SessionConfig
+
+	// QueryForSession is used for inserting a session value from a database
+	// to the entity that implements SessionStore
+	// This is used in the case where a person logs in while the entity that
+	// implements SessionStore is down and must query session from database
+	//
+	// If this is set, the implementing function should return the session id
+	// from a database which will then be set to SessionStore if/when it comes back up
+	//
+	// This is bascially a recovery method if implementing SessionStore ever
+	// goes down or some how gets its values flushed
+	//
+	// Deprecated: Use cacheutil#FallbackSessionStore as SessionStore
+	// instead, which falls back to a secondary store while its primary
+	// store is down and copies sessions back once it recovers
+	// This is ignored if SessionStore is *cacheutil.FallbackSessionStore
+	QueryForSession func(w http.ResponseWriter, db httputil.Querier, userID string) (sessionID string, err error)
+
+	// DecodeCookieErrResponse is config used to respond to user if decoding
+	// a cookie is invalid
+	// This usually happens when a user sends an invalid cookie on request
+	//
+	// Default status value is http.StatusBadRequest
+	// Default response value is []byte("Invalid cookie")
+	DecodeCookieErrResponse HTTPResponseConfig
+
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+
+	// Degradation determines response while both SessionStore, if set,
+	// and database are failing, see Degradation
+	// Default is nil which responds with ServerErrResponse
+	Degradation *Degradation
+
+	// NoRowsErrResponse is config used to respond to user if the returned
+	// error result of AuthHandler#queryForUser is sql.ErrNoRows
+	// This should be returned if there are no results when trying to grab
+	// a user from the database
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("User Not Found")
+	//NoRowsErrResponse HTTPResponseConfig
+}
+
+type AuthHandler struct {
+	db           httputil.DBInterfaceV2
+	queryForUser QueryDB
+	config       AuthHandlerConfig
+}
+
+func NewAuthHandler(
+	db httputil.DBInterfaceV2,
+	queryForUser QueryDB,
+	config AuthHandlerConfig,
+) *AuthHandler {
+	return &AuthHandler{
+		db:           db,
+		queryForUser: queryForUser,
+		config:       config,
+	}
+}
+
+func (a *AuthHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var userBytes []byte
+		var middlewareUser middlewareUser
+		var session *sessions.Session
+		var err error
+
+		// Setting up default values from passed configs if none are set
+		setHTTPResponseDefaults(&a.config.DecodeCookieErrResponse, http.StatusBadRequest, []byte(invalidCookieTxt))
+		setHTTPResponseDefaults(&a.config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+		setUser := func() error {
+			err = a.config.Degradation.do(func() error {
+				var queryErr error
+				userBytes, queryErr = a.queryForUser(w, r, a.db)
+				return queryErr
+			})
+
+			if err != nil {
+				isFatalErr := true
+
+				switch err.(type) {
+				case securecookie.Error:
+					cookieErr := err.(securecookie.Error)
+
+					if cookieErr.IsDecode() {
+						isFatalErr = false
+						w.WriteHeader(*a.config.DecodeCookieErrResponse.HTTPStatus)
+						w.Write(a.config.DecodeCookieErrResponse.HTTPResponse)
+					}
+
+					w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+					w.Write(a.config.ServerErrResponse.HTTPResponse)
+				default:
+					if err == sql.ErrNoRows {
+						isFatalErr = false
+						next.ServeHTTP(w, r)
+						//return err
+					} else if !a.config.Degradation.serve("auth", w, r, next) {
+						w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+						w.Write(a.config.ServerErrResponse.HTTPResponse)
+					}
+				}
+
+				if isFatalErr {
+					httputil.Logger.Errorf("query for user err: %s", err.Error())
+				}
+
+				return err
+			}
+
+			err = json.Unmarshal(userBytes, &middlewareUser)
+
+			if err != nil {
+				w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+				w.Write(a.config.ServerErrResponse.HTTPResponse)
+				return err
+			}
+
+			return nil
+		}
+
+		serveUser := func() {
+			ctx := ctxutil.WithUserJSON(r.Context(), userBytes)
+			next.ServeHTTP(w, r.WithContext(ctxutil.WithUser(ctx, middlewareUser)))
+		}
+
+		if a.config.SessionStore == nil {
+			if err = setUser(); err != nil {
+				return
+			}
+
+			serveUser()
+			return
+		}
+
+		// If user sets SessionStore, then we try retrieving session from implemented
+		// SessionStore which usually is a file system or in-memory database i.e. Redis
+		session, err = a.config.SessionStore.Get(r, a.config.SessionConfig.SessionName)
+
+		if err != nil {
+			w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+			w.Write(a.config.ServerErrResponse.HTTPResponse)
+			return
+		}
+
+		if !session.IsNew {
+			val, ok := session.Values[a.config.SessionConfig.Keys.UserKey]
+
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var stale bool
+			userBytes, stale, err = a.config.SessionConfig.DecodeValue(val.([]byte))
+
+			// Session that can't be migrated is discarded so user logs in
+			// again rather than getting errors on every request
+			if err != nil {
+				httputil.Logger.Errorf("invalid session value: %s", err.Error())
+				delete(session.Values, a.config.SessionConfig.Keys.UserKey)
+				session.Save(r, w)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Save migrated value so it's only migrated once
+			if stale {
+				session.Values[a.config.SessionConfig.Keys.UserKey] = a.config.SessionConfig.EncodeValue(userBytes)
+
+				if err = session.Save(r, w); err != nil {
+					httputil.Logger.Errorf("saving migrated session err: %s", err.Error())
+				}
+			}
+
+			if err = json.Unmarshal(userBytes, &middlewareUser); err != nil {
+				httputil.Logger.Errorf("invalid json from session: %s", err.Error())
+				w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+				w.Write(a.config.ServerErrResponse.HTTPResponse)
+				return
+			}
+
+			serveUser()
+			return
+		}
+
+		// If session is considered new, that means either current user
+		// is truly not logged in or session store was/is down
+		// If user is sending a cookie with our session name, try
+		// retrieving user from AuthHandler#queryForUser
+		if _, err = r.Cookie(a.config.SessionConfig.SessionName); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err = setUser(); err != nil {
+			return
+		}
+
+		// FallbackSessionStore already copies sessions created while its
+		// primary store was down so there is nothing to restore
+		if _, ok := a.config.SessionStore.(*cacheutil.FallbackSessionStore); !ok && a.config.QueryForSession != nil {
+			if !a.restoreSession(next, w, r, userBytes, middlewareUser.ID) {
+				return
+			}
+		}
+
+		serveUser()
+	})
+}
+
+// restoreSession tests to see if session store is responsive and if it
+// is, that means current user logged in while session store was down
+// and was using the database to grab their session
+// Since session store is back up, current user's session is queried from
+// database with AuthHandlerConfig#QueryForSession and set to session store
+// to be used instead of database for future requests
+//
+// Returns false if response has already been written
+func (a *AuthHandler) restoreSession(
+	next http.Handler,
+	w http.ResponseWriter,
+	r *http.Request,
+	userBytes []byte,
+	userID string,
+) bool {
+	if _, err := a.config.SessionStore.Ping(); err != nil {
+		return true
+	}
+
+	sessionStr, err := a.config.QueryForSession(w, a.db, userID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			httputil.Debugf("apiutil: auth middleware db no row found")
+			next.ServeHTTP(w, r)
+			return false
+		}
+
+		httputil.Debugf("apiutil: within query session")
+		w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+		w.Write(a.config.ServerErrResponse.HTTPResponse)
+		return false
+	}
+
+	httputil.Debugf("apiutil: session bytes: %s", sessionStr)
+
+	session, err := a.config.SessionStore.New(r, a.config.SessionConfig.SessionName)
+
+	if err != nil {
+		httputil.Debugf("apiutil: within new session")
+		w.WriteHeader(*a.config.ServerErrResponse.HTTPStatus)
+		w.Write(a.config.ServerErrResponse.HTTPResponse)
+		return false
+	}
+
+	session.ID = sessionStr
+	httputil.Debugf("apiutil: session id: %s", session.ID)
+	session.Values[a.config.SessionConfig.Keys.UserKey] = a.config.SessionConfig.EncodeValue(userBytes)
+	session.Save(r, w)
+	return true
+}
+
+// setConfig is really only here for testing purposes
+func (a *AuthHandler) setConfig(config AuthHandlerConfig) {
+	a.config = config
+}
@@ -0,0 +1,417 @@
+//go:build !nosessions
+// +build !nosessions
+
+package apiutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func getSessionFuncErr(r *http.Request, name string) (*sessions.Session, error) {
+	return nil, errors.New("error")
+}
+func pingSessionFunc() (bool, error) {
+	return true, nil
+}
+func pingSessionFuncErr() (bool, error) {
+	return false, errors.New("error")
+}
+func saveSessionFunc(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	return nil
+}
+func getFuncNewSession(r *http.Request, name string) (*sessions.Session, error) {
+	ms := newDefaultMockSessionStore()
+	s := sessions.NewSession(ms, cookieName)
+	s.IsNew = true
+	return s, nil
+}
+func getFuncSession(r *http.Request, name string) (*sessions.Session, error) {
+	ms := newDefaultMockSessionStore()
+	s := sessions.NewSession(ms, cookieName)
+	s.IsNew = false
+	return s, nil
+}
+func getFuncSessionWithValues(r *http.Request, name string) (*sessions.Session, error) {
+	ms := newDefaultMockSessionStore()
+	s := sessions.NewSession(ms, cookieName)
+	u := mUser
+	bUser, err := json.Marshal(&u)
+
+	if err != nil {
+		return s, err
+	}
+
+	s.Values[cookieName] = bUser
+	return s, nil
+}
+func getFuncSessionWithInvalidValues(r *http.Request, name string) (*sessions.Session, error) {
+	ms := newDefaultMockSessionStore()
+	s := sessions.NewSession(ms, cookieName)
+	foo := []string{"foo"}
+	bUser, err := json.Marshal(&foo)
+
+	if err != nil {
+		return s, err
+	}
+
+	s.Values[cookieName] = bUser
+	return s, nil
+}
+
+func newDefaultMockSessionStore() *cachetest.MockSessionStore {
+	return &cachetest.MockSessionStore{
+		GetFunc:  getFuncSession,
+		NewFunc:  getFuncNewSession,
+		PingFunc: pingSessionFunc,
+		SaveFunc: saveSessionFunc,
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	queryUser := "queryUser"
+	querySession := "querySession"
+
+	mockSessionStore := &cachetest.MockSessionStore{
+		GetFunc:  getSessionFuncErr,
+		NewFunc:  getSessionFuncErr,
+		PingFunc: pingSessionFuncErr,
+		SaveFunc: saveSessionFunc,
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/url", nil)
+	mockDB := &dbtest.MockDB{
+		RecoverErrorFunc: func(err error) (httputil.DBInterfaceV2, error) {
+			return nil, nil
+		},
+	}
+
+	authConfig := AuthHandlerConfig{
+		SessionConfig: cacheutil.SessionConfig{
+			SessionName: "user",
+			Keys: cacheutil.SessionKeys{
+				UserKey: "user",
+			},
+		},
+		QueryForSession: func(w http.ResponseWriter, db httputil.Querier, userID string) (string, error) {
+			if userID == "1" {
+				return "some session", nil
+			}
+
+			if userID == "0" {
+				return "", sql.ErrNoRows
+			}
+
+			return "", errors.New("error")
+		},
+	}
+	queryForUser := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		if r.Header.Get(queryUser) == decodeErr {
+			return nil, cachetest.NewMockSessionError(nil, "Decode cookie error", false, true, false)
+		}
+
+		if r.Header.Get(queryUser) == internalErr {
+			fmt.Printf("made to internal error\n")
+			return nil, cachetest.NewMockSessionError(nil, "Internal cookie error", false, false, true)
+		}
+
+		if r.Header.Get(queryUser) == noRowsErr {
+			return nil, sql.ErrNoRows
+		}
+
+		if r.Header.Get(queryUser) == generalErr {
+			return nil, errors.New(generalErr)
+		}
+
+		if r.Header.Get(queryUser) == invalidJSON {
+			sMap := []string{"foobar"}
+			return json.Marshal(sMap)
+		}
+
+		u := mUser
+
+		if r.Header.Get(querySession) == noRowsErr {
+			u.ID = "0"
+		}
+
+		if r.Header.Get(querySession) == generalErr {
+			u.ID = "-1"
+		}
+
+		return json.Marshal(&u)
+	}
+
+	authHandler := NewAuthHandler(mockDB, queryForUser, authConfig)
+
+	// Testing default settings without cache
+	rr := httptest.NewRecorder()
+	h := authHandler.MiddlewareFunc(mockHandler)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Testing with cookie decode error and without cache
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, decodeErr)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf(statusErrTxt, http.StatusBadRequest, rr.Code)
+	}
+
+	// Testing with internal cookie error and without cache
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, internalErr)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Testing with sql no rows error and without cache
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, noRowsErr)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Testing with server error and without cache
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, generalErr)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Testing with invalid json and without cache
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, invalidJSON)
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Setting up session with session error
+	authConfig.SessionStore = mockSessionStore
+	authHandler.setConfig(authConfig)
+
+	// Testing session store get error
+	rr = httptest.NewRecorder()
+	request.Header.Set(queryUser, "")
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Setting up new session with no cookie found
+	mockSessionStore.GetFunc = getFuncNewSession
+	authConfig.SessionStore = mockSessionStore
+	authHandler.setConfig(authConfig)
+
+	// Testing session store get error
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Testing cookie but the session store is still down
+	request.AddCookie(sessions.NewCookie("user", "val", &sessions.Options{}))
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Setting up where session backend is back up
+	// but grabbing session from database can't be found
+	mockSessionStore.PingFunc = pingSessionFunc
+	request.Header.Set(querySession, noRowsErr)
+
+	// Testing that query for session returns
+	// no row error but with ok status
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Testing that an error occured when trying
+	// to query for session and should get internal error
+	request.Header.Set(querySession, generalErr)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Testing that we get internal error when trying
+	// to get new session from session store
+	request.Header.Set(querySession, "")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Setting up for successful new session from session store
+	mockSessionStore.NewFunc = getFuncNewSession
+	authConfig.SessionStore = mockSessionStore
+	authHandler.setConfig(authConfig)
+
+	// Testing successful new session
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Setting up successfully getting session from
+	// cache store that is not new but unable
+	// to find user value within session
+	mockSessionStore.GetFunc = getFuncSession
+	authConfig.SessionStore = mockSessionStore
+	authHandler.setConfig(authConfig)
+
+	// Testing successful new session but without
+	// finding user value in session
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	// Setting up for session to get value but have invalid json
+	mockSessionStore.GetFunc = getFuncSessionWithInvalidValues
+	authConfig.SessionStore = mockSessionStore
+
+	// Testing json error occurs when trying to retrive user
+	// info from session
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
+	}
+
+	// Setting up for session to successfully get value and
+	// have right user info
+	mockSessionStore.GetFunc = getFuncSessionWithValues
+	authConfig.SessionStore = mockSessionStore
+
+	// Testing successful getting session from store
+	// with right user info
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+}
+
+func TestAuthMiddlewareSessionMigration(t *testing.T) {
+	store := cachetest.NewMemorySessionStore([]byte("01234567890123456789012345678901"))
+	sessionConfig := cacheutil.SessionConfig{
+		SessionName: cookieName,
+		Keys:        cacheutil.SessionKeys{UserKey: cookieName},
+		Version:     1,
+		Migrate: func(oldVersion int, data []byte) ([]byte, error) {
+			if string(data) == "invalid" {
+				return nil, errors.New("invalid")
+			}
+
+			return []byte(strings.Replace(string(data), `"userID"`, `"id"`, 1)), nil
+		},
+	}
+	queryForUser := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		return nil, sql.ErrNoRows
+	}
+
+	var ctxUser middlewareUser
+
+	h := NewAuthHandler(nil, queryForUser, AuthHandlerConfig{
+		SessionStore:  store,
+		SessionConfig: sessionConfig,
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxUser = middlewareUser{}
+
+		if u := r.Context().Value(MiddlewareUserCtxKey); u != nil {
+			ctxUser = u.(middlewareUser)
+		}
+	}))
+
+	// Session stored before versioning was used is migrated
+	cookie, err := store.CreateSession(cookieName, map[interface{}]interface{}{
+		cookieName: []byte(`{"userID":"1"}`),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.AddCookie(cookie)
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if ctxUser.ID != "1" {
+		t.Errorf("user id should be 1; got %s", ctxUser.ID)
+	}
+
+	// Migrated session is saved with current version
+	req = httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.AddCookie(cookie)
+	session, _ := store.New(req, cookieName)
+
+	if _, stale, _ := sessionConfig.DecodeValue(session.Values[cookieName].([]byte)); stale {
+		t.Errorf("migrated session should be saved with current version")
+	}
+
+	// Session that can't be migrated is treated as logged out
+	cookie, _ = store.CreateSession(cookieName, map[interface{}]interface{}{
+		cookieName: []byte("invalid"),
+	})
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.AddCookie(cookie)
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if ctxUser.ID != "" {
+		t.Errorf("user should not be set; got %s", ctxUser.ID)
+	}
+}
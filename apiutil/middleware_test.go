@@ -10,9 +10,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/gorilla/sessions"
-
-	"github.com/TravisS25/httputil/apiutil/apitest"
 	"github.com/TravisS25/httputil/cacheutil/cachetest"
 
 	"github.com/TravisS25/httputil/cacheutil"
@@ -40,9 +37,7 @@ var (
 	}
 
 	// This should be used for read only
-	mockHandler = &apitest.MockHandler{
-		ServeHTTPFunc: func(w http.ResponseWriter, r *http.Request) {},
-	}
+	mockHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
 	// This should be used for read only
 	groupMap = map[string]bool{
@@ -77,321 +72,6 @@ func hasKeyCacheFunc(key string) (bool, error) {
 	return false, nil
 }
 
-func getSessionFuncErr(r *http.Request, name string) (*sessions.Session, error) {
-	return nil, errors.New("error")
-}
-func pingSessionFunc() (bool, error) {
-	return true, nil
-}
-func pingSessionFuncErr() (bool, error) {
-	return false, errors.New("error")
-}
-func saveSessionFunc(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
-	return nil
-}
-func getFuncNewSession(r *http.Request, name string) (*sessions.Session, error) {
-	ms := newDefaultMockSessionStore()
-	s := sessions.NewSession(ms, cookieName)
-	s.IsNew = true
-	return s, nil
-}
-func getFuncSession(r *http.Request, name string) (*sessions.Session, error) {
-	ms := newDefaultMockSessionStore()
-	s := sessions.NewSession(ms, cookieName)
-	s.IsNew = false
-	return s, nil
-}
-func getFuncSessionWithValues(r *http.Request, name string) (*sessions.Session, error) {
-	ms := newDefaultMockSessionStore()
-	s := sessions.NewSession(ms, cookieName)
-	u := mUser
-	bUser, err := json.Marshal(&u)
-
-	if err != nil {
-		return s, err
-	}
-
-	s.Values[cookieName] = bUser
-	return s, nil
-}
-func getFuncSessionWithInvalidValues(r *http.Request, name string) (*sessions.Session, error) {
-	ms := newDefaultMockSessionStore()
-	s := sessions.NewSession(ms, cookieName)
-	foo := []string{"foo"}
-	bUser, err := json.Marshal(&foo)
-
-	if err != nil {
-		return s, err
-	}
-
-	s.Values[cookieName] = bUser
-	return s, nil
-}
-
-func newDefaultMockSessionStore() *cachetest.MockSessionStore {
-	return &cachetest.MockSessionStore{
-		GetFunc:  getFuncSession,
-		NewFunc:  getFuncNewSession,
-		PingFunc: pingSessionFunc,
-		SaveFunc: saveSessionFunc,
-	}
-}
-
-func TestAuthMiddleware(t *testing.T) {
-	queryUser := "queryUser"
-	querySession := "querySession"
-
-	mockSessionStore := &cachetest.MockSessionStore{
-		GetFunc:  getSessionFuncErr,
-		NewFunc:  getSessionFuncErr,
-		PingFunc: pingSessionFuncErr,
-		SaveFunc: saveSessionFunc,
-	}
-
-	request := httptest.NewRequest(http.MethodGet, "/url", nil)
-	mockDB := &dbtest.MockDB{
-		RecoverErrorFunc: func(err error) bool {
-			return true
-		},
-	}
-
-	authConfig := AuthHandlerConfig{
-		SessionConfig: cacheutil.SessionConfig{
-			SessionName: "user",
-			Keys: cacheutil.SessionKeys{
-				UserKey: "user",
-			},
-		},
-		QueryForSession: func(w http.ResponseWriter, db httputil.Querier, userID string) (string, error) {
-			if userID == "1" {
-				return "some session", nil
-			}
-
-			if userID == "0" {
-				return "", sql.ErrNoRows
-			}
-
-			return "", errors.New("error")
-		},
-	}
-	queryForUser := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
-		if r.Header.Get(queryUser) == decodeErr {
-			return nil, cachetest.NewMockSessionError(nil, "Decode cookie error", false, true, false)
-		}
-
-		if r.Header.Get(queryUser) == internalErr {
-			fmt.Printf("made to internal error\n")
-			return nil, cachetest.NewMockSessionError(nil, "Internal cookie error", false, false, true)
-		}
-
-		if r.Header.Get(queryUser) == noRowsErr {
-			return nil, sql.ErrNoRows
-		}
-
-		if r.Header.Get(queryUser) == generalErr {
-			return nil, errors.New(generalErr)
-		}
-
-		if r.Header.Get(queryUser) == invalidJSON {
-			sMap := []string{"foobar"}
-			return json.Marshal(sMap)
-		}
-
-		u := mUser
-
-		if r.Header.Get(querySession) == noRowsErr {
-			u.ID = "0"
-		}
-
-		if r.Header.Get(querySession) == generalErr {
-			u.ID = "-1"
-		}
-
-		return json.Marshal(&u)
-	}
-
-	authHandler := NewAuthHandler(mockDB, queryForUser, authConfig)
-
-	// Testing default settings without cache
-	rr := httptest.NewRecorder()
-	h := authHandler.MiddlewareFunc(mockHandler)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Testing with cookie decode error and without cache
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, decodeErr)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf(statusErrTxt, http.StatusBadRequest, rr.Code)
-	}
-
-	// Testing with internal cookie error and without cache
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, internalErr)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Testing with sql no rows error and without cache
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, noRowsErr)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Testing with server error and without cache
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, generalErr)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Testing with invalid json and without cache
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, invalidJSON)
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Setting up session with session error
-	authConfig.SessionStore = mockSessionStore
-	authHandler.setConfig(authConfig)
-
-	// Testing session store get error
-	rr = httptest.NewRecorder()
-	request.Header.Set(queryUser, "")
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Setting up new session with no cookie found
-	mockSessionStore.GetFunc = getFuncNewSession
-	authConfig.SessionStore = mockSessionStore
-	authHandler.setConfig(authConfig)
-
-	// Testing session store get error
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Testing cookie but the session store is still down
-	request.AddCookie(sessions.NewCookie("user", "val", &sessions.Options{}))
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Setting up where session backend is back up
-	// but grabbing session from database can't be found
-	mockSessionStore.PingFunc = pingSessionFunc
-	request.Header.Set(querySession, noRowsErr)
-
-	// Testing that query for session returns
-	// no row error but with ok status
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Testing that an error occured when trying
-	// to query for session and should get internal error
-	request.Header.Set(querySession, generalErr)
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Testing that we get internal error when trying
-	// to get new session from session store
-	request.Header.Set(querySession, "")
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Setting up for successful new session from session store
-	mockSessionStore.NewFunc = getFuncNewSession
-	authConfig.SessionStore = mockSessionStore
-	authHandler.setConfig(authConfig)
-
-	// Testing successful new session
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Setting up successfully getting session from
-	// cache store that is not new but unable
-	// to find user value within session
-	mockSessionStore.GetFunc = getFuncSession
-	authConfig.SessionStore = mockSessionStore
-	authHandler.setConfig(authConfig)
-
-	// Testing successful new session but without
-	// finding user value in session
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-
-	// Setting up for session to get value but have invalid json
-	mockSessionStore.GetFunc = getFuncSessionWithInvalidValues
-	authConfig.SessionStore = mockSessionStore
-
-	// Testing json error occurs when trying to retrive user
-	// info from session
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf(statusErrTxt, http.StatusInternalServerError, rr.Code)
-	}
-
-	// Setting up for session to successfully get value and
-	// have right user info
-	mockSessionStore.GetFunc = getFuncSessionWithValues
-	authConfig.SessionStore = mockSessionStore
-
-	// Testing successful getting session from store
-	// with right user info
-	rr = httptest.NewRecorder()
-	h.ServeHTTP(rr, request)
-
-	if rr.Code != http.StatusOK {
-		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
-	}
-}
-
 func TestGroupMiddleware(t *testing.T) {
 	queryGroups := "queryGroups"
 
@@ -401,8 +81,8 @@ func TestGroupMiddleware(t *testing.T) {
 		HasKeyFunc: hasKeyCacheFunc,
 	}
 	mockDB := &dbtest.MockDB{
-		RecoverErrorFunc: func(err error) bool {
-			return true
+		RecoverErrorFunc: func(err error) (httputil.DBInterfaceV2, error) {
+			return nil, nil
 		},
 	}
 	queryForGroups := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
@@ -633,8 +313,8 @@ func TestRoutingMiddleware(t *testing.T) {
 		HasKeyFunc: hasKeyCacheFunc,
 	}
 	mockDB := &dbtest.MockDB{
-		RecoverErrorFunc: func(err error) bool {
-			return true
+		RecoverErrorFunc: func(err error) (httputil.DBInterfaceV2, error) {
+			return nil, nil
 		},
 	}
 	queryForRouting := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
@@ -819,3 +499,117 @@ func TestRoutingMiddleware(t *testing.T) {
 		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
 	}
 }
+
+func TestGroupAndRoutingMiddlewareBatchCache(t *testing.T) {
+	getCalls := 0
+	mockCache := &cachetest.MockCache{
+		GetFunc: func(key string) ([]byte, error) {
+			getCalls++
+			return getCacheFunc(key)
+		},
+		MGetFunc: func(keys ...string) ([][]byte, error) {
+			values := make([][]byte, len(keys))
+
+			for i, k := range keys {
+				values[i], _ = getCacheFunc(k)
+			}
+
+			return values, nil
+		},
+	}
+	queryDB := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		return nil, errors.New(generalErr)
+	}
+	pathRegex := func(r *http.Request) (string, error) {
+		return "/url1", nil
+	}
+
+	groupHandler := NewGroupHandler(nil, queryDB, GroupHandlerConfig{CacheStore: mockCache})
+	routingHandler := NewRoutingHandler(nil, queryDB, pathRegex, nil, RoutingHandlerConfig{CacheStore: mockCache})
+	h := groupHandler.MiddlewareFunc(routingHandler.MiddlewareFunc(mockHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/url1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if getCalls != 0 {
+		t.Errorf("routing handler should use urls fetched by group handler; got %d get calls", getCalls)
+	}
+}
+
+func TestGroupAndRoutingMiddlewarePermissionChange(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	pubsub := cachetest.NewMemoryPubSub()
+
+	cache.Set(fmt.Sprintf(GroupKey, mUser.Email), map[string]bool{"User": true}, 0)
+	cache.Set(fmt.Sprintf(URLKey, mUser.Email), map[string]bool{"/url2": true}, 0)
+
+	queryCalls := 0
+	queryDB := func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		queryCalls++
+
+		if r.Context().Value(GroupCtxKey) == nil {
+			return json.Marshal(groupMap)
+		}
+
+		return json.Marshal(map[string]bool{"/url1": true})
+	}
+	pathRegex := func(r *http.Request) (string, error) {
+		return "/url1", nil
+	}
+
+	groupHandler := NewGroupHandler(nil, queryDB, GroupHandlerConfig{CacheStore: cache, PubSub: pubsub})
+	routingHandler := NewRoutingHandler(nil, queryDB, pathRegex, nil, RoutingHandlerConfig{CacheStore: cache, PubSub: pubsub})
+
+	defer groupHandler.Close()
+	defer routingHandler.Close()
+
+	var groups map[string]bool
+
+	h := groupHandler.MiddlewareFunc(routingHandler.MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			groups = GetUserGroups(r)
+		}),
+	))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/url1", nil)
+		req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Stale cached urls don't allow url
+	if rr := serve(); rr.Code != http.StatusForbidden {
+		t.Errorf(statusErrTxt, http.StatusForbidden, rr.Code)
+	}
+
+	if err := PublishPermissionChange(pubsub, mUser.Email); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if rr := serve(); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if queryCalls != 2 {
+		t.Errorf("should query groups and urls from db; got %d queries", queryCalls)
+	}
+	if !groups["Admin"] {
+		t.Errorf("should have refreshed groups; got %v", groups)
+	}
+
+	// Refreshed values are read from cache
+	if rr := serve(); rr.Code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+	if queryCalls != 2 {
+		t.Errorf("should read refreshed values from cache; got %d queries", queryCalls)
+	}
+}
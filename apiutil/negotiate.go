@@ -0,0 +1,377 @@
+package apiutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TravisS25/httputil"
+)
+
+// Encoder writes payload to w in the format of the media type it
+// was registered with through RegisterEncoder
+type Encoder func(w io.Writer, payload interface{}) error
+
+var (
+	encoderMu sync.RWMutex
+	encoders  = map[string]Encoder{
+		"application/json": encodeJSON,
+		"application/xml":  encodeXML,
+		"text/xml":         encodeXML,
+		"text/csv":         encodeCSV,
+	}
+
+	// encoderSupports determines whether encoder of media type can
+	// encode a payload for encoders that only support certain types
+	encoderSupports = map[string]func(payload interface{}) bool{}
+)
+
+// RegisterEncoder registers encoder for mediaType to be used by
+// Negotiate, replacing any existing encoder of mediaType
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	encoders[strings.ToLower(mediaType)] = encoder
+}
+
+// Negotiate inspects the Accept header of r and writes payload with
+// the best matching registered encoder, defaulting to json
+//
+// By default json, xml, csv, MessagePack and Protocol Buffers, when
+// payload is a proto.Message, are supported where csv requires payload
+// to be httputil.Rower, []map[string]interface{}, a slice of structs,
+// ListResponse, like the list payload of Resource, or a map with the
+// rows under the "data" key
+func Negotiate(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	mediaType, encoder := negotiateEncoder(r.Header.Get("Accept"), payload)
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Add("Vary", "Accept")
+
+	// Encode to memory first so errors can still be written
+	var buf strings.Builder
+
+	if err := encoder(&buf, payload); err != nil {
+		w.Header().Del("Content-Type")
+		ServerError(w, err, "")
+		return
+	}
+
+	io.WriteString(w, buf.String())
+}
+
+func negotiateEncoder(accept string, payload interface{}) (string, Encoder) {
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, v := range ranges {
+		if encoder, ok := encoders[v.mediaType]; ok {
+			if supports, ok := encoderSupports[v.mediaType]; !ok || supports(payload) {
+				return v.mediaType, encoder
+			}
+		}
+
+		if v.mediaType == "*/*" || v.mediaType == "application/*" {
+			break
+		}
+	}
+
+	return "application/json", encoders["application/json"]
+}
+
+func encodeJSON(w io.Writer, payload interface{}) error {
+	return json.NewEncoder(w).Encode(payload)
+}
+
+// encodeXML encodes payload with encoding/xml, converting maps and
+// slices, which encoding/xml doesn't support, into elements
+func encodeXML(w io.Writer, payload interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+
+	if err := encodeXMLValue(enc, "response", reflect.ValueOf(payload)); err != nil {
+		return err
+	}
+
+	return enc.Flush()
+}
+
+func encodeXMLValue(enc *xml.Encoder, name string, val reflect.Value) error {
+	for val.IsValid() && (val.Kind() == reflect.Interface || val.Kind() == reflect.Ptr) {
+		if val.IsNil() {
+			return enc.EncodeElement("", xml.StartElement{Name: xml.Name{Local: name}})
+		}
+
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return enc.EncodeElement("", xml.StartElement{Name: xml.Name{Local: name}})
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch val.Kind() {
+	case reflect.Map:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, val.Len())
+		keyVals := make(map[string]reflect.Value, val.Len())
+
+		for _, k := range val.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			keys = append(keys, key)
+			keyVals[key] = val.MapIndex(k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if err := encodeXMLValue(enc, k, keyVals[k]); err != nil {
+				return err
+			}
+		}
+
+		return enc.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+			return enc.EncodeElement(string(val.Bytes()), start)
+		}
+
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+
+		for i := 0; i < val.Len(); i++ {
+			if err := encodeXMLValue(enc, "item", val.Index(i)); err != nil {
+				return err
+			}
+		}
+
+		return enc.EncodeToken(start.End())
+	}
+
+	return enc.EncodeElement(val.Interface(), start)
+}
+
+// encodeCSV encodes rows of payload with a header row of the columns
+func encodeCSV(w io.Writer, payload interface{}) error {
+	columns, rows, err := csvRows(payload)
+
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+
+	if err = writer.Write(columns); err != nil {
+		return err
+	}
+
+	if err = writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+func csvRows(payload interface{}) ([]string, [][]string, error) {
+	switch v := payload.(type) {
+	case map[string]interface{}:
+		if data, ok := v["data"]; ok {
+			payload = data
+		}
+	case ListResponse:
+		payload = v.Data
+	case *ListResponse:
+		payload = v.Data
+	}
+
+	if rower, ok := payload.(httputil.Rower); ok {
+		// Columns can't be read once rows are closed after iterating
+		columns, err := rower.Columns()
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		maps, err := rowerToMaps(rower)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return columns, mapsToCSV(columns, maps), nil
+	}
+
+	if maps, ok := payload.([]map[string]interface{}); ok {
+		columns := make([]string, 0)
+
+		if len(maps) > 0 {
+			for k := range maps[0] {
+				columns = append(columns, k)
+			}
+
+			sort.Strings(columns)
+		}
+
+		return columns, mapsToCSV(columns, maps), nil
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(payload))
+
+	if val.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("apiutil: can't encode %T as csv", payload)
+	}
+
+	// Marshal through json so struct tags are honored
+	b, err := json.Marshal(val.Interface())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var maps []map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err = dec.Decode(&maps); err != nil {
+		return nil, nil, fmt.Errorf("apiutil: can't encode %T as csv", payload)
+	}
+
+	var columns []string
+	elemType := val.Type().Elem()
+
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() == reflect.Struct {
+		columns = jsonFieldNames(elemType)
+	} else if len(maps) > 0 {
+		for k := range maps[0] {
+			columns = append(columns, k)
+		}
+
+		sort.Strings(columns)
+	}
+
+	return columns, mapsToCSV(columns, maps), nil
+}
+
+func mapsToCSV(columns []string, maps []map[string]interface{}) [][]string {
+	rows := make([][]string, 0, len(maps))
+
+	for _, m := range maps {
+		row := make([]string, len(columns))
+
+		for i, c := range columns {
+			switch v := m[c].(type) {
+			case nil:
+			case string:
+				row[i] = v
+			case []byte:
+				row[i] = string(v)
+			case map[string]interface{}, []interface{}:
+				b, _ := json.Marshal(v)
+				row[i] = string(b)
+			default:
+				row[i] = fmt.Sprint(v)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// jsonFieldNames returns json names of fields of typ in order
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				names = append(names, jsonFieldNames(embedded)...)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
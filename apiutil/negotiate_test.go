@@ -0,0 +1,59 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	type item struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		Note string `json:"-"`
+	}
+
+	payload := map[string]interface{}{
+		"data":  []item{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar, baz"}},
+		"count": 2,
+	}
+
+	tests := []struct {
+		name                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{"default", "", "application/json", `{"count":2,"data":[{"id":1,"name":"foo"},{"id":2,"name":"bar, baz"}]}` + "\n"},
+		{"wildcard", "*/*", "application/json", `{"count":2,"data":[{"id":1,"name":"foo"},{"id":2,"name":"bar, baz"}]}` + "\n"},
+		{"csv", "text/csv", "text/csv", "id,name\n1,foo\n2,\"bar, baz\"\n"},
+		{"quality", "application/json;q=0.5, text/csv", "text/csv", "id,name\n1,foo\n2,\"bar, baz\"\n"},
+		{"unsupported", "image/png", "application/json", `{"count":2,"data":[{"id":1,"name":"foo"},{"id":2,"name":"bar, baz"}]}` + "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", test.accept)
+			rr := httptest.NewRecorder()
+			Negotiate(rr, req, payload)
+
+			if rr.Header().Get("Content-Type") != test.expectedContentType {
+				v.Errorf("got content type %s; want %s", rr.Header().Get("Content-Type"), test.expectedContentType)
+			}
+			if rr.Body.String() != test.expectedBody {
+				v.Errorf("got body %q; want %q", rr.Body.String(), test.expectedBody)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	rr := httptest.NewRecorder()
+	Negotiate(rr, req, map[string]interface{}{"id": 1, "tags": []string{"a"}})
+
+	if !strings.Contains(rr.Body.String(), "<response><id>1</id><tags><item>a</item></tags></response>") {
+		t.Errorf("got xml body %s", rr.Body.String())
+	}
+}
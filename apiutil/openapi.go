@@ -0,0 +1,609 @@
+package apiutil
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// OpenAPIPath is default path OpenAPIHandler should be registered to
+	OpenAPIPath = "/openapi.json"
+
+	openAPIVersion = "3.0.3"
+)
+
+// OpenAPIDocument is generated OpenAPI 3 document
+type OpenAPIDocument map[string]interface{}
+
+// OpenAPIInfo is the "info" object of generated document
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// OpenAPIResource describes a single resource of generated document
+type OpenAPIResource struct {
+	// Path is path of list and create operations
+	// Detail, update and delete operations are at Path + "/{id}"
+	Path string
+
+	// Tag is tag used to group operations of resource
+	// Default is Path without leading slash
+	Tag string
+
+	// Resource, if set, will determine the fields, params and operations
+	// of resource where create and update are only added if resource
+	// has a validator
+	Resource *Resource
+
+	// Fields and ParamConf are used to describe list query params
+	// if Resource is not set
+	Fields    map[string]queryutil.FieldConfig
+	ParamConf queryutil.ParamConfig
+
+	// Methods are http methods to generate operations for if Resource
+	// is not set
+	// Default is GET
+	Methods []string
+
+	// Form is used to generate request schema of create and update from
+	// its json tags
+	Form interface{}
+
+	// Response is used to generate response schema of list and detail
+	// from its json tags
+	Response interface{}
+}
+
+// OpenAPIConfig is config struct used in conjunction with GenerateOpenAPI
+type OpenAPIConfig struct {
+	Info      OpenAPIInfo
+	Resources []OpenAPIResource
+}
+
+// openAPIErrorSchema is schema of errors written by WriteError
+var openAPIErrorSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"code":    map[string]interface{}{"type": "string"},
+		"message": map[string]interface{}{"type": "string"},
+		"fields": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+	},
+}
+
+// GenerateOpenAPI generates OpenAPI 3 document of resources within config
+func GenerateOpenAPI(config OpenAPIConfig) OpenAPIDocument {
+	paths := make(map[string]interface{})
+
+	for _, resource := range config.Resources {
+		generateResourcePaths(paths, resource)
+	}
+
+	info := map[string]interface{}{
+		"title":   config.Info.Title,
+		"version": config.Info.Version,
+	}
+
+	if config.Info.Description != "" {
+		info["description"] = config.Info.Description
+	}
+
+	return OpenAPIDocument{
+		"openapi": openAPIVersion,
+		"info":    info,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"responses": map[string]interface{}{
+				"FormError": openAPIResponse(
+					"Form validation errors keyed by field",
+					"application/json",
+					openAPIErrorSchema,
+				),
+				"QueryParamError": openAPIResponse(
+					"Invalid filter, sort or group query param",
+					"application/json",
+					openAPIErrorSchema,
+				),
+				"NotFound": openAPIResponse(
+					"Resource not found",
+					"application/json",
+					openAPIErrorSchema,
+				),
+				"ServerError": openAPIResponse(
+					"Server error",
+					"application/json",
+					openAPIErrorSchema,
+				),
+			},
+		},
+	}
+}
+
+// OpenAPIHandler returns handler that writes generated document of config
+// The document is only generated on first request
+func OpenAPIHandler(config OpenAPIConfig) http.HandlerFunc {
+	var once sync.Once
+	var payload []byte
+	var err error
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			payload, err = json.Marshal(GenerateOpenAPI(config))
+		})
+
+		if HasServerError(w, err, "") {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+	}
+}
+
+func generateResourcePaths(paths map[string]interface{}, resource OpenAPIResource) {
+	tag := resource.Tag
+
+	if tag == "" {
+		tag = strings.Trim(resource.Path, "/")
+	}
+
+	fields := resource.Fields
+	paramConf := resource.ParamConf
+	idParam := "id"
+	methods := resource.Methods
+
+	if resource.Resource != nil {
+		fields = resource.Resource.config.Fields
+		paramConf = resource.Resource.config.ParamConf
+		idParam = resource.Resource.config.IDParam
+		methods = []string{http.MethodGet, http.MethodDelete}
+
+		if resource.Resource.config.Validator != nil {
+			methods = append(methods, http.MethodPost, http.MethodPut)
+		}
+	}
+
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet}
+	}
+
+	hasMethod := func(method string) bool {
+		for _, m := range methods {
+			if strings.EqualFold(m, method) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	responseSchema := map[string]interface{}{"type": "object"}
+	formSchema := map[string]interface{}{"type": "object"}
+
+	if resource.Response != nil {
+		responseSchema = openAPISchema(reflect.TypeOf(resource.Response), nil)
+	}
+	if resource.Form != nil {
+		formSchema = openAPISchema(reflect.TypeOf(resource.Form), nil)
+	}
+
+	listPath := make(map[string]interface{})
+	detailPath := make(map[string]interface{})
+	idParameter := map[string]interface{}{
+		"name":     idParam,
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]interface{}{"type": "integer"},
+	}
+	requestBody := map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": formSchema},
+		},
+	}
+
+	if hasMethod(http.MethodGet) {
+		listPath["get"] = map[string]interface{}{
+			"tags":       []string{tag},
+			"summary":    "List " + tag,
+			"parameters": openAPIListParams(fields, paramConf),
+			"responses": map[string]interface{}{
+				"200": openAPIResponse("List of results", "application/json", map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"data": map[string]interface{}{
+							"type":  "array",
+							"items": responseSchema,
+						},
+						"count": map[string]interface{}{"type": "integer"},
+						"pagination": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"take":          map[string]interface{}{"type": "integer"},
+								"skip":          map[string]interface{}{"type": "integer"},
+								"requestedTake": map[string]interface{}{"type": "integer"},
+								"requestedSkip": map[string]interface{}{"type": "integer"},
+								"total":         map[string]interface{}{"type": "integer"},
+							},
+						},
+					},
+				}),
+				"406": openAPIRef("QueryParamError"),
+				"500": openAPIRef("ServerError"),
+			},
+		}
+		detailPath["get"] = map[string]interface{}{
+			"tags":       []string{tag},
+			"summary":    "Get " + tag,
+			"parameters": []interface{}{idParameter},
+			"responses": map[string]interface{}{
+				"200": openAPIResponse("Result", "application/json", responseSchema),
+				"404": openAPIRef("NotFound"),
+				"500": openAPIRef("ServerError"),
+			},
+		}
+	}
+
+	if hasMethod(http.MethodPost) {
+		listPath["post"] = map[string]interface{}{
+			"tags":        []string{tag},
+			"summary":     "Create " + tag,
+			"requestBody": requestBody,
+			"responses": map[string]interface{}{
+				"201": openAPIResponse("Created", "application/json", map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id": map[string]interface{}{"type": "integer"},
+					},
+				}),
+				"406": openAPIRef("FormError"),
+				"500": openAPIRef("ServerError"),
+			},
+		}
+	}
+
+	if hasMethod(http.MethodPut) {
+		detailPath["put"] = map[string]interface{}{
+			"tags":        []string{tag},
+			"summary":     "Update " + tag,
+			"parameters":  []interface{}{idParameter},
+			"requestBody": requestBody,
+			"responses": map[string]interface{}{
+				"200": openAPIResponse("Updated", "application/json", map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id": map[string]interface{}{"type": "integer"},
+					},
+				}),
+				"404": openAPIRef("NotFound"),
+				"406": openAPIRef("FormError"),
+				"500": openAPIRef("ServerError"),
+			},
+		}
+	}
+
+	if hasMethod(http.MethodDelete) {
+		detailPath["delete"] = map[string]interface{}{
+			"tags":       []string{tag},
+			"summary":    "Delete " + tag,
+			"parameters": []interface{}{idParameter},
+			"responses": map[string]interface{}{
+				"204": map[string]interface{}{"description": "Deleted"},
+				"404": openAPIRef("NotFound"),
+				"500": openAPIRef("ServerError"),
+			},
+		}
+	}
+
+	if len(listPath) > 0 {
+		paths[resource.Path] = listPath
+	}
+	if len(detailPath) > 0 {
+		paths[fmt.Sprintf("%s/{%s}", strings.TrimRight(resource.Path, "/"), idParam)] = detailPath
+	}
+}
+
+// openAPIListParams describes the query params used by
+// queryutil#GetQueriedAndCountResults based on fields
+func openAPIListParams(fields map[string]queryutil.FieldConfig, paramConf queryutil.ParamConfig) []interface{} {
+	var filterFields, sortFields, groupFields, selectFields []string
+
+	for k, v := range fields {
+		if v.OperationConf.CanFilterBy {
+			filterFields = append(filterFields, k)
+		}
+		if v.OperationConf.CanSortBy {
+			sortFields = append(sortFields, k)
+		}
+		if v.OperationConf.CanGroupBy {
+			groupFields = append(groupFields, k)
+		}
+		if v.OperationConf.CanSelect {
+			selectFields = append(selectFields, k)
+		}
+	}
+
+	sort.Strings(filterFields)
+	sort.Strings(sortFields)
+	sort.Strings(groupFields)
+	sort.Strings(selectFields)
+
+	paramName := func(name *string, defaultName string) string {
+		if name != nil {
+			return *name
+		}
+
+		return defaultName
+	}
+	fieldEnum := func(fields []string) map[string]interface{} {
+		schema := map[string]interface{}{"type": "string"}
+
+		if len(fields) > 0 {
+			schema["enum"] = fields
+		}
+
+		return schema
+	}
+
+	params := []interface{}{
+		map[string]interface{}{
+			"name":   paramName(paramConf.Take, "take"),
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "integer", "minimum": 0},
+		},
+		map[string]interface{}{
+			"name":   paramName(paramConf.Skip, "skip"),
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "integer", "minimum": 0},
+		},
+	}
+
+	if len(filterFields) > 0 {
+		params = append(params, map[string]interface{}{
+			"name":        paramName(paramConf.Filter, "filters"),
+			"in":          "query",
+			"description": "JSON array of filters",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"field":    fieldEnum(filterFields),
+								"operator": fieldEnum(queryutil.FilterOperators()),
+								"value":    map[string]interface{}{},
+							},
+							"required": []string{"field", "operator"},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	if len(sortFields) > 0 {
+		params = append(params, map[string]interface{}{
+			"name":        paramName(paramConf.Sort, "sorts"),
+			"in":          "query",
+			"description": "JSON array of sorts",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"field": fieldEnum(sortFields),
+								"dir":   fieldEnum([]string{"asc", "desc"}),
+							},
+							"required": []string{"field", "dir"},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	if len(groupFields) > 0 {
+		params = append(params, map[string]interface{}{
+			"name":        paramName(paramConf.Group, "groups"),
+			"in":          "query",
+			"description": "JSON array of groups",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"field": fieldEnum(groupFields),
+								"dir":   fieldEnum([]string{"asc", "desc"}),
+							},
+							"required": []string{"field"},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	if len(selectFields) > 0 {
+		params = append(params, map[string]interface{}{
+			"name":        paramName(paramConf.Fields, "fields"),
+			"in":          "query",
+			"description": "Comma separated fields to return",
+			"style":       "form",
+			"explode":     false,
+			"schema": map[string]interface{}{
+				"type":  "array",
+				"items": fieldEnum(selectFields),
+			},
+		})
+	}
+
+	return params
+}
+
+func openAPIResponse(description, contentType string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/responses/" + name}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// openAPISchema generates schema of typ based off of its json tags
+// seen is used to stop recursive types from looping forever
+func openAPISchema(typ reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	nullable := false
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+		nullable = true
+	}
+
+	schema := openAPITypeSchema(typ, seen)
+
+	if nullable {
+		schema["nullable"] = true
+	}
+
+	return schema
+}
+
+func openAPITypeSchema(typ reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	if typ == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	// Types with custom marshalling can't be described reliably
+	if typ.Implements(jsonMarshalerType) || reflect.PtrTo(typ).Implements(jsonMarshalerType) {
+		return map[string]interface{}{}
+	}
+	if typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+
+		return map[string]interface{}{
+			"type":  "array",
+			"items": openAPISchema(typ.Elem(), seen),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": openAPISchema(typ.Elem(), seen),
+		}
+	case reflect.Struct:
+		if seen[typ] {
+			return map[string]interface{}{"type": "object"}
+		}
+
+		childSeen := make(map[reflect.Type]bool, len(seen)+1)
+
+		for k := range seen {
+			childSeen[k] = true
+		}
+
+		childSeen[typ] = true
+		properties := make(map[string]interface{})
+		openAPIStructProperties(typ, properties, childSeen)
+
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+	}
+
+	return map[string]interface{}{}
+}
+
+func openAPIStructProperties(typ reflect.Type, properties map[string]interface{}, seen map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		// Embedded structs without a json name are flattened
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				openAPIStructProperties(embedded, properties, seen)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := openAPISchema(field.Type, seen)
+
+		for _, opt := range parts[1:] {
+			if opt == "string" {
+				schema["type"] = "string"
+				delete(schema, "format")
+			}
+		}
+
+		properties[name] = schema
+	}
+}
@@ -0,0 +1,125 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+func TestGenerateOpenAPI(t *testing.T) {
+	type userForm struct {
+		Email string   `json:"email"`
+		Age   *int     `json:"age"`
+		Tags  []string `json:"tags"`
+	}
+
+	type user struct {
+		ID        int64     `json:"id,string"`
+		Email     string    `json:"email"`
+		CreatedAt time.Time `json:"created_at"`
+		Secret    string    `json:"-"`
+	}
+
+	config := OpenAPIConfig{
+		Info: OpenAPIInfo{Title: "test", Version: "1.0"},
+		Resources: []OpenAPIResource{
+			{
+				Path: "/api/users",
+				Fields: map[string]queryutil.FieldConfig{
+					"email": {
+						DBField:       "user.email",
+						OperationConf: queryutil.OperationConfig{CanFilterBy: true, CanSortBy: true},
+					},
+				},
+				Methods:  []string{http.MethodGet, http.MethodPost},
+				Form:     userForm{},
+				Response: user{},
+			},
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	OpenAPIHandler(config).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name string `json:"name"`
+			} `json:"parameters"`
+			RequestBody *struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]map[string]interface{} `json:"properties"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+		} `json:"paths"`
+	}
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	list, ok := doc.Paths["/api/users"]
+
+	if !ok {
+		t.Fatalf("should have list path")
+	}
+
+	if _, ok = doc.Paths["/api/users/{id}"]["get"]; !ok {
+		t.Errorf("should have detail get operation")
+	}
+	if _, ok = doc.Paths["/api/users/{id}"]["delete"]; ok {
+		t.Errorf("should not have delete operation")
+	}
+
+	params := make(map[string]bool)
+
+	for _, p := range list["get"].Parameters {
+		params[p.Name] = true
+	}
+
+	for _, v := range []string{"take", "skip", "filters", "sorts"} {
+		if !params[v] {
+			t.Errorf("list should have param '%s'", v)
+		}
+	}
+
+	if params["groups"] {
+		t.Errorf("list should not have groups param")
+	}
+
+	props := list["post"].RequestBody.Content["application/json"].Schema.Properties
+
+	if props["age"]["nullable"] != true {
+		t.Errorf("age should be nullable")
+	}
+	if props["tags"]["type"] != "array" {
+		t.Errorf("got tags type %v; want array", props["tags"]["type"])
+	}
+
+	schema := openAPISchema(timeType, nil)
+
+	if schema["format"] != "date-time" {
+		t.Errorf("got time format %v; want date-time", schema["format"])
+	}
+
+	schema = openAPISchema(reflect.TypeOf(user{}), nil)
+	properties := schema["properties"].(map[string]interface{})
+
+	if _, ok = properties["Secret"]; ok {
+		t.Errorf("should not have ignored field")
+	}
+	if properties["id"].(map[string]interface{})["type"] != "string" {
+		t.Errorf("id with string option should be string")
+	}
+}
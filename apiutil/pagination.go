@@ -0,0 +1,156 @@
+package apiutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// TotalCountHeader is header set by Paginate with the total
+	// number of results
+	TotalCountHeader = "X-Total-Count"
+
+	// LinkHeader is header set by Paginate with RFC 5988 links
+	LinkHeader = "Link"
+)
+
+// ListResponse is json envelope of list endpoints eg. Resource#List
+type ListResponse struct {
+	Data  interface{} `json:"data"`
+	Count int         `json:"count"`
+
+	// Pagination is take and skip applied to list, see
+	// queryutil#ParsePagination, so clients know if theirs were capped
+	Pagination *queryutil.Pagination `json:"pagination,omitempty"`
+
+	// Debug is set if queries are debugged, see queryutil#DebugEnabled
+	Debug *queryutil.QueryDebug `json:"debug,omitempty"`
+}
+
+// NewListResponse returns ListResponse of data with count stamped into
+// pagination as its total
+func NewListResponse(data interface{}, count int, pagination queryutil.Pagination) ListResponse {
+	pagination.Total = count
+
+	return ListResponse{
+		Data:       data,
+		Count:      count,
+		Pagination: &pagination,
+	}
+}
+
+// PaginateConfig is config struct used in conjunction with PaginateWithConfig
+type PaginateConfig struct {
+	// TakeParam is query param used for number of results per page
+	// Default is "take"
+	TakeParam string
+
+	// SkipParam is query param used for number of results to skip
+	// Default is "skip"
+	SkipParam string
+
+	// DisableLinks will not set the Link header
+	DisableLinks bool
+
+	// DisableTotalCount will not set the X-Total-Count header
+	DisableTotalCount bool
+}
+
+// Paginate sets the Link and X-Total-Count headers of w based on total,
+// usually the count returned from queryutil#GetQueriedAndCountResults,
+// along with the take and skip of the current request
+//
+// Links are built from the url of r with only the take and skip
+// query params changed so any filters or sorts are kept
+func Paginate(w http.ResponseWriter, r *http.Request, total, take, skip int) {
+	PaginateWithConfig(w, r, total, take, skip, PaginateConfig{})
+}
+
+// PaginateWithConfig is the same as Paginate but allows changing the
+// query param names and which headers are set
+func PaginateWithConfig(w http.ResponseWriter, r *http.Request, total, take, skip int, config PaginateConfig) {
+	if config.TakeParam == "" {
+		config.TakeParam = "take"
+	}
+	if config.SkipParam == "" {
+		config.SkipParam = "skip"
+	}
+
+	if !config.DisableTotalCount {
+		w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	}
+
+	if config.DisableLinks || take <= 0 {
+		return
+	}
+
+	if skip < 0 {
+		skip = 0
+	}
+
+	lastSkip := 0
+
+	if total > 0 {
+		lastSkip = ((total - 1) / take) * take
+	}
+
+	links := make([]string, 0, 4)
+	addLink := func(rel string, linkSkip int) {
+		links = append(links, fmt.Sprintf(
+			`<%s>; rel="%s"`,
+			paginationURL(r, config, take, linkSkip),
+			rel,
+		))
+	}
+
+	addLink("first", 0)
+
+	if skip > 0 {
+		prevSkip := skip - take
+
+		if prevSkip < 0 {
+			prevSkip = 0
+		}
+
+		addLink("prev", prevSkip)
+	}
+
+	if skip+take < total {
+		addLink("next", skip+take)
+	}
+
+	addLink("last", lastSkip)
+	w.Header().Set(LinkHeader, strings.Join(links, ", "))
+}
+
+func paginationURL(r *http.Request, config PaginateConfig, take, skip int) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set(config.TakeParam, strconv.Itoa(take))
+	query.Set(config.SkipParam, strconv.Itoa(skip))
+	u.RawQuery = query.Encode()
+
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+
+	if u.Host != "" && u.Scheme == "" {
+		u.Scheme = "http"
+
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			u.Scheme = "https"
+		}
+	}
+
+	return (&url.URL{
+		Scheme:   u.Scheme,
+		Host:     u.Host,
+		Path:     u.Path,
+		RawQuery: u.RawQuery,
+	}).String()
+}
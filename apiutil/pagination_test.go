@@ -0,0 +1,57 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+func TestPaginate(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/api/users?take=10&skip=10&filters=foo", nil)
+	w := httptest.NewRecorder()
+	Paginate(w, r, 35, 10, 10)
+
+	if count := w.Header().Get(TotalCountHeader); count != "35" {
+		t.Errorf("should have total count 35; got %s", count)
+	}
+
+	link := w.Header().Get(LinkHeader)
+
+	for _, v := range []string{
+		`<http://example.com/api/users?filters=foo&skip=0&take=10>; rel="first"`,
+		`<http://example.com/api/users?filters=foo&skip=0&take=10>; rel="prev"`,
+		`<http://example.com/api/users?filters=foo&skip=20&take=10>; rel="next"`,
+		`<http://example.com/api/users?filters=foo&skip=30&take=10>; rel="last"`,
+	} {
+		if !strings.Contains(link, v) {
+			t.Errorf("link header should contain %s; got %s", v, link)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	Paginate(w, r, 35, 10, 30)
+
+	if link = w.Header().Get(LinkHeader); strings.Contains(link, `rel="next"`) {
+		t.Errorf("last page should not have next link; got %s", link)
+	}
+}
+
+func TestNewListResponse(t *testing.T) {
+	requested := 500
+	res := NewListResponse([]int{1, 2}, 35, queryutil.Pagination{Take: 100, RequestedTake: &requested})
+
+	b, err := json.Marshal(res)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := `{"data":[1,2],"count":35,"pagination":{"take":100,"skip":0,"requestedTake":500,"total":35}}`
+
+	if string(b) != expected {
+		t.Errorf("should encode %s; got %s", expected, string(b))
+	}
+}
@@ -0,0 +1,78 @@
+package apiutil
+
+import (
+	"sync"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+const (
+	// PermissionChangeChannel is pub/sub channel emails of users whose
+	// groups or urls have changed are published to
+	PermissionChangeChannel = "permission-changes"
+)
+
+// PublishPermissionChange notifies every GroupHandler and RoutingHandler
+// subscribed to pubsub that groups or urls of user with userEmail have
+// changed, so they're queried from database and refreshed in cache on
+// the next request of user instead of waiting for cached values to expire
+// This should be called by handlers that change groups or urls of a
+// user after their transaction is committed
+func PublishPermissionChange(pubsub cacheutil.PubSub, userEmail string) error {
+	return pubsub.Publish(PermissionChangeChannel, userEmail)
+}
+
+// staleUsers is set of emails of users whose cached groups or urls are
+// stale, populated by PublishPermissionChange notifications
+type staleUsers struct {
+	mu     sync.Mutex
+	emails map[string]bool
+	close  func() error
+}
+
+// subscribeStaleUsers returns *staleUsers subscribed to
+// PermissionChangeChannel of pubsub
+// Returns nil if pubsub is nil or subscribing fails, in which case cached
+// values are used until they expire
+func subscribeStaleUsers(pubsub cacheutil.PubSub) *staleUsers {
+	if pubsub == nil {
+		return nil
+	}
+
+	s := &staleUsers{emails: make(map[string]bool)}
+	closeFn, err := pubsub.Subscribe(PermissionChangeChannel, s.add)
+
+	if err != nil {
+		httputil.Logger.Errorf("apiutil: subscribe to permission changes err: %s", err.Error())
+		return nil
+	}
+
+	s.close = closeFn
+	return s
+}
+
+func (s *staleUsers) add(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.emails[email] = true
+}
+
+// take returns whether email is stale and removes it from set so only
+// the next request of user refreshes cache
+func (s *staleUsers) take(email string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.emails[email] {
+		return false
+	}
+
+	delete(s.emails, email)
+	return true
+}
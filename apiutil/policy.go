@@ -0,0 +1,21 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+// SendFilteredPayload is SendPayload that first removes fields of payload
+// not visible to the groups of the user of r, set by GroupHandler, based
+// on policy, so one endpoint can serve differently privileged users
+// See queryutil#FieldPolicy#Filter for how fields are matched
+func SendFilteredPayload(w http.ResponseWriter, r *http.Request, policy queryutil.FieldPolicy, payload interface{}) {
+	filtered, err := policy.Filter(payload, GetGroupNames(r))
+
+	if HasError(w, err) {
+		return
+	}
+
+	SendPayload(w, filtered)
+}
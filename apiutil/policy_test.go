@@ -0,0 +1,33 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+func TestSendFilteredPayload(t *testing.T) {
+	policy := queryutil.FieldPolicy{"salary": {"Admin"}}
+	payload := func() map[string]interface{} {
+		return map[string]interface{}{"name": "foo", "salary": 10}
+	}
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	SendFilteredPayload(rr, r, policy, payload())
+
+	if rr.Body.String() != `{"name":"foo"}` {
+		t.Errorf("anonymous user should not see salary; got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	r = r.WithContext(context.WithValue(r.Context(), GroupCtxKey, map[string]bool{"Admin": true}))
+	SendFilteredPayload(rr, r, policy, payload())
+
+	if rr.Body.String() != `{"name":"foo","salary":10}` {
+		t.Errorf("admin should see salary; got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,138 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/ctxutil"
+	"github.com/TravisS25/httputil/httpclientutil"
+)
+
+var (
+	// PrincipalCtxKey is key used to store Principal within context by
+	// WithPrincipal
+	PrincipalCtxKey = MiddlewareKey{KeyName: "principal"}
+)
+
+// Principal is snapshot of the user of request, along with the request
+// id and language, that can be marshalled to json and attached to
+// background tasks enqueued by the request so work done by the task is
+// still attributed to the user eg. in audit logs
+//
+// Principal is taken with PrincipalFromRequest within the handler and
+// restored into the context of the worker with WithPrincipal
+type Principal struct {
+	// UserID is id of user, see GetUserID
+	UserID string `json:"userID"`
+
+	// Email is email of user
+	Email string `json:"email,omitempty"`
+
+	// ImpersonatorID is id of user impersonating UserID, if any
+	ImpersonatorID string `json:"impersonatorID,omitempty"`
+
+	// Groups are names of groups of user, see GetGroupNames
+	Groups []string `json:"groups,omitempty"`
+
+	// RequestID is id of request, see httpclientutil#RequestID
+	RequestID string `json:"requestID,omitempty"`
+
+	// Language is language of request, see LanguageFromRequest
+	Language string `json:"language,omitempty"`
+
+	// IP is ip of client of request, see ClientIP
+	IP string `json:"ip,omitempty"`
+}
+
+// PrincipalFromRequest returns Principal of user of r
+// Request id is taken from context of r, else the
+// httpclientutil#RequestIDHeader header of r
+func PrincipalFromRequest(r *http.Request) Principal {
+	p := Principal{
+		UserID:    GetUserID(r),
+		Groups:    GetGroupNames(r),
+		RequestID: ctxutil.RequestIDFrom(r.Context()),
+		Language:  LanguageFromRequest(r),
+		IP:        ClientIP(r),
+	}
+
+	if user, ok := ctxutil.UserFrom(r.Context()); ok {
+		p.Email = user.Email
+	}
+
+	if p.RequestID == "" {
+		p.RequestID = r.Header.Get(httpclientutil.RequestIDHeader)
+	}
+	if IsImpersonating(r) {
+		p.ImpersonatorID = GetActorID(r)
+	}
+
+	sort.Strings(p.Groups)
+	return p
+}
+
+// WithPrincipal returns ctx with p, which restores the user, groups,
+// language and client ip of p so GetUserID, GetGroupNames,
+// LanguageFromRequest, ClientIP etc. return the same as they did for
+// the request p was taken from when given a request with the returned
+// context, eg. http.NewRequest(...).WithContext(ctx)
+//
+// Request id of p is also set with ctxutil#WithRequestID so
+// requests made by the task can be traced back to the original request
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	ctx = context.WithValue(ctx, PrincipalCtxKey, p)
+
+	if p.UserID != "" || p.Email != "" {
+		ctx = ctxutil.WithUser(ctx, middlewareUser{ID: p.UserID, Email: p.Email})
+	}
+	if p.ImpersonatorID != "" {
+		ctx = ctxutil.WithImpersonator(ctx, middlewareUser{ID: p.ImpersonatorID})
+	}
+	if len(p.Groups) > 0 {
+		groups := make(map[string]bool, len(p.Groups))
+
+		for _, g := range p.Groups {
+			groups[g] = true
+		}
+
+		ctx = ctxutil.WithGroups(ctx, groups)
+	}
+	if p.Language != "" {
+		ctx = ctxutil.WithLanguage(ctx, p.Language)
+	}
+	if p.IP != "" {
+		ctx = ctxutil.WithClientIP(ctx, p.IP)
+	}
+	if p.RequestID != "" {
+		ctx = ctxutil.WithRequestID(ctx, p.RequestID)
+	}
+
+	return ctx
+}
+
+// GetPrincipal returns Principal of ctx set by WithPrincipal
+func GetPrincipal(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(PrincipalCtxKey).(Principal)
+	return p, ok
+}
+
+// InsertPrincipal stores entry, attributed to Principal of ctx set by
+// WithPrincipal, which is used to audit work of background tasks
+// ActorID, ImpersonatorID and IP of entry are only set if empty
+func (a *AuditLogger) InsertPrincipal(ctx context.Context, db httputil.DBInterface, entry AuditEntry) error {
+	if p, ok := GetPrincipal(ctx); ok {
+		if entry.ActorID == "" {
+			entry.ActorID = p.UserID
+		}
+		if entry.ImpersonatorID == "" {
+			entry.ImpersonatorID = p.ImpersonatorID
+		}
+		if entry.IP == "" {
+			entry.IP = p.IP
+		}
+	}
+
+	return InsertAudit(db, a.config, entry)
+}
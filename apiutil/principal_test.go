@@ -0,0 +1,69 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+	"github.com/TravisS25/httputil/httpclientutil"
+)
+
+func TestPrincipal(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/report", nil)
+	req.Header.Set(httpclientutil.RequestIDHeader, "req-1")
+
+	ctx := context.WithValue(req.Context(), MiddlewareUserCtxKey, middlewareUser{ID: "2", Email: "user@test.com"})
+	ctx = context.WithValue(ctx, ImpersonatorCtxKey, middlewareUser{ID: "1"})
+	ctx = context.WithValue(ctx, GroupCtxKey, map[string]bool{"Staff": true, "Admin": true})
+	ctx = context.WithValue(ctx, LanguageCtxKey, "pt-BR")
+	ctx = context.WithValue(ctx, ClientIPCtxKey, "1.2.3.4")
+
+	p := PrincipalFromRequest(req.WithContext(ctx))
+	expected := Principal{
+		UserID:         "2",
+		Email:          "user@test.com",
+		ImpersonatorID: "1",
+		Groups:         []string{"Admin", "Staff"},
+		RequestID:      "req-1",
+		Language:       "pt-BR",
+		IP:             "1.2.3.4",
+	}
+
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("got principal %+v; want %+v", p, expected)
+	}
+
+	b, err := json.Marshal(p)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	var restored Principal
+
+	if err = json.Unmarshal(b, &restored); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	workerCtx := WithPrincipal(context.Background(), restored)
+	workerReq := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(workerCtx)
+
+	if got := PrincipalFromRequest(workerReq); !reflect.DeepEqual(got, expected) {
+		t.Errorf("should restore principal; got %+v", got)
+	}
+
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec(`insert into audit_log`).
+		WithArgs("2", "1", "export", "report", nil, "1.2.3.4", "", dbtest.AnyArg()).
+		WillReturnResult(dbtest.NewResult(1, 1))
+
+	entry := AuditEntry{Action: "export", EntityType: "report"}
+
+	if err = NewAuditLogger(AuditConfig{}).InsertPrincipal(workerCtx, db, entry); err != nil {
+		t.Errorf("should not return error; got %s", err.Error())
+	}
+}
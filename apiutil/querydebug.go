@@ -0,0 +1,31 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"github.com/TravisS25/httputil/ctxutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+// DebugGroupAuthorizer returns function to be used for
+// queryutil#DebugConfig#Authorize that allows users within any of groups,
+// set by GroupHandler, to debug queries
+func DebugGroupAuthorizer(groups ...string) func(r queryutil.FormRequest) bool {
+	return func(r queryutil.FormRequest) bool {
+		req, ok := r.(*http.Request)
+
+		if !ok {
+			return false
+		}
+
+		userGroups := ctxutil.GroupsFrom(req.Context())
+
+		for _, g := range groups {
+			if userGroups[g] {
+				return true
+			}
+		}
+
+		return false
+	}
+}
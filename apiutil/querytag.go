@@ -0,0 +1,58 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/muxutil"
+)
+
+// QueryTagConfig is config struct used for QueryTagHandler
+type QueryTagConfig struct {
+	// Tag returns tag of queries of r
+	// Default is muxutil#RouteLabel
+	Tag func(r *http.Request) string
+}
+
+// QueryTagHandler tags queries of every request with dbutil#WithQueryTag
+// so they can be attributed to the route that executed them eg. within
+// pg_stat_activity or slow query logs of the database
+//
+// Database set by DBHandler is bound to the context of the request with
+// dbutil#WithContext so queries of handlers that use GetDB, including
+// ones generated by queryutil, are prefixed with the tag as sql comment
+// and transactions of postgres databases set application_name to
+// confutil#Database#ApplicationName followed by the tag
+type QueryTagHandler struct {
+	config QueryTagConfig
+}
+
+// NewQueryTagHandler returns *QueryTagHandler
+func NewQueryTagHandler(config QueryTagConfig) *QueryTagHandler {
+	if config.Tag == nil {
+		config.Tag = muxutil.RouteLabel
+	}
+
+	return &QueryTagHandler{config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (q *QueryTagHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := q.config.Tag(r)
+
+		if tag == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := dbutil.WithQueryTag(r.Context(), tag)
+
+		if db := GetDB(r); db != nil {
+			ctx = context.WithValue(ctx, DBCtxKey, dbutil.WithContext(ctx, db))
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
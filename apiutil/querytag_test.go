@@ -0,0 +1,42 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestQueryTagHandler(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec(`/\* item-update \*/ update item`).WillReturnResult(dbtest.NewResult(0, 1))
+
+	var tag string
+
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), DBCtxKey, dbutil.NewDBProvider(db).DB())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Use(NewQueryTagHandler(QueryTagConfig{}).MiddlewareFunc)
+	router.HandleFunc("/item/{id}", func(w http.ResponseWriter, r *http.Request) {
+		tag = dbutil.GetQueryTag(r.Context())
+
+		if _, err := GetDB(r).Exec("update item set name = ?", "foo"); err != nil {
+			t.Errorf("should not return err; got %s", err.Error())
+		}
+	}).Name("item-update")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/item/1", nil))
+
+	if tag != "item-update" {
+		t.Errorf("should tag request with route name; got %q", tag)
+	}
+}
@@ -0,0 +1,208 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// QueryParamType is expected type of a query param
+type QueryParamType int
+
+const (
+	// QueryString accepts any value
+	QueryString QueryParamType = iota
+
+	// QueryInt requires value to be an integer
+	QueryInt
+
+	// QueryFloat requires value to be a number
+	QueryFloat
+
+	// QueryBool requires value to be parsable by strconv#ParseBool
+	QueryBool
+
+	// QueryJSON requires value to be valid json like the filter and
+	// sort params used by queryutil
+	QueryJSON
+)
+
+// QueryParamSpec is declarative spec of a single query param
+type QueryParamSpec struct {
+	// Type is expected type of param
+	Type QueryParamType
+
+	// Required requires param to be set and not empty
+	Required bool
+
+	// Min and Max are inclusive range for QueryInt and QueryFloat params
+	// or the length range of QueryString params
+	Min *float64
+	Max *float64
+
+	// Enum is list of allowed values
+	Enum []string
+
+	// Pattern is regular expression QueryString params must match
+	Pattern string
+
+	// Multiple allows param to be set more than once
+	Multiple bool
+}
+
+// QueryValidatorConfig is config struct used for QueryValidator
+type QueryValidatorConfig struct {
+	// Params is spec of every query param of the route
+	Params map[string]QueryParamSpec
+
+	// DisallowUnknown returns an error for params not within Params
+	DisallowUnknown bool
+}
+
+// QueryValidator validates raw query params of a request against a
+// declarative spec before next handler is called, responding with 400
+// and a json map of param to error message if invalid
+//
+// This complements formutil which only validates json bodies and
+// protects queryutil param parsing from junk input
+type QueryValidator struct {
+	patterns map[string]*regexp.Regexp
+	config   QueryValidatorConfig
+}
+
+// NewQueryValidator returns *QueryValidator
+// Will panic if any QueryParamSpec#Pattern is invalid as specs
+// are expected to be static
+func NewQueryValidator(config QueryValidatorConfig) *QueryValidator {
+	patterns := make(map[string]*regexp.Regexp)
+
+	for k, v := range config.Params {
+		if v.Pattern != "" {
+			patterns[k] = regexp.MustCompile(v.Pattern)
+		}
+	}
+
+	return &QueryValidator{
+		patterns: patterns,
+		config:   config,
+	}
+}
+
+func (q *QueryValidator) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if errs := q.Validate(r.URL.Query()); len(errs) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			SendPayload(w, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Validate validates values against spec of q and returns map of param
+// to error message for every invalid param
+func (q *QueryValidator) Validate(values url.Values) map[string]string {
+	errs := make(map[string]string)
+
+	if q.config.DisallowUnknown {
+		for k := range values {
+			if _, ok := q.config.Params[k]; !ok {
+				errs[k] = "Unknown param"
+			}
+		}
+	}
+
+	for name, spec := range q.config.Params {
+		paramValues, ok := values[name]
+
+		if !ok || len(paramValues) == 0 || (len(paramValues) == 1 && paramValues[0] == "") {
+			if spec.Required {
+				errs[name] = "Required"
+			}
+
+			continue
+		}
+
+		if len(paramValues) > 1 && !spec.Multiple {
+			errs[name] = "Can only be set once"
+			continue
+		}
+
+		for _, v := range paramValues {
+			if msg := q.validateValue(name, v, spec); msg != "" {
+				errs[name] = msg
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+func (q *QueryValidator) validateValue(name, value string, spec QueryParamSpec) string {
+	if len(spec.Enum) > 0 {
+		found := false
+
+		for _, v := range spec.Enum {
+			if v == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Sprintf("Must be one of: %s", strings.Join(spec.Enum, ", "))
+		}
+	}
+
+	switch spec.Type {
+	case QueryInt:
+		num, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil {
+			return "Must be an integer"
+		}
+
+		return validateQueryRange(float64(num), spec, "")
+	case QueryFloat:
+		num, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			return "Must be a number"
+		}
+
+		return validateQueryRange(num, spec, "")
+	case QueryBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "Must be true or false"
+		}
+	case QueryJSON:
+		if !json.Valid([]byte(value)) {
+			return "Must be valid json"
+		}
+	default:
+		if exp, ok := q.patterns[name]; ok && !exp.MatchString(value) {
+			return "Invalid format"
+		}
+
+		return validateQueryRange(float64(len([]rune(value))), spec, " characters")
+	}
+
+	return ""
+}
+
+func validateQueryRange(num float64, spec QueryParamSpec, unit string) string {
+	if spec.Min != nil && num < *spec.Min {
+		return fmt.Sprintf("Must be at least %s%s", strconv.FormatFloat(*spec.Min, 'f', -1, 64), unit)
+	}
+	if spec.Max != nil && num > *spec.Max {
+		return fmt.Sprintf("Must be at most %s%s", strconv.FormatFloat(*spec.Max, 'f', -1, 64), unit)
+	}
+
+	return ""
+}
@@ -0,0 +1,73 @@
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQueryValidator(t *testing.T) {
+	min := float64(0)
+	max := float64(100)
+
+	validator := NewQueryValidator(QueryValidatorConfig{
+		Params: map[string]QueryParamSpec{
+			"take":    {Type: QueryInt, Min: &min, Max: &max},
+			"status":  {Enum: []string{"open", "closed"}, Required: true},
+			"active":  {Type: QueryBool},
+			"filters": {Type: QueryJSON},
+			"code":    {Pattern: "^[A-Z]{3}$"},
+		},
+		DisallowUnknown: true,
+	})
+
+	handler := validator.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedErrs   map[string]string
+	}{
+		{"valid", `?take=10&status=open&active=true&filters=[]&code=ABC`, http.StatusOK, nil},
+		{
+			"invalid",
+			`?take=101&active=maybe&filters={&code=abc&status=pending&status=open&foo=bar`,
+			http.StatusBadRequest,
+			map[string]string{
+				"take":    "Must be at most 100",
+				"active":  "Must be true or false",
+				"filters": "Must be valid json",
+				"code":    "Invalid format",
+				"status":  "Can only be set once",
+				"foo":     "Unknown param",
+			},
+		},
+		{"required", `?take=abc`, http.StatusBadRequest, map[string]string{"take": "Must be an integer", "status": "Required"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+test.query, nil))
+
+			if rr.Code != test.expectedStatus {
+				v.Fatalf("got status %d; want %d", rr.Code, test.expectedStatus)
+			}
+
+			if test.expectedErrs != nil {
+				var errs map[string]string
+
+				if err := json.Unmarshal(rr.Body.Bytes(), &errs); err != nil {
+					v.Fatalf("should not have error; got %s", err.Error())
+				}
+
+				if !reflect.DeepEqual(errs, test.expectedErrs) {
+					v.Errorf("got errors %v; want %v", errs, test.expectedErrs)
+				}
+			}
+		})
+	}
+}
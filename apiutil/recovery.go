@@ -0,0 +1,71 @@
+package apiutil
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/TravisS25/httputil"
+)
+
+// RecoveryHandlerConfig is config struct used for RecoveryHandler
+type RecoveryHandlerConfig struct {
+	// ServerErrorResponse is config used to respond to user if a panic
+	// occurs before a response was written
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error, please try again later")
+	ServerErrorResponse HTTPResponseConfig
+}
+
+// RecoveryHandler recovers panics of next handler, logs them and sends
+// them as *httputil.PanicError to httputil#Reporter
+// This replaces PanicHandlerFunc of negroni#Recovery
+type RecoveryHandler struct {
+	config RecoveryHandlerConfig
+}
+
+// NewRecoveryHandler returns *RecoveryHandler
+func NewRecoveryHandler(config RecoveryHandlerConfig) *RecoveryHandler {
+	setHTTPResponseDefaults(
+		&config.ServerErrorResponse,
+		http.StatusInternalServerError,
+		[]byte(ErrServerMessage.Error()),
+	)
+
+	return &RecoveryHandler{config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (rh *RecoveryHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseTracker{ResponseWriter: w}
+
+		defer func() {
+			p := recover()
+
+			if p == nil {
+				return
+			}
+
+			// Let server abort response as intended
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			err := &httputil.PanicError{Value: p, Stack: debug.Stack()}
+			httputil.Logger.Errorf("%s %s: %s\n%s", r.Method, r.URL.String(), err.Error(), err.Stack)
+			httputil.CaptureError(r.Context(), err, map[string]string{
+				"source": "panic",
+				"method": r.Method,
+				"url":    r.URL.String(),
+			})
+
+			if !rw.written {
+				w.WriteHeader(*rh.config.ServerErrorResponse.HTTPStatus)
+				w.Write(rh.config.ServerErrorResponse.HTTPResponse)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,63 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+)
+
+type testReporter struct {
+	errs []error
+	tags []map[string]string
+}
+
+func (t *testReporter) CaptureError(ctx context.Context, err error, tags map[string]string) {
+	t.errs = append(t.errs, err)
+	t.tags = append(t.tags, tags)
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	reporter := &testReporter{}
+	httputil.Reporter = reporter
+	defer func() { httputil.Reporter = httputil.NopReporter{} }()
+
+	handler := NewRecoveryHandler(RecoveryHandlerConfig{}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/written" {
+			w.WriteHeader(http.StatusAccepted)
+		}
+
+		panic("boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rr.Code != http.StatusInternalServerError || rr.Body.String() != ErrServerMessage.Error() {
+		t.Errorf("got status %d and body %s", rr.Code, rr.Body.String())
+	}
+	if len(reporter.errs) != 1 {
+		t.Fatalf("should capture panic")
+	}
+	if p, ok := reporter.errs[0].(*httputil.PanicError); !ok || p.Value != "boom" || len(p.Stack) == 0 {
+		t.Errorf("should capture *httputil.PanicError; got %v", reporter.errs[0])
+	}
+	if reporter.tags[0]["url"] != "/panic" {
+		t.Errorf("got tags %v", reporter.tags[0])
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/written", nil))
+
+	if rr.Code != http.StatusAccepted || rr.Body.Len() != 0 {
+		t.Errorf("should not write response once written; got status %d", rr.Code)
+	}
+
+	ServerError(httptest.NewRecorder(), ErrBodyMessage, "")
+
+	if len(reporter.errs) != 3 || reporter.tags[2]["source"] != "server_error" {
+		t.Errorf("server errors should be captured; got %v", reporter.tags)
+	}
+}
@@ -0,0 +1,726 @@
+package apiutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/idutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	notFoundTxt = "Not found"
+)
+
+// ResourceValidator validates the request of a create or update and
+// returns the form that will be written to the database
+//
+// This has the same method set as formutil#RequestValidator which
+// can't be referenced here as formutil depends on apiutil
+type ResourceValidator interface {
+	Validate(req *http.Request, instance interface{}) (interface{}, error)
+}
+
+// ResourceHook is called before or after a step of a Resource handler
+// item is the form for create and update, the id for detail and delete,
+// and the results for list
+// Returning an error will stop the request with a server error unless
+// the hook has already written a response
+type ResourceHook func(w http.ResponseWriter, r *http.Request, db httputil.Entity, item interface{}) error
+
+// ResourceHooks are the hook points of Resource
+// Before and after hooks for create, update and delete are called
+// within the same transaction as the write
+type ResourceHooks struct {
+	BeforeList   ResourceHook
+	AfterList    ResourceHook
+	BeforeDetail ResourceHook
+	AfterDetail  ResourceHook
+	BeforeCreate ResourceHook
+	AfterCreate  ResourceHook
+	BeforeUpdate ResourceHook
+	AfterUpdate  ResourceHook
+	BeforeDelete ResourceHook
+	AfterDelete  ResourceHook
+}
+
+// ResourceConfig is config struct used in conjunction with NewResource
+type ResourceConfig struct {
+	// Table is the database table of the resource - Required
+	Table string
+
+	// IDColumn is primary key column of Table
+	// Default is "id"
+	IDColumn string
+
+	// IDParam is the router variable name of the id
+	// Default is "id"
+	IDParam string
+
+	// ListQuery is query used for list handler
+	// Default is "select * from <Table>"
+	ListQuery string
+
+	// CountQuery is count query used for list handler
+	// Default is "select count(*) from <Table>"
+	CountQuery string
+
+	// DetailQuery is query used for detail handler with one
+	// placeholder for the id
+	// Default is "select * from <Table> where <IDColumn> = ?"
+	DetailQuery string
+
+	// Fields, ParamConf and QueryConf are passed to
+	// queryutil#GetQueriedAndCountResults for list handler
+	Fields    map[string]queryutil.FieldConfig
+	ParamConf queryutil.ParamConfig
+	QueryConf queryutil.QueryConfig
+
+	// Validator is used to validate create and update requests
+	// Returned form should be struct with "db" tags for the columns
+	// to write or map[string]interface{} of column to value
+	// If not set, create and update handlers will return 405
+	Validator ResourceValidator
+
+	// Hooks are called before and after each step
+	Hooks ResourceHooks
+
+	// InsertLogger, if set, is called with the json of the written
+	// form after every create, update and delete for auditing
+	InsertLogger InsertLogger
+
+	// CacheStore and CacheKeys, if set, will delete given keys after
+	// every create, update and delete
+	CacheStore cacheutil.CacheStore
+	CacheKeys  []string
+
+	// VersionTables, if set along with CacheStore, are the tables the
+	// list handler reads from
+	// List sets an etag from their versions and responds with 304 if
+	// it matches, see HasNotModified, and create, update and delete bump
+	// their versions with cacheutil#BumpVersions
+	// Writes to these tables made outside of Resource must bump their
+	// versions too
+	VersionTables []cacheutil.CacheSetup
+
+	// IDCodec, if set, obfuscates ids of resource, see idutil, where
+	// IDColumns of rows are encoded in responses and the router
+	// variable of the id is decoded before it's queried
+	// Ids that can't be decoded respond with 404
+	// Filters of id fields should use idutil#DecodeTransform
+	IDCodec idutil.Codec
+
+	// IDColumns are columns of rows encoded with IDCodec eg. IDColumn
+	// and foreign keys like "user_id"
+	// Default is IDColumn
+	IDColumns []string
+}
+
+// Resource generates list, detail, create, update and delete handlers
+// for a single database table
+type Resource struct {
+	db     httputil.DBInterfaceV2
+	config ResourceConfig
+}
+
+// NewResource returns *Resource with defaults applied to config
+// List and count queries of config are registered with
+// queryutil#Registry so resources keep working in strict mode
+func NewResource(db httputil.DBInterfaceV2, config ResourceConfig) *Resource {
+	if config.IDColumn == "" {
+		config.IDColumn = "id"
+	}
+	if config.IDParam == "" {
+		config.IDParam = "id"
+	}
+	if config.IDCodec != nil && len(config.IDColumns) == 0 {
+		config.IDColumns = []string{config.IDColumn}
+	}
+	if config.ListQuery == "" {
+		config.ListQuery = fmt.Sprintf("select * from %s", config.Table)
+	}
+	if config.CountQuery == "" {
+		config.CountQuery = fmt.Sprintf("select count(*) from %s", config.Table)
+	}
+	if config.DetailQuery == "" {
+		config.DetailQuery = fmt.Sprintf("select * from %s where %s = ?", config.Table, config.IDColumn)
+	}
+	if config.QueryConf.SQLBindVar == nil {
+		bindVar := sqlx.DOLLAR
+		config.QueryConf.SQLBindVar = &bindVar
+	}
+	if config.QueryConf.TakeLimit == nil {
+		takeLimit := queryutil.DefaultTakeLimit
+		config.QueryConf.TakeLimit = &takeLimit
+	}
+	if config.ParamConf.Take == nil {
+		take := "take"
+		config.ParamConf.Take = &take
+	}
+	if config.ParamConf.Skip == nil {
+		skip := "skip"
+		config.ParamConf.Skip = &skip
+	}
+
+	queryutil.Registry.Register(config.Fields, config.ListQuery, config.CountQuery)
+
+	return &Resource{db: db, config: config}
+}
+
+// Register registers handlers of resource on router where list and create
+// are at path and detail, update and delete are at path + "/{id:[0-9]+}"
+// If ResourceConfig#IDCodec is set, ids are matched by "[A-Za-z0-9]+"
+func (res *Resource) Register(router *mux.Router, path string) {
+	idPattern := "[0-9]+"
+
+	if res.config.IDCodec != nil {
+		idPattern = "[A-Za-z0-9]+"
+	}
+
+	detailPath := fmt.Sprintf("%s/{%s:%s}", strings.TrimRight(path, "/"), res.config.IDParam, idPattern)
+
+	router.HandleFunc(path, res.List).Methods(http.MethodGet)
+	router.HandleFunc(path, res.Create).Methods(http.MethodPost)
+	router.HandleFunc(detailPath, res.Detail).Methods(http.MethodGet)
+	router.HandleFunc(detailPath, res.Update).Methods(http.MethodPut)
+	router.HandleFunc(detailPath, res.Delete).Methods(http.MethodDelete)
+}
+
+// List writes ListResponse of the queried results along with
+// pagination headers
+// If ResourceConfig#VersionTables is set, 304 is written instead when
+// none of them changed since the etag of the request
+// If queries are debugged, see queryutil#DebugEnabled, "debug" is
+// also written with the generated queries
+func (res *Resource) List(w http.ResponseWriter, r *http.Request) {
+	if res.versioned() && HasNotModified(w, r, res.config.CacheStore, res.config.VersionTables...) {
+		return
+	}
+
+	pagination, err := queryutil.ParsePagination(r, res.config.ParamConf, res.config.QueryConf)
+
+	if HasError(w, err) {
+		return
+	}
+
+	if !res.runHook(w, r, res.config.Hooks.BeforeList, res.db, nil) {
+		return
+	}
+
+	query := res.config.ListQuery
+	countQuery := res.config.CountQuery
+
+	rower, count, debug, err := queryutil.GetQueriedAndCountResultsWithDebug(
+		&query,
+		&countQuery,
+		nil,
+		res.config.Fields,
+		r,
+		res.db,
+		res.config.ParamConf,
+		res.config.QueryConf,
+	)
+
+	if HasError(w, err) {
+		return
+	}
+
+	rows, err := rowerToMaps(rower)
+
+	if HasError(w, err) {
+		return
+	}
+
+	if !res.runHook(w, r, res.config.Hooks.AfterList, res.db, rows) {
+		return
+	}
+
+	for _, row := range rows {
+		if HasError(w, res.encodeIDs(row)) {
+			return
+		}
+	}
+
+	payload := NewListResponse(rows, count, pagination)
+	payload.Debug = debug
+
+	Paginate(w, r, count, pagination.Take, pagination.Skip)
+	SendPayload(w, payload)
+}
+
+// Detail writes the row with the id of the request
+func (res *Resource) Detail(w http.ResponseWriter, r *http.Request) {
+	id, err := res.requestID(r)
+
+	if HasError(w, err) {
+		return
+	}
+
+	if !res.runHook(w, r, res.config.Hooks.BeforeDetail, res.db, id) {
+		return
+	}
+
+	row, err := res.queryDetail(res.db, id)
+
+	if HasError(w, err) {
+		return
+	}
+
+	if !res.runHook(w, r, res.config.Hooks.AfterDetail, res.db, row) {
+		return
+	}
+
+	if HasError(w, res.encodeIDs(row)) {
+		return
+	}
+
+	SendPayload(w, row)
+}
+
+// Create validates the request and inserts the returned form
+// Writes 201 with {"id": id} on success
+func (res *Resource) Create(w http.ResponseWriter, r *http.Request) {
+	if res.config.Validator == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	form, err := res.config.Validator.Validate(r, nil)
+
+	if HasError(w, err) {
+		return
+	}
+
+	columns, values, err := formColumns(form, res.config.IDColumn)
+
+	if HasError(w, err) {
+		return
+	}
+
+	var id interface{}
+
+	ok := res.withTx(w, r, form, func(tx httputil.Tx) error {
+		if !res.runHook(w, r, res.config.Hooks.BeforeCreate, tx, form) {
+			return errHookResponded
+		}
+
+		if id, err = res.insert(tx, columns, values); err != nil {
+			return err
+		}
+
+		if !res.runHook(w, r, res.config.Hooks.AfterCreate, tx, form) {
+			return errHookResponded
+		}
+
+		return nil
+	})
+
+	if !ok {
+		return
+	}
+
+	if res.config.IDCodec != nil {
+		// Row is already committed so id is returned as is if it
+		// can't be encoded
+		if encoded, err := idutil.EncodeValue(res.config.IDCodec, id); err == nil {
+			id = encoded
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	SendPayload(w, map[string]interface{}{"id": id})
+}
+
+// Update validates the request, with the current row as the instance,
+// and updates the row with the returned form
+func (res *Resource) Update(w http.ResponseWriter, r *http.Request) {
+	if res.config.Validator == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := res.requestID(r)
+
+	if HasError(w, err) {
+		return
+	}
+
+	instance, err := res.queryDetail(res.db, id)
+
+	if HasError(w, err) {
+		return
+	}
+
+	form, err := res.config.Validator.Validate(r, instance)
+
+	if HasError(w, err) {
+		return
+	}
+
+	columns, values, err := formColumns(form, res.config.IDColumn)
+
+	if HasError(w, err) {
+		return
+	}
+
+	sets := make([]string, 0, len(columns))
+
+	for _, c := range columns {
+		sets = append(sets, c+" = ?")
+	}
+
+	query := res.rebind(fmt.Sprintf(
+		"update %s set %s where %s = ?",
+		res.config.Table,
+		strings.Join(sets, ", "),
+		res.config.IDColumn,
+	))
+
+	ok := res.withTx(w, r, form, func(tx httputil.Tx) error {
+		if !res.runHook(w, r, res.config.Hooks.BeforeUpdate, tx, form) {
+			return errHookResponded
+		}
+
+		if _, err = tx.Exec(query, append(values, id)...); err != nil {
+			return err
+		}
+
+		if !res.runHook(w, r, res.config.Hooks.AfterUpdate, tx, form) {
+			return errHookResponded
+		}
+
+		return nil
+	})
+
+	if !ok {
+		return
+	}
+
+	SendPayload(w, map[string]interface{}{"id": mux.Vars(r)[res.config.IDParam]})
+}
+
+// Delete deletes the row with the id of the request
+// Writes 204 on success
+func (res *Resource) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := res.requestID(r)
+
+	if HasError(w, err) {
+		return
+	}
+
+	query := res.rebind(fmt.Sprintf("delete from %s where %s = ?", res.config.Table, res.config.IDColumn))
+
+	ok := res.withTx(w, r, map[string]interface{}{res.config.IDColumn: id}, func(tx httputil.Tx) error {
+		if !res.runHook(w, r, res.config.Hooks.BeforeDelete, tx, id) {
+			return errHookResponded
+		}
+
+		result, err := tx.Exec(query, id)
+
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		if !res.runHook(w, r, res.config.Hooks.AfterDelete, tx, id) {
+			return errHookResponded
+		}
+
+		return nil
+	})
+
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errHookResponded = errors.New("apiutil: hook responded")
+
+// withTx runs fn within a transaction, writing an error response if
+// anything fails, and on success writes the audit log and invalidates
+// cache keys
+// Returns whether the transaction was committed
+func (res *Resource) withTx(w http.ResponseWriter, r *http.Request, payload interface{}, fn func(tx httputil.Tx) error) bool {
+	tx, err := res.db.Begin()
+
+	if HasError(w, err) {
+		return false
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+
+		if err != errHookResponded {
+			WriteError(w, err)
+		}
+
+		return false
+	}
+
+	if res.config.InsertLogger != nil {
+		payloadBytes, err := json.Marshal(payload)
+
+		if err == nil {
+			err = res.config.InsertLogger.InsertLog(r, string(payloadBytes), res.db)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			WriteError(w, err)
+			return false
+		}
+	}
+
+	if HasError(w, res.db.Commit(tx)) {
+		return false
+	}
+
+	if res.config.CacheStore != nil && len(res.config.CacheKeys) > 0 {
+		res.config.CacheStore.Del(res.config.CacheKeys...)
+	}
+
+	if res.versioned() {
+		if err = cacheutil.BumpVersions(res.config.CacheStore, res.config.VersionTables...); err != nil {
+			httputil.Logger.Errorf("apiutil: bump versions err: %s", err.Error())
+		}
+	}
+
+	return true
+}
+
+func (res *Resource) versioned() bool {
+	return res.config.CacheStore != nil && len(res.config.VersionTables) > 0
+}
+
+// runHook calls hook, if set, and returns whether the request should continue
+func (res *Resource) runHook(w http.ResponseWriter, r *http.Request, hook ResourceHook, db httputil.Entity, item interface{}) bool {
+	if hook == nil {
+		return true
+	}
+
+	rw, ok := w.(*responseTracker)
+
+	if !ok {
+		rw = &responseTracker{ResponseWriter: w}
+	}
+
+	if err := hook(rw, r, db, item); err != nil {
+		if !rw.written {
+			WriteError(w, err)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func (res *Resource) insert(tx httputil.Tx, columns []string, values []interface{}) (interface{}, error) {
+	var id interface{}
+
+	placeholders := make([]string, 0, len(columns))
+
+	for range columns {
+		placeholders = append(placeholders, "?")
+	}
+
+	query := fmt.Sprintf(
+		"insert into %s (%s) values (%s)",
+		res.config.Table,
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+
+	// Postgres based databases don't support LastInsertId
+	// so the id has to be returned from the query
+	if *res.config.QueryConf.SQLBindVar == sqlx.DOLLAR {
+		err := tx.QueryRow(res.rebind(query+" returning "+res.config.IDColumn), values...).Scan(&id)
+		return id, err
+	}
+
+	result, err := tx.Exec(res.rebind(query), values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.LastInsertId()
+}
+
+// requestID returns id of router variable of request, decoded with
+// ResourceConfig#IDCodec if set
+// Returns sql.ErrNoRows if id can't be decoded
+func (res *Resource) requestID(r *http.Request) (interface{}, error) {
+	id := mux.Vars(r)[res.config.IDParam]
+
+	if res.config.IDCodec == nil {
+		return id, nil
+	}
+
+	decoded, err := res.config.IDCodec.Decode(id)
+
+	if err != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return decoded, nil
+}
+
+// encodeIDs encodes ResourceConfig#IDColumns of row if
+// ResourceConfig#IDCodec is set
+func (res *Resource) encodeIDs(row map[string]interface{}) error {
+	if res.config.IDCodec == nil {
+		return nil
+	}
+
+	return idutil.EncodeMap(res.config.IDCodec, row, res.config.IDColumns...)
+}
+
+func (res *Resource) queryDetail(db httputil.Querier, id interface{}) (map[string]interface{}, error) {
+	rower, err := db.Query(res.rebind(res.config.DetailQuery), id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rowerToMaps(rower)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return rows[0], nil
+}
+
+func (res *Resource) rebind(query string) string {
+	return sqlx.Rebind(*res.config.QueryConf.SQLBindVar, query)
+}
+
+// responseTracker records whether a hook wrote a response
+type responseTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (rt *responseTracker) WriteHeader(status int) {
+	rt.written = true
+	rt.ResponseWriter.WriteHeader(status)
+}
+
+func (rt *responseTracker) Write(b []byte) (int, error) {
+	rt.written = true
+	return rt.ResponseWriter.Write(b)
+}
+
+// rowerToMaps scans every row of rower into a map of column to value
+func rowerToMaps(rower httputil.Rower) ([]map[string]interface{}, error) {
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+
+	for rower.Next() {
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+
+		for i, c := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[c] = string(b)
+			} else {
+				row[c] = values[i]
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err = dbutil.RowerErr(rower); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// formColumns returns the columns and values to write from form which
+// should be map[string]interface{} or struct with "db" tags
+// idColumn is always excluded
+func formColumns(form interface{}, idColumn string) ([]string, []interface{}, error) {
+	var columns []string
+	var values []interface{}
+
+	if m, ok := form.(map[string]interface{}); ok {
+		for k := range m {
+			if k != idColumn {
+				columns = append(columns, k)
+			}
+		}
+
+		// Keep column order deterministic for the generated query
+		sort.Strings(columns)
+
+		for _, c := range columns {
+			values = append(values, m[c])
+		}
+
+		return columns, values, nil
+	}
+
+	val := reflect.Indirect(reflect.ValueOf(form))
+
+	if val.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("apiutil: resource form must be struct or map; got %T", form)
+	}
+
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+
+		if tag == "" || tag == "-" || tag == idColumn || field.PkgPath != "" {
+			continue
+		}
+
+		columns = append(columns, tag)
+		values = append(values, val.Field(i).Interface())
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, errors.New("apiutil: resource form has no \"db\" tagged fields")
+	}
+
+	return columns, values, nil
+}
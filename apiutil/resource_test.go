@@ -0,0 +1,46 @@
+package apiutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormColumns(t *testing.T) {
+	type form struct {
+		ID    int64  `db:"id"`
+		Name  string `db:"name"`
+		Email string `db:"email,omitempty"`
+		Skip  string `db:"-"`
+		Other string
+	}
+
+	columns, values, err := formColumns(&form{ID: 1, Name: "foo", Email: "foo@example.com"}, "id")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(columns, []string{"name", "email"}) {
+		t.Errorf("got columns %v", columns)
+	}
+	if !reflect.DeepEqual(values, []interface{}{"foo", "foo@example.com"}) {
+		t.Errorf("got values %v", values)
+	}
+
+	columns, values, err = formColumns(map[string]interface{}{"id": 1, "b": 2, "a": 1}, "id")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(columns, []string{"a", "b"}) {
+		t.Errorf("got columns %v", columns)
+	}
+	if !reflect.DeepEqual(values, []interface{}{1, 2}) {
+		t.Errorf("got values %v", values)
+	}
+
+	if _, _, err = formColumns("foo", "id"); err == nil {
+		t.Errorf("should have error for non struct form")
+	}
+}
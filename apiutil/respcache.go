@@ -0,0 +1,199 @@
+package apiutil
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+const (
+	// ResponseCacheKey is format of key of cached response, where %s
+	// is hash of request, see CacheHandler#Key
+	ResponseCacheKey = "responsecache:%s"
+
+	// ResponseCacheHeader is set on responses of CacheHandler to "HIT"
+	// if response was served from cache, else "MISS"
+	ResponseCacheHeader = "X-Cache"
+)
+
+var (
+	// ErrResponseCacheStore is returned from NewCacheHandler when
+	// CacheHandlerConfig#CacheStore isn't set
+	ErrResponseCacheStore = errors.New("apiutil: response cache store is required")
+)
+
+// CacheHandlerConfig is config struct used for CacheHandler
+type CacheHandlerConfig struct {
+	// CacheStore stores responses - Required
+	CacheStore cacheutil.CacheStore
+
+	// Tags are the tables responses are read from, whose versions are
+	// part of cache keys so responses are invalidated when a table is
+	// written to, see cacheutil#BumpVersions
+	// These should be the same as ResourceConfig#VersionTables of
+	// resources writing to the tables
+	Tags []cacheutil.CacheSetup
+
+	// TTL is how long responses are cached
+	// Default is 1 minute
+	TTL time.Duration
+
+	// VaryByUser caches responses per user instead of per groups of
+	// user, which is required if responses contain data scoped to the
+	// user eg. rows filtered by user id
+	VaryByUser bool
+}
+
+// cachedResponse is response as stored in cache
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// CacheHandler caches full responses of GET requests, keyed by path,
+// query and groups of user, so expensive read endpoints can be cached
+// by wrapping them with MiddlewareFunc
+//
+// Responses are invalidated when tables of CacheHandlerConfig#Tags are
+// written to as their versions are part of keys, where stale responses
+// are left to expire with CacheHandlerConfig#TTL
+// Only 200 responses without Set-Cookie header are cached
+//
+// Groups are read with GetUserGroups so GroupHandler should come
+// before this middleware
+type CacheHandler struct {
+	config CacheHandlerConfig
+}
+
+// NewCacheHandler returns *CacheHandler
+func NewCacheHandler(config CacheHandlerConfig) (*CacheHandler, error) {
+	if config.CacheStore == nil {
+		return nil, ErrResponseCacheStore
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+
+	return &CacheHandler{config: config}, nil
+}
+
+// Config returns config of handler with defaults applied
+func (c *CacheHandler) Config() CacheHandlerConfig {
+	return c.config
+}
+
+// Key returns key response of r is cached under
+// Error is returned if versions of tags can't be retrieved
+func (c *CacheHandler) Key(r *http.Request) (string, error) {
+	versions, err := cacheutil.TableVersions(c.config.CacheStore, c.config.Tags...)
+
+	if err != nil {
+		return "", err
+	}
+
+	scope := GetUserID(r)
+
+	if !c.config.VaryByUser {
+		groups := make([]string, 0)
+
+		for k, v := range GetUserGroups(r) {
+			if v {
+				groups = append(groups, k)
+			}
+		}
+
+		sort.Strings(groups)
+		scope = strings.Join(groups, ",")
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(strings.Join(versions, ",")))
+	hash.Write([]byte("|" + r.URL.Path + "?" + r.URL.RawQuery))
+	hash.Write([]byte("|" + scope))
+
+	return fmt.Sprintf(ResponseCacheKey, hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// Invalidate invalidates every response read from tags, or from every
+// tag of CacheHandlerConfig#Tags if none are passed, by bumping their
+// versions
+// This is only needed for writes that don't already bump versions of
+// tables eg. through Resource
+func (c *CacheHandler) Invalidate(tags ...cacheutil.CacheSetup) error {
+	if len(tags) == 0 {
+		tags = c.config.Tags
+	}
+
+	return cacheutil.BumpVersions(c.config.CacheStore, tags...)
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (c *CacheHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := c.Key(r)
+
+		if err != nil {
+			// Cache being down shouldn't take endpoint down with it
+			httputil.Logger.Errorf("apiutil: response cache key err: %s", err.Error())
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if b, err := c.config.CacheStore.Get(key); err == nil {
+			var res cachedResponse
+
+			if err = json.Unmarshal(b, &res); err == nil {
+				for k, v := range res.Header {
+					w.Header()[k] = v
+				}
+
+				w.Header().Set(ResponseCacheHeader, "HIT")
+				w.WriteHeader(res.Status)
+				w.Write(res.Body)
+				return
+			}
+		} else if err != cacheutil.ErrCacheNil {
+			httputil.Logger.Errorf("apiutil: response cache get err: %s", err.Error())
+		}
+
+		w.Header().Set(ResponseCacheHeader, "MISS")
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status != http.StatusOK || w.Header().Get("Set-Cookie") != "" {
+			return
+		}
+
+		header := w.Header().Clone()
+		header.Del(ResponseCacheHeader)
+
+		payload, err := json.Marshal(cachedResponse{
+			Status: rec.status,
+			Header: header,
+			Body:   rec.body.Bytes(),
+		})
+
+		if err == nil {
+			err = c.config.CacheStore.SetErr(key, payload, c.config.TTL)
+		}
+		if err != nil {
+			httputil.Logger.Errorf("apiutil: response cache set err: %s", err.Error())
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestCacheHandler(t *testing.T) {
+	if _, err := NewCacheHandler(CacheHandlerConfig{}); err != ErrResponseCacheStore {
+		t.Errorf("should return ErrResponseCacheStore; got %v", err)
+	}
+
+	invoices := cacheutil.CacheSetup{StringVal: "invoices"}
+	cache, err := NewCacheHandler(CacheHandlerConfig{
+		CacheStore: cachetest.NewMemoryCache(),
+		Tags:       []cacheutil.CacheSetup{invoices},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	calls := 0
+	h := cache.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": 1}]`))
+	}))
+
+	serve := func(method, target string, groups map[string]bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+
+		if groups != nil {
+			req = req.WithContext(context.WithValue(req.Context(), GroupCtxKey, groups))
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	admin := map[string]bool{"admin": true}
+
+	if rr := serve(http.MethodGet, "/invoices?take=10", admin); rr.Header().Get(ResponseCacheHeader) != "MISS" {
+		t.Errorf("first request should miss; got %s", rr.Header().Get(ResponseCacheHeader))
+	}
+
+	rr := serve(http.MethodGet, "/invoices?take=10", admin)
+
+	if rr.Header().Get(ResponseCacheHeader) != "HIT" || calls != 1 {
+		t.Errorf("second request should hit; got %s with %d calls", rr.Header().Get(ResponseCacheHeader), calls)
+	}
+	if rr.Body.String() != `[{"id": 1}]` || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("should replay cached response; got %s", rr.Body.String())
+	}
+
+	// Other query, other groups and other methods are never served from
+	// the cached response
+	serve(http.MethodGet, "/invoices?take=20", admin)
+	serve(http.MethodGet, "/invoices?take=10", map[string]bool{"staff": true})
+	serve(http.MethodPost, "/invoices?take=10", admin)
+
+	if calls != 4 {
+		t.Errorf("should call handler 4 times; got %d", calls)
+	}
+
+	if err = cache.Invalidate(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if rr = serve(http.MethodGet, "/invoices?take=10", admin); rr.Header().Get(ResponseCacheHeader) != "MISS" {
+		t.Errorf("should miss after invalidation; got %s", rr.Header().Get(ResponseCacheHeader))
+	}
+
+	serve(http.MethodGet, "/invoices?fail=1", admin)
+
+	if rr = serve(http.MethodGet, "/invoices?fail=1", admin); rr.Code != http.StatusInternalServerError ||
+		rr.Header().Get(ResponseCacheHeader) != "MISS" {
+		t.Errorf("should not cache errors; got %d", rr.Code)
+	}
+}
@@ -0,0 +1,377 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	minio "github.com/minio/minio-go"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/storageutil"
+)
+
+const (
+	// DefaultRetentionLockKey is key of lock held while pruning if
+	// RetentionConfig#LockKey is not set
+	DefaultRetentionLockKey = "retention-lock"
+)
+
+var (
+	// ErrRetentionTable is returned by NewRetentionManager if a
+	// RetentionPolicy doesn't have a table
+	ErrRetentionTable = errors.New("apiutil: retention policy requires table")
+)
+
+// RetentionPolicy determines how long rows of a log table, eg. audit
+// table or table of Middleware#LogInserter, are kept
+// Rows are pruned if they are older than MaxAge or aren't within the
+// newest MaxRows rows, whichever prunes more
+type RetentionPolicy struct {
+	// Table is log table to prune
+	Table string
+
+	// IDColumn is increasing primary key of Table, which is used to
+	// find the newest rows and to prune in batches
+	// Default is "id"
+	IDColumn string
+
+	// TimeColumn is column of time row was created
+	// Default is "created_at"
+	TimeColumn string
+
+	// MaxAge is how long rows are kept
+	// Default is 0 which doesn't prune by age
+	MaxAge time.Duration
+
+	// MaxRows is max number of rows kept
+	// Default is 0 which doesn't prune by count
+	MaxRows int64
+}
+
+func (r *RetentionPolicy) setDefaults() {
+	if r.IDColumn == "" {
+		r.IDColumn = "id"
+	}
+	if r.TimeColumn == "" {
+		r.TimeColumn = "created_at"
+	}
+}
+
+// RetentionConfig is config struct used for RetentionManager
+type RetentionConfig struct {
+	// Policies are tables pruned by RetentionManager
+	Policies []RetentionPolicy
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding
+	// Default is Postgres
+	DBType string
+
+	// Interval is how often tables are pruned by RetentionManager#Run
+	// Default is 1 hour
+	Interval time.Duration
+
+	// BatchSize is max number of rows archived and deleted at once so
+	// pruning large tables doesn't hold locks on them for long
+	// Default is 1000
+	BatchSize int
+
+	// CacheStore is used for lock so only one instance of an app prunes
+	// at once
+	// Default is nil which prunes without lock
+	CacheStore cacheutil.CacheStore
+
+	// LockKey is key of lock held while pruning
+	// Default is DefaultRetentionLockKey
+	LockKey string
+
+	// Bucket is bucket rows are archived to, as json lines, before
+	// they are deleted
+	// Default is nil which deletes rows without archiving them
+	Bucket *storageutil.Bucket
+
+	// ObjectPrefix is prepended to name of archived objects, which are
+	// named "<prefix><table>/<time>-<first id>-<last id>.jsonl"
+	// Default is "archive/"
+	ObjectPrefix string
+
+	// Clock is used to determine age of rows
+	// Default is httputil#RealClock
+	Clock httputil.Clock
+}
+
+func (r *RetentionConfig) setDefaults() {
+	if r.DBType == "" {
+		r.DBType = dbutil.Postgres
+	}
+	if r.Interval <= 0 {
+		r.Interval = time.Hour
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = 1000
+	}
+	if r.LockKey == "" {
+		r.LockKey = DefaultRetentionLockKey
+	}
+	if r.ObjectPrefix == "" {
+		r.ObjectPrefix = "archive/"
+	}
+	if r.Clock == nil {
+		r.Clock = httputil.RealClock
+	}
+
+	for i := range r.Policies {
+		r.Policies[i].setDefaults()
+	}
+}
+
+// RetentionManager prunes log tables by their RetentionPolicy,
+// archiving rows to storage before deleting them
+type RetentionManager struct {
+	db     httputil.XODB
+	config RetentionConfig
+}
+
+// NewRetentionManager returns *RetentionManager
+func NewRetentionManager(db httputil.XODB, config RetentionConfig) (*RetentionManager, error) {
+	config.Policies = append([]RetentionPolicy(nil), config.Policies...)
+	config.setDefaults()
+
+	for _, policy := range config.Policies {
+		if policy.Table == "" {
+			return nil, ErrRetentionTable
+		}
+	}
+
+	return &RetentionManager{db: db, config: config}, nil
+}
+
+// Run prunes tables every RetentionConfig#Interval until ctx is done
+// Errors are logged with httputil#Logger, and pruning held by another
+// instance is skipped
+// This is blocking so should be run within its own goroutine
+func (m *RetentionManager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := m.Prune(ctx); err != nil && err != cacheutil.ErrLockNotAcquired {
+				httputil.Logger.Errorf("apiutil: pruning logs err: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Prune prunes every table once and returns number of rows deleted by
+// table
+// Returns cacheutil#ErrLockNotAcquired if another instance is pruning
+func (m *RetentionManager) Prune(ctx context.Context) (map[string]int64, error) {
+	pruned := make(map[string]int64, len(m.config.Policies))
+	prune := func() error {
+		for _, policy := range m.config.Policies {
+			count, err := m.prunePolicy(ctx, policy)
+			pruned[policy.Table] = count
+
+			if err != nil {
+				return errors.Wrapf(err, "apiutil: pruning %s", policy.Table)
+			}
+		}
+
+		return nil
+	}
+
+	if m.config.CacheStore == nil {
+		return pruned, prune()
+	}
+
+	return pruned, cacheutil.WithLock(ctx, m.config.CacheStore, m.config.LockKey, cacheutil.LockConfig{}, prune)
+}
+
+// prunePolicy archives and deletes expired rows of policy in batches
+func (m *RetentionManager) prunePolicy(ctx context.Context, policy RetentionPolicy) (int64, error) {
+	where, args, err := m.expiredWhere(policy)
+
+	if err != nil || where == "" {
+		return 0, err
+	}
+
+	var total int64
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return total, err
+		}
+
+		rows, lastID, err := m.expiredBatch(policy, where, args)
+
+		if err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		if m.config.Bucket != nil {
+			if err = m.archive(policy, rows); err != nil {
+				return total, err
+			}
+		}
+
+		res, err := m.db.Exec(
+			m.rebind(fmt.Sprintf("delete from %s where (%s) and %s <= ?", policy.Table, where, policy.IDColumn)),
+			append(append([]interface{}(nil), args...), lastID)...,
+		)
+
+		if err != nil {
+			return total, err
+		}
+
+		count, _ := res.RowsAffected()
+		total += count
+
+		if len(rows) < m.config.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// expiredWhere returns where clause matching expired rows of policy,
+// which is empty if policy doesn't expire any rows
+func (m *RetentionManager) expiredWhere(policy RetentionPolicy) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	if policy.MaxAge > 0 {
+		conditions = append(conditions, policy.TimeColumn+" < ?")
+		args = append(args, m.config.Clock.Now().UTC().Add(-policy.MaxAge))
+	}
+
+	if policy.MaxRows > 0 {
+		var cutoffID interface{}
+
+		err := m.db.QueryRow(
+			m.rebind(fmt.Sprintf(
+				"select %[1]s from %[2]s order by %[1]s desc limit 1 offset ?",
+				policy.IDColumn,
+				policy.Table,
+			)),
+			policy.MaxRows,
+		).Scan(&cutoffID)
+
+		if err != nil && err != sql.ErrNoRows {
+			return "", nil, err
+		}
+		if err == nil {
+			conditions = append(conditions, policy.IDColumn+" <= ?")
+			args = append(args, cutoffID)
+		}
+	}
+
+	return strings.Join(conditions, " or "), args, nil
+}
+
+// expiredBatch returns next batch of expired rows of policy, oldest
+// first, along with id of the last row
+func (m *RetentionManager) expiredBatch(policy RetentionPolicy, where string, args []interface{}) ([]map[string]interface{}, interface{}, error) {
+	rower, err := m.db.Query(
+		m.rebind(fmt.Sprintf(
+			"select * from %s where %s order by %s limit %d",
+			policy.Table,
+			where,
+			policy.IDColumn,
+			m.config.BatchSize,
+		)),
+		args...,
+	)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []map[string]interface{}
+	var lastID interface{}
+
+	for rower.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err = rower.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+
+		for i, column := range columns {
+			// Drivers return text as []byte which would be encoded
+			// as base64
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+
+			row[column] = values[i]
+		}
+
+		rows = append(rows, row)
+		lastID = row[policy.IDColumn]
+	}
+
+	return rows, lastID, nil
+}
+
+// archive stores rows of policy in bucket as json lines
+func (m *RetentionManager) archive(policy RetentionPolicy, rows []map[string]interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	objectName := fmt.Sprintf(
+		"%s%s/%s-%v-%v.jsonl",
+		m.config.ObjectPrefix,
+		policy.Table,
+		m.config.Clock.Now().UTC().Format("20060102T150405"),
+		rows[0][policy.IDColumn],
+		rows[len(rows)-1][policy.IDColumn],
+	)
+
+	_, err := m.config.Bucket.PutObject(
+		m.config.Bucket.Name,
+		objectName,
+		bytes.NewReader(buf.Bytes()),
+		int64(buf.Len()),
+		minio.PutObjectOptions{ContentType: "application/x-ndjson"},
+	)
+
+	return err
+}
+
+func (m *RetentionManager) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(m.config.DBType), query)
+}
@@ -0,0 +1,119 @@
+package apiutil
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+	"github.com/TravisS25/httputil/storageutil"
+	"github.com/TravisS25/httputil/storageutil/storagetest"
+)
+
+func TestRetentionManagerPrune(t *testing.T) {
+	var objectName string
+	var archived []byte
+
+	now := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	cache := cachetest.NewMemoryCache()
+	storage := &storagetest.MockStorageReaderWriter{
+		PutObjectFunc: func(bucketName, name string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (int64, error) {
+			objectName = name
+			archived, _ = ioutil.ReadAll(reader)
+			return int64(len(archived)), nil
+		},
+	}
+
+	db := dbtest.NewExpectDB(t)
+	manager, err := NewRetentionManager(db, RetentionConfig{
+		Policies: []RetentionPolicy{
+			{Table: "audit_log", MaxAge: time.Hour * 24 * 30, MaxRows: 100},
+		},
+		CacheStore: cache,
+		Bucket:     &storageutil.Bucket{StorageReaderWriter: storage, Name: "logs"},
+		Clock:      httputil.NewMockClock(now),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	cutoff := now.Add(-time.Hour * 24 * 30)
+
+	db.ExpectQuery(`select id from audit_log order by id desc limit 1 offset \$1`).
+		WithArgs(int64(100)).
+		WillReturnRows(dbtest.NewRows("id").AddRow(int64(7)))
+	db.ExpectQuery(`select \* from audit_log where created_at < \$1 or id <= \$2 order by id limit 1000`).
+		WithArgs(cutoff, int64(7)).
+		WillReturnRows(dbtest.NewRows("id", "action").AddRow(int64(3), []byte("create")).AddRow(int64(5), []byte("delete")))
+	db.ExpectExec(`delete from audit_log where \(created_at < \$1 or id <= \$2\) and id <= \$3`).
+		WithArgs(cutoff, int64(7), int64(5)).
+		WillReturnResult(dbtest.NewResult(0, 2))
+
+	pruned, err := manager.Prune(context.Background())
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+	if pruned["audit_log"] != 2 {
+		t.Errorf("should prune 2 rows; got %d", pruned["audit_log"])
+	}
+	if objectName != "archive/audit_log/20200131T000000-3-5.jsonl" {
+		t.Errorf("got object %s", objectName)
+	}
+	if string(archived) != "{\"action\":\"create\",\"id\":3}\n{\"action\":\"delete\",\"id\":5}\n" {
+		t.Errorf("got archive %q", archived)
+	}
+
+	// Pruning is skipped while another instance holds lock
+	lock, err := cacheutil.AcquireLock(cache, DefaultRetentionLockKey, time.Minute)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	defer lock.Release()
+
+	if _, err = manager.Prune(context.Background()); err != cacheutil.ErrLockNotAcquired {
+		t.Errorf("should return ErrLockNotAcquired; got %v", err)
+	}
+}
+
+func TestRetentionManagerUnderLimit(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	manager, err := NewRetentionManager(db, RetentionConfig{
+		Policies: []RetentionPolicy{{Table: "request_log", MaxRows: 100}},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	db.ExpectQuery(`select id from request_log`).WillReturnRows(dbtest.NewRows("id"))
+
+	pruned, err := manager.Prune(context.Background())
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+	if pruned["request_log"] != 0 {
+		t.Errorf("should not prune rows; got %d", pruned["request_log"])
+	}
+
+	if _, err = NewRetentionManager(db, RetentionConfig{Policies: []RetentionPolicy{{MaxRows: 1}}}); err != ErrRetentionTable {
+		t.Errorf("should return ErrRetentionTable; got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package apiutil
+
+import (
+	"context"
+
+	"github.com/TravisS25/httputil/ctxutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// UserIDSetting returns dbutil#SessionSetting whose value is the id of the
+// user set by Middleware#AuthMiddleware
+// If name is empty, dbutil#CurrentUserSetting is used
+//
+// This is used in conjunction with dbutil#WithSessionTransaction so
+// postgres row level security policies can reference the current user
+func UserIDSetting(name string) dbutil.SessionSetting {
+	if name == "" {
+		name = dbutil.CurrentUserSetting
+	}
+
+	return dbutil.SessionSetting{
+		Name: name,
+		Value: func(ctx context.Context) (string, bool) {
+			user, _ := ctxutil.UserFrom(ctx)
+			return user.ID, user.ID != ""
+		},
+	}
+}
@@ -0,0 +1,274 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+const (
+	// RoleKey is used as a key when storing role hierarchy in cache
+	RoleKey = "role-hierarchy"
+)
+
+var (
+	// PermissionCtxKey is key used to store permissions of user, as
+	// map[string]bool, within request context by RoleHandler
+	PermissionCtxKey = MiddlewareKey{KeyName: "permissions"}
+)
+
+// Role is a group along with the roles it inherits and the permissions
+// it grants directly eg.
+//
+//	Role{Name: "Admin", Inherits: []string{"Editor"}, Permissions: []string{"user:write"}}
+type Role struct {
+	Name        string   `json:"name"`
+	Inherits    []string `json:"inherits"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleHierarchy is set of roles used to expand groups of users into
+// every role and permission they inherit
+type RoleHierarchy struct {
+	roles map[string]Role
+}
+
+// NewRoleHierarchy returns *RoleHierarchy of roles
+func NewRoleHierarchy(roles []Role) *RoleHierarchy {
+	h := &RoleHierarchy{roles: make(map[string]Role, len(roles))}
+
+	for _, role := range roles {
+		h.roles[role.Name] = role
+	}
+
+	return h
+}
+
+// Resolve returns every role in groups along with the roles they
+// inherit, and every permission granted by those roles
+// Groups that are not in hierarchy are still returned as roles
+// Cycles within hierarchy are ignored
+func (h *RoleHierarchy) Resolve(groups ...string) (roles map[string]bool, permissions map[string]bool) {
+	roles = make(map[string]bool)
+	permissions = make(map[string]bool)
+	queue := append([]string(nil), groups...)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if roles[name] {
+			continue
+		}
+
+		roles[name] = true
+		role := h.roles[name]
+
+		for _, p := range role.Permissions {
+			permissions[p] = true
+		}
+
+		queue = append(queue, role.Inherits...)
+	}
+
+	return roles, permissions
+}
+
+// RoleResolverConfig is config struct used for RoleResolver
+type RoleResolverConfig struct {
+	// CacheStore is used to store role hierarchy so it's shared between
+	// instances of app
+	// If nil, role hierarchy is queried from database
+	CacheStore cacheutil.CacheStore
+
+	// CacheExpiration is expiration of role hierarchy stored in
+	// CacheStore
+	// If 0, role hierarchy never expires
+	CacheExpiration time.Duration
+
+	// RefreshInterval is how long role hierarchy is kept in memory before
+	// it's loaded again
+	// Default value is 1 minute
+	RefreshInterval time.Duration
+}
+
+// RoleResolver loads RoleHierarchy with QueryDB, which should return
+// json array of Role, and keeps it in memory for
+// RoleResolverConfig#RefreshInterval
+type RoleResolver struct {
+	db         httputil.DBInterfaceV2
+	queryRoles QueryDB
+	config     RoleResolverConfig
+
+	mu        sync.Mutex
+	hierarchy *RoleHierarchy
+	loadedAt  time.Time
+}
+
+// NewRoleResolver returns *RoleResolver
+func NewRoleResolver(db httputil.DBInterfaceV2, queryRoles QueryDB, config RoleResolverConfig) *RoleResolver {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Minute
+	}
+
+	return &RoleResolver{
+		db:         db,
+		queryRoles: queryRoles,
+		config:     config,
+	}
+}
+
+// Hierarchy returns role hierarchy kept in memory, loading it from
+// cache or database if it's expired
+func (rr *RoleResolver) Hierarchy(w http.ResponseWriter, r *http.Request) (*RoleHierarchy, error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.hierarchy != nil && time.Since(rr.loadedAt) < rr.config.RefreshInterval {
+		return rr.hierarchy, nil
+	}
+
+	roleBytes, err := rr.load(w, r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []Role
+
+	if err = json.Unmarshal(roleBytes, &roles); err != nil {
+		return nil, err
+	}
+
+	rr.hierarchy = NewRoleHierarchy(roles)
+	rr.loadedAt = time.Now()
+	return rr.hierarchy, nil
+}
+
+// Invalidate removes role hierarchy from memory and cache so it's
+// loaded from database on next use
+// This should be called after roles are changed
+func (rr *RoleResolver) Invalidate() {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.hierarchy = nil
+
+	if rr.config.CacheStore != nil {
+		rr.config.CacheStore.Del(RoleKey)
+	}
+}
+
+// Resolve returns roles and permissions of groups
+func (rr *RoleResolver) Resolve(w http.ResponseWriter, r *http.Request, groups ...string) (map[string]bool, map[string]bool, error) {
+	h, err := rr.Hierarchy(w, r)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles, permissions := h.Resolve(groups...)
+	return roles, permissions, nil
+}
+
+func (rr *RoleResolver) load(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if rr.config.CacheStore != nil {
+		if roleBytes, err := rr.config.CacheStore.Get(RoleKey); err == nil {
+			return roleBytes, nil
+		}
+	}
+
+	httputil.Debugf("apiutil: role resolver query db")
+	roleBytes, err := rr.queryRoles(w, r, rr.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rr.config.CacheStore != nil {
+		rr.config.CacheStore.Set(RoleKey, roleBytes, rr.config.CacheExpiration)
+	}
+
+	return roleBytes, nil
+}
+
+// RoleHandlerConfig is config struct used for RoleHandler
+type RoleHandlerConfig struct {
+	// ServerErrResponse is config used to respond to user if some type
+	// of server error occurs
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// RoleHandler expands groups of user set by GroupHandler with the roles
+// they inherit, so HasGroup matches inherited roles, and sets permissions
+// of user for HasPermission
+// MiddlewareFunc should be placed after GroupHandler
+type RoleHandler struct {
+	resolver *RoleResolver
+	config   RoleHandlerConfig
+}
+
+// NewRoleHandler returns *RoleHandler
+func NewRoleHandler(resolver *RoleResolver, config RoleHandlerConfig) *RoleHandler {
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &RoleHandler{
+		resolver: resolver,
+		config:   config,
+	}
+}
+
+func (rh *RoleHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groupMap := GetUserGroups(r)
+
+		if groupMap == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		groups := make([]string, 0, len(groupMap))
+
+		for k, v := range groupMap {
+			if v {
+				groups = append(groups, k)
+			}
+		}
+
+		roles, permissions, err := rh.resolver.Resolve(w, r, groups...)
+
+		if err != nil {
+			httputil.Logger.Errorf("apiutil: resolve roles err: %s", err.Error())
+			writeHTTPResponse(w, rh.config.ServerErrResponse)
+			return
+		}
+
+		ctx := ctxutil.WithGroups(r.Context(), roles)
+		ctx = context.WithValue(ctx, PermissionCtxKey, permissions)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// HasPermission is a function that checks if user of request has any
+// of the given permissions, including permissions of inherited roles
+// Returns false if RoleHandler was not used for request
+func HasPermission(r *http.Request, permissions ...string) bool {
+	permissionMap, _ := r.Context().Value(PermissionCtxKey).(map[string]bool)
+
+	for _, p := range permissions {
+		if permissionMap[p] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+const testRoles = `[
+	{"name": "Admin", "inherits": ["Editor"], "permissions": ["user:write"]},
+	{"name": "Editor", "inherits": ["Viewer", "Admin"], "permissions": ["invoice:write"]},
+	{"name": "Viewer", "permissions": ["invoice:read"]}
+]`
+
+func TestRoleHierarchyResolve(t *testing.T) {
+	resolver := NewRoleResolver(nil, func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		return []byte(testRoles), nil
+	}, RoleResolverConfig{})
+
+	h, err := resolver.Hierarchy(nil, nil)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	roles, permissions := h.Resolve("Editor", "Other")
+
+	for _, role := range []string{"Admin", "Editor", "Viewer", "Other"} {
+		if !roles[role] {
+			t.Errorf("should have role %s; got %v", role, roles)
+		}
+	}
+	for _, p := range []string{"user:write", "invoice:write", "invoice:read"} {
+		if !permissions[p] {
+			t.Errorf("should have permission %s; got %v", p, permissions)
+		}
+	}
+
+	roles, permissions = h.Resolve("Viewer")
+
+	if len(roles) != 1 || len(permissions) != 1 || !permissions["invoice:read"] {
+		t.Errorf("viewer should only have invoice:read; got %v and %v", roles, permissions)
+	}
+}
+
+func TestRoleHandler(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	queryCalls := 0
+
+	resolver := NewRoleResolver(nil, func(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+		queryCalls++
+		return []byte(testRoles), nil
+	}, RoleResolverConfig{CacheStore: cache})
+
+	var canWrite, canDelete, isViewer bool
+
+	h := NewRoleHandler(resolver, RoleHandlerConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			canWrite = HasPermission(r, "invoice:write")
+			canDelete = HasPermission(r, "invoice:delete")
+			isViewer = HasGroup(r, "Viewer")
+		}),
+	)
+
+	serve := func() {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req = req.WithContext(context.WithValue(req.Context(), GroupCtxKey, map[string]bool{"Editor": true}))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve()
+
+	if !canWrite || canDelete || !isViewer {
+		t.Errorf("editor should have invoice:write and inherit viewer; got %v, %v and %v", canWrite, canDelete, isViewer)
+	}
+
+	serve()
+
+	if queryCalls != 1 {
+		t.Errorf("should query roles once; got %d queries", queryCalls)
+	}
+
+	// Roles are loaded from cache once invalidated from memory
+	resolver.mu.Lock()
+	resolver.hierarchy = nil
+	resolver.mu.Unlock()
+
+	serve()
+
+	if queryCalls != 1 {
+		t.Errorf("should load roles from cache; got %d queries", queryCalls)
+	}
+
+	resolver.Invalidate()
+	serve()
+
+	if queryCalls != 2 {
+		t.Errorf("should query roles after invalidate; got %d queries", queryCalls)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+
+	if HasPermission(req, "invoice:write") {
+		t.Errorf("should not have permission without RoleHandler")
+	}
+}
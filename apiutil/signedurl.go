@@ -0,0 +1,147 @@
+package apiutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureParam is query param that holds signature of url
+	// generated by SignURL
+	SignatureParam = "signature"
+
+	// ExpiresParam is query param that holds unix time that url
+	// generated by SignURL expires at
+	ExpiresParam = "expires"
+
+	invalidSignatureTxt = "Invalid signature"
+	expiredSignatureTxt = "Link has expired"
+)
+
+var (
+	// ErrInvalidSignature is returned from VerifySignedURL if url has no
+	// signature or wasn't signed with the same secret
+	ErrInvalidSignature = errors.New("apiutil: invalid url signature")
+
+	// ErrExpiredSignature is returned from VerifySignedURL if url has
+	// passed its expiration
+	ErrExpiredSignature = errors.New("apiutil: url signature expired")
+)
+
+// SignURL returns rawURL with ExpiresParam and SignatureParam query params
+// which grants access to it until expiresIn has passed, eg. for links to
+// exports sent by email
+//
+// Only the path and query of rawURL are signed so urls stay valid behind
+// proxies, so secrets should not be shared across apis that have the
+// same paths
+func SignURL(secret []byte, rawURL string, expiresIn time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Del(SignatureParam)
+	query.Set(ExpiresParam, strconv.FormatInt(time.Now().Add(expiresIn).Unix(), 10))
+	u.RawQuery = query.Encode()
+
+	query.Set(SignatureParam, urlSignature(secret, u.EscapedPath(), query))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// VerifySignedURL returns nil if u was signed by SignURL with secret and
+// hasn't expired, else returns ErrInvalidSignature or ErrExpiredSignature
+func VerifySignedURL(secret []byte, u *url.URL) error {
+	query := u.Query()
+	signature := query.Get(SignatureParam)
+
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+
+	query.Del(SignatureParam)
+
+	if !hmac.Equal([]byte(signature), []byte(urlSignature(secret, u.EscapedPath(), query))) {
+		return ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+// urlSignature returns base64 encoded hmac of path and query, where
+// query is encoded sorted by key so order of params doesn't matter
+func urlSignature(secret []byte, path string, query url.Values) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(path + "?" + query.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignedURLHandlerConfig is config struct used for SignedURLHandler
+type SignedURLHandlerConfig struct {
+	// InvalidSignatureResponse is config used to respond to user if url
+	// is not signed or signature is invalid
+	//
+	// Default status value is http.StatusForbidden
+	// Default response value is []byte("Invalid signature")
+	InvalidSignatureResponse HTTPResponseConfig
+
+	// ExpiredSignatureResponse is config used to respond to user if url
+	// has expired
+	//
+	// Default status value is http.StatusGone
+	// Default response value is []byte("Link has expired")
+	ExpiredSignatureResponse HTTPResponseConfig
+}
+
+// SignedURLHandler only lets through requests to urls generated by
+// SignURL that haven't expired, which allows time limited access to
+// resources without a session
+type SignedURLHandler struct {
+	secret []byte
+	config SignedURLHandlerConfig
+}
+
+// NewSignedURLHandler returns *SignedURLHandler verifying urls signed
+// with secret
+func NewSignedURLHandler(secret []byte, config SignedURLHandlerConfig) *SignedURLHandler {
+	setHTTPResponseDefaults(&config.InvalidSignatureResponse, http.StatusForbidden, []byte(invalidSignatureTxt))
+	setHTTPResponseDefaults(&config.ExpiredSignatureResponse, http.StatusGone, []byte(expiredSignatureTxt))
+
+	return &SignedURLHandler{
+		secret: secret,
+		config: config,
+	}
+}
+
+func (s *SignedURLHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch VerifySignedURL(s.secret, r.URL) {
+		case nil:
+			next.ServeHTTP(w, r)
+		case ErrExpiredSignature:
+			w.WriteHeader(*s.config.ExpiredSignatureResponse.HTTPStatus)
+			w.Write(s.config.ExpiredSignatureResponse.HTTPResponse)
+		default:
+			w.WriteHeader(*s.config.InvalidSignatureResponse.HTTPStatus)
+			w.Write(s.config.InvalidSignatureResponse.HTTPResponse)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURLHandler(t *testing.T) {
+	secret := []byte("secret")
+	handler := NewSignedURLHandler(secret, SignedURLHandlerConfig{}).MiddlewareFunc(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	serve := func(target string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr.Code
+	}
+
+	signed, err := SignURL(secret, "https://example.com/exports/1?format=csv", time.Hour)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	u, _ := url.Parse(signed)
+
+	if code := serve(u.RequestURI()); code != http.StatusOK {
+		t.Errorf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	// Tampering with params invalidates signature
+	if code := serve(strings.Replace(u.RequestURI(), "format=csv", "format=pdf", 1)); code != http.StatusForbidden {
+		t.Errorf("got status %d; want %d", code, http.StatusForbidden)
+	}
+	if code := serve(strings.Replace(u.RequestURI(), "/exports/1", "/exports/2", 1)); code != http.StatusForbidden {
+		t.Errorf("got status %d; want %d", code, http.StatusForbidden)
+	}
+	if code := serve("/exports/1?format=csv"); code != http.StatusForbidden {
+		t.Errorf("got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	other, _ := SignURL([]byte("other"), "/exports/1", time.Hour)
+
+	if code := serve(other); code != http.StatusForbidden {
+		t.Errorf("got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	expired, _ := SignURL(secret, "/exports/1", -time.Minute)
+
+	if code := serve(expired); code != http.StatusGone {
+		t.Errorf("got status %d; want %d", code, http.StatusGone)
+	}
+}
@@ -0,0 +1,157 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+const (
+	// DefaultSlowThreshold is default SlowRequestConfig#Threshold
+	DefaultSlowThreshold = time.Second * 2
+
+	slowRequestSubject = "Slow request: %s %s"
+)
+
+// SlowRequestConfig is config struct used for SlowRequestHandler
+type SlowRequestConfig struct {
+	// Threshold is duration over which requests are reported as slow
+	// Default value is DefaultSlowThreshold
+	Threshold time.Duration
+
+	// Messenger, if set, is used to email slow requests to AlertTo
+	Messenger mailutil.SendMessage
+
+	// AlertTo are emails slow requests are sent to
+	AlertTo []string
+
+	// AlertFrom is email slow requests are sent from
+	AlertFrom string
+
+	// OnSlow is called with every slow request after it's logged
+	OnSlow func(r *http.Request, slow SlowRequest)
+
+	// Clock is what duration of requests is measured with
+	// Default is httputil#RealClock
+	Clock httputil.Clock
+}
+
+// SlowRequest is request that took longer than
+// SlowRequestConfig#Threshold
+type SlowRequest struct {
+	Method   string              `json:"method"`
+	URL      string              `json:"url"`
+	Status   int                 `json:"status"`
+	Duration time.Duration       `json:"duration"`
+	Queries  []dbutil.QueryEntry `json:"queries"`
+}
+
+// SlowRequestHandler measures how long requests take and logs a warning,
+// including every query executed, for requests that take longer than
+// SlowRequestConfig#Threshold
+//
+// Database set by DBHandler is bound to a dbutil#QueryLog so queries of
+// handlers that use GetDB, including ones generated by queryutil, are
+// included, which helps find queries that need indexes
+type SlowRequestHandler struct {
+	config SlowRequestConfig
+}
+
+// NewSlowRequestHandler returns *SlowRequestHandler
+func NewSlowRequestHandler(config SlowRequestConfig) *SlowRequestHandler {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultSlowThreshold
+	}
+	if config.Clock == nil {
+		config.Clock = httputil.RealClock
+	}
+
+	return &SlowRequestHandler{config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (s *SlowRequestHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, queryLog := dbutil.WithQueryLog(r.Context())
+
+		if db := GetDB(r); db != nil {
+			ctx = context.WithValue(ctx, DBCtxKey, dbutil.WithContext(ctx, db))
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := s.config.Clock.Now()
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		duration := s.config.Clock.Now().Sub(start)
+
+		if duration <= s.config.Threshold {
+			return
+		}
+
+		slow := SlowRequest{
+			Method:   r.Method,
+			URL:      r.URL.String(),
+			Status:   rec.status,
+			Duration: duration,
+			Queries:  queryLog.Entries(),
+		}
+
+		logSlowRequest(slow)
+
+		if s.config.Messenger != nil && len(s.config.AlertTo) > 0 {
+			go s.sendAlert(slow)
+		}
+		if s.config.OnSlow != nil {
+			s.config.OnSlow(r, slow)
+		}
+	})
+}
+
+func (s *SlowRequestHandler) sendAlert(slow SlowRequest) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s %s took %s with status %d<br /><br />", slow.Method, html.EscapeString(slow.URL), slow.Duration, slow.Status)
+
+	for _, q := range slow.Queries {
+		fmt.Fprintf(&buf, "%s<br /><pre>%s</pre>%v<br /><br />", q.Duration, html.EscapeString(q.Query), html.EscapeString(fmt.Sprint(q.Args)))
+	}
+
+	err := mailutil.SendEmail(
+		s.config.AlertTo,
+		s.config.AlertFrom,
+		fmt.Sprintf(slowRequestSubject, slow.Method, slow.URL),
+		nil,
+		buf.Bytes(),
+		s.config.Messenger,
+	)
+
+	if err != nil {
+		httputil.Logger.Errorf("apiutil: slow request alert err: %s", err.Error())
+	}
+}
+
+func logSlowRequest(slow SlowRequest) {
+	queries := make([]string, 0, len(slow.Queries))
+
+	for _, q := range slow.Queries {
+		queries = append(queries, fmt.Sprintf("%s (%s)", q.Query, q.Duration))
+	}
+
+	httputil.Logger.WithFields(logrus.Fields{
+		"method":   slow.Method,
+		"url":      slow.URL,
+		"status":   slow.Status,
+		"duration": slow.Duration.String(),
+		"queries":  queries,
+	}).Warn("slow request")
+}
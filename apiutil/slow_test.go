@@ -0,0 +1,55 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestSlowRequestHandler(t *testing.T) {
+	var slowRequests []SlowRequest
+
+	clock := httputil.NewMockClock(time.Now())
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec("update item").WillReturnResult(dbtest.NewResult(0, 1))
+
+	handler := NewSlowRequestHandler(SlowRequestConfig{
+		Threshold: time.Second,
+		Clock:     clock,
+		OnSlow: func(r *http.Request, slow SlowRequest) {
+			slowRequests = append(slowRequests, slow)
+		},
+	}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reqDB := GetDB(r); reqDB != nil {
+			reqDB.Exec("update item set name = ?", "foo")
+		}
+
+		clock.Add(time.Second * 2)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/item", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = req.WithContext(context.WithValue(req.Context(), DBCtxKey, dbutil.NewDBProvider(db).DB()))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(slowRequests) != 2 {
+		t.Fatalf("should report 2 slow requests; got %d", len(slowRequests))
+	}
+
+	slow := slowRequests[1]
+
+	if slow.Status != http.StatusAccepted || slow.Duration != time.Second*2 {
+		t.Errorf("got status %d and duration %s", slow.Status, slow.Duration)
+	}
+	if len(slow.Queries) != 1 || slow.Queries[0].Query != "update item set name = ?" {
+		t.Errorf("should include queries of request; got %v", slow.Queries)
+	}
+}
@@ -0,0 +1,53 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// TimeoutHandlerConfig is config struct used for TimeoutHandler
+type TimeoutHandlerConfig struct {
+	// TimeoutResponse is config used to respond to user if handler did
+	// not write its response before the timeout
+	//
+	// Default status value is http.StatusServiceUnavailable
+	// Default response value is []byte("Request timeout")
+	TimeoutResponse HTTPResponseConfig
+}
+
+// TimeoutHandler returns middleware that gives the context of every
+// request a deadline of d, so it can be used per route eg.
+//
+//	router.Handle("/api/report", apiutil.TimeoutHandler(time.Second*30, config)(handler))
+//
+// If handler has not written its response once the deadline passes,
+// TimeoutResponse is written and anything written by handler after is
+// discarded
+//
+// Database set by DBHandler is bound to the context of the request with
+// dbutil#WithContext so queries of handlers that use GetDB, including
+// ones generated by queryutil, are cancelled in the database once the
+// deadline passes instead of running to completion
+func TimeoutHandler(d time.Duration, config TimeoutHandlerConfig) func(http.Handler) http.Handler {
+	setHTTPResponseDefaults(&config.TimeoutResponse, http.StatusServiceUnavailable, []byte(requestTimeoutTxt))
+
+	limit := NewLimitHandler(nil, LimitHandlerConfig{
+		MaxBodyBytes:    -1,
+		WriteTimeout:    d,
+		TimeoutResponse: config.TimeoutResponse,
+	})
+
+	return func(next http.Handler) http.Handler {
+		return limit.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if db := GetDB(r); db != nil {
+				ctx := context.WithValue(r.Context(), DBCtxKey, dbutil.WithContext(r.Context(), db))
+				r = r.WithContext(ctx)
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
@@ -0,0 +1,52 @@
+package apiutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	timeout := TimeoutHandler(time.Millisecond*20, TimeoutHandlerConfig{})
+	db := dbtest.NewExpectDB(t)
+
+	serve := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req = req.WithContext(context.WithValue(req.Context(), DBCtxKey, db))
+
+		rr := httptest.NewRecorder()
+		timeout(h).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(func(w http.ResponseWriter, r *http.Request) {
+		ctxDB, ok := GetDB(r).(dbutil.ContextDB)
+
+		if !ok {
+			t.Fatalf("should bind db to context of request")
+		}
+		if _, ok = ctxDB.Context().Deadline(); !ok {
+			t.Errorf("should have deadline")
+		}
+
+		w.Write([]byte("done"))
+	})
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "done" {
+		t.Errorf(statusErrTxt, http.StatusOK, rr.Code)
+	}
+
+	rr = serve(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Write([]byte("late"))
+	})
+
+	if rr.Code != http.StatusServiceUnavailable || rr.Body.String() != requestTimeoutTxt {
+		t.Errorf(statusErrTxt, http.StatusServiceUnavailable, rr.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package apiutil
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// TxCtxKey is key used to store transaction of request within
+	// request context by TxHandler
+	TxCtxKey = MiddlewareKey{KeyName: "tx"}
+)
+
+// TxHandlerConfig is config struct used for TxHandler
+type TxHandlerConfig struct {
+	// Methods are http methods a transaction is started for
+	// Default value is POST, PUT, PATCH and DELETE
+	Methods []string
+
+	// ServerErrResponse is config used to respond to user if
+	// transaction can't be started or committed
+	//
+	// Default status value is http.StatusInternalServerError
+	// Default response value is []byte("Server error")
+	ServerErrResponse HTTPResponseConfig
+}
+
+// TxHandler begins a transaction for every mutating request and stores
+// it within request context for GetTx
+//
+// Transaction is committed if handler responds with a 2xx status, else
+// it is rolled back, including when handler panics
+// Response is held in memory until transaction is committed so the
+// client never gets a success response for a commit that failed
+//
+// Database set by DBHandler is used, else db, so this should come
+// after DBHandler and any TimeoutHandler
+type TxHandler struct {
+	db     httputil.DBInterface
+	config TxHandlerConfig
+}
+
+// NewTxHandler returns *TxHandler
+func NewTxHandler(db httputil.DBInterface, config TxHandlerConfig) *TxHandler {
+	if config.Methods == nil {
+		config.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	setHTTPResponseDefaults(&config.ServerErrResponse, http.StatusInternalServerError, []byte(serverErrTxt))
+
+	return &TxHandler{db: db, config: config}
+}
+
+// MiddlewareFunc is function that implements the mux.MiddlewareFunc interface
+func (t *TxHandler) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasString(t.config.Methods, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var db httputil.Transaction = t.db
+
+		if reqDB := GetDB(r); reqDB != nil {
+			db = reqDB
+		}
+
+		tx, err := db.Begin()
+
+		if err != nil {
+			httputil.Logger.Errorf("apiutil: begin request transaction err: %s", err.Error())
+			writeHTTPResponse(w, t.config.ServerErrResponse)
+			return
+		}
+
+		done := false
+
+		defer func() {
+			if !done {
+				tx.Rollback()
+			}
+		}()
+
+		rec := &txRecorder{ResponseWriter: w, status: http.StatusOK}
+		ctx := context.WithValue(r.Context(), TxCtxKey, tx)
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		done = true
+
+		if rec.status < 200 || rec.status >= 300 {
+			tx.Rollback()
+			rec.flush()
+			return
+		}
+
+		if err = db.Commit(tx); err != nil {
+			httputil.Logger.Errorf("apiutil: commit request transaction err: %s", err.Error())
+			writeHTTPResponse(w, t.config.ServerErrResponse)
+			return
+		}
+
+		rec.flush()
+	})
+}
+
+// GetTx returns transaction of request set by TxHandler, else nil
+func GetTx(r *http.Request) httputil.Tx {
+	tx, _ := r.Context().Value(TxCtxKey).(httputil.Tx)
+	return tx
+}
+
+// txRecorder holds status and body of response in memory until flush
+type txRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *txRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *txRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
+
+func (rec *txRecorder) flush() {
+	rec.ResponseWriter.WriteHeader(rec.status)
+	rec.body.WriteTo(rec.ResponseWriter)
+}
@@ -0,0 +1,72 @@
+package apiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestTxHandler(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	status := http.StatusCreated
+
+	handler := NewTxHandler(db, TxHandlerConfig{}).MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tx := GetTx(r)
+
+		if r.Method == http.MethodGet {
+			if tx != nil {
+				t.Errorf("should not start transaction for get")
+			}
+			return
+		}
+
+		if status == 0 {
+			panic("handler panic")
+		}
+
+		tx.Exec("insert into item (name) values (?)", "foo")
+		w.WriteHeader(status)
+		w.Write([]byte("done"))
+	}))
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, "/item", nil))
+		return rr
+	}
+
+	serve(http.MethodGet)
+
+	db.ExpectBegin()
+	db.ExpectExec("insert into item").WillReturnResult(dbtest.NewResult(1, 1))
+	db.ExpectCommit()
+
+	if rr := serve(http.MethodPost); rr.Code != http.StatusCreated || rr.Body.String() != "done" {
+		t.Errorf("should write response after commit; got %d %s", rr.Code, rr.Body.String())
+	}
+
+	status = http.StatusBadRequest
+	db.ExpectBegin()
+	db.ExpectExec("insert into item").WillReturnResult(dbtest.NewResult(1, 1))
+	db.ExpectRollback()
+
+	if rr := serve(http.MethodPost); rr.Code != http.StatusBadRequest {
+		t.Errorf("should write response of handler; got %d", rr.Code)
+	}
+
+	status = 0
+	db.ExpectBegin()
+	db.ExpectRollback()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("should re-panic")
+			}
+		}()
+
+		serve(http.MethodPost)
+	}()
+}
@@ -0,0 +1,420 @@
+package apiutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/muxutil"
+)
+
+const (
+	// DefaultUsageTable is table usage is flushed to if
+	// UsageConfig#TableName is not set
+	DefaultUsageTable = "api_usage"
+
+	// UsageKey is format of key of count of requests of user to route
+	// within period, where first %s is user id, second is period and
+	// third is route
+	UsageKey = "usage:%s:%s:%s"
+
+	// UsageTotalKey is format of key of count of requests of user within
+	// period, which is checked against UsageConfig#Quota
+	UsageTotalKey = "usage:%s:%s"
+)
+
+const (
+	quotaExceededTxt = "API quota exceeded"
+
+	usageTableQuery = `
+	create table if not exists %s (
+		user_id varchar(64) not null,
+		route varchar(255) not null,
+		period varchar(32) not null,
+		request_count bigint not null,
+		primary key (user_id, route, period)
+	)`
+)
+
+// UsageRecord is count of requests of user to route within period
+type UsageRecord struct {
+	UserID string `json:"userID" db:"user_id"`
+	Route  string `json:"route" db:"route"`
+	Period string `json:"period" db:"period"`
+	Count  int64  `json:"count" db:"request_count"`
+}
+
+// UsageConfig is config struct used for UsageTracker
+type UsageConfig struct {
+	// CacheStore stores counters shared by every instance of app,
+	// which are flushed to database by UsageTracker#Flush
+	CacheStore cacheutil.CacheStore
+
+	// TableName is table usage is flushed to
+	// Default is DefaultUsageTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding and upserts
+	// Default is Postgres
+	DBType string
+
+	// Route returns route counted for request
+	// Default is muxutil#RouteLabel
+	Route func(r *http.Request) string
+
+	// Period returns period counted for time eg. day or month
+	// Default returns day as "2006-01-02"
+	Period func(t time.Time) string
+
+	// Quota is max number of requests of a user within period before
+	// requests are rejected with QuotaResponse
+	// Default is 0 which doesn't limit requests
+	Quota int64
+
+	// QuotaFor, if set, returns quota of user of request instead of
+	// Quota eg. by plan of user, where 0 doesn't limit requests
+	QuotaFor func(r *http.Request) int64
+
+	// CacheExpiration is expiration of counters in cache, which
+	// should be longer than period
+	// Default is 7 days
+	CacheExpiration time.Duration
+
+	// FlushInterval is how often counters are flushed to database by
+	// UsageTracker#Run
+	// Default is 1 minute
+	FlushInterval time.Duration
+
+	// Clock is used to determine period of requests
+	// Default is httputil#RealClock
+	Clock httputil.Clock
+
+	// QuotaResponse is config used to respond to user if quota is
+	// exceeded
+	//
+	// Default status value is http.StatusTooManyRequests
+	// Default response value is []byte("API quota exceeded")
+	QuotaResponse HTTPResponseConfig
+}
+
+func (u *UsageConfig) setDefaults() {
+	if u.TableName == "" {
+		u.TableName = DefaultUsageTable
+	}
+	if u.DBType == "" {
+		u.DBType = dbutil.Postgres
+	}
+	if u.Route == nil {
+		u.Route = muxutil.RouteLabel
+	}
+	if u.Period == nil {
+		u.Period = func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}
+	}
+	if u.CacheExpiration <= 0 {
+		u.CacheExpiration = time.Hour * 24 * 7
+	}
+	if u.FlushInterval <= 0 {
+		u.FlushInterval = time.Minute
+	}
+	if u.Clock == nil {
+		u.Clock = httputil.RealClock
+	}
+
+	setHTTPResponseDefaults(&u.QuotaResponse, http.StatusTooManyRequests, []byte(quotaExceededTxt))
+}
+
+// CreateUsageTable creates usage table if it does not exist
+func CreateUsageTable(db httputil.XODB, config UsageConfig) error {
+	config.setDefaults()
+
+	_, err := db.Exec(fmt.Sprintf(usageTableQuery, config.TableName))
+	return err
+}
+
+// UsageTracker is middleware that counts requests of users, per route
+// and period, for quotas and billing reports of API consumers
+// Requests without user, see GetUserID, are not counted so it should
+// come after AuthHandler
+//
+// Counters are kept in cache so quotas apply across instances and
+// are flushed to database by UsageTracker#Run
+// CacheStore doesn't support atomic increments so concurrent requests
+// of a user may undercount, which is acceptable for usage reports but
+// means Quota is a soft limit
+type UsageTracker struct {
+	db     httputil.XODB
+	config UsageConfig
+
+	mu    sync.Mutex
+	dirty map[UsageRecord]bool
+}
+
+// NewUsageTracker returns *UsageTracker
+func NewUsageTracker(db httputil.XODB, config UsageConfig) *UsageTracker {
+	config.setDefaults()
+
+	return &UsageTracker{
+		db:     db,
+		config: config,
+		dirty:  make(map[UsageRecord]bool),
+	}
+}
+
+// MiddlewareFunc counts request of user and rejects it with
+// UsageConfig#QuotaResponse if user has exceeded their quota
+func (u *UsageTracker) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := GetUserID(r)
+
+		if userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		period := u.config.Period(u.config.Clock.Now().UTC())
+		totalKey := fmt.Sprintf(UsageTotalKey, userID, period)
+		quota := u.config.Quota
+
+		if u.config.QuotaFor != nil {
+			quota = u.config.QuotaFor(r)
+		}
+
+		if quota > 0 && u.count(totalKey) >= quota {
+			writeHTTPResponse(w, u.config.QuotaResponse)
+			return
+		}
+
+		record := UsageRecord{UserID: userID, Route: u.config.Route(r), Period: period}
+		u.incr(totalKey)
+		u.incr(fmt.Sprintf(UsageKey, userID, period, record.Route))
+
+		u.mu.Lock()
+		u.dirty[record] = true
+		u.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Count returns count of requests of user within period, including
+// those not yet flushed to database
+func (u *UsageTracker) Count(userID, period string) int64 {
+	return u.count(fmt.Sprintf(UsageTotalKey, userID, period))
+}
+
+func (u *UsageTracker) count(key string) int64 {
+	b, err := u.config.CacheStore.Get(key)
+
+	if err != nil {
+		return 0
+	}
+
+	count, _ := strconv.ParseInt(string(b), 10, 64)
+	return count
+}
+
+func (u *UsageTracker) incr(key string) {
+	u.config.CacheStore.Set(key, u.count(key)+1, u.config.CacheExpiration)
+}
+
+// Flush stores counters of routes requested through this instance
+// since last flush in database
+//
+// Counters are stored as their value in cache, which is shared by every
+// instance, and never lower counts already in database so counters
+// lost from cache don't lower usage of reports
+func (u *UsageTracker) Flush() error {
+	u.mu.Lock()
+	dirty := u.dirty
+	u.dirty = make(map[UsageRecord]bool)
+	u.mu.Unlock()
+
+	query := sqlx.Rebind(sqlx.BindType(u.config.DBType), u.upsertQuery())
+
+	for key := range dirty {
+		record := key
+		record.Count = u.count(fmt.Sprintf(UsageKey, record.UserID, record.Period, record.Route))
+
+		if record.Count == 0 {
+			continue
+		}
+
+		if _, err := u.db.Exec(query, record.UserID, record.Route, record.Period, record.Count); err != nil {
+			// Records are flushed again next time
+			u.mu.Lock()
+			for r := range dirty {
+				u.dirty[r] = true
+			}
+			u.mu.Unlock()
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *UsageTracker) upsertQuery() string {
+	insert := fmt.Sprintf(
+		"insert into %s (user_id, route, period, request_count) values (?, ?, ?, ?)",
+		u.config.TableName,
+	)
+
+	switch u.config.DBType {
+	case dbutil.Mysql:
+		return insert + " on duplicate key update request_count = greatest(request_count, values(request_count))"
+	case dbutil.Sqlite:
+		return insert + " on conflict (user_id, route, period) do update set " +
+			"request_count = max(request_count, excluded.request_count)"
+	}
+
+	return insert + fmt.Sprintf(
+		" on conflict (user_id, route, period) do update set "+
+			"request_count = greatest(%s.request_count, excluded.request_count)",
+		u.config.TableName,
+	)
+}
+
+// Run flushes counters every UsageConfig#FlushInterval until ctx is
+// done, flushing once more before returning
+// Errors are logged with httputil#Logger
+// This is blocking so should be run within its own goroutine
+func (u *UsageTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(u.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := u.Flush(); err != nil {
+				httputil.Logger.Errorf("apiutil: flushing usage err: %s", err.Error())
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+			if err := u.Flush(); err != nil {
+				httputil.Logger.Errorf("apiutil: flushing usage err: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Usage returns usage of user flushed to database within periods from
+// and to, inclusive, ordered by period and route
+// If from or to are empty, usage isn't limited by them
+func (u *UsageTracker) Usage(userID, from, to string) ([]UsageRecord, error) {
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if from != "" {
+		conditions = append(conditions, "period >= ?")
+		args = append(args, from)
+	}
+	if to != "" {
+		conditions = append(conditions, "period <= ?")
+		args = append(args, to)
+	}
+
+	rower, err := u.db.Query(
+		sqlx.Rebind(sqlx.BindType(u.config.DBType), fmt.Sprintf(
+			"select user_id, route, period, request_count from %s where %s order by period, route",
+			u.config.TableName,
+			strings.Join(conditions, " and "),
+		)),
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]UsageRecord, 0)
+
+	for rower.Next() {
+		var record UsageRecord
+
+		if err = rower.Scan(&record.UserID, &record.Route, &record.Period, &record.Count); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// UsageResponse is json sent by UsageTracker#Handler
+type UsageResponse struct {
+	Records []UsageRecord `json:"records"`
+
+	// Total is count of requests of every record
+	Total int64 `json:"total"`
+
+	// Quota is quota of user, where 0 is unlimited
+	Quota int64 `json:"quota"`
+
+	// Current is count of requests within current period, including
+	// those not yet flushed
+	Current int64 `json:"current"`
+}
+
+// Handler returns endpoint of usage of current user within "from" and
+// "to" periods of query params
+// If canViewAll is set and returns true, usage of another user can be
+// requested with "userID" query param eg. for billing reports of admins
+func (u *UsageTracker) Handler(canViewAll func(r *http.Request) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		userID := GetUserID(r)
+
+		if other := query.Get("userID"); other != "" && other != userID {
+			if canViewAll == nil || !canViewAll(r) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(forbiddenAccessTxt))
+				return
+			}
+
+			userID = other
+		}
+
+		if userID == "" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(forbiddenAccessTxt))
+			return
+		}
+
+		records, err := u.Usage(userID, query.Get("from"), query.Get("to"))
+
+		if HasServerError(w, err, "") {
+			return
+		}
+
+		res := UsageResponse{
+			Records: records,
+			Quota:   u.config.Quota,
+			Current: u.Count(userID, u.config.Period(u.config.Clock.Now().UTC())),
+		}
+
+		if u.config.QuotaFor != nil && userID == GetUserID(r) {
+			res.Quota = u.config.QuotaFor(r)
+		}
+
+		for _, record := range records {
+			res.Total += record.Count
+		}
+
+		SendPayload(w, res)
+	}
+}
@@ -0,0 +1,104 @@
+package apiutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestUsageTracker(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	tracker := NewUsageTracker(db, UsageConfig{
+		CacheStore: cachetest.NewMemoryCache(),
+		Quota:      2,
+		Clock:      httputil.NewMockClock(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)),
+		Route: func(r *http.Request) string {
+			return r.URL.Path
+		},
+	})
+	h := tracker.MiddlewareFunc(mockHandler)
+
+	serve := func(path string, user bool) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+
+		if user {
+			req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve("/invoices", true); code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, code)
+	}
+	if code := serve("/customers", true); code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, code)
+	}
+	if code := serve("/customers", true); code != http.StatusTooManyRequests {
+		t.Errorf(statusErrTxt, http.StatusTooManyRequests, code)
+	}
+	if code := serve("/customers", false); code != http.StatusOK {
+		t.Errorf(statusErrTxt, http.StatusOK, code)
+	}
+	if count := tracker.Count(mUser.ID, "2020-03-01"); count != 2 {
+		t.Errorf("should count 2 requests; got %d", count)
+	}
+
+	db.MatchExpectationsInOrder(false)
+	db.ExpectExec(`insert into api_usage .* on conflict \(user_id, route, period\) do update`).
+		WithArgs(mUser.ID, "/invoices", "2020-03-01", int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`insert into api_usage`).
+		WithArgs(mUser.ID, "/customers", "2020-03-01", int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	if err := tracker.Flush(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+
+	// Nothing was requested since last flush
+	if err := tracker.Flush(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	db.ExpectQuery(`select user_id, route, period, request_count from api_usage where user_id = \$1 and period >= \$2 order by period, route`).
+		WithArgs(mUser.ID, "2020-03-01").
+		WillReturnRows(dbtest.NewRows("user_id", "route", "period", "request_count").
+			AddRow(mUser.ID, "/customers", "2020-03-01", int64(1)).
+			AddRow(mUser.ID, "/invoices", "2020-03-01", int64(1)))
+
+	req := httptest.NewRequest(http.MethodGet, "/usage?from=2020-03-01", nil)
+	req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+	rr := httptest.NewRecorder()
+	tracker.Handler(nil)(rr, req)
+
+	var res UsageResponse
+
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatalf("should decode usage; got %s", err.Error())
+	}
+	if len(res.Records) != 2 || res.Total != 2 || res.Quota != 2 || res.Current != 2 {
+		t.Errorf("got usage %+v", res)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/usage?userID=other", nil)
+	req = req.WithContext(context.WithValue(req.Context(), MiddlewareUserCtxKey, mUser))
+	rr = httptest.NewRecorder()
+	tracker.Handler(nil)(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf(statusErrTxt, http.StatusForbidden, rr.Code)
+	}
+}
@@ -0,0 +1,38 @@
+// Package authutil hashes and verifies user passwords
+package authutil
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidPassword is returned from CheckPassword when password
+	// doesn't match hash
+	ErrInvalidPassword = errors.New("authutil: invalid password")
+)
+
+// HashPassword returns bcrypt hash of password with bcrypt.DefaultCost
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword compares password against bcrypt hash
+// Returns ErrInvalidPassword if they don't match, else returns error
+// if hash is not a valid bcrypt hash
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrInvalidPassword
+	}
+
+	return err
+}
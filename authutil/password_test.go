@@ -0,0 +1,28 @@
+package authutil
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	if err = CheckPassword(hash, "secret"); err != nil {
+		t.Errorf("should match password; got %s\n", err)
+	}
+	if err = CheckPassword(hash, "other"); err != ErrInvalidPassword {
+		t.Errorf("should return ErrInvalidPassword; got %v\n", err)
+	}
+	if err = CheckPassword(confutil.HashPassword, "currentpassword"); err != nil {
+		t.Errorf("should match confutil.HashPassword; got %s\n", err)
+	}
+	if err = CheckPassword("invalid", "secret"); err == nil || err == ErrInvalidPassword {
+		t.Errorf("should return hash error; got %v\n", err)
+	}
+}
@@ -1,12 +1,17 @@
 package cacheutil
 
 import (
+	"context"
 	"errors"
 	"time"
 
-	"github.com/go-redis/redis"
-	"github.com/gorilla/sessions"
-	redistore "gopkg.in/boj/redistore.v1"
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// PingKey is key CachePinger checks for caches that don't
+	// implement httputil#Pinger
+	PingKey = "cacheutil-ping"
 )
 
 var (
@@ -38,98 +43,65 @@ var (
 
 // CacheStore is interface used to get, set and delete cached values
 // from structs that implement it
+//
+// Set ignores write failures and should only be used where losing a
+// write is acceptable, else SetErr should be used
+// SetNX only sets value if key does not exist and returns whether
+// value was set
+//
+// MGet returns values in the same order as keys, where keys that don't
+// exist are nil, and MSet sets every value of map keyed by key, both in
+// one round trip
+//
+// TTL returns remaining time to live of key, which is 0 if key doesn't
+// expire, or ErrCacheNil if key does not exist
 type CacheStore interface {
 	Get(key string) ([]byte, error)
+	MGet(keys ...string) ([][]byte, error)
 	Set(key string, value interface{}, expiration time.Duration)
+	SetErr(key string, value interface{}, expiration time.Duration) error
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	MSet(values map[string]interface{}, expiration time.Duration) error
 	Del(keys ...string)
 	HasKey(key string) (bool, error)
+	TTL(key string) (time.Duration, error)
 }
 
-type SessionStore interface {
-	sessions.Store
-	Ping() (bool, error)
-}
-
-// ClientCache is default struct that implements the CacheStore interface
-// The underlining implementation is based off of the
-// "github.com/go-redis/redis" library
-type ClientCache struct {
-	*redis.Client
-}
-
-// NewClientCache returns pointer of ClientCache
-func NewClientCache(client *redis.Client) *ClientCache {
-	return &ClientCache{client}
-}
-
-// Get gets value based on key passed
-// Returns error if key does not exist
-func (c *ClientCache) Get(key string) ([]byte, error) {
-	var resultsErr error
-
-	results, err := c.Client.Get(key).Bytes()
-
-	if err != nil {
-		if err == redis.Nil {
-			resultsErr = ErrCacheNil
-		} else {
-			resultsErr = err
-		}
+// CachePinger returns cache as httputil#Pinger so caches can be health
+// checked like any other store
+// If cache doesn't implement httputil#Pinger itself, eg. it's wrapped by
+// EncryptedCache, cache is pinged by checking for PingKey
+func CachePinger(cache CacheStore) httputil.Pinger {
+	if pinger, ok := cache.(httputil.Pinger); ok {
+		return pinger
 	}
 
-	return results, resultsErr
-}
-
-// Set sets value in redis server based on key and value given
-// Expiration sets how long the cache will stay in the server
-// If 0, key/value will never be deleted
-func (c *ClientCache) Set(key string, value interface{}, expiration time.Duration) {
-	c.Client.Set(key, value, expiration)
-}
-
-// Del deletes given string array of keys from server if exists
-func (c *ClientCache) Del(keys ...string) {
-	c.Client.Del(keys...)
-}
-
-// HasKey takes key value and determines if that key is in cache
-func (c *ClientCache) HasKey(key string) (bool, error) {
-	_, err := c.Get(key)
-
-	if err != nil {
-		return false, err
-	}
+	return httputil.PingerFunc(func(ctx context.Context) error {
+		if _, err := cache.HasKey(PingKey); err != nil && err != ErrCacheNil {
+			return err
+		}
 
-	return true, nil
+		return nil
+	})
 }
 
 type SessionConfig struct {
 	SessionName string
 	Keys        SessionKeys
-}
-
-type SessionKeys struct {
-	UserKey string
-}
 
-type RedisStore struct {
-	*redistore.RediStore
-}
+	// Version is current version of the schema of values stored in
+	// session, which should be incremented whenever the schema changes
+	// Values stored before versioning was used are version 0
+	Version int
 
-func NewRedisStore(store *redistore.RediStore) *RedisStore {
-	return &RedisStore{
-		RediStore: store,
-	}
+	// Migrate is called for values stored with an older version than
+	// Version and should return the values in the current schema
+	// If nil, values of older versions are used as is
+	Migrate SessionMigration
 }
 
-func (r *RedisStore) Ping() (bool, error) {
-	conn := r.RediStore.Pool.Get()
-	defer conn.Close()
-	data, err := conn.Do("PING")
-	if err != nil || data == nil {
-		return false, err
-	}
-	return (data == "PONG"), nil
+type SessionKeys struct {
+	UserKey string
 }
 
 type CacheValidateConfig struct {
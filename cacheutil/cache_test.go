@@ -1,8 +1,9 @@
 package cacheutil
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"testing"
 	"time"
 )
 
@@ -16,14 +17,52 @@ func (t TestCacheStore) Get(key string) ([]byte, error) {
 	return nil, errors.New("nil")
 }
 
+func (t TestCacheStore) MGet(keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+
+	for i, k := range keys {
+		values[i], _ = t.Get(k)
+	}
+
+	return values, nil
+}
+
 func (t TestCacheStore) Set(key string, value interface{}, expiration time.Duration) {
 
 }
 
+func (t TestCacheStore) SetErr(key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+func (t TestCacheStore) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (t TestCacheStore) MSet(values map[string]interface{}, expiration time.Duration) error {
+	return nil
+}
+
 func (t TestCacheStore) Del(keys ...string) {
 
 }
 
+func (t TestCacheStore) HasKey(key string) (bool, error) {
+	if _, err := t.Get(key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (t TestCacheStore) TTL(key string) (time.Duration, error) {
+	if _, err := t.Get(key); err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
 var (
 	cache CacheStore
 )
@@ -32,23 +71,25 @@ func init() {
 	cache = TestCacheStore{}
 }
 
-func ExampleClientCache_Get_ex1() {
-	// ... Doing something and want to retrieve cache
-	value, _ := cache.Get("success")
-	fmt.Println(string(value))
-	// Output: success
+// downTestStore is statsTestStore whose every key errors
+type downTestStore struct {
+	statsTestStore
+}
+
+func (d *downTestStore) HasKey(key string) (bool, error) {
+	return d.statsTestStore.HasKey("error")
 }
 
-func ExampleClientCache_Get_ex2() {
-	// ... Doing something and want to retrieve cache
-	// But key item is not in cache
-	_, err := cache.Get("fail")
+func TestCachePinger(t *testing.T) {
+	pinger := CachePinger(&statsTestStore{values: make(map[string][]byte)})
+
+	if err := pinger.PingContext(context.Background()); err != nil {
+		t.Errorf("missing ping key should not return error; got %s", err.Error())
+	}
 
-	if err != nil {
-		// If returns errors, then there was no key of given value
-		// so do what you will with error
+	pinger = CachePinger(&downTestStore{statsTestStore{values: make(map[string][]byte)}})
 
-		fmt.Println(string(err.Error()))
-		// Output: nil
+	if err := pinger.PingContext(context.Background()); err == nil {
+		t.Errorf("should return error of cache")
 	}
 }
@@ -2,10 +2,9 @@ package cachetest
 
 import (
 	"errors"
-	"net/http"
 	"time"
 
-	"github.com/gorilla/sessions"
+	"github.com/TravisS25/httputil/cacheutil"
 )
 
 var (
@@ -14,7 +13,12 @@ var (
 
 type MockCache struct {
 	GetFunc    func(key string) ([]byte, error)
+	MGetFunc   func(keys ...string) ([][]byte, error)
+	SetErrFunc func(key string, value interface{}, expiration time.Duration) error
+	SetNXFunc  func(key string, value interface{}, expiration time.Duration) (bool, error)
+	MSetFunc   func(values map[string]interface{}, expiration time.Duration) error
 	HasKeyFunc func(key string) (bool, error)
+	TTLFunc    func(key string) (time.Duration, error)
 }
 
 func (m *MockCache) Get(key string) ([]byte, error) {
@@ -26,35 +30,65 @@ func (m *MockCache) Get(key string) ([]byte, error) {
 }
 func (m *MockCache) Set(key string, value interface{}, expiration time.Duration) {}
 func (m *MockCache) Del(keys ...string)                                          {}
-func (m *MockCache) HasKey(key string) (bool, error) {
-	if m.HasKeyFunc == nil {
-		errors.New("mockcache: testing")
+func (m *MockCache) SetErr(key string, value interface{}, expiration time.Duration) error {
+	if m.SetErrFunc == nil {
+		return nil
 	}
 
-	return m.HasKeyFunc(key)
+	return m.SetErrFunc(key, value, expiration)
 }
+func (m *MockCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if m.SetNXFunc == nil {
+		return true, nil
+	}
 
-type MockSessionStore struct {
-	GetFunc  func(r *http.Request, name string) (*sessions.Session, error)
-	NewFunc  func(r *http.Request, name string) (*sessions.Session, error)
-	SaveFunc func(r *http.Request, w http.ResponseWriter, s *sessions.Session) error
-	PingFunc func() (bool, error)
+	return m.SetNXFunc(key, value, expiration)
 }
+func (m *MockCache) MGet(keys ...string) ([][]byte, error) {
+	if m.MGetFunc != nil {
+		return m.MGetFunc(keys...)
+	}
+
+	// Default to GetFunc for every key so mocks that only set
+	// GetFunc behave the same for MGet
+	values := make([][]byte, len(keys))
+
+	for i, k := range keys {
+		val, err := m.Get(k)
+
+		if err != nil {
+			if err == cacheutil.ErrCacheNil {
+				continue
+			}
+
+			return nil, err
+		}
 
-func (m *MockSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
-	return m.GetFunc(r, name)
+		values[i] = val
+	}
+
+	return values, nil
 }
+func (m *MockCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	if m.MSetFunc == nil {
+		return nil
+	}
 
-func (m *MockSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
-	return m.NewFunc(r, name)
+	return m.MSetFunc(values, expiration)
 }
+func (m *MockCache) HasKey(key string) (bool, error) {
+	if m.HasKeyFunc == nil {
+		errors.New("mockcache: testing")
+	}
 
-func (m *MockSessionStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
-	return m.SaveFunc(r, w, s)
+	return m.HasKeyFunc(key)
 }
+func (m *MockCache) TTL(key string) (time.Duration, error) {
+	if m.TTLFunc == nil {
+		return 0, cacheutil.ErrCacheNil
+	}
 
-func (m *MockSessionStore) Ping() (bool, error) {
-	return m.PingFunc()
+	return m.TTLFunc(key)
 }
 
 func NewMockSessionError(cause error, err string, isUsage, isDecode, isInternal bool) *MockSessionError {
@@ -0,0 +1,110 @@
+package cachetest
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// FakeCache is MemoryCache that can be queried for its state so tests
+// can assert on what was cached without wiring funcs like MockCache
+//
+// Every operation is recorded as stats, see Calls, and keys expire
+// against Clock, which only changes when advanced with Advance
+type FakeCache struct {
+	*cacheutil.InstrumentedCache
+
+	// Clock is clock expiration of keys is checked against
+	Clock *httputil.MockClock
+
+	memory *MemoryCache
+}
+
+// NewFakeCache returns empty *FakeCache with Clock set to the
+// current time
+func NewFakeCache() *FakeCache {
+	memory := NewMemoryCache()
+	clock := httputil.NewMockClock(time.Now())
+	memory.SetClock(clock)
+
+	return &FakeCache{
+		InstrumentedCache: cacheutil.NewInstrumentedCache(memory, nil),
+		Clock:             clock,
+		memory:            memory,
+	}
+}
+
+// Advance advances Clock by d, expiring every key whose time to
+// live is less than d
+func (f *FakeCache) Advance(d time.Duration) {
+	f.Clock.Add(d)
+}
+
+// Keys returns sorted keys that haven't expired matching pattern, which
+// uses the syntax of path#Match like the glob of redis "KEYS"
+// eg. "user-*"
+func (f *FakeCache) Keys(pattern string) []string {
+	f.memory.mu.RLock()
+	defer f.memory.mu.RUnlock()
+
+	now := f.Clock.Now()
+	keys := make([]string, 0)
+
+	for k, item := range f.memory.items {
+		if item.expired(now) {
+			continue
+		}
+
+		if ok, _ := path.Match(pattern, k); ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns number of keys that haven't expired
+func (f *FakeCache) Len() int {
+	return len(f.Keys("*"))
+}
+
+// Values returns value of every key that hasn't expired, keyed by key
+func (f *FakeCache) Values() map[string][]byte {
+	f.memory.mu.RLock()
+	defer f.memory.mu.RUnlock()
+
+	now := f.Clock.Now()
+	values := make(map[string][]byte, len(f.memory.items))
+
+	for k, item := range f.memory.items {
+		if !item.expired(now) {
+			values[k] = item.value
+		}
+	}
+
+	return values
+}
+
+// Calls returns number of times op was called eg. cacheutil#OpGet
+func (f *FakeCache) Calls(op string) int64 {
+	return f.Stats().Operations[op].Count
+}
+
+// Version returns current version of table of setup given by
+// cacheutil#TableVersions and cacheutil#BumpVersions, or 0 if table
+// has no version yet
+func (f *FakeCache) Version(setup cacheutil.CacheSetup) int64 {
+	value, err := f.memory.Get(cacheutil.VersionKey(setup))
+
+	if err != nil {
+		return 0
+	}
+
+	version, _ := strconv.ParseInt(string(value), 10, 64)
+	return version
+}
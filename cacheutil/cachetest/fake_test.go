@@ -0,0 +1,47 @@
+package cachetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+func TestFakeCache(t *testing.T) {
+	cache := NewFakeCache()
+	cache.Set("user-1", "foo", time.Minute)
+	cache.Set("user-2", "bar", 0)
+	cache.Set("group-1", "baz", 0)
+
+	if keys := cache.Keys("user-*"); len(keys) != 2 || keys[0] != "user-1" {
+		t.Errorf("should return matching keys; got %v", keys)
+	}
+
+	if _, err := cache.Get("missing"); err != cacheutil.ErrCacheNil {
+		t.Errorf("should return ErrCacheNil; got %v", err)
+	}
+
+	if calls := cache.Calls(cacheutil.OpSet); calls != 3 {
+		t.Errorf("should record 3 sets; got %d", calls)
+	}
+
+	cache.Advance(time.Minute + time.Second)
+
+	if cache.Len() != 2 {
+		t.Errorf("should expire key; got %v", cache.Values())
+	}
+
+	setup := cacheutil.CacheSetup{StringVal: "user"}
+
+	if cache.Version(setup) != 0 {
+		t.Errorf("should not have version")
+	}
+
+	if err := cacheutil.BumpVersions(cache, setup); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if cache.Version(setup) == 0 {
+		t.Errorf("should have version")
+	}
+}
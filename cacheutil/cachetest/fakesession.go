@@ -0,0 +1,161 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cachetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/gorilla/securecookie"
+)
+
+// FakeSessionStore is MemorySessionStore that keeps cookies set by
+// responses, like a browser would, so a test can make several requests
+// as the same user and query the sessions stored
+//
+// Sessions expire against Clock, which only changes when advanced
+// with Advance
+type FakeSessionStore struct {
+	*MemorySessionStore
+
+	// Clock is clock expiration of sessions is checked against
+	Clock *httputil.MockClock
+
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie
+}
+
+// NewFakeSessionStore returns *FakeSessionStore using keyPairs to sign
+// session cookies with Clock set to the current time
+func NewFakeSessionStore(keyPairs ...[]byte) *FakeSessionStore {
+	store := NewMemorySessionStore(keyPairs...)
+	clock := httputil.NewMockClock(time.Now())
+	store.SetClock(clock)
+
+	return &FakeSessionStore{
+		MemorySessionStore: store,
+		Clock:              clock,
+		cookies:            make(map[string]*http.Cookie),
+	}
+}
+
+// Advance advances Clock by d, expiring every session whose MaxAge
+// is less than d
+func (f *FakeSessionStore) Advance(d time.Duration) {
+	f.Clock.Add(d)
+}
+
+// Do serves r with handler after adding cookies kept from previous
+// responses, then keeps cookies set by the response
+func (f *FakeSessionStore) Do(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	f.AddCookies(r)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	f.KeepCookies(rr.Result())
+	return rr
+}
+
+// AddCookies adds cookies kept from previous responses to r
+func (f *FakeSessionStore) AddCookies(r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range f.cookies {
+		r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+}
+
+// KeepCookies keeps cookies set by resp to be added to following
+// requests, where cookies deleted by resp are removed
+func (f *FakeSessionStore) KeepCookies(resp *http.Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range resp.Cookies() {
+		if c.MaxAge < 0 || c.Value == "" {
+			delete(f.cookies, c.Name)
+			continue
+		}
+
+		f.cookies[c.Name] = c
+	}
+}
+
+// Login stores a session named name with values and keeps its cookie
+// so following requests through Do are of the session
+func (f *FakeSessionStore) Login(name string, values map[interface{}]interface{}) error {
+	c, err := f.CreateSession(name, values)
+
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cookies[name] = c
+	return nil
+}
+
+// Logout removes cookie of session named name, which does not delete
+// the stored session
+func (f *FakeSessionStore) Logout(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.cookies, name)
+}
+
+// Values returns stored values of session named name whose cookie is
+// kept, or nil if there is no cookie or session is not stored or expired
+func (f *FakeSessionStore) Values(name string) map[interface{}]interface{} {
+	f.mu.Lock()
+	c, ok := f.cookies[name]
+	f.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	var id string
+
+	if err := securecookie.DecodeMulti(name, c.Value, &id, f.Codecs...); err != nil {
+		return nil
+	}
+
+	f.MemorySessionStore.mu.RLock()
+	defer f.MemorySessionStore.mu.RUnlock()
+
+	stored, ok := f.sessions[id]
+
+	if !ok || stored.expired(f.Clock.Now()) {
+		return nil
+	}
+
+	values := make(map[interface{}]interface{}, len(stored.values))
+
+	for k, v := range stored.values {
+		values[k] = v
+	}
+
+	return values
+}
+
+// Len returns number of stored sessions that haven't expired
+func (f *FakeSessionStore) Len() int {
+	f.MemorySessionStore.mu.RLock()
+	defer f.MemorySessionStore.mu.RUnlock()
+
+	now := f.Clock.Now()
+	count := 0
+
+	for _, stored := range f.sessions {
+		if !stored.expired(now) {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,56 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cachetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFakeSessionStore(t *testing.T) {
+	store := NewFakeSessionStore([]byte("01234567890123456789012345678901"))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
+
+		if r.Method == http.MethodDelete {
+			session.Options.MaxAge = -1
+		} else {
+			count, _ := session.Values["count"].(int)
+			session.Values["count"] = count + 1
+		}
+
+		session.Save(r, w)
+	})
+
+	store.Do(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+	store.Do(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if count := store.Values("session")["count"]; count != 2 {
+		t.Errorf("should keep session between requests; got %v", count)
+	}
+
+	store.Do(handler, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	if store.Values("session") != nil || store.Len() != 0 {
+		t.Errorf("should delete session")
+	}
+
+	if err := store.Login("session", map[interface{}]interface{}{"count": 5}); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	store.Do(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if count := store.Values("session")["count"]; count != 6 {
+		t.Errorf("should use session of login; got %v", count)
+	}
+
+	store.Advance(25 * time.Hour)
+
+	if store.Values("session") != nil || store.Len() != 0 {
+		t.Errorf("should expire session")
+	}
+}
@@ -0,0 +1,207 @@
+package cachetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+}
+
+func newMemoryItem(value interface{}, expiration time.Duration, now time.Time) (memoryItem, error) {
+	var valueBytes []byte
+	var err error
+
+	switch v := value.(type) {
+	case []byte:
+		valueBytes = v
+	case string:
+		valueBytes = []byte(v)
+	default:
+		if valueBytes, err = json.Marshal(v); err != nil {
+			return memoryItem{}, fmt.Errorf("cachetest: %s", err.Error())
+		}
+	}
+
+	item := memoryItem{value: valueBytes}
+
+	if expiration > 0 {
+		item.expires = now.Add(expiration)
+	}
+
+	return item, nil
+}
+
+func (m memoryItem) expired(now time.Time) bool {
+	return !m.expires.IsZero() && now.After(m.expires)
+}
+
+// clockNow returns time of clock or the system time if clock is nil
+func clockNow(clock httputil.Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+
+	return clock.Now()
+}
+
+// MemoryCache is an in-memory implementation of cacheutil.CacheStore
+// used for tests that need a working cache without running redis
+type MemoryCache struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+	clock httputil.Clock
+}
+
+// NewMemoryCache returns empty *MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryItem)}
+}
+
+// SetClock sets clock expiration of keys is checked against so tests can
+// expire keys, eg. lockouts, by advancing httputil#MockClock
+// It should be called before cache is used
+// Default is the system time
+func (m *MemoryCache) SetClock(clock httputil.Clock) {
+	m.clock = clock
+}
+
+// Get returns value of key or cacheutil.ErrCacheNil if key does
+// not exist or is expired
+func (m *MemoryCache) Get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[key]
+
+	if !ok || item.expired(clockNow(m.clock)) {
+		return nil, cacheutil.ErrCacheNil
+	}
+
+	return item.value, nil
+}
+
+// MGet returns values of keys where keys that do not exist or are
+// expired are nil
+func (m *MemoryCache) MGet(keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+
+	for i, k := range keys {
+		values[i], _ = m.Get(k)
+	}
+
+	return values, nil
+}
+
+// Set sets value of key
+// []byte and string values are stored as is, every other value
+// is stored as json
+func (m *MemoryCache) Set(key string, value interface{}, expiration time.Duration) {
+	m.SetErr(key, value, expiration)
+}
+
+// SetErr is the same as Set but returns error if value can't be
+// encoded as json
+func (m *MemoryCache) SetErr(key string, value interface{}, expiration time.Duration) error {
+	item, err := newMemoryItem(value, expiration, clockNow(m.clock))
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = item
+	return nil
+}
+
+// SetNX sets value of key only if key does not exist or is expired
+// and returns whether value was set
+func (m *MemoryCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	item, err := newMemoryItem(value, expiration, clockNow(m.clock))
+
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.items[key]; ok && !current.expired(clockNow(m.clock)) {
+		return false, nil
+	}
+
+	m.items[key] = item
+	return true, nil
+}
+
+// MSet sets every value of values keyed by key
+// No values are set if any value can't be encoded as json
+func (m *MemoryCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	items := make(map[string]memoryItem, len(values))
+
+	for k, v := range values {
+		item, err := newMemoryItem(v, expiration, clockNow(m.clock))
+
+		if err != nil {
+			return err
+		}
+
+		items[k] = item
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, item := range items {
+		m.items[k] = item
+	}
+
+	return nil
+}
+
+// Del deletes given keys
+func (m *MemoryCache) Del(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range keys {
+		delete(m.items, k)
+	}
+}
+
+// HasKey returns whether key exists
+func (m *MemoryCache) HasKey(key string) (bool, error) {
+	if _, err := m.Get(key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// TTL returns remaining time to live of key against clock of cache, 0
+// if key doesn't expire
+// Returns cacheutil.ErrCacheNil if key does not exist or is expired
+func (m *MemoryCache) TTL(key string) (time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := clockNow(m.clock)
+	item, ok := m.items[key]
+
+	if !ok || item.expired(now) {
+		return 0, cacheutil.ErrCacheNil
+	}
+	if item.expires.IsZero() {
+		return 0, nil
+	}
+
+	return item.expires.Sub(now), nil
+}
@@ -0,0 +1,59 @@
+package cachetest
+
+import "sync"
+
+// MemoryPubSub is an in-memory implementation of cacheutil.PubSub used
+// for tests that need to publish messages without running redis
+// Handlers are called synchronously within Publish
+type MemoryPubSub struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[string]map[int]func(message string)
+}
+
+// NewMemoryPubSub returns *MemoryPubSub without subscriptions
+func NewMemoryPubSub() *MemoryPubSub {
+	return &MemoryPubSub{handlers: make(map[string]map[int]func(message string))}
+}
+
+// Publish calls every handler subscribed to channel with message
+func (m *MemoryPubSub) Publish(channel, message string) error {
+	m.mu.RLock()
+	handlers := make([]func(message string), 0, len(m.handlers[channel]))
+
+	for _, handler := range m.handlers[channel] {
+		handlers = append(handlers, handler)
+	}
+
+	m.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+
+	return nil
+}
+
+// Subscribe subscribes handler to channel until the returned close
+// function is called
+func (m *MemoryPubSub) Subscribe(channel string, handler func(message string)) (func() error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextID
+	m.nextID++
+
+	if m.handlers[channel] == nil {
+		m.handlers[channel] = make(map[int]func(message string))
+	}
+
+	m.handlers[channel][id] = handler
+
+	return func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.handlers[channel], id)
+		return nil
+	}, nil
+}
@@ -0,0 +1,220 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cachetest
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+type MockSessionStore struct {
+	GetFunc    func(r *http.Request, name string) (*sessions.Session, error)
+	NewFunc    func(r *http.Request, name string) (*sessions.Session, error)
+	SaveFunc   func(r *http.Request, w http.ResponseWriter, s *sessions.Session) error
+	PingFunc   func() (bool, error)
+	ExpiryFunc func(s *sessions.Session) (time.Duration, error)
+}
+
+func (m *MockSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return m.GetFunc(r, name)
+}
+
+func (m *MockSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return m.NewFunc(r, name)
+}
+
+func (m *MockSessionStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	return m.SaveFunc(r, w, s)
+}
+
+func (m *MockSessionStore) Ping() (bool, error) {
+	return m.PingFunc()
+}
+
+func (m *MockSessionStore) Expiry(s *sessions.Session) (time.Duration, error) {
+	if m.ExpiryFunc == nil {
+		return 0, cacheutil.ErrCacheNil
+	}
+
+	return m.ExpiryFunc(s)
+}
+
+// MemorySessionStore is an in-memory implementation of
+// cacheutil.SessionStore where only the session id is stored
+// within the cookie, the same as a redis store would
+//
+// Sessions expire after the MaxAge of their options, the same as a redis
+// store would, which can be tested by advancing the clock of SetClock
+type MemorySessionStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	mu       sync.RWMutex
+	sessions map[string]memorySession
+	clock    httputil.Clock
+}
+
+type memorySession struct {
+	values  map[interface{}]interface{}
+	expires time.Time
+}
+
+// NewMemorySessionStore returns *MemorySessionStore using keyPairs
+// to sign session cookies
+func NewMemorySessionStore(keyPairs ...[]byte) *MemorySessionStore {
+	return &MemorySessionStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   86400,
+			HttpOnly: true,
+		},
+		sessions: make(map[string]memorySession),
+	}
+}
+
+// SetClock sets clock expiration of sessions is checked against
+// It should be called before store is used
+// Default is the system time
+func (m *MemorySessionStore) SetClock(clock httputil.Clock) {
+	m.clock = clock
+}
+
+// Get returns session from request registry
+func (m *MemorySessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(m, name)
+}
+
+// New returns session for name, loaded from store if request
+// contains a valid session cookie
+func (m *MemorySessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(m, name)
+	opts := *m.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+
+	if err != nil {
+		return session, nil
+	}
+
+	if err = securecookie.DecodeMulti(name, c.Value, &session.ID, m.Codecs...); err != nil {
+		return session, err
+	}
+
+	m.mu.RLock()
+	stored, ok := m.sessions[session.ID]
+	m.mu.RUnlock()
+
+	if ok && !stored.expired(clockNow(m.clock)) {
+		for k, v := range stored.values {
+			session.Values[k] = v
+		}
+
+		session.IsNew = false
+	}
+
+	return session, nil
+}
+
+// Save stores session values and writes session cookie to w
+// If session MaxAge is less than 0, the session is deleted
+func (m *MemorySessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		m.mu.Lock()
+		delete(m.sessions, session.ID)
+		m.mu.Unlock()
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newSessionID()
+	}
+
+	m.setValues(session.ID, session.Values, session.Options.MaxAge)
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, m.Codecs...)
+
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Ping always returns true as the store is in memory
+func (m *MemorySessionStore) Ping() (bool, error) {
+	return true, nil
+}
+
+// Expiry returns remaining time until session expires against clock of
+// store, 0 if session doesn't expire
+// Returns cacheutil.ErrCacheNil if session isn't stored or is expired
+func (m *MemorySessionStore) Expiry(session *sessions.Session) (time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := clockNow(m.clock)
+	stored, ok := m.sessions[session.ID]
+
+	if !ok || stored.expired(now) {
+		return 0, cacheutil.ErrCacheNil
+	}
+	if stored.expires.IsZero() {
+		return 0, nil
+	}
+
+	return stored.expires.Sub(now), nil
+}
+
+// CreateSession stores a session with values and returns the cookie that
+// references it which can be added to requests to act as a logged in user
+func (m *MemorySessionStore) CreateSession(name string, values map[interface{}]interface{}) (*http.Cookie, error) {
+	id := newSessionID()
+	m.setValues(id, values, m.Options.MaxAge)
+	encoded, err := securecookie.EncodeMulti(name, id, m.Codecs...)
+
+	if err != nil {
+		return nil, fmt.Errorf("cachetest: %s", err.Error())
+	}
+
+	return sessions.NewCookie(name, encoded, m.Options), nil
+}
+
+func (m *MemorySessionStore) setValues(id string, values map[interface{}]interface{}, maxAge int) {
+	stored := memorySession{values: make(map[interface{}]interface{}, len(values))}
+
+	for k, v := range values {
+		stored.values[k] = v
+	}
+	if maxAge > 0 {
+		stored.expires = clockNow(m.clock).Add(time.Duration(maxAge) * time.Second)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = stored
+}
+
+func (m memorySession) expired(now time.Time) bool {
+	return !m.expires.IsZero() && now.After(m.expires)
+}
+
+func newSessionID() string {
+	return strings.TrimRight(
+		base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)),
+		"=",
+	)
+}
@@ -0,0 +1,177 @@
+package cacheutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	// ErrDecryptValue is returned by EncryptedCache if a cached value
+	// could not be decrypted, which usually means it was stored in
+	// plaintext or with a different key
+	ErrDecryptValue = errors.New("cacheutil: could not decrypt cache value")
+)
+
+// EncryptedCache wraps CacheStore and encrypts every value with
+// AES-GCM before it's stored so sensitive data like user info or
+// group/url data isn't stored in plaintext in shared cache servers
+//
+// The key of a value is used as additional data so an encrypted
+// value can't be copied to a different key
+// Keys themselves are not encrypted
+type EncryptedCache struct {
+	CacheStore
+	aead cipher.AEAD
+}
+
+// NewEncryptedCache returns *EncryptedCache using key to encrypt values
+// which must be 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256
+func NewEncryptedCache(cache CacheStore, key []byte) (*EncryptedCache, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("cacheutil: %s", err.Error())
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, fmt.Errorf("cacheutil: %s", err.Error())
+	}
+
+	return &EncryptedCache{
+		CacheStore: cache,
+		aead:       aead,
+	}, nil
+}
+
+// Get gets and decrypts value of key
+// Returns ErrDecryptValue if value could not be decrypted
+func (e *EncryptedCache) Get(key string) ([]byte, error) {
+	val, err := e.CacheStore.Get(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.decrypt(key, val)
+}
+
+// MGet gets and decrypts values of keys
+// Returns ErrDecryptValue if any value could not be decrypted
+func (e *EncryptedCache) MGet(keys ...string) ([][]byte, error) {
+	values, err := e.CacheStore.MGet(keys...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, val := range values {
+		if val == nil {
+			continue
+		}
+
+		if values[i], err = e.decrypt(keys[i], val); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// Set encrypts and sets value of key
+func (e *EncryptedCache) Set(key string, value interface{}, expiration time.Duration) {
+	e.SetErr(key, value, expiration)
+}
+
+// SetErr encrypts and sets value of key
+// Values that are not []byte, string or encoding.BinaryMarshaler
+// are encoded as json before being encrypted
+func (e *EncryptedCache) SetErr(key string, value interface{}, expiration time.Duration) error {
+	val, err := e.encrypt(key, value)
+
+	if err != nil {
+		return err
+	}
+
+	return e.CacheStore.SetErr(key, val, expiration)
+}
+
+// SetNX encrypts and sets value of key only if key does not exist
+func (e *EncryptedCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	val, err := e.encrypt(key, value)
+
+	if err != nil {
+		return false, err
+	}
+
+	return e.CacheStore.SetNX(key, val, expiration)
+}
+
+// MSet encrypts and sets every value of values keyed by key
+func (e *EncryptedCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	encrypted := make(map[string]interface{}, len(values))
+
+	for k, v := range values {
+		val, err := e.encrypt(k, v)
+
+		if err != nil {
+			return err
+		}
+
+		encrypted[k] = val
+	}
+
+	return e.CacheStore.MSet(encrypted, expiration)
+}
+
+func (e *EncryptedCache) encrypt(key string, value interface{}) ([]byte, error) {
+	var plaintext []byte
+	var err error
+
+	switch v := value.(type) {
+	case []byte:
+		plaintext = v
+	case string:
+		plaintext = []byte(v)
+	case encoding.BinaryMarshaler:
+		if plaintext, err = v.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	default:
+		if plaintext, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, []byte(key)), nil
+}
+
+func (e *EncryptedCache) decrypt(key string, val []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+
+	if len(val) < nonceSize {
+		return nil, ErrDecryptValue
+	}
+
+	plaintext, err := e.aead.Open(nil, val[:nonceSize], val[nonceSize:], []byte(key))
+
+	if err != nil {
+		return nil, ErrDecryptValue
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,62 @@
+package cacheutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptedCache(t *testing.T) {
+	store := &statsTestStore{values: make(map[string][]byte)}
+
+	if _, err := NewEncryptedCache(store, []byte("short")); err == nil {
+		t.Fatalf("should return error for invalid key length")
+	}
+
+	cache, err := NewEncryptedCache(store, []byte("0123456789abcdef0123456789abcdef"))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if err = cache.SetErr("user", []byte(`{"email": "foo@email.com"}`), 0); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if bytes.Contains(store.values["user"], []byte("foo@email.com")) {
+		t.Errorf("value should not be stored in plaintext")
+	}
+
+	val, err := cache.Get("user")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if string(val) != `{"email": "foo@email.com"}` {
+		t.Errorf("got value %s", val)
+	}
+
+	if err = cache.MSet(map[string]interface{}{"groups": map[string]bool{"Admin": true}}, 0); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	values, err := cache.MGet("groups", "missing")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if string(values[0]) != `{"Admin":true}` || values[1] != nil {
+		t.Errorf("got values %s", values)
+	}
+
+	// Encrypted value copied to a different key should not decrypt
+	store.values["copy"] = store.values["user"]
+
+	if _, err = cache.Get("copy"); err != ErrDecryptValue {
+		t.Errorf("should return ErrDecryptValue; got %v", err)
+	}
+
+	store.values["plain"] = []byte("plaintext")
+
+	if _, err = cache.Get("plain"); err != ErrDecryptValue {
+		t.Errorf("should return ErrDecryptValue; got %v", err)
+	}
+}
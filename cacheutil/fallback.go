@@ -0,0 +1,202 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cacheutil
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// FallbackSessionConfig is config struct used for FallbackSessionStore
+type FallbackSessionConfig struct {
+	// CheckInterval is how often the primary store is pinged to determine
+	// if it's healthy
+	// Default is 5 seconds
+	CheckInterval time.Duration
+}
+
+// FallbackSessionStore is SessionStore that wraps a primary store eg.
+// redis and a secondary store eg. database
+//
+// Sessions are read from and written to the primary store while it's
+// healthy, and the secondary store while the primary is down
+// Once the primary recovers, sessions created during the outage are
+// copied from the secondary store into the primary store when they're
+// first read, so users stay logged in
+//
+// Both stores must use the same cookie codecs so session cookies written
+// by either store can be decoded by the other
+type FallbackSessionStore struct {
+	primary   SessionStore
+	secondary SessionStore
+	config    FallbackSessionConfig
+
+	mu        sync.Mutex
+	healthy   bool
+	lastCheck time.Time
+}
+
+// NewFallbackSessionStore returns *FallbackSessionStore
+func NewFallbackSessionStore(primary, secondary SessionStore, config FallbackSessionConfig) *FallbackSessionStore {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = time.Second * 5
+	}
+
+	return &FallbackSessionStore{
+		primary:   primary,
+		secondary: secondary,
+		config:    config,
+	}
+}
+
+// Get returns session from request registry
+func (f *FallbackSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(f, name)
+}
+
+// New returns session for name from the primary store if it's healthy,
+// else from the secondary store
+// If the session is only found in the secondary store, it's copied into
+// the primary store
+func (f *FallbackSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	if f.Healthy() {
+		session, err := f.primary.New(r, name)
+
+		if err != nil && !isDecodeError(err) {
+			f.setHealthy(false)
+			return f.newSecondary(r, name)
+		}
+		if session == nil {
+			return nil, err
+		}
+		if err != nil || !session.IsNew || !hasCookie(r, name) {
+			return f.wrap(session, name), err
+		}
+
+		// Session may have been created while primary was down
+		secondarySession, err := f.secondary.New(r, name)
+
+		if err != nil || secondarySession.IsNew {
+			return f.wrap(session, name), nil
+		}
+
+		session.ID = secondarySession.ID
+		session.Values = secondarySession.Values
+		session.IsNew = false
+
+		if err = f.primary.Save(r, discardResponseWriter{}, session); err != nil {
+			f.setHealthy(false)
+		}
+
+		return f.wrap(session, name), nil
+	}
+
+	return f.newSecondary(r, name)
+}
+
+// Save saves session to the primary store if it's healthy, else to the
+// secondary store
+// If saving to the primary store fails, it's marked as down and session
+// is saved to the secondary store
+func (f *FallbackSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if f.Healthy() {
+		if err := f.primary.Save(r, w, session); err == nil {
+			return nil
+		}
+
+		f.setHealthy(false)
+	}
+
+	return f.secondary.Save(r, w, session)
+}
+
+// Ping returns true if either store is responsive
+func (f *FallbackSessionStore) Ping() (bool, error) {
+	if f.Healthy() {
+		return true, nil
+	}
+
+	return f.secondary.Ping()
+}
+
+// Expiry returns expiry of session from the primary store if it's
+// healthy, else from the secondary store
+func (f *FallbackSessionStore) Expiry(session *sessions.Session) (time.Duration, error) {
+	if f.Healthy() {
+		return f.primary.Expiry(session)
+	}
+
+	return f.secondary.Expiry(session)
+}
+
+// Healthy returns whether the primary store is responsive
+// The primary store is pinged at most once every
+// FallbackSessionConfig#CheckInterval
+func (f *FallbackSessionStore) Healthy() bool {
+	f.mu.Lock()
+
+	if !f.lastCheck.IsZero() && time.Since(f.lastCheck) < f.config.CheckInterval {
+		healthy := f.healthy
+		f.mu.Unlock()
+		return healthy
+	}
+
+	f.lastCheck = time.Now()
+	f.mu.Unlock()
+
+	ok, err := f.primary.Ping()
+	healthy := ok && err == nil
+	f.setHealthy(healthy)
+	return healthy
+}
+
+func (f *FallbackSessionStore) setHealthy(healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.healthy = healthy
+	f.lastCheck = time.Now()
+}
+
+func (f *FallbackSessionStore) newSecondary(r *http.Request, name string) (*sessions.Session, error) {
+	session, err := f.secondary.New(r, name)
+
+	if session == nil {
+		return nil, err
+	}
+
+	return f.wrap(session, name), err
+}
+
+// wrap returns copy of session that's saved through f
+func (f *FallbackSessionStore) wrap(session *sessions.Session, name string) *sessions.Session {
+	wrapped := sessions.NewSession(f, name)
+	wrapped.ID = session.ID
+	wrapped.Values = session.Values
+	wrapped.Options = session.Options
+	wrapped.IsNew = session.IsNew
+	return wrapped
+}
+
+func isDecodeError(err error) bool {
+	cookieErr, ok := err.(securecookie.Error)
+	return ok && cookieErr.IsDecode()
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+	return err == nil
+}
+
+// discardResponseWriter is used to copy sessions into the primary store
+// where the cookie doesn't change as the session id stays the same
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header         { return http.Header{} }
+func (discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (discardResponseWriter) WriteHeader(status int)      {}
@@ -0,0 +1,160 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cacheutil_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+var errStoreDown = errors.New("store down")
+
+// downableStore is session store that fails every operation while down
+type downableStore struct {
+	*cachetest.MemorySessionStore
+	down bool
+}
+
+func (d *downableStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	if d.down {
+		return sessions.NewSession(d, name), errStoreDown
+	}
+
+	return d.MemorySessionStore.New(r, name)
+}
+
+func (d *downableStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if d.down {
+		return errStoreDown
+	}
+
+	return d.MemorySessionStore.Save(r, w, s)
+}
+
+func (d *downableStore) Ping() (bool, error) {
+	if d.down {
+		return false, errStoreDown
+	}
+
+	return true, nil
+}
+
+func TestFallbackSessionStore(t *testing.T) {
+	key := []byte("01234567890123456789012345678901")
+	primary := &downableStore{MemorySessionStore: cachetest.NewMemorySessionStore(key)}
+	secondary := cachetest.NewMemorySessionStore(key)
+	store := cacheutil.NewFallbackSessionStore(primary, secondary, cacheutil.FallbackSessionConfig{
+		CheckInterval: time.Hour,
+	})
+
+	login := func() *http.Cookie {
+		rr := httptest.NewRecorder()
+		session, err := store.Get(httptest.NewRequest(http.MethodPost, "/login", nil), "user")
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		session.Values["user"] = "foo"
+
+		if err = session.Save(httptest.NewRequest(http.MethodPost, "/login", nil), rr); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		return rr.Result().Cookies()[0]
+	}
+	user := func(cookie *http.Cookie) interface{} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookie)
+		session, err := store.Get(r, "user")
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		return session.Values["user"]
+	}
+
+	cookie := login()
+
+	if user(cookie) != "foo" {
+		t.Errorf("should read session from primary")
+	}
+
+	// Primary goes down mid request so store falls back to secondary
+	primary.down = true
+	outageCookie := login()
+
+	if store.Healthy() {
+		t.Fatalf("primary should be marked as down")
+	}
+	if user(outageCookie) != "foo" {
+		t.Errorf("should read session from secondary while primary is down")
+	}
+	if ok, _ := store.Ping(); !ok {
+		t.Errorf("should be responsive while secondary is up")
+	}
+
+	// Primary recovers once checked again
+	primary.down = false
+	store = cacheutil.NewFallbackSessionStore(primary, secondary, cacheutil.FallbackSessionConfig{})
+
+	if !store.Healthy() {
+		t.Fatalf("primary should be healthy")
+	}
+	if user(outageCookie) != "foo" {
+		t.Errorf("should resynchronize session created during outage")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(outageCookie)
+
+	if session, _ := primary.New(r, "user"); session.IsNew || session.Values["user"] != "foo" {
+		t.Errorf("session should be copied into primary")
+	}
+}
+
+func TestFallbackSessionStoreExpiry(t *testing.T) {
+	key := []byte("01234567890123456789012345678901")
+	clock := httputil.NewMockClock(time.Date(2019, time.March, 4, 12, 0, 0, 0, time.UTC))
+	primary := cachetest.NewMemorySessionStore(key)
+	primary.SetClock(clock)
+	store := cacheutil.NewFallbackSessionStore(primary, cachetest.NewMemorySessionStore(key), cacheutil.FallbackSessionConfig{})
+
+	session, err := store.New(httptest.NewRequest(http.MethodPost, "/login", nil), "user")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if _, err = store.Expiry(session); err != cacheutil.ErrCacheNil {
+		t.Errorf("should return ErrCacheNil for unsaved session; got %v", err)
+	}
+
+	session.Values["user"] = "foo"
+
+	if err = session.Save(httptest.NewRequest(http.MethodPost, "/login", nil), httptest.NewRecorder()); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	clock.Add(time.Hour)
+
+	if expiry, err := store.Expiry(session); err != nil || expiry != time.Hour*23 {
+		t.Errorf("should expire in 23 hours; got %s, %v", expiry, err)
+	}
+
+	clock.Add(time.Hour * 24)
+
+	if _, err = store.Expiry(session); err != cacheutil.ErrCacheNil {
+		t.Errorf("should return ErrCacheNil for expired session; got %v", err)
+	}
+}
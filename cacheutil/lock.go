@@ -0,0 +1,132 @@
+package cacheutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// LockKeyFormat is format of key of lock of cached table, where %s
+	// is CacheSetup#StringVal
+	LockKeyFormat = "%s-lock"
+)
+
+var (
+	// ErrLockNotAcquired is returned by AcquireLock if lock is held and
+	// by WithLock if lock was not released within LockConfig#Wait
+	ErrLockNotAcquired = errors.New("cacheutil: lock not acquired")
+)
+
+// LockKey returns key of lock of cached table of setup
+func LockKey(setup CacheSetup) string {
+	return fmt.Sprintf(LockKeyFormat, setup.StringVal)
+}
+
+// LockConfig is config struct used for WithLock
+type LockConfig struct {
+	// TTL is how long lock is held before it expires, in case holder
+	// crashes before releasing it
+	// Default is 30 seconds
+	TTL time.Duration
+
+	// Wait is how long to wait for lock held by someone else
+	// Default is 0 which doesn't wait
+	Wait time.Duration
+
+	// RetryInterval is how often lock is retried while waiting
+	// Default is 100 milliseconds
+	RetryInterval time.Duration
+}
+
+func (l *LockConfig) setDefaults() {
+	if l.TTL <= 0 {
+		l.TTL = time.Second * 30
+	}
+	if l.RetryInterval <= 0 {
+		l.RetryInterval = time.Millisecond * 100
+	}
+}
+
+// Lock is distributed lock held in cache so only one instance of an
+// app does something at once eg. warming cache of a table
+type Lock struct {
+	cache CacheStore
+	key   string
+	token string
+}
+
+// AcquireLock sets key to a random token, if key is not set, and returns
+// *Lock holding it for ttl
+// Returns ErrLockNotAcquired if lock is held by someone else
+func AcquireLock(cache CacheStore, key string, ttl time.Duration) (*Lock, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	token := hex.EncodeToString(b)
+	set, err := cache.SetNX(key, token, ttl)
+
+	if err != nil {
+		return nil, err
+	}
+	if !set {
+		return nil, ErrLockNotAcquired
+	}
+
+	return &Lock{cache: cache, key: key, token: token}, nil
+}
+
+// Release deletes lock if it's still held by l so a lock that expired
+// and was acquired by someone else is not released
+//
+// Checking and deleting are not atomic so l should be released well
+// before its ttl
+func (l *Lock) Release() error {
+	current, err := l.cache.Get(l.key)
+
+	if err == ErrCacheNil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(current) != l.token {
+		return nil
+	}
+
+	l.cache.Del(l.key)
+	return nil
+}
+
+// WithLock calls fn while holding lock of key, waiting up to
+// LockConfig#Wait for lock to be released if it's held
+// Returns ErrLockNotAcquired if lock is not acquired in time, else the
+// error of fn
+func WithLock(ctx context.Context, cache CacheStore, key string, config LockConfig, fn func() error) error {
+	config.setDefaults()
+	deadline := time.Now().Add(config.Wait)
+
+	for {
+		lock, err := AcquireLock(cache, key, config.TTL)
+
+		if err == nil {
+			defer lock.Release()
+			return fn()
+		}
+		if err != ErrLockNotAcquired || !time.Now().Before(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrLockNotAcquired
+		case <-time.After(config.RetryInterval):
+		}
+	}
+}
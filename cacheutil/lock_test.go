@@ -0,0 +1,57 @@
+package cacheutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestWithLock(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	lock, err := cacheutil.AcquireLock(cache, "users-lock", time.Minute)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if _, err = cacheutil.AcquireLock(cache, "users-lock", time.Minute); err != cacheutil.ErrLockNotAcquired {
+		t.Fatalf("held lock should return ErrLockNotAcquired; got %v", err)
+	}
+
+	// Lock released while waiting is acquired
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		lock.Release()
+	}()
+
+	called := false
+	err = cacheutil.WithLock(
+		context.Background(),
+		cache,
+		"users-lock",
+		cacheutil.LockConfig{Wait: time.Second, RetryInterval: time.Millisecond * 5},
+		func() error {
+			called = true
+			return nil
+		},
+	)
+
+	if err != nil || !called {
+		t.Fatalf("should call fn once lock is released; got %v", err)
+	}
+	if ok, _ := cache.HasKey("users-lock"); ok {
+		t.Errorf("lock should be released after fn")
+	}
+
+	// Releasing a lock acquired by someone else does nothing
+	cache.Set("users-lock", "other", 0)
+
+	if err = lock.Release(); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if ok, _ := cache.HasKey("users-lock"); !ok {
+		t.Errorf("lock of someone else should not be released")
+	}
+}
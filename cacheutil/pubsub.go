@@ -0,0 +1,12 @@
+package cacheutil
+
+// PubSub is interface used to publish messages to and subscribe to
+// messages of a channel, which is used to notify every instance of an
+// app of changes eg. invalidating cached values
+//
+// Subscribe calls handler for every message published to channel until
+// the returned close function is called
+type PubSub interface {
+	Publish(channel, message string) error
+	Subscribe(channel string, handler func(message string)) (func() error, error)
+}
@@ -0,0 +1,236 @@
+//go:build !noredis
+// +build !noredis
+
+package cacheutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// ClientCache is default struct that implements the CacheStore interface
+// The underlining implementation is based off of the
+// "github.com/go-redis/redis" library
+type ClientCache struct {
+	*redis.Client
+
+	collector StatsCollector
+}
+
+// NewClientCache returns pointer of ClientCache
+func NewClientCache(client *redis.Client) *ClientCache {
+	return &ClientCache{Client: client}
+}
+
+// NewClientCacheWithStats returns pointer of ClientCache that records
+// stats of every operation with collector
+// If collector is nil, MemoryStatsCollector is used
+func NewClientCacheWithStats(client *redis.Client, collector StatsCollector) *ClientCache {
+	if collector == nil {
+		collector = NewMemoryStatsCollector()
+	}
+
+	return &ClientCache{Client: client, collector: collector}
+}
+
+// Stats returns stats recorded by cache
+// Returns empty stats if cache was not created with
+// NewClientCacheWithStats
+func (c *ClientCache) Stats() CacheStats {
+	if c.collector == nil {
+		return CacheStats{Operations: make(map[string]OperationStats)}
+	}
+
+	return c.collector.Stats()
+}
+
+// PingContext pings redis and returns error if it's unreachable
+// It implements httputil#Pinger
+func (c *ClientCache) PingContext(ctx context.Context) error {
+	return c.Client.WithContext(ctx).Ping().Err()
+}
+
+func (c *ClientCache) record(op string, outcome Outcome, start time.Time) {
+	if c.collector != nil {
+		c.collector.Record(op, outcome, time.Since(start))
+	}
+}
+
+// Get gets value based on key passed
+// Returns error if key does not exist
+func (c *ClientCache) Get(key string) ([]byte, error) {
+	start := time.Now()
+	results, err := c.get(key)
+	c.record(OpGet, readOutcome(err), start)
+	return results, err
+}
+
+func (c *ClientCache) get(key string) ([]byte, error) {
+	var resultsErr error
+
+	results, err := c.Client.Get(key).Bytes()
+
+	if err != nil {
+		if err == redis.Nil {
+			resultsErr = ErrCacheNil
+		} else {
+			resultsErr = err
+		}
+	}
+
+	return results, resultsErr
+}
+
+// MGet gets values of keys in one round trip
+// Values are returned in the same order as keys and are nil for
+// keys that do not exist
+func (c *ClientCache) MGet(keys ...string) ([][]byte, error) {
+	start := time.Now()
+	values, err := c.mget(keys...)
+	c.record(OpMGet, mgetOutcome(values, err), start)
+	return values, err
+}
+
+func (c *ClientCache) mget(keys ...string) ([][]byte, error) {
+	results, err := c.Client.MGet(keys...).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, len(results))
+
+	for i, result := range results {
+		switch v := result.(type) {
+		case string:
+			values[i] = []byte(v)
+		case []byte:
+			values[i] = v
+		}
+	}
+
+	return values, nil
+}
+
+// Set sets value in redis server based on key and value given
+// Expiration sets how long the cache will stay in the server
+// If 0, key/value will never be deleted
+func (c *ClientCache) Set(key string, value interface{}, expiration time.Duration) {
+	c.SetErr(key, value, expiration)
+}
+
+// SetErr is the same as Set but returns error if value could not be set
+func (c *ClientCache) SetErr(key string, value interface{}, expiration time.Duration) error {
+	start := time.Now()
+	err := c.Client.Set(key, value, expiration).Err()
+	c.record(OpSet, writeOutcome(err), start)
+	return err
+}
+
+// SetNX sets value in redis server only if key does not exist and
+// returns whether value was set
+func (c *ClientCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	start := time.Now()
+	set, err := c.Client.SetNX(key, value, expiration).Result()
+	c.record(OpSetNX, writeOutcome(err), start)
+	return set, err
+}
+
+// MSet sets every value of values keyed by key using a pipeline so
+// every value is set in one round trip
+// Redis MSET is not used as it doesn't support expiration
+func (c *ClientCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	pipe := c.Client.Pipeline()
+
+	for k, v := range values {
+		pipe.Set(k, v, expiration)
+	}
+
+	_, err := pipe.Exec()
+	c.record(OpMSet, writeOutcome(err), start)
+	return err
+}
+
+// Del deletes given string array of keys from server if exists
+func (c *ClientCache) Del(keys ...string) {
+	start := time.Now()
+	err := c.Client.Del(keys...).Err()
+	c.record(OpDel, writeOutcome(err), start)
+}
+
+// HasKey takes key value and determines if that key is in cache
+func (c *ClientCache) HasKey(key string) (bool, error) {
+	start := time.Now()
+	_, err := c.get(key)
+	c.record(OpHasKey, readOutcome(err), start)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// TTL returns remaining time to live of key, 0 if key doesn't expire
+// Returns ErrCacheNil if key does not exist
+func (c *ClientCache) TTL(key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := c.Client.PTTL(key).Result()
+
+	if err == nil {
+		ttl, err = redisTTL(ttl)
+	}
+
+	c.record(OpTTL, readOutcome(err), start)
+	return ttl, err
+}
+
+// redisTTL converts reply of PTTL, where -2 is returned if key does not
+// exist and -1 if key doesn't expire
+// Depending on version of client, replies of -1 and -2 are either
+// scaled by precision of command or not
+func redisTTL(ttl time.Duration) (time.Duration, error) {
+	switch ttl {
+	case -2, -2 * time.Millisecond:
+		return 0, ErrCacheNil
+	case -1, -1 * time.Millisecond:
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Publish publishes message to channel
+func (c *ClientCache) Publish(channel, message string) error {
+	return c.Client.Publish(channel, message).Err()
+}
+
+// Subscribe subscribes to channel and calls handler for every message
+// published to channel within its own goroutine until the returned close
+// function is called
+// Returns error if subscription could not be confirmed by redis server
+func (c *ClientCache) Subscribe(channel string, handler func(message string)) (func() error, error) {
+	pubsub := c.Client.Subscribe(channel)
+
+	// Wait for confirmation so messages published after Subscribe
+	// returns are not missed
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler(msg.Payload)
+		}
+	}()
+
+	return pubsub.Close, nil
+}
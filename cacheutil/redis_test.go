@@ -0,0 +1,38 @@
+//go:build !noredis
+// +build !noredis
+
+package cacheutil
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClientCacheStatsWithoutCollector(t *testing.T) {
+	cache := NewClientCache(nil)
+
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Operations == nil {
+		t.Errorf("should return empty stats; got %v", stats)
+	}
+}
+
+func ExampleClientCache_Get_ex1() {
+	// ... Doing something and want to retrieve cache
+	value, _ := cache.Get("success")
+	fmt.Println(string(value))
+	// Output: success
+}
+
+func ExampleClientCache_Get_ex2() {
+	// ... Doing something and want to retrieve cache
+	// But key item is not in cache
+	_, err := cache.Get("fail")
+
+	if err != nil {
+		// If returns errors, then there was no key of given value
+		// so do what you will with error
+
+		fmt.Println(string(err.Error()))
+		// Output: nil
+	}
+}
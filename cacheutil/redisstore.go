@@ -0,0 +1,75 @@
+//go:build !noredis && !nosessions
+// +build !noredis,!nosessions
+
+package cacheutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/sessions"
+	redistore "gopkg.in/boj/redistore.v1"
+)
+
+type RedisStore struct {
+	*redistore.RediStore
+
+	// KeyPrefix is prefix of keys sessions are stored under, which must
+	// be the same as prefix set with redistore#RediStore#SetKeyPrefix
+	// Default is "session_"
+	KeyPrefix string
+}
+
+func NewRedisStore(store *redistore.RediStore) *RedisStore {
+	return &RedisStore{
+		RediStore: store,
+	}
+}
+
+func (r *RedisStore) Ping() (bool, error) {
+	conn := r.RediStore.Pool.Get()
+	defer conn.Close()
+	data, err := conn.Do("PING")
+	if err != nil || data == nil {
+		return false, err
+	}
+	return (data == "PONG"), nil
+}
+
+// PingContext returns error if redis is unreachable or doesn't respond
+// with "PONG"
+// It implements httputil#Pinger
+func (r *RedisStore) PingContext(ctx context.Context) error {
+	return SessionPinger(r).PingContext(ctx)
+}
+
+// Expiry returns remaining time until session expires in redis
+// Returns ErrCacheNil if session isn't stored
+func (r *RedisStore) Expiry(session *sessions.Session) (time.Duration, error) {
+	if session.ID == "" {
+		return 0, ErrCacheNil
+	}
+
+	prefix := r.KeyPrefix
+
+	if prefix == "" {
+		prefix = "session_"
+	}
+
+	conn := r.RediStore.Pool.Get()
+	defer conn.Close()
+	data, err := conn.Do("PTTL", prefix+session.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	ttl, ok := data.(int64)
+
+	if !ok {
+		return 0, fmt.Errorf("cacheutil: invalid reply of PTTL: %v", data)
+	}
+
+	return redisTTL(time.Duration(ttl) * time.Millisecond)
+}
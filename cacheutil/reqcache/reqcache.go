@@ -0,0 +1,105 @@
+// Package reqcache caches values for the lifetime of a request so
+// expensive lookups like a user's permissions, feature flags or config
+// rows are only computed once per request across middleware and handlers
+//
+// MiddlewareFunc must come before any middleware or handler that uses
+// the cache, else Get always misses and Set is a no-op
+package reqcache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type ctxKey struct{}
+
+type requestCache struct {
+	mu     sync.RWMutex
+	values map[interface{}]interface{}
+}
+
+// NewContext returns copy of ctx with an empty request cache
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestCache{
+		values: make(map[interface{}]interface{}),
+	})
+}
+
+// MiddlewareFunc installs an empty request cache within the context
+// of every request
+func MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context())))
+	})
+}
+
+// Get returns value of key and whether it was found in request cache
+// of ctx
+func Get(ctx context.Context, key interface{}) (interface{}, bool) {
+	cache := fromContext(ctx)
+
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	val, ok := cache.values[key]
+	return val, ok
+}
+
+// Set sets value of key in request cache of ctx
+// Keys should be of an unexported type, the same as context keys,
+// to avoid collisions between packages
+func Set(ctx context.Context, key, value interface{}) {
+	cache := fromContext(ctx)
+
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.values[key] = value
+}
+
+// Delete deletes key from request cache of ctx
+func Delete(ctx context.Context, key interface{}) {
+	cache := fromContext(ctx)
+
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.values, key)
+}
+
+// GetOrSet returns value of key if found in request cache of ctx, else
+// calls fn and caches its value
+// Errors returned by fn are not cached so the next call will retry
+//
+// The cache is not locked while fn is called so fn may use the cache,
+// but concurrent calls for the same key may call fn more than once
+func GetOrSet(ctx context.Context, key interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	if val, ok := Get(ctx, key); ok {
+		return val, nil
+	}
+
+	val, err := fn()
+
+	if err != nil {
+		return nil, err
+	}
+
+	Set(ctx, key, val)
+	return val, nil
+}
+
+func fromContext(ctx context.Context) *requestCache {
+	cache, _ := ctx.Value(ctxKey{}).(*requestCache)
+	return cache
+}
@@ -0,0 +1,75 @@
+package reqcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testKey string
+
+func TestGetOrSet(t *testing.T) {
+	calls := 0
+	permissions := func() (interface{}, error) {
+		calls++
+		return []string{"read"}, nil
+	}
+
+	handler := MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			val, err := GetOrSet(r.Context(), testKey("permissions"), permissions)
+
+			if err != nil {
+				t.Fatalf("should not return error; got %s", err.Error())
+			}
+			if val.([]string)[0] != "read" {
+				t.Errorf("got value %v", val)
+			}
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if calls != 2 {
+		t.Errorf("should compute once per request; got %d calls", calls)
+	}
+}
+
+func TestGetOrSetError(t *testing.T) {
+	ctx := NewContext(context.Background())
+	errFn := func() (interface{}, error) {
+		return nil, errors.New("db down")
+	}
+
+	if _, err := GetOrSet(ctx, testKey("flags"), errFn); err == nil {
+		t.Fatalf("should return error")
+	}
+	if _, ok := Get(ctx, testKey("flags")); ok {
+		t.Errorf("error should not be cached")
+	}
+}
+
+func TestWithoutMiddleware(t *testing.T) {
+	ctx := context.Background()
+	Set(ctx, testKey("key"), "value")
+
+	if _, ok := Get(ctx, testKey("key")); ok {
+		t.Errorf("should not find value without request cache")
+	}
+
+	ctx = NewContext(ctx)
+	Set(ctx, testKey("key"), "value")
+
+	if val, ok := Get(ctx, testKey("key")); !ok || val != "value" {
+		t.Errorf("got value %v", val)
+	}
+
+	Delete(ctx, testKey("key"))
+
+	if _, ok := Get(ctx, testKey("key")); ok {
+		t.Errorf("value should be deleted")
+	}
+}
@@ -0,0 +1,73 @@
+package cacheutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// sessionEnvelopeMarker is first byte of versioned session values
+// json can never start with a null byte so values stored before
+// versioning was used can still be read
+const sessionEnvelopeMarker byte = 0
+
+var (
+	// ErrInvalidSessionValue is returned from SessionConfig#DecodeValue
+	// if envelope of value is malformed
+	ErrInvalidSessionValue = errors.New("cacheutil: invalid session value")
+
+	// ErrNewerSessionVersion is returned from SessionConfig#DecodeValue
+	// if value was stored with a newer version than SessionConfig#Version,
+	// which usually happens when a deploy is rolled back
+	ErrNewerSessionVersion = errors.New("cacheutil: session value has newer version")
+)
+
+// SessionMigration is function used to migrate session values stored
+// with oldVersion to the current SessionConfig#Version
+type SessionMigration func(oldVersion int, data []byte) ([]byte, error)
+
+// EncodeValue wraps data in an envelope with SessionConfig#Version
+// Values stored in session should be encoded with this so they can be
+// migrated with SessionConfig#Migrate when the schema changes
+func (s SessionConfig) EncodeValue(data []byte) []byte {
+	buf := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(data))
+	buf[0] = sessionEnvelopeMarker
+	n := binary.PutUvarint(buf[1:], uint64(s.Version))
+	return append(buf[:1+n], data...)
+}
+
+// DecodeValue returns data of value encoded with SessionConfig#EncodeValue,
+// migrated with SessionConfig#Migrate if it was stored with an older version
+// Values without an envelope are considered version 0
+//
+// stale is true if value was stored with an older version, in which case
+// it should be encoded again and saved so it's only migrated once
+func (s SessionConfig) DecodeValue(value []byte) (data []byte, stale bool, err error) {
+	version := 0
+	data = value
+
+	if len(value) > 0 && value[0] == sessionEnvelopeMarker {
+		v, n := binary.Uvarint(value[1:])
+
+		if n <= 0 {
+			return nil, false, ErrInvalidSessionValue
+		}
+
+		version = int(v)
+		data = value[1+n:]
+	}
+
+	if version == s.Version {
+		return data, false, nil
+	}
+	if version > s.Version {
+		return nil, false, ErrNewerSessionVersion
+	}
+	if s.Migrate != nil {
+		if data, err = s.Migrate(version, data); err != nil {
+			return nil, false, fmt.Errorf("cacheutil: migrating session value from version %d: %s", version, err.Error())
+		}
+	}
+
+	return data, true, nil
+}
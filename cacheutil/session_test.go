@@ -0,0 +1,51 @@
+package cacheutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSessionConfigDecodeValue(t *testing.T) {
+	user := []byte(`{"id":"1"}`)
+	v1 := SessionConfig{Version: 1}
+	v2 := SessionConfig{
+		Version: 2,
+		Migrate: func(oldVersion int, data []byte) ([]byte, error) {
+			if oldVersion == 0 {
+				return nil, errors.New("unsupported")
+			}
+
+			return []byte(strings.Replace(string(data), `"id"`, `"userID"`, 1)), nil
+		},
+	}
+
+	data, stale, err := v1.DecodeValue(v1.EncodeValue(user))
+
+	if err != nil || stale || string(data) != string(user) {
+		t.Errorf("should return data as is; got %s, %v, %v", data, stale, err)
+	}
+
+	// Values stored before versioning are version 0
+	data, stale, err = v1.DecodeValue(user)
+
+	if err != nil || !stale || string(data) != string(user) {
+		t.Errorf("should return unversioned data as stale; got %s, %v, %v", data, stale, err)
+	}
+
+	data, stale, err = v2.DecodeValue(v1.EncodeValue(user))
+
+	if err != nil || !stale || string(data) != `{"userID":"1"}` {
+		t.Errorf("should migrate data; got %s, %v, %v", data, stale, err)
+	}
+
+	if _, _, err = v2.DecodeValue(user); err == nil {
+		t.Errorf("should return migration error")
+	}
+	if _, _, err = v1.DecodeValue(v2.EncodeValue(user)); err != ErrNewerSessionVersion {
+		t.Errorf("should return ErrNewerSessionVersion; got %v", err)
+	}
+	if _, _, err = v1.DecodeValue([]byte{sessionEnvelopeMarker}); err != ErrInvalidSessionValue {
+		t.Errorf("should return ErrInvalidSessionValue; got %v", err)
+	}
+}
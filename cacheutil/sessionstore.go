@@ -0,0 +1,48 @@
+//go:build !nosessions
+// +build !nosessions
+
+package cacheutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrSessionStoreDown is returned from SessionPinger if store responds
+	// to ping without error but isn't healthy
+	ErrSessionStoreDown = errors.New("cacheutil: session store is down")
+)
+
+// SessionStore is sessions.Store that can be pinged to determine if
+// it's healthy
+//
+// Expiry returns remaining time until session expires, which is 0 if
+// session doesn't expire, or ErrCacheNil if session isn't stored
+type SessionStore interface {
+	sessions.Store
+	Ping() (bool, error)
+	Expiry(session *sessions.Session) (time.Duration, error)
+}
+
+// SessionPinger returns store as httputil#Pinger so session stores can be
+// health checked like any other store
+func SessionPinger(store SessionStore) httputil.Pinger {
+	return httputil.PingerFunc(func(ctx context.Context) error {
+		ok, err := store.Ping()
+
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrSessionStoreDown
+		}
+
+		return nil
+	})
+}
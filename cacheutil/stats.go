@@ -0,0 +1,265 @@
+package cacheutil
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	OpGet    = "get"
+	OpMGet   = "mget"
+	OpSet    = "set"
+	OpSetNX  = "setNX"
+	OpMSet   = "mset"
+	OpDel    = "del"
+	OpHasKey = "hasKey"
+	OpTTL    = "ttl"
+)
+
+// Outcome is result of a cache operation
+type Outcome int
+
+const (
+	// OutcomeOK is outcome of operations that don't hit or miss like Set
+	OutcomeOK Outcome = iota
+	OutcomeHit
+	OutcomeMiss
+	OutcomeError
+)
+
+// OperationStats is stats of a single cache operation eg. OpGet
+type OperationStats struct {
+	Count        int64         `json:"count"`
+	Errors       int64         `json:"errors"`
+	TotalLatency time.Duration `json:"totalLatency"`
+	MaxLatency   time.Duration `json:"maxLatency"`
+}
+
+// AvgLatency returns average latency of operation
+func (o OperationStats) AvgLatency() time.Duration {
+	if o.Count == 0 {
+		return 0
+	}
+
+	return o.TotalLatency / time.Duration(o.Count)
+}
+
+// CacheStats is snapshot of stats recorded by StatsCollector
+type CacheStats struct {
+	Hits       int64                     `json:"hits"`
+	Misses     int64                     `json:"misses"`
+	Errors     int64                     `json:"errors"`
+	Operations map[string]OperationStats `json:"operations"`
+}
+
+// HitRatio returns ratio of hits to hits and misses
+func (c CacheStats) HitRatio() float64 {
+	if c.Hits+c.Misses == 0 {
+		return 0
+	}
+
+	return float64(c.Hits) / float64(c.Hits+c.Misses)
+}
+
+// StatsProvider is implemented by caches that record stats
+type StatsProvider interface {
+	Stats() CacheStats
+}
+
+// StatsCollector records stats of cache operations
+// Implementations can export stats to things like prometheus
+type StatsCollector interface {
+	StatsProvider
+	Record(op string, outcome Outcome, latency time.Duration)
+}
+
+// MemoryStatsCollector is StatsCollector that keeps stats in memory
+type MemoryStatsCollector struct {
+	mu    sync.Mutex
+	stats CacheStats
+}
+
+// NewMemoryStatsCollector returns *MemoryStatsCollector
+func NewMemoryStatsCollector() *MemoryStatsCollector {
+	return &MemoryStatsCollector{
+		stats: CacheStats{Operations: make(map[string]OperationStats)},
+	}
+}
+
+// Record records outcome and latency of op
+func (m *MemoryStatsCollector) Record(op string, outcome Outcome, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	opStats := m.stats.Operations[op]
+	opStats.Count++
+	opStats.TotalLatency += latency
+
+	if latency > opStats.MaxLatency {
+		opStats.MaxLatency = latency
+	}
+
+	switch outcome {
+	case OutcomeHit:
+		m.stats.Hits++
+	case OutcomeMiss:
+		m.stats.Misses++
+	case OutcomeError:
+		m.stats.Errors++
+		opStats.Errors++
+	}
+
+	m.stats.Operations[op] = opStats
+}
+
+// Stats returns snapshot of recorded stats
+func (m *MemoryStatsCollector) Stats() CacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	stats.Operations = make(map[string]OperationStats, len(m.stats.Operations))
+
+	for k, v := range m.stats.Operations {
+		stats.Operations[k] = v
+	}
+
+	return stats
+}
+
+// InstrumentedCache wraps CacheStore and records stats of every
+// operation with StatsCollector
+// ErrCacheNil returned from Get or HasKey is recorded as a miss
+type InstrumentedCache struct {
+	CacheStore
+	collector StatsCollector
+}
+
+// NewInstrumentedCache returns *InstrumentedCache
+// If collector is nil, MemoryStatsCollector is used
+func NewInstrumentedCache(cache CacheStore, collector StatsCollector) *InstrumentedCache {
+	if collector == nil {
+		collector = NewMemoryStatsCollector()
+	}
+
+	return &InstrumentedCache{
+		CacheStore: cache,
+		collector:  collector,
+	}
+}
+
+// Get is wrapper for CacheStore#Get
+func (i *InstrumentedCache) Get(key string) ([]byte, error) {
+	start := time.Now()
+	val, err := i.CacheStore.Get(key)
+	i.collector.Record(OpGet, readOutcome(err), time.Since(start))
+	return val, err
+}
+
+// MGet is wrapper for CacheStore#MGet
+// Recorded as a miss if any key does not exist
+func (i *InstrumentedCache) MGet(keys ...string) ([][]byte, error) {
+	start := time.Now()
+	values, err := i.CacheStore.MGet(keys...)
+	i.collector.Record(OpMGet, mgetOutcome(values, err), time.Since(start))
+	return values, err
+}
+
+// Set is wrapper for CacheStore#Set
+func (i *InstrumentedCache) Set(key string, value interface{}, expiration time.Duration) {
+	start := time.Now()
+	i.CacheStore.Set(key, value, expiration)
+	i.collector.Record(OpSet, OutcomeOK, time.Since(start))
+}
+
+// SetErr is wrapper for CacheStore#SetErr
+func (i *InstrumentedCache) SetErr(key string, value interface{}, expiration time.Duration) error {
+	start := time.Now()
+	err := i.CacheStore.SetErr(key, value, expiration)
+	i.collector.Record(OpSet, writeOutcome(err), time.Since(start))
+	return err
+}
+
+// SetNX is wrapper for CacheStore#SetNX
+func (i *InstrumentedCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	start := time.Now()
+	set, err := i.CacheStore.SetNX(key, value, expiration)
+	i.collector.Record(OpSetNX, writeOutcome(err), time.Since(start))
+	return set, err
+}
+
+// MSet is wrapper for CacheStore#MSet
+func (i *InstrumentedCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	start := time.Now()
+	err := i.CacheStore.MSet(values, expiration)
+	i.collector.Record(OpMSet, writeOutcome(err), time.Since(start))
+	return err
+}
+
+// Del is wrapper for CacheStore#Del
+func (i *InstrumentedCache) Del(keys ...string) {
+	start := time.Now()
+	i.CacheStore.Del(keys...)
+	i.collector.Record(OpDel, OutcomeOK, time.Since(start))
+}
+
+// HasKey is wrapper for CacheStore#HasKey
+func (i *InstrumentedCache) HasKey(key string) (bool, error) {
+	start := time.Now()
+	ok, err := i.CacheStore.HasKey(key)
+
+	outcome := readOutcome(err)
+
+	if err == nil && !ok {
+		outcome = OutcomeMiss
+	}
+
+	i.collector.Record(OpHasKey, outcome, time.Since(start))
+	return ok, err
+}
+
+// TTL is wrapper for CacheStore#TTL
+func (i *InstrumentedCache) TTL(key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := i.CacheStore.TTL(key)
+	i.collector.Record(OpTTL, readOutcome(err), time.Since(start))
+	return ttl, err
+}
+
+// Stats returns stats of collector
+func (i *InstrumentedCache) Stats() CacheStats {
+	return i.collector.Stats()
+}
+
+func readOutcome(err error) Outcome {
+	switch err {
+	case nil:
+		return OutcomeHit
+	case ErrCacheNil:
+		return OutcomeMiss
+	}
+
+	return OutcomeError
+}
+
+func mgetOutcome(values [][]byte, err error) Outcome {
+	if err != nil {
+		return OutcomeError
+	}
+
+	for _, v := range values {
+		if v == nil {
+			return OutcomeMiss
+		}
+	}
+
+	return OutcomeHit
+}
+
+func writeOutcome(err error) Outcome {
+	if err != nil {
+		return OutcomeError
+	}
+
+	return OutcomeOK
+}
@@ -0,0 +1,166 @@
+package cacheutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type statsTestStore struct {
+	values map[string][]byte
+}
+
+func (s *statsTestStore) Get(key string) ([]byte, error) {
+	if key == "error" {
+		return nil, errors.New("connection refused")
+	}
+
+	val, ok := s.values[key]
+
+	if !ok {
+		return nil, ErrCacheNil
+	}
+
+	return val, nil
+}
+
+func (s *statsTestStore) MGet(keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+
+	for i, k := range keys {
+		values[i] = s.values[k]
+	}
+
+	return values, nil
+}
+
+func (s *statsTestStore) Set(key string, value interface{}, expiration time.Duration) {
+	switch v := value.(type) {
+	case []byte:
+		s.values[key] = v
+	case string:
+		s.values[key] = []byte(v)
+	}
+}
+
+func (s *statsTestStore) SetErr(key string, value interface{}, expiration time.Duration) error {
+	s.Set(key, value, expiration)
+	return nil
+}
+
+func (s *statsTestStore) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+
+	s.Set(key, value, expiration)
+	return true, nil
+}
+
+func (s *statsTestStore) MSet(values map[string]interface{}, expiration time.Duration) error {
+	for k, v := range values {
+		s.Set(k, v, expiration)
+	}
+
+	return nil
+}
+
+func (s *statsTestStore) Del(keys ...string) {
+	for _, k := range keys {
+		delete(s.values, k)
+	}
+}
+
+func (s *statsTestStore) HasKey(key string) (bool, error) {
+	_, err := s.Get(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *statsTestStore) TTL(key string) (time.Duration, error) {
+	if _, err := s.Get(key); err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+func TestInstrumentedCache(t *testing.T) {
+	cache := NewInstrumentedCache(&statsTestStore{values: make(map[string][]byte)}, nil)
+
+	cache.Set("key", []byte("value"), 0)
+
+	if _, err := cache.Get("key"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if _, err := cache.Get("missing"); err != ErrCacheNil {
+		t.Fatalf("should return ErrCacheNil; got %v", err)
+	}
+	if _, err := cache.Get("error"); err == nil {
+		t.Fatalf("should return error")
+	}
+
+	cache.HasKey("missing")
+	cache.Del("key")
+
+	stats := cache.Stats()
+
+	if stats.Hits != 1 {
+		t.Errorf("hits should be 1; got %d", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("misses should be 2; got %d", stats.Misses)
+	}
+	if stats.Errors != 1 {
+		t.Errorf("errors should be 1; got %d", stats.Errors)
+	}
+	if stats.Operations[OpGet].Count != 3 {
+		t.Errorf("get count should be 3; got %d", stats.Operations[OpGet].Count)
+	}
+	if stats.Operations[OpGet].Errors != 1 {
+		t.Errorf("get errors should be 1; got %d", stats.Operations[OpGet].Errors)
+	}
+	if stats.Operations[OpSet].Count != 1 || stats.Operations[OpDel].Count != 1 {
+		t.Errorf("set and del count should be 1; got %d and %d", stats.Operations[OpSet].Count, stats.Operations[OpDel].Count)
+	}
+	if stats.HitRatio() != float64(1)/3 {
+		t.Errorf("hit ratio should be 1/3; got %f", stats.HitRatio())
+	}
+
+	stats.Operations[OpGet] = OperationStats{}
+
+	if cache.Stats().Operations[OpGet].Count != 3 {
+		t.Errorf("stats should be a copy")
+	}
+}
+
+func TestInstrumentedCacheBatch(t *testing.T) {
+	cache := NewInstrumentedCache(&statsTestStore{values: make(map[string][]byte)}, nil)
+
+	if err := cache.MSet(map[string]interface{}{"a": []byte("1"), "b": []byte("2")}, 0); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	values, _ := cache.MGet("a", "b")
+
+	if string(values[0]) != "1" || string(values[1]) != "2" {
+		t.Errorf("got values %s", values)
+	}
+
+	if values, _ = cache.MGet("a", "missing"); values[1] != nil {
+		t.Errorf("missing key should be nil; got %s", values[1])
+	}
+
+	stats := cache.Stats()
+
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("should have 1 hit and 1 miss; got %d and %d", stats.Hits, stats.Misses)
+	}
+	if stats.Operations[OpMSet].Count != 1 {
+		t.Errorf("mset count should be 1; got %d", stats.Operations[OpMSet].Count)
+	}
+}
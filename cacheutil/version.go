@@ -0,0 +1,98 @@
+package cacheutil
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// VersionKeyFormat is format of key of version of cached table, where
+	// %s is CacheSetup#StringVal
+	VersionKeyFormat = "%s-version"
+)
+
+// VersionKey returns key of version of cached table of setup
+func VersionKey(setup CacheSetup) string {
+	return fmt.Sprintf(VersionKeyFormat, setup.StringVal)
+}
+
+// TableVersions returns current version of each table of setups, in
+// the same order, where tables without a version are given one
+//
+// Versions change every time BumpVersions is called for their table so
+// they can be used to tell if anything cached from a table, or queried
+// from it, is stale eg. for etags of list endpoints
+func TableVersions(cache CacheStore, setups ...CacheSetup) ([]string, error) {
+	if len(setups) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(setups))
+
+	for _, setup := range setups {
+		keys = append(keys, VersionKey(setup))
+	}
+
+	values, err := cache.MGet(keys...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(setups))
+
+	for i, value := range values {
+		if value != nil {
+			versions[i] = string(value)
+			continue
+		}
+
+		if versions[i], err = bumpVersion(cache, keys[i], 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
+
+// BumpVersions gives each table of setups a new version greater than
+// its current one
+// This should be called after every write to the tables, along with
+// deleting their cached values
+func BumpVersions(cache CacheStore, setups ...CacheSetup) error {
+	for _, setup := range setups {
+		key := VersionKey(setup)
+		current, err := cache.Get(key)
+
+		if err != nil && err != ErrCacheNil {
+			return err
+		}
+
+		previous, _ := strconv.ParseInt(string(current), 10, 64)
+
+		if _, err = bumpVersion(cache, key, previous); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bumpVersion sets version of key to current time, or previous + 1 if
+// clock is behind previous, so versions only increase
+func bumpVersion(cache CacheStore, key string, previous int64) (string, error) {
+	version := time.Now().UnixNano()
+
+	if version <= previous {
+		version = previous + 1
+	}
+
+	value := strconv.FormatInt(version, 10)
+
+	if err := cache.SetErr(key, value, 0); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,45 @@
+package cacheutil_test
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestTableVersions(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	users := cacheutil.CacheSetup{StringVal: "users"}
+	groups := cacheutil.CacheSetup{StringVal: "groups"}
+
+	versions, err := cacheutil.TableVersions(cache, users, groups)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if len(versions) != 2 || versions[0] == "" || versions[1] == "" {
+		t.Fatalf("should give every table a version; got %v", versions)
+	}
+
+	again, _ := cacheutil.TableVersions(cache, users, groups)
+
+	if again[0] != versions[0] || again[1] != versions[1] {
+		t.Errorf("should keep versions; got %v and %v", versions, again)
+	}
+
+	// Version far in the future still increases
+	cache.Set(cacheutil.VersionKey(groups), "9223372036854775806", 0)
+
+	if err = cacheutil.BumpVersions(cache, users, groups); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	bumped, _ := cacheutil.TableVersions(cache, users, groups)
+
+	if bumped[0] == versions[0] {
+		t.Errorf("should bump version of users; got %s", bumped[0])
+	}
+	if bumped[1] != "9223372036854775807" {
+		t.Errorf("should increase version of groups; got %s", bumped[1])
+	}
+}
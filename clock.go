@@ -0,0 +1,59 @@
+package httputil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time so code that depends on it, eg. date
+// validation and expiry, can be tested deterministically with MockClock
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// RealClock is Clock of the system time, which is the default Clock of
+// every config that takes one
+var RealClock Clock = realClock{}
+
+// MockClock is Clock whose time only changes when it's set or advanced
+// It's safe for concurrent use
+type MockClock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewMockClock returns *MockClock set to now
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Now returns time of clock
+func (m *MockClock) Now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.now
+}
+
+// Set sets time of clock to now
+func (m *MockClock) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
+
+// Add advances time of clock by d and returns the new time
+func (m *MockClock) Add(d time.Duration) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+	return m.now
+}
@@ -0,0 +1,109 @@
+// Command formgen generates the queryutil.FieldConfig map, form struct
+// and baseline validators of a database table, see package formgen
+//
+// Usage:
+//
+//	formgen -dsn=postgres://... -table=users -pkg=forms -out=users_gen.go -exclude=id
+//
+// Tables of the current schema are looked up with dbutil.Inspect so
+// columns of their primary key are left out of the form struct
+//
+// Only postgres is supported as other drivers are not dependencies of
+// this library, use formgen.Generate with dbutil.TableColumns directly
+// for other databases
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/formutil/formgen"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "data source name of database, default is $DATABASE_URL")
+	table := flag.String("table", "", "table to generate from, optionally qualified with schema")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of generated file, default is $GOPACKAGE set by go generate")
+	typeName := flag.String("type", "", "prefix of generated names, default is table in camel case")
+	exclude := flag.String("exclude", "", "comma separated columns to leave out of form struct")
+	out := flag.String("out", "", "file to write to, default is stdout")
+	flag.Parse()
+
+	if err := run(*dsn, *table, *pkg, *typeName, *exclude, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "formgen: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(dsn, table, pkg, typeName, exclude, out string) error {
+	if dsn == "" || table == "" {
+		return fmt.Errorf("-dsn and -table are required")
+	}
+
+	db, err := sqlx.Open(dbutil.Postgres, dsn)
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	config := formgen.Config{
+		Package:  pkg,
+		Table:    table,
+		TypeName: typeName,
+	}
+
+	if exclude != "" {
+		config.Exclude = strings.Split(exclude, ",")
+	}
+
+	var buf bytes.Buffer
+
+	if err = generate(&buf, &dbutil.DB{DB: db}, config); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	return ioutil.WriteFile(out, buf.Bytes(), 0644)
+}
+
+// generate generates table of config from schema returned from
+// dbutil.Inspect, unless table is qualified with schema
+func generate(w io.Writer, db *dbutil.DB, config formgen.Config) error {
+	if !strings.Contains(config.Table, ".") {
+		schema, err := dbutil.Inspect(db)
+
+		if err != nil {
+			return err
+		}
+
+		table, ok := schema.Table(config.Table)
+
+		if !ok {
+			return dbutil.ErrTableNotFound
+		}
+
+		return formgen.GenerateTable(w, config, table)
+	}
+
+	columns, err := dbutil.TableColumns(db, dbutil.Postgres, config.Table)
+
+	if err != nil {
+		return err
+	}
+
+	return formgen.Generate(w, config, columns)
+}
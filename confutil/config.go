@@ -1,7 +1,6 @@
 package confutil
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 	"regexp"
@@ -10,6 +9,9 @@ import (
 	"github.com/pkg/errors"
 
 	yaml "gopkg.in/yaml.v2"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/ctxutil"
 )
 
 const (
@@ -43,14 +45,15 @@ const (
 )
 
 var (
-	UserCtxKey      = Key{KeyName: "user"}
-	GroupCtxKey     = Key{KeyName: "groupName"}
+	// UserCtxKey and GroupCtxKey are the same keys used by apiutil
+	// middleware, see ctxutil
+	UserCtxKey      = ctxutil.UserJSONKey
+	GroupCtxKey     = ctxutil.GroupsKey
 	FormDateTimeExp = regexp.MustCompile("^[0-9]{1,2}/[0-9]{1,2}/[0-9]{4} [0-9]{1,2}:[0-9]{2} (?i)(AM|PM)$")
 )
 
-type Key struct {
-	KeyName string
-}
+// Key is type of context keys, see ctxutil#Key
+type Key = ctxutil.Key
 
 // ConfigSettings simply takes a string which should reference an enviroment variable
 // that points to config file used for application
@@ -69,8 +72,8 @@ func ConfigSettings(envString string) (*Settings, error) {
 	return settings, nil
 }
 
-// CheckError simply prints given error in verbose to stdout
+// CheckError logs given error in verbose with httputil#Logger
 func CheckError(err error, customMessage string) {
 	err = errors.Wrap(err, customMessage)
-	fmt.Printf("%+v\n", err)
+	httputil.Logger.Errorf("%+v", err)
 }
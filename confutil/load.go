@@ -0,0 +1,483 @@
+package confutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// EnvKeySeparator is the separator used within environment variable
+	// names to indicate nesting of config keys
+	// eg. DATABASE_CONFIG__PROD__PASSWORD maps to database_config.prod.password
+	EnvKeySeparator = "__"
+)
+
+// Config file formats supported by Load
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+var (
+	// ErrInvalidConfigFormat is returned when LoadConfig#Format is not
+	// one of the supported formats
+	ErrInvalidConfigFormat = errors.New("confutil: invalid config format")
+
+	// ErrEmptyConfigPath is returned when no path is passed to Load or Watch
+	ErrEmptyConfigPath = errors.New("confutil: config path can't be empty")
+)
+
+// RequiredFieldsError is returned from Load when one or more of
+// LoadConfig#RequiredFields are not set
+type RequiredFieldsError struct {
+	Fields []string
+}
+
+func (r *RequiredFieldsError) Error() string {
+	return fmt.Sprintf("confutil: missing required config fields: %s", strings.Join(r.Fields, ", "))
+}
+
+// LoadConfig is config struct used in conjunction with Load and Watch
+type LoadConfig struct {
+	// Format is the format of the config file which should be one of
+	// FormatYAML, FormatJSON or FormatTOML
+	//
+	// If empty, the format is determined by the file extension
+	// and defaults to FormatYAML
+	Format string
+
+	// Profile is the name of the profile within Settings#Profiles
+	// to apply after the config is loaded
+	Profile string
+
+	// ExpandEnv will expand any ${VAR} or $VAR references within the
+	// config file with the value of the environment variable before parsing
+	ExpandEnv bool
+
+	// DisableEnvOverrides will skip overriding config values with
+	// environment variables
+	//
+	// By default, any environment variable that contains EnvKeySeparator
+	// (or that starts with EnvPrefix if set) is used to override the
+	// config value at the matching path, eg.
+	// DATABASE_CONFIG__PROD__PASSWORD=secret overrides
+	// database_config.prod.password
+	DisableEnvOverrides bool
+
+	// EnvPrefix restricts environment overrides to variables starting with
+	// the prefix followed by an underscore, eg. with prefix "APP",
+	// APP_DOMAIN overrides domain
+	EnvPrefix string
+
+	// SecretResolvers is map of scheme to SecretResolver used to resolve
+	// config values that reference secrets eg. "vault:secret/db#password"
+	// Values are resolved after environment overrides are applied
+	//
+	// If nil, no secrets are resolved; DefaultSecretResolvers can be
+	// used to resolve env and file references
+	SecretResolvers map[string]SecretResolver
+
+	// RequiredFields is a list of dotted yaml paths that must be set
+	// and not empty after the config is loaded, eg. "database_config.prod.host"
+	RequiredFields []string
+
+	// OnChange is called with the newly loaded settings every time
+	// the config file is successfully reloaded by Watch
+	OnChange func(settings *Settings)
+
+	// OnError is called when reloading the config file by Watch fails
+	// The previous settings are kept when this happens
+	OnError func(err error)
+}
+
+// Load reads the config file at given path, applies environment
+// expansion and overrides based on config and validates that all
+// required fields are set
+//
+// Unlike ConfigSettings, errors are returned for every stage so the caller
+// can decide how to fail
+func Load(path string, config LoadConfig) (*Settings, error) {
+	if path == "" {
+		return nil, ErrEmptyConfigPath
+	}
+
+	source, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if config.Format == "" {
+		config.Format = formatFromPath(path)
+	}
+
+	return loadSettings(source, config)
+}
+
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatYAML
+	}
+}
+
+// decodeRaw decodes source into generic map based on format
+// JSON and TOML are converted to the same map type yaml decodes to
+// so the rest of the loading process is the same for every format
+func decodeRaw(source []byte, format string) (map[interface{}]interface{}, error) {
+	var raw map[interface{}]interface{}
+
+	switch format {
+	case FormatYAML, "":
+		if err := yaml.Unmarshal(source, &raw); err != nil {
+			return nil, errors.Wrap(err, "confutil")
+		}
+	case FormatJSON:
+		var m map[string]interface{}
+
+		if err := json.Unmarshal(source, &m); err != nil {
+			return nil, errors.Wrap(err, "confutil")
+		}
+
+		raw, _ = toRawValue(m).(map[interface{}]interface{})
+	case FormatTOML:
+		var m map[string]interface{}
+
+		if err := toml.Unmarshal(source, &m); err != nil {
+			return nil, errors.Wrap(err, "confutil")
+		}
+
+		raw, _ = toRawValue(m).(map[interface{}]interface{})
+	default:
+		return nil, ErrInvalidConfigFormat
+	}
+
+	return raw, nil
+}
+
+func toRawValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+
+		for key, t := range v {
+			m[key] = toRawValue(t)
+		}
+
+		return m
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+
+		for _, t := range v {
+			list = append(list, toRawValue(t))
+		}
+
+		return list
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+
+		for _, t := range v {
+			list = append(list, toRawValue(t))
+		}
+
+		return list
+	default:
+		return val
+	}
+}
+
+func loadSettings(source []byte, config LoadConfig) (*Settings, error) {
+	var settings *Settings
+
+	if config.ExpandEnv {
+		source = []byte(os.ExpandEnv(string(source)))
+	}
+
+	raw, err := decodeRaw(source, config.Format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		raw = make(map[interface{}]interface{})
+	}
+
+	if !config.DisableEnvOverrides {
+		applyEnvOverrides(raw, config.EnvPrefix, os.Environ())
+	}
+
+	if config.SecretResolvers != nil {
+		if _, err = resolveSecrets(raw, config.SecretResolvers); err != nil {
+			return nil, err
+		}
+	}
+
+	if missing := missingFields(raw, config.RequiredFields); len(missing) > 0 {
+		return nil, &RequiredFieldsError{Fields: missing}
+	}
+
+	merged, err := yaml.Marshal(raw)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if err = yaml.Unmarshal(merged, &settings); err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if settings == nil {
+		settings = &Settings{}
+	}
+
+	if config.Profile != "" {
+		return settings.Select(config.Profile)
+	}
+
+	return settings, nil
+}
+
+// applyEnvOverrides sets values within raw based on the given environ
+// list which should be in "key=value" form
+//
+// Overrides whose path goes through a value that isn't a map, or that
+// would replace a map with a value, are skipped as they're most likely
+// unrelated variables that happen to contain EnvKeySeparator
+func applyEnvOverrides(raw map[interface{}]interface{}, prefix string, environ []string) {
+	// Sort so overrides are applied deterministically
+	sort.Strings(environ)
+
+	for _, env := range environ {
+		idx := strings.Index(env, "=")
+
+		if idx < 1 {
+			continue
+		}
+
+		key := env[:idx]
+		value := env[idx+1:]
+
+		if prefix != "" {
+			if !strings.HasPrefix(key, prefix+"_") {
+				continue
+			}
+
+			key = strings.TrimPrefix(key, prefix+"_")
+		} else if !strings.Contains(key, EnvKeySeparator) {
+			continue
+		}
+
+		var typedVal interface{}
+
+		// Decode value as yaml so things like bools and ints
+		// are set with their proper type
+		if err := yaml.Unmarshal([]byte(value), &typedVal); err != nil || typedVal == nil {
+			typedVal = value
+		}
+
+		path := strings.Split(strings.ToLower(key), EnvKeySeparator)
+
+		if !setRawPath(raw, path, typedVal) {
+			httputil.Debugf("confutil: skipping env override %s as it conflicts with config", key)
+		}
+	}
+}
+
+// setRawPath sets val at path within raw, creating maps along the way
+// Returns false without setting val if a value along path isn't a map
+// or if val would replace a map
+func setRawPath(raw map[interface{}]interface{}, path []string, val interface{}) bool {
+	current := raw
+
+	for i, p := range path {
+		existing, exists := current[p]
+
+		if i == len(path)-1 {
+			if _, isMap := existing.(map[interface{}]interface{}); isMap {
+				return false
+			}
+
+			current[p] = val
+			return true
+		}
+
+		next, ok := existing.(map[interface{}]interface{})
+
+		if !ok {
+			if exists && existing != nil {
+				return false
+			}
+
+			next = make(map[interface{}]interface{})
+			current[p] = next
+		}
+
+		current = next
+	}
+
+	return true
+}
+
+// missingFields returns every dotted path within fields that is not
+// found in raw or has an empty value
+func missingFields(raw map[interface{}]interface{}, fields []string) []string {
+	missing := make([]string, 0)
+
+	for _, field := range fields {
+		var current interface{} = raw
+
+		for _, p := range strings.Split(field, ".") {
+			m, ok := current.(map[interface{}]interface{})
+
+			if !ok {
+				current = nil
+				break
+			}
+
+			current = m[p]
+		}
+
+		if current == nil || current == "" {
+			missing = append(missing, field)
+		}
+	}
+
+	return missing
+}
+
+// SettingsWatcher keeps settings loaded from a config file up to
+// date by reloading them every time the file changes
+type SettingsWatcher struct {
+	path     string
+	config   LoadConfig
+	settings *Settings
+	watcher  *fsnotify.Watcher
+	mu       sync.RWMutex
+	done     chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Watch loads the config file at given path just like Load and then
+// watches the file for changes, reloading settings and calling
+// LoadConfig#OnChange each time it is successfully reloaded
+//
+// SettingsWatcher#Close should be called when watching is no longer needed
+func Watch(path string, config LoadConfig) (*SettingsWatcher, error) {
+	settings, err := Load(path, config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	// Watch the directory instead of the file as a lot of editors and
+	// deploy tools replace the file instead of writing to it
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	s := &SettingsWatcher{
+		path:     path,
+		config:   config,
+		settings: settings,
+		watcher:  watcher,
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Settings returns the most recently loaded settings
+func (s *SettingsWatcher) Settings() *Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+// Close stops watching the config file
+// It's safe to call more than once
+func (s *SettingsWatcher) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.closeErr = s.watcher.Close()
+	})
+
+	return s.closeErr
+}
+
+func (s *SettingsWatcher) run() {
+	fileName := filepath.Clean(s.path)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != fileName {
+				continue
+			}
+
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			settings, err := Load(s.path, s.config)
+
+			if err != nil {
+				if s.config.OnError != nil {
+					s.config.OnError(err)
+				}
+				continue
+			}
+
+			s.mu.Lock()
+			s.settings = settings
+			s.mu.Unlock()
+
+			if s.config.OnChange != nil {
+				s.config.OnChange(settings)
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			if s.config.OnError != nil {
+				s.config.OnError(errors.Wrap(err, "confutil"))
+			}
+		}
+	}
+}
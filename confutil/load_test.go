@@ -0,0 +1,306 @@
+package confutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+domain: example.com
+https: false
+database_config:
+  prod:
+    host: localhost
+    password: ${TEST_DB_PASSWORD}
+`
+
+func TestLoadSettingsEnvOverrides(t *testing.T) {
+	var err error
+	var settings *Settings
+
+	raw := map[interface{}]interface{}{
+		"https": false,
+		"database_config": map[interface{}]interface{}{
+			"prod": map[interface{}]interface{}{"host": "localhost"},
+		},
+	}
+	applyEnvOverrides(raw, "", []string{
+		"DATABASE_CONFIG__PROD__PORT=5432",
+		"DATABASE_CONFIG__PROD=foo",
+		"HTTPS__ENABLED=true",
+		"PATH=/usr/bin",
+	})
+
+	if _, ok := raw["path"]; ok {
+		t.Errorf("variables without separator should not be applied\n")
+	}
+
+	prod := raw["database_config"].(map[interface{}]interface{})["prod"].(map[interface{}]interface{})
+
+	if prod["port"] != 5432 {
+		t.Errorf("port should be 5432; got %v\n", prod["port"])
+	}
+
+	// Overrides that conflict with existing values are skipped
+	if raw["https"] != false {
+		t.Errorf("https should not be replaced with map; got %v\n", raw["https"])
+	}
+	if prod["host"] != "localhost" {
+		t.Errorf("prod should not be replaced with value; got %v\n", prod)
+	}
+
+	raw = make(map[interface{}]interface{})
+	applyEnvOverrides(raw, "APP", []string{"APP_DOMAIN=foo.com", "DOMAIN=bar.com"})
+
+	if raw["domain"] != "foo.com" {
+		t.Errorf("domain should be foo.com; got %v\n", raw["domain"])
+	}
+
+	if settings, err = loadSettings([]byte(testConfig), LoadConfig{DisableEnvOverrides: true}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if settings.Domain != "example.com" || settings.DatabaseConfig.Prod.Host != "localhost" {
+		t.Errorf("settings not loaded properly: %v\n", settings)
+	}
+
+	_, err = loadSettings([]byte(testConfig), LoadConfig{
+		DisableEnvOverrides: true,
+		RequiredFields:      []string{"database_config.prod.host", "database_config.prod.user", "csrf"},
+	})
+
+	fieldErr, ok := err.(*RequiredFieldsError)
+
+	if !ok {
+		t.Fatalf("should have returned RequiredFieldsError; got %v\n", err)
+	}
+
+	if len(fieldErr.Fields) != 2 {
+		t.Errorf("should have 2 missing fields; got %v\n", fieldErr.Fields)
+	}
+}
+
+func TestLoadEnvOverrideConflict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confutil")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+
+	if err = ioutil.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	os.Setenv("HTTPS__ENABLED", "true")
+	defer os.Unsetenv("HTTPS__ENABLED")
+
+	settings, err := Load(path, LoadConfig{})
+
+	if err != nil {
+		t.Fatalf("unrelated env variable should not fail load; got %s\n", err.Error())
+	}
+
+	if settings.HTTPS {
+		t.Errorf("https should be false\n")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confutil")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+
+	if err = ioutil.WriteFile(path, []byte("domain: example.com\n"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	changes := make(chan *Settings, 10)
+	errs := make(chan error, 10)
+
+	watcher, err := Watch(path, LoadConfig{
+		DisableEnvOverrides: true,
+		OnChange: func(settings *Settings) {
+			changes <- settings
+		},
+		OnError: func(err error) {
+			errs <- err
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if watcher.Settings().Domain != "example.com" {
+		t.Errorf("domain should be example.com; got %s\n", watcher.Settings().Domain)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("domain: foo.com\n"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	select {
+	case settings := <-changes:
+		if settings.Domain != "foo.com" {
+			t.Errorf("domain should be foo.com; got %s\n", settings.Domain)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("OnChange should be called when file changes\n")
+	}
+
+	if watcher.Settings().Domain != "foo.com" {
+		t.Errorf("watcher domain should be foo.com; got %s\n", watcher.Settings().Domain)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("domain: [\n"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("OnError should be called when file is invalid\n")
+	}
+
+	if watcher.Settings().Domain != "foo.com" {
+		t.Errorf("previous settings should be kept; got %s\n", watcher.Settings().Domain)
+	}
+
+	if err = watcher.Close(); err != nil {
+		t.Errorf("should close; got %s\n", err.Error())
+	}
+	if err = watcher.Close(); err != nil {
+		t.Errorf("second close should not fail; got %s\n", err.Error())
+	}
+
+	if _, err = Watch("", LoadConfig{}); err != ErrEmptyConfigPath {
+		t.Errorf("should have returned ErrEmptyConfigPath; got %v\n", err)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	resolvers := map[string]SecretResolver{
+		"test": SecretResolverFunc(func(ref string) (string, error) {
+			if ref == "secret/db#password" {
+				return "supersecret", nil
+			}
+
+			return "", ErrSecretNotFound
+		}),
+	}
+
+	raw := map[interface{}]interface{}{
+		"domain": "https://example.com",
+		"database_config": map[interface{}]interface{}{
+			"prod": map[interface{}]interface{}{
+				"password": "test:secret/db#password",
+			},
+		},
+	}
+
+	if _, err := resolveSecrets(raw, resolvers); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	prod := raw["database_config"].(map[interface{}]interface{})["prod"].(map[interface{}]interface{})
+
+	if prod["password"] != "supersecret" {
+		t.Errorf("password should be resolved; got %v\n", prod["password"])
+	}
+
+	if raw["domain"] != "https://example.com" {
+		t.Errorf("unregistered scheme should not be resolved; got %v\n", raw["domain"])
+	}
+
+	raw["csrf"] = "test:missing"
+
+	if _, err := resolveSecrets(raw, resolvers); err != ErrSecretNotFound {
+		t.Errorf("should have returned ErrSecretNotFound; got %v\n", err)
+	}
+}
+
+func TestLoadSettingsFormats(t *testing.T) {
+	jsonConfig := `{"domain": "example.com", "server": {"read_timeout": "30s", "idle_timeout": 60}}`
+	tomlConfig := "domain = \"example.com\"\n[server]\nread_timeout = \"30s\"\nidle_timeout = 60\n"
+
+	for format, source := range map[string]string{FormatJSON: jsonConfig, FormatTOML: tomlConfig} {
+		settings, err := loadSettings([]byte(source), LoadConfig{Format: format, DisableEnvOverrides: true})
+
+		if err != nil {
+			t.Fatalf("format %s err: %s\n", format, err.Error())
+		}
+
+		if settings.Domain != "example.com" {
+			t.Errorf("format %s: domain should be example.com; got %s\n", format, settings.Domain)
+		}
+
+		if settings.Server.ReadTimeout.Duration != 30*time.Second {
+			t.Errorf("format %s: read timeout should be 30s; got %s\n", format, settings.Server.ReadTimeout)
+		}
+
+		if settings.Server.IdleTimeout.Duration != time.Minute {
+			t.Errorf("format %s: idle timeout should be 1m; got %s\n", format, settings.Server.IdleTimeout)
+		}
+	}
+
+	if _, err := loadSettings([]byte(jsonConfig), LoadConfig{Format: "xml"}); err != ErrInvalidConfigFormat {
+		t.Errorf("should have returned ErrInvalidConfigFormat; got %v\n", err)
+	}
+}
+
+func TestSettingsSelect(t *testing.T) {
+	config := `
+domain: example.com
+https: true
+database_config:
+  prod:
+    host: db.example.com
+    user: app
+profiles:
+  dev:
+    https: false
+    database_config:
+      prod:
+        host: localhost
+`
+	settings, err := loadSettings([]byte(config), LoadConfig{DisableEnvOverrides: true})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dev, err := settings.Select(ProfileDev)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if dev.HTTPS || dev.Domain != "example.com" {
+		t.Errorf("dev profile not merged properly: %v\n", dev)
+	}
+
+	if dev.DatabaseConfig.Prod.Host != "localhost" || dev.DatabaseConfig.Prod.User != "app" {
+		t.Errorf("dev database not merged properly: %v\n", dev.DatabaseConfig.Prod)
+	}
+
+	if settings.DatabaseConfig.Prod.Host != "db.example.com" {
+		t.Errorf("base settings should not be modified\n")
+	}
+
+	if _, err = settings.Select(ProfileStaging); err == nil {
+		t.Errorf("should have returned error for missing profile\n")
+	}
+}
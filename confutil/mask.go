@@ -0,0 +1,111 @@
+package confutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// SecretTag is struct tag of fields of config structs whose values
+	// are secrets and are masked by Masked eg. `secret:"true"`
+	SecretTag = "secret"
+
+	// MaskedValue replaces every non empty secret within Masked
+	MaskedValue = "********"
+)
+
+// Masked returns v, usually *Settings or one of its fields, as
+// map[string]interface{} keyed by yaml names of fields, where every
+// non empty field tagged with SecretTag is replaced with MaskedValue
+// so config can be logged or served without leaking secrets
+//
+// Fields tagged `yaml:"-"` and unexported fields are excluded
+// Duration values are returned as human readable strings
+func Masked(v interface{}) interface{} {
+	return maskValue(reflect.ValueOf(v))
+}
+
+func maskValue(val reflect.Value) interface{} {
+	if !val.IsValid() {
+		return nil
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+
+		return maskValue(val.Elem())
+	case reflect.Struct:
+		if d, ok := val.Interface().(Duration); ok {
+			return d.String()
+		}
+
+		return maskStruct(val)
+	case reflect.Map:
+		if val.IsNil() {
+			return nil
+		}
+
+		m := make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+
+		for iter.Next() {
+			m[fmt.Sprintf("%v", iter.Key().Interface())] = maskValue(iter.Value())
+		}
+
+		return m
+	case reflect.Slice, reflect.Array:
+		if val.Kind() == reflect.Slice && val.IsNil() {
+			return nil
+		}
+
+		s := make([]interface{}, 0, val.Len())
+
+		for i := 0; i < val.Len(); i++ {
+			s = append(s, maskValue(val.Index(i)))
+		}
+
+		return s
+	default:
+		return val.Interface()
+	}
+}
+
+func maskStruct(val reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{}, val.NumField())
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		if field.Tag.Get(SecretTag) == "true" {
+			if !val.Field(i).IsZero() {
+				m[name] = MaskedValue
+			} else {
+				m[name] = ""
+			}
+
+			continue
+		}
+
+		m[name] = maskValue(val.Field(i))
+	}
+
+	return m
+}
@@ -0,0 +1,51 @@
+package confutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMasked(t *testing.T) {
+	settings := &Settings{
+		Domain: "example.com",
+		CSRF:   "csrfkey",
+		DatabaseConfig: DatabaseConfig{
+			Prod: &Database{Host: "db.example.com", Password: "dbpassword"},
+		},
+		Server: ServerConfig{ReadTimeout: Duration{Duration: time.Second * 30}},
+		Caches: map[string]CacheConfig{
+			"group": {Redis: &RedisCache{Address: "localhost:6379"}},
+		},
+		ActiveProfile: ProfileProd,
+	}
+
+	masked := Masked(settings).(map[string]interface{})
+
+	if masked["domain"] != "example.com" {
+		t.Errorf("should keep value; got %v", masked["domain"])
+	}
+	if masked["csrf"] != MaskedValue {
+		t.Errorf("should mask secret; got %v", masked["csrf"])
+	}
+
+	prod := masked["database_config"].(map[string]interface{})["prod"].(map[string]interface{})
+
+	if prod["host"] != "db.example.com" || prod["password"] != MaskedValue {
+		t.Errorf("should mask nested secret; got %v", prod)
+	}
+	if test := masked["database_config"].(map[string]interface{})["test"]; test != nil {
+		t.Errorf("nil pointer should be nil; got %v", test)
+	}
+	if timeout := masked["server"].(map[string]interface{})["read_timeout"]; timeout != "30s" {
+		t.Errorf("should return duration as string; got %v", timeout)
+	}
+
+	redis := masked["caches"].(map[string]interface{})["group"].(map[string]interface{})["redis"].(map[string]interface{})
+
+	if redis["address"] != "localhost:6379" || redis["password"] != "" {
+		t.Errorf("should keep empty secret empty; got %v", redis)
+	}
+	if _, ok := masked["-"]; ok {
+		t.Errorf("should exclude fields tagged yaml:\"-\"")
+	}
+}
@@ -0,0 +1,119 @@
+package confutil
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Common profile names
+const (
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+// ErrProfileNotFound is returned from Settings#Select when the
+// given profile is not within Settings#Profiles
+type ErrProfileNotFound struct {
+	Profile string
+}
+
+func (e *ErrProfileNotFound) Error() string {
+	return fmt.Sprintf("confutil: profile '%s' not found", e.Profile)
+}
+
+// Select returns a copy of the current settings with the overrides of the
+// given profile merged on top
+//
+// Only the keys set within the profile are overridden so a profile only
+// has to contain what is different from the base settings, eg.
+//
+//	domain: example.com
+//	profiles:
+//	  dev:
+//	    domain: localhost
+//	    database_config:
+//	      prod:
+//	        host: localhost
+//
+// Maps are merged key by key while every other value, including lists,
+// is replaced
+//
+// If profile is empty, a copy of the base settings is returned
+func (s *Settings) Select(profile string) (*Settings, error) {
+	var selected *Settings
+
+	base, err := settingsToRaw(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if profile != "" {
+		overrides, ok := s.Profiles[profile]
+
+		if !ok {
+			return nil, &ErrProfileNotFound{Profile: profile}
+		}
+
+		if overrides != nil {
+			mergeRaw(base, overrides)
+		}
+	}
+
+	source, err := yaml.Marshal(base)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if err = yaml.Unmarshal(source, &selected); err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	selected.Profiles = s.Profiles
+	selected.ActiveProfile = profile
+	return selected, nil
+}
+
+func settingsToRaw(s *Settings) (map[interface{}]interface{}, error) {
+	var raw map[interface{}]interface{}
+
+	// Profiles are excluded as they are never overridden
+	// by another profile
+	profiles := s.Profiles
+	s.Profiles = nil
+	source, err := yaml.Marshal(s)
+	s.Profiles = profiles
+
+	if err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if err = yaml.Unmarshal(source, &raw); err != nil {
+		return nil, errors.Wrap(err, "confutil")
+	}
+
+	if raw == nil {
+		raw = make(map[interface{}]interface{})
+	}
+
+	return raw, nil
+}
+
+// mergeRaw recursively merges src on top of dst
+func mergeRaw(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[interface{}]interface{})
+		dstMap, dstIsMap := dst[k].(map[interface{}]interface{})
+
+		if srcIsMap && dstIsMap {
+			mergeRaw(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+}
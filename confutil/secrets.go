@@ -0,0 +1,152 @@
+package confutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SecretKeySeparator separates the path of a secret from the key
+	// within the secret eg. "vault:secret/db#password"
+	SecretKeySeparator = "#"
+)
+
+// Secret reference schemes used by DefaultSecretResolvers and
+// the resolvers a user would normally register
+const (
+	EnvSecretScheme   = "env"
+	FileSecretScheme  = "file"
+	VaultSecretScheme = "vault"
+	AWSSecretScheme   = "aws"
+)
+
+var (
+	// ErrSecretNotFound is returned by a SecretResolver when the
+	// referenced secret or key within the secret does not exist
+	ErrSecretNotFound = errors.New("confutil: secret not found")
+)
+
+// SecretResolver takes a secret reference, minus the scheme, and
+// returns the value of the secret
+// eg. for the config value "vault:secret/db#password", the vault
+// resolver will receive "secret/db#password"
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc allows an ordinary function to be used as a SecretResolver
+type SecretResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref)
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// DefaultSecretResolvers returns resolvers that don't need any
+// outside client, which are the env and file resolvers
+func DefaultSecretResolvers() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		EnvSecretScheme:  EnvSecretResolver{},
+		FileSecretScheme: FileSecretResolver{},
+	}
+}
+
+// EnvSecretResolver resolves references to environment variables
+// eg. "env:DB_PASSWORD"
+type EnvSecretResolver struct{}
+
+// Resolve returns value of environment variable ref
+func (e EnvSecretResolver) Resolve(ref string) (string, error) {
+	val, ok := os.LookupEnv(ref)
+
+	if !ok {
+		return "", errors.Wrapf(ErrSecretNotFound, "env '%s'", ref)
+	}
+
+	return val, nil
+}
+
+// FileSecretResolver resolves references to files which is useful
+// for things like docker and kubernetes secrets mounted as files
+// eg. "file:/run/secrets/db_password"
+type FileSecretResolver struct {
+	// BaseDir is used for relative references
+	BaseDir string
+}
+
+// Resolve returns contents of file ref with trailing new lines trimmed
+func (f FileSecretResolver) Resolve(ref string) (string, error) {
+	if !filepath.IsAbs(ref) && f.BaseDir != "" {
+		ref = filepath.Join(f.BaseDir, ref)
+	}
+
+	source, err := ioutil.ReadFile(ref)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.Wrapf(ErrSecretNotFound, "file '%s'", ref)
+		}
+
+		return "", errors.Wrap(err, "confutil")
+	}
+
+	return strings.TrimRight(string(source), "\r\n"), nil
+}
+
+func splitSecretRef(ref string) (string, string) {
+	idx := strings.LastIndex(ref, SecretKeySeparator)
+
+	if idx < 0 {
+		return ref, ""
+	}
+
+	return ref[:idx], ref[idx+1:]
+}
+
+// resolveSecrets walks through every value within raw and replaces any
+// string starting with a registered scheme followed by ":" with the
+// result of the scheme's resolver
+func resolveSecrets(raw interface{}, resolvers map[string]SecretResolver) (interface{}, error) {
+	var err error
+
+	switch val := raw.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range val {
+			if val[k], err = resolveSecrets(v, resolvers); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, v := range val {
+			if val[i], err = resolveSecrets(v, resolvers); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		idx := strings.Index(val, ":")
+
+		if idx < 1 {
+			return val, nil
+		}
+
+		resolver, ok := resolvers[val[:idx]]
+
+		if !ok {
+			return val, nil
+		}
+
+		secret, err := resolver.Resolve(val[idx+1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return secret, nil
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,55 @@
+//go:build !noaws
+// +build !noaws
+
+package confutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/pkg/errors"
+)
+
+// AWSSecretResolver resolves references to secrets in aws secrets manager
+// eg. "aws:prod/db#password"
+//
+// If a key is given, the secret string is expected to be json and the
+// value of the key is returned, else the whole secret string is returned
+type AWSSecretResolver struct {
+	Client secretsmanageriface.SecretsManagerAPI
+}
+
+// Resolve gets the secret for ref from secrets manager
+func (a *AWSSecretResolver) Resolve(ref string) (string, error) {
+	name, key := splitSecretRef(ref)
+	output, err := a.Client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "confutil")
+	}
+
+	secretStr := aws.StringValue(output.SecretString)
+
+	if key == "" {
+		return secretStr, nil
+	}
+
+	var values map[string]interface{}
+
+	if err = json.Unmarshal([]byte(secretStr), &values); err != nil {
+		return "", errors.Wrapf(err, "confutil: aws secret '%s' is not json", name)
+	}
+
+	val, ok := values[key]
+
+	if !ok {
+		return "", errors.Wrapf(ErrSecretNotFound, "aws '%s'", ref)
+	}
+
+	return fmt.Sprintf("%v", val), nil
+}
@@ -0,0 +1,65 @@
+//go:build !novault
+// +build !novault
+
+package confutil
+
+import (
+	"fmt"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// VaultReader is used to read secrets from vault
+// *vaultapi.Logical, returned from vaultapi.Client#Logical,
+// implements this interface
+type VaultReader interface {
+	Read(path string) (*vaultapi.Secret, error)
+}
+
+// VaultSecretResolver resolves references to secrets in vault
+// eg. "vault:secret/db#password"
+//
+// Both kv version 1 and version 2 secret engines are supported
+type VaultSecretResolver struct {
+	Reader VaultReader
+}
+
+// NewVaultSecretResolver returns *VaultSecretResolver using given vault client
+func NewVaultSecretResolver(client *vaultapi.Client) *VaultSecretResolver {
+	return &VaultSecretResolver{Reader: client.Logical()}
+}
+
+// Resolve reads secret at path of ref and returns the value of the key
+func (v *VaultSecretResolver) Resolve(ref string) (string, error) {
+	path, key := splitSecretRef(ref)
+
+	if key == "" {
+		return "", fmt.Errorf("confutil: vault reference '%s' must contain key", ref)
+	}
+
+	secret, err := v.Reader.Read(path)
+
+	if err != nil {
+		return "", errors.Wrap(err, "confutil")
+	}
+
+	if secret == nil || secret.Data == nil {
+		return "", errors.Wrapf(ErrSecretNotFound, "vault '%s'", path)
+	}
+
+	data := secret.Data
+
+	// kv version 2 nests the actual values under "data"
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	val, ok := data[key]
+
+	if !ok {
+		return "", errors.Wrapf(ErrSecretNotFound, "vault '%s'", ref)
+	}
+
+	return fmt.Sprintf("%v", val), nil
+}
@@ -1,5 +1,59 @@
 package confutil
 
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that can be decoded from human readable
+// strings within a config file eg. "30s", "15m", "1h30m"
+// Plain integers are treated as seconds
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var val interface{}
+
+	if err := unmarshal(&val); err != nil {
+		return err
+	}
+
+	switch v := val.(type) {
+	case string:
+		duration, err := time.ParseDuration(v)
+
+		if err != nil {
+			return fmt.Errorf("confutil: invalid duration '%s'", v)
+		}
+
+		d.Duration = duration
+	case int:
+		d.Duration = time.Duration(v) * time.Second
+	case int64:
+		d.Duration = time.Duration(v) * time.Second
+	case float64:
+		d.Duration = time.Duration(v * float64(time.Second))
+	case nil:
+		d.Duration = 0
+	default:
+		return fmt.Errorf("confutil: invalid duration '%v'", v)
+	}
+
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON returns duration as human readable string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
 // CacheOptions is config struct for cache settings
 // type CacheOptions struct {
 // 	Address  string
@@ -20,7 +74,7 @@ type Email struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 }
 
 // RedisSession is config struct for setting up session store
@@ -29,32 +83,32 @@ type RedisSession struct {
 	Size       int    `yaml:"size"`
 	Network    string `yaml:"network"`
 	Address    string `yaml:"address"`
-	Password   string `yaml:"password"`
-	AuthKey    string `yaml:"auth_key"`
-	EncryptKey string `yaml:"encrypt_key"`
+	Password   string `yaml:"password" secret:"true"`
+	AuthKey    string `yaml:"auth_key" secret:"true"`
+	EncryptKey string `yaml:"encrypt_key" secret:"true"`
 }
 
 // RedisCache is config struct for setting up caching for
 // a redis server
 type RedisCache struct {
 	Address  string `yaml:"address"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	DB       int    `yaml:"db"`
 }
 
 // CookieStore is config struct for storing sessions
 // in cookies
 type CookieStore struct {
-	AuthKey    string `yaml:"auth_key"`
-	EncryptKey string `yaml:"encrypt_key"`
+	AuthKey    string `yaml:"auth_key" secret:"true"`
+	EncryptKey string `yaml:"encrypt_key" secret:"true"`
 }
 
 // FileSystemStore is config struct for storing sessions
 // in the file system
 type FileSystemStore struct {
 	Dir        string `yaml:"dir"`
-	AuthKey    string `yaml:"auth_key"`
-	EncryptKey string `yaml:"encrypt_key"`
+	AuthKey    string `yaml:"auth_key" secret:"true"`
+	EncryptKey string `yaml:"encrypt_key" secret:"true"`
 }
 
 // StoreConfig is overall config struct that allows user
@@ -63,19 +117,66 @@ type StoreConfig struct {
 	Redis           *RedisSession    `yaml:"redis"`
 	FileSystemStore *FileSystemStore `yaml:"file_system_store"`
 	CookieStore     *CookieStore     `yaml:"cookie_store"`
-	AuthKey         string           `yaml:"auth_key"`
-	EncryptKey      string           `yaml:"encrypt_key"`
+	AuthKey         string           `yaml:"auth_key" secret:"true"`
+	EncryptKey      string           `yaml:"encrypt_key" secret:"true"`
+
+	// SessionLifetime is how long a session is valid for
+	SessionLifetime Duration `yaml:"session_lifetime"`
 }
 
 type CacheConfig struct {
 	Redis *RedisCache `yaml:"redis"`
+
+	// DefaultTTL is default expiration used when setting cache values
+	DefaultTTL Duration `yaml:"default_ttl"`
+
+	// EncryptKey is key used to encrypt cached values if set and
+	// must be 16, 24 or 32 bytes
+	EncryptKey string `yaml:"encrypt_key" secret:"true"`
+}
+
+// MigrationConfig is config struct for running database
+// migrations when the app starts
+type MigrationConfig struct {
+	// Dir is directory that contains migration files
+	Dir string `yaml:"dir"`
+
+	// Table is table used to store applied migrations
+	Table string `yaml:"table"`
+
+	// RunOnBoot determines whether pending migrations are
+	// applied when the app starts
+	RunOnBoot bool `yaml:"run_on_boot"`
+}
+
+// ServerConfig is config struct for setting up the http server
+type ServerConfig struct {
+	Address         string   `yaml:"address"`
+	ReadTimeout     Duration `yaml:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout"`
+}
+
+// SentryConfig is config struct to send errors to sentry
+type SentryConfig struct {
+	// DSN is data source name of sentry project
+	// Errors are not sent if empty
+	DSN         string `yaml:"dsn" secret:"true"`
+	Environment string `yaml:"environment"`
+	Release     string `yaml:"release"`
 }
 
 // Stripe is config struct to set up stripe in app
 type Stripe struct {
 	TestMode            bool   `yaml:"test_mode"`
-	StripeTestSecretKey string `yaml:"stripe_test_secret_key"`
-	StripeLiveSecretKey string `yaml:"stripe_live_secret_key"`
+	StripeTestSecretKey string `yaml:"stripe_test_secret_key" secret:"true"`
+	StripeLiveSecretKey string `yaml:"stripe_live_secret_key" secret:"true"`
+
+	// StripeTestWebhookSecret and StripeLiveWebhookSecret are signing
+	// secrets of webhook endpoints used to verify events
+	StripeTestWebhookSecret string `yaml:"stripe_test_webhook_secret" secret:"true"`
+	StripeLiveWebhookSecret string `yaml:"stripe_live_webhook_secret" secret:"true"`
 }
 
 // DatabaseConfig is overall config struct to set up
@@ -90,10 +191,47 @@ type DatabaseConfig struct {
 type Database struct {
 	DBName   string `yaml:"db_name"`
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	Host     string `yaml:"host"`
 	Port     string `yaml:"port"`
 	SSLMode  string `yaml:"ssl_mode"`
+
+	// Dialect is type of database eg. "postgres", "mysql" or "sqlite3",
+	// which Validate uses to check the fields its data source needs
+	// Sqlite only needs DBName, the path of the database file
+	// Default is "postgres"
+	Dialect string `yaml:"dialect"`
+
+	// MaxOpenConns is max number of open connections to database
+	// Default is 0 which is unlimited
+	MaxOpenConns int `yaml:"max_open_conns"`
+
+	// MaxIdleConns is max number of idle connections kept in pool
+	// Default is 0 which uses database/sql default of 2
+	MaxIdleConns int `yaml:"max_idle_conns"`
+
+	// ConnMaxLifetime is max amount of time a connection may be reused
+	// Default is 0 which reuses connections forever
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime"`
+
+	// ConnMaxIdleTime is max amount of time a connection may be idle
+	// Default is 0 which keeps idle connections forever
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time"`
+
+	// StatementTimeout is max amount of time a statement may run before
+	// the database cancels it, which for mysql only applies to selects
+	// Default is 0 which uses the setting of the database
+	StatementTimeout Duration `yaml:"statement_timeout"`
+
+	// LockTimeout is max amount of time a statement may wait for a lock
+	// Mysql only supports whole seconds so it's rounded up
+	// Default is 0 which uses the setting of the database
+	LockTimeout Duration `yaml:"lock_timeout"`
+
+	// ApplicationName is name postgres connections report as
+	// application_name, eg. within pg_stat_activity, so queries can be
+	// attributed to the app
+	ApplicationName string `yaml:"application_name"`
 }
 
 // type S3Config struct {
@@ -101,13 +239,18 @@ type Database struct {
 // 	Buckets map[string]*S3Storage `yaml:"buckets"`
 // }
 
+// S3Config is map of name to storage endpoint and bucket
 type S3Config map[string]S3Storage
 
 type S3Storage struct {
 	EndPoint        string `yaml:"end_point"`
 	AccessKeyID     string `yaml:"access_key_id"`
-	SecretAccessKey string `yaml:"secret_access_key"`
+	SecretAccessKey string `yaml:"secret_access_key" secret:"true"`
 	UseSSL          bool   `yaml:"use_ssl"`
+
+	// Bucket is name of bucket used by this storage
+	// Default is the name of the storage within S3Config
+	Bucket string `yaml:"bucket"`
 }
 
 // Settings is the configuration settings for the app
@@ -115,21 +258,32 @@ type Settings struct {
 	Prod bool `yaml:"prod"`
 	// AuthKey        string          `yaml:"auth_key"`
 	// EncryptKey     string          `yaml:"encrypt_key"`
-	Domain         string         `yaml:"domain"`
-	ClientDomain   string         `yaml:"client_domain"`
-	CSRF           string         `yaml:"csrf"`
-	TemplatesDir   string         `yaml:"templates_dir"`
-	HTTPS          bool           `yaml:"https"`
-	AssetsLocation string         `yaml:"assets_location"`
-	AllowedOrigins []string       `yaml:"allowed_origins"`
-	EmailConfig    EmailConfig    `yaml:"email_config"`
-	Store          StoreConfig    `yaml:"store"`
-	Cache          CacheConfig    `yaml:"cache"`
-	DatabaseConfig DatabaseConfig `yaml:"database_config"`
-	Stripe         Stripe         `yaml:"stripe"`
-	S3Config       S3Config       `yaml:"s3_config"`
-
-	Databases map[string][]Database `yaml:"databases"`
-	Emails    map[string]Email      `yaml:"emails"`
-	StripeMap map[string]Stripe     `yaml:"stripe_map"`
+	Domain         string          `yaml:"domain"`
+	ClientDomain   string          `yaml:"client_domain"`
+	CSRF           string          `yaml:"csrf" secret:"true"`
+	TemplatesDir   string          `yaml:"templates_dir"`
+	HTTPS          bool            `yaml:"https"`
+	AssetsLocation string          `yaml:"assets_location"`
+	AllowedOrigins []string        `yaml:"allowed_origins"`
+	EmailConfig    EmailConfig     `yaml:"email_config"`
+	Store          StoreConfig     `yaml:"store"`
+	Cache          CacheConfig     `yaml:"cache"`
+	DatabaseConfig DatabaseConfig  `yaml:"database_config"`
+	Stripe         Stripe          `yaml:"stripe"`
+	S3Config       S3Config        `yaml:"s3_config"`
+	Server         ServerConfig    `yaml:"server"`
+	Migrations     MigrationConfig `yaml:"migrations"`
+	Sentry         SentryConfig    `yaml:"sentry"`
+
+	Databases map[string][]Database  `yaml:"databases"`
+	Emails    map[string]Email       `yaml:"emails"`
+	StripeMap map[string]Stripe      `yaml:"stripe_map"`
+	Caches    map[string]CacheConfig `yaml:"caches"`
+
+	// Profiles is map of profile name to overrides of these settings
+	// See Settings#Select
+	Profiles map[string]map[interface{}]interface{} `yaml:"profiles" secret:"true"`
+
+	// ActiveProfile is the profile that was selected, if any
+	ActiveProfile string `yaml:"-"`
 }
@@ -0,0 +1,240 @@
+package confutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// CSRFKeyLength is the length the csrf key must be as
+	// gorilla/csrf requires a 32 byte key
+	CSRFKeyLength = 32
+
+	// sqliteDialect is Database#Dialect of sqlite, the same as dbutil#Sqlite
+	sqliteDialect = "sqlite3"
+)
+
+var (
+	// validEncryptKeyLengths are key lengths of AES-128, AES-192 and AES-256
+	validEncryptKeyLengths = map[int]bool{16: true, 24: true, 32: true}
+
+	validSSLModes = map[string]bool{
+		"":            true,
+		"disable":     true,
+		"allow":       true,
+		"prefer":      true,
+		"require":     true,
+		"verify-ca":   true,
+		"verify-full": true,
+	}
+)
+
+// SettingsErrors is returned from Validate and contains every
+// problem found with the settings
+type SettingsErrors []string
+
+func (s SettingsErrors) Error() string {
+	return "confutil: invalid settings:\n - " + strings.Join(s, "\n - ")
+}
+
+func (s *SettingsErrors) add(format string, args ...interface{}) {
+	*s = append(*s, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the cross field requirements of settings, such as a redis
+// store having an address or the csrf key being the right length, and returns
+// SettingsErrors listing every problem found, else nil
+//
+// Validate should be called at startup so misconfiguration fails fast
+// instead of at the first request that needs the misconfigured setting
+func Validate(settings *Settings) error {
+	var errs SettingsErrors
+
+	if settings == nil {
+		errs.add("settings can't be nil")
+		return errs
+	}
+
+	if settings.CSRF != "" && len(settings.CSRF) != CSRFKeyLength {
+		errs.add("csrf: key must be %d bytes; got %d", CSRFKeyLength, len(settings.CSRF))
+	}
+
+	validateStore(settings.Store, &errs)
+
+	validateCache("cache", settings.Cache, &errs)
+
+	for k, v := range settings.Caches {
+		if v.Redis == nil {
+			errs.add("caches.%s.redis: required", k)
+		}
+
+		validateCache("caches."+k, v, &errs)
+	}
+
+	for k, v := range settings.S3Config {
+		if v.EndPoint == "" {
+			errs.add("s3_config.%s.end_point: required", k)
+		}
+	}
+
+	validateDatabases(settings, &errs)
+
+	if settings.EmailConfig.TestMode {
+		if settings.EmailConfig.TestEmail == nil {
+			errs.add("email_config.test_email: required when email_config.test_mode is true")
+		} else {
+			validateEmail("email_config.test_email", *settings.EmailConfig.TestEmail, &errs)
+		}
+	} else if settings.EmailConfig.LiveEmail != nil {
+		validateEmail("email_config.live_email", *settings.EmailConfig.LiveEmail, &errs)
+	}
+
+	for k, v := range settings.Emails {
+		validateEmail("emails."+k, v, &errs)
+	}
+
+	if settings.Stripe.TestMode && settings.Stripe.StripeTestSecretKey == "" {
+		errs.add("stripe.stripe_test_secret_key: required when stripe.test_mode is true")
+	}
+
+	server := settings.Server
+	timeoutNames := []string{"read_timeout", "write_timeout", "idle_timeout", "shutdown_timeout"}
+
+	for i, d := range []Duration{
+		server.ReadTimeout,
+		server.WriteTimeout,
+		server.IdleTimeout,
+		server.ShutdownTimeout,
+	} {
+		if d.Duration < 0 {
+			errs.add("server.%s: can't be negative", timeoutNames[i])
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateStore(store StoreConfig, errs *SettingsErrors) {
+	if store.Redis == nil && store.FileSystemStore == nil && store.CookieStore == nil {
+		errs.add("store: one of redis, file_system_store or cookie_store must be set")
+	}
+
+	if store.Redis != nil {
+		if store.Redis.Address == "" {
+			errs.add("store.redis.address: required when store.redis is set")
+		}
+		if store.Redis.Size <= 0 {
+			errs.add("store.redis.size: must be greater than 0")
+		}
+		if store.Redis.AuthKey == "" {
+			errs.add("store.redis.auth_key: required when store.redis is set")
+		}
+	}
+
+	if store.FileSystemStore != nil && store.FileSystemStore.AuthKey == "" {
+		errs.add("store.file_system_store.auth_key: required when store.file_system_store is set")
+	}
+
+	if store.CookieStore != nil && store.CookieStore.AuthKey == "" {
+		errs.add("store.cookie_store.auth_key: required when store.cookie_store is set")
+	}
+
+	if store.SessionLifetime.Duration < 0 {
+		errs.add("store.session_lifetime: can't be negative")
+	}
+}
+
+func validateCache(prefix string, cache CacheConfig, errs *SettingsErrors) {
+	if cache.Redis != nil && cache.Redis.Address == "" {
+		errs.add("%s.redis.address: required when %s.redis is set", prefix, prefix)
+	}
+
+	if cache.DefaultTTL.Duration < 0 {
+		errs.add("%s.default_ttl: can't be negative", prefix)
+	}
+
+	if key := cache.EncryptKey; key != "" && !validEncryptKeyLengths[len(key)] {
+		errs.add("%s.encrypt_key: key must be 16, 24 or 32 bytes; got %d", prefix, len(key))
+	}
+}
+
+func validateDatabases(settings *Settings, errs *SettingsErrors) {
+	dbConfig := settings.DatabaseConfig
+	hasDB := dbConfig.Prod != nil || dbConfig.Test != nil
+
+	for _, v := range settings.Databases {
+		if len(v) > 0 {
+			hasDB = true
+			break
+		}
+	}
+
+	if !hasDB {
+		errs.add("database_config: at least one database must be configured")
+		return
+	}
+
+	if dbConfig.TestMode && dbConfig.Test == nil {
+		errs.add("database_config.test: required when database_config.test_mode is true")
+	}
+
+	if dbConfig.Prod != nil {
+		validateDatabase("database_config.prod", *dbConfig.Prod, errs)
+	}
+
+	if dbConfig.Test != nil {
+		validateDatabase("database_config.test", *dbConfig.Test, errs)
+	}
+
+	for k, list := range settings.Databases {
+		for i, v := range list {
+			validateDatabase(fmt.Sprintf("databases.%s[%d]", k, i), v, errs)
+		}
+	}
+}
+
+func validateDatabase(prefix string, db Database, errs *SettingsErrors) {
+	// Sqlite databases are files so there's no host or user
+	isSqlite := db.Dialect == sqliteDialect
+
+	if db.Host == "" && !isSqlite {
+		errs.add("%s.host: required", prefix)
+	}
+	if db.DBName == "" {
+		errs.add("%s.db_name: required", prefix)
+	}
+	if db.User == "" && !isSqlite {
+		errs.add("%s.user: required", prefix)
+	}
+	if !validSSLModes[db.SSLMode] {
+		errs.add("%s.ssl_mode: invalid value '%s'", prefix, db.SSLMode)
+	}
+	if db.MaxOpenConns < 0 {
+		errs.add("%s.max_open_conns: can't be negative", prefix)
+	}
+	if db.MaxIdleConns < 0 {
+		errs.add("%s.max_idle_conns: can't be negative", prefix)
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		errs.add("%s.max_idle_conns: can't be greater than max_open_conns", prefix)
+	}
+	if db.ConnMaxLifetime.Duration < 0 {
+		errs.add("%s.conn_max_lifetime: can't be negative", prefix)
+	}
+	if db.ConnMaxIdleTime.Duration < 0 {
+		errs.add("%s.conn_max_idle_time: can't be negative", prefix)
+	}
+}
+
+func validateEmail(prefix string, email Email, errs *SettingsErrors) {
+	if email.Host == "" {
+		errs.add("%s.host: required", prefix)
+	}
+	if email.Port <= 0 {
+		errs.add("%s.port: must be greater than 0", prefix)
+	}
+}
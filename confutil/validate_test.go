@@ -0,0 +1,111 @@
+package confutil
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	settings := &Settings{
+		CSRF: "tooshort",
+		Store: StoreConfig{
+			Redis: &RedisSession{Size: 10},
+		},
+		Cache: CacheConfig{
+			Redis:      &RedisCache{},
+			EncryptKey: "tooshort",
+		},
+	}
+
+	err := Validate(settings)
+	errs, ok := err.(SettingsErrors)
+
+	if !ok {
+		t.Fatalf("should have returned SettingsErrors; got %v\n", err)
+	}
+
+	// csrf, store address, store auth key, cache address,
+	// cache encrypt key, no database
+	if len(errs) != 6 {
+		t.Errorf("should have 6 errors; got %d: %s\n", len(errs), errs.Error())
+	}
+
+	settings = &Settings{
+		CSRF: "12345678901234567890123456789012",
+		Store: StoreConfig{
+			CookieStore: &CookieStore{AuthKey: "key"},
+		},
+		DatabaseConfig: DatabaseConfig{
+			Prod: &Database{
+				Host:    "localhost",
+				DBName:  "app",
+				User:    "app",
+				SSLMode: "disable",
+			},
+		},
+	}
+
+	if err = Validate(settings); err != nil {
+		t.Errorf("should be valid; got %s\n", err.Error())
+	}
+
+	settings.DatabaseConfig.Prod.MaxOpenConns = 5
+	settings.DatabaseConfig.Prod.MaxIdleConns = 10
+	settings.DatabaseConfig.Prod.ConnMaxLifetime.Duration = -1
+
+	err = Validate(settings)
+	errs, ok = err.(SettingsErrors)
+
+	if !ok {
+		t.Fatalf("should have returned SettingsErrors; got %v\n", err)
+	}
+
+	// max idle conns greater than max open conns, negative lifetime
+	if len(errs) != 2 {
+		t.Errorf("should have 2 errors; got %d: %s\n", len(errs), errs.Error())
+	}
+
+	settings.DatabaseConfig.Prod.MaxIdleConns = 0
+	settings.DatabaseConfig.Prod.ConnMaxLifetime.Duration = 0
+	settings.Caches = map[string]CacheConfig{
+		"sessions": {Redis: &RedisCache{Address: "localhost:6379"}},
+		"queue":    {EncryptKey: "tooshort"},
+	}
+	settings.S3Config = S3Config{
+		"uploads": {Bucket: "uploads"},
+	}
+
+	err = Validate(settings)
+	errs, ok = err.(SettingsErrors)
+
+	if !ok {
+		t.Fatalf("should have returned SettingsErrors; got %v\n", err)
+	}
+
+	// queue redis, queue encrypt key, uploads end point
+	if len(errs) != 3 {
+		t.Errorf("should have 3 errors; got %d: %s\n", len(errs), errs.Error())
+	}
+
+	settings.Caches = nil
+	settings.S3Config = nil
+	settings.DatabaseConfig.Prod = &Database{Dialect: "sqlite3", DBName: "app.db"}
+
+	// Sqlite only needs db name
+	if err = Validate(settings); err != nil {
+		t.Errorf("sqlite should be valid; got %s\n", err.Error())
+	}
+
+	settings.DatabaseConfig.Prod.DBName = ""
+
+	err = Validate(settings)
+	errs, ok = err.(SettingsErrors)
+
+	if !ok {
+		t.Fatalf("should have returned SettingsErrors; got %v\n", err)
+	}
+
+	// sqlite db name
+	if len(errs) != 1 {
+		t.Errorf("should have 1 error; got %d: %s\n", len(errs), errs.Error())
+	}
+}
@@ -0,0 +1,156 @@
+package ctxutil
+
+import (
+	"context"
+)
+
+// Key is type of every context key set by httputil packages, which
+// can't collide with keys of other packages as the type is unexported
+// to them
+// Values should be set and read with the typed functions of this
+// package instead of context.WithValue so reading them never panics
+type Key struct {
+	KeyName string
+}
+
+var (
+	// UserJSONKey is key of json of user set by apiutil#AuthHandler
+	UserJSONKey = Key{KeyName: "user"}
+
+	// UserKey is key of User set by apiutil#AuthHandler
+	UserKey = Key{KeyName: "middlewareUser"}
+
+	// GroupsKey is key of groups of user set by apiutil#GroupHandler
+	GroupsKey = Key{KeyName: "groupName"}
+
+	// ImpersonatorKey is key of real user of request while impersonating
+	// another user, see apiutil#ImpersonationHandler
+	ImpersonatorKey = Key{KeyName: "impersonator"}
+
+	// RequestIDKey is key of id of request used to trace it across
+	// services
+	RequestIDKey = Key{KeyName: "requestID"}
+
+	// ClientIPKey is key of ip of client set by apiutil#ClientIPHandler
+	ClientIPKey = Key{KeyName: "clientIP"}
+
+	// LanguageKey is key of language of request set by
+	// apiutil#LanguageHandler
+	LanguageKey = Key{KeyName: "language"}
+)
+
+// User is user of request as set by authentication middleware
+type User struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// WithUser returns ctx with user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, UserKey, user)
+}
+
+// UserFrom returns user of ctx set by WithUser, or false if ctx doesn't
+// have one
+func UserFrom(ctx context.Context) (User, bool) {
+	return userFrom(ctx, UserKey)
+}
+
+// WithUserJSON returns ctx with json of user, as stored in session
+func WithUserJSON(ctx context.Context, user []byte) context.Context {
+	return context.WithValue(ctx, UserJSONKey, user)
+}
+
+// UserJSONFrom returns json of user of ctx set by WithUserJSON, else nil
+func UserJSONFrom(ctx context.Context) []byte {
+	user, _ := ctx.Value(UserJSONKey).([]byte)
+	return user
+}
+
+// WithGroups returns ctx with groups of user, where groups are keys
+// of map whose value is true
+func WithGroups(ctx context.Context, groups map[string]bool) context.Context {
+	return context.WithValue(ctx, GroupsKey, groups)
+}
+
+// GroupsFrom returns groups of user of ctx set by WithGroups, else nil
+// Groups set as []string, as done by older middleware, are returned
+// as map too
+func GroupsFrom(ctx context.Context) map[string]bool {
+	switch groups := ctx.Value(GroupsKey).(type) {
+	case map[string]bool:
+		return groups
+	case []string:
+		groupMap := make(map[string]bool, len(groups))
+
+		for _, v := range groups {
+			groupMap[v] = true
+		}
+
+		return groupMap
+	}
+
+	return nil
+}
+
+// WithImpersonator returns ctx with real user of request while it's
+// impersonating another user
+func WithImpersonator(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, ImpersonatorKey, user)
+}
+
+// ImpersonatorFrom returns user of ctx set by WithImpersonator, or
+// false if request isn't impersonating
+func ImpersonatorFrom(ctx context.Context) (User, bool) {
+	return userFrom(ctx, ImpersonatorKey)
+}
+
+// WithRequestID returns ctx with id of request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, id)
+}
+
+// RequestIDFrom returns id of request of ctx set by WithRequestID, else
+// empty string
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// WithClientIP returns ctx with ip of client
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ClientIPKey, ip)
+}
+
+// ClientIPFrom returns ip of client of ctx set by WithClientIP, else
+// empty string
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
+
+// WithLanguage returns ctx with language of request
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, LanguageKey, lang)
+}
+
+// LanguageFrom returns language of ctx set by WithLanguage, else empty
+// string
+func LanguageFrom(ctx context.Context) string {
+	lang, _ := ctx.Value(LanguageKey).(string)
+	return lang
+}
+
+// userFrom returns user of key, which may also be set as *User
+func userFrom(ctx context.Context, key Key) (User, bool) {
+	switch user := ctx.Value(key).(type) {
+	case User:
+		return user, true
+	case *User:
+		if user != nil {
+			return *user, true
+		}
+	}
+
+	return User{}, false
+}
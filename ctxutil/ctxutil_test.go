@@ -0,0 +1,72 @@
+package ctxutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserFrom(t *testing.T) {
+	if _, ok := UserFrom(context.Background()); ok {
+		t.Errorf("should not return user of empty context")
+	}
+
+	ctx := WithUser(context.Background(), User{ID: "1", Email: "user@test.com"})
+
+	if user, ok := UserFrom(ctx); !ok || user.ID != "1" {
+		t.Errorf("should return user; got %+v", user)
+	}
+
+	ctx = context.WithValue(context.Background(), UserKey, &User{ID: "2"})
+
+	if user, ok := UserFrom(ctx); !ok || user.ID != "2" {
+		t.Errorf("should return user set as pointer; got %+v", user)
+	}
+
+	ctx = context.WithValue(context.Background(), UserKey, "invalid")
+
+	if _, ok := UserFrom(ctx); ok {
+		t.Errorf("should not return user of invalid type")
+	}
+
+	if _, ok := ImpersonatorFrom(ctx); ok {
+		t.Errorf("should not return impersonator")
+	}
+}
+
+func TestGroupsFrom(t *testing.T) {
+	if groups := GroupsFrom(context.Background()); groups != nil {
+		t.Errorf("should return nil groups; got %v", groups)
+	}
+
+	ctx := WithGroups(context.Background(), map[string]bool{"Admin": true})
+
+	if groups := GroupsFrom(ctx); !groups["Admin"] {
+		t.Errorf("should return groups; got %v", groups)
+	}
+
+	ctx = context.WithValue(context.Background(), GroupsKey, []string{"Admin", "Staff"})
+
+	if groups := GroupsFrom(ctx); len(groups) != 2 || !groups["Staff"] {
+		t.Errorf("should return groups set as slice; got %v", groups)
+	}
+}
+
+func TestStringValues(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "id")
+	ctx = WithClientIP(ctx, "1.2.3.4")
+	ctx = WithLanguage(ctx, "pt-BR")
+	ctx = WithUserJSON(ctx, []byte(`{"id":"1"}`))
+
+	if RequestIDFrom(ctx) != "id" || ClientIPFrom(ctx) != "1.2.3.4" || LanguageFrom(ctx) != "pt-BR" {
+		t.Errorf("should return values of context")
+	}
+	if string(UserJSONFrom(ctx)) != `{"id":"1"}` {
+		t.Errorf("should return json of user; got %s", UserJSONFrom(ctx))
+	}
+
+	ctx = context.WithValue(context.Background(), RequestIDKey, 1)
+
+	if RequestIDFrom(ctx) != "" || UserJSONFrom(ctx) != nil {
+		t.Errorf("should return empty values of invalid types")
+	}
+}
@@ -0,0 +1,199 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+// ContextDB is implemented by databases returned from WithContext
+type ContextDB interface {
+	Context() context.Context
+}
+
+// WithContext returns db bound to ctx, where every query, exec and
+// transaction uses ctx if db supports contexts like *DB, so they are
+// cancelled in the database once ctx is done, eg. once request times out
+//
+// QueryWithTimeout also uses ctx as parent of its timeout
+// If ctx is from WithQueryLog, queries are recorded to its *QueryLog
+// If ctx is from WithQueryTag, queries are tagged with its tag
+func WithContext(ctx context.Context, db httputil.DBInterfaceV2) httputil.DBInterfaceV2 {
+	if c, ok := db.(*contextDB); ok {
+		db = c.DBInterfaceV2
+	}
+
+	return &contextDB{DBInterfaceV2: db, ctx: ctx}
+}
+
+type contextDB struct {
+	httputil.DBInterfaceV2
+	ctx context.Context
+}
+
+func (c *contextDB) Context() context.Context {
+	return c.ctx
+}
+
+func (c *contextDB) QueryTimeout() time.Duration {
+	if t, ok := c.DBInterfaceV2.(QueryTimeouter); ok {
+		return t.QueryTimeout()
+	}
+
+	return 0
+}
+
+func (c *contextDB) BindVar() int {
+	return BindVarOf(c.DBInterfaceV2)
+}
+
+func (c *contextDB) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	return c.QueryContext(c.ctx, query, args...)
+}
+
+func (c *contextDB) QueryContext(ctx context.Context, query string, args ...interface{}) (httputil.Rower, error) {
+	defer observeQuery(ctx, time.Now(), query, args)
+	tagged := tagQuery(ctx, query)
+
+	if db, ok := c.DBInterfaceV2.(httputil.QuerierContext); ok {
+		return db.QueryContext(ctx, tagged, args...)
+	}
+
+	return c.DBInterfaceV2.Query(tagged, args...)
+}
+
+func (c *contextDB) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	defer observeQuery(c.ctx, time.Now(), query, args)
+	query = tagQuery(c.ctx, query)
+
+	if db, ok := c.DBInterfaceV2.(interface {
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) httputil.Scanner
+	}); ok {
+		return db.QueryRowContext(c.ctx, query, args...)
+	}
+
+	return c.DBInterfaceV2.QueryRow(query, args...)
+}
+
+func (c *contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(c.ctx, time.Now(), query, args)
+	query = tagQuery(c.ctx, query)
+
+	if db, ok := c.DBInterfaceV2.(interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	}); ok {
+		return db.ExecContext(c.ctx, query, args...)
+	}
+
+	return c.DBInterfaceV2.Exec(query, args...)
+}
+
+func (c *contextDB) Get(dest interface{}, query string, args ...interface{}) error {
+	defer observeQuery(c.ctx, time.Now(), query, args)
+	query = tagQuery(c.ctx, query)
+
+	if db, ok := c.DBInterfaceV2.(interface {
+		GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	}); ok {
+		return db.GetContext(c.ctx, dest, query, args...)
+	}
+
+	return c.DBInterfaceV2.Get(dest, query, args...)
+}
+
+func (c *contextDB) Select(dest interface{}, query string, args ...interface{}) error {
+	defer observeQuery(c.ctx, time.Now(), query, args)
+	query = tagQuery(c.ctx, query)
+
+	if db, ok := c.DBInterfaceV2.(interface {
+		SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	}); ok {
+		return db.SelectContext(c.ctx, dest, query, args...)
+	}
+
+	return c.DBInterfaceV2.Select(dest, query, args...)
+}
+
+func (c *contextDB) Begin() (httputil.Tx, error) {
+	var tx httputil.Tx
+	var err error
+
+	if db, ok := c.DBInterfaceV2.(interface {
+		BeginContext(ctx context.Context) (httputil.Tx, error)
+	}); ok {
+		tx, err = db.BeginContext(c.ctx)
+	} else {
+		tx, err = c.DBInterfaceV2.Begin()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tagTx(c.ctx, c.DBInterfaceV2, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// BeginContext is the same as DB#Begin but transaction is rolled back
+// once ctx is done
+func (db *DB) BeginContext(ctx context.Context) (httputil.Tx, error) {
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.DB.BeginTxx(ctx, nil)
+
+	if err != nil {
+		db.queries.release()
+		return nil, err
+	}
+
+	customTx := NewCustomTx(tx)
+	customTx.release = db.queries.release
+	return customTx, nil
+}
+
+// QueryRowContext is wrapper for sqlx.DB.QueryRowContext
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) httputil.Scanner {
+	if err := db.queries.acquire(); err != nil {
+		return errScanner{err: err}
+	}
+
+	return &trackedRow{Row: db.DB.QueryRowContext(ctx, query, args...), release: db.queries.release}
+}
+
+// ExecContext is wrapper for sqlx.DB.ExecContext
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	defer db.queries.release()
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// GetContext is wrapper for sqlx.DB.GetContext
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := db.queries.acquire(); err != nil {
+		return err
+	}
+
+	defer db.queries.release()
+	return db.DB.GetContext(ctx, dest, query, args...)
+}
+
+// SelectContext is wrapper for sqlx.DB.SelectContext
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := db.queries.acquire(); err != nil {
+		return err
+	}
+
+	defer db.queries.release()
+	return db.DB.SelectContext(ctx, dest, query, args...)
+}
@@ -1,10 +1,12 @@
 package dbutil
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/TravisS25/httputil/confutil"
 
@@ -28,6 +30,7 @@ const (
 const (
 	Postgres = "postgres"
 	Mysql    = "mysql"
+	Sqlite   = "sqlite3"
 )
 
 const (
@@ -41,6 +44,21 @@ var (
 
 //------------------------ INTERFACES ---------------------------
 
+// SqlxExt is implemented by *DB and *CustomTx for sqlx functions that
+// depend on the driver of the connection
+type SqlxExt interface {
+	Preparex(query string) (*sqlx.Stmt, error)
+	Rebind(query string) string
+	DriverName() string
+}
+
+// ExtDB is httputil.DBInterface extended with SqlxExt so the driver, and
+// with it the bind var, of a connection can be retrieved
+type ExtDB interface {
+	httputil.DBInterface
+	SqlxExt
+}
+
 // type CustomMarshalJSON interface {
 // 	SetExclusionJSONFields(fields map[string]bool)
 // }
@@ -78,6 +96,11 @@ func NewCustomTx(tx *sqlx.Tx) *CustomTx {
 // CustomTx is struct that extends off of sql.Tx
 type CustomTx struct {
 	tx *sqlx.Tx
+
+	// release is called once transaction is committed or rolled back
+	// when started from *DB so DB#Drain can wait for it
+	release func()
+	once    sync.Once
 }
 
 // QueryRow is wrapper for sql.QueryRow with custom return of httputil.Scanner
@@ -97,14 +120,22 @@ func (c *CustomTx) Exec(query string, args ...interface{}) (sql.Result, error) {
 
 // Commit is wrapper for sql.Tx.Commit
 func (c *CustomTx) Commit() error {
+	defer c.done()
 	return c.tx.Commit()
 }
 
 // Rollback is wrapper for sql.Tx.Rollback
 func (c *CustomTx) Rollback() error {
+	defer c.done()
 	return c.tx.Rollback()
 }
 
+func (c *CustomTx) done() {
+	if c.release != nil {
+		c.once.Do(c.release)
+	}
+}
+
 // Get is wrapper for sqlx.Get
 func (c *CustomTx) Get(dest interface{}, query string, args ...interface{}) error {
 	return c.tx.Get(dest, query, args...)
@@ -115,19 +146,51 @@ func (c *CustomTx) Select(dest interface{}, query string, args ...interface{}) e
 	return c.tx.Select(dest, query, args...)
 }
 
+// Preparex is wrapper for sqlx.Tx.Preparex
+func (c *CustomTx) Preparex(query string) (*sqlx.Stmt, error) {
+	return c.tx.Preparex(query)
+}
+
+// Rebind is wrapper for sqlx.Tx.Rebind
+func (c *CustomTx) Rebind(query string) string {
+	return c.tx.Rebind(query)
+}
+
+// DriverName is wrapper for sqlx.Tx.DriverName
+func (c *CustomTx) DriverName() string {
+	return c.tx.DriverName()
+}
+
 // DB extends sqlx.DB with some extra functions
 type DB struct {
 	*sqlx.DB
 	dbConfigList  []confutil.Database
 	currentConfig confutil.Database
 	dbType        string
+	appName       string
+	queryTimeout  time.Duration
+	queries       inflight
 	//mu            sync.Mutex
 }
 
 // Begin is wrapper for sqlx.DB.Begin
+// Transaction is in progress for DB#Drain until it is committed or
+// rolled back
 func (db *DB) Begin() (httputil.Tx, error) {
-	tx, _ := db.DB.Beginx()
-	return NewCustomTx(tx), nil
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.DB.Beginx()
+
+	if err != nil {
+		db.queries.release()
+		return nil, err
+	}
+
+	customTx := NewCustomTx(tx)
+	customTx.release = db.queries.release
+	return customTx, nil
 }
 
 // Commit is wrapper for sqlx.Tx.Commit
@@ -137,12 +200,20 @@ func (db *DB) Commit(tx httputil.Tx) error {
 
 // QueryRow is wrapper for sqlx.DB.QueryRow
 func (db *DB) QueryRow(query string, args ...interface{}) httputil.Scanner {
-	return db.DB.QueryRow(query, args...)
+	if err := db.queries.acquire(); err != nil {
+		return errScanner{err: err}
+	}
+
+	return &trackedRow{Row: db.DB.QueryRow(query, args...), release: db.queries.release}
 }
 
 // Query is wrapper for sqlx.DB.Query
 func (db *DB) Query(query string, args ...interface{}) (httputil.Rower, error) {
-	return db.DB.Query(query, args...)
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	return db.trackRows(db.DB.Query(query, args...))
 }
 
 // // RecoverError will check if given err is not nil and if it is
@@ -207,6 +278,8 @@ func (db *DB) Query(query string, args ...interface{}) (httputil.Rower, error) {
 // This function does not check what type of err is passed, just checks
 // if err is nil or not so it's up to user to use appropriately; however
 // we do a quick ping check just to make sure db is truely down
+// Failed connections are sent to httputil#Reporter and recorded for
+// RecentFailovers
 //
 // This function is NOT thread safe so one should create a mutex around
 // this function when trying to recover from error
@@ -215,21 +288,24 @@ func (db *DB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
 		// db.mu.Lock()
 		// defer db.mu.Unlock()
 
-		dbInfo := fmt.Sprintf(
-			DBConnStr,
-			db.currentConfig.Host,
-			db.currentConfig.User,
-			db.currentConfig.Password,
-			db.currentConfig.DBName,
-			db.currentConfig.Port,
-			db.currentConfig.SSLMode,
-		)
+		dbInfo, err := DSN(db.currentConfig, db.dbType)
 
-		_, err = db.Driver().Open(dbInfo)
+		if err == nil {
+			_, err = db.Driver().Open(dbInfo)
+		}
 
 		if err != nil {
-			fmt.Printf("connection officially failed\n")
+			httputil.CaptureError(context.Background(), err, map[string]string{"source": "db_failover"})
+
+			event := FailoverEvent{
+				Time:   time.Now(),
+				DBType: db.dbType,
+				From:   db.currentConfig.Host,
+			}
+
 			if len(db.dbConfigList) == 0 {
+				event.Err = ErrEmptyConfigList.Error()
+				recordFailover(event)
 				return nil, ErrEmptyConfigList
 			}
 
@@ -237,9 +313,14 @@ func (db *DB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
 			newDB, err := NewDBWithList(db.dbConfigList, db.dbType)
 
 			if err != nil {
+				event.Err = ErrNoConnection.Error()
+				recordFailover(event)
+				httputil.CaptureError(context.Background(), ErrNoConnection, map[string]string{"source": "db_failover"})
 				return nil, ErrNoConnection
 			}
 
+			event.To = newDB.currentConfig.Host
+			recordFailover(event)
 			return newDB, err
 		}
 
@@ -251,44 +332,72 @@ func (db *DB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
 //----------------------------- FUNCTIONS -------------------------------------
 
 // NewDB is function that returns *DB with given DB config
+// dbType should be Postgres, Mysql or Sqlite and the data source
+// name is built from dbConfig with DSN
+// Connection pool settings of dbConfig are applied to the returned db
 // If db connection fails, returns error
 func NewDB(dbConfig confutil.Database, dbType string) (*DB, error) {
-	dbInfo := fmt.Sprintf(
-		DBConnStr,
-		dbConfig.Host,
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.DBName,
-		dbConfig.Port,
-		dbConfig.SSLMode,
-	)
+	dbInfo, err := DSN(dbConfig, dbType)
+
+	if err != nil {
+		return nil, err
+	}
 
 	db, err := sqlx.Open(dbType, dbInfo)
 	if err != nil {
 		return nil, err
 	}
+
+	applyPoolConfig(db, dbConfig)
+
 	if err = db.Ping(); err != nil {
 		return nil, err
 	}
-	return &DB{DB: db, dbType: dbType}, nil
+	return &DB{DB: db, dbType: dbType, appName: dbConfig.ApplicationName}, nil
+}
+
+// ApplicationName returns confutil#Database#ApplicationName of db
+func (db *DB) ApplicationName() string {
+	return db.appName
+}
+
+// FailoverRetryPolicy is policy NewDBWithList uses to retry connecting
+// to its list of configs when every one fails with a transient error
+var FailoverRetryPolicy = httputil.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond * 500,
 }
 
+// NewDBWithList returns *DB of the first config of dbConfigList that
+// connects, which is used to fail over when RecoverError is called
+// Connecting to the list is retried with FailoverRetryPolicy
+// Returns ErrNoConnection if no config connects
 func NewDBWithList(dbConfigList []confutil.Database, dbType string) (*DB, error) {
 	if len(dbConfigList) == 0 {
 		return nil, ErrEmptyConfigList
 	}
 
-	for _, v := range dbConfigList {
-		newDB, err := NewDB(v, dbType)
+	var newDB *DB
 
-		if err == nil {
-			newDB.dbConfigList = dbConfigList
-			newDB.currentConfig = v
-			return newDB, nil
+	err := httputil.Retry(context.Background(), FailoverRetryPolicy, func(ctx context.Context) error {
+		var err error
+
+		for _, v := range dbConfigList {
+			if newDB, err = NewDB(v, dbType); err == nil {
+				newDB.dbConfigList = dbConfigList
+				newDB.currentConfig = v
+				return nil
+			}
 		}
+
+		return err
+	})
+
+	if err != nil {
+		return nil, ErrNoConnection
 	}
 
-	return nil, ErrNoConnection
+	return newDB, nil
 }
 
 func dbError(w http.ResponseWriter, err error, db httputil.Recover) bool {
@@ -353,6 +462,7 @@ func HasQueryOrDBError(w http.ResponseWriter, err error, db httputil.Recover, no
 // }
 
 // QueryCount is used for queries that consist of count in select statement
+// See QueryCountRetry to retry transient errors
 func QueryCount(db httputil.SqlxDB, query string, args ...interface{}) (*Count, error) {
 	var dest Count
 	err := db.Get(&dest, query, args...)
@@ -36,7 +36,7 @@ type MockDB struct {
 	GetFunc    func(dest interface{}, query string, args ...interface{}) error
 	SelectFunc func(dest interface{}, query string, args ...interface{}) error
 
-	RecoverErrorFunc func(err error) bool
+	RecoverErrorFunc func(err error) (httputil.DBInterfaceV2, error)
 }
 
 func (m *MockDB) QueryRow(query string, args ...interface{}) httputil.Scanner {
@@ -48,7 +48,7 @@ func (m *MockDB) Query(query string, args ...interface{}) (httputil.Rower, error
 }
 
 func (m *MockDB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return m.Exec(query, args...)
+	return m.ExecFunc(query, args...)
 }
 
 func (m *MockDB) Begin() (tx httputil.Tx, err error) {
@@ -67,6 +67,6 @@ func (m *MockDB) Select(dest interface{}, query string, args ...interface{}) err
 	return m.SelectFunc(dest, query, args...)
 }
 
-func (m *MockDB) RecoverError(err error) bool {
+func (m *MockDB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
 	return m.RecoverErrorFunc(err)
 }
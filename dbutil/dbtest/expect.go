@@ -0,0 +1,645 @@
+package dbtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	kindQuery    = "Query"
+	kindExec     = "Exec"
+	kindGet      = "Get"
+	kindSelect   = "Select"
+	kindBegin    = "Begin"
+	kindCommit   = "Commit"
+	kindRollback = "Rollback"
+	kindRecover  = "RecoverError"
+)
+
+var (
+	whitespaceExp = regexp.MustCompile(`\s+`)
+)
+
+// QueryMatcher is used by ExpectDB to determine if the expected query
+// matches the actual query
+// Should return error describing mismatch else nil
+type QueryMatcher func(expected, actual string) error
+
+// QueryMatcherRegexp treats the expected query as a regular expression
+// This is the default QueryMatcher of ExpectDB
+var QueryMatcherRegexp QueryMatcher = func(expected, actual string) error {
+	exp, err := regexp.Compile(expected)
+
+	if err != nil {
+		return err
+	}
+
+	if !exp.MatchString(actual) {
+		return fmt.Errorf("query '%s' does not match regex '%s'", actual, expected)
+	}
+
+	return nil
+}
+
+// QueryMatcherEqual compares the expected and actual query with
+// all whitespace collapsed
+var QueryMatcherEqual QueryMatcher = func(expected, actual string) error {
+	expected = strings.TrimSpace(whitespaceExp.ReplaceAllString(expected, " "))
+	actual = strings.TrimSpace(whitespaceExp.ReplaceAllString(actual, " "))
+
+	if expected != actual {
+		return fmt.Errorf("query '%s' does not equal '%s'", actual, expected)
+	}
+
+	return nil
+}
+
+// Argument can be passed to WithArgs of an expectation to match an
+// argument by something other than equality
+type Argument interface {
+	Match(v interface{}) bool
+}
+
+type anyArg struct{}
+
+func (a anyArg) Match(v interface{}) bool {
+	return true
+}
+
+// AnyArg returns Argument that matches any value
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+type expecter interface {
+	base() *expectation
+}
+
+type expectation struct {
+	kind      string
+	query     string
+	args      []interface{}
+	err       error
+	triggered bool
+}
+
+func (e *expectation) base() *expectation {
+	return e
+}
+
+func (e *expectation) String() string {
+	if e.query == "" {
+		return e.kind
+	}
+
+	if e.args == nil {
+		return fmt.Sprintf("%s '%s'", e.kind, e.query)
+	}
+
+	return fmt.Sprintf("%s '%s' with args %v", e.kind, e.query, e.args)
+}
+
+func (e *expectation) match(kind, query string, args []interface{}, matcher QueryMatcher) error {
+	if e.kind != kind {
+		return fmt.Errorf("expected %s; got %s", e.kind, kind)
+	}
+
+	if e.query != "" {
+		if err := matcher(e.query, query); err != nil {
+			return err
+		}
+	}
+
+	if e.args == nil {
+		return nil
+	}
+
+	if len(e.args) != len(args) {
+		return fmt.Errorf("expected %d args; got %d", len(e.args), len(args))
+	}
+
+	for i, v := range e.args {
+		if arg, ok := v.(Argument); ok {
+			if !arg.Match(args[i]) {
+				return fmt.Errorf("arg %d with value %v does not match", i, args[i])
+			}
+		} else if !reflect.DeepEqual(v, args[i]) {
+			return fmt.Errorf("expected arg %d to be %v; got %v", i, v, args[i])
+		}
+	}
+
+	return nil
+}
+
+// ExpectedQuery is returned from ExpectDB#ExpectQuery and is used
+// for both Query and QueryRow calls
+type ExpectedQuery struct {
+	expectation
+	rows httputil.Rower
+}
+
+// WithArgs sets the args the query is expected to be called with
+// Values can be Argument to match by something other than equality
+func (e *ExpectedQuery) WithArgs(args ...interface{}) *ExpectedQuery {
+	e.args = args
+	return e
+}
+
+// WillReturnRows sets rows returned from query
+func (e *ExpectedQuery) WillReturnRows(rows httputil.Rower) *ExpectedQuery {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError sets error returned from query
+func (e *ExpectedQuery) WillReturnError(err error) *ExpectedQuery {
+	e.err = err
+	return e
+}
+
+// ExpectedExec is returned from ExpectDB#ExpectExec
+type ExpectedExec struct {
+	expectation
+	result sql.Result
+}
+
+// WithArgs sets the args the exec is expected to be called with
+// Values can be Argument to match by something other than equality
+func (e *ExpectedExec) WithArgs(args ...interface{}) *ExpectedExec {
+	e.args = args
+	return e
+}
+
+// WillReturnResult sets result returned from exec
+func (e *ExpectedExec) WillReturnResult(result sql.Result) *ExpectedExec {
+	e.result = result
+	return e
+}
+
+// WillReturnError sets error returned from exec
+func (e *ExpectedExec) WillReturnError(err error) *ExpectedExec {
+	e.err = err
+	return e
+}
+
+// ExpectedDest is returned from ExpectDB#ExpectGet and ExpectDB#ExpectSelect
+type ExpectedDest struct {
+	expectation
+	value interface{}
+}
+
+// WithArgs sets the args the query is expected to be called with
+// Values can be Argument to match by something other than equality
+func (e *ExpectedDest) WithArgs(args ...interface{}) *ExpectedDest {
+	e.args = args
+	return e
+}
+
+// WillReturnValue sets the value that will be assigned to dest
+// Value should be the same type dest points to, or a pointer to it
+func (e *ExpectedDest) WillReturnValue(value interface{}) *ExpectedDest {
+	e.value = value
+	return e
+}
+
+// WillReturnError sets error returned from Get or Select
+func (e *ExpectedDest) WillReturnError(err error) *ExpectedDest {
+	e.err = err
+	return e
+}
+
+// ExpectedTx is returned from ExpectDB#ExpectBegin, ExpectDB#ExpectCommit
+// and ExpectDB#ExpectRollback
+type ExpectedTx struct {
+	expectation
+}
+
+// WillReturnError sets error returned from transaction call
+func (e *ExpectedTx) WillReturnError(err error) *ExpectedTx {
+	e.err = err
+	return e
+}
+
+// ExpectedRecover is returned from ExpectDB#ExpectRecoverError
+type ExpectedRecover struct {
+	expectation
+	db httputil.DBInterfaceV2
+}
+
+// WillReturnDB sets db returned from RecoverError
+// If not set, the ExpectDB itself is returned
+func (e *ExpectedRecover) WillReturnDB(db httputil.DBInterfaceV2) *ExpectedRecover {
+	e.db = db
+	return e
+}
+
+// WillReturnError sets error returned from RecoverError
+func (e *ExpectedRecover) WillReturnError(err error) *ExpectedRecover {
+	e.err = err
+	return e
+}
+
+// ExpectDB is an expectation based mock that implements httputil.DBInterfaceV2
+//
+// Each call against ExpectDB must match an expectation set beforehand eg.
+//
+//	db := dbtest.NewExpectDB(t)
+//	db.ExpectQuery("select id from users").WithArgs(1).WillReturnRows(rows)
+//	db.ExpectExec("update users").WillReturnResult(dbtest.NewResult(0, 1))
+//
+// By default, expectations must be met in the order they were set
+// which can be changed with ExpectDB#MatchExpectationsInOrder
+type ExpectDB struct {
+	// QueryMatcher is used to match queries against expectations
+	// Default is QueryMatcherRegexp
+	QueryMatcher QueryMatcher
+
+	mu         sync.Mutex
+	ordered    bool
+	expected   []expecter
+	unexpected []error
+}
+
+// NewExpectDB returns *ExpectDB that will fail t at the end of the
+// test if any expectations were not met
+// t can be nil, in which case ExpectDB#ExpectationsWereMet should be
+// called manually
+func NewExpectDB(t testing.TB) *ExpectDB {
+	m := &ExpectDB{
+		QueryMatcher: QueryMatcherRegexp,
+		ordered:      true,
+	}
+
+	if t != nil {
+		t.Cleanup(func() {
+			if err := m.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	return m
+}
+
+// MatchExpectationsInOrder sets whether expectations have to be met
+// in the order they were set
+func (m *ExpectDB) MatchExpectationsInOrder(ordered bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ordered = ordered
+}
+
+// ExpectQuery expects Query or QueryRow to be called with query
+func (m *ExpectDB) ExpectQuery(query string) *ExpectedQuery {
+	e := &ExpectedQuery{expectation: expectation{kind: kindQuery, query: query}}
+	m.add(e)
+	return e
+}
+
+// ExpectExec expects Exec to be called with query
+func (m *ExpectDB) ExpectExec(query string) *ExpectedExec {
+	e := &ExpectedExec{expectation: expectation{kind: kindExec, query: query}}
+	m.add(e)
+	return e
+}
+
+// ExpectGet expects Get to be called with query
+func (m *ExpectDB) ExpectGet(query string) *ExpectedDest {
+	e := &ExpectedDest{expectation: expectation{kind: kindGet, query: query}}
+	m.add(e)
+	return e
+}
+
+// ExpectSelect expects Select to be called with query
+func (m *ExpectDB) ExpectSelect(query string) *ExpectedDest {
+	e := &ExpectedDest{expectation: expectation{kind: kindSelect, query: query}}
+	m.add(e)
+	return e
+}
+
+// ExpectBegin expects Begin to be called
+func (m *ExpectDB) ExpectBegin() *ExpectedTx {
+	e := &ExpectedTx{expectation: expectation{kind: kindBegin}}
+	m.add(e)
+	return e
+}
+
+// ExpectCommit expects a transaction to be committed
+func (m *ExpectDB) ExpectCommit() *ExpectedTx {
+	e := &ExpectedTx{expectation: expectation{kind: kindCommit}}
+	m.add(e)
+	return e
+}
+
+// ExpectRollback expects a transaction to be rolled back
+func (m *ExpectDB) ExpectRollback() *ExpectedTx {
+	e := &ExpectedTx{expectation: expectation{kind: kindRollback}}
+	m.add(e)
+	return e
+}
+
+// ExpectRecoverError expects RecoverError to be called
+func (m *ExpectDB) ExpectRecoverError() *ExpectedRecover {
+	e := &ExpectedRecover{expectation: expectation{kind: kindRecover}}
+	m.add(e)
+	return e
+}
+
+// ExpectationsWereMet returns error if any expectations were not
+// triggered or if there were any unexpected calls
+func (m *ExpectDB) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var msgs []string
+
+	for _, e := range m.expected {
+		if b := e.base(); !b.triggered {
+			msgs = append(msgs, "expectation was not met: "+b.String())
+		}
+	}
+
+	for _, err := range m.unexpected {
+		msgs = append(msgs, err.Error())
+	}
+
+	if len(msgs) > 0 {
+		return errors.New("dbtest:\n - " + strings.Join(msgs, "\n - "))
+	}
+
+	return nil
+}
+
+func (m *ExpectDB) add(e expecter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected = append(m.expected, e)
+}
+
+// next finds and triggers the expectation that matches the given call
+func (m *ExpectDB) next(kind, query string, args []interface{}) (expecter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matcher := m.QueryMatcher
+
+	if matcher == nil {
+		matcher = QueryMatcherRegexp
+	}
+
+	call := (&expectation{kind: kind, query: query, args: args}).String()
+
+	for _, e := range m.expected {
+		b := e.base()
+
+		if b.triggered {
+			continue
+		}
+
+		if err := b.match(kind, query, args, matcher); err != nil {
+			if m.ordered {
+				err = fmt.Errorf(
+					"call to %s was not expected, next expectation is %s: %s",
+					call,
+					b.String(),
+					err.Error(),
+				)
+				m.unexpected = append(m.unexpected, err)
+				return nil, err
+			}
+
+			continue
+		}
+
+		b.triggered = true
+		return e, nil
+	}
+
+	err := fmt.Errorf("call to %s was not expected", call)
+	m.unexpected = append(m.unexpected, err)
+	return nil, err
+}
+
+// QueryRow matches ExpectQuery expectation and returns the first row
+// of its rows or sql.ErrNoRows on scan if there are none
+func (m *ExpectDB) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	rows, err := m.Query(query, args...)
+
+	if err != nil {
+		return &errScanner{err: err}
+	}
+
+	if rows == nil || !rows.Next() {
+		return &errScanner{err: sql.ErrNoRows}
+	}
+
+	return rows
+}
+
+// Query matches ExpectQuery expectation
+func (m *ExpectDB) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	e, err := m.next(kindQuery, query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	q := e.(*ExpectedQuery)
+	return q.rows, q.err
+}
+
+// Exec matches ExpectExec expectation
+func (m *ExpectDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	e, err := m.next(kindExec, query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ex := e.(*ExpectedExec)
+
+	if ex.err != nil {
+		return nil, ex.err
+	}
+
+	if ex.result == nil {
+		return NewResult(0, 0), nil
+	}
+
+	return ex.result, nil
+}
+
+// Get matches ExpectGet expectation
+func (m *ExpectDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return m.dest(kindGet, dest, query, args)
+}
+
+// Select matches ExpectSelect expectation
+func (m *ExpectDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return m.dest(kindSelect, dest, query, args)
+}
+
+func (m *ExpectDB) dest(kind string, dest interface{}, query string, args []interface{}) error {
+	e, err := m.next(kind, query, args)
+
+	if err != nil {
+		return err
+	}
+
+	d := e.(*ExpectedDest)
+
+	if d.err != nil {
+		return d.err
+	}
+
+	return setDest(dest, d.value)
+}
+
+// Begin matches ExpectBegin expectation
+// Queries made against the returned transaction are matched against
+// the same expectations as the ExpectDB itself
+func (m *ExpectDB) Begin() (httputil.Tx, error) {
+	e, err := m.next(kindBegin, "", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = e.base().err; err != nil {
+		return nil, err
+	}
+
+	return &expectTx{db: m}, nil
+}
+
+// Commit matches ExpectCommit expectation
+func (m *ExpectDB) Commit(tx httputil.Tx) error {
+	return tx.Commit()
+}
+
+// RecoverError matches ExpectRecoverError expectation
+func (m *ExpectDB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
+	e, nextErr := m.next(kindRecover, "", nil)
+
+	if nextErr != nil {
+		return nil, nextErr
+	}
+
+	r := e.(*ExpectedRecover)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.db != nil {
+		return r.db, nil
+	}
+
+	return m, nil
+}
+
+func (m *ExpectDB) txEnd(kind string) error {
+	e, err := m.next(kind, "", nil)
+
+	if err != nil {
+		return err
+	}
+
+	return e.base().err
+}
+
+type expectTx struct {
+	db *ExpectDB
+}
+
+func (t *expectTx) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	return t.db.QueryRow(query, args...)
+}
+
+func (t *expectTx) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	return t.db.Query(query, args...)
+}
+
+func (t *expectTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.db.Exec(query, args...)
+}
+
+func (t *expectTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return t.db.Get(dest, query, args...)
+}
+
+func (t *expectTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return t.db.Select(dest, query, args...)
+}
+
+func (t *expectTx) Commit() error {
+	return t.db.txEnd(kindCommit)
+}
+
+func (t *expectTx) Rollback() error {
+	return t.db.txEnd(kindRollback)
+}
+
+type errScanner struct {
+	err error
+}
+
+func (e *errScanner) Scan(dest ...interface{}) error {
+	return e.err
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// NewResult returns sql.Result with the given last insert id and
+// rows affected to be used with ExpectedExec#WillReturnResult
+func NewResult(lastInsertID, rowsAffected int64) sql.Result {
+	return result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+}
+
+func setDest(dest, value interface{}) error {
+	destVal := reflect.ValueOf(dest)
+
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return errors.New("dbtest: dest must be non nil pointer")
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+
+	if val.Type() == destVal.Type() {
+		val = val.Elem()
+	}
+
+	if !val.Type().AssignableTo(destVal.Elem().Type()) {
+		return fmt.Errorf(
+			"dbtest: value of type %s can't be assigned to dest of type %s",
+			val.Type(),
+			destVal.Elem().Type(),
+		)
+	}
+
+	destVal.Elem().Set(val)
+	return nil
+}
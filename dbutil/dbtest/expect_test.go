@@ -0,0 +1,114 @@
+package dbtest
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+type testUser struct {
+	ID    int
+	Email string
+}
+
+func TestExpectDB(t *testing.T) {
+	var err error
+
+	db := NewExpectDB(nil)
+	db.ExpectExec("update users").WithArgs(AnyArg(), 1).WillReturnResult(NewResult(0, 1))
+	db.ExpectBegin()
+	db.ExpectExec("delete from users").WithArgs(2)
+	db.ExpectRollback()
+
+	res, err := db.Exec("update users set email = $1 where id = $2", "foo@example.com", 1)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if affected, _ := res.RowsAffected(); affected != 1 {
+		t.Errorf("should have 1 row affected; got %d", affected)
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if _, err = tx.Exec("delete from users where id = $1", 2); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	db = NewExpectDB(nil)
+	db.ExpectExec("insert into users")
+	db.ExpectExec("update users")
+
+	if _, err = db.Exec("update users set email = 'foo'"); err == nil {
+		t.Errorf("should have error for out of order call")
+	}
+
+	if err = db.ExpectationsWereMet(); err == nil {
+		t.Errorf("should have error for unmet expectations")
+	}
+
+	db = NewExpectDB(nil)
+	db.MatchExpectationsInOrder(false)
+	db.ExpectExec("insert into users")
+	db.ExpectExec("update users")
+
+	if _, err = db.Exec("update users set email = 'foo'"); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	if _, err = db.Exec("insert into users (email) values ('foo')"); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+}
+
+func TestExpectDBDest(t *testing.T) {
+	var user testUser
+	var users []testUser
+
+	db := NewExpectDB(t)
+	db.ExpectGet("select").WithArgs(1).WillReturnValue(testUser{ID: 1, Email: "foo@example.com"})
+	db.ExpectSelect("select").WillReturnValue([]testUser{{ID: 1}, {ID: 2}})
+	db.ExpectGet("select").WillReturnError(sql.ErrNoRows)
+	db.ExpectRecoverError().WillReturnError(errors.New("down"))
+
+	if err := db.Get(&user, "select * from users where id = $1", 1); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if user.Email != "foo@example.com" {
+		t.Errorf("should have email 'foo@example.com'; got '%s'", user.Email)
+	}
+
+	if err := db.Select(&users, "select * from users"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if len(users) != 2 {
+		t.Errorf("should have 2 users; got %d", len(users))
+	}
+
+	if err := db.Get(&user, "select * from users where id = 3"); err != sql.ErrNoRows {
+		t.Errorf("should have sql.ErrNoRows; got %v", err)
+	}
+
+	if _, err := db.RecoverError(sql.ErrConnDone); err == nil {
+		t.Errorf("should have error")
+	}
+}
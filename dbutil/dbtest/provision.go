@@ -0,0 +1,226 @@
+package dbtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// TestDBConfig is config struct used in conjunction with NewTestDB
+type TestDBConfig struct {
+	// Config is the connection config of an existing database on the
+	// server the test database will be created on
+	// This is usually "postgres" for Postgres or "defaultdb" for Cockroach
+	Config confutil.Database
+
+	// DBType is the type of database eg. dbutil.Postgres
+	// Default is dbutil.Postgres
+	DBType string
+
+	// MigrationDir is directory of migrations that will be applied
+	// to the test database if set
+	MigrationDir string
+
+	// FixtureFiles are files that will be loaded into the test database,
+	// in order, after migrations are applied
+	//
+	// Files ending in ".sql" are executed as is while files ending in
+	// ".yml" or ".yaml" should be map of table name to list of rows eg.
+	//
+	//	users:
+	//	  - id: 1
+	//	    email: foo@example.com
+	FixtureFiles []string
+
+	// KeepDB will skip dropping the test database on TestDB#Close
+	// which can be useful to inspect a failing test
+	KeepDB bool
+}
+
+// TestDB is a temporary database created by NewTestDB
+// The embedded *dbutil.DB implements httputil.DBInterfaceV2 so it
+// can be passed directly to handlers and queryutil functions
+type TestDB struct {
+	*dbutil.DB
+
+	name    string
+	adminDB *dbutil.DB
+	config  TestDBConfig
+}
+
+// Name returns the name of the created test database
+func (t *TestDB) Name() string {
+	return t.name
+}
+
+// Close closes the connection to the test database and drops it
+func (t *TestDB) Close() error {
+	defer t.adminDB.Close()
+
+	if err := t.DB.Close(); err != nil {
+		return err
+	}
+
+	if t.config.KeepDB {
+		return nil
+	}
+
+	_, err := t.adminDB.Exec(fmt.Sprintf("drop database if exists %s", t.name))
+	return err
+}
+
+// NewTestDB creates a uniquely named database on the server of
+// TestDBConfig#Config, applies migrations and fixtures and returns
+// the connection to it
+//
+// TestDB#Close should be deferred to drop the database once the test is done
+func NewTestDB(config TestDBConfig) (*TestDB, error) {
+	if config.DBType == "" {
+		config.DBType = dbutil.Postgres
+	}
+
+	adminDB, err := dbutil.NewDB(config.Config, config.DBType)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "dbtest: could not connect to server")
+	}
+
+	name := fmt.Sprintf("test_%d_%d", time.Now().Unix(), rand.Intn(100000))
+
+	if _, err = adminDB.Exec(fmt.Sprintf("create database %s", name)); err != nil {
+		adminDB.Close()
+		return nil, errors.Wrap(err, "dbtest: could not create database")
+	}
+
+	testConfig := config.Config
+	testConfig.DBName = name
+	db, err := dbutil.NewDB(testConfig, config.DBType)
+
+	if err != nil {
+		adminDB.Exec(fmt.Sprintf("drop database if exists %s", name))
+		adminDB.Close()
+		return nil, errors.Wrap(err, "dbtest: could not connect to test database")
+	}
+
+	testDB := &TestDB{
+		DB:      db,
+		name:    name,
+		adminDB: adminDB,
+		config:  config,
+	}
+
+	if config.MigrationDir != "" {
+		if _, err = dbutil.Migrate(db, dbutil.MigrateConfig{
+			Dir:    config.MigrationDir,
+			DBType: config.DBType,
+		}); err != nil {
+			testDB.Close()
+			return nil, err
+		}
+	}
+
+	for _, f := range config.FixtureFiles {
+		if err = LoadFixtureFile(db, config.DBType, f); err != nil {
+			testDB.Close()
+			return nil, err
+		}
+	}
+
+	return testDB, nil
+}
+
+// LoadFixtureFile loads the given sql or yaml fixture file into db
+// See TestDBConfig#FixtureFiles for the expected yaml format
+func LoadFixtureFile(db *dbutil.DB, dbType, path string) error {
+	source, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return errors.Wrap(err, "dbtest")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sql":
+		if _, err = db.Exec(string(source)); err != nil {
+			return errors.Wrapf(err, "dbtest: fixture '%s'", path)
+		}
+	case ".yml", ".yaml":
+		var tables yaml.MapSlice
+
+		if err = yaml.Unmarshal(source, &tables); err != nil {
+			return errors.Wrapf(err, "dbtest: fixture '%s'", path)
+		}
+
+		for _, table := range tables {
+			var rows []map[string]interface{}
+
+			tableBytes, err := yaml.Marshal(table.Value)
+
+			if err != nil {
+				return errors.Wrapf(err, "dbtest: fixture '%s'", path)
+			}
+
+			if err = yaml.Unmarshal(tableBytes, &rows); err != nil {
+				return errors.Wrapf(err, "dbtest: fixture '%s'", path)
+			}
+
+			if err = insertFixtureRows(db, dbType, fmt.Sprintf("%v", table.Key), rows); err != nil {
+				return errors.Wrapf(err, "dbtest: fixture '%s'", path)
+			}
+		}
+	default:
+		return fmt.Errorf("dbtest: unsupported fixture file '%s'", path)
+	}
+
+	return nil
+}
+
+func insertFixtureRows(db *dbutil.DB, dbType, table string, rows []map[string]interface{}) error {
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+
+		for k := range row {
+			columns = append(columns, k)
+		}
+
+		sort.Strings(columns)
+
+		args := make([]interface{}, 0, len(columns))
+		placeholders := make([]string, 0, len(columns))
+
+		for _, c := range columns {
+			args = append(args, row[c])
+			placeholders = append(placeholders, "?")
+		}
+
+		query := sqlx.Rebind(
+			sqlx.BindType(dbType),
+			fmt.Sprintf(
+				"insert into %s (%s) values (%s)",
+				table,
+				strings.Join(columns, ", "),
+				strings.Join(placeholders, ", "),
+			),
+		)
+
+		if _, err := db.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package dbtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Rows implements httputil.Rower and is used to declare the rows a
+// query should return in tests eg.
+//
+//	rows := dbtest.NewRows("id", "name").
+//		AddRow(1, "foo").
+//		AddRow(2, "bar")
+//
+// Rows can be used with ExpectedQuery#WillReturnRows or returned
+// directly wherever a httputil.Rower is needed
+type Rows struct {
+	columns   []string
+	rows      [][]interface{}
+	rowErrors map[int]error
+	pos       int
+}
+
+// NewRows returns *Rows with the given columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{
+		columns:   columns,
+		rowErrors: make(map[int]error),
+	}
+}
+
+// AddRow adds a row with the given values which should be in the
+// same order and count as the columns of Rows
+// Panics if the number of values does not match the number of columns
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf(
+			"dbtest: expected %d values for row; got %d",
+			len(r.columns),
+			len(values),
+		))
+	}
+
+	r.rows = append(r.rows, values)
+	return r
+}
+
+// RowError sets error that will be returned when scanning the row
+// at the given index, starting at 0
+func (r *Rows) RowError(row int, err error) *Rows {
+	r.rowErrors[row] = err
+	return r
+}
+
+// Columns returns the columns of Rows
+func (r *Rows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+// Next advances to the next row and returns false once there are
+// no more rows
+func (r *Rows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+
+	r.pos++
+	return true
+}
+
+// Scan copies the values of the current row into dest
+// Values are converted the same basic way database/sql would,
+// including calling sql.Scanner implementations
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.pos == 0 || r.pos > len(r.rows) {
+		return errors.New("dbtest: Scan called without calling Next")
+	}
+
+	if err, ok := r.rowErrors[r.pos-1]; ok {
+		return err
+	}
+
+	row := r.rows[r.pos-1]
+
+	if len(dest) != len(row) {
+		return fmt.Errorf("dbtest: expected %d destination arguments in Scan; got %d", len(row), len(dest))
+	}
+
+	for i, v := range row {
+		if err := scanValue(dest[i], v); err != nil {
+			return fmt.Errorf("dbtest: scanning column '%s': %s", r.columns[i], err.Error())
+		}
+	}
+
+	return nil
+}
+
+// RowsFromMaps returns *Rows with a row for every map
+// If no columns are given, the sorted keys of the first map are used
+func RowsFromMaps(maps []map[string]interface{}, columns ...string) *Rows {
+	if len(columns) == 0 && len(maps) > 0 {
+		for k := range maps[0] {
+			columns = append(columns, k)
+		}
+
+		sort.Strings(columns)
+	}
+
+	rows := NewRows(columns...)
+
+	for _, m := range maps {
+		values := make([]interface{}, 0, len(columns))
+
+		for _, c := range columns {
+			values = append(values, m[c])
+		}
+
+		rows.AddRow(values...)
+	}
+
+	return rows
+}
+
+// RowsFromStructs returns *Rows with a row for every struct within slice,
+// which should be a slice of structs or pointers to structs
+//
+// Columns are taken from the "db" tag of each exported field, the same
+// tag used by sqlx, else the lowercased field name
+// Fields tagged with "-" are skipped
+func RowsFromStructs(slice interface{}) (*Rows, error) {
+	sliceVal := reflect.ValueOf(slice)
+
+	if sliceVal.Kind() != reflect.Slice {
+		return nil, errors.New("dbtest: RowsFromStructs expects slice")
+	}
+
+	elemType := sliceVal.Type().Elem()
+
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return nil, errors.New("dbtest: RowsFromStructs expects slice of structs")
+	}
+
+	columns := make([]string, 0, elemType.NumField())
+	fieldIdxs := make([]int, 0, elemType.NumField())
+
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Tag.Get("db")
+
+		if name == "-" {
+			continue
+		}
+
+		if idx := strings.Index(name, ","); idx >= 0 {
+			name = name[:idx]
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		columns = append(columns, name)
+		fieldIdxs = append(fieldIdxs, i)
+	}
+
+	rows := NewRows(columns...)
+
+	for i := 0; i < sliceVal.Len(); i++ {
+		elem := reflect.Indirect(sliceVal.Index(i))
+
+		if !elem.IsValid() {
+			return nil, fmt.Errorf("dbtest: nil element at index %d", i)
+		}
+
+		values := make([]interface{}, 0, len(fieldIdxs))
+
+		for _, idx := range fieldIdxs {
+			values = append(values, elem.Field(idx).Interface())
+		}
+
+		rows.AddRow(values...)
+	}
+
+	return rows, nil
+}
+
+func scanValue(dest, value interface{}) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	destVal := reflect.ValueOf(dest)
+
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return errors.New("destination must be non nil pointer")
+	}
+
+	destElem := destVal.Elem()
+
+	if value == nil {
+		switch destElem.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			destElem.Set(reflect.Zero(destElem.Type()))
+			return nil
+		}
+
+		return fmt.Errorf("can't scan nil into %s", destElem.Type())
+	}
+
+	val := reflect.ValueOf(value)
+
+	if val.Type().AssignableTo(destElem.Type()) {
+		destElem.Set(val)
+		return nil
+	}
+
+	// Allows value of T to be scanned into *T the same as database/sql
+	// does for nullable columns
+	if destElem.Kind() == reflect.Ptr {
+		ptr := reflect.New(destElem.Type().Elem())
+
+		if err := scanValue(ptr.Interface(), value); err != nil {
+			return err
+		}
+
+		destElem.Set(ptr)
+		return nil
+	}
+
+	if b, ok := value.([]byte); ok && destElem.Kind() == reflect.String {
+		destElem.SetString(string(b))
+		return nil
+	}
+
+	if s, ok := value.(string); ok && destElem.Type() == reflect.TypeOf([]byte(nil)) {
+		destElem.SetBytes([]byte(s))
+		return nil
+	}
+
+	if val.Type().ConvertibleTo(destElem.Type()) && !isStringNumberConversion(val, destElem) {
+		destElem.Set(val.Convert(destElem.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("can't scan %s into %s", val.Type(), destElem.Type())
+}
+
+// isStringNumberConversion guards against reflect converting an int
+// into a single rune string which is never what is wanted here
+func isStringNumberConversion(val, dest reflect.Value) bool {
+	if dest.Kind() != reflect.String {
+		return false
+	}
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
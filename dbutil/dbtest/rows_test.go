@@ -0,0 +1,75 @@
+package dbtest
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	var id int64
+	var name string
+	var email *string
+	var note sql.NullString
+
+	rows := NewRows("id", "name", "email", "note").
+		AddRow(1, []byte("foo"), "foo@example.com", nil).
+		AddRow(int64(2), "bar", nil, "note")
+
+	count := 0
+
+	for rows.Next() {
+		if err := rows.Scan(&id, &name, &email, &note); err != nil {
+			t.Fatalf("should not have error; got %s", err.Error())
+		}
+
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("should have 2 rows; got %d", count)
+	}
+
+	if id != 2 || name != "bar" || email != nil || !note.Valid {
+		t.Errorf("last row scanned incorrectly; got %d %s %v %v", id, name, email, note)
+	}
+
+	rows = NewRows("name").AddRow(1)
+	rows.Next()
+
+	if err := rows.Scan(&name); err == nil {
+		t.Errorf("should have error scanning int into string")
+	}
+}
+
+func TestRowsFromStructs(t *testing.T) {
+	type user struct {
+		ID       int    `db:"id"`
+		Email    string `db:"email"`
+		Password string `db:"-"`
+		Name     string
+		internal string
+	}
+
+	rows, err := RowsFromStructs([]*user{{ID: 1, Email: "foo@example.com", Name: "foo"}})
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	columns, _ := rows.Columns()
+
+	if len(columns) != 3 || columns[0] != "id" || columns[1] != "email" || columns[2] != "name" {
+		t.Errorf("should have columns [id email name]; got %v", columns)
+	}
+
+	if _, err = RowsFromStructs([]int{1}); err == nil {
+		t.Errorf("should have error for non struct slice")
+	}
+
+	rows = RowsFromMaps([]map[string]interface{}{{"b": 2, "a": 1}})
+	columns, _ = rows.Columns()
+
+	if len(columns) != 2 || columns[0] != "a" {
+		t.Errorf("should have sorted columns; got %v", columns)
+	}
+}
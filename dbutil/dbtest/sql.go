@@ -0,0 +1,48 @@
+package dbtest
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	parenSpaceExp = regexp.MustCompile(`\(\s+|\s+\)|\s+,`)
+)
+
+// NormalizeSQL returns query with whitespace collapsed and trimmed, and
+// without whitespace after "(" or before ")" and "," so formatting of
+// generated queries doesn't matter when comparing them
+func NormalizeSQL(query string) string {
+	query = strings.TrimSpace(whitespaceExp.ReplaceAllString(query, " "))
+	return parenSpaceExp.ReplaceAllStringFunc(query, strings.TrimSpace)
+}
+
+// AssertSQL fails t if expected and actual queries are not equal once
+// normalized with NormalizeSQL
+func AssertSQL(t testing.TB, expected, actual string) {
+	t.Helper()
+
+	if NormalizeSQL(expected) != NormalizeSQL(actual) {
+		t.Errorf("query should be:\n%s\ngot:\n%s", NormalizeSQL(expected), NormalizeSQL(actual))
+	}
+}
+
+// AssertQuery fails t if expected and actual queries are not equal once
+// normalized with NormalizeSQL, or args don't deeply equal expectedArgs
+// It's meant to be used with queryutil#BuildQuery eg.
+//
+//	query, args, err := queryutil.BuildQuery(...)
+//	dbtest.AssertQuery(t, "select * from foo where foo.id = $1", query, []interface{}{1}, args)
+func AssertQuery(t testing.TB, expected, actual string, expectedArgs, actualArgs []interface{}) {
+	t.Helper()
+	AssertSQL(t, expected, actual)
+
+	if len(expectedArgs) == 0 && len(actualArgs) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(expectedArgs, actualArgs) {
+		t.Errorf("args should be %v; got %v", expectedArgs, actualArgs)
+	}
+}
@@ -0,0 +1,20 @@
+package dbtest
+
+import "testing"
+
+func TestNormalizeSQL(t *testing.T) {
+	query := NormalizeSQL(`
+		select
+			count( * ),
+			foo.id
+		from
+			foo
+		where
+			foo.id in ( $1 , $2 )
+	`)
+	expected := "select count(*), foo.id from foo where foo.id in ($1, $2)"
+
+	if query != expected {
+		t.Errorf("got %s; want %s", query, expected)
+	}
+}
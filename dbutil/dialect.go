@@ -0,0 +1,250 @@
+package dbutil
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// MysqlDSN is format of mysql data source name
+	MysqlDSN = "%s:%s@tcp(%s:%s)/%s?parseTime=true"
+
+	// SqliteMemory is data source name of in-memory sqlite database
+	SqliteMemory = ":memory:"
+)
+
+// Dialect is metadata of sql database used to generate queries
+// that run across databases
+type Dialect struct {
+	// Name is type of database eg. Postgres
+	Name string
+
+	// BindVar is sqlx bind type of query placeholders
+	BindVar int
+
+	// Like is operator used for case insensitive pattern matching
+	Like string
+
+	// LimitOffset is clause appended to query for limit and offset
+	// with limit being first placeholder and offset second
+	LimitOffset string
+
+	// MaxParams is max number of bind parameters of a single query
+	MaxParams int
+
+	// GroupingSets is whether database supports "rollup(...)" and
+	// "cube(...)" within group by
+	GroupingSets bool
+
+	// Retryable determines if error of database is transient and the
+	// query that returned it can be retried, see RetryableOf
+	// Default is httputil#IsRetryable
+	Retryable func(err error) bool
+}
+
+// Concat returns sql expression that concatenates parts
+func (d Dialect) Concat(parts ...string) string {
+	if d.Name == Mysql {
+		return "concat(" + strings.Join(parts, ", ") + ")"
+	}
+
+	return strings.Join(parts, " || ")
+}
+
+var dialects = map[string]Dialect{
+	Postgres: {
+		Name:         Postgres,
+		BindVar:      sqlx.DOLLAR,
+		Like:         "ilike",
+		LimitOffset:  " limit ? offset ?",
+		MaxParams:    65535,
+		GroupingSets: true,
+		Retryable:    httputil.IsRetryable,
+	},
+	// Mysql and sqlite "like" is case insensitive by default
+	Mysql: {
+		Name:        Mysql,
+		BindVar:     sqlx.QUESTION,
+		Like:        "like",
+		LimitOffset: " limit ? offset ?",
+		MaxParams:   65535,
+		Retryable:   mysqlRetryable,
+	},
+	// Sqlite before 3.32 only allows 999 parameters
+	Sqlite: {
+		Name:        Sqlite,
+		BindVar:     sqlx.QUESTION,
+		Like:        "like",
+		LimitOffset: " limit ? offset ?",
+		MaxParams:   999,
+		Retryable:   sqliteRetryable,
+	},
+}
+
+// GetDialect returns Dialect of dbType
+// If dbType is not supported, postgres dialect is returned
+func GetDialect(dbType string) Dialect {
+	if d, ok := dialects[dbType]; ok {
+		return d
+	}
+
+	return dialects[Postgres]
+}
+
+// BindVarProvider is implemented by connections that know the sqlx bind
+// var of their driver like *DB and *CustomTx
+type BindVarProvider interface {
+	BindVar() int
+}
+
+// BindVarOf returns bind var of db if it implements BindVarProvider,
+// else sqlx.UNKNOWN
+func BindVarOf(db interface{}) int {
+	if p, ok := db.(BindVarProvider); ok {
+		return p.BindVar()
+	}
+
+	return sqlx.UNKNOWN
+}
+
+// BindVar returns sqlx bind var of driver of db eg. sqlx.DOLLAR
+// for postgres
+func (db *DB) BindVar() int {
+	return sqlx.BindType(db.DriverName())
+}
+
+// BindVar returns sqlx bind var of driver of transaction
+func (c *CustomTx) BindVar() int {
+	return sqlx.BindType(c.tx.DriverName())
+}
+
+// DSN returns data source name of dbConfig for dbType used to open
+// database connection
+func DSN(dbConfig confutil.Database, dbType string) (string, error) {
+	switch dbType {
+	case Postgres:
+		return PostgresDataSource(dbConfig), nil
+	case Mysql:
+		return MysqlDataSource(dbConfig), nil
+	case Sqlite:
+		return SqliteDataSource(dbConfig), nil
+	}
+
+	return "", fmt.Errorf("dbutil: Unsupported database type \"%s\"", dbType)
+}
+
+// PostgresDataSource returns postgres data source name of dbConfig
+// StatementTimeout, LockTimeout and ApplicationName of dbConfig, if
+// set, are sent as run time parameters of every connection
+func PostgresDataSource(dbConfig confutil.Database) string {
+	dsn := fmt.Sprintf(
+		DBConnStr,
+		dbConfig.Host,
+		dbConfig.User,
+		dbConfig.Password,
+		dbConfig.DBName,
+		dbConfig.Port,
+		dbConfig.SSLMode,
+	)
+
+	if dbConfig.StatementTimeout.Duration > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", dbConfig.StatementTimeout.Milliseconds())
+	}
+	if dbConfig.LockTimeout.Duration > 0 {
+		dsn += fmt.Sprintf(" lock_timeout=%d", dbConfig.LockTimeout.Milliseconds())
+	}
+	if dbConfig.ApplicationName != "" {
+		dsn += " application_name=" + postgresDSNValue(dbConfig.ApplicationName)
+	}
+
+	return dsn
+}
+
+// postgresDSNValue quotes value of key/value data source name
+func postgresDSNValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+}
+
+// MysqlDataSource returns mysql data source name of dbConfig
+// SSLMode of SSLRequire uses tls without verification while
+// SSLVerifyCA and SSLVerifyFull verify the server certificate
+// StatementTimeout and LockTimeout of dbConfig, if set, are set as
+// session variables of every connection
+func MysqlDataSource(dbConfig confutil.Database) string {
+	port := dbConfig.Port
+
+	if port == "" {
+		port = "3306"
+	}
+
+	dsn := fmt.Sprintf(
+		MysqlDSN,
+		dbConfig.User,
+		dbConfig.Password,
+		dbConfig.Host,
+		port,
+		dbConfig.DBName,
+	)
+
+	switch dbConfig.SSLMode {
+	case SSLRequire:
+		dsn += "&tls=skip-verify"
+	case SSLVerifyCA, SSLVerifyFull:
+		dsn += "&tls=true"
+	}
+
+	if dbConfig.StatementTimeout.Duration > 0 {
+		dsn += fmt.Sprintf("&max_execution_time=%d", dbConfig.StatementTimeout.Milliseconds())
+	}
+	if d := dbConfig.LockTimeout.Duration; d > 0 {
+		dsn += fmt.Sprintf("&innodb_lock_wait_timeout=%d", int64((d+time.Second-1)/time.Second))
+	}
+
+	return dsn
+}
+
+// SqliteDataSource returns sqlite data source name of dbConfig where
+// DBName is path to database file
+// If DBName is empty or SqliteMemory, an in-memory database is used
+func SqliteDataSource(dbConfig confutil.Database) string {
+	if dbConfig.DBName == "" || dbConfig.DBName == SqliteMemory {
+		return SqliteMemory
+	}
+
+	return "file:" + (&url.URL{Path: dbConfig.DBName}).EscapedPath() + "?_foreign_keys=on"
+}
+
+var identifierExp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+// ValidIdentifier returns whether name is a plain, optionally qualified,
+// sql identifier eg. "user_id" or "foo.user_id" that is safe to place
+// within a query unquoted
+func ValidIdentifier(name string) bool {
+	return identifierExp.MatchString(name)
+}
+
+// QuoteIdentifier quotes every dot separated part of name, escaping
+// quote characters within it, so it can't be used for sql injection
+func (d Dialect) QuoteIdentifier(name string) string {
+	quote := `"`
+
+	if d.Name == Mysql {
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+
+	for i, p := range parts {
+		parts[i] = quote + strings.Replace(p, quote, quote+quote, -1) + quote
+	}
+
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,121 @@
+package dbutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestDSN(t *testing.T) {
+	dbConfig := confutil.Database{
+		DBName:   "app",
+		User:     "user",
+		Password: "pass",
+		Host:     "localhost",
+		Port:     "5432",
+		SSLMode:  dbutil.SSLRequire,
+	}
+
+	tests := []struct {
+		dbType   string
+		config   confutil.Database
+		expected string
+	}{
+		{
+			dbType:   dbutil.Postgres,
+			config:   dbConfig,
+			expected: "host=localhost user=user password=pass dbname=app port=5432 sslmode=require",
+		},
+		{
+			dbType:   dbutil.Mysql,
+			config:   confutil.Database{DBName: "app", User: "user", Password: "pass", Host: "localhost"},
+			expected: "user:pass@tcp(localhost:3306)/app?parseTime=true",
+		},
+		{
+			dbType:   dbutil.Mysql,
+			config:   dbConfig,
+			expected: "user:pass@tcp(localhost:5432)/app?parseTime=true&tls=skip-verify",
+		},
+		{
+			dbType: dbutil.Postgres,
+			config: confutil.Database{
+				DBName:           "app",
+				Host:             "localhost",
+				StatementTimeout: confutil.Duration{Duration: time.Second * 5},
+				LockTimeout:      confutil.Duration{Duration: time.Second},
+				ApplicationName:  "it's app",
+			},
+			expected: "host=localhost user= password= dbname=app port= sslmode= statement_timeout=5000 lock_timeout=1000 application_name='it\\'s app'",
+		},
+		{
+			dbType: dbutil.Mysql,
+			config: confutil.Database{
+				DBName:           "app",
+				User:             "user",
+				Host:             "localhost",
+				StatementTimeout: confutil.Duration{Duration: time.Second * 5},
+				LockTimeout:      confutil.Duration{Duration: time.Millisecond * 1500},
+			},
+			expected: "user:@tcp(localhost:3306)/app?parseTime=true&max_execution_time=5000&innodb_lock_wait_timeout=2",
+		},
+		{
+			dbType:   dbutil.Sqlite,
+			config:   confutil.Database{},
+			expected: dbutil.SqliteMemory,
+		},
+		{
+			dbType:   dbutil.Sqlite,
+			config:   confutil.Database{DBName: "/tmp/app.db"},
+			expected: "file:/tmp/app.db?_foreign_keys=on",
+		},
+	}
+
+	for _, test := range tests {
+		dsn, err := dbutil.DSN(test.config, test.dbType)
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s\n", err)
+		}
+		if dsn != test.expected {
+			t.Errorf("got %s; should be %s\n", dsn, test.expected)
+		}
+	}
+
+	if _, err := dbutil.DSN(dbConfig, "oracle"); err == nil {
+		t.Errorf("should return error for unsupported database\n")
+	}
+}
+
+func TestDialectConcat(t *testing.T) {
+	if c := dbutil.GetDialect(dbutil.Mysql).Concat("'%'", "?"); c != "concat('%', ?)" {
+		t.Errorf("got %s; should be concat('%%', ?)\n", c)
+	}
+	if c := dbutil.GetDialect(dbutil.Sqlite).Concat("'%'", "?"); c != "'%' || ?" {
+		t.Errorf("got %s; should be '%%' || ?\n", c)
+	}
+	if d := dbutil.GetDialect("unknown"); d.Name != dbutil.Postgres {
+		t.Errorf("should default to postgres; got %s\n", d.Name)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	for _, name := range []string{"id", "foo.user_id", "_bar"} {
+		if !dbutil.ValidIdentifier(name) {
+			t.Errorf("%s should be valid identifier\n", name)
+		}
+	}
+	for _, name := range []string{"", "1id", "id; drop table foo", "foo.", "lower(name)"} {
+		if dbutil.ValidIdentifier(name) {
+			t.Errorf("%s should be invalid identifier\n", name)
+		}
+	}
+
+	if q := dbutil.GetDialect(dbutil.Postgres).QuoteIdentifier(`foo.na"me`); q != `"foo"."na""me"` {
+		t.Errorf("got %s\n", q)
+	}
+	if q := dbutil.GetDialect(dbutil.Mysql).QuoteIdentifier("foo.name"); q != "`foo`.`name`" {
+		t.Errorf("got %s\n", q)
+	}
+}
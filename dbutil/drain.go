@@ -0,0 +1,196 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrDBClosed is returned from queries and transactions started on
+	// *DB after DB#Drain or DB#Close is called
+	ErrDBClosed = errors.New("dbutil: database is closed")
+)
+
+// inflight tracks queries and transactions that are in progress so
+// DB#Drain can wait for them
+type inflight struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	idle     chan struct{}
+}
+
+func (i *inflight) acquire() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.draining {
+		return ErrDBClosed
+	}
+
+	i.count++
+	return nil
+}
+
+func (i *inflight) release() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.count--
+
+	if i.count == 0 && i.idle != nil {
+		close(i.idle)
+		i.idle = nil
+	}
+}
+
+// drain stops new acquires and returns channel that is closed once
+// every acquire has been released
+func (i *inflight) drain() <-chan struct{} {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.draining = true
+
+	if i.idle != nil {
+		return i.idle
+	}
+
+	idle := make(chan struct{})
+
+	if i.count == 0 {
+		close(idle)
+	} else {
+		i.idle = idle
+	}
+
+	return idle
+}
+
+// InFlight returns number of queries and transactions in progress
+// Rows of queries are in progress until they are exhausted or closed
+func (db *DB) InFlight() int {
+	db.queries.mu.Lock()
+	defer db.queries.mu.Unlock()
+	return db.queries.count
+}
+
+// Drain stops db from accepting new queries and transactions, which
+// return ErrDBClosed, waits for the ones in progress to finish or ctx to
+// be done, then closes db
+//
+// Returns ctx.Err() if ctx is done before every query finished, in
+// which case db is still closed
+//
+// Drain should be called after http.Server#Shutdown so requests that
+// are still running are able to commit their transactions
+func (db *DB) Drain(ctx context.Context) error {
+	var err error
+
+	select {
+	case <-db.queries.drain():
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if closeErr := db.DB.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	return err
+}
+
+// Close stops db from accepting new queries and closes it without
+// waiting for queries in progress
+// Use Drain to wait for them
+func (db *DB) Close() error {
+	db.queries.drain()
+	return db.DB.Close()
+}
+
+// Exec is wrapper for sqlx.DB.Exec
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	defer db.queries.release()
+	return db.DB.Exec(query, args...)
+}
+
+// Get is wrapper for sqlx.DB.Get
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	if err := db.queries.acquire(); err != nil {
+		return err
+	}
+
+	defer db.queries.release()
+	return db.DB.Get(dest, query, args...)
+}
+
+// Select is wrapper for sqlx.DB.Select
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	if err := db.queries.acquire(); err != nil {
+		return err
+	}
+
+	defer db.queries.release()
+	return db.DB.Select(dest, query, args...)
+}
+
+// trackRows returns rower that releases its acquire once it is
+// exhausted or closed
+func (db *DB) trackRows(rows *sql.Rows, err error) (httputil.Rower, error) {
+	if err != nil {
+		db.queries.release()
+		return nil, err
+	}
+
+	return &trackedRows{Rows: rows, release: db.queries.release}, nil
+}
+
+type trackedRows struct {
+	*sql.Rows
+	release func()
+	once    sync.Once
+}
+
+func (t *trackedRows) Next() bool {
+	if t.Rows.Next() {
+		return true
+	}
+
+	t.once.Do(t.release)
+	return false
+}
+
+func (t *trackedRows) Close() error {
+	err := t.Rows.Close()
+	t.once.Do(t.release)
+	return err
+}
+
+// trackedRow releases its acquire once it is scanned
+type trackedRow struct {
+	*sql.Row
+	release func()
+	once    sync.Once
+}
+
+func (t *trackedRow) Scan(dest ...interface{}) error {
+	defer t.once.Do(t.release)
+	return t.Row.Scan(dest...)
+}
+
+// errScanner is returned from QueryRow when query can't be started
+type errScanner struct {
+	err error
+}
+
+func (e errScanner) Scan(dest ...interface{}) error {
+	return e.err
+}
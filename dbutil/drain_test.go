@@ -0,0 +1,133 @@
+package dbutil_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func init() {
+	sql.Register("draintest", drainDriver{})
+}
+
+// drainDriver is database driver where every query succeeds with no rows
+type drainDriver struct{}
+
+func (drainDriver) Open(name string) (driver.Conn, error) {
+	return drainConn{}, nil
+}
+
+type drainConn struct{}
+
+func (drainConn) Prepare(query string) (driver.Stmt, error) { return drainStmt{}, nil }
+func (drainConn) Close() error                              { return nil }
+func (drainConn) Begin() (driver.Tx, error)                 { return drainTx{}, nil }
+
+type drainTx struct{}
+
+func (drainTx) Commit() error   { return nil }
+func (drainTx) Rollback() error { return nil }
+
+type drainStmt struct{}
+
+func (drainStmt) Close() error  { return nil }
+func (drainStmt) NumInput() int { return -1 }
+func (drainStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (drainStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return drainRows{}, nil
+}
+
+type drainRows struct{}
+
+func (drainRows) Columns() []string              { return []string{"id"} }
+func (drainRows) Close() error                   { return nil }
+func (drainRows) Next(dest []driver.Value) error { return io.EOF }
+
+func newDrainDB(t *testing.T) *dbutil.DB {
+	sqlDB, err := sql.Open("draintest", "")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	return &dbutil.DB{DB: sqlx.NewDb(sqlDB, "draintest")}
+}
+
+func TestDBDrain(t *testing.T) {
+	db := newDrainDB(t)
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	rower, err := db.Query("select id from item")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if db.InFlight() != 2 {
+		t.Errorf("should have 2 in flight; got %d", db.InFlight())
+	}
+
+	for rower.Next() {
+	}
+
+	drained := make(chan error)
+
+	go func() {
+		drained <- db.Drain(context.Background())
+	}()
+
+	// Wait for drain to start so new queries are rejected
+	for i := 0; i < 100; i++ {
+		if _, err = db.Exec("select 1"); err == dbutil.ErrDBClosed {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if err != dbutil.ErrDBClosed {
+		t.Fatalf("should return ErrDBClosed; got %v", err)
+	}
+
+	select {
+	case <-drained:
+		t.Fatalf("should wait for transaction in progress")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	if _, err = tx.Exec("insert into item (id) values (1)"); err != nil {
+		t.Errorf("should let transaction in progress finish; got %s", err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		t.Errorf("should not have error; got %s", err.Error())
+	}
+
+	if err = <-drained; err != nil {
+		t.Errorf("should drain; got %s", err.Error())
+	}
+
+	db = newDrainDB(t)
+
+	if _, err = db.Begin(); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if err = db.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("should return context.DeadlineExceeded; got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package dbutil
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxFailoverEvents is max number of events kept by RecentFailovers
+var MaxFailoverEvents = 50
+
+// FailoverEvent is recorded every time DB#RecoverError finds the
+// current database down
+type FailoverEvent struct {
+	Time   time.Time `json:"time"`
+	DBType string    `json:"dbType"`
+
+	// From is host of database that was down
+	From string `json:"from"`
+
+	// To is host of database failed over to, which is empty if no
+	// database could be connected to
+	To string `json:"to"`
+
+	// Err is error returned from RecoverError, if any
+	Err string `json:"err,omitempty"`
+}
+
+var failovers struct {
+	mu     sync.Mutex
+	events []FailoverEvent
+}
+
+// RecentFailovers returns up to MaxFailoverEvents of the most recent
+// failover events, oldest first
+func RecentFailovers() []FailoverEvent {
+	failovers.mu.Lock()
+	defer failovers.mu.Unlock()
+
+	events := make([]FailoverEvent, len(failovers.events))
+	copy(events, failovers.events)
+	return events
+}
+
+func recordFailover(event FailoverEvent) {
+	failovers.mu.Lock()
+	defer failovers.mu.Unlock()
+
+	failovers.events = append(failovers.events, event)
+
+	if over := len(failovers.events) - MaxFailoverEvents; over > 0 {
+		failovers.events = append([]FailoverEvent(nil), failovers.events[over:]...)
+	}
+}
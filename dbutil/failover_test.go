@@ -0,0 +1,24 @@
+package dbutil
+
+import (
+	"testing"
+)
+
+func TestRecentFailovers(t *testing.T) {
+	defer func(max int) {
+		MaxFailoverEvents = max
+		failovers.events = nil
+	}(MaxFailoverEvents)
+
+	MaxFailoverEvents = 2
+
+	for _, host := range []string{"a", "b", "c"} {
+		recordFailover(FailoverEvent{From: host})
+	}
+
+	events := RecentFailovers()
+
+	if len(events) != 2 || events[0].From != "b" || events[1].From != "c" {
+		t.Errorf("should keep most recent events; got %v", events)
+	}
+}
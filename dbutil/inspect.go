@@ -0,0 +1,383 @@
+package dbutil
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	inspectColumnsQuery = `
+	select
+		c.table_name,
+		c.column_name,
+		c.data_type,
+		c.is_nullable,
+		c.column_default,
+		c.character_maximum_length
+	from
+		information_schema.columns c
+	join
+		information_schema.tables t on t.table_schema = c.table_schema
+		and t.table_name = c.table_name
+	where
+		c.table_schema = current_schema()
+	and
+		t.table_type = 'BASE TABLE'
+	order by
+		c.table_name, c.ordinal_position`
+
+	inspectIndexesQuery = `
+	select
+		tablename,
+		indexname,
+		indexdef
+	from
+		pg_indexes
+	where
+		schemaname = current_schema()
+	order by
+		tablename, indexname`
+
+	inspectPrimaryKeysQuery = `
+	select
+		table_name,
+		constraint_name
+	from
+		information_schema.table_constraints
+	where
+		table_schema = current_schema()
+	and
+		constraint_type = 'PRIMARY KEY'`
+)
+
+const (
+	// SchemaAdded is SchemaChange#Kind of table, column or index that's
+	// only in the inspected schema
+	SchemaAdded = "added"
+
+	// SchemaRemoved is SchemaChange#Kind of table, column or index that's
+	// only in the expected schema
+	SchemaRemoved = "removed"
+
+	// SchemaChanged is SchemaChange#Kind of column or index whose
+	// definition differs between schemas
+	SchemaChanged = "changed"
+)
+
+// Index is metadata of table index returned from Inspect
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+}
+
+// Table is metadata of table returned from Inspect
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	Indexes []Index  `json:"indexes"`
+}
+
+// Column returns column of t with name
+func (t Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return Column{}, false
+}
+
+// PrimaryKey returns columns of primary key of t, if any
+func (t Table) PrimaryKey() []string {
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			return idx.Columns
+		}
+	}
+
+	return nil
+}
+
+// Schema is metadata of every table of database returned from Inspect
+type Schema struct {
+	Tables []Table `json:"tables"`
+}
+
+// Table returns table of s with name
+func (s *Schema) Table(name string) (Table, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return Table{}, false
+}
+
+// SchemaChange is difference between schemas returned from Schema#Diff
+type SchemaChange struct {
+	// Kind is SchemaAdded, SchemaRemoved or SchemaChanged
+	Kind string `json:"kind"`
+
+	Table string `json:"table"`
+
+	// Column is column that changed, if any
+	Column string `json:"column,omitempty"`
+
+	// Index is index that changed, if any
+	Index string `json:"index,omitempty"`
+}
+
+func (s SchemaChange) String() string {
+	switch {
+	case s.Column != "":
+		return fmt.Sprintf("column %s.%s %s", s.Table, s.Column, s.Kind)
+	case s.Index != "":
+		return fmt.Sprintf("index %s.%s %s", s.Table, s.Index, s.Kind)
+	}
+
+	return fmt.Sprintf("table %s %s", s.Table, s.Kind)
+}
+
+// Diff returns changes of s compared to expected eg. tables that are in
+// s but not expected are SchemaAdded
+func (s *Schema) Diff(expected *Schema) []SchemaChange {
+	var changes []SchemaChange
+
+	for _, t := range s.Tables {
+		et, ok := expected.Table(t.Name)
+
+		if !ok {
+			changes = append(changes, SchemaChange{Kind: SchemaAdded, Table: t.Name})
+			continue
+		}
+
+		for _, c := range t.Columns {
+			ec, ok := et.Column(c.Name)
+
+			switch {
+			case !ok:
+				changes = append(changes, SchemaChange{Kind: SchemaAdded, Table: t.Name, Column: c.Name})
+			case ec != c:
+				changes = append(changes, SchemaChange{Kind: SchemaChanged, Table: t.Name, Column: c.Name})
+			}
+		}
+		for _, c := range et.Columns {
+			if _, ok := t.Column(c.Name); !ok {
+				changes = append(changes, SchemaChange{Kind: SchemaRemoved, Table: t.Name, Column: c.Name})
+			}
+		}
+
+		changes = append(changes, diffIndexes(t.Name, t.Indexes, et.Indexes)...)
+	}
+
+	for _, t := range expected.Tables {
+		if _, ok := s.Table(t.Name); !ok {
+			changes = append(changes, SchemaChange{Kind: SchemaRemoved, Table: t.Name})
+		}
+	}
+
+	return changes
+}
+
+func diffIndexes(table string, indexes, expected []Index) []SchemaChange {
+	var changes []SchemaChange
+
+	byName := make(map[string]Index, len(expected))
+
+	for _, idx := range expected {
+		byName[idx.Name] = idx
+	}
+
+	for _, idx := range indexes {
+		e, ok := byName[idx.Name]
+
+		switch {
+		case !ok:
+			changes = append(changes, SchemaChange{Kind: SchemaAdded, Table: table, Index: idx.Name})
+		case e.Unique != idx.Unique || e.Primary != idx.Primary ||
+			strings.Join(e.Columns, ",") != strings.Join(idx.Columns, ","):
+			changes = append(changes, SchemaChange{Kind: SchemaChanged, Table: table, Index: idx.Name})
+		}
+
+		delete(byName, idx.Name)
+	}
+
+	removed := make([]string, 0, len(byName))
+
+	for name := range byName {
+		removed = append(removed, name)
+	}
+
+	sort.Strings(removed)
+
+	for _, name := range removed {
+		changes = append(changes, SchemaChange{Kind: SchemaRemoved, Table: table, Index: name})
+	}
+
+	return changes
+}
+
+// Inspect returns tables, columns and indexes of current schema of
+// postgres or cockroach database db, ordered by table name
+//
+// Columns are in the order they're defined and indexes are ordered by
+// name, where columns of expression indexes are their expressions
+func Inspect(db httputil.Querier) (*Schema, error) {
+	rows, err := db.Query(inspectColumnsQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{}
+	tables := make(map[string]int)
+
+	for rows.Next() {
+		var table string
+		var col Column
+		var nullable string
+		var def sql.NullString
+		var maxLength sql.NullInt64
+
+		if err = rows.Scan(&table, &col.Name, &col.DataType, &nullable, &def, &maxLength); err != nil {
+			return nil, err
+		}
+
+		col.DataType = strings.ToLower(col.DataType)
+		col.Nullable = strings.EqualFold(nullable, "yes")
+		col.HasDefault = def.Valid
+		col.MaxLength = int(maxLength.Int64)
+
+		i, ok := tables[table]
+
+		if !ok {
+			i = len(schema.Tables)
+			tables[table] = i
+			schema.Tables = append(schema.Tables, Table{Name: table})
+		}
+
+		schema.Tables[i].Columns = append(schema.Tables[i].Columns, col)
+	}
+
+	if err = RowerErr(rows); err != nil {
+		return nil, err
+	}
+
+	if rows, err = db.Query(inspectPrimaryKeysQuery); err != nil {
+		return nil, err
+	}
+
+	primaryKeys := make(map[string]string)
+
+	for rows.Next() {
+		var table, name string
+
+		if err = rows.Scan(&table, &name); err != nil {
+			return nil, err
+		}
+
+		primaryKeys[table] = name
+	}
+
+	if err = RowerErr(rows); err != nil {
+		return nil, err
+	}
+
+	if rows, err = db.Query(inspectIndexesQuery); err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var table, name, def string
+
+		if err = rows.Scan(&table, &name, &def); err != nil {
+			return nil, err
+		}
+
+		i, ok := tables[table]
+
+		if !ok {
+			continue
+		}
+
+		idx := parseIndexDef(def)
+		idx.Name = name
+		idx.Primary = primaryKeys[table] == name
+
+		// Cockroach names primary index "primary" regardless of the
+		// name of its constraint
+		if name == "primary" && primaryKeys[table] != "" {
+			idx.Primary = true
+		}
+		if idx.Primary {
+			idx.Unique = true
+		}
+
+		schema.Tables[i].Indexes = append(schema.Tables[i].Indexes, idx)
+	}
+
+	if err = RowerErr(rows); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(schema.Tables, func(i, j int) bool {
+		return schema.Tables[i].Name < schema.Tables[j].Name
+	})
+
+	return schema, nil
+}
+
+// parseIndexDef returns whether index of def, as returned from
+// pg_indexes, is unique along with its columns eg.
+// "CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email)"
+func parseIndexDef(def string) Index {
+	idx := Index{Unique: strings.HasPrefix(strings.ToUpper(def), "CREATE UNIQUE")}
+	start := strings.Index(def, "(")
+
+	if start == -1 {
+		return idx
+	}
+
+	depth := 0
+	part := start + 1
+
+	for i := start; i < len(def); i++ {
+		switch def[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+
+			if depth == 0 {
+				idx.Columns = append(idx.Columns, indexColumn(def[part:i]))
+				return idx
+			}
+		case ',':
+			if depth == 1 {
+				idx.Columns = append(idx.Columns, indexColumn(def[part:i]))
+				part = i + 1
+			}
+		}
+	}
+
+	return idx
+}
+
+// indexColumn trims sort order and quotes from column of index
+func indexColumn(col string) string {
+	col = strings.TrimSpace(col)
+
+	for _, suffix := range []string{" ASC", " DESC"} {
+		col = strings.TrimSuffix(col, suffix)
+	}
+
+	return strings.Trim(col, `"`)
+}
@@ -0,0 +1,74 @@
+package dbutil_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestInspect(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectQuery("information_schema.columns").WillReturnRows(
+		dbtest.NewRows("table_name", "column_name", "data_type", "is_nullable", "column_default", "character_maximum_length").
+			AddRow("users", "id", "bigint", "NO", "unique_rowid()", nil).
+			AddRow("users", "email", "character varying", "NO", nil, 255).
+			AddRow("items", "id", "bigint", "NO", nil, nil),
+	)
+	db.ExpectQuery("information_schema.table_constraints").WillReturnRows(
+		dbtest.NewRows("table_name", "constraint_name").
+			AddRow("users", "users_pkey"),
+	)
+	db.ExpectQuery("pg_indexes").WillReturnRows(
+		dbtest.NewRows("tablename", "indexname", "indexdef").
+			AddRow("users", "users_email_key", `CREATE UNIQUE INDEX users_email_key ON public.users USING btree (lower(email) ASC, "id" DESC)`).
+			AddRow("users", "users_pkey", "CREATE UNIQUE INDEX users_pkey ON public.users USING btree (id)"),
+	)
+
+	schema, err := dbutil.Inspect(db)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := &dbutil.Schema{
+		Tables: []dbutil.Table{
+			{
+				Name:    "items",
+				Columns: []dbutil.Column{{Name: "id", DataType: "bigint"}},
+			},
+			{
+				Name: "users",
+				Columns: []dbutil.Column{
+					{Name: "id", DataType: "bigint", HasDefault: true},
+					{Name: "email", DataType: "character varying", MaxLength: 255},
+				},
+				Indexes: []dbutil.Index{
+					{Name: "users_email_key", Columns: []string{"lower(email)", "id"}, Unique: true},
+					{Name: "users_pkey", Columns: []string{"id"}, Unique: true, Primary: true},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("got schema %+v; want %+v", schema, expected)
+	}
+
+	expected.Tables = expected.Tables[1:]
+	expected.Tables[0].Columns = expected.Tables[0].Columns[:1]
+	expected.Tables[0].Indexes = []dbutil.Index{{Name: "users_pkey", Columns: []string{"email"}, Primary: true}}
+
+	changes := schema.Diff(expected)
+	expectedChanges := []dbutil.SchemaChange{
+		{Kind: dbutil.SchemaAdded, Table: "items"},
+		{Kind: dbutil.SchemaAdded, Table: "users", Column: "email"},
+		{Kind: dbutil.SchemaAdded, Table: "users", Index: "users_email_key"},
+		{Kind: dbutil.SchemaChanged, Table: "users", Index: "users_pkey"},
+	}
+
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("got changes %v; want %v", changes, expectedChanges)
+	}
+}
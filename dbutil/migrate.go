@@ -0,0 +1,431 @@
+package dbutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/TravisS25/httputil"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultMigrationTable is table used to keep track of applied
+	// migrations if MigrateConfig#TableName is not set
+	DefaultMigrationTable = "schema_migrations"
+)
+
+const (
+	migrationTableQuery = `
+	create table if not exists %s (
+		version bigint not null primary key,
+		dirty boolean not null,
+		applied_at timestamp not null default current_timestamp
+	)`
+)
+
+var (
+	// ErrDirtyMigration is returned when a previous migration failed part
+	// way through and the database has to be fixed manually before
+	// running migrations again
+	ErrDirtyMigration = errors.New("dbutil: database has dirty migration, fix and clean manually")
+
+	// ErrMissingDownMigration is returned when trying to roll back a
+	// migration that has no down file
+	ErrMissingDownMigration = errors.New("dbutil: migration has no down file")
+
+	// ErrNoSchemaFile is returned from DetectDrift if
+	// MigrateConfig#SchemaFile is not set
+	ErrNoSchemaFile = errors.New("dbutil: migrate config has no schema file")
+
+	migrationFileExp = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration is a single versioned migration read from a migration directory
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// MigrateConfig is config struct used in conjunction with Migrate and MigrateDown
+type MigrateConfig struct {
+	// Dir is directory that contains migration files
+	// Files should be named "<version>_<name>.up.sql" and
+	// "<version>_<name>.down.sql" eg. "0001_create_users.up.sql"
+	Dir string
+
+	// TableName is table used to store applied migrations
+	// Default is DefaultMigrationTable
+	TableName string
+
+	// DBType is the type of database being migrated eg. Postgres
+	// This is used for placeholder binding and to determine if
+	// migrations can be applied within a transaction
+	DBType string
+
+	// DisableTransaction will apply migrations without a transaction
+	// even if the database supports transactional ddl
+	DisableTransaction bool
+
+	// SchemaFile, if set, is json file the schema of the database, as
+	// returned from Inspect, is written to after Migrate or MigrateDown
+	// succeeds, which DetectDrift compares the database against to find
+	// changes made outside of migrations
+	// Only postgres and cockroach are supported
+	SchemaFile string
+}
+
+func (m *MigrateConfig) setDefaults() {
+	if m.TableName == "" {
+		m.TableName = DefaultMigrationTable
+	}
+	if m.DBType == "" {
+		m.DBType = Postgres
+	}
+}
+
+// useTransaction returns whether ddl statements can be rolled back
+// Mysql implicitly commits on ddl so a transaction gives no protection
+func (m MigrateConfig) useTransaction() bool {
+	return !m.DisableTransaction && m.DBType != Mysql
+}
+
+// LoadMigrations reads every migration file within dir and returns
+// migrations sorted by version
+func LoadMigrations(dir string) ([]Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migrationMap := make(map[int64]*Migration)
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		matches := migrationFileExp.FindStringSubmatch(f.Name())
+
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		source, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := migrationMap[version]
+
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			migrationMap[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("dbutil: duplicate migration version %d", version)
+		}
+
+		if matches[3] == "up" {
+			m.UpSQL = string(source)
+		} else {
+			m.DownSQL = string(source)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(migrationMap))
+
+	for _, v := range migrationMap {
+		migrations = append(migrations, *v)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// AppliedMigrations returns map of every applied migration version and
+// whether that version is dirty
+func AppliedMigrations(db httputil.Querier, config MigrateConfig) (map[int64]bool, error) {
+	config.setDefaults()
+	rower, err := db.Query(fmt.Sprintf("select version, dirty from %s", config.TableName))
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]bool)
+
+	for rower.Next() {
+		var version int64
+		var dirty bool
+
+		if err = rower.Scan(&version, &dirty); err != nil {
+			return nil, err
+		}
+
+		applied[version] = dirty
+	}
+
+	return applied, nil
+}
+
+// Migrate applies every migration within MigrateConfig#Dir that has not
+// been applied yet, in order of version, and returns the number applied
+//
+// Each migration is recorded in MigrateConfig#TableName; if a migration
+// fails while not in a transaction, it is left marked as dirty and every
+// call after will return ErrDirtyMigration until it is fixed manually
+func Migrate(db httputil.DBInterface, config MigrateConfig) (int, error) {
+	config.setDefaults()
+
+	migrations, err := LoadMigrations(config.Dir)
+
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := prepareMigrations(db, config)
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		if err = applyMigration(db, config, m.Version, m.UpSQL, true); err != nil {
+			return count, fmt.Errorf("dbutil: migration %d_%s failed: %s", m.Version, m.Name, err.Error())
+		}
+
+		count++
+	}
+
+	return count, writeSchemaFile(db, config)
+}
+
+// MigrateDown rolls back the given number of most recently applied
+// migrations and returns the number rolled back
+func MigrateDown(db httputil.DBInterface, config MigrateConfig, steps int) (int, error) {
+	config.setDefaults()
+
+	migrations, err := LoadMigrations(config.Dir)
+
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := prepareMigrations(db, config)
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if m.DownSQL == "" {
+			return count, ErrMissingDownMigration
+		}
+
+		if err = applyMigration(db, config, m.Version, m.DownSQL, false); err != nil {
+			return count, fmt.Errorf("dbutil: rollback %d_%s failed: %s", m.Version, m.Name, err.Error())
+		}
+
+		count++
+	}
+
+	return count, writeSchemaFile(db, config)
+}
+
+// DetectDrift returns changes of schema of db compared to
+// MigrateConfig#SchemaFile, which are changes made outside of
+// migrations since Migrate or MigrateDown last ran
+// Returns ErrNoSchemaFile if MigrateConfig#SchemaFile is not set
+func DetectDrift(db httputil.Querier, config MigrateConfig) ([]SchemaChange, error) {
+	if config.SchemaFile == "" {
+		return nil, ErrNoSchemaFile
+	}
+
+	b, err := ioutil.ReadFile(config.SchemaFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expected Schema
+
+	if err = json.Unmarshal(b, &expected); err != nil {
+		return nil, err
+	}
+
+	schema, err := Inspect(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.Diff(&expected), nil
+}
+
+func writeSchemaFile(db httputil.Querier, config MigrateConfig) error {
+	if config.SchemaFile == "" {
+		return nil
+	}
+
+	schema, err := Inspect(db)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(schema, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(config.SchemaFile, b, 0644)
+}
+
+// prepareMigrations creates migration table if it does not exist and
+// returns applied migrations or ErrDirtyMigration if any are dirty
+func prepareMigrations(db httputil.DBInterface, config MigrateConfig) (map[int64]bool, error) {
+	if _, err := db.Exec(fmt.Sprintf(migrationTableQuery, config.TableName)); err != nil {
+		return nil, err
+	}
+
+	applied, err := AppliedMigrations(db, config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dirty := range applied {
+		if dirty {
+			return nil, ErrDirtyMigration
+		}
+	}
+
+	return applied, nil
+}
+
+func applyMigration(db httputil.DBInterface, config MigrateConfig, version int64, query string, up bool) error {
+	var err error
+
+	bindVar := sqlx.BindType(config.DBType)
+	insertQuery := sqlx.Rebind(
+		bindVar,
+		fmt.Sprintf("insert into %s (version, dirty) values (?, ?)", config.TableName),
+	)
+	updateQuery := sqlx.Rebind(
+		bindVar,
+		fmt.Sprintf("update %s set dirty = ? where version = ?", config.TableName),
+	)
+	deleteQuery := sqlx.Rebind(
+		bindVar,
+		fmt.Sprintf("delete from %s where version = ?", config.TableName),
+	)
+
+	if config.useTransaction() {
+		var tx httputil.Tx
+
+		if tx, err = db.Begin(); err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if up {
+			_, err = tx.Exec(insertQuery, version, false)
+		} else {
+			_, err = tx.Exec(deleteQuery, version)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	// Without a transaction, mark the version as dirty first so a
+	// failure part way through is detected on the next run
+	if up {
+		_, err = db.Exec(insertQuery, version, true)
+	} else {
+		_, err = db.Exec(updateQuery, true, version)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(query); err != nil {
+		return err
+	}
+
+	if up {
+		_, err = db.Exec(updateQuery, false, version)
+	} else {
+		_, err = db.Exec(deleteQuery, version)
+	}
+
+	return err
+}
+
+// MigrationVersion returns the highest applied migration version and
+// whether it is dirty
+// Returns sql.ErrNoRows if no migrations have been applied
+func MigrationVersion(db httputil.Querier, config MigrateConfig) (int64, bool, error) {
+	applied, err := AppliedMigrations(db, config)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(applied) == 0 {
+		return 0, false, sql.ErrNoRows
+	}
+
+	var version int64
+
+	for k := range applied {
+		if k > version {
+			version = k
+		}
+	}
+
+	return version, applied[version], nil
+}
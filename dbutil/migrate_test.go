@@ -0,0 +1,50 @@
+package dbutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"0002_add_email.up.sql":      "alter table users add column email text;",
+		"0002_add_email.down.sql":    "alter table users drop column email;",
+		"0001_create_users.up.sql":   "create table users (id serial);",
+		"0001_create_users.down.sql": "drop table users;",
+		"README.md":                  "not a migration",
+	}
+
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	migrations, err := LoadMigrations(dir)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("should have 2 migrations; got %d\n", len(migrations))
+	}
+
+	if migrations[0].Version != 1 || migrations[0].Name != "create_users" {
+		t.Errorf("migrations not sorted by version: %v\n", migrations)
+	}
+
+	if migrations[1].DownSQL != files["0002_add_email.down.sql"] {
+		t.Errorf("down sql not loaded properly: %s\n", migrations[1].DownSQL)
+	}
+}
@@ -0,0 +1,131 @@
+package dbutil
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+)
+
+// queryLogCtxKey is key used to store *QueryLog within context
+type queryLogCtxKey struct{}
+
+// QueryEntry is query executed by database returned from WithContext
+type QueryEntry struct {
+	Query    string        `json:"query"`
+	Args     []interface{} `json:"args"`
+	Duration time.Duration `json:"duration"`
+
+	// Tag is tag of context of query, see WithQueryTag
+	Tag string `json:"tag,omitempty"`
+}
+
+// QueryLog records queries executed by database returned from
+// WithContext when its context is from WithQueryLog, which allows
+// middleware to find the queries, including ones generated by
+// queryutil, that made a request slow or fail
+type QueryLog struct {
+	mu      sync.Mutex
+	entries []QueryEntry
+}
+
+// WithQueryLog returns copy of ctx with a new *QueryLog
+func WithQueryLog(ctx context.Context) (context.Context, *QueryLog) {
+	log := &QueryLog{}
+	return context.WithValue(ctx, queryLogCtxKey{}, log), log
+}
+
+// GetQueryLog returns *QueryLog of ctx, else nil
+func GetQueryLog(ctx context.Context) *QueryLog {
+	log, _ := ctx.Value(queryLogCtxKey{}).(*QueryLog)
+	return log
+}
+
+// Entries returns copy of queries recorded so far in the order they
+// were executed
+func (q *QueryLog) Entries() []QueryEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := make([]QueryEntry, len(q.entries))
+	copy(entries, q.entries)
+	return entries
+}
+
+// observeQuery records query to *QueryLog of ctx, if any, with the
+// time since start
+func observeQuery(ctx context.Context, start time.Time, query string, args []interface{}) {
+	log := GetQueryLog(ctx)
+
+	if log == nil {
+		return
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	log.entries = append(log.entries, QueryEntry{
+		Query:    query,
+		Args:     args,
+		Duration: time.Since(start),
+		Tag:      GetQueryTag(ctx),
+	})
+}
+
+// queryTagCtxKey is key used to store tag of queries within context
+type queryTagCtxKey struct{}
+
+// WithQueryTag returns copy of ctx with tag, eg. name of route of
+// request, which database returned from WithContext adds to queries as
+// sql comment and, for postgres transactions, to application_name so
+// queries can be attributed within pg_stat_activity and slow query logs
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagCtxKey{}, tag)
+}
+
+// GetQueryTag returns tag of ctx, else empty string
+func GetQueryTag(ctx context.Context) string {
+	tag, _ := ctx.Value(queryTagCtxKey{}).(string)
+	return tag
+}
+
+var queryTagReplacer = strings.NewReplacer("*/", "", "/*", "", "\n", " ")
+
+// tagQuery prefixes query with comment of tag of ctx, if any
+func tagQuery(ctx context.Context, query string) string {
+	tag := GetQueryTag(ctx)
+
+	if tag == "" {
+		return query
+	}
+
+	return "/* " + queryTagReplacer.Replace(tag) + " */ " + query
+}
+
+// tagTx sets application_name of postgres transaction tx to application
+// name of db followed by tag of ctx, if any, for the rest of tx
+func tagTx(ctx context.Context, db interface{}, tx httputil.Tx) error {
+	tag := GetQueryTag(ctx)
+
+	if tag == "" {
+		return nil
+	}
+	if ext, ok := tx.(SqlxExt); !ok || ext.DriverName() != Postgres {
+		return nil
+	}
+
+	if n, ok := db.(interface{ ApplicationName() string }); ok && n.ApplicationName() != "" {
+		tag = n.ApplicationName() + ":" + tag
+	}
+
+	_, err := tx.Exec(
+		sqlx.Rebind(BindVarOf(tx), "select set_config('application_name', ?, true)"),
+		tag,
+	)
+
+	return err
+}
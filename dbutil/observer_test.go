@@ -0,0 +1,56 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestQueryLog(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec("insert into item").WillReturnResult(dbtest.NewResult(1, 1))
+	db.ExpectQuery("select id from item").WillReturnRows(dbtest.NewRows("id"))
+
+	ctx, log := dbutil.WithQueryLog(context.Background())
+	ctxDB := dbutil.WithContext(ctx, db)
+
+	if _, err := ctxDB.Exec("insert into item (name) values (?)", "foo"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if _, err := dbutil.QueryWithTimeout(ctxDB, 0, "select id from item"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	entries := log.Entries()
+
+	if len(entries) != 2 {
+		t.Fatalf("should record 2 queries; got %d", len(entries))
+	}
+	if entries[0].Query != "insert into item (name) values (?)" || entries[0].Args[0] != "foo" {
+		t.Errorf("should record query and args; got %v", entries[0])
+	}
+	if dbutil.GetQueryLog(context.Background()) != nil {
+		t.Errorf("should not have query log")
+	}
+}
+
+func TestQueryTag(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec(`^/\* item-update \*/ update item`).WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`^update item`).WillReturnResult(dbtest.NewResult(0, 1))
+
+	ctx, log := dbutil.WithQueryLog(dbutil.WithQueryTag(context.Background(), "item-update*/"))
+
+	if _, err := dbutil.WithContext(ctx, db).Exec("update item set name = ?", "foo"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if _, err := dbutil.WithContext(context.Background(), db).Exec("update item set name = ?", "foo"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if entries := log.Entries(); len(entries) != 1 || entries[0].Tag != "item-update*/" {
+		t.Errorf("should record tag of query; got %v", entries)
+	}
+}
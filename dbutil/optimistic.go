@@ -0,0 +1,124 @@
+package dbutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+	"github.com/jmoiron/sqlx"
+)
+
+// StaleVersionError is returned from UpdateWithVersion when the row
+// was modified by someone else since it was read
+type StaleVersionError struct {
+	Table   string
+	ID      interface{}
+	Version int64
+}
+
+func (s *StaleVersionError) Error() string {
+	return fmt.Sprintf("dbutil: stale version %d of %s with id %v", s.Version, s.Table, s.ID)
+}
+
+// VersionUpdate is config struct used in conjunction with UpdateWithVersion
+type VersionUpdate struct {
+	// Table is table to update - Required
+	Table string
+
+	// IDColumn is primary key column of Table
+	// Default is "id"
+	IDColumn string
+
+	// VersionColumn is column used to store version of row
+	// Default is "version"
+	VersionColumn string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding
+	// Default is Postgres
+	DBType string
+
+	// ID is primary key of row to update
+	ID interface{}
+
+	// Version is version of row when it was read by the client
+	Version int64
+
+	// Values is map of column to value to update
+	Values map[string]interface{}
+}
+
+// UpdateWithVersion updates row of update with optimistic concurrency
+// by building a query like
+//
+// update <table> set <columns>, version = version + 1 where id = ? and version = ?
+//
+// If no rows are affected, either because the version changed or the
+// row no longer exists, *StaleVersionError is returned
+func UpdateWithVersion(db httputil.XODB, update VersionUpdate) error {
+	if update.IDColumn == "" {
+		update.IDColumn = "id"
+	}
+	if update.VersionColumn == "" {
+		update.VersionColumn = "version"
+	}
+	if update.DBType == "" {
+		update.DBType = Postgres
+	}
+
+	// Keep column order deterministic for the generated query
+	columns := make([]string, 0, len(update.Values))
+
+	for k := range update.Values {
+		if k != update.IDColumn && k != update.VersionColumn {
+			columns = append(columns, k)
+		}
+	}
+
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+2)
+
+	for _, c := range columns {
+		sets = append(sets, c+" = ?")
+		args = append(args, update.Values[c])
+	}
+
+	sets = append(sets, fmt.Sprintf("%s = %s + 1", update.VersionColumn, update.VersionColumn))
+	args = append(args, update.ID, update.Version)
+
+	query := sqlx.Rebind(
+		sqlx.BindType(update.DBType),
+		fmt.Sprintf(
+			"update %s set %s where %s = ? and %s = ?",
+			update.Table,
+			strings.Join(sets, ", "),
+			update.IDColumn,
+			update.VersionColumn,
+		),
+	)
+
+	result, err := db.Exec(query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return &StaleVersionError{
+			Table:   update.Table,
+			ID:      update.ID,
+			Version: update.Version,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package dbutil_test
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestUpdateWithVersion(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.QueryMatcher = dbtest.QueryMatcherEqual
+	query := "update item set name = $1, price = $2, version = version + 1 where id = $3 and version = $4"
+
+	db.ExpectExec(query).
+		WithArgs("foo", 10, 1, int64(2)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(query).
+		WithArgs("foo", 10, 1, int64(2)).
+		WillReturnResult(dbtest.NewResult(0, 0))
+
+	update := dbutil.VersionUpdate{
+		Table:   "item",
+		ID:      1,
+		Version: 2,
+		Values: map[string]interface{}{
+			"name":    "foo",
+			"price":   10,
+			"version": 5,
+		},
+	}
+
+	if err := dbutil.UpdateWithVersion(db, update); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	err := dbutil.UpdateWithVersion(db, update)
+
+	if _, ok := err.(*dbutil.StaleVersionError); !ok {
+		t.Errorf("should have stale version error; got %v", err)
+	}
+}
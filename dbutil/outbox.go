@@ -0,0 +1,315 @@
+package dbutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultOutboxTable is table used to store outbox messages if
+	// OutboxConfig#TableName is not set
+	DefaultOutboxTable = "outbox"
+)
+
+const (
+	outboxPostgresTableQuery = `
+	create table if not exists %s (
+		id bigserial not null primary key,
+		topic text not null,
+		payload text not null,
+		attempts int not null default 0,
+		last_error text,
+		created_at timestamp not null default current_timestamp,
+		published_at timestamp
+	)`
+
+	outboxMysqlTableQuery = `
+	create table if not exists %s (
+		id bigint not null auto_increment primary key,
+		topic varchar(255) not null,
+		payload longtext not null,
+		attempts int not null default 0,
+		last_error text,
+		created_at timestamp not null default current_timestamp,
+		published_at timestamp null
+	)`
+)
+
+// OutboxMessage is a single message written by WriteOutbox
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Publisher publishes outbox messages to things like webhooks, task
+// queues or message brokers
+// Messages are published at least once so publishing should be idempotent,
+// eg. by using OutboxMessage#ID as a deduplication key
+type Publisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+// PublisherFunc is function adapter for Publisher
+type PublisherFunc func(ctx context.Context, msg OutboxMessage) error
+
+// Publish calls p(ctx, msg)
+func (p PublisherFunc) Publish(ctx context.Context, msg OutboxMessage) error {
+	return p(ctx, msg)
+}
+
+// OutboxConfig is config struct used in conjunction with WriteOutbox
+// and NewOutboxRelay
+type OutboxConfig struct {
+	// TableName is table used to store outbox messages
+	// Default is DefaultOutboxTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding and table creation
+	// Default is Postgres
+	DBType string
+
+	// BatchSize is max number of messages published per poll
+	// Default is 100
+	BatchSize int
+
+	// PollInterval is how long OutboxRelay#Run waits between polls
+	// when there are no messages
+	// Default is 5 seconds
+	PollInterval time.Duration
+
+	// MaxAttempts is number of failed publishes after which a message
+	// is no longer retried
+	// Default is 0 which retries forever
+	MaxAttempts int
+
+	// PublishRetry is policy used to retry publishes of a message, within
+	// the same poll, that fail with transient errors eg. webhook timeouts
+	// before the message is marked failed
+	// Default is httputil#RetryPolicy defaults
+	PublishRetry httputil.RetryPolicy
+}
+
+func (o *OutboxConfig) setDefaults() {
+	if o.TableName == "" {
+		o.TableName = DefaultOutboxTable
+	}
+	if o.DBType == "" {
+		o.DBType = Postgres
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second * 5
+	}
+}
+
+// CreateOutboxTable creates outbox table if it does not exist
+func CreateOutboxTable(db httputil.XODB, config OutboxConfig) error {
+	config.setDefaults()
+	query := outboxPostgresTableQuery
+
+	if config.DBType == Mysql {
+		query = outboxMysqlTableQuery
+	}
+
+	_, err := db.Exec(fmt.Sprintf(query, config.TableName))
+	return err
+}
+
+// WriteOutbox writes payload for topic to the default outbox table
+// within tx so the message is only stored if the business transaction
+// commits
+// payload of type []byte or string is stored as is, anything else is
+// stored as json
+func WriteOutbox(tx httputil.XODB, topic string, payload interface{}) error {
+	return WriteOutboxWithConfig(tx, OutboxConfig{}, topic, payload)
+}
+
+// WriteOutboxWithConfig is the same as WriteOutbox but allows setting
+// the table and database type
+func WriteOutboxWithConfig(tx httputil.XODB, config OutboxConfig, topic string, payload interface{}) error {
+	var payloadStr string
+
+	config.setDefaults()
+
+	switch v := payload.(type) {
+	case []byte:
+		payloadStr = string(v)
+	case string:
+		payloadStr = v
+	default:
+		b, err := json.Marshal(payload)
+
+		if err != nil {
+			return err
+		}
+
+		payloadStr = string(b)
+	}
+
+	query := sqlx.Rebind(
+		sqlx.BindType(config.DBType),
+		fmt.Sprintf("insert into %s (topic, payload) values (?, ?)", config.TableName),
+	)
+
+	_, err := tx.Exec(query, topic, payloadStr)
+	return err
+}
+
+// OutboxRelay polls unpublished outbox messages and hands them to a
+// Publisher, marking them as published on success so events aren't
+// lost when a transaction commits but an async publish fails
+type OutboxRelay struct {
+	db        httputil.DBInterface
+	publisher Publisher
+	config    OutboxConfig
+}
+
+// NewOutboxRelay returns *OutboxRelay
+func NewOutboxRelay(db httputil.DBInterface, publisher Publisher, config OutboxConfig) *OutboxRelay {
+	config.setDefaults()
+
+	return &OutboxRelay{
+		db:        db,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+// Run calls RelayOnce until ctx is done, waiting OutboxConfig#PollInterval
+// between polls when there was nothing to publish or an error occurred
+// Errors are sent to httputil#Reporter and passed to onErr, which can be nil
+func (o *OutboxRelay) Run(ctx context.Context, onErr func(err error)) error {
+	for {
+		published, err := o.RelayOnce(ctx)
+
+		if err != nil {
+			httputil.CaptureError(ctx, err, map[string]string{"source": "outbox"})
+
+			if onErr != nil {
+				onErr(err)
+			}
+		}
+
+		// Keep draining while there are full batches
+		if err == nil && published >= o.config.BatchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.config.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes a single batch of unpublished messages in order
+// and returns the number of messages published
+//
+// Messages are locked within a transaction while publishing, using
+// "for update skip locked", so multiple relays can run at once
+// A failed publish increments the attempts of the message and is
+// retried on a later poll
+func (o *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	tx, err := o.db.Begin()
+
+	if err != nil {
+		return 0, err
+	}
+
+	bindVar := sqlx.BindType(o.config.DBType)
+	selectQuery := fmt.Sprintf(
+		"select id, topic, payload, attempts, created_at from %s where published_at is null",
+		o.config.TableName,
+	)
+
+	if o.config.MaxAttempts > 0 {
+		selectQuery += fmt.Sprintf(" and attempts < %d", o.config.MaxAttempts)
+	}
+
+	selectQuery += fmt.Sprintf(" order by id limit %d for update skip locked", o.config.BatchSize)
+
+	rower, err := tx.Query(selectQuery)
+
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var messages []OutboxMessage
+
+	for rower.Next() {
+		var msg OutboxMessage
+		var payload string
+
+		if err = rower.Scan(&msg.ID, &msg.Topic, &payload, &msg.Attempts, &msg.CreatedAt); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		msg.Payload = []byte(payload)
+		messages = append(messages, msg)
+	}
+
+	publishedQuery := sqlx.Rebind(
+		bindVar,
+		fmt.Sprintf("update %s set published_at = current_timestamp where id = ?", o.config.TableName),
+	)
+	failedQuery := sqlx.Rebind(
+		bindVar,
+		fmt.Sprintf("update %s set attempts = attempts + 1, last_error = ? where id = ?", o.config.TableName),
+	)
+
+	published := 0
+
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+
+		pubErr := httputil.Retry(ctx, o.config.PublishRetry, func(ctx context.Context) error {
+			return o.publisher.Publish(ctx, msg)
+		})
+
+		if pubErr != nil {
+			// Message won't be retried so it's reported as it's lost
+			if o.config.MaxAttempts > 0 && msg.Attempts+1 >= o.config.MaxAttempts {
+				httputil.CaptureError(ctx, pubErr, map[string]string{
+					"source": "outbox",
+					"topic":  msg.Topic,
+				})
+			}
+
+			_, err = tx.Exec(failedQuery, pubErr.Error(), msg.ID)
+		} else {
+			_, err = tx.Exec(publishedQuery, msg.ID)
+			published++
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
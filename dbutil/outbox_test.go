@@ -0,0 +1,63 @@
+package dbutil_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestWriteOutbox(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.QueryMatcher = dbtest.QueryMatcherEqual
+	db.ExpectExec("insert into outbox (topic, payload) values ($1, $2)").
+		WithArgs("user.created", `{"id":1}`).
+		WillReturnResult(dbtest.NewResult(1, 1))
+
+	if err := dbutil.WriteOutbox(db, "user.created", map[string]int{"id": 1}); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+}
+
+func TestOutboxRelayOnce(t *testing.T) {
+	now := time.Now()
+	db := dbtest.NewExpectDB(t)
+	db.ExpectBegin()
+	db.ExpectQuery("select id, topic, payload, attempts, created_at from outbox where published_at is null").
+		WillReturnRows(
+			dbtest.NewRows("id", "topic", "payload", "attempts", "created_at").
+				AddRow(int64(1), "user.created", `{"id":1}`, 0, now).
+				AddRow(int64(2), "user.created", `{"id":2}`, 0, now),
+		)
+	db.ExpectExec("update outbox set published_at").
+		WithArgs(int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec("update outbox set attempts").
+		WithArgs("broker down", int64(2)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	var topics []string
+
+	relay := dbutil.NewOutboxRelay(db, dbutil.PublisherFunc(func(ctx context.Context, msg dbutil.OutboxMessage) error {
+		if msg.ID == 2 {
+			return errors.New("broker down")
+		}
+
+		topics = append(topics, msg.Topic)
+		return nil
+	}), dbutil.OutboxConfig{})
+
+	published, err := relay.RelayOnce(context.Background())
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	if published != 1 || len(topics) != 1 {
+		t.Errorf("got %d published; want 1", published)
+	}
+}
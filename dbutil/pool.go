@@ -0,0 +1,52 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/jmoiron/sqlx"
+)
+
+// StatsHook receives connection pool statistics of database, usually
+// to export them as metrics
+type StatsHook func(stats sql.DBStats)
+
+// Stats is wrapper for sql.DB.Stats
+func (db *DB) Stats() sql.DBStats {
+	return db.DB.Stats()
+}
+
+// ReportStats calls hook with Stats every interval until ctx is done
+// This is blocking so should be run within its own goroutine
+func (db *DB) ReportStats(ctx context.Context, interval time.Duration, hook StatsHook) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			hook(db.Stats())
+		}
+	}
+}
+
+// applyPoolConfig sets connection pool settings of dbConfig on db
+// Settings that are 0 are left as database/sql defaults
+func applyPoolConfig(db *sqlx.DB, dbConfig confutil.Database) {
+	if dbConfig.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	}
+	if dbConfig.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	}
+	if dbConfig.ConnMaxLifetime.Duration > 0 {
+		db.SetConnMaxLifetime(dbConfig.ConnMaxLifetime.Duration)
+	}
+	if dbConfig.ConnMaxIdleTime.Duration > 0 {
+		db.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime.Duration)
+	}
+}
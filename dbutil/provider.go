@@ -0,0 +1,78 @@
+package dbutil
+
+import (
+	"sync"
+
+	"github.com/TravisS25/httputil"
+)
+
+// DBProvider holds the current database connection and swaps it for
+// the connection returned by RecoverError so every caller of DB uses
+// the recovered connection without guarding a shared variable itself
+type DBProvider struct {
+	mu sync.RWMutex
+	db httputil.DBInterfaceV2
+}
+
+// NewDBProvider returns *DBProvider with db as current connection
+func NewDBProvider(db httputil.DBInterfaceV2) *DBProvider {
+	return &DBProvider{db: db}
+}
+
+// DB returns current connection whose RecoverError swaps the
+// connection of the provider
+func (d *DBProvider) DB() httputil.DBInterfaceV2 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return &providerDB{DBInterfaceV2: d.db, provider: d}
+}
+
+// RecoverError calls RecoverError of current connection and, if a
+// connection is returned, makes it the current connection
+func (d *DBProvider) RecoverError(err error) (httputil.DBInterfaceV2, error) {
+	d.mu.RLock()
+	db := d.db
+	d.mu.RUnlock()
+
+	return d.recover(db, err)
+}
+
+// recover recovers from err that occurred on db
+// If db was already swapped by another caller, the current
+// connection is returned instead of recovering again
+func (d *DBProvider) recover(db httputil.DBInterfaceV2, err error) (httputil.DBInterfaceV2, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.db != db {
+		return d.db, nil
+	}
+
+	newDB, err := db.RecoverError(err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if newDB != nil {
+		d.db = newDB
+	}
+
+	return d.db, nil
+}
+
+// providerDB is connection returned by DBProvider#DB that recovers
+// through its provider
+type providerDB struct {
+	httputil.DBInterfaceV2
+	provider *DBProvider
+}
+
+func (p *providerDB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
+	return p.provider.recover(p.DBInterfaceV2, err)
+}
+
+func (p *providerDB) BindVar() int {
+	return BindVarOf(p.DBInterfaceV2)
+}
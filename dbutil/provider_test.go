@@ -0,0 +1,47 @@
+package dbutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestDBProvider(t *testing.T) {
+	recovered := &dbtest.MockDB{}
+	recoverCount := 0
+	failed := &dbtest.MockDB{
+		RecoverErrorFunc: func(err error) (httputil.DBInterfaceV2, error) {
+			recoverCount++
+			return recovered, nil
+		},
+	}
+
+	provider := dbutil.NewDBProvider(failed)
+	first := provider.DB()
+	second := provider.DB()
+
+	if _, err := first.RecoverError(errors.New("connection lost")); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	// Connection was already swapped so should not recover again
+	if _, err := second.RecoverError(errors.New("connection lost")); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	if recoverCount != 1 {
+		t.Errorf("should recover once; got %d\n", recoverCount)
+	}
+
+	recoveredErr := errors.New("no connection")
+	recovered.RecoverErrorFunc = func(err error) (httputil.DBInterfaceV2, error) {
+		return nil, recoveredErr
+	}
+
+	if _, err := provider.DB().RecoverError(errors.New("connection lost")); err != recoveredErr {
+		t.Errorf("should return error of RecoverError; got %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package dbutil
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+)
+
+// QueryRetryPolicy is default policy of QueryCountRetry, GetRetry and
+// SelectRetry when they're given a policy with no MaxAttempts
+// Retryable of policy defaults to RetryableOf the database queried
+var QueryRetryPolicy = httputil.RetryPolicy{}
+
+// RetryableOf returns Dialect#Retryable of driver of db if db
+// implements SqlxExt like *DB and *CustomTx, else httputil#IsRetryable
+func RetryableOf(db interface{}) func(err error) bool {
+	if ext, ok := db.(interface{ DriverName() string }); ok {
+		if d, ok := dialects[ext.DriverName()]; ok && d.Retryable != nil {
+			return d.Retryable
+		}
+	}
+
+	return httputil.IsRetryable
+}
+
+// QueryCountRetry is QueryCount that retries transient errors, eg.
+// CockroachDB "restart transaction" errors, with policy, using
+// GetContext of db with ctx if db implements it
+//
+// If policy has no MaxAttempts, QueryRetryPolicy is used and if policy
+// has no Retryable, RetryableOf db is used
+func QueryCountRetry(
+	ctx context.Context,
+	db httputil.SqlxDB,
+	policy httputil.RetryPolicy,
+	query string,
+	args ...interface{},
+) (*Count, error) {
+	var dest Count
+	err := GetRetry(ctx, db, policy, &dest, query, args...)
+	return &dest, err
+}
+
+// GetRetry is Get of db that retries transient errors with policy
+// See QueryCountRetry for defaults
+func GetRetry(
+	ctx context.Context,
+	db httputil.SqlxDB,
+	policy httputil.RetryPolicy,
+	dest interface{},
+	query string,
+	args ...interface{},
+) error {
+	return httputil.Retry(ctx, retryPolicy(db, policy), func(ctx context.Context) error {
+		if c, ok := db.(interface {
+			GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+		}); ok {
+			return c.GetContext(ctx, dest, query, args...)
+		}
+
+		return db.Get(dest, query, args...)
+	})
+}
+
+// SelectRetry is Select of db that retries transient errors with
+// policy, where dest is reset before every attempt so rows of failed
+// attempts are not kept
+// See QueryCountRetry for defaults
+func SelectRetry(
+	ctx context.Context,
+	db httputil.SqlxDB,
+	policy httputil.RetryPolicy,
+	dest interface{},
+	query string,
+	args ...interface{},
+) error {
+	return httputil.Retry(ctx, retryPolicy(db, policy), func(ctx context.Context) error {
+		if v := reflect.ValueOf(dest); v.Kind() == reflect.Ptr && !v.IsNil() {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+
+		if c, ok := db.(interface {
+			SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+		}); ok {
+			return c.SelectContext(ctx, dest, query, args...)
+		}
+
+		return db.Select(dest, query, args...)
+	})
+}
+
+func retryPolicy(db interface{}, policy httputil.RetryPolicy) httputil.RetryPolicy {
+	if policy.MaxAttempts <= 0 {
+		retryable := policy.Retryable
+		policy = QueryRetryPolicy
+
+		if retryable != nil {
+			policy.Retryable = retryable
+		}
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = RetryableOf(db)
+	}
+
+	return policy
+}
+
+// mysqlRetryable is Dialect#Retryable of mysql which also retries
+// deadlocks (1213) and lock wait timeouts (1205)
+func mysqlRetryable(err error) bool {
+	if httputil.IsRetryable(err) {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1213") || strings.Contains(msg, "Error 1205")
+}
+
+// sqliteRetryable is Dialect#Retryable of sqlite which also retries
+// busy and locked databases
+func sqliteRetryable(err error) bool {
+	if httputil.IsRetryable(err) {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
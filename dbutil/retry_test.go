@@ -0,0 +1,59 @@
+package dbutil_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestQueryCountRetry(t *testing.T) {
+	policy := httputil.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	restartErr := errors.New("pq: restart transaction: TransactionRetryWithProtoRefreshError")
+
+	db := dbtest.NewExpectDB(t)
+	db.ExpectGet("select count").WillReturnError(restartErr)
+	db.ExpectGet("select count").WillReturnValue(dbutil.Count{Total: 5})
+
+	count, err := dbutil.QueryCountRetry(context.Background(), db, policy, "select count(*) as total from item")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if count.Total != 5 {
+		t.Errorf("should have total of 5; got %d", count.Total)
+	}
+
+	fatalErr := errors.New("pq: relation \"item\" does not exist")
+	db.ExpectSelect("select id").WillReturnError(fatalErr)
+
+	var ids []int64
+
+	if err = dbutil.SelectRetry(context.Background(), db, policy, &ids, "select id from item"); err != fatalErr {
+		t.Errorf("should return fatal error without retrying; got %v", err)
+	}
+}
+
+func TestDialectRetryable(t *testing.T) {
+	tests := []struct {
+		dbType    string
+		err       error
+		retryable bool
+	}{
+		{dbutil.Postgres, errors.New("restart transaction"), true},
+		{dbutil.Postgres, errors.New("Error 1213: Deadlock found"), false},
+		{dbutil.Mysql, errors.New("Error 1213: Deadlock found"), true},
+		{dbutil.Mysql, errors.New("Error 1062: Duplicate entry"), false},
+		{dbutil.Sqlite, errors.New("database is locked"), true},
+	}
+
+	for _, test := range tests {
+		if dbutil.GetDialect(test.dbType).Retryable(test.err) != test.retryable {
+			t.Errorf("%s should have retryable %t for %q", test.dbType, test.retryable, test.err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrInvalidSavepoint is returned when savepoint name is not a
+	// plain sql identifier
+	ErrInvalidSavepoint = errors.New("dbutil: Invalid savepoint name")
+)
+
+// savepointID is used to generate unique savepoint names for
+// WithNestedTransaction
+var savepointID uint64
+
+// Savepointer is implemented by transactions that can partially roll
+// back to a savepoint like *CustomTx
+type Savepointer interface {
+	Savepoint(name string) error
+	RollbackToSavepoint(name string) error
+	ReleaseSavepoint(name string) error
+}
+
+// Savepoint creates savepoint with name within transaction that can
+// later be rolled back to with RollbackToSavepoint
+func (c *CustomTx) Savepoint(name string) error {
+	return execSavepoint(c, "savepoint %s", name)
+}
+
+// RollbackToSavepoint rolls back everything executed within transaction
+// since savepoint with name was created, leaving transaction usable
+func (c *CustomTx) RollbackToSavepoint(name string) error {
+	return execSavepoint(c, "rollback to savepoint %s", name)
+}
+
+// ReleaseSavepoint releases savepoint with name, keeping everything
+// executed since it was created
+func (c *CustomTx) ReleaseSavepoint(name string) error {
+	return execSavepoint(c, "release savepoint %s", name)
+}
+
+// WithNestedTransaction creates a savepoint within tx and calls fn,
+// releasing savepoint if fn returns nil, else rolling back to it and
+// returning the error of fn
+//
+// This allows part of a transaction, eg. optional child inserts, to fail
+// without aborting the whole transaction
+//
+// If tx does not implement Savepointer, savepoint statements are
+// executed on tx directly
+func WithNestedTransaction(tx httputil.Tx, fn func(tx httputil.Tx) error) error {
+	sp, ok := tx.(Savepointer)
+
+	if !ok {
+		sp = &execSavepointer{XODB: tx}
+	}
+
+	name := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointID, 1))
+
+	if err := sp.Savepoint(name); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := sp.RollbackToSavepoint(name); rbErr != nil {
+			return rbErr
+		}
+
+		return err
+	}
+
+	return sp.ReleaseSavepoint(name)
+}
+
+func execSavepoint(db httputil.XODB, stmt, name string) error {
+	if !ValidIdentifier(name) {
+		return ErrInvalidSavepoint
+	}
+
+	_, err := db.Exec(fmt.Sprintf(stmt, name))
+	return err
+}
+
+type execSavepointer struct {
+	httputil.XODB
+}
+
+func (e *execSavepointer) Savepoint(name string) error {
+	return execSavepoint(e, "savepoint %s", name)
+}
+
+func (e *execSavepointer) RollbackToSavepoint(name string) error {
+	return execSavepoint(e, "rollback to savepoint %s", name)
+}
+
+func (e *execSavepointer) ReleaseSavepoint(name string) error {
+	return execSavepoint(e, "release savepoint %s", name)
+}
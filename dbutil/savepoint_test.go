@@ -0,0 +1,45 @@
+package dbutil_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestWithNestedTransaction(t *testing.T) {
+	tx := &sessionTx{}
+
+	err := dbutil.WithNestedTransaction(tx, func(tx httputil.Tx) error {
+		tx.Exec("insert into child values (1)")
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if len(tx.execs) != 3 ||
+		!strings.HasPrefix(tx.execs[0], "savepoint sp_") ||
+		!strings.HasPrefix(tx.execs[2], "release savepoint sp_") {
+		t.Fatalf("should create and release savepoint; got %v\n", tx.execs)
+	}
+
+	tx = &sessionTx{}
+	fnErr := errors.New("fn error")
+
+	err = dbutil.WithNestedTransaction(tx, func(tx httputil.Tx) error {
+		return fnErr
+	})
+
+	if err != fnErr {
+		t.Errorf("should return error of fn; got %v\n", err)
+	}
+	if len(tx.execs) != 2 || !strings.HasPrefix(tx.execs[1], "rollback to savepoint sp_") {
+		t.Errorf("should roll back to savepoint; got %v\n", tx.execs)
+	}
+	if tx.rolledBack {
+		t.Errorf("should not roll back whole transaction\n")
+	}
+}
@@ -0,0 +1,163 @@
+package dbutil
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	columnsQuery = `
+	select
+		column_name,
+		data_type,
+		is_nullable,
+		column_default,
+		character_maximum_length
+	from
+		information_schema.columns
+	where
+		table_schema = %s
+	and
+		table_name = ?
+	order by
+		ordinal_position`
+)
+
+var (
+	// ErrTableNotFound is returned from TableColumns if table has no columns
+	ErrTableNotFound = errors.New("dbutil: table not found")
+
+	sqliteTypeExp = regexp.MustCompile(`^([^(]+)\(\s*([0-9]+)`)
+)
+
+// Column is metadata of table column returned from TableColumns
+// and Inspect
+type Column struct {
+	// Name is name of column
+	Name string `json:"name"`
+
+	// DataType is lowercase type of column without length eg. "varchar"
+	DataType string `json:"dataType"`
+
+	// Nullable is whether column allows null values
+	Nullable bool `json:"nullable"`
+
+	// HasDefault is whether column has a default value, which includes
+	// auto incrementing columns
+	HasDefault bool `json:"hasDefault"`
+
+	// MaxLength is max length of character columns, else 0
+	MaxLength int `json:"maxLength,omitempty"`
+}
+
+// TableColumns returns columns of table in the order they're defined
+// table can be qualified with schema eg. "public.users", else the
+// current schema is used
+// dbType should be Postgres, Mysql or Sqlite
+// Returns ErrTableNotFound if table has no columns
+func TableColumns(db httputil.Querier, dbType, table string) ([]Column, error) {
+	if !ValidIdentifier(table) {
+		return nil, fmt.Errorf("dbutil: invalid table name '%s'", table)
+	}
+
+	if dbType == Sqlite {
+		return sqliteColumns(db, table)
+	}
+
+	schema := "current_schema()"
+
+	if dbType == Mysql {
+		schema = "database()"
+	}
+
+	var args []interface{}
+
+	if i := strings.LastIndex(table, "."); i != -1 {
+		schema = "?"
+		args = append(args, table[:i])
+		table = table[i+1:]
+	}
+
+	args = append(args, table)
+	rows, err := db.Query(
+		sqlx.Rebind(GetDialect(dbType).BindVar, fmt.Sprintf(columnsQuery, schema)),
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []Column
+
+	for rows.Next() {
+		var col Column
+		var nullable string
+		var def sql.NullString
+		var maxLength sql.NullInt64
+
+		if err = rows.Scan(&col.Name, &col.DataType, &nullable, &def, &maxLength); err != nil {
+			return nil, err
+		}
+
+		col.DataType = strings.ToLower(col.DataType)
+		col.Nullable = strings.EqualFold(nullable, "yes")
+		col.HasDefault = def.Valid
+		col.MaxLength = int(maxLength.Int64)
+		columns = append(columns, col)
+	}
+
+	if len(columns) == 0 {
+		return nil, ErrTableNotFound
+	}
+
+	return columns, nil
+}
+
+func sqliteColumns(db httputil.Querier, table string) ([]Column, error) {
+	rows, err := db.Query(
+		fmt.Sprintf("pragma table_info(%s)", GetDialect(Sqlite).QuoteIdentifier(table)),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []Column
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var col Column
+		var def sql.NullString
+
+		if err = rows.Scan(&cid, &col.Name, &col.DataType, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+
+		col.DataType = strings.ToLower(strings.TrimSpace(col.DataType))
+
+		if m := sqliteTypeExp.FindStringSubmatch(col.DataType); m != nil {
+			col.DataType = strings.TrimSpace(m[1])
+			col.MaxLength, _ = strconv.Atoi(m[2])
+		}
+
+		col.Nullable = notNull == 0 && pk == 0
+
+		// "integer primary key" is an alias of rowid which auto increments
+		col.HasDefault = def.Valid || (pk == 1 && col.DataType == "integer")
+		columns = append(columns, col)
+	}
+
+	if len(columns) == 0 {
+		return nil, ErrTableNotFound
+	}
+
+	return columns, nil
+}
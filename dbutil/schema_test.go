@@ -0,0 +1,71 @@
+package dbutil_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestTableColumns(t *testing.T) {
+	db := dbtest.NewExpectDB(t)
+	db.ExpectQuery("information_schema.columns").
+		WithArgs("public", "users").
+		WillReturnRows(
+			dbtest.NewRows("column_name", "data_type", "is_nullable", "column_default", "character_maximum_length").
+				AddRow("id", "integer", "NO", "nextval('users_id_seq')", nil).
+				AddRow("email", "character varying", "NO", nil, 255).
+				AddRow("note", "text", "YES", nil, nil),
+		)
+
+	columns, err := dbutil.TableColumns(db, dbutil.Postgres, "public.users")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := []dbutil.Column{
+		{Name: "id", DataType: "integer", HasDefault: true},
+		{Name: "email", DataType: "character varying", MaxLength: 255},
+		{Name: "note", DataType: "text", Nullable: true},
+	}
+
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("got columns %v; want %v", columns, expected)
+	}
+
+	db.ExpectQuery("pragma table_info").
+		WillReturnRows(
+			dbtest.NewRows("cid", "name", "type", "notnull", "dflt_value", "pk").
+				AddRow(0, "id", "INTEGER", 0, nil, 1).
+				AddRow(1, "email", "VARCHAR(100)", 1, nil, 0),
+		)
+
+	if columns, err = dbutil.TableColumns(db, dbutil.Sqlite, "users"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected = []dbutil.Column{
+		{Name: "id", DataType: "integer", HasDefault: true},
+		{Name: "email", DataType: "varchar", MaxLength: 100},
+	}
+
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("got columns %v; want %v", columns, expected)
+	}
+
+	db.ExpectQuery("information_schema.columns").
+		WithArgs("missing").
+		WillReturnRows(dbtest.NewRows("column_name", "data_type", "is_nullable", "column_default", "character_maximum_length"))
+
+	if _, err = dbutil.TableColumns(db, dbutil.Postgres, "missing"); err != dbutil.ErrTableNotFound {
+		t.Errorf("should return ErrTableNotFound; got %v", err)
+	}
+	if _, err = dbutil.TableColumns(db, dbutil.Postgres, "users;drop"); err == nil {
+		t.Errorf("should return error for invalid table name")
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
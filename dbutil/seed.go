@@ -0,0 +1,185 @@
+package dbutil
+
+import (
+	"fmt"
+
+	"github.com/TravisS25/httputil"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultSeedTable is table used to keep track of applied seeds if
+	// SeedConfig#TableName is not set
+	DefaultSeedTable = "seed_versions"
+)
+
+const (
+	seedTableQuery = `
+	create table if not exists %s (
+		name varchar(255) not null primary key,
+		version bigint not null,
+		applied_at timestamp not null default current_timestamp
+	)`
+)
+
+// SeedFunc is a named and versioned set of reference data, such as
+// groups, routing urls or lookup tables, that is applied by Seed
+type SeedFunc struct {
+	// Name uniquely identifies seed - Required
+	Name string
+
+	// Version of seed, where seed is applied again whenever version is
+	// greater than the version last applied
+	// Should be increased every time Func changes
+	Version int64
+
+	// Func inserts or updates reference data within tx
+	// As Func is applied again for every new version, it should
+	// upsert data instead of assuming it does not exist - Required
+	Func func(tx httputil.Tx) error
+}
+
+// SeedConfig is config struct used in conjunction with SeedWithConfig
+type SeedConfig struct {
+	// TableName is table used to store applied seed versions
+	// Default is DefaultSeedTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding
+	// Default is Postgres
+	DBType string
+}
+
+func (s *SeedConfig) setDefaults() {
+	if s.TableName == "" {
+		s.TableName = DefaultSeedTable
+	}
+	if s.DBType == "" {
+		s.DBType = Postgres
+	}
+}
+
+// Seed applies every seed whose version has not been applied yet using
+// the default seed table, in order of seeds, and returns the number
+// applied
+func Seed(db httputil.DBInterface, seeds []SeedFunc) (int, error) {
+	return SeedWithConfig(db, SeedConfig{}, seeds)
+}
+
+// SeedWithConfig is the same as Seed but with config
+//
+// Each seed is applied within its own transaction along with its
+// version being recorded in SeedConfig#TableName, so a seed that fails
+// is not recorded and is applied again on the next call
+//
+// Caches of seeded data, eg. ones set with cacheutil#CacheSetup, are not
+// refreshed and should be reloaded once seeds are applied
+func SeedWithConfig(db httputil.DBInterface, config SeedConfig, seeds []SeedFunc) (int, error) {
+	config.setDefaults()
+	names := make(map[string]bool, len(seeds))
+
+	for _, seed := range seeds {
+		if seed.Name == "" || seed.Func == nil {
+			return 0, fmt.Errorf("dbutil: seed must have name and func")
+		}
+		if names[seed.Name] {
+			return 0, fmt.Errorf("dbutil: duplicate seed '%s'", seed.Name)
+		}
+
+		names[seed.Name] = true
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(seedTableQuery, config.TableName)); err != nil {
+		return 0, err
+	}
+
+	applied, err := AppliedSeeds(db, config)
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, seed := range seeds {
+		version, ok := applied[seed.Name]
+
+		if ok && version >= seed.Version {
+			continue
+		}
+
+		if err = applySeed(db, config, seed, ok); err != nil {
+			return count, fmt.Errorf("dbutil: seed %s version %d failed: %s", seed.Name, seed.Version, err.Error())
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// AppliedSeeds returns map of every applied seed name and its version
+func AppliedSeeds(db httputil.Querier, config SeedConfig) (map[string]int64, error) {
+	config.setDefaults()
+	rower, err := db.Query(fmt.Sprintf("select name, version from %s", config.TableName))
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]int64)
+
+	for rower.Next() {
+		var name string
+		var version int64
+
+		if err = rower.Scan(&name, &version); err != nil {
+			return nil, err
+		}
+
+		applied[name] = version
+	}
+
+	return applied, nil
+}
+
+func applySeed(db httputil.DBInterface, config SeedConfig, seed SeedFunc, exists bool) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	if err = seed.Func(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if exists {
+		_, err = tx.Exec(
+			sqlx.Rebind(
+				sqlx.BindType(config.DBType),
+				fmt.Sprintf("update %s set version = ? where name = ?", config.TableName),
+			),
+			seed.Version,
+			seed.Name,
+		)
+	} else {
+		_, err = tx.Exec(
+			sqlx.Rebind(
+				sqlx.BindType(config.DBType),
+				fmt.Sprintf("insert into %s (name, version) values (?, ?)", config.TableName),
+			),
+			seed.Name,
+			seed.Version,
+		)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,81 @@
+package dbutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestSeed(t *testing.T) {
+	var applied []string
+
+	seed := func(name string, version int64, err error) dbutil.SeedFunc {
+		return dbutil.SeedFunc{
+			Name:    name,
+			Version: version,
+			Func: func(tx httputil.Tx) error {
+				if err != nil {
+					return err
+				}
+
+				applied = append(applied, name)
+				_, err := tx.Exec("insert into groups (name) values ('Admin') on conflict do nothing")
+				return err
+			},
+		}
+	}
+
+	db := dbtest.NewExpectDB(t)
+	db.ExpectExec("create table if not exists seed_versions").
+		WillReturnResult(dbtest.NewResult(0, 0))
+	db.ExpectQuery("select name, version from seed_versions").
+		WillReturnRows(
+			dbtest.NewRows("name", "version").
+				AddRow("groups", int64(1)).
+				AddRow("urls", int64(1)),
+		)
+
+	// urls has new version
+	db.ExpectBegin()
+	db.ExpectExec("insert into groups").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`update seed_versions set version = \$1 where name = \$2`).
+		WithArgs(int64(2), "urls").
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	// lookups has never been applied
+	db.ExpectBegin()
+	db.ExpectExec("insert into groups").WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`insert into seed_versions \(name, version\) values \(\$1, \$2\)`).
+		WithArgs("lookups", int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	// failed seed is not recorded
+	db.ExpectBegin()
+	db.ExpectRollback()
+
+	count, err := dbutil.Seed(db, []dbutil.SeedFunc{
+		seed("groups", 1, nil),
+		seed("urls", 2, nil),
+		seed("lookups", 1, nil),
+		seed("broken", 1, errors.New("seed failed")),
+	})
+
+	if err == nil {
+		t.Errorf("should have error")
+	}
+	if count != 2 || len(applied) != 2 || applied[0] != "urls" || applied[1] != "lookups" {
+		t.Errorf("should apply urls and lookups; got %d %v", count, applied)
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+
+	if _, err = dbutil.Seed(db, []dbutil.SeedFunc{seed("groups", 1, nil), seed("groups", 2, nil)}); err == nil {
+		t.Errorf("should have error for duplicate seed")
+	}
+}
@@ -0,0 +1,105 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// CurrentUserSetting is default postgres setting used for
+	// current user id in row level security policies
+	CurrentUserSetting = "app.current_user_id"
+
+	// TenantSetting is default postgres setting used for tenant id
+	// in row level security policies
+	TenantSetting = "app.tenant_id"
+)
+
+// SessionSetting is a postgres setting that is set local to a
+// transaction, usually to be referenced within row level security
+// policies like:
+//
+// create policy user_policy on item using (user_id = current_setting('app.current_user_id')::bigint)
+type SessionSetting struct {
+	// Name is name of setting eg. "app.current_user_id"
+	Name string
+
+	// Value returns value of setting from ctx and whether it is set
+	// Settings that are not set are skipped
+	Value func(ctx context.Context) (string, bool)
+}
+
+// ContextSetting returns SessionSetting whose value is the value
+// of key within ctx
+func ContextSetting(name string, key interface{}) SessionSetting {
+	return SessionSetting{
+		Name: name,
+		Value: func(ctx context.Context) (string, bool) {
+			val := ctx.Value(key)
+
+			if val == nil {
+				return "", false
+			}
+
+			return fmt.Sprint(val), true
+		},
+	}
+}
+
+// SetLocalSettings sets every setting, that has a value within ctx,
+// local to the transaction tx is within
+//
+// "select set_config(name, value, true)" is used as it's the same as
+// "set local name = value" but allows placeholders
+func SetLocalSettings(ctx context.Context, tx httputil.XODB, settings ...SessionSetting) error {
+	for _, setting := range settings {
+		value, ok := setting.Value(ctx)
+
+		if !ok {
+			continue
+		}
+
+		if _, err := tx.Exec("select set_config($1, $2, true)", setting.Name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithTransaction begins a transaction, calls fn and commits if fn returns
+// nil, else rolls back and returns the error of fn
+func WithTransaction(db httputil.Transaction, fn func(tx httputil.Tx) error) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return db.Commit(tx)
+}
+
+// WithSessionTransaction is the same as WithTransaction but sets
+// settings local to the transaction, from ctx, before fn is called so
+// postgres row level security policies work with pooled connections
+func WithSessionTransaction(
+	ctx context.Context,
+	db httputil.Transaction,
+	settings []SessionSetting,
+	fn func(tx httputil.Tx) error,
+) error {
+	return WithTransaction(db, func(tx httputil.Tx) error {
+		if err := SetLocalSettings(ctx, tx, settings...); err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
@@ -0,0 +1,87 @@
+package dbutil_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+type tenantKey struct{}
+
+type sessionTx struct {
+	httputil.Tx
+	execs      []string
+	args       [][]interface{}
+	rolledBack bool
+}
+
+func (s *sessionTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	s.execs = append(s.execs, query)
+	s.args = append(s.args, args)
+	return nil, nil
+}
+
+func (s *sessionTx) Rollback() error {
+	s.rolledBack = true
+	return nil
+}
+
+type sessionDB struct {
+	tx        *sessionTx
+	committed bool
+}
+
+func (s *sessionDB) Begin() (httputil.Tx, error) {
+	return s.tx, nil
+}
+
+func (s *sessionDB) Commit(tx httputil.Tx) error {
+	s.committed = true
+	return nil
+}
+
+func TestWithSessionTransaction(t *testing.T) {
+	db := &sessionDB{tx: &sessionTx{}}
+	ctx := context.WithValue(context.Background(), tenantKey{}, 5)
+	settings := []dbutil.SessionSetting{
+		dbutil.ContextSetting(dbutil.TenantSetting, tenantKey{}),
+		dbutil.ContextSetting(dbutil.CurrentUserSetting, "missing"),
+	}
+
+	called := false
+	err := dbutil.WithSessionTransaction(ctx, db, settings, func(tx httputil.Tx) error {
+		called = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if !called || !db.committed {
+		t.Fatalf("should call fn and commit\n")
+	}
+	if len(db.tx.execs) != 1 {
+		t.Fatalf("should only set settings within ctx; got %d\n", len(db.tx.execs))
+	}
+	if db.tx.args[0][0] != dbutil.TenantSetting || db.tx.args[0][1] != "5" {
+		t.Errorf("unexpected args; got %v\n", db.tx.args[0])
+	}
+
+	db = &sessionDB{tx: &sessionTx{}}
+	fnErr := errors.New("fn error")
+
+	err = dbutil.WithSessionTransaction(ctx, db, settings, func(tx httputil.Tx) error {
+		return fnErr
+	})
+
+	if err != fnErr {
+		t.Errorf("should return error of fn; got %v\n", err)
+	}
+	if !db.tx.rolledBack || db.committed {
+		t.Errorf("should roll back and not commit\n")
+	}
+}
@@ -0,0 +1,378 @@
+package dbutil
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// DefaultShadowQueueSize is default ShadowConfig#QueueSize
+	DefaultShadowQueueSize = 1000
+
+	// DivergenceExec is kind of Divergence of mirrored write
+	DivergenceExec = "exec"
+
+	// DivergenceRead is kind of Divergence of compared read
+	DivergenceRead = "read"
+)
+
+var (
+	// ErrShadowQueueFull is error of Divergence when write couldn't be
+	// mirrored as queue of ShadowDB is full
+	ErrShadowQueueFull = errors.New("dbutil: shadow queue full")
+
+	// ErrShadowClosed is error of Divergence when write couldn't be
+	// mirrored as ShadowDB is closed
+	ErrShadowClosed = errors.New("dbutil: shadow closed")
+
+	// ErrRowsAffected is error of Divergence when mirrored write
+	// affected a different number of rows than on primary
+	ErrRowsAffected = errors.New("dbutil: rows affected differ")
+
+	// ErrResultsDiffer is error of Divergence when compared read
+	// returned different results than on primary
+	ErrResultsDiffer = errors.New("dbutil: results differ")
+)
+
+// Divergence is reported when secondary database of ShadowDB failed or
+// returned different results than primary
+type Divergence struct {
+	// Kind is DivergenceExec or DivergenceRead
+	Kind string
+
+	Query string
+	Args  []interface{}
+
+	// Primary and Secondary are rows affected of writes and results
+	// of reads
+	Primary   interface{}
+	Secondary interface{}
+
+	Err error
+}
+
+// ShadowConfig is config of ShadowDB
+type ShadowConfig struct {
+	// CompareReads determines whether Get and Select are also run
+	// against secondary and compared to results of primary
+	// Query and QueryRow are never compared as their rows are consumed
+	// by the caller
+	CompareReads bool
+
+	// QueueSize is max number of writes and reads waiting to be run
+	// against secondary, after which they're dropped and reported
+	// with ErrShadowQueueFull
+	// Default value is DefaultShadowQueueSize
+	QueueSize int
+
+	// OnDivergence is called with every divergence
+	// Default logs divergence with httputil#Logger
+	OnDivergence func(Divergence)
+}
+
+// ShadowDB sends every query to primary and mirrors writes, and
+// optionally reads, to secondary in the background, in the order
+// they were committed, reporting where secondary diverges
+// This is used to verify secondary eg. cockroachdb while migrating
+// from primary eg. postgres without affecting requests
+//
+// Writes within transactions are mirrored within a transaction once
+// the primary transaction commits
+type ShadowDB struct {
+	httputil.DBInterfaceV2
+	shadow *shadow
+}
+
+type shadow struct {
+	secondary httputil.DBInterfaceV2
+	config    ShadowConfig
+	jobs      chan func()
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+}
+
+// NewShadowDB returns *ShadowDB and starts mirroring to secondary until
+// Close is called
+func NewShadowDB(primary, secondary httputil.DBInterfaceV2, config ShadowConfig) *ShadowDB {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultShadowQueueSize
+	}
+	if config.OnDivergence == nil {
+		config.OnDivergence = logDivergence
+	}
+
+	s := &shadow{
+		secondary: secondary,
+		config:    config,
+		jobs:      make(chan func(), config.QueueSize),
+		done:      make(chan struct{}),
+	}
+
+	go s.run()
+
+	return &ShadowDB{DBInterfaceV2: primary, shadow: s}
+}
+
+// Close stops accepting writes and waits for queued writes to be
+// mirrored
+func (s *ShadowDB) Close() error {
+	s.shadow.mu.Lock()
+
+	if !s.shadow.closed {
+		s.shadow.closed = true
+		close(s.shadow.jobs)
+	}
+
+	s.shadow.mu.Unlock()
+	<-s.shadow.done
+	return nil
+}
+
+// Exec execs against primary and, if successful, mirrors to secondary
+func (s *ShadowDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	result, err := s.DBInterfaceV2.Exec(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.shadow.mirror([]shadowExec{{query: query, args: args, affected: rowsAffected(result)}}, false)
+	return result, nil
+}
+
+// Get gets from primary and, if ShadowConfig#CompareReads is set,
+// compares result to secondary
+func (s *ShadowDB) Get(dest interface{}, query string, args ...interface{}) error {
+	if err := s.DBInterfaceV2.Get(dest, query, args...); err != nil {
+		return err
+	}
+
+	s.shadow.compare(dest, query, args, s.shadow.secondary.Get)
+	return nil
+}
+
+// Select selects from primary and, if ShadowConfig#CompareReads is set,
+// compares result to secondary
+func (s *ShadowDB) Select(dest interface{}, query string, args ...interface{}) error {
+	if err := s.DBInterfaceV2.Select(dest, query, args...); err != nil {
+		return err
+	}
+
+	s.shadow.compare(dest, query, args, s.shadow.secondary.Select)
+	return nil
+}
+
+// Begin begins transaction on primary whose writes are mirrored once
+// it commits
+func (s *ShadowDB) Begin() (httputil.Tx, error) {
+	tx, err := s.DBInterfaceV2.Begin()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &shadowTx{Tx: tx, shadow: s.shadow}, nil
+}
+
+// Commit commits tx
+func (s *ShadowDB) Commit(tx httputil.Tx) error {
+	return tx.Commit()
+}
+
+// RecoverError recovers primary and keeps mirroring to the same
+// secondary
+func (s *ShadowDB) RecoverError(err error) (httputil.DBInterfaceV2, error) {
+	db, err := s.DBInterfaceV2.RecoverError(err)
+
+	if err != nil || db == nil {
+		return db, err
+	}
+
+	return &ShadowDB{DBInterfaceV2: db, shadow: s.shadow}, nil
+}
+
+// BindVar returns bind var of primary
+func (s *ShadowDB) BindVar() int {
+	return BindVarOf(s.DBInterfaceV2)
+}
+
+type shadowExec struct {
+	query    string
+	args     []interface{}
+	affected int64
+}
+
+type shadowTx struct {
+	httputil.Tx
+	shadow *shadow
+	execs  []shadowExec
+}
+
+func (s *shadowTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	result, err := s.Tx.Exec(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.execs = append(s.execs, shadowExec{query: query, args: args, affected: rowsAffected(result)})
+	return result, nil
+}
+
+func (s *shadowTx) Commit() error {
+	if err := s.Tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(s.execs) > 0 {
+		s.shadow.mirror(s.execs, true)
+	}
+
+	return nil
+}
+
+func (s *shadow) run() {
+	defer close(s.done)
+
+	for job := range s.jobs {
+		job()
+	}
+}
+
+// enqueue queues job, reporting divergence with query if it's dropped
+func (s *shadow) enqueue(kind, query string, args []interface{}, job func()) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.config.OnDivergence(Divergence{Kind: kind, Query: query, Args: args, Err: ErrShadowClosed})
+		return
+	}
+
+	select {
+	case s.jobs <- job:
+	default:
+		s.config.OnDivergence(Divergence{Kind: kind, Query: query, Args: args, Err: ErrShadowQueueFull})
+	}
+}
+
+func (s *shadow) mirror(execs []shadowExec, inTx bool) {
+	s.enqueue(DivergenceExec, execs[0].query, execs[0].args, func() {
+		var db httputil.XODB = s.secondary
+		var tx httputil.Tx
+		var err error
+
+		if inTx {
+			if tx, err = s.secondary.Begin(); err != nil {
+				s.config.OnDivergence(Divergence{Kind: DivergenceExec, Query: execs[0].query, Args: execs[0].args, Err: err})
+				return
+			}
+
+			db = tx
+		}
+
+		for _, e := range execs {
+			result, err := db.Exec(e.query, e.args...)
+
+			if err != nil {
+				s.config.OnDivergence(Divergence{Kind: DivergenceExec, Query: e.query, Args: e.args, Err: err})
+
+				if tx != nil {
+					tx.Rollback()
+				}
+
+				return
+			}
+
+			if affected := rowsAffected(result); affected != e.affected {
+				s.config.OnDivergence(Divergence{
+					Kind:      DivergenceExec,
+					Query:     e.query,
+					Args:      e.args,
+					Primary:   e.affected,
+					Secondary: affected,
+					Err:       ErrRowsAffected,
+				})
+			}
+		}
+
+		if tx != nil {
+			if err = tx.Commit(); err != nil {
+				s.config.OnDivergence(Divergence{Kind: DivergenceExec, Query: execs[0].query, Args: execs[0].args, Err: err})
+			}
+		}
+	})
+}
+
+func (s *shadow) compare(
+	dest interface{},
+	query string,
+	args []interface{},
+	read func(dest interface{}, query string, args ...interface{}) error,
+) {
+	if !s.config.CompareReads {
+		return
+	}
+
+	destType := reflect.TypeOf(dest)
+
+	if destType == nil || destType.Kind() != reflect.Ptr {
+		return
+	}
+
+	// Primary result is copied as dest can be modified by caller
+	// before comparison runs
+	primary := reflect.New(destType.Elem())
+	primary.Elem().Set(reflect.ValueOf(dest).Elem())
+	primaryResult := primary.Elem().Interface()
+
+	s.enqueue(DivergenceRead, query, args, func() {
+		secondary := reflect.New(destType.Elem())
+
+		if err := read(secondary.Interface(), query, args...); err != nil {
+			s.config.OnDivergence(Divergence{Kind: DivergenceRead, Query: query, Args: args, Err: err})
+			return
+		}
+
+		if secondaryResult := secondary.Elem().Interface(); !reflect.DeepEqual(primaryResult, secondaryResult) {
+			s.config.OnDivergence(Divergence{
+				Kind:      DivergenceRead,
+				Query:     query,
+				Args:      args,
+				Primary:   primaryResult,
+				Secondary: secondaryResult,
+				Err:       ErrResultsDiffer,
+			})
+		}
+	})
+}
+
+func rowsAffected(result sql.Result) int64 {
+	if result == nil {
+		return -1
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return -1
+	}
+
+	return affected
+}
+
+func logDivergence(d Divergence) {
+	httputil.Logger.Warnf(
+		"dbutil: shadow %s diverged: %s; query: %s; primary: %v; secondary: %v",
+		d.Kind,
+		d.Err,
+		d.Query,
+		d.Primary,
+		d.Secondary,
+	)
+}
@@ -0,0 +1,75 @@
+package dbutil_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestShadowDB(t *testing.T) {
+	primary := dbtest.NewExpectDB(t)
+	secondary := dbtest.NewExpectDB(t)
+
+	primary.ExpectExec("update item").WillReturnResult(dbtest.NewResult(0, 1))
+	primary.ExpectBegin()
+	primary.ExpectExec("insert into item").WillReturnResult(dbtest.NewResult(1, 1))
+	primary.ExpectCommit()
+	primary.ExpectGet("select name").WillReturnValue("foo")
+
+	secondary.ExpectExec("update item").WillReturnResult(dbtest.NewResult(0, 2))
+	secondary.ExpectBegin()
+	secondary.ExpectExec("insert into item").WillReturnResult(dbtest.NewResult(1, 1))
+	secondary.ExpectCommit()
+	secondary.ExpectGet("select name").WillReturnValue("bar")
+
+	var mu sync.Mutex
+	var divergences []dbutil.Divergence
+
+	db := dbutil.NewShadowDB(primary, secondary, dbutil.ShadowConfig{
+		CompareReads: true,
+		OnDivergence: func(d dbutil.Divergence) {
+			mu.Lock()
+			defer mu.Unlock()
+			divergences = append(divergences, d)
+		},
+	})
+
+	if _, err := db.Exec("update item set name = ?", "foo"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if _, err = tx.Exec("insert into item (name) values (?)", "foo"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	var name string
+
+	if err = db.Get(&name, "select name from item where id = ?", 1); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if name != "foo" {
+		t.Errorf("should return result of primary; got %s", name)
+	}
+
+	db.Close()
+
+	if len(divergences) != 2 {
+		t.Fatalf("should have 2 divergences; got %v", divergences)
+	}
+	if divergences[0].Err != dbutil.ErrRowsAffected || divergences[0].Secondary != int64(2) {
+		t.Errorf("should report rows affected; got %+v", divergences[0])
+	}
+	if divergences[1].Err != dbutil.ErrResultsDiffer || divergences[1].Secondary != "bar" {
+		t.Errorf("should report different results; got %+v", divergences[1])
+	}
+}
@@ -0,0 +1,182 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrQueryTimeout is returned when a query takes longer than the
+	// timeout it was executed with
+	ErrQueryTimeout = errors.New("dbutil: Query timed out")
+)
+
+// QueryTimeouter is implemented by databases that have a default
+// timeout for queries like *DB
+type QueryTimeouter interface {
+	QueryTimeout() time.Duration
+}
+
+// SetQueryTimeout sets default timeout used by QueryWithTimeout when
+// no timeout is given
+// Default is 0 which is no timeout
+func (db *DB) SetQueryTimeout(timeout time.Duration) {
+	db.queryTimeout = timeout
+}
+
+// QueryTimeout returns default timeout set by SetQueryTimeout
+func (db *DB) QueryTimeout() time.Duration {
+	return db.queryTimeout
+}
+
+// PingContext is wrapper for sqlx.DB.PingContext that times out after
+// the default timeout set by SetQueryTimeout if ctx has no deadline,
+// returning ErrQueryTimeout if the deadline is exceeded
+// It implements httputil#Pinger
+func (db *DB) PingContext(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok && db.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.queryTimeout)
+		defer cancel()
+	}
+
+	if err := db.DB.PingContext(ctx); err != nil {
+		return timeoutError(ctx, err)
+	}
+
+	return nil
+}
+
+// QueryContext is wrapper for sqlx.DB.QueryContext
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (httputil.Rower, error) {
+	if err := db.queries.acquire(); err != nil {
+		return nil, err
+	}
+
+	return db.trackRows(db.DB.QueryContext(ctx, query, args...))
+}
+
+// QueryContext is wrapper for sql.Tx.QueryContext
+func (c *CustomTx) QueryContext(ctx context.Context, query string, args ...interface{}) (httputil.Rower, error) {
+	return c.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryWithTimeout executes query with a context that times out after
+// timeout, returning ErrQueryTimeout if the deadline is exceeded
+//
+// If timeout is 0, the default timeout of db is used if db implements
+// QueryTimeouter
+// If db was returned from WithContext, its context is used as parent so
+// its deadline also applies
+// If there is still no timeout or deadline, or db does not implement
+// httputil.QuerierContext, query is executed as is with db.Query
+//
+// The context is cancelled once the returned rower is exhausted
+// Errors while iterating can be retrieved with RowerErr
+func QueryWithTimeout(
+	db httputil.Querier,
+	timeout time.Duration,
+	query string,
+	args ...interface{},
+) (httputil.Rower, error) {
+	if timeout <= 0 {
+		if t, ok := db.(QueryTimeouter); ok {
+			timeout = t.QueryTimeout()
+		}
+	}
+
+	parent := context.Background()
+
+	if c, ok := db.(ContextDB); ok {
+		parent = c.Context()
+	}
+
+	_, hasDeadline := parent.Deadline()
+	ctxDB, ok := db.(httputil.QuerierContext)
+
+	if (timeout <= 0 && !hasDeadline) || !ok {
+		return db.Query(query, args...)
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	rower, err := ctxDB.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		cancel()
+		return nil, timeoutError(ctx, err)
+	}
+
+	return &timeoutRower{Rower: rower, ctx: ctx, cancel: cancel}, nil
+}
+
+// RowerErr returns error encountered while iterating rower, if rower
+// has an Err method like sql.Rows, else nil
+func RowerErr(rower httputil.Rower) error {
+	if r, ok := rower.(interface{ Err() error }); ok {
+		return r.Err()
+	}
+
+	return nil
+}
+
+func timeoutError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrQueryTimeout
+	}
+
+	return err
+}
+
+// timeoutRower cancels its context once rows are exhausted and
+// converts deadline errors into ErrQueryTimeout
+type timeoutRower struct {
+	httputil.Rower
+	ctx    context.Context
+	cancel context.CancelFunc
+	err    error
+}
+
+func (t *timeoutRower) Next() bool {
+	if t.Rower.Next() {
+		return true
+	}
+
+	if err := RowerErr(t.Rower); err != nil {
+		t.err = timeoutError(t.ctx, err)
+	}
+
+	if c, ok := t.Rower.(interface{ Close() error }); ok {
+		c.Close()
+	}
+
+	t.cancel()
+	return false
+}
+
+func (t *timeoutRower) Scan(dest ...interface{}) error {
+	if err := t.Rower.Scan(dest...); err != nil {
+		if err != sql.ErrNoRows {
+			return timeoutError(t.ctx, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (t *timeoutRower) Err() error {
+	return t.err
+}
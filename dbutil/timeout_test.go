@@ -0,0 +1,126 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+type timeoutDB struct {
+	timeout     time.Duration
+	contextUsed bool
+}
+
+func (t *timeoutDB) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	return nil
+}
+
+func (t *timeoutDB) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	return &ctxRower{ctx: context.Background()}, nil
+}
+
+func (t *timeoutDB) QueryContext(ctx context.Context, query string, args ...interface{}) (httputil.Rower, error) {
+	t.contextUsed = true
+
+	if query == "slow" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return &ctxRower{ctx: ctx, rows: 1}, nil
+}
+
+func (t *timeoutDB) QueryTimeout() time.Duration {
+	return t.timeout
+}
+
+type ctxRower struct {
+	ctx  context.Context
+	rows int
+	err  error
+}
+
+func (c *ctxRower) Next() bool {
+	if c.rows == 0 {
+		return false
+	}
+
+	c.rows--
+	<-c.ctx.Done()
+	c.err = c.ctx.Err()
+	return false
+}
+
+func (c *ctxRower) Scan(dest ...interface{}) error { return nil }
+func (c *ctxRower) Columns() ([]string, error)     { return nil, nil }
+func (c *ctxRower) Err() error                     { return c.err }
+
+func TestQueryWithTimeout(t *testing.T) {
+	db := &timeoutDB{}
+
+	if _, err := dbutil.QueryWithTimeout(db, 0, "slow"); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if db.contextUsed {
+		t.Fatalf("should not use context without timeout\n")
+	}
+
+	if _, err := dbutil.QueryWithTimeout(db, time.Millisecond, "slow"); err != dbutil.ErrQueryTimeout {
+		t.Fatalf("should return ErrQueryTimeout; got %v\n", err)
+	}
+
+	db = &timeoutDB{timeout: time.Millisecond}
+	rower, err := dbutil.QueryWithTimeout(db, 0, "select")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	for rower.Next() {
+	}
+
+	if err = dbutil.RowerErr(rower); err != dbutil.ErrQueryTimeout {
+		t.Errorf("should return ErrQueryTimeout while iterating; got %v\n", err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	db := &timeoutDB{}
+	ctxDB := dbutil.WithContext(ctx, &contextTestDB{timeoutDB: db})
+
+	if _, err := dbutil.QueryWithTimeout(ctxDB, 0, "slow"); err != dbutil.ErrQueryTimeout {
+		t.Fatalf("should return ErrQueryTimeout from context deadline; got %v\n", err)
+	}
+	if !db.contextUsed {
+		t.Errorf("should use context of db\n")
+	}
+
+	db.contextUsed = false
+
+	if _, err := ctxDB.Query("slow"); err != context.DeadlineExceeded {
+		t.Errorf("should query with context of db; got %v\n", err)
+	}
+	if !db.contextUsed {
+		t.Errorf("should use context of db\n")
+	}
+}
+
+// contextTestDB is timeoutDB that implements httputil.DBInterfaceV2
+type contextTestDB struct {
+	httputil.DBInterfaceV2
+	*timeoutDB
+}
+
+func (c *contextTestDB) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	return c.timeoutDB.QueryRow(query, args...)
+}
+
+func (c *contextTestDB) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	return c.timeoutDB.Query(query, args...)
+}
@@ -0,0 +1,112 @@
+package httputil
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Deprecation is call of deprecated function recorded by Deprecated
+type Deprecation struct {
+	// Name is deprecated function eg. "queryutil.ApplyAll"
+	Name string `json:"name"`
+
+	// Replacement is what should be used instead
+	Replacement string `json:"replacement"`
+
+	// Caller is file and line deprecated function was called from and
+	// Function is the function that called it
+	Caller   string `json:"caller"`
+	Function string `json:"function"`
+}
+
+// DeprecationUsage is number of calls of deprecated function from a
+// call site, see DeprecationUsages
+type DeprecationUsage struct {
+	Deprecation
+	Count int64 `json:"count"`
+}
+
+var (
+	// DeprecationWarnings determines whether the first call of every
+	// deprecated function from every call site is logged at warn level
+	// with Logger
+	// Should only be set on startup
+	// Default is true
+	DeprecationWarnings = true
+
+	// OnDeprecation, if set, is called on every call of a deprecated
+	// function eg. to increment a metric labelled by name and caller
+	// Should only be set on startup
+	OnDeprecation func(d Deprecation)
+
+	deprecations = struct {
+		mu     sync.Mutex
+		usages map[Deprecation]int64
+	}{usages: make(map[Deprecation]int64)}
+)
+
+// Deprecated records call of deprecated function name, which should be
+// called first thing within the deprecated function, along with its
+// replacement, so consumers can find and migrate every call site
+// incrementally while the old function keeps working
+//
+// First call from every call site is logged at warn level, if
+// DeprecationWarnings is set, and every call is passed to OnDeprecation
+func Deprecated(name, replacement string) {
+	d := Deprecation{Name: name, Replacement: replacement}
+
+	// Skip Deprecated and the deprecated function itself
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		d.Caller = file + ":" + strconv.Itoa(line)
+
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			d.Function = fn.Name()
+		}
+	}
+
+	deprecations.mu.Lock()
+	deprecations.usages[d]++
+	first := deprecations.usages[d] == 1
+	deprecations.mu.Unlock()
+
+	if first && DeprecationWarnings {
+		Logger.WithFields(logrus.Fields{
+			"deprecated":  d.Name,
+			"replacement": d.Replacement,
+			"caller":      d.Caller,
+			"function":    d.Function,
+		}).Warnf("%s is deprecated; use %s", d.Name, d.Replacement)
+	}
+
+	if OnDeprecation != nil {
+		OnDeprecation(d)
+	}
+}
+
+// DeprecationUsages returns calls of deprecated functions recorded so
+// far by call site, sorted by name and then caller, eg. to be served by
+// a diagnostics endpoint
+func DeprecationUsages() []DeprecationUsage {
+	deprecations.mu.Lock()
+	defer deprecations.mu.Unlock()
+
+	usages := make([]DeprecationUsage, 0, len(deprecations.usages))
+
+	for d, count := range deprecations.usages {
+		usages = append(usages, DeprecationUsage{Deprecation: d, Count: count})
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Name != usages[j].Name {
+			return usages[i].Name < usages[j].Name
+		}
+
+		return usages[i].Caller < usages[j].Caller
+	})
+
+	return usages
+}
@@ -0,0 +1,58 @@
+package httputil
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func deprecatedFoo() {
+	Deprecated("httputil.deprecatedFoo", "httputil.Foo")
+}
+
+func TestDeprecated(t *testing.T) {
+	var logs bytes.Buffer
+	var calls []Deprecation
+
+	Logger.SetOutput(&logs)
+	OnDeprecation = func(d Deprecation) {
+		calls = append(calls, d)
+	}
+
+	defer func() {
+		Logger.SetOutput(os.Stderr)
+		OnDeprecation = nil
+	}()
+
+	for i := 0; i < 3; i++ {
+		deprecatedFoo()
+	}
+
+	deprecatedFoo()
+
+	if n := strings.Count(logs.String(), "httputil.deprecatedFoo is deprecated"); n != 2 {
+		t.Errorf("should log once per call site; got %d logs: %s", n, logs.String())
+	}
+	if !strings.Contains(logs.String(), "deprecation_test.go") {
+		t.Errorf("log should contain caller; got %s", logs.String())
+	}
+	if len(calls) != 4 {
+		t.Fatalf("OnDeprecation should be called on every call; got %d calls", len(calls))
+	}
+	if calls[0].Function != "github.com/TravisS25/httputil.TestDeprecated" {
+		t.Errorf("function should be caller of deprecated function; got %s", calls[0].Function)
+	}
+
+	var counts []int64
+
+	for _, u := range DeprecationUsages() {
+		if u.Name == "httputil.deprecatedFoo" {
+			counts = append(counts, u.Count)
+		}
+	}
+
+	if len(counts) != 2 || counts[0]+counts[1] != 4 {
+		t.Errorf("should count usages by call site; got %v", counts)
+	}
+}
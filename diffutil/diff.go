@@ -0,0 +1,336 @@
+// Package diffutil compares decoded forms against the instances they
+// update so only changed columns are written and audited
+package diffutil
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotStruct is returned from Compare when form or instance is
+	// not a struct or pointer to struct
+	ErrNotStruct = errors.New("diffutil: value must be struct or pointer to struct")
+)
+
+// Change is a single field whose value differs between instance and form
+type Change struct {
+	// Column is db tag of field of instance, else its json name
+	Column string `json:"column"`
+
+	// Field is json name of field of instance
+	Field string `json:"field"`
+
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Changes are changes returned from Compare ordered by column
+type Changes []Change
+
+// Values returns map of column to new value of every change, which can
+// be used to build an update of only changed columns eg. with
+// dbutil#UpdateWithVersion or UpdateQuery
+func (c Changes) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(c))
+
+	for _, v := range c {
+		values[v.Column] = v.After
+	}
+
+	return values
+}
+
+// Columns returns column of every change
+func (c Changes) Columns() []string {
+	columns := make([]string, 0, len(c))
+
+	for _, v := range c {
+		columns = append(columns, v.Column)
+	}
+
+	return columns
+}
+
+// Has returns whether column changed
+func (c Changes) Has(column string) bool {
+	for _, v := range c {
+		if v.Column == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON returns json object of field to before and after values eg.
+// {"name":{"before":"foo","after":"bar"}}, which is used as payload of
+// audit entries
+func (c Changes) JSON() string {
+	type beforeAfter struct {
+		Before interface{} `json:"before"`
+		After  interface{} `json:"after"`
+	}
+
+	payload := make(map[string]beforeAfter, len(c))
+
+	for _, v := range c {
+		payload[v.Field] = beforeAfter{Before: v.Before, After: v.After}
+	}
+
+	b, err := json.Marshal(payload)
+
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+// Compare returns changes between instance, as loaded from database, and
+// form, as decoded from request, which don't have to be the same type
+//
+// Fields of form are matched to fields of instance by db tag, else by
+// json name, where fields with tag "-" or without a match are ignored
+// Nil pointer fields of form are treated as not submitted and ignored,
+// so forms with pointer fields can be used for partial updates
+//
+// Values are compared after dereferencing pointers, converting form
+// values to the type of instance fields where possible and calling
+// driver.Valuer, so eg. formutil#Int64 is compared to int64
+func Compare(instance, form interface{}) (Changes, error) {
+	instanceFields, err := structFields(instance)
+
+	if err != nil {
+		return nil, err
+	}
+
+	formFields, err := structFields(form)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byColumn := make(map[string]field, len(instanceFields))
+	byJSON := make(map[string]field, len(instanceFields))
+
+	for _, f := range instanceFields {
+		if f.column != "" {
+			byColumn[f.column] = f
+		}
+
+		byJSON[f.json] = f
+	}
+
+	changes := make(Changes, 0)
+
+	for _, f := range formFields {
+		before, ok := byColumn[f.column]
+
+		if !ok || f.column == "" {
+			if before, ok = byJSON[f.json]; !ok {
+				continue
+			}
+		}
+
+		after, ok := deref(f.value)
+
+		if !ok {
+			continue
+		}
+
+		beforeVal, _ := deref(before.value)
+		afterVal := convert(after, before.value.Type())
+
+		if equal(beforeVal, afterVal) {
+			continue
+		}
+
+		column := before.column
+
+		if column == "" {
+			column = before.json
+		}
+
+		changes = append(changes, Change{
+			Column: column,
+			Field:  before.json,
+			Before: valueInterface(beforeVal),
+			After:  valueInterface(afterVal),
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Column < changes[j].Column
+	})
+
+	return changes, nil
+}
+
+// UpdateQuery returns update query, with "?" placeholders, of values
+// for row of table with idColumn of id along with its args, where
+// columns are in sorted order
+// Query should be rebound for databases that don't use "?"
+func UpdateQuery(table, idColumn string, id interface{}, values map[string]interface{}) (string, []interface{}) {
+	columns := make([]string, 0, len(values))
+
+	for k := range values {
+		if k != idColumn {
+			columns = append(columns, k)
+		}
+	}
+
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns)+1)
+
+	for _, c := range columns {
+		sets = append(sets, c+" = ?")
+		args = append(args, values[c])
+	}
+
+	args = append(args, id)
+
+	return fmt.Sprintf(
+		"update %s set %s where %s = ?",
+		table,
+		strings.Join(sets, ", "),
+		idColumn,
+	), args
+}
+
+type field struct {
+	column string
+	json   string
+	value  reflect.Value
+}
+
+// structFields returns exported fields of v, including fields of
+// embedded structs
+func structFields(v interface{}) ([]field, error) {
+	val := reflect.ValueOf(v)
+
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, ErrNotStruct
+		}
+
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	return appendFields(nil, val), nil
+}
+
+func appendFields(fields []field, val reflect.Value) []field {
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		column := tagName(sf.Tag.Get("db"))
+		jsonName := tagName(sf.Tag.Get("json"))
+
+		if column == "-" || jsonName == "-" {
+			continue
+		}
+
+		if sf.Anonymous && column == "" && sf.Type.Kind() == reflect.Struct {
+			fields = appendFields(fields, val.Field(i))
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if jsonName == "" {
+			jsonName = sf.Name
+		}
+
+		fields = append(fields, field{column: column, json: jsonName, value: val.Field(i)})
+	}
+
+	return fields
+}
+
+func tagName(tag string) string {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i]
+	}
+
+	return tag
+}
+
+// deref returns value pointed to by v, where false is returned for nil
+// pointers
+func deref(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, false
+		}
+
+		v = v.Elem()
+	}
+
+	return v, true
+}
+
+// convert converts v to typ, or the type typ points to, if possible
+func convert(v reflect.Value, typ reflect.Type) reflect.Value {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if v.Type() == typ {
+		return v
+	}
+
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil && dv != nil {
+			v = reflect.ValueOf(dv)
+		}
+	}
+
+	// Converting integers to strings results in runes so is skipped
+	if !v.Type().ConvertibleTo(typ) || (typ.Kind() == reflect.String && v.Kind() != reflect.String) {
+		return v
+	}
+
+	return v.Convert(typ)
+}
+
+func equal(a, b reflect.Value) bool {
+	if !a.IsValid() || (a.Kind() == reflect.Ptr && a.IsNil()) {
+		return false
+	}
+
+	if at, ok := a.Interface().(time.Time); ok {
+		if bt, ok := b.Interface().(time.Time); ok {
+			return at.Equal(bt)
+		}
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil
+	}
+
+	return v.Interface()
+}
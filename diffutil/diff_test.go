@@ -0,0 +1,101 @@
+package diffutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type base struct {
+	ID int64 `json:"id" db:"id"`
+}
+
+type item struct {
+	base
+	Name      string    `json:"name" db:"name"`
+	Price     float64   `json:"price" db:"price"`
+	Note      *string   `json:"note" db:"note"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	Secret    string    `json:"-" db:"secret"`
+}
+
+type itemForm struct {
+	Name      *string    `json:"name"`
+	Price     float32    `json:"price"`
+	Note      *string    `json:"note"`
+	CreatedAt *time.Time `json:"createdAt"`
+	Secret    string     `json:"secret"`
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	name := "bar"
+	note := "note"
+	instance := item{
+		base:      base{ID: 1},
+		Name:      "foo",
+		Price:     2,
+		CreatedAt: now,
+		Secret:    "secret",
+	}
+	form := itemForm{
+		Name:      &name,
+		Price:     2,
+		Note:      &note,
+		CreatedAt: &now,
+		Secret:    "changed",
+	}
+
+	changes, err := Compare(&instance, form)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	expected := Changes{
+		{Column: "name", Field: "name", Before: "foo", After: "bar"},
+		{Column: "note", Field: "note", Before: nil, After: "note"},
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("should have changes %v; got %v", expected, changes)
+	}
+
+	values := changes.Values()
+
+	if len(values) != 2 || values["name"] != "bar" || values["note"] != "note" {
+		t.Errorf("should have values of changes; got %v", values)
+	}
+	if changes.JSON() != `{"name":{"before":"foo","after":"bar"},"note":{"before":null,"after":"note"}}` {
+		t.Errorf("should have json of changes; got %s", changes.JSON())
+	}
+
+	form.Name = nil
+	form.Note = nil
+
+	if changes, err = Compare(instance, &form); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if len(changes) != 0 {
+		t.Errorf("should ignore nil fields of form; got %v", changes)
+	}
+
+	if _, err = Compare(instance, "form"); err != ErrNotStruct {
+		t.Errorf("should return ErrNotStruct; got %v", err)
+	}
+}
+
+func TestUpdateQuery(t *testing.T) {
+	query, args := UpdateQuery("item", "id", 1, map[string]interface{}{
+		"price": 3,
+		"name":  "foo",
+		"id":    2,
+	})
+
+	if query != "update item set name = ?, price = ? where id = ?" {
+		t.Errorf("should have update query; got %s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"foo", 3, 1}) {
+		t.Errorf("should have args; got %v", args)
+	}
+}
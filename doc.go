@@ -0,0 +1,30 @@
+// Package httputil is a collection of utilities used to build http apis
+//
+// Optional integrations are behind build tags so apps that only need
+// packages like queryutil or formutil don't have to pull in dependencies
+// of integrations they don't use.  Every integration is included by
+// default and can be excluded with the following tags:
+//
+//	noredis     cacheutil#ClientCache and cacheutil#RedisStore (go-redis, redistore)
+//	nosessions  cacheutil#SessionStore and its implementations (gorilla/sessions)
+//	nogomail    mailutil#MailMessenger (gomail)
+//	novault     confutil#VaultSecretResolver (vault)
+//	noaws       confutil#AWSSecretResolver (aws-sdk-go)
+//
+// eg. go build -tags "noredis nosessions nogomail novault noaws"
+//
+// Core packages depend on interfaces like cacheutil#CacheStore and
+// mailutil#SendMessage rather than these integrations so any other
+// implementation can be used in their place
+//
+// With nosessions, the session based handlers of apiutil (login, logout,
+// impersonation, accepting invites and the auth middleware) and
+// apitest#Server are excluded, as are startutil#GetStoreSettings and
+// session store of startutil#App
+//
+// With noredis, startutil returns startutil#ErrRedisExcluded when a
+// cache or session store is configured to use redis
+//
+// With nogomail, startutil#GetMessenger returns nil so mailer of
+// startutil#App must be set with startutil#WithMailer
+package httputil
@@ -0,0 +1,230 @@
+// Package enumutil is registry of enums, eg. status of an order, used to
+// validate and (de)serialize enum values of forms, responses and filters
+package enumutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Value is a single value of Enum
+type Value struct {
+	// Name is value used by api eg. "pending"
+	Name string
+
+	// DB is value stored in database eg. 1 or "P"
+	// Default value is Name
+	DB interface{}
+}
+
+// UnknownValueError is returned when value is not part of enum
+type UnknownValueError struct {
+	Enum  string
+	Value interface{}
+	Valid []string
+}
+
+func (u *UnknownValueError) Error() string {
+	return fmt.Sprintf(
+		"enumutil: %v is not a valid %s; must be one of %s",
+		u.Value,
+		u.Enum,
+		strings.Join(u.Valid, ", "),
+	)
+}
+
+// Enum is named list of values
+type Enum struct {
+	name   string
+	values []Value
+	byName map[string]Value
+	byDB   map[interface{}]Value
+}
+
+// New returns *Enum of values without registering it
+// Panics if values have duplicate names
+func New(name string, values ...Value) *Enum {
+	e := &Enum{
+		name:   name,
+		values: make([]Value, 0, len(values)),
+		byName: make(map[string]Value, len(values)),
+		byDB:   make(map[interface{}]Value, len(values)),
+	}
+
+	for _, v := range values {
+		if _, ok := e.byName[v.Name]; ok {
+			panic(fmt.Sprintf("enumutil: duplicate value \"%s\" of %s", v.Name, name))
+		}
+		if v.DB == nil {
+			v.DB = v.Name
+		}
+
+		e.values = append(e.values, v)
+		e.byName[v.Name] = v
+		e.byDB[v.DB] = v
+	}
+
+	return e
+}
+
+// Name returns name of enum
+func (e *Enum) Name() string {
+	return e.name
+}
+
+// Names returns names of values of enum in the order they were declared
+func (e *Enum) Names() []string {
+	names := make([]string, 0, len(e.values))
+
+	for _, v := range e.values {
+		names = append(names, v.Name)
+	}
+
+	return names
+}
+
+// Values returns values of enum in the order they were declared
+func (e *Enum) Values() []Value {
+	return append([]Value(nil), e.values...)
+}
+
+// Valid returns whether name is value of enum
+func (e *Enum) Valid(name string) bool {
+	_, ok := e.byName[name]
+	return ok
+}
+
+// DBValue returns database value of name
+// Returns *UnknownValueError if name is not value of enum
+func (e *Enum) DBValue(name string) (interface{}, error) {
+	if v, ok := e.byName[name]; ok {
+		return v.DB, nil
+	}
+
+	return nil, e.unknown(name)
+}
+
+// FromDB returns name of database value dbValue
+// Database values of type []byte are compared as strings and int
+// types as int64 as they're scanned by drivers
+// Returns *UnknownValueError if dbValue is not value of enum
+func (e *Enum) FromDB(dbValue interface{}) (string, error) {
+	if v, ok := e.byDB[dbValue]; ok {
+		return v.Name, nil
+	}
+
+	for _, v := range e.values {
+		if normalize(v.DB) == normalize(dbValue) {
+			return v.Name, nil
+		}
+	}
+
+	return "", e.unknown(dbValue)
+}
+
+// Transform can be used as queryutil#FieldConfig#ValueTransform so
+// filters of enum fields are converted to their database value and
+// unknown values are rejected instead of returning no results
+func (e *Enum) Transform(value interface{}) (interface{}, error) {
+	name, ok := value.(string)
+
+	if !ok {
+		return nil, e.unknown(value)
+	}
+
+	return e.DBValue(name)
+}
+
+// MarshalValue returns json of name of database value dbValue
+// Can be used within MarshalJSON of types of enum fields
+func (e *Enum) MarshalValue(dbValue interface{}) ([]byte, error) {
+	name, err := e.FromDB(dbValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(name)
+}
+
+// UnmarshalValue returns database value of json string data
+// Can be used within UnmarshalJSON of types of enum fields
+func (e *Enum) UnmarshalValue(data []byte) (interface{}, error) {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return nil, e.unknown(string(data))
+	}
+
+	return e.DBValue(name)
+}
+
+func (e *Enum) unknown(value interface{}) error {
+	return &UnknownValueError{Enum: e.name, Value: value, Valid: e.Names()}
+}
+
+func normalize(value interface{}) interface{} {
+	switch t := value.(type) {
+	case []byte:
+		return string(t)
+	case int:
+		return int64(t)
+	case int8:
+		return int64(t)
+	case int16:
+		return int64(t)
+	case int32:
+		return int64(t)
+	}
+
+	return value
+}
+
+var (
+	registry   = make(map[string]*Enum)
+	registryMu sync.RWMutex
+)
+
+// Register returns *Enum of values and registers it by name so it can be
+// looked up with Get
+// Panics if enum with name is already registered
+func Register(name string, values ...Value) *Enum {
+	e := New(name, values...)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("enumutil: enum \"%s\" already registered", name))
+	}
+
+	registry[name] = e
+	return e
+}
+
+// Get returns registered enum by name
+func Get(name string) (*Enum, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	e, ok := registry[name]
+	return e, ok
+}
+
+// Registered returns names of registered enums in sorted order
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+
+	for k := range registry {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,72 @@
+package enumutil
+
+import (
+	"testing"
+)
+
+func TestEnum(t *testing.T) {
+	status := Register(
+		"status",
+		Value{Name: "pending", DB: 1},
+		Value{Name: "shipped", DB: 2},
+	)
+
+	if e, ok := Get("status"); !ok || e != status {
+		t.Fatalf("should get registered enum")
+	}
+	if !status.Valid("pending") || status.Valid("lost") {
+		t.Errorf("should only have valid values")
+	}
+
+	v, err := status.Transform("shipped")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if v != 2 {
+		t.Errorf("should transform to db value; got %v", v)
+	}
+
+	_, err = status.Transform("lost")
+
+	if _, ok := err.(*UnknownValueError); !ok {
+		t.Fatalf("should return *UnknownValueError; got %v", err)
+	}
+	if err.Error() != "enumutil: lost is not a valid status; must be one of pending, shipped" {
+		t.Errorf("should have clear error; got %s", err.Error())
+	}
+
+	name, err := status.FromDB(int64(2))
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if name != "shipped" {
+		t.Errorf("should have name shipped; got %s", name)
+	}
+
+	b, err := status.MarshalValue(1)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if string(b) != `"pending"` {
+		t.Errorf("should marshal name; got %s", b)
+	}
+
+	if v, err = status.UnmarshalValue([]byte(`"pending"`)); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if v != 1 {
+		t.Errorf("should unmarshal to db value; got %v", v)
+	}
+	if _, err = status.UnmarshalValue([]byte(`3`)); err == nil {
+		t.Errorf("should have error")
+	}
+
+	color := New("color", Value{Name: "red"})
+
+	if v, _ = color.DBValue("red"); v != "red" {
+		t.Errorf("should default db value to name; got %v", v)
+	}
+}
@@ -0,0 +1,118 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	// CodeNotFound is code of Error returned from NotFound
+	CodeNotFound = "not_found"
+
+	// CodeConflict is code of Error returned from Conflict
+	CodeConflict = "conflict"
+
+	// CodeInvalid is code of Error returned from Invalid
+	CodeInvalid = "invalid"
+
+	// CodeBadRequest is code of Error returned from BadRequest
+	CodeBadRequest = "bad_request"
+
+	// CodeUnauthorized is code of Error returned from Unauthorized
+	CodeUnauthorized = "unauthorized"
+
+	// CodeTimeout is code of Error for requests that took too long
+	CodeTimeout = "timeout"
+
+	// CodeInternal is code of Error for server errors
+	CodeInternal = "internal"
+)
+
+// Error is domain error that knows how it should be sent to clients
+// Use apiutil#WriteError to write it, or any error wrapping it, as
+// response
+type Error struct {
+	// Code is machine readable code of error eg. CodeNotFound
+	Code string `json:"code"`
+
+	// Message is human readable message sent to clients
+	Message string `json:"message"`
+
+	// Status is http status sent to clients
+	Status int `json:"-"`
+
+	// Fields are errors keyed by form field, if any
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Err is underlying error, which is logged but never sent to clients
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+	}
+
+	return e.Message
+}
+
+// Unwrap returns underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns copy of e with err as underlying error
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// NotFound returns *Error with 404 status
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message, Status: http.StatusNotFound}
+}
+
+// Conflict returns *Error with 409 status
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message, Status: http.StatusConflict}
+}
+
+// Invalid returns *Error with 406 status, which is the status used for
+// invalid input throughout this library, and errors keyed by form field
+// fields can be nil
+func Invalid(message string, fields map[string]string) *Error {
+	return &Error{Code: CodeInvalid, Message: message, Status: http.StatusNotAcceptable, Fields: fields}
+}
+
+// BadRequest returns *Error with 400 status for malformed requests,
+// such as query params that can't be parsed
+func BadRequest(message string) *Error {
+	return &Error{Code: CodeBadRequest, Message: message, Status: http.StatusBadRequest}
+}
+
+// Unauthorized returns *Error with 401 status
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message, Status: http.StatusUnauthorized}
+}
+
+// AsError returns first *Error within chain of err, following both
+// Unwrap and Cause, else returns nil
+func AsError(err error) *Error {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e
+		}
+
+		switch v := err.(type) {
+		case interface{ Unwrap() error }:
+			err = v.Unwrap()
+		case interface{ Cause() error }:
+			err = v.Cause()
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
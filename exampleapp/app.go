@@ -0,0 +1,146 @@
+//go:build exampleapp
+// +build exampleapp
+
+// Package exampleapp is a reference application that wires confutil,
+// startutil, the apiutil middleware, a queryutil backed CRUD resource,
+// formutil validation and caching together
+//
+// It's only built with the "exampleapp" build tag so it's not part of
+// the library, and its tests, run with
+//
+//	go test -tags exampleapp ./exampleapp/...
+//
+// act as an integration test of the whole package against an in-memory
+// sqlite database
+//
+// Copy this package as a starting point of new applications, see
+// exampleapp/cmd/exampleapp for the main package
+package exampleapp
+
+import (
+	"net/http"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/formutil"
+	"github.com/TravisS25/httputil/muxutil"
+	"github.com/TravisS25/httputil/queryutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+const (
+	// ItemsPath is path of item resource
+	ItemsPath = "/api/items"
+
+	// ItemListCacheKey is cache key deleted every time items are written
+	ItemListCacheKey = "items:list"
+
+	// Schema creates tables of application
+	// Real applications should use dbutil#Migrate instead
+	Schema = `
+	create table if not exists items (
+		id integer primary key,
+		name varchar(100) not null,
+		price integer not null
+	)`
+)
+
+// ItemFields are fields items can be filtered, sorted and grouped by
+var ItemFields = map[string]queryutil.FieldConfig{
+	"id": {
+		DBField: "items.id",
+		OperationConf: queryutil.OperationConfig{
+			CanFilterBy: true,
+			CanSortBy:   true,
+		},
+	},
+	"name": {
+		DBField: "items.name",
+		OperationConf: queryutil.OperationConfig{
+			CanFilterBy: true,
+			CanSortBy:   true,
+			CanGroupBy:  true,
+		},
+	},
+	"price": {
+		DBField: "items.price",
+		OperationConf: queryutil.OperationConfig{
+			CanFilterBy: true,
+			CanSortBy:   true,
+		},
+	},
+}
+
+// ItemForm is form of create and update requests of items
+type ItemForm struct {
+	Name  string `json:"name" db:"name"`
+	Price int64  `json:"price" db:"price"`
+}
+
+// itemValidator implements apiutil#ResourceValidator
+type itemValidator struct {
+	*formutil.FormValidation
+}
+
+func (v itemValidator) Validate(r *http.Request, instance interface{}) (interface{}, error) {
+	var form ItemForm
+
+	if err := formutil.CheckBodyAndDecode(r, &form); err != nil {
+		return nil, err
+	}
+
+	return form, validation.ValidateStruct(
+		&form,
+		validation.Field(&form.Name, formutil.Required, validation.Length(0, 100)),
+		validation.Field(&form.Price, validation.Min(0)),
+	)
+}
+
+// NewHandler returns handler of app with every route and middleware
+// App#Start should be called before NewHandler
+func NewHandler(app *startutil.App) http.Handler {
+	provider := dbutil.NewDBProvider(app.DB())
+	bindVar := sqlx.QUESTION
+	filter := "filters"
+	sort := "sorts"
+
+	if app.DBType() == dbutil.Postgres {
+		bindVar = sqlx.DOLLAR
+	}
+
+	router := mux.NewRouter()
+	router.Use(
+		apiutil.NewRecoveryHandler(apiutil.RecoveryHandlerConfig{}).MiddlewareFunc,
+		apiutil.NewLimitHandler(muxutil.PathRegex, apiutil.LimitHandlerConfig{}).MiddlewareFunc,
+		apiutil.NewLanguageHandler(apiutil.LanguageHandlerConfig{}).MiddlewareFunc,
+		apiutil.NewDBHandler(provider).MiddlewareFunc,
+		apiutil.NewQueryTagHandler(apiutil.QueryTagConfig{}).MiddlewareFunc,
+	)
+
+	items := apiutil.NewResource(provider.DB(), apiutil.ResourceConfig{
+		Table:  "items",
+		Fields: ItemFields,
+		ParamConf: queryutil.ParamConfig{
+			Filter: &filter,
+			Sort:   &sort,
+		},
+		QueryConf: queryutil.QueryConfig{
+			SQLBindVar: &bindVar,
+			Dialect:    app.DBType(),
+		},
+		Validator:  itemValidator{FormValidation: app.FormValidator()},
+		CacheStore: app.Cache(),
+		CacheKeys:  []string{ItemListCacheKey},
+	})
+	items.Register(router, ItemsPath)
+
+	router.HandleFunc(apiutil.HealthPath, apiutil.HealthHandler(apiutil.HealthConfig{
+		Pingers: app.Pingers(),
+	})).Methods(http.MethodGet).Name("health")
+
+	return router
+}
@@ -0,0 +1,140 @@
+//go:build exampleapp
+// +build exampleapp
+
+package exampleapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/TravisS25/httputil/apiutil/apitest"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+func newTestApp(t *testing.T) *startutil.App {
+	db, err := sqlx.Open(dbutil.Sqlite, dbutil.SqliteMemory)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	// Every connection to ":memory:" is a new database
+	db.SetMaxOpenConns(1)
+
+	// The cookie store keeps sessions working without the nosessions tag
+	settings := &confutil.Settings{
+		Store: confutil.StoreConfig{
+			CookieStore: &confutil.CookieStore{AuthKey: "exampleapp-test-auth-key"},
+		},
+	}
+
+	app := startutil.NewApp(
+		settings,
+		startutil.AppConfig{DBType: dbutil.Sqlite},
+		startutil.WithDB(&dbutil.DB{DB: db}),
+		startutil.WithCache(cachetest.NewMemoryCache()),
+	)
+
+	if err = app.Start(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if _, err = app.DB().Exec(Schema); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		app.Stop()
+		db.Close()
+	})
+
+	return app
+}
+
+func TestItems(t *testing.T) {
+	app := newTestApp(t)
+	handler := NewHandler(app)
+
+	app.Cache().Set(ItemListCacheKey, []byte("stale"), 0)
+
+	apitest.RunScenarioCases(t, []apitest.ScenarioCase{
+		{
+			TestName: "crud",
+			Handler:  handler,
+			Steps: []apitest.ScenarioStep{
+				{
+					StepName:       "create",
+					Method:         http.MethodPost,
+					RequestURL:     ItemsPath,
+					Form:           ItemForm{Name: "widget", Price: 5},
+					ExpectedStatus: http.StatusCreated,
+					Extract:        map[string]string{"id": "id"},
+				},
+				{
+					StepName:       "invalid",
+					Method:         http.MethodPost,
+					RequestURL:     ItemsPath,
+					Form:           ItemForm{Price: -1},
+					ExpectedStatus: http.StatusNotAcceptable,
+				},
+				{
+					StepName:       "detail",
+					Method:         http.MethodGet,
+					RequestURL:     ItemsPath + "/{{id}}",
+					ExpectedStatus: http.StatusOK,
+					Extract:        map[string]string{"name": "name"},
+					PostResponseValidation: func(vars apitest.ScenarioVars) error {
+						if vars["name"] != "widget" {
+							t.Errorf("should return created item; got %v", vars["name"])
+						}
+
+						return nil
+					},
+				},
+				{
+					StepName:       "update",
+					Method:         http.MethodPut,
+					RequestURL:     ItemsPath + "/{{id}}",
+					Form:           ItemForm{Name: "gadget", Price: 7},
+					ExpectedStatus: http.StatusOK,
+				},
+				{
+					StepName:       "list",
+					Method:         http.MethodGet,
+					RequestURL:     ItemsPath + "?filters=" + url.QueryEscape(`[{"field":"name","operator":"eq","value":"gadget"}]`),
+					ExpectedStatus: http.StatusOK,
+					Extract:        map[string]string{"count": "count", "price": "data.0.price"},
+					PostResponseValidation: func(vars apitest.ScenarioVars) error {
+						if vars["count"] != float64(1) || vars["price"] != float64(7) {
+							t.Errorf("should list updated item; got %v", vars)
+						}
+
+						return nil
+					},
+				},
+				{
+					StepName:       "delete",
+					Method:         http.MethodDelete,
+					RequestURL:     ItemsPath + "/{{id}}",
+					ExpectedStatus: http.StatusNoContent,
+				},
+				{
+					StepName:       "deleted",
+					Method:         http.MethodGet,
+					RequestURL:     ItemsPath + "/{{id}}",
+					ExpectedStatus: http.StatusNotFound,
+				},
+			},
+		},
+	})
+
+	if _, err := app.Cache().Get(ItemListCacheKey); err == nil {
+		t.Errorf("should delete list cache key after writes")
+	}
+}
@@ -0,0 +1,56 @@
+//go:build exampleapp
+// +build exampleapp
+
+// Command exampleapp serves package exampleapp with settings of a config
+// file, see confutil#Load
+//
+// Usage:
+//
+//	go run -tags exampleapp ./exampleapp/cmd/exampleapp -config=config.yaml -profile=dev
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/lib/pq"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/exampleapp"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+func main() {
+	path := flag.String("config", "config.yaml", "config file of application")
+	profile := flag.String("profile", "", "profile of config file to apply")
+	flag.Parse()
+
+	if err := run(*path, *profile); err != nil {
+		fmt.Fprintf(os.Stderr, "exampleapp: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(path, profile string) error {
+	settings, err := confutil.Load(path, confutil.LoadConfig{
+		Profile:   profile,
+		ExpandEnv: true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	app := startutil.NewApp(settings, startutil.AppConfig{})
+
+	if err = app.Start(); err != nil {
+		return err
+	}
+	if _, err = app.DB().Exec(exampleapp.Schema); err != nil {
+		app.Stop()
+		return err
+	}
+
+	return app.Serve(exampleapp.NewHandler(app))
+}
@@ -0,0 +1,689 @@
+// Package exportutil exports rows of list queries to csv and xlsx files
+// in object storage asynchronously, so large exports don't time out
+// requests, and emails users a signed link to download them
+package exportutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/mailutil"
+	"github.com/TravisS25/httputil/queryutil"
+	"github.com/TravisS25/httputil/reportutil"
+	"github.com/TravisS25/httputil/storageutil"
+)
+
+const (
+	// ExportTopic is outbox topic used by Exporter#Create
+	ExportTopic = "export"
+
+	// DefaultJobTable is table used to store export jobs if
+	// ExporterConfig#TableName is not set
+	DefaultJobTable = "export_job"
+
+	// DefaultEmailSubject is subject of emails sent when exports are
+	// done if ExporterConfig#EmailSubject is not set
+	DefaultEmailSubject = "Your export is ready"
+
+	// DefaultEmailTemplate is html template of emails sent when exports
+	// are done if ExporterConfig#EmailTemplate is not set
+	// Templates are executed with EmailData
+	DefaultEmailTemplate = `<p>Your {{.Job.Name}} export is ready to <a href="{{.URL}}">download</a>.</p>` +
+		`<p>This link expires {{.Expires.Format "Jan 2, 2006 3:04 PM MST"}}.</p>`
+)
+
+// Format is file format of exports
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// ContentType returns content type of files of format
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return httputil.ContentTypeXLSX
+	}
+
+	return httputil.ContentTypeCSV
+}
+
+// Status is status of export job
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+const (
+	jobPostgresTableQuery = `
+	create table if not exists %s (
+		id bigserial not null primary key,
+		user_id varchar(255) not null,
+		email varchar(255) not null,
+		name varchar(255) not null,
+		format varchar(10) not null,
+		params text not null,
+		status varchar(20) not null,
+		progress int not null default 0,
+		total int not null default 0,
+		object_name varchar(1024) not null default '',
+		error text not null,
+		created_at timestamp not null default current_timestamp,
+		updated_at timestamp not null default current_timestamp
+	)`
+
+	jobMysqlTableQuery = `
+	create table if not exists %s (
+		id bigint not null auto_increment primary key,
+		user_id varchar(255) not null,
+		email varchar(255) not null,
+		name varchar(255) not null,
+		format varchar(10) not null,
+		params text not null,
+		status varchar(20) not null,
+		progress int not null default 0,
+		total int not null default 0,
+		object_name varchar(1024) not null default '',
+		error text not null,
+		created_at timestamp not null default current_timestamp,
+		updated_at timestamp not null default current_timestamp
+	)`
+
+	jobColumns = "id, user_id, email, name, format, params, status, progress, total, object_name, error, created_at, updated_at"
+)
+
+var (
+	// ErrExportNotRegistered is returned from Exporter#Create if name of
+	// job wasn't registered with Exporter#Register
+	ErrExportNotRegistered = errors.New("exportutil: export not registered")
+
+	// ErrInvalidFormat is returned from Exporter#Create if format of job
+	// is not FormatCSV or FormatXLSX
+	ErrInvalidFormat = errors.New("exportutil: invalid format")
+)
+
+// Job is export of rows of a registered Definition filtered, sorted
+// and grouped by url query params of the request that created it
+type Job struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"userId"`
+	Email  string `json:"-"`
+	Name   string `json:"name"`
+	Format Format `json:"format"`
+
+	// Params are encoded url query params list query is built with
+	Params string `json:"params"`
+
+	Status Status `json:"status"`
+
+	// Progress is number of rows exported so far
+	Progress int `json:"progress"`
+
+	// Total is number of rows to export if Definition#CountQuery is
+	// set, else 0 until job is done
+	Total int `json:"total"`
+
+	ObjectName string    `json:"-"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Definition is list query that can be exported
+// Query is built the same way as queryutil#GetQueriedResults without
+// limit and offset so every matching row is exported
+type Definition struct {
+	Query string
+
+	// CountQuery, if set, is used to set Job#Total before exporting
+	// so clients can show progress as a percentage
+	CountQuery string
+
+	// PrependVars returns vars of placeholders of Query, and CountQuery,
+	// before the ones generated from url query params eg. to scope the
+	// export to the user of job
+	PrependVars func(job Job) []interface{}
+
+	Fields    map[string]queryutil.FieldConfig
+	ParamConf queryutil.ParamConfig
+	QueryConf queryutil.QueryConfig
+
+	// Columns are columns of exported files in order
+	// Default is every column returned from Query with column names
+	// as headers
+	Columns []reportutil.Column
+}
+
+// EmailData is data DefaultEmailTemplate and ExporterConfig#EmailTemplate
+// are executed with
+type EmailData struct {
+	Job     Job
+	URL     string
+	Expires time.Time
+}
+
+// ExporterConfig is config struct used for Exporter
+type ExporterConfig struct {
+	// TableName is table used to store export jobs
+	// Default is DefaultJobTable
+	TableName string
+
+	// DBType is the type of database eg. dbutil#Postgres
+	// This is used for placeholder binding and table creation
+	// Default is dbutil#Postgres
+	DBType string
+
+	// Bucket is bucket exported files are stored in
+	Bucket storageutil.Bucket
+
+	// ObjectPrefix is prepended to names of exported files
+	// Default is "exports/"
+	ObjectPrefix string
+
+	// ProgressInterval is number of rows exported between updates of
+	// Job#Progress
+	// Default is 1000
+	ProgressInterval int
+
+	// Messenger sends emails with download links of exports to
+	// Job#Email once they are done
+	// No email is sent if nil
+	Messenger mailutil.SendMessage
+
+	// EmailFrom is address emails are sent from
+	EmailFrom string
+
+	// EmailSubject is subject of emails
+	// Default is DefaultEmailSubject
+	EmailSubject string
+
+	// EmailTemplate is html template of body of emails
+	// Default is DefaultEmailTemplate
+	EmailTemplate string
+
+	// DownloadURL is absolute url DownloadHandler is registered at,
+	// which is signed along with the id of the job for download links
+	DownloadURL string
+
+	// URLSecret is secret download links are signed with, see
+	// apiutil#SignURL
+	URLSecret []byte
+
+	// LinkExpiry is how long download links are valid
+	// Default is 24 hours
+	LinkExpiry time.Duration
+
+	// PresignExpiry is how long the presigned storage urls download
+	// links redirect to are valid
+	// Default is 5 minutes
+	PresignExpiry time.Duration
+
+	// OutboxConfig is config used to write jobs to the outbox in
+	// Exporter#Create
+	OutboxConfig dbutil.OutboxConfig
+}
+
+func (e *ExporterConfig) setDefaults() {
+	if e.TableName == "" {
+		e.TableName = DefaultJobTable
+	}
+	if e.DBType == "" {
+		e.DBType = dbutil.Postgres
+	}
+	if e.ObjectPrefix == "" {
+		e.ObjectPrefix = "exports/"
+	}
+	if e.ProgressInterval <= 0 {
+		e.ProgressInterval = 1000
+	}
+	if e.EmailSubject == "" {
+		e.EmailSubject = DefaultEmailSubject
+	}
+	if e.EmailTemplate == "" {
+		e.EmailTemplate = DefaultEmailTemplate
+	}
+	if e.LinkExpiry <= 0 {
+		e.LinkExpiry = time.Hour * 24
+	}
+	if e.PresignExpiry <= 0 {
+		e.PresignExpiry = time.Minute * 5
+	}
+}
+
+// CreateJobTable creates export job table if it does not exist
+func CreateJobTable(db httputil.XODB, config ExporterConfig) error {
+	config.setDefaults()
+	query := jobPostgresTableQuery
+
+	if config.DBType == dbutil.Mysql {
+		query = jobMysqlTableQuery
+	}
+
+	_, err := db.Exec(fmt.Sprintf(query, config.TableName))
+	return err
+}
+
+// Exporter creates export jobs of registered definitions and runs them
+// from the outbox, see dbutil#OutboxRelay, so exports are written to
+// storage in the background
+type Exporter struct {
+	db          httputil.DBInterface
+	definitions map[string]Definition
+	email       *template.Template
+	config      ExporterConfig
+}
+
+// NewExporter returns *Exporter
+// Returns error if ExporterConfig#EmailTemplate can't be parsed
+func NewExporter(db httputil.DBInterface, config ExporterConfig) (*Exporter, error) {
+	config.setDefaults()
+
+	email, err := template.New("export-email").Parse(config.EmailTemplate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{
+		db:          db,
+		definitions: make(map[string]Definition),
+		email:       email,
+		config:      config,
+	}, nil
+}
+
+// Register registers def as export called name
+// Queries of def are registered with queryutil#Registry
+func (e *Exporter) Register(name string, def Definition) {
+	queryutil.Registry.Register(def.Fields, def.Query)
+
+	if def.CountQuery != "" {
+		queryutil.Registry.Register(def.Fields, def.CountQuery)
+	}
+
+	e.definitions[name] = def
+}
+
+// Create inserts job, setting its id and status, and writes it to the
+// outbox within tx so it's only run if tx commits
+func (e *Exporter) Create(tx httputil.XODB, job *Job) error {
+	if _, ok := e.definitions[job.Name]; !ok {
+		return ErrExportNotRegistered
+	}
+	if job.Format != FormatCSV && job.Format != FormatXLSX {
+		return ErrInvalidFormat
+	}
+
+	job.Status = StatusPending
+	query := fmt.Sprintf(
+		"insert into %s (user_id, email, name, format, params, status, error) values (?, ?, ?, ?, ?, ?, '')",
+		e.config.TableName,
+	)
+	args := []interface{}{job.UserID, job.Email, job.Name, job.Format, job.Params, job.Status}
+
+	// Postgres doesn't support LastInsertId so the id has to be
+	// returned from the query
+	if e.config.DBType != dbutil.Mysql {
+		if err := tx.QueryRow(e.rebind(query+" returning id"), args...).Scan(&job.ID); err != nil {
+			return err
+		}
+	} else {
+		result, err := tx.Exec(e.rebind(query), args...)
+
+		if err != nil {
+			return err
+		}
+		if job.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+
+	return dbutil.WriteOutboxWithConfig(tx, e.config.OutboxConfig, ExportTopic, strconv.FormatInt(job.ID, 10))
+}
+
+// Job returns job with id
+// Returns sql.ErrNoRows if job doesn't exist
+func (e *Exporter) Job(id int64) (Job, error) {
+	var job Job
+
+	err := e.db.QueryRow(
+		e.rebind(fmt.Sprintf("select %s from %s where id = ?", jobColumns, e.config.TableName)),
+		id,
+	).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Email,
+		&job.Name,
+		&job.Format,
+		&job.Params,
+		&job.Status,
+		&job.Progress,
+		&job.Total,
+		&job.ObjectName,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+
+	return job, err
+}
+
+// Publisher returns dbutil#Publisher that runs jobs written by Create
+// Messages of other topics return error so if the outbox is shared,
+// messages should be routed to this publisher by topic
+func (e *Exporter) Publisher() dbutil.Publisher {
+	return dbutil.PublisherFunc(func(ctx context.Context, msg dbutil.OutboxMessage) error {
+		if msg.Topic != ExportTopic {
+			return fmt.Errorf("exportutil: invalid topic '%s'", msg.Topic)
+		}
+
+		id, err := strconv.ParseInt(string(msg.Payload), 10, 64)
+
+		if err != nil {
+			return err
+		}
+
+		return e.Run(ctx, id)
+	})
+}
+
+// Run exports rows of job with id to storage, updating its progress
+// every ExporterConfig#ProgressInterval rows, and emails a download
+// link to the user of the job once done
+//
+// Jobs that are already done are skipped as outbox messages can be
+// published more than once
+// If the export fails, the job is marked as failed and the error is
+// returned so it's retried by the outbox
+func (e *Exporter) Run(ctx context.Context, id int64) error {
+	job, err := e.Job(id)
+
+	if err != nil {
+		return err
+	}
+	if job.Status == StatusDone {
+		return nil
+	}
+
+	if err = e.export(ctx, &job); err != nil {
+		if updateErr := e.update(job.ID, "status = ?, error = ?", StatusFailed, err.Error()); updateErr != nil {
+			return updateErr
+		}
+
+		return err
+	}
+
+	if err = e.sendEmail(job); err != nil {
+		// Export is done so it shouldn't be retried because of email
+		httputil.CaptureError(ctx, err, map[string]string{"source": "export"})
+	}
+
+	return nil
+}
+
+func (e *Exporter) export(ctx context.Context, job *Job) error {
+	def, ok := e.definitions[job.Name]
+
+	if !ok {
+		return ErrExportNotRegistered
+	}
+
+	params, err := url.ParseQuery(job.Params)
+
+	if err != nil {
+		return err
+	}
+
+	var prependVars []interface{}
+
+	if def.PrependVars != nil {
+		prependVars = def.PrependVars(*job)
+	}
+
+	queryConf := def.QueryConf
+	queryConf.ExcludeLimitWithOffset = true
+	req := formRequest(params)
+
+	if def.CountQuery != "" {
+		countQuery := def.CountQuery
+
+		if job.Total, err = queryutil.GetCountResults(
+			&countQuery,
+			prependVars,
+			def.Fields,
+			req,
+			e.db,
+			def.ParamConf,
+			queryConf,
+		); err != nil {
+			return err
+		}
+	}
+
+	if err = e.update(job.ID, "status = ?, progress = 0, total = ?, error = ''", StatusRunning, job.Total); err != nil {
+		return err
+	}
+
+	query := def.Query
+	rower, err := queryutil.GetQueriedResults(
+		&query,
+		prependVars,
+		def.Fields,
+		req,
+		e.db,
+		def.ParamConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	job.ObjectName = fmt.Sprintf("%s%d/%s.%s", e.config.ObjectPrefix, job.ID, job.Name, job.Format)
+
+	pr, pw := io.Pipe()
+	putErr := make(chan error, 1)
+
+	go func() {
+		_, err := e.config.Bucket.PutObject(
+			e.config.Bucket.Name,
+			job.ObjectName,
+			pr,
+			-1,
+			minio.PutObjectOptions{ContentType: job.Format.ContentType()},
+		)
+
+		// Unblocks writes if storage fails before every row is written
+		pr.CloseWithError(err)
+		putErr <- err
+	}()
+
+	err = e.writeRows(ctx, pw, job, def.Columns, rower)
+	pw.CloseWithError(err)
+
+	if storeErr := <-putErr; err == nil {
+		err = storeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	job.Status = StatusDone
+	job.Total = job.Progress
+
+	return e.update(
+		job.ID,
+		"status = ?, progress = ?, total = ?, object_name = ?",
+		job.Status,
+		job.Progress,
+		job.Total,
+		job.ObjectName,
+	)
+}
+
+// writeRows writes header and every row of rower to w in format of job
+func (e *Exporter) writeRows(
+	ctx context.Context,
+	w io.Writer,
+	job *Job,
+	columns []reportutil.Column,
+	rower httputil.Rower,
+) error {
+	names, err := rower.Columns()
+
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		for _, name := range names {
+			columns = append(columns, reportutil.Column{Header: name, Field: name})
+		}
+	}
+
+	writer, err := newRowWriter(w, job.Format)
+
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+
+	for i, col := range columns {
+		record[i] = col.Header
+	}
+
+	if err = writer.WriteRow(record); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(names))
+	valuePtrs := make([]interface{}, len(names))
+	row := make(map[string]interface{}, len(names))
+	job.Progress = 0
+
+	for rower.Next() {
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return err
+		}
+
+		for i, name := range names {
+			row[name] = values[i]
+		}
+
+		for i, col := range columns {
+			if col.Format != nil {
+				record[i] = col.Format(row[col.Field])
+			} else {
+				record[i] = reportutil.FormatValue(row[col.Field])
+			}
+		}
+
+		if err = writer.WriteRow(record); err != nil {
+			return err
+		}
+
+		job.Progress++
+
+		if job.Progress%e.config.ProgressInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			if err = e.update(job.ID, "progress = ?", job.Progress); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Close()
+}
+
+// DownloadLink returns link to DownloadHandler for job signed with
+// ExporterConfig#URLSecret along with when it expires
+func (e *Exporter) DownloadLink(job Job) (string, time.Time, error) {
+	u, err := url.Parse(e.config.DownloadURL)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	query := u.Query()
+	query.Set(JobIDParam, strconv.FormatInt(job.ID, 10))
+	u.RawQuery = query.Encode()
+
+	expires := time.Now().Add(e.config.LinkExpiry)
+	link, err := apiutil.SignURL(e.config.URLSecret, u.String(), e.config.LinkExpiry)
+	return link, expires, err
+}
+
+func (e *Exporter) sendEmail(job Job) error {
+	if e.config.Messenger == nil || job.Email == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	link, expires, err := e.DownloadLink(job)
+
+	if err != nil {
+		return err
+	}
+
+	if err = e.email.Execute(&buf, EmailData{Job: job, URL: link, Expires: expires}); err != nil {
+		return err
+	}
+
+	return mailutil.SendEmail(
+		[]string{job.Email},
+		e.config.EmailFrom,
+		e.config.EmailSubject,
+		nil,
+		buf.Bytes(),
+		e.config.Messenger,
+	)
+}
+
+// update sets columns of job with id, along with updated_at
+func (e *Exporter) update(id int64, set string, args ...interface{}) error {
+	query := fmt.Sprintf(
+		"update %s set %s, updated_at = current_timestamp where id = ?",
+		e.config.TableName,
+		set,
+	)
+
+	_, err := e.db.Exec(e.rebind(query), append(args, id)...)
+	return err
+}
+
+func (e *Exporter) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(e.config.DBType), query)
+}
+
+// formRequest is queryutil#FormRequest of url query params of job
+type formRequest url.Values
+
+func (f formRequest) FormValue(key string) string {
+	return url.Values(f).Get(key)
+}
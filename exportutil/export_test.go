@@ -0,0 +1,161 @@
+package exportutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+	"github.com/TravisS25/httputil/mailutil"
+	"github.com/TravisS25/httputil/queryutil"
+	"github.com/TravisS25/httputil/reportutil"
+	"github.com/TravisS25/httputil/storageutil"
+	"github.com/TravisS25/httputil/storageutil/storagetest"
+)
+
+type mockMessenger struct {
+	messages []*mailutil.Message
+}
+
+func (m *mockMessenger) Send(msg *mailutil.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func jobRows(format Format, status Status) *dbtest.Rows {
+	now := time.Now()
+
+	return dbtest.NewRows(strings.Split(jobColumns, ", ")...).AddRow(
+		int64(1), "10", "user@example.com", "users", string(format),
+		"sorts="+url.QueryEscape(`[{"field": "name", "dir": "desc"}]`),
+		string(status), 0, 0, "", "", now, now,
+	)
+}
+
+func TestExporterRun(t *testing.T) {
+	var stored []byte
+
+	messenger := &mockMessenger{}
+	storage := &storagetest.MockStorageReaderWriter{
+		PutObjectFunc: func(bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (int64, error) {
+			if bucketName != "exports" || objectName != "exports/1/users.csv" {
+				t.Errorf("got bucket %s and object %s", bucketName, objectName)
+			}
+
+			stored, _ = ioutil.ReadAll(reader)
+			return int64(len(stored)), nil
+		},
+	}
+
+	db := dbtest.NewExpectDB(t)
+	exporter, err := NewExporter(db, ExporterConfig{
+		Bucket:           storageutil.Bucket{StorageReaderWriter: storage, Name: "exports"},
+		ProgressInterval: 1,
+		Messenger:        messenger,
+		DownloadURL:      "https://example.com/exports/download",
+		URLSecret:        []byte("secret"),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	exporter.Register("users", Definition{
+		Query: "select id, name from users",
+		Fields: map[string]queryutil.FieldConfig{
+			"name": {DBField: "name", OperationConf: queryutil.OperationConfig{CanSortBy: true}},
+		},
+		QueryConf: queryutil.QueryConfig{Dialect: dbutil.Postgres},
+		Columns:   []reportutil.Column{{Header: "Name", Field: "name"}, {Header: "ID", Field: "id"}},
+	})
+
+	db.ExpectQuery("select id, user_id").WithArgs(int64(1)).WillReturnRows(jobRows(FormatCSV, StatusPending))
+	db.ExpectExec(`update export_job set status = \$1`).
+		WithArgs(StatusRunning, 0, int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectQuery(`select id, name from users order by\s+name desc`).
+		WillReturnRows(dbtest.NewRows("id", "name").AddRow(int64(2), "bob").AddRow(int64(1), "al, jr"))
+	db.ExpectExec(`update export_job set progress = \$1`).
+		WithArgs(1, int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`update export_job set progress = \$1`).
+		WithArgs(2, int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec(`update export_job set status = \$1, progress = \$2, total = \$3, object_name = \$4`).
+		WithArgs(StatusDone, 2, 2, "exports/1/users.csv", int64(1)).
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	if err = exporter.Run(context.Background(), 1); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+
+	if string(stored) != "Name,ID\nbob,2\n\"al, jr\",1\n" {
+		t.Errorf("got csv %q", stored)
+	}
+	if len(messenger.messages) != 1 || !strings.Contains(messenger.messages[0].GetMessage(), "signature=") {
+		t.Errorf("should email signed download link")
+	}
+
+	// Done jobs aren't exported again
+	db.ExpectQuery("select id, user_id").WithArgs(int64(1)).WillReturnRows(jobRows(FormatCSV, StatusDone))
+
+	if err = exporter.Run(context.Background(), 1); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := newRowWriter(&buf, FormatXLSX)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	writer.WriteRow([]string{"Name", "Notes"})
+	writer.WriteRow([]string{"bob", "<b>&</b>"})
+
+	if err = writer.Close(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+
+	if err != nil {
+		t.Fatalf("should be zip; got %s", err.Error())
+	}
+
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		rc, _ := f.Open()
+		sheet, _ := ioutil.ReadAll(rc)
+		rc.Close()
+
+		if !bytes.Contains(sheet, []byte("<t xml:space=\"preserve\">&lt;b&gt;&amp;&lt;/b&gt;</t>")) {
+			t.Errorf("should escape values; got %s", sheet)
+		}
+
+		return
+	}
+
+	t.Errorf("should have sheet")
+}
@@ -0,0 +1,161 @@
+package exportutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+)
+
+const (
+	// FormatParam is url query param of CreateHandler requests that
+	// sets format of export
+	// Default format is FormatCSV
+	FormatParam = "format"
+
+	// JobIDParam is url query param of StatusHandler and DownloadHandler
+	// requests that holds id of job
+	JobIDParam = "id"
+
+	invalidFormatTxt = "Invalid export format"
+	jobNotFoundTxt   = "Export not found"
+)
+
+// CreateHandler returns handler that creates job exporting definition
+// registered as name, with the url query params of the request, for the
+// user of the request and responds 202 with the Job
+//
+// userEmail returns email download link is sent to, it can be nil if
+// ExporterConfig#Messenger is not set
+func (e *Exporter) CreateHandler(name string, userEmail func(r *http.Request) (string, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		params := r.URL.Query()
+		format := Format(params.Get(FormatParam))
+		params.Del(FormatParam)
+
+		if format == "" {
+			format = FormatCSV
+		}
+		if format != FormatCSV && format != FormatXLSX {
+			apiutil.WriteError(w, httputil.Invalid(invalidFormatTxt, nil))
+			return
+		}
+
+		job := Job{
+			UserID: apiutil.GetUserID(r),
+			Name:   name,
+			Format: format,
+			Params: params.Encode(),
+		}
+
+		if userEmail != nil {
+			if job.Email, err = userEmail(r); apiutil.HasError(w, err) {
+				return
+			}
+		}
+
+		tx, err := e.db.Begin()
+
+		if apiutil.HasError(w, err) {
+			return
+		}
+
+		if err = e.Create(tx, &job); err != nil {
+			tx.Rollback()
+			apiutil.WriteError(w, err)
+			return
+		}
+
+		if apiutil.HasError(w, tx.Commit()) {
+			return
+		}
+
+		w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+		w.WriteHeader(http.StatusAccepted)
+		apiutil.SendPayload(w, job)
+	})
+}
+
+// StatusHandler returns handler that responds with the Job of JobIDParam
+// so clients can poll its progress
+// Jobs of other users respond 404
+func (e *Exporter) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		job, ok := e.requestJob(w, r)
+
+		if !ok {
+			return
+		}
+		if job.UserID != apiutil.GetUserID(r) {
+			apiutil.WriteError(w, httputil.NotFound(jobNotFoundTxt))
+			return
+		}
+
+		w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+		apiutil.SendPayload(w, job)
+	})
+}
+
+// DownloadHandler returns handler for download links of DownloadLink
+// which redirects to a presigned url of the exported file
+//
+// Links are only verified by signature, as they're opened from emails,
+// so the handler doesn't need a session
+func (e *Exporter) DownloadHandler(config apiutil.SignedURLHandlerConfig) http.Handler {
+	signed := apiutil.NewSignedURLHandler(e.config.URLSecret, config)
+
+	return signed.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		job, ok := e.requestJob(w, r)
+
+		if !ok {
+			return
+		}
+		if job.Status != StatusDone {
+			apiutil.WriteError(w, httputil.NotFound(jobNotFoundTxt))
+			return
+		}
+
+		reqParams := url.Values{}
+		reqParams.Set(
+			"response-content-disposition",
+			fmt.Sprintf(`attachment; filename="%s.%s"`, job.Name, job.Format),
+		)
+
+		u, err := e.config.Bucket.PresignedGetObject(
+			e.config.Bucket.Name,
+			job.ObjectName,
+			e.config.PresignExpiry,
+			reqParams,
+		)
+
+		if apiutil.HasError(w, err) {
+			return
+		}
+
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	}))
+}
+
+// requestJob returns job of JobIDParam of r
+// Writes error and returns false if id is invalid or job doesn't exist
+func (e *Exporter) requestJob(w http.ResponseWriter, r *http.Request) (Job, bool) {
+	id, err := strconv.ParseInt(r.URL.Query().Get(JobIDParam), 10, 64)
+
+	if err != nil {
+		apiutil.WriteError(w, httputil.NotFound(jobNotFoundTxt))
+		return Job{}, false
+	}
+
+	job, err := e.Job(id)
+
+	if apiutil.HasError(w, err) {
+		return Job{}, false
+	}
+
+	return job, true
+}
@@ -0,0 +1,140 @@
+package exportutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+)
+
+// xlsxFiles are every file of xlsx files other than the sheet, which
+// is streamed by xlsxWriter
+var xlsxFiles = []struct {
+	name    string
+	content string
+}{
+	{
+		"[Content_Types].xml",
+		xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`,
+	},
+	{
+		"_rels/.rels",
+		xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+	},
+	{
+		"xl/workbook.xml",
+		xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`,
+	},
+	{
+		"xl/_rels/workbook.xml.rels",
+		xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+	},
+}
+
+// rowWriter writes rows of exports to files of a single format
+type rowWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+func newRowWriter(w io.Writer, format Format) (rowWriter, error) {
+	if format == FormatXLSX {
+		return newXLSXWriter(w)
+	}
+
+	return &csvWriter{writer: csv.NewWriter(w)}, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvWriter) WriteRow(values []string) error {
+	return c.writer.Write(values)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// xlsxWriter streams rows to a single sheet of xlsx file, where every
+// value is written as inline string, so rows don't have to be held in
+// memory like they would with shared strings
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	buf   bytes.Buffer
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+
+	for _, f := range xlsxFiles {
+		fw, err := zw.Create(f.name)
+
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(fw, f.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.WriteString(
+		sheet,
+		xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteRow(values []string) error {
+	x.buf.Reset()
+	x.buf.WriteString("<row>")
+
+	for _, v := range values {
+		x.buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+
+		if err := xml.EscapeText(&x.buf, []byte(v)); err != nil {
+			return err
+		}
+
+		x.buf.WriteString("</t></is></c>")
+	}
+
+	x.buf.WriteString("</row>")
+
+	_, err := x.sheet.Write(x.buf.Bytes())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+
+	return x.zw.Close()
+}
@@ -0,0 +1,181 @@
+// Package flagutil allows features to be turned on and off at runtime,
+// for everyone or only for certain users and groups
+package flagutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/cacheutil/reqcache"
+)
+
+var (
+	// ErrFlagNotFound is returned by Store if flag has no stored state
+	ErrFlagNotFound = errors.New("flagutil: flag not found")
+
+	// FlagsCtxKey is key used to store *Flags within request context
+	// by Flags#MiddlewareFunc
+	FlagsCtxKey = apiutil.MiddlewareKey{KeyName: "flags"}
+)
+
+type reqcacheKey string
+
+// Definition defines a flag and its default value which is used until
+// the flag is toggled
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Flag is stored state of a flag which overrides the default value
+// of its definition
+// Flag is enabled for a request if Enabled is true or user of request
+// is within Users or any group of user is within Groups
+type Flag struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Users   []string `json:"users"`
+	Groups  []string `json:"groups"`
+}
+
+// Store is used to get and set state of flags
+type Store interface {
+	// GetFlag returns state of flag or ErrFlagNotFound if flag
+	// has never been set
+	GetFlag(name string) (Flag, error)
+	SetFlag(flag Flag) error
+}
+
+// Config is config struct used for Flags
+type Config struct {
+	// UserID returns id of user of request matched against Flag#Users
+	// Default is apiutil#GetUserID
+	UserID func(r *http.Request) string
+
+	// Groups returns groups of user of request matched against Flag#Groups
+	// Default is apiutil#GetGroupNames, which are the groups loaded
+	// into context by GroupHandler
+	Groups func(r *http.Request) []string
+}
+
+// Flags determines whether flags are enabled for requests based on
+// flag definitions and state within store
+type Flags struct {
+	store       Store
+	definitions map[string]Definition
+	names       []string
+	config      Config
+}
+
+// NewFlags returns *Flags
+func NewFlags(store Store, config Config, definitions ...Definition) *Flags {
+	if config.UserID == nil {
+		config.UserID = apiutil.GetUserID
+	}
+	if config.Groups == nil {
+		config.Groups = apiutil.GetGroupNames
+	}
+
+	f := &Flags{
+		store:       store,
+		definitions: make(map[string]Definition, len(definitions)),
+		names:       make([]string, 0, len(definitions)),
+		config:      config,
+	}
+
+	for _, d := range definitions {
+		if _, ok := f.definitions[d.Name]; !ok {
+			f.names = append(f.names, d.Name)
+		}
+
+		f.definitions[d.Name] = d
+	}
+
+	return f
+}
+
+// IsEnabled returns whether flag is enabled for r
+// Returns false if flag is not defined and the default value of flag
+// if its state can't be retrieved from store
+//
+// State of flag is only retrieved once per request if
+// reqcache#MiddlewareFunc comes before
+func (f *Flags) IsEnabled(r *http.Request, name string) bool {
+	def, ok := f.definitions[name]
+
+	if !ok {
+		return false
+	}
+
+	val, err := reqcache.GetOrSet(r.Context(), reqcacheKey(name), func() (interface{}, error) {
+		return f.store.GetFlag(name)
+	})
+
+	if err != nil {
+		if err != ErrFlagNotFound {
+			httputil.Logger.Errorf("flagutil: getting flag '%s': %s", name, err.Error())
+		}
+
+		return def.Default
+	}
+
+	flag := val.(Flag)
+
+	if flag.Enabled {
+		return true
+	}
+
+	if userID := f.config.UserID(r); userID != "" {
+		for _, u := range flag.Users {
+			if u == userID {
+				return true
+			}
+		}
+	}
+
+	for _, group := range f.config.Groups(r) {
+		for _, g := range flag.Groups {
+			if g == group {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Definitions returns every flag definition in the order they were
+// passed to NewFlags
+func (f *Flags) Definitions() []Definition {
+	definitions := make([]Definition, 0, len(f.names))
+
+	for _, name := range f.names {
+		definitions = append(definitions, f.definitions[name])
+	}
+
+	return definitions
+}
+
+// MiddlewareFunc adds f to request context so IsEnabled can be used
+func (f *Flags) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), FlagsCtxKey, f)))
+	})
+}
+
+// IsEnabled returns whether flag is enabled for r using *Flags added
+// to context by Flags#MiddlewareFunc
+// Returns false if Flags#MiddlewareFunc was not used
+func IsEnabled(r *http.Request, name string) bool {
+	f, ok := r.Context().Value(FlagsCtxKey).(*Flags)
+
+	if !ok {
+		return false
+	}
+
+	return f.IsEnabled(r, name)
+}
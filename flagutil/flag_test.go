@@ -0,0 +1,116 @@
+package flagutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/cacheutil/reqcache"
+)
+
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (c *countingStore) GetFlag(name string) (Flag, error) {
+	c.gets++
+	return c.Store.GetFlag(name)
+}
+
+func TestIsEnabled(t *testing.T) {
+	store := &countingStore{Store: NewCacheStore(cachetest.NewMemoryCache())}
+	flags := NewFlags(
+		store,
+		Config{
+			UserID: func(r *http.Request) string {
+				return r.Header.Get("user")
+			},
+		},
+		Definition{Name: "newCheckout", Default: true},
+		Definition{Name: "reports"},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), apiutil.GroupCtxKey, map[string]bool{"Manager": true}))
+
+	if !flags.IsEnabled(req, "newCheckout") {
+		t.Errorf("should use default value until flag is set")
+	}
+	if flags.IsEnabled(req, "reports") {
+		t.Errorf("should use default value until flag is set")
+	}
+	if flags.IsEnabled(req, "undefined") {
+		t.Errorf("undefined flag should not be enabled")
+	}
+
+	store.SetFlag(Flag{Name: "newCheckout"})
+	store.SetFlag(Flag{Name: "reports", Users: []string{"1"}, Groups: []string{"Admin"}})
+
+	if flags.IsEnabled(req, "newCheckout") {
+		t.Errorf("stored state should override default value")
+	}
+	if flags.IsEnabled(req, "reports") {
+		t.Errorf("should not be enabled for user outside of users and groups")
+	}
+
+	req.Header.Set("user", "1")
+
+	if !flags.IsEnabled(req, "reports") {
+		t.Errorf("should be enabled for user within users")
+	}
+
+	req.Header.Set("user", "2")
+	store.SetFlag(Flag{Name: "reports", Groups: []string{"Manager"}})
+
+	if !flags.IsEnabled(req, "reports") {
+		t.Errorf("should be enabled for user within groups")
+	}
+
+	store.gets = 0
+	handler := reqcache.MiddlewareFunc(flags.MiddlewareFunc(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		IsEnabled(r, "reports")
+		IsEnabled(r, "reports")
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if store.gets != 1 {
+		t.Errorf("flag should be retrieved once per request; got %d", store.gets)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	flags := NewFlags(NewCacheStore(cachetest.NewMemoryCache()), Config{}, Definition{Name: "reports"})
+	handler := NewAdminHandler(flags)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name": "undefined"}`)))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name": "reports", "enabled": true}`)))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var statuses []FlagStatus
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("should return valid json; got %s", err.Error())
+	}
+	if len(statuses) != 1 || statuses[0].State == nil || !statuses[0].State.Enabled {
+		t.Errorf("got statuses %+v", statuses)
+	}
+}
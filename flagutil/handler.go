@@ -0,0 +1,95 @@
+package flagutil
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/TravisS25/httputil/apiutil"
+)
+
+const (
+	flagNotFoundTxt = "Flag not found"
+)
+
+// FlagStatus is definition of flag along with its stored state
+// State is nil if flag has never been toggled
+type FlagStatus struct {
+	Definition
+	State *Flag `json:"state"`
+}
+
+// AdminHandler is handler used to list and toggle flags at runtime
+//
+// GET requests respond with []FlagStatus of every defined flag
+// PUT and POST requests take Flag as json body, which replaces the
+// stored state of the flag, and respond with the stored Flag
+//
+// AdminHandler does no authorization itself so it should be
+// registered behind RoutingHandler or similar
+type AdminHandler struct {
+	flags *Flags
+}
+
+// NewAdminHandler returns *AdminHandler
+func NewAdminHandler(flags *Flags) *AdminHandler {
+	return &AdminHandler{flags: flags}
+}
+
+func (a *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.list(w)
+	case http.MethodPut, http.MethodPost:
+		a.toggle(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *AdminHandler) list(w http.ResponseWriter) {
+	statuses := make([]FlagStatus, 0, len(a.flags.names))
+
+	for _, def := range a.flags.Definitions() {
+		status := FlagStatus{Definition: def}
+		flag, err := a.flags.store.GetFlag(def.Name)
+
+		switch err {
+		case nil:
+			status.State = &flag
+		case ErrFlagNotFound:
+		default:
+			apiutil.ServerError(w, err, "")
+			return
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	apiutil.SendPayload(w, statuses)
+}
+
+func (a *AdminHandler) toggle(w http.ResponseWriter, r *http.Request) {
+	var flag Flag
+
+	if apiutil.HasBodyError(w, r) {
+		return
+	}
+
+	if apiutil.HasDecodeError(w, json.NewDecoder(r.Body).Decode(&flag)) {
+		return
+	}
+
+	if _, ok := a.flags.definitions[flag.Name]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(flagNotFoundTxt))
+		return
+	}
+
+	if apiutil.HasServerError(w, a.flags.store.SetFlag(flag), "") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	apiutil.SendPayload(w, flag)
+}
@@ -0,0 +1,229 @@
+package flagutil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// FlagKey is used as a key when storing flags in cache
+	FlagKey = "flag-%s"
+
+	// DefaultTable is default table used by DBStore
+	DefaultTable = "feature_flag"
+
+	// DefaultCacheTTL is default ttl used by CachedStore
+	DefaultCacheTTL = time.Second * 30
+)
+
+// CacheStore is Store that stores flags within cacheutil#CacheStore
+type CacheStore struct {
+	cache cacheutil.CacheStore
+}
+
+// NewCacheStore returns *CacheStore
+func NewCacheStore(cache cacheutil.CacheStore) *CacheStore {
+	return &CacheStore{cache: cache}
+}
+
+// GetFlag returns state of flag from cache
+func (c *CacheStore) GetFlag(name string) (Flag, error) {
+	var flag Flag
+
+	flagBytes, err := c.cache.Get(fmt.Sprintf(FlagKey, name))
+
+	if err != nil {
+		if err == cacheutil.ErrCacheNil {
+			return flag, ErrFlagNotFound
+		}
+
+		return flag, err
+	}
+
+	if err = json.Unmarshal(flagBytes, &flag); err != nil {
+		return flag, err
+	}
+
+	return flag, nil
+}
+
+// SetFlag stores state of flag in cache with no expiration
+func (c *CacheStore) SetFlag(flag Flag) error {
+	flagBytes, err := json.Marshal(flag)
+
+	if err != nil {
+		return err
+	}
+
+	return c.cache.SetErr(fmt.Sprintf(FlagKey, flag.Name), flagBytes, 0)
+}
+
+// DBStore is Store that stores flags within a postgres table like:
+//
+//	create table feature_flag(
+//		name text primary key,
+//		enabled boolean not null default false,
+//		users jsonb not null default '[]',
+//		groups jsonb not null default '[]'
+//	);
+//
+// DBStore should generally be wrapped with CachedStore so the table
+// isn't queried on every request
+type DBStore struct {
+	db    httputil.XODB
+	table string
+}
+
+// NewDBStore returns *DBStore using table to store flags
+// If table is empty, DefaultTable is used
+// Returns error if table is not a valid identifier
+func NewDBStore(db httputil.XODB, table string) (*DBStore, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+
+	if !dbutil.ValidIdentifier(table) {
+		return nil, fmt.Errorf("flagutil: invalid table name '%s'", table)
+	}
+
+	return &DBStore{db: db, table: table}, nil
+}
+
+// GetFlag returns state of flag from table
+func (d *DBStore) GetFlag(name string) (Flag, error) {
+	var users, groups []byte
+
+	flag := Flag{Name: name}
+	err := d.db.QueryRow(
+		"select enabled, users, groups from "+d.table+" where name = $1",
+		name,
+	).Scan(&flag.Enabled, &users, &groups)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return flag, ErrFlagNotFound
+		}
+
+		return flag, err
+	}
+
+	if err = json.Unmarshal(users, &flag.Users); err != nil {
+		return flag, err
+	}
+	if err = json.Unmarshal(groups, &flag.Groups); err != nil {
+		return flag, err
+	}
+
+	return flag, nil
+}
+
+// SetFlag inserts or updates state of flag in table
+func (d *DBStore) SetFlag(flag Flag) error {
+	users, err := json.Marshal(nonNil(flag.Users))
+
+	if err != nil {
+		return err
+	}
+
+	groups, err := json.Marshal(nonNil(flag.Groups))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(
+		`insert into `+d.table+` (name, enabled, users, groups) values ($1, $2, $3, $4)
+		on conflict (name) do update set enabled = excluded.enabled,
+		users = excluded.users, groups = excluded.groups`,
+		flag.Name,
+		flag.Enabled,
+		string(users),
+		string(groups),
+	)
+
+	return err
+}
+
+type cachedFlag struct {
+	flag    Flag
+	err     error
+	expires time.Time
+}
+
+// CachedStore wraps Store and caches state of flags in memory for ttl
+// Flags set through CachedStore are updated immediately but flags set
+// by other instances can take up to ttl to be seen
+type CachedStore struct {
+	store Store
+	ttl   time.Duration
+
+	mu    sync.RWMutex
+	flags map[string]cachedFlag
+}
+
+// NewCachedStore returns *CachedStore
+// If ttl is 0, DefaultCacheTTL is used
+func NewCachedStore(store Store, ttl time.Duration) *CachedStore {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return &CachedStore{
+		store: store,
+		ttl:   ttl,
+		flags: make(map[string]cachedFlag),
+	}
+}
+
+// GetFlag returns state of flag from memory if not expired, else
+// from wrapped store
+// ErrFlagNotFound is cached the same as flags
+func (c *CachedStore) GetFlag(name string) (Flag, error) {
+	c.mu.RLock()
+	cached, ok := c.flags[name]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.flag, cached.err
+	}
+
+	flag, err := c.store.GetFlag(name)
+
+	if err != nil && err != ErrFlagNotFound {
+		return flag, err
+	}
+
+	c.mu.Lock()
+	c.flags[name] = cachedFlag{flag: flag, err: err, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return flag, err
+}
+
+// SetFlag sets state of flag in wrapped store and memory
+func (c *CachedStore) SetFlag(flag Flag) error {
+	if err := c.store.SetFlag(flag); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.flags[flag.Name] = cachedFlag{flag: flag, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
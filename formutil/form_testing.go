@@ -1,10 +1,11 @@
 package formutil
 
 import (
-	"fmt"
 	"testing"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+
+	"github.com/TravisS25/httputil"
 )
 
 // FormTestCase is config struct for function RunFormTests
@@ -56,7 +57,7 @@ func RunFormTestsV2(t *testing.T, deferFunc func(testName string), formTests []F
 				if formTest.IsValidForm {
 					//hasError = true
 					t.Errorf("Should be valid form; got %s\n", err)
-					fmt.Println(formTest.Form)
+					httputil.Debugf("formutil: form: %+v", formTest.Form)
 				}
 
 				validationErrors, ok := err.(validation.Errors)
@@ -138,7 +139,7 @@ func RunFormTests(t *testing.T, formTests []FormTestCase) {
 				if formTest.IsValidForm {
 					//hasError = true
 					t.Errorf("Should be valid form; got %s\n", err)
-					fmt.Println(formTest.Form)
+					httputil.Debugf("formutil: form: %+v", formTest.Form)
 				}
 
 				validationErrors, ok = err.(validation.Errors)
@@ -10,20 +10,17 @@ import (
 	"strings"
 	"time"
 
-	"github.com/TravisS25/httputil/apiutil"
-
 	"github.com/TravisS25/httputil/dbutil"
 
 	"github.com/TravisS25/httputil/confutil"
 
-	"github.com/go-redis/redis"
-
 	"github.com/TravisS25/httputil/queryutil"
 
 	"github.com/pkg/errors"
 
 	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/enumutil"
 	"github.com/TravisS25/httputil/timeutil"
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/jmoiron/sqlx"
@@ -45,6 +42,11 @@ var (
 	// The purpose of this is to simulate your cache backend failing and being forced
 	// to use database for data
 	AllowCacheConfig = true
+
+	// DateLayouts is the list of layouts Date and CoerceFilterDates will try
+	// when parsing date values
+	// If empty, timeutil.FlexibleLayouts is used
+	DateLayouts []string
 )
 
 var (
@@ -161,6 +163,49 @@ type CockroachFormSelection struct {
 	Value int64  `json:"value,string"`
 }
 
+// Date is used to decode json date values that can come in any of
+// the layouts from DateLayouts, such as ISO 8601, RFC3339 or
+// confutil.FormDateTimeLayout
+// The decoded value is always normalized to UTC
+type Date struct {
+	value *time.Time
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s == "" {
+		d.value = nil
+		return nil
+	}
+
+	t, err := timeutil.ParseFlexible(s, DateLayouts...)
+
+	if err != nil {
+		return err
+	}
+
+	d.value = &t
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.value == nil {
+		return json.Marshal(nil)
+	}
+
+	return json.Marshal(d.value.Format(time.RFC3339))
+}
+
+// Value returns decoded time or nil if no date was given
+func (d Date) Value() *time.Time {
+	return d.value
+}
+
 //----------------------- VALIDATION RULES ------------------------------
 
 // FormValidation is the main struct that other structs will
@@ -172,6 +217,7 @@ type FormValidation struct {
 
 	db    httputil.Querier
 	cache cacheutil.CacheStore
+	clock httputil.Clock
 }
 
 // IsValid returns *validRule based on isValid parameter
@@ -203,6 +249,7 @@ func (f *FormValidation) ValidateDate(
 		timezone:    timezone,
 		canBeFuture: canBeFuture,
 		canBePast:   canBePast,
+		clock:       f.clock,
 	}
 }
 
@@ -212,7 +259,8 @@ func (f *FormValidation) ValidateDate(
 // can be nil
 // Note of caution, the ids we are validating against should be the first placeholder parameters within the query passed
 //
-// If the ids passed happen to be type formutil#Int64, it will extract the values so it can be used against the query properly
+// If the ids passed happen to be type formutil#Int64 or formutil#Int64Slice, it will extract the values so it can be used against the query properly
+// String ids eg. uuids can be passed as []string and are compared to ids within cache as is
 //
 // The cacheConfig parameter can be nil if you do not need/have a cache backend
 func (f *FormValidation) ValidateIDs(
@@ -293,6 +341,12 @@ func (f *FormValidation) SetCache(cache cacheutil.CacheStore) {
 	f.cache = cache
 }
 
+// SetClock sets httputil.Clock ValidateDate compares dates against
+// Default is httputil#RealClock
+func (f *FormValidation) SetClock(clock httputil.Clock) {
+	f.clock = clock
+}
+
 // RequiredError is wrapper for the field parameter
 // Returns field name with custom required message
 func (f *FormValidation) RequiredError(field string) string {
@@ -426,6 +480,7 @@ type validateDateRule struct {
 	timezone      string
 	canBeFuture   bool
 	canBePast     bool
+	clock         httputil.Clock
 	internalError validation.InternalError
 }
 
@@ -455,14 +510,20 @@ func (v *validateDateRule) Validate(value interface{}) error {
 		return validation.NewInternalError(errors.New("Input must be string or *string"))
 	}
 
+	clock := v.clock
+
+	if clock == nil {
+		clock = httputil.RealClock
+	}
+
 	if v.timezone != "" {
-		currentTime, err = timeutil.GetCurrentLocalDateInUTC(v.timezone)
+		currentTime, err = timeutil.LocalDateInUTC(clock.Now(), v.timezone)
 
 		if err != nil {
 			return validation.NewInternalError(err)
 		}
 	} else {
-		current := time.Now().UTC()
+		current := clock.Now().UTC()
 		currentTime = &current
 	}
 
@@ -501,10 +562,54 @@ func (v *validateDateRule) Error(message string) *validateDateRule {
 		timezone:      v.timezone,
 		canBeFuture:   v.canBeFuture,
 		canBePast:     v.canBePast,
+		clock:         v.clock,
 		internalError: v.internalError,
 	}
 }
 
+// ValidateEnum returns rule that validates value is name, or list of
+// names, of value of enum
+// Empty values are valid so should be combined with validation.Required
+// if value is required
+func (f *FormValidation) ValidateEnum(enum *enumutil.Enum) *validateEnumRule {
+	return &validateEnumRule{enum: enum, message: InvalidTxt}
+}
+
+type validateEnumRule struct {
+	enum    *enumutil.Enum
+	message string
+}
+
+func (v *validateEnumRule) Validate(value interface{}) error {
+	value, isNil := validation.Indirect(value)
+
+	if isNil || validation.IsEmpty(value) {
+		return nil
+	}
+
+	switch t := value.(type) {
+	case string:
+		if !v.enum.Valid(t) {
+			return errors.New(v.message)
+		}
+	case []string:
+		for _, name := range t {
+			if !v.enum.Valid(name) {
+				return errors.New(v.message)
+			}
+		}
+	default:
+		return errors.New(v.message)
+	}
+
+	return nil
+}
+
+// Error sets the error message for the rule.
+func (v *validateEnumRule) Error(message string) *validateEnumRule {
+	return &validateEnumRule{enum: v.enum, message: message}
+}
+
 type validRule struct {
 	isValid       bool
 	internalError validation.InternalError
@@ -554,7 +659,7 @@ func (v *validateExistsRule) Validate(value interface{}) error {
 	if v.cacheConfig != nil && AllowCacheConfig {
 		exists, err := v.cacheConfig.Cache.HasKey(v.cacheConfig.Key)
 
-		if err != nil && err != redis.Nil {
+		if err != nil && err != cacheutil.ErrCacheNil {
 			return validation.NewInternalError(err)
 		}
 
@@ -621,8 +726,7 @@ func (v *validateUniquenessRule) Validate(value interface{}) error {
 		return nil
 	}
 
-	fmt.Printf("instance: %s\n", v.instanceValue)
-	fmt.Printf("value: %s\n", value)
+	httputil.Debugf("formutil: uniqueness instance: %v, value: %v", v.instanceValue, value)
 
 	if v.instanceValue == value {
 		return nil
@@ -634,7 +738,7 @@ func (v *validateUniquenessRule) Validate(value interface{}) error {
 	if v.cacheConfig != nil {
 		alreadyExists, err = v.cacheConfig.Cache.HasKey(v.cacheConfig.Key)
 
-		if err != nil && err != redis.Nil {
+		if err != nil && err != cacheutil.ErrCacheNil {
 			return validation.NewInternalError(err)
 		}
 
@@ -741,6 +845,33 @@ func (v *validateIDsRule) Validate(value interface{}) error {
 		} else {
 			emptySlice = true
 		}
+	case Int64Slice, []int64:
+		vals, ok := value.([]int64)
+
+		if !ok {
+			vals = value.(Int64Slice).Value()
+		}
+
+		if len(vals) != 0 {
+			expectedLen = len(vals)
+			ids = Int64Interfaces(vals)
+		} else {
+			emptySlice = true
+		}
+	case []string:
+		// String ids eg. uuids
+		vals := value.([]string)
+
+		if len(vals) != 0 {
+			expectedLen = len(vals)
+			ids = make([]interface{}, 0, len(vals))
+
+			for _, v := range vals {
+				ids = append(ids, v)
+			}
+		} else {
+			emptySlice = true
+		}
 	default:
 		expectedLen = 1
 		singleVal = value
@@ -859,6 +990,71 @@ func (v *validateIDsRule) Error(message string) *validateIDsRule {
 
 //----------------------- FUNCTIONS ------------------------------
 
+// CoerceFilterDates takes given filters and for every filter whose field is
+// within dateFields, parses its value(s) with DateLayouts and replaces them
+// with the UTC RFC3339 representation so the database receives one consistent
+// format no matter which layout the client sent
+//
+// Returns error if a value for a date field is not a string or can't be parsed
+func CoerceFilterDates(filters []queryutil.Filter, dateFields ...string) error {
+	coerce := func(field string, val interface{}) (interface{}, error) {
+		s, ok := val.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("formutil: date value for field '%s' must be string", field)
+		}
+
+		t, err := timeutil.ParseFlexible(s, DateLayouts...)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "formutil: invalid date for field '%s'", field)
+		}
+
+		return t.Format(time.RFC3339), nil
+	}
+
+	for i, f := range filters {
+		isDateField := false
+
+		for _, v := range dateFields {
+			if f.Field == v {
+				isDateField = true
+				break
+			}
+		}
+
+		if !isDateField || f.Value == nil {
+			continue
+		}
+
+		if list, ok := f.Value.([]interface{}); ok {
+			values := make([]interface{}, 0, len(list))
+
+			for _, t := range list {
+				val, err := coerce(f.Field, t)
+
+				if err != nil {
+					return err
+				}
+
+				values = append(values, val)
+			}
+
+			filters[i].Value = values
+		} else {
+			val, err := coerce(f.Field, f.Value)
+
+			if err != nil {
+				return err
+			}
+
+			filters[i].Value = val
+		}
+	}
+
+	return nil
+}
+
 func StandardizeSpaces(s string) string {
 	return strings.Join(strings.Fields(s), " ")
 }
@@ -926,7 +1122,7 @@ func GetFormSelections(
 	getFormSelectionsFromDB := func() ([]FormSelection, error) {
 		query, args, err = queryutil.InQueryRebind(bindVar, query, args...)
 
-		if apiutil.HasServerError(w, err, "") {
+		if hasServerError(w, err) {
 			return nil, err
 		}
 
@@ -968,17 +1164,19 @@ func GetFormSelections(
 
 	err = json.Unmarshal(jsonBytes, &forms)
 
-	if apiutil.HasServerError(w, err, "") {
+	if hasServerError(w, err) {
 		return nil, err
 	}
 
 	return forms, nil
 }
 
+// CheckBodyAndDecode decodes body of req into form based on the
+// Content-Type header of req, defaulting to json
+// See DecodeBody, which importing apiutil sets to apiutil#DecodeBody
 func CheckBodyAndDecode(req *http.Request, form interface{}) error {
 	if req.Body != nil {
-		dec := json.NewDecoder(req.Body)
-		err := dec.Decode(&form)
+		err := DecodeBody(req, form)
 
 		if err != nil {
 			confutil.CheckError(err, "")
@@ -1004,11 +1202,10 @@ func CheckBodyAndDecodeV2(req *http.Request, form interface{}, excludeMethods ..
 	}
 
 	if req.Body != nil {
-		dec := json.NewDecoder(req.Body)
-		err := dec.Decode(&form)
+		err := DecodeBody(req, form)
 
 		if err != nil {
-			fmt.Printf(err.Error())
+			httputil.Debugf("formutil: decode body: %s", err.Error())
 			return ErrInvalidJSON
 		}
 	} else {
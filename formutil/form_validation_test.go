@@ -3,7 +3,14 @@ package formutil
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/enumutil"
+	"github.com/TravisS25/httputil/queryutil"
 )
 
 type TestFormCacheValidation struct {
@@ -35,9 +42,37 @@ func TestInt64(t *testing.T) {
 }
 
 func TestValidateDateRule(t *testing.T) {
-	v := validateDateRule{}
-	v.Validate(nil)
-	t.Errorf("boom")
+	f := &FormValidation{}
+	f.SetClock(httputil.NewMockClock(time.Date(2019, time.March, 5, 2, 0, 0, 0, time.UTC)))
+
+	tests := []struct {
+		rule    *validateDateRule
+		value   string
+		message string
+	}{
+		{f.ValidateDate(confutil.DateLayout, "", false, true), "2019-03-04", ""},
+		{f.ValidateDate(confutil.DateLayout, "", false, true), "2019-03-06", InvalidFutureDateTxt},
+		{f.ValidateDate(confutil.DateLayout, "", true, false), "2019-03-04", InvalidPastDateTxt},
+		// Local date in New York is still the 4th
+		{f.ValidateDate(confutil.DateLayout, "America/New_York", true, false), "2019-03-04", ""},
+		{f.ValidateDate(confutil.DateLayout, "America/New_York", false, true), "2019-03-05", InvalidFutureDateTxt},
+		{f.ValidateDate(confutil.DateLayout, "", true, true), "03/04/2019", InvalidFormatTxt},
+	}
+
+	for _, test := range tests {
+		err := test.rule.Validate(test.value)
+
+		if test.message == "" && err != nil {
+			t.Errorf("%s should be valid; got %s", test.value, err.Error())
+		}
+		if test.message != "" && (err == nil || err.Error() != test.message) {
+			t.Errorf("%s should return %q; got %v", test.value, test.message, err)
+		}
+	}
+
+	if err := (&validateDateRule{}).Validate(nil); err != nil {
+		t.Errorf("nil should be valid; got %s", err.Error())
+	}
 }
 
 // func (t TestFormCacheValidation) Validate(item interface{}) error {
@@ -75,3 +110,194 @@ func TestValidateDateRule(t *testing.T) {
 func ExampleFormCache() {
 
 }
+
+func TestValidateEnumRule(t *testing.T) {
+	var f FormValidation
+	status := enumutil.New("status", enumutil.Value{Name: "pending"}, enumutil.Value{Name: "shipped"})
+	rule := f.ValidateEnum(status)
+
+	if err := rule.Validate("pending"); err != nil {
+		t.Errorf("should be valid; got %s", err.Error())
+	}
+	if err := rule.Validate(""); err != nil {
+		t.Errorf("empty value should be valid; got %s", err.Error())
+	}
+	if err := rule.Validate([]string{"pending", "lost"}); err == nil || err.Error() != InvalidTxt {
+		t.Errorf("should have invalid error; got %v", err)
+	}
+	if err := rule.Error("bad status").Validate("lost"); err == nil || err.Error() != "bad status" {
+		t.Errorf("should have custom error; got %v", err)
+	}
+}
+
+func TestDate(t *testing.T) {
+	defer func(layouts []string) {
+		DateLayouts = layouts
+	}(DateLayouts)
+
+	tests := []struct {
+		data    string
+		layouts []string
+		want    string
+		wantErr bool
+	}{
+		{`"2019-03-04T10:00:00-05:00"`, nil, "2019-03-04T15:00:00Z", false},
+		{`"2019-03-04"`, nil, "2019-03-04T00:00:00Z", false},
+		{`"03/04/2019"`, nil, "2019-03-04T00:00:00Z", false},
+		{`"0001-01-01T00:00:00Z"`, nil, "0001-01-01T00:00:00Z", false},
+		{`""`, nil, "", false},
+		{`null`, nil, "", false},
+		{`"not a date"`, nil, "", true},
+		{`20190304`, nil, "", true},
+		{`["2019-03-04"]`, nil, "", true},
+		{`"03/04/2019"`, []string{confutil.FormDateLayout}, "2019-03-04T00:00:00Z", false},
+		{`"2019-03-04"`, []string{confutil.FormDateLayout}, "", true},
+		{`"2019-03-04"`, []string{"not a layout"}, "", true},
+	}
+
+	for _, test := range tests {
+		DateLayouts = test.layouts
+
+		var d Date
+		err := json.Unmarshal([]byte(test.data), &d)
+
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s should return error with layouts %v", test.data, test.layouts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s should not return error; got %s", test.data, err.Error())
+			continue
+		}
+
+		if test.want == "" {
+			if d.Value() != nil {
+				t.Errorf("%s should have nil value; got %v", test.data, d.Value())
+			}
+		} else if d.Value() == nil || d.Value().Format(time.RFC3339) != test.want {
+			t.Errorf("%s should have value %s; got %v", test.data, test.want, d.Value())
+		}
+
+		want := "null"
+
+		if test.want != "" {
+			want = `"` + test.want + `"`
+		}
+
+		if b, err := json.Marshal(d); err != nil || string(b) != want {
+			t.Errorf("%s should marshal to %s; got %s, %v", test.data, want, b, err)
+		}
+	}
+}
+
+func TestCoerceFilterDates(t *testing.T) {
+	defer func(layouts []string) {
+		DateLayouts = layouts
+	}(DateLayouts)
+
+	tests := []struct {
+		name    string
+		layouts []string
+		filters []queryutil.Filter
+		want    []queryutil.Filter
+		wantErr bool
+	}{
+		{
+			name: "single values",
+			filters: []queryutil.Filter{
+				{Field: "created", Operator: "gte", Value: "03/04/2019"},
+				{Field: "name", Operator: "eq", Value: "03/04/2019"},
+			},
+			want: []queryutil.Filter{
+				{Field: "created", Operator: "gte", Value: "2019-03-04T00:00:00Z"},
+				{Field: "name", Operator: "eq", Value: "03/04/2019"},
+			},
+		},
+		{
+			name: "list values",
+			filters: []queryutil.Filter{
+				{Field: "created", Operator: "in", Value: []interface{}{"2019-03-04", "2019-03-04T10:00:00-05:00"}},
+			},
+			want: []queryutil.Filter{
+				{Field: "created", Operator: "in", Value: []interface{}{"2019-03-04T00:00:00Z", "2019-03-04T15:00:00Z"}},
+			},
+		},
+		{
+			name: "nil and empty list values",
+			filters: []queryutil.Filter{
+				{Field: "created", Operator: "isnull"},
+				{Field: "updated", Operator: "in", Value: []interface{}{}},
+			},
+			want: []queryutil.Filter{
+				{Field: "created", Operator: "isnull"},
+				{Field: "updated", Operator: "in", Value: []interface{}{}},
+			},
+		},
+		{
+			name: "zero date",
+			filters: []queryutil.Filter{
+				{Field: "created", Operator: "eq", Value: "0001-01-01T00:00:00Z"},
+			},
+			want: []queryutil.Filter{
+				{Field: "created", Operator: "eq", Value: "0001-01-01T00:00:00Z"},
+			},
+		},
+		{
+			name:    "custom layouts",
+			layouts: []string{confutil.FormDateLayout},
+			filters: []queryutil.Filter{
+				{Field: "created", Operator: "eq", Value: "03/04/2019"},
+			},
+			want: []queryutil.Filter{
+				{Field: "created", Operator: "eq", Value: "2019-03-04T00:00:00Z"},
+			},
+		},
+		{
+			name:    "empty date",
+			filters: []queryutil.Filter{{Field: "created", Operator: "eq", Value: ""}},
+			wantErr: true,
+		},
+		{
+			name:    "non string value",
+			filters: []queryutil.Filter{{Field: "created", Operator: "eq", Value: 20190304}},
+			wantErr: true,
+		},
+		{
+			name:    "non string list value",
+			filters: []queryutil.Filter{{Field: "created", Operator: "in", Value: []interface{}{"2019-03-04", true}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			filters: []queryutil.Filter{{Field: "created", Operator: "eq", Value: "not a date"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid layouts",
+			layouts: []string{"not a layout"},
+			filters: []queryutil.Filter{{Field: "created", Operator: "eq", Value: "2019-03-04"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		DateLayouts = test.layouts
+		err := CoerceFilterDates(test.filters, "created", "updated")
+
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s should return error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s should not return error; got %s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(test.filters, test.want) {
+			t.Errorf("%s should coerce filters to %v; got %v", test.name, test.want, test.filters)
+		}
+	}
+}
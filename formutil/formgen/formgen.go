@@ -0,0 +1,278 @@
+// Package formgen generates the queryutil.FieldConfig map, form struct
+// and baseline formutil validators of a database table so they stay in
+// sync with its schema
+//
+// It's used through the formgen command, eg.
+//
+//	//go:generate go run github.com/TravisS25/httputil/cmd/formgen -dsn=$DATABASE_URL -table=users -out=users_gen.go
+package formgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// initialisms are parts of column names that are upper cased in go names
+var initialisms = map[string]bool{
+	"id":   true,
+	"ip":   true,
+	"url":  true,
+	"uri":  true,
+	"uuid": true,
+	"json": true,
+	"html": true,
+	"http": true,
+	"api":  true,
+	"sql":  true,
+}
+
+// Config is config struct used for Generate
+type Config struct {
+	// Package is package name of generated file - Required
+	Package string
+
+	// Table is table the columns belong to - Required
+	// It's used as prefix of queryutil.FieldConfig keys and db fields
+	Table string
+
+	// TypeName is prefix of generated names where "<TypeName>Form"
+	// is form struct and "<TypeName>FieldConfig" is field config map
+	// Default is Table in camel case eg. "user_accounts" is "UserAccounts"
+	TypeName string
+
+	// Exclude are columns that aren't added to form struct eg. "id"
+	// Excluded columns are still added to field config map
+	Exclude []string
+}
+
+type field struct {
+	Column  string
+	Name    string
+	JSON    string
+	Type    string
+	Rules   []string
+	Exclude bool
+}
+
+type file struct {
+	Package  string
+	Table    string
+	TypeName string
+	Imports  []string
+	Fields   []field
+}
+
+var fileTemplate = template.Must(template.New("formgen").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`// Code generated by formgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+
+// {{.TypeName}}FieldConfig is field config of {{.Table}} table
+var {{.TypeName}}FieldConfig = map[string]queryutil.FieldConfig{
+{{- range .Fields}}
+	"{{$.Table}}.{{.JSON}}": {
+		DBField: "{{$.Table}}.{{.Column}}",
+		OperationConf: queryutil.OperationConfig{
+			CanFilterBy: true,
+			CanSortBy:   true,
+			CanGroupBy:  true,
+		},
+	},
+{{- end}}
+}
+
+// {{.TypeName}}Form is form of {{.Table}} table
+type {{.TypeName}}Form struct {
+{{- range .Fields}}{{if not .Exclude}}
+	{{.Name}} {{.Type}} ` + "`" + `json:"{{.JSON}}" db:"{{.Column}}"` + "`" + `
+{{- end}}{{end}}
+}
+
+// Validate validates {{.TypeName}}Form with rules derived from schema
+// of {{.Table}} table
+func (f {{.TypeName}}Form) Validate() error {
+	return validation.ValidateStruct(
+		&f,
+{{- range .Fields}}{{if and (not .Exclude) .Rules}}
+		validation.Field(&f.{{.Name}}, {{join .Rules ", "}}),
+{{- end}}{{end}}
+	)
+}
+`))
+
+// Generate writes go source of the field config map, form struct and
+// validators of columns to w
+// columns are usually returned from dbutil#TableColumns, see
+// GenerateTable for tables returned from dbutil#Inspect
+//
+// Nullable columns are pointers and non nullable text columns
+// without a default are required
+func Generate(w io.Writer, config Config, columns []dbutil.Column) error {
+	if config.Package == "" || config.Table == "" {
+		return errors.New("formgen: package and table are required")
+	}
+
+	table := config.Table
+
+	if i := strings.LastIndex(table, "."); i != -1 {
+		table = table[i+1:]
+	}
+	if config.TypeName == "" {
+		config.TypeName = exportedName(table)
+	}
+
+	exclude := make(map[string]bool, len(config.Exclude))
+
+	for _, v := range config.Exclude {
+		exclude[v] = true
+	}
+
+	f := file{
+		Package:  config.Package,
+		Table:    table,
+		TypeName: config.TypeName,
+	}
+	imports := map[string]bool{
+		"github.com/TravisS25/httputil/queryutil": true,
+		"github.com/go-ozzo/ozzo-validation":      true,
+	}
+
+	for _, col := range columns {
+		fd := field{
+			Column:  col.Name,
+			Name:    exportedName(col.Name),
+			JSON:    jsonName(col.Name),
+			Type:    goType(col.DataType),
+			Exclude: exclude[col.Name],
+		}
+
+		if fd.Exclude {
+			f.Fields = append(f.Fields, fd)
+			continue
+		}
+
+		if fd.Type == "time.Time" {
+			imports["time"] = true
+		}
+		if fd.Type == "string" && !col.Nullable && !col.HasDefault {
+			fd.Rules = append(fd.Rules, "formutil.Required")
+			imports["github.com/TravisS25/httputil/formutil"] = true
+		}
+		if fd.Type == "string" && col.MaxLength > 0 {
+			fd.Rules = append(fd.Rules, fmt.Sprintf("validation.Length(0, %d)", col.MaxLength))
+		}
+		if col.Nullable {
+			fd.Type = "*" + fd.Type
+		}
+
+		f.Fields = append(f.Fields, fd)
+	}
+
+	for _, v := range []string{
+		"time",
+		"github.com/TravisS25/httputil/formutil",
+		"github.com/TravisS25/httputil/queryutil",
+		"github.com/go-ozzo/ozzo-validation",
+	} {
+		if imports[v] {
+			f.Imports = append(f.Imports, v)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+
+	if err != nil {
+		return fmt.Errorf("formgen: generated invalid source: %s", err.Error())
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+// GenerateTable is Generate of columns of table, usually returned from
+// dbutil#Inspect, where columns of the primary key of table are also
+// excluded from form struct
+func GenerateTable(w io.Writer, config Config, table dbutil.Table) error {
+	if config.Table == "" {
+		config.Table = table.Name
+	}
+
+	config.Exclude = append(append([]string(nil), config.Exclude...), table.PrimaryKey()...)
+	return Generate(w, config, table.Columns)
+}
+
+// goType returns go type of database type
+func goType(dataType string) string {
+	switch {
+	case strings.HasPrefix(dataType, "interval"):
+		return "string"
+	case strings.HasPrefix(dataType, "int"),
+		strings.HasSuffix(dataType, "int"),
+		strings.HasSuffix(dataType, "serial"):
+		return "int64"
+	case strings.HasPrefix(dataType, "bool"):
+		return "bool"
+	case strings.HasPrefix(dataType, "numeric"),
+		strings.HasPrefix(dataType, "decimal"),
+		strings.HasPrefix(dataType, "real"),
+		strings.HasPrefix(dataType, "double"),
+		strings.HasPrefix(dataType, "float"):
+		return "float64"
+	case strings.HasPrefix(dataType, "timestamp"),
+		strings.HasPrefix(dataType, "date"),
+		strings.HasPrefix(dataType, "time"):
+		return "time.Time"
+	}
+
+	return "string"
+}
+
+// exportedName returns column in camel case eg. "status_id" is "StatusID"
+func exportedName(column string) string {
+	var b strings.Builder
+
+	for _, part := range strings.Split(column, "_") {
+		if part == "" {
+			continue
+		}
+
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+		} else {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return b.String()
+}
+
+// jsonName returns column in lower camel case eg. "status_id" is "statusID"
+func jsonName(column string) string {
+	parts := strings.SplitN(column, "_", 2)
+
+	if len(parts) == 1 {
+		return strings.ToLower(parts[0])
+	}
+
+	return strings.ToLower(parts[0]) + exportedName(parts[1])
+}
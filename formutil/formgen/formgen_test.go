@@ -0,0 +1,64 @@
+package formgen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestGenerate(t *testing.T) {
+	var buf bytes.Buffer
+
+	columns := []dbutil.Column{
+		{Name: "id", DataType: "integer", HasDefault: true},
+		{Name: "email", DataType: "character varying", MaxLength: 255},
+		{Name: "status_id", DataType: "bigint"},
+		{Name: "date_expired", DataType: "timestamp with time zone", Nullable: true},
+		{Name: "note", DataType: "text", Nullable: true},
+	}
+
+	err := Generate(&buf, Config{
+		Package: "forms",
+		Table:   "public.user_accounts",
+		Exclude: []string{"id"},
+	}, columns)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	src := buf.String()
+
+	if _, err = parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("should generate valid source; got %s\n%s", err.Error(), src)
+	}
+
+	for _, v := range []string{
+		`var UserAccountsFieldConfig = map[string]queryutil.FieldConfig{`,
+		`"user_accounts.statusID": {`,
+		`DBField: "user_accounts.status_id",`,
+		`DBField: "user_accounts.id",`,
+		"type UserAccountsForm struct {",
+		"StatusID    int64      `json:\"statusID\" db:\"status_id\"`",
+		"DateExpired *time.Time `json:\"dateExpired\" db:\"date_expired\"`",
+		"Note        *string    `json:\"note\" db:\"note\"`",
+		`validation.Field(&f.Email, formutil.Required, validation.Length(0, 255)),`,
+		`"time"`,
+	} {
+		if !strings.Contains(src, v) {
+			t.Errorf("generated source should contain %s; got\n%s", v, src)
+		}
+	}
+
+	if strings.Contains(src, "ID int64") || strings.Contains(src, "f.Note") {
+		t.Errorf("excluded and optional columns should not be in form or validated; got\n%s", src)
+	}
+
+	if err = Generate(&buf, Config{Table: "users"}, columns); err == nil {
+		t.Errorf("should return error without package")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/formutil"
 
 	validation "github.com/go-ozzo/ozzo-validation"
@@ -193,7 +194,7 @@ func RunRequestFormTests(t *testing.T, deferFunc func() error, formTests []FormR
 					err := deferFunc()
 
 					if err != nil {
-						fmt.Printf("deferFunc: " + err.Error())
+						httputil.Debugf("formtest: defer func err: %s", err.Error())
 					}
 				}
 			}()
@@ -368,7 +369,7 @@ func validateFormTests(t *testing.T, formTest FormTestCase) {
 		if formTest.IsValidForm {
 			//hasError = true
 			t.Errorf("Should be valid form; got %s\n", err)
-			fmt.Println(formTest.Form)
+			httputil.Debugf("formtest: form: %+v", formTest.Form)
 		}
 
 		ok := true
@@ -0,0 +1,75 @@
+package formutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+// formutil does not import apiutil so it can be used without pulling in
+// redis, sessions etc.  Importing apiutil replaces DecodeBody and
+// MatchLanguage with its content negotiation and language matching
+
+var (
+	// DecodeBody is used by CheckBodyAndDecode and CheckBodyAndDecodeV2
+	// to decode body of request into v
+	// Default decodes json
+	DecodeBody = func(r *http.Request, v interface{}) error {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+
+	// MatchLanguage is used by LocalizeErrors to return which of langs
+	// should be used for r, or empty string if none match
+	// Default matches language of context set by ctxutil#WithLanguage,
+	// else the first tag of Accept-Language header, case insensitively
+	// falling back to the base language eg. "fr" for "fr-CA"
+	MatchLanguage = func(r *http.Request, langs []string) string {
+		lang := ctxutil.LanguageFrom(r.Context())
+
+		if lang == "" {
+			tag := strings.Split(r.Header.Get("Accept-Language"), ",")[0]
+			lang = strings.TrimSpace(strings.Split(tag, ";")[0])
+		}
+
+		for _, v := range langs {
+			if strings.EqualFold(lang, v) {
+				return v
+			}
+		}
+
+		for _, v := range langs {
+			if strings.EqualFold(baseLanguage(lang), baseLanguage(v)) {
+				return v
+			}
+		}
+
+		return ""
+	}
+)
+
+func baseLanguage(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		return tag[:i]
+	}
+
+	return tag
+}
+
+const serverErrTxt = "Server error, please try again later"
+
+func hasServerError(w http.ResponseWriter, err error) bool {
+	if err != nil {
+		confutil.CheckError(err, "Server Err:")
+		httputil.CaptureError(context.Background(), err, map[string]string{"source": "server_error"})
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(serverErrTxt))
+		return true
+	}
+
+	return false
+}
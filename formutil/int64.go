@@ -0,0 +1,129 @@
+package formutil
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+// Int64Slice is slice of int64 ids that's encoded as json array of
+// strings, eg. ["1", "2"], as ids that large eg. cockroach ids lose
+// precision as javascript numbers
+// Both strings and numbers are accepted when decoding
+type Int64Slice []int64
+
+func (s Int64Slice) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+
+	vals := make([]string, 0, len(s))
+
+	for _, v := range s {
+		vals = append(vals, strconv.FormatInt(v, 10))
+	}
+
+	return json.Marshal(vals)
+}
+
+func (s *Int64Slice) UnmarshalJSON(b []byte) error {
+	var vals []Int64
+
+	if err := json.Unmarshal(b, &vals); err != nil {
+		return err
+	}
+	if vals == nil {
+		*s = nil
+		return nil
+	}
+
+	ids := make(Int64Slice, 0, len(vals))
+
+	for _, v := range vals {
+		ids = append(ids, v.Value())
+	}
+
+	*s = ids
+	return nil
+}
+
+// Value returns s as []int64
+func (s Int64Slice) Value() []int64 {
+	return []int64(s)
+}
+
+// Interfaces returns s as []interface{} which can be passed as ids to
+// queryutil#InQueryRebind
+func (s Int64Slice) Interfaces() []interface{} {
+	return Int64Interfaces(s)
+}
+
+// Int64Map is map of int64 ids that's encoded as json object with string
+// values, eg. {"customer": "1"}
+// Both strings and numbers are accepted when decoding
+type Int64Map map[string]int64
+
+func (m Int64Map) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	vals := make(map[string]string, len(m))
+
+	for k, v := range m {
+		vals[k] = strconv.FormatInt(v, 10)
+	}
+
+	return json.Marshal(vals)
+}
+
+func (m *Int64Map) UnmarshalJSON(b []byte) error {
+	var vals map[string]Int64
+
+	if err := json.Unmarshal(b, &vals); err != nil {
+		return err
+	}
+	if vals == nil {
+		*m = nil
+		return nil
+	}
+
+	ids := make(Int64Map, len(vals))
+
+	for k, v := range vals {
+		ids[k] = v.Value()
+	}
+
+	*m = ids
+	return nil
+}
+
+// Int64Interfaces converts ids to []interface{} which can be passed to
+// queryutil#InQueryRebind
+func Int64Interfaces(ids []int64) []interface{} {
+	vals := make([]interface{}, 0, len(ids))
+
+	for _, v := range ids {
+		vals = append(vals, v)
+	}
+
+	return vals
+}
+
+// ParseInt64s parses string encoded ids eg. from query params
+func ParseInt64s(vals []string) (Int64Slice, error) {
+	ids := make(Int64Slice, 0, len(vals))
+
+	for _, v := range vals {
+		id, err := strconv.ParseInt(v, 10, confutil.IntBitSize)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,47 @@
+package formutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInt64Slice(t *testing.T) {
+	var form struct {
+		IDs Int64Slice `json:"ids"`
+		Map Int64Map   `json:"map"`
+	}
+
+	err := json.Unmarshal([]byte(`{"ids": ["9007199254740993", 2], "map": {"customer": "1"}}`), &form)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	if !reflect.DeepEqual(form.IDs, Int64Slice{9007199254740993, 2}) {
+		t.Errorf("should decode ids; got %v\n", form.IDs)
+	}
+	if form.Map["customer"] != 1 {
+		t.Errorf("should decode map; got %v\n", form.Map)
+	}
+
+	b, err := json.Marshal(form)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	expected := `{"ids":["9007199254740993","2"],"map":{"customer":"1"}}`
+
+	if string(b) != expected {
+		t.Errorf("should encode %s; got %s\n", expected, string(b))
+	}
+
+	if !reflect.DeepEqual(form.IDs.Interfaces(), []interface{}{int64(9007199254740993), int64(2)}) {
+		t.Errorf("should convert to interfaces; got %v\n", form.IDs.Interfaces())
+	}
+
+	if err = json.Unmarshal([]byte(`{"ids": ["foo"]}`), &form); err == nil {
+		t.Errorf("should return error for invalid id\n")
+	}
+}
@@ -0,0 +1,89 @@
+package formutil
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var (
+	messagesMu sync.RWMutex
+	messages   = map[string]map[string]string{}
+)
+
+// RegisterMessages registers translations of validation messages for
+// lang, keyed by the untranslated message eg.
+//
+//	RegisterMessages("fr", map[string]string{"cannot be blank": "ne peut pas être vide"})
+//
+// Messages already registered for lang are kept unless replaced
+func RegisterMessages(lang string, translations map[string]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+
+	if messages[lang] == nil {
+		messages[lang] = make(map[string]string, len(translations))
+	}
+
+	for k, v := range translations {
+		messages[lang][k] = v
+	}
+}
+
+// LocalizeErrors returns copy of validation.Errors of err with messages
+// translated to language of r, see apiutil#LanguageFromRequest
+// Messages without translations and errors that are not
+// validation.Errors are returned as is
+func LocalizeErrors(r *http.Request, err error) error {
+	errs, ok := err.(validation.Errors)
+
+	if !ok {
+		return err
+	}
+
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+
+	langs := make([]string, 0, len(messages))
+
+	for k := range messages {
+		langs = append(langs, k)
+	}
+
+	lang := MatchLanguage(r, langs)
+
+	if lang == "" {
+		return err
+	}
+
+	return localizeErrors(errs, messages[lang])
+}
+
+func localizeErrors(errs validation.Errors, translations map[string]string) validation.Errors {
+	localized := make(validation.Errors, len(errs))
+
+	for k, v := range errs {
+		switch e := v.(type) {
+		case validation.Errors:
+			localized[k] = localizeErrors(e, translations)
+		case nil:
+			localized[k] = nil
+		default:
+			if msg, ok := translations[e.Error()]; ok {
+				localized[k] = errors.New(msg)
+			} else {
+				localized[k] = e
+			}
+		}
+	}
+
+	return localized
+}
+
+// HasLocalizedFormErrors is the same as HasFormErrors but validation
+// messages are translated to language of r with LocalizeErrors
+func HasLocalizedFormErrors(w http.ResponseWriter, r *http.Request, err error) bool {
+	return formErrors(w, LocalizeErrors(r, err), nil)
+}
@@ -0,0 +1,44 @@
+package formutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+func TestLocalizeErrors(t *testing.T) {
+	RegisterMessages("fr", map[string]string{"cannot be blank": "ne peut pas être vide"})
+
+	errs := validation.Errors{
+		"name":    errors.New("cannot be blank"),
+		"email":   errors.New("must be a valid email address"),
+		"address": validation.Errors{"city": errors.New("cannot be blank")},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept-Language", "fr-CA, en;q=0.5")
+
+	localized := LocalizeErrors(req, errs).(validation.Errors)
+
+	if localized["name"].Error() != "ne peut pas être vide" {
+		t.Errorf("should translate message; got %s", localized["name"])
+	}
+	if localized["email"].Error() != "must be a valid email address" {
+		t.Errorf("should keep message without translation; got %s", localized["email"])
+	}
+	if localized["address"].(validation.Errors)["city"].Error() != "ne peut pas être vide" {
+		t.Errorf("should translate nested messages; got %s", localized["address"])
+	}
+	if errs["name"].Error() != "cannot be blank" {
+		t.Errorf("should not modify original errors")
+	}
+
+	req.Header.Set("Accept-Language", "de")
+
+	if LocalizeErrors(req, errs).(validation.Errors)["name"].Error() != "cannot be blank" {
+		t.Errorf("should not translate unregistered language")
+	}
+}
@@ -0,0 +1,251 @@
+package formutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// DefaultJSONPart is default name of the part containing the json form
+	// of multipart requests
+	DefaultJSONPart = "json"
+
+	// DefaultMultipartMaxMemory is default number of bytes of multipart
+	// requests stored in memory, where the rest of the files are stored in
+	// temporary files
+	DefaultMultipartMaxMemory = 32 << 20
+
+	// FileTooLargeTxt is error of file parts larger than
+	// MultipartConfig#MaxFileSize
+	FileTooLargeTxt = "File too large"
+)
+
+var (
+	// ErrInvalidMultipart is returned from DecodeMultipartForm when
+	// request is not valid multipart request
+	ErrInvalidMultipart = errors.New("Invalid multipart form")
+)
+
+// MultipartConfig is config struct used for DecodeMultipartForm
+type MultipartConfig struct {
+	// JSONPart is name of the part containing the json form, which can be
+	// sent as either a regular field or a file part
+	// Default is DefaultJSONPart
+	JSONPart string
+
+	// MaxMemory is number of bytes stored in memory while parsing request
+	// Default is DefaultMultipartMaxMemory
+	MaxMemory int64
+
+	// RequiredFiles are names of file parts that must have at least one file
+	RequiredFiles []string
+
+	// MaxFileSize is max size in bytes of every file part
+	// Default is 0 which doesn't limit size
+	MaxFileSize int64
+}
+
+// MultipartFiles gives access to the file parts of request decoded by
+// DecodeMultipartForm
+type MultipartFiles struct {
+	form *multipart.Form
+}
+
+// FileValidator can be implemented by forms decoded by
+// DecodeMultipartForm to validate file parts along with the json form
+type FileValidator interface {
+	ValidateFiles(files *MultipartFiles) error
+}
+
+// File returns first file of part name or nil if there isn't any
+func (m *MultipartFiles) File(name string) *multipart.FileHeader {
+	if files := m.Files(name); len(files) > 0 {
+		return files[0]
+	}
+
+	return nil
+}
+
+// Files returns every file of part name
+func (m *MultipartFiles) Files(name string) []*multipart.FileHeader {
+	if m == nil || m.form == nil {
+		return nil
+	}
+
+	return m.form.File[name]
+}
+
+// Names returns names of every file part
+func (m *MultipartFiles) Names() []string {
+	if m == nil || m.form == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(m.form.File))
+
+	for k := range m.form.File {
+		names = append(names, k)
+	}
+
+	return names
+}
+
+// Open opens first file of part name
+// Returns http.ErrMissingFile if there isn't any
+func (m *MultipartFiles) Open(name string) (multipart.File, *multipart.FileHeader, error) {
+	header := m.File(name)
+
+	if header == nil {
+		return nil, nil, http.ErrMissingFile
+	}
+
+	file, err := header.Open()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, header, nil
+}
+
+// RemoveAll removes temporary files created while parsing request
+func (m *MultipartFiles) RemoveAll() error {
+	if m == nil || m.form == nil {
+		return nil
+	}
+
+	return m.form.RemoveAll()
+}
+
+// DecodeMultipartForm decodes multipart request with a json part, which
+// is decoded into form, along with file parts, which are returned as
+// *MultipartFiles
+//
+// Once decoded, the json form and file parts are validated together where
+// form is validated if it implements validation.Validatable, required
+// files and file sizes are validated based on config and files are
+// validated if form implements FileValidator
+// Every error is merged into a single validation.Errors so it can be
+// written with apiutil#WriteError like any other form
+//
+// Returns ErrInvalidMultipart if request could not be parsed,
+// ErrBodyMessage if the json part is missing and ErrInvalidJSON if it
+// could not be decoded
+// *MultipartFiles is returned whenever request was parsed, even with
+// validation errors, so RemoveAll should be deferred by caller
+func DecodeMultipartForm(r *http.Request, form interface{}, config MultipartConfig) (*MultipartFiles, error) {
+	if config.JSONPart == "" {
+		config.JSONPart = DefaultJSONPart
+	}
+	if config.MaxMemory <= 0 {
+		config.MaxMemory = DefaultMultipartMaxMemory
+	}
+
+	if err := r.ParseMultipartForm(config.MaxMemory); err != nil {
+		httputil.Debugf("formutil: parse multipart form: %s", err.Error())
+		return nil, ErrInvalidMultipart
+	}
+
+	files := &MultipartFiles{form: r.MultipartForm}
+	jsonBytes, err := multipartJSON(files, config.JSONPart)
+
+	if err != nil {
+		return files, err
+	}
+	if err = json.Unmarshal(jsonBytes, form); err != nil {
+		httputil.Debugf("formutil: decode json part: %s", err.Error())
+		return files, ErrInvalidJSON
+	}
+
+	errs := validation.Errors{}
+
+	if v, ok := form.(validation.Validatable); ok {
+		if err = mergeValidationErrors(errs, v.Validate()); err != nil {
+			return files, err
+		}
+	}
+
+	for _, name := range config.RequiredFiles {
+		if files.File(name) == nil {
+			errs[name] = errors.New(RequiredTxt)
+		}
+	}
+
+	if config.MaxFileSize > 0 {
+		for _, name := range files.Names() {
+			for _, header := range files.Files(name) {
+				if header.Size > config.MaxFileSize {
+					errs[name] = errors.New(FileTooLargeTxt)
+				}
+			}
+		}
+	}
+
+	if v, ok := form.(FileValidator); ok {
+		if err = mergeValidationErrors(errs, v.ValidateFiles(files)); err != nil {
+			return files, err
+		}
+	}
+
+	if len(errs) > 0 {
+		return files, errs
+	}
+
+	return files, nil
+}
+
+// multipartJSON returns json part, which is either a regular field or a
+// file part
+func multipartJSON(files *MultipartFiles, name string) ([]byte, error) {
+	if vals := files.form.Value[name]; len(vals) > 0 {
+		return []byte(vals[0]), nil
+	}
+
+	file, _, err := files.Open(name)
+
+	if err != nil {
+		return nil, ErrBodyMessage
+	}
+
+	defer file.Close()
+
+	b, err := ioutil.ReadAll(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("formutil: read json part: %s", err.Error())
+	}
+
+	// The json part is not a file so it shouldn't be returned as one
+	delete(files.form.File, name)
+	return b, nil
+}
+
+// mergeValidationErrors adds err to errs if it's validation.Errors, else
+// returns err as is eg. for validation.InternalError
+func mergeValidationErrors(errs validation.Errors, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	vErrs, ok := err.(validation.Errors)
+
+	if !ok {
+		return err
+	}
+
+	for k, v := range vErrs {
+		if v != nil {
+			errs[k] = v
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package formutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+)
+
+type uploadForm struct {
+	Title string `json:"title"`
+}
+
+func (u uploadForm) Validate() error {
+	return validation.ValidateStruct(
+		&u,
+		validation.Field(&u.Title, validation.Required),
+	)
+}
+
+func (u uploadForm) ValidateFiles(files *MultipartFiles) error {
+	if header := files.File("document"); header != nil && header.Filename != "doc.txt" {
+		return validation.Errors{"document": errors.New(InvalidTxt)}
+	}
+
+	return nil
+}
+
+func multipartRequestBody(t *testing.T, jsonPart string, jsonAsFile bool, files map[string]string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if jsonPart != "" {
+		if jsonAsFile {
+			part, err := writer.CreateFormFile(DefaultJSONPart, "form.json")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			part.Write([]byte(jsonPart))
+		} else {
+			writer.WriteField(DefaultJSONPart, jsonPart)
+		}
+	}
+
+	for name, filename := range files {
+		part, err := writer.CreateFormFile(name, filename)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		part.Write([]byte("content"))
+	}
+
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestDecodeMultipartForm(t *testing.T) {
+	decode := func(jsonPart string, jsonAsFile bool, files map[string]string, config MultipartConfig) (uploadForm, *MultipartFiles, error) {
+		var form uploadForm
+
+		body, contentType := multipartRequestBody(t, jsonPart, jsonAsFile, files)
+		r := httptest.NewRequest("POST", "/upload", body)
+		r.Header.Set("Content-Type", contentType)
+
+		mf, err := DecodeMultipartForm(r, &form, config)
+		return form, mf, err
+	}
+
+	config := MultipartConfig{RequiredFiles: []string{"document"}}
+
+	for _, jsonAsFile := range []bool{false, true} {
+		form, files, err := decode(`{"title": "foo"}`, jsonAsFile, map[string]string{"document": "doc.txt"}, config)
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s\n", err)
+		}
+		if form.Title != "foo" {
+			t.Errorf("should decode json part; got %+v\n", form)
+		}
+
+		file, header, err := files.Open("document")
+
+		if err != nil {
+			t.Fatalf("should open file; got %s\n", err)
+		}
+
+		content, _ := ioutil.ReadAll(file)
+		file.Close()
+
+		if header.Filename != "doc.txt" || string(content) != "content" {
+			t.Errorf("should return file; got %s %s\n", header.Filename, string(content))
+		}
+		if files.File(DefaultJSONPart) != nil {
+			t.Errorf("should not return json part as file\n")
+		}
+
+		files.RemoveAll()
+	}
+
+	_, _, err := decode(`{"title": ""}`, false, map[string]string{"document": "other.txt"}, config)
+	errs, ok := err.(validation.Errors)
+
+	if !ok || len(errs) != 2 || errs["title"] == nil || errs["document"] == nil {
+		t.Errorf("should return title and document errors; got %v\n", err)
+	}
+
+	_, _, err = decode(`{"title": "foo"}`, false, map[string]string{"image": "image.png"}, MultipartConfig{
+		RequiredFiles: []string{"document"},
+		MaxFileSize:   1,
+	})
+	errs, ok = err.(validation.Errors)
+
+	if !ok || len(errs) != 2 || errs["document"] == nil || errs["image"] == nil {
+		t.Errorf("should return required and size errors; got %v\n", err)
+	}
+
+	if _, _, err = decode("", false, nil, config); err != ErrBodyMessage {
+		t.Errorf("should return ErrBodyMessage; got %v\n", err)
+	}
+	if _, _, err = decode("{", false, nil, config); err != ErrInvalidJSON {
+		t.Errorf("should return ErrInvalidJSON; got %v\n", err)
+	}
+
+	r := httptest.NewRequest("POST", "/upload", bytes.NewBufferString("{}"))
+	r.Header.Set("Content-Type", "application/json")
+
+	if _, err = DecodeMultipartForm(r, &uploadForm{}, config); err != ErrInvalidMultipart {
+		t.Errorf("should return ErrInvalidMultipart; got %v\n", err)
+	}
+}
@@ -0,0 +1,247 @@
+package formutil
+
+import (
+	"encoding"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+
+	"github.com/TravisS25/httputil/timeutil"
+)
+
+// QueryTag is struct tag used by DecodeQueryForm to determine the query
+// param of a field
+// If a field doesn't have this tag, its json name is used
+const QueryTag = "query"
+
+var (
+	// ErrInvalidQueryForm is returned from DecodeQueryForm when dst is not
+	// a pointer to a struct
+	ErrInvalidQueryForm = errors.New("formutil: query form must be pointer to struct")
+
+	timeType            = reflect.TypeOf(time.Time{})
+	dateType            = reflect.TypeOf(Date{})
+	booleanType         = reflect.TypeOf(Boolean{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeQueryForm decodes the query params of r into dst, which must be
+// a pointer to a struct, so GET endpoints such as search forms can be
+// validated the same way as json bodies
+//
+// The param of each field is determined by its "query" tag, else its
+// "json" tag, else its name, and fields tagged "-" are skipped
+// Fields can be strings, bools, ints, uints, floats, time.Time, Date,
+// Boolean, types implementing encoding.TextUnmarshaler, pointers to any of
+// those and slices of any of those, where slices are decoded from
+// repeated params eg. "?id=1&id=2" or comma separated values eg. "?id=1,2"
+// Times are parsed with timeutil#ParseFlexible using DateLayouts
+// Fields without a param are left as is
+//
+// Values that can't be converted are returned as validation.Errors keyed
+// by param with InvalidTxt, so they're written the same way as other form
+// errors by apiutil#WriteError
+// If every value is converted and dst implements validation.Validatable,
+// the result of its Validate function is returned
+func DecodeQueryForm(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidQueryForm
+	}
+
+	errs := validation.Errors{}
+	decodeQueryStruct(r.URL.Query(), rv.Elem(), errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if v, ok := dst.(validation.Validatable); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+func decodeQueryStruct(values map[string][]string, rv reflect.Value, errs validation.Errors) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			decodeQueryStruct(values, fv, errs)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := queryParamName(field)
+
+		if name == "" {
+			continue
+		}
+
+		vals, ok := values[name]
+
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		if err := setQueryField(fv, vals); err != nil {
+			errs[name] = errors.New(InvalidTxt)
+		}
+	}
+}
+
+func queryParamName(field reflect.StructField) string {
+	for _, tag := range []string{QueryTag, "json"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" {
+			if name == "-" {
+				return ""
+			}
+
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func setQueryField(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice && !isQueryScalar(fv.Type()) {
+		split := make([]string, 0, len(vals))
+
+		for _, v := range vals {
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					split = append(split, s)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(fv.Type(), len(split), len(split))
+
+		for i, v := range split {
+			if err := setQueryValue(slice.Index(i), v); err != nil {
+				return err
+			}
+		}
+
+		fv.Set(slice)
+		return nil
+	}
+
+	return setQueryValue(fv, vals[len(vals)-1])
+}
+
+// isQueryScalar returns whether t is decoded from a single value even
+// though it's a slice eg. []byte
+func isQueryScalar(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Uint8 || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setQueryValue(fv reflect.Value, val string) error {
+	if fv.Kind() == reflect.Ptr {
+		if val == "" {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+
+		ptr := reflect.New(fv.Type().Elem())
+
+		if err := setQueryValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		t, err := timeutil.ParseFlexible(val, DateLayouts...)
+
+		if err != nil {
+			return err
+		}
+
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case dateType:
+		var d Date
+
+		if val != "" {
+			t, err := timeutil.ParseFlexible(val, DateLayouts...)
+
+			if err != nil {
+				return err
+			}
+
+			d.value = &t
+		}
+
+		fv.Set(reflect.ValueOf(d))
+		return nil
+	case booleanType:
+		b, _ := strconv.ParseBool(val)
+		fv.Set(reflect.ValueOf(Boolean{value: b}))
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		fv.SetBytes([]byte(val))
+	default:
+		return errors.New("formutil: unsupported query field type " + fv.Type().String())
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package formutil
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+type searchPage struct {
+	Take int `query:"take"`
+}
+
+type searchForm struct {
+	searchPage
+	Name     string     `json:"name"`
+	Active   *bool      `json:"active"`
+	IDs      Int64Slice `query:"id"`
+	Statuses []string   `json:"statuses"`
+	Since    Date       `json:"since"`
+	Until    *time.Time `json:"until"`
+	Ignored  string     `json:"-"`
+}
+
+func (s searchForm) Validate() error {
+	return validation.ValidateStruct(
+		&s,
+		validation.Field(&s.Name, validation.Required),
+	)
+}
+
+func TestDecodeQueryForm(t *testing.T) {
+	var form searchForm
+
+	r := httptest.NewRequest(
+		"GET",
+		"/search?take=20&name=foo&active=true&id=1&id=2,3&statuses=open,closed"+
+			"&since=2020-01-02&until=2020-01-03T00:00:00Z&Ignored=bar",
+		nil,
+	)
+
+	if err := DecodeQueryForm(r, &form); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	if form.Take != 20 || form.Name != "foo" || form.Active == nil || !*form.Active {
+		t.Errorf("should decode scalars; got %+v\n", form)
+	}
+	if !reflect.DeepEqual(form.IDs, Int64Slice{1, 2, 3}) {
+		t.Errorf("should decode ids; got %v\n", form.IDs)
+	}
+	if !reflect.DeepEqual(form.Statuses, []string{"open", "closed"}) {
+		t.Errorf("should decode statuses; got %v\n", form.Statuses)
+	}
+	if form.Since.Value() == nil || form.Since.Value().Day() != 2 {
+		t.Errorf("should decode date; got %v\n", form.Since.Value())
+	}
+	if form.Until == nil || form.Until.Day() != 3 {
+		t.Errorf("should decode time; got %v\n", form.Until)
+	}
+	if form.Ignored != "" {
+		t.Errorf("should skip ignored field; got %s\n", form.Ignored)
+	}
+
+	r = httptest.NewRequest("GET", "/search?take=foo&id=1,bar", nil)
+	err := DecodeQueryForm(r, &searchForm{})
+	errs, ok := err.(validation.Errors)
+
+	if !ok {
+		t.Fatalf("should return validation.Errors; got %v\n", err)
+	}
+	if len(errs) != 2 || errs["take"] == nil || errs["id"] == nil {
+		t.Errorf("should return errors for take and id; got %v\n", errs)
+	}
+
+	r = httptest.NewRequest("GET", "/search?take=10", nil)
+	errs, ok = DecodeQueryForm(r, &searchForm{}).(validation.Errors)
+
+	if !ok || errs["name"] == nil {
+		t.Errorf("should return validation error for name; got %v\n", errs)
+	}
+
+	if err = DecodeQueryForm(r, searchForm{}); err != ErrInvalidQueryForm {
+		t.Errorf("should return ErrInvalidQueryForm; got %v\n", err)
+	}
+}
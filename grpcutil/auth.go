@@ -0,0 +1,260 @@
+// Package grpcutil allows grpc services to share the authentication
+// and authorization model of the http middleware within apiutil
+package grpcutil
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+// ctxKeys are context keys copied from the http middleware chain
+// into the grpc context
+var ctxKeys = []interface{}{
+	ctxutil.UserJSONKey,
+	ctxutil.UserKey,
+	ctxutil.GroupsKey,
+	ctxutil.ClientIPKey,
+}
+
+// AuthInterceptorConfig is config struct used for AuthInterceptor
+type AuthInterceptorConfig struct {
+	// Middlewares are http middleware, like apiutil#AuthHandler and
+	// apiutil#GroupHandler, that are run in order for every grpc call
+	// against a request built from the incoming metadata, so cookies
+	// and the authorization header are handled the same as over http
+	//
+	// The path of the request is the full grpc method,
+	// eg. "/package.Service/Method"
+	Middlewares []func(http.Handler) http.Handler
+
+	// RequireUser returns codes.Unauthenticated for calls without a
+	// logged in user
+	RequireUser bool
+
+	// AnonMethods are full grpc methods that don't require a user
+	// even if RequireUser is set
+	AnonMethods map[string]bool
+
+	// MethodGroups are full grpc methods that require user to be in
+	// at least one of the groups
+	// Methods not within map are allowed for every user
+	MethodGroups map[string][]string
+
+	// Authorize, if set, is called after the middleware for every call
+	// and returned error is sent to the client
+	// Errors should be created with grpc/status
+	Authorize func(ctx context.Context, fullMethod string) error
+}
+
+// AuthInterceptor performs the same session auth and group checks as the
+// http middleware for grpc calls and populates the same context keys so
+// apiutil#UserCtxKey etc. can be read within grpc handlers
+type AuthInterceptor struct {
+	config AuthInterceptorConfig
+}
+
+// NewAuthInterceptor returns *AuthInterceptor
+func NewAuthInterceptor(config AuthInterceptorConfig) *AuthInterceptor {
+	return &AuthInterceptor{config: config}
+}
+
+// Unary returns grpc.UnaryServerInterceptor to be used with grpc.UnaryInterceptor
+func (a *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns grpc.StreamServerInterceptor to be used with grpc.StreamInterceptor
+func (a *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *AuthInterceptor) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	ctx, err := a.runMiddlewares(ctx, fullMethod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, hasUser := ctxutil.UserFrom(ctx)
+
+	if !hasUser {
+		if a.config.RequireUser && !a.config.AnonMethods[fullMethod] {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+	}
+
+	if groups, ok := a.config.MethodGroups[fullMethod]; ok {
+		if !hasUser {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		if !InGroup(ctx, groups...) {
+			return nil, status.Error(codes.PermissionDenied, "forbidden to access method")
+		}
+	}
+
+	if a.config.Authorize != nil {
+		if err = a.config.Authorize(ctx, fullMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// runMiddlewares runs the http middlewares against a request built from
+// metadata of ctx and copies the resulting context values into ctx
+func (a *AuthInterceptor) runMiddlewares(ctx context.Context, fullMethod string) (context.Context, error) {
+	if len(a.config.Middlewares) == 0 {
+		return ctx, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, nil)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range md {
+			// Pseudo headers and binary metadata are not valid http headers
+			if strings.HasPrefix(k, ":") || strings.HasSuffix(k, "-bin") {
+				continue
+			}
+
+			for _, val := range v {
+				req.Header.Add(k, val)
+			}
+		}
+	}
+
+	var reqCtx context.Context
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCtx = r.Context()
+	})
+
+	for i := len(a.config.Middlewares) - 1; i >= 0; i-- {
+		handler = a.config.Middlewares[i](handler)
+	}
+
+	rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+	handler.ServeHTTP(rec, req)
+
+	// Middleware responded instead of calling next handler
+	if reqCtx == nil {
+		return nil, status.Error(httpStatusCode(rec.status), strings.TrimSpace(rec.body.String()))
+	}
+
+	for _, key := range ctxKeys {
+		if val := reqCtx.Value(key); val != nil {
+			ctx = context.WithValue(ctx, key, val)
+		}
+	}
+
+	return ctx, nil
+}
+
+// User returns json of user set by apiutil#AuthHandler, else nil
+func User(ctx context.Context) []byte {
+	return ctxutil.UserJSONFrom(ctx)
+}
+
+// InGroup returns whether user of ctx is in any of groups
+func InGroup(ctx context.Context, groups ...string) bool {
+	userGroups := ctxutil.GroupsFrom(ctx)
+
+	for _, v := range groups {
+		if userGroups[v] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// httpStatusCode converts http status written by middleware into
+// grpc status code
+func httpStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	if code >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+
+	return codes.Unknown
+}
+
+// responseRecorder holds status and body written by middleware that
+// responded instead of calling next handler
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
+
+// serverStream overrides context of grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,75 @@
+package grpcutil
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	// Stand in for apiutil#AuthHandler and apiutil#GroupHandler
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("Authorization") {
+			case "":
+				next.ServeHTTP(w, r)
+			case "admin", "user":
+				ctx := ctxutil.WithUserJSON(r.Context(), []byte(`{"id": "1"}`))
+				ctx = ctxutil.WithUser(ctx, ctxutil.User{ID: "1"})
+				ctx = ctxutil.WithGroups(ctx, map[string]bool{r.Header.Get("Authorization"): true})
+				next.ServeHTTP(w, r.WithContext(ctx))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Invalid cookie"))
+			}
+		})
+	}
+
+	interceptor := NewAuthInterceptor(AuthInterceptorConfig{
+		Middlewares:  []func(http.Handler) http.Handler{auth},
+		MethodGroups: map[string][]string{"/test.Service/Admin": {"admin"}},
+	}).Unary()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return User(ctx), nil
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		auth         string
+		expectedCode codes.Code
+	}{
+		{"anon", "/test.Service/List", "", codes.OK},
+		{"invalid", "/test.Service/List", "foo", codes.InvalidArgument},
+		{"admin", "/test.Service/Admin", "admin", codes.OK},
+		{"forbidden", "/test.Service/Admin", "user", codes.PermissionDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(v *testing.T) {
+			ctx := context.Background()
+
+			if test.auth != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", test.auth))
+			}
+
+			res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.method}, handler)
+
+			if status.Code(err) != test.expectedCode {
+				v.Fatalf("got code %s; want %s", status.Code(err), test.expectedCode)
+			}
+
+			if test.expectedCode == codes.OK && test.auth != "" && res.([]byte) == nil {
+				v.Errorf("user should be set in context")
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Package httpclientutil implements a client for calling third party
+// http apis with timeouts, auth, retries of idempotent requests, request
+// id propagation and hooks for logging and metrics
+package httpclientutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/ctxutil"
+)
+
+const (
+	// DefaultTimeout is default timeout of requests, including retries
+	// of the request
+	DefaultTimeout = time.Second * 30
+
+	// RequestIDHeader is header the request id of ctx is sent with
+	RequestIDHeader = "X-Request-ID"
+)
+
+var (
+	// ErrInvalidBaseURL is returned by NewClient if Config#BaseURL is
+	// not an absolute url
+	ErrInvalidBaseURL = errors.New("httpclientutil: base url must be absolute")
+)
+
+// WithRequestID returns ctx with request id, which is sent with every
+// request made with the returned ctx as RequestIDHeader so requests
+// can be traced across services
+// This is the same as ctxutil#WithRequestID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return ctxutil.WithRequestID(ctx, id)
+}
+
+// RequestID returns request id of ctx set by WithRequestID, if any
+func RequestID(ctx context.Context) string {
+	return ctxutil.RequestIDFrom(ctx)
+}
+
+// TransportConfig is config struct used for NewHTTPClient
+type TransportConfig struct {
+	// Timeout is timeout of a single request, including reading the
+	// response body
+	// Default is DefaultTimeout
+	Timeout time.Duration
+
+	// DialTimeout is timeout of connecting to hosts
+	// Default is 10 seconds
+	DialTimeout time.Duration
+
+	// TLSHandshakeTimeout is timeout of tls handshakes
+	// Default is 10 seconds
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout is timeout of waiting for response headers
+	// after the request is written
+	// Default is 0 which is limited only by Timeout
+	ResponseHeaderTimeout time.Duration
+
+	// IdleConnTimeout is how long idle connections are kept open
+	// Default is 90 seconds
+	IdleConnTimeout time.Duration
+
+	// MaxIdleConnsPerHost is max number of idle connections kept open
+	// per host
+	// Default is 10
+	MaxIdleConnsPerHost int
+
+	// Proxy returns proxy of requests
+	// Default is http#ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLSConfig is tls config of connections eg. to set client
+	// certificates or root CAs
+	TLSConfig *tls.Config
+}
+
+func (t *TransportConfig) setDefaults() {
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultTimeout
+	}
+	if t.DialTimeout <= 0 {
+		t.DialTimeout = time.Second * 10
+	}
+	if t.TLSHandshakeTimeout <= 0 {
+		t.TLSHandshakeTimeout = time.Second * 10
+	}
+	if t.IdleConnTimeout <= 0 {
+		t.IdleConnTimeout = time.Second * 90
+	}
+	if t.MaxIdleConnsPerHost <= 0 {
+		t.MaxIdleConnsPerHost = 10
+	}
+	if t.Proxy == nil {
+		t.Proxy = http.ProxyFromEnvironment
+	}
+}
+
+// NewHTTPClient returns *http.Client with the timeouts, proxy and tls
+// config of config
+// Unlike http#DefaultClient, requests of the returned client time out
+func NewHTTPClient(config TransportConfig) *http.Client {
+	config.setDefaults()
+
+	return &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			Proxy: config.Proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   config.DialTimeout,
+				KeepAlive: time.Second * 30,
+			}).DialContext,
+			TLSClientConfig:       config.TLSConfig,
+			TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+			IdleConnTimeout:       config.IdleConnTimeout,
+			MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// AuthFunc authenticates req eg. by setting its Authorization header
+// It's called before every attempt of a request so tokens can be
+// refreshed between retries
+type AuthFunc func(req *http.Request) error
+
+// BearerAuth returns AuthFunc that sends token as bearer token
+func BearerAuth(token string) AuthFunc {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// BasicAuth returns AuthFunc that sends username and password with
+// basic auth
+func BasicAuth(username, password string) AuthFunc {
+	return func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	}
+}
+
+// HeaderAuth returns AuthFunc that sends value as header eg. api keys
+// sent as "X-Api-Key"
+func HeaderAuth(header, value string) AuthFunc {
+	return func(req *http.Request) error {
+		req.Header.Set(header, value)
+		return nil
+	}
+}
+
+// Config is config struct used for Client
+type Config struct {
+	// BaseURL is url paths of requests are relative to
+	// eg. "https://api.example.com/v1" - Required
+	BaseURL string
+
+	// Header is sent with every request eg. "User-Agent"
+	Header http.Header
+
+	// Auth, if set, authenticates every request
+	Auth AuthFunc
+
+	// HTTPClient is used to send requests
+	// Default is NewHTTPClient with default TransportConfig
+	HTTPClient *http.Client
+
+	// Retry is policy used to retry idempotent requests that fail with
+	// transient errors or respond with RetryStatuses
+	// Requests are idempotent if their method is idempotent or they
+	// have an "Idempotency-Key" header
+	// Default is httputil#RetryPolicy defaults
+	Retry httputil.RetryPolicy
+
+	// RetryStatuses are statuses of responses that are retried
+	// Default is 429, 502, 503 and 504
+	RetryStatuses []int
+
+	// OnRequest, if set, is called before every attempt of a request is
+	// sent, after auth and headers are set
+	OnRequest func(req *http.Request)
+
+	// OnResponse, if set, is called after every attempt of a request
+	// with its response or error and how long it took
+	// eg. to log requests or record metrics
+	OnResponse func(req *http.Request, res *http.Response, err error, duration time.Duration)
+}
+
+func (c *Config) setDefaults() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = NewHTTPClient(TransportConfig{})
+	}
+	if c.RetryStatuses == nil {
+		c.RetryStatuses = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+}
+
+// Client sends requests to a third party api
+type Client struct {
+	baseURL *url.URL
+	config  Config
+}
+
+// NewClient returns *Client
+// Returns ErrInvalidBaseURL if Config#BaseURL is invalid
+func NewClient(config Config) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimRight(config.BaseURL, "/"))
+
+	if err != nil || !baseURL.IsAbs() {
+		return nil, ErrInvalidBaseURL
+	}
+
+	config.setDefaults()
+
+	return &Client{
+		baseURL: baseURL,
+		config:  config,
+	}, nil
+}
+
+// NewRequest returns *Request for method of path relative to
+// Config#BaseURL eg. "/users"
+func (c *Client) NewRequest(method, path string) *Request {
+	u := *c.baseURL
+	u.Path += "/" + strings.TrimLeft(path, "/")
+
+	return &Request{
+		client: c,
+		method: method,
+		url:    &u,
+		query:  u.Query(),
+		header: http.Header{},
+	}
+}
+
+// Get is shorthand for NewRequest(http.MethodGet, path).Do(ctx, v)
+func (c *Client) Get(ctx context.Context, path string, v interface{}) error {
+	return c.NewRequest(http.MethodGet, path).Do(ctx, v)
+}
+
+// Post is shorthand for NewRequest(http.MethodPost, path).JSON(body).Do(ctx, v)
+func (c *Client) Post(ctx context.Context, path string, body, v interface{}) error {
+	return c.NewRequest(http.MethodPost, path).JSON(body).Do(ctx, v)
+}
+
+func (c *Client) retryStatus(status int) bool {
+	for _, s := range c.config.RetryStatuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryable is default RetryPolicy#Retryable of requests
+func (c *Client) retryable(err error) bool {
+	if s, ok := err.(*StatusError); ok {
+		return c.retryStatus(s.Status)
+	}
+
+	return httputil.IsRetryable(err)
+}
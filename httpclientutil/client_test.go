@@ -0,0 +1,160 @@
+package httpclientutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, config Config) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	config.BaseURL = server.URL + "/v1/"
+	config.Retry = httputil.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+
+	client, err := NewClient(config)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	for _, baseURL := range []string{"", "/v1", "://bad"} {
+		if _, err := NewClient(Config{BaseURL: baseURL}); err != ErrInvalidBaseURL {
+			t.Errorf("base url %q should return ErrInvalidBaseURL; got %v", baseURL, err)
+		}
+	}
+}
+
+func TestRequestDo(t *testing.T) {
+	var responses int
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/users" {
+			t.Errorf("got path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("notify") != "true" {
+			t.Errorf("got query %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("User-Agent") != "test" {
+			t.Errorf("got user agent %q", r.Header.Get("User-Agent"))
+		}
+		if r.Header.Get(RequestIDHeader) != "req-1" {
+			t.Errorf("got request id %q", r.Header.Get(RequestIDHeader))
+		}
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", httputil.ContentTypeJSON)
+		json.NewEncoder(w).Encode(map[string]string{"id": "1", "name": body["name"]})
+	}, Config{
+		Header: http.Header{"User-Agent": []string{"test"}},
+		Auth:   BearerAuth("token"),
+		OnResponse: func(req *http.Request, res *http.Response, err error, duration time.Duration) {
+			responses++
+		},
+	})
+
+	var user map[string]string
+	ctx := WithRequestID(context.Background(), "req-1")
+	err := client.NewRequest(http.MethodPost, "users").
+		Param("notify", "true").
+		JSON(map[string]string{"name": "foo"}).
+		Do(ctx, &user)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if user["id"] != "1" || user["name"] != "foo" {
+		t.Errorf("got user %v", user)
+	}
+	if responses != 1 {
+		t.Errorf("OnResponse should be called once; got %d", responses)
+	}
+}
+
+func TestRequestDoRetry(t *testing.T) {
+	var calls int32
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	}, Config{})
+
+	var res struct {
+		OK bool `json:"ok"`
+	}
+
+	if err := client.Get(context.Background(), "/status", &res); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !res.OK || calls != 3 {
+		t.Errorf("should succeed on third call; got %d calls", calls)
+	}
+
+	calls = 0
+	err := client.Post(context.Background(), "/status", nil, nil)
+
+	if s, ok := err.(*StatusError); !ok || s.Status != http.StatusServiceUnavailable {
+		t.Fatalf("should return *StatusError; got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("post should not be retried; got %d calls", calls)
+	}
+
+	calls = 0
+	err = client.NewRequest(http.MethodPost, "/status").
+		Header(IdempotencyKeyHeader, "key").
+		Do(context.Background(), nil)
+
+	if err != nil || calls != 3 {
+		t.Errorf("post with idempotency key should be retried; got %d calls, err %v", calls, err)
+	}
+}
+
+func TestRequestDoStatusError(t *testing.T) {
+	var calls int32
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}, Config{})
+
+	err := client.Get(context.Background(), "/users/1", nil)
+	s, ok := err.(*StatusError)
+
+	if !ok {
+		t.Fatalf("should return *StatusError; got %v", err)
+	}
+	if s.Status != http.StatusNotFound || string(s.Body) != `{"error":"not found"}` {
+		t.Errorf("got status %d and body %s", s.Status, s.Body)
+	}
+	if calls != 1 {
+		t.Errorf("404 should not be retried; got %d calls", calls)
+	}
+}
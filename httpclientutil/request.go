@@ -0,0 +1,217 @@
+package httpclientutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// IdempotencyKeyHeader is header that makes requests of methods
+	// that aren't idempotent, eg. POST, retryable
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// maxErrorBody is max number of bytes of body kept by StatusError
+	maxErrorBody = 4096
+)
+
+// StatusError is returned by Request#Do when api responds with a status
+// of 400 or above
+type StatusError struct {
+	Method string
+	URL    string
+	Status int
+
+	// Body is the start of the response body eg. error message of api
+	Body []byte
+}
+
+func (s *StatusError) Error() string {
+	return fmt.Sprintf(
+		"httpclientutil: %s %s responded %d: %s",
+		s.Method,
+		s.URL,
+		s.Status,
+		string(s.Body),
+	)
+}
+
+// Request builds a request of Client
+// Methods return the request so they can be chained eg.
+//
+//	err := client.NewRequest(http.MethodPost, "/users").
+//		Param("notify", "true").
+//		JSON(user).
+//		Do(ctx, &created)
+type Request struct {
+	client      *Client
+	method      string
+	url         *url.URL
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+	err         error
+}
+
+// Param adds url query param key with value
+func (r *Request) Param(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Header sets header key to value
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// JSON sets body of request to v encoded as json
+func (r *Request) JSON(v interface{}) *Request {
+	body, err := json.Marshal(v)
+
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	return r.Body(body, httputil.ContentTypeJSON)
+}
+
+// Form sets body of request to form encoded values
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body([]byte(values.Encode()), "application/x-www-form-urlencoded")
+}
+
+// Body sets body of request with its content type
+// body is held in memory so it can be sent again when retried
+func (r *Request) Body(body []byte, contentType string) *Request {
+	r.body = body
+	r.contentType = contentType
+	return r
+}
+
+// Do sends request and decodes json response into v if v is not nil
+// Responses with status of 400 or above return *StatusError
+//
+// Idempotent requests are retried by Config#Retry, see Config#Retry
+func (r *Request) Do(ctx context.Context, v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	policy := r.client.config.Retry
+
+	if !r.idempotent() {
+		policy.MaxAttempts = 1
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = r.client.retryable
+	}
+
+	return httputil.Retry(ctx, policy, func(ctx context.Context) error {
+		return r.do(ctx, v)
+	})
+}
+
+func (r *Request) do(ctx context.Context, v interface{}) error {
+	req, err := r.newHTTPRequest(ctx)
+
+	if err != nil {
+		return httputil.Permanent(err)
+	}
+	if r.client.config.OnRequest != nil {
+		r.client.config.OnRequest(req)
+	}
+
+	start := time.Now()
+	res, err := r.client.config.HTTPClient.Do(req)
+
+	if r.client.config.OnResponse != nil {
+		r.client.config.OnResponse(req, res, err, time.Since(start))
+	}
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+		return &StatusError{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Status: res.StatusCode,
+			Body:   body,
+		}
+	}
+
+	if v == nil || res.StatusCode == http.StatusNoContent {
+		_, err = io.Copy(ioutil.Discard, res.Body)
+		return err
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (r *Request) newHTTPRequest(ctx context.Context) (*http.Request, error) {
+	u := *r.url
+	u.RawQuery = r.query.Encode()
+
+	var body io.Reader
+
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequest(r.method, u.String(), body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	for key, values := range r.client.config.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range r.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", httputil.ContentTypeJSON)
+	}
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	if r.client.config.Auth != nil {
+		if err = r.client.config.Auth(req); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// idempotent returns whether request can be safely sent more than once
+func (r *Request) idempotent() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+
+	return r.header.Get(IdempotencyKeyHeader) != ""
+}
@@ -0,0 +1,145 @@
+// Package idutil obfuscates sequential database ids, so they aren't
+// exposed publicly, by encoding them in responses and decoding them
+// from filters and router variables before they're queried
+package idutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	hashids "github.com/speps/go-hashids"
+)
+
+var (
+	// ErrInvalidID is returned when id can't be decoded
+	ErrInvalidID = errors.New("idutil: invalid id")
+)
+
+// Codec encodes and decodes ids
+type Codec interface {
+	Encode(id int64) (string, error)
+	Decode(s string) (int64, error)
+}
+
+// HashidsCodec is Codec using hashids
+type HashidsCodec struct {
+	h *hashids.HashID
+}
+
+// NewHashidsCodec returns *HashidsCodec where salt should be secret and
+// unique per application and minLength is min length of encoded ids
+func NewHashidsCodec(salt string, minLength int) (*HashidsCodec, error) {
+	hd := hashids.NewData()
+	hd.Salt = salt
+	hd.MinLength = minLength
+
+	h, err := hashids.NewWithData(hd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &HashidsCodec{h: h}, nil
+}
+
+// Encode implements Codec
+func (c *HashidsCodec) Encode(id int64) (string, error) {
+	return c.h.EncodeInt64([]int64{id})
+}
+
+// Decode implements Codec
+// Returns ErrInvalidID if s is not the encoding of a single id
+func (c *HashidsCodec) Decode(s string) (int64, error) {
+	ids, err := c.h.DecodeInt64WithError(s)
+
+	if err != nil || len(ids) != 1 {
+		return 0, ErrInvalidID
+	}
+
+	// Several strings can decode to the same id so only the one
+	// returned from Encode is accepted
+	if encoded, err := c.Encode(ids[0]); err != nil || encoded != s {
+		return 0, ErrInvalidID
+	}
+
+	return ids[0], nil
+}
+
+// EncodeValue encodes id of any integer type, or numeric string, as
+// returned from database
+// nil is returned as is
+func EncodeValue(codec Codec, id interface{}) (interface{}, error) {
+	var i int64
+
+	switch v := id.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		i = v
+	case int:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case float64:
+		i = int64(v)
+	case []byte:
+		return EncodeValue(codec, string(v))
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("idutil: can't encode id \"%s\"", v)
+		}
+
+		i = parsed
+	default:
+		return nil, fmt.Errorf("idutil: can't encode id of type %T", id)
+	}
+
+	return codec.Encode(i)
+}
+
+// EncodeConverter returns converter, which can be used as
+// queryutil#RowerMapConfig#Converters, that encodes ids with codec
+func EncodeConverter(codec Codec) func(interface{}) (interface{}, error) {
+	return func(val interface{}) (interface{}, error) {
+		return EncodeValue(codec, val)
+	}
+}
+
+// DecodeTransform returns transform, which can be used as
+// queryutil#FieldConfig#ValueTransform, that decodes filter values of
+// id fields with codec so obfuscated ids are queried as int64
+func DecodeTransform(codec Codec) func(interface{}) (interface{}, error) {
+	return func(val interface{}) (interface{}, error) {
+		s, ok := val.(string)
+
+		if !ok {
+			return nil, ErrInvalidID
+		}
+
+		return codec.Decode(s)
+	}
+}
+
+// EncodeMap encodes values of keys of row with codec
+func EncodeMap(codec Codec, row map[string]interface{}, keys ...string) error {
+	for _, k := range keys {
+		v, ok := row[k]
+
+		if !ok {
+			continue
+		}
+
+		encoded, err := EncodeValue(codec, v)
+
+		if err != nil {
+			return err
+		}
+
+		row[k] = encoded
+	}
+
+	return nil
+}
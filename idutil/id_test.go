@@ -0,0 +1,50 @@
+package idutil
+
+import (
+	"testing"
+)
+
+func TestHashidsCodec(t *testing.T) {
+	codec, err := NewHashidsCodec("salt", 8)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	encoded, err := EncodeValue(codec, int64(12))
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	s := encoded.(string)
+
+	if len(s) < 8 {
+		t.Errorf("should have min length of 8; got %s", s)
+	}
+
+	id, err := DecodeTransform(codec)(s)
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if id != int64(12) {
+		t.Errorf("should decode to 12; got %v", id)
+	}
+
+	if _, err = codec.Decode("12"); err != ErrInvalidID {
+		t.Errorf("should return ErrInvalidID; got %v", err)
+	}
+	if _, err = DecodeTransform(codec)(12); err != ErrInvalidID {
+		t.Errorf("should return ErrInvalidID; got %v", err)
+	}
+
+	row := map[string]interface{}{"id": []byte("12"), "name": "foo"}
+
+	if err = EncodeMap(codec, row, "id", "user_id"); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if row["id"] != s || row["name"] != "foo" {
+		t.Errorf("should only encode id; got %v", row)
+	}
+}
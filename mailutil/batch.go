@@ -0,0 +1,384 @@
+package mailutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// DefaultBatchTable is table used to store status of recipients of
+	// batches if BatchConfig#TableName is not set
+	DefaultBatchTable = "mail_batch_recipient"
+
+	// RecipientSent is status of recipients that were sent email
+	RecipientSent = "sent"
+
+	// RecipientFailed is status of recipients whose email failed to
+	// send, which are retried when batch is resumed
+	RecipientFailed = "failed"
+)
+
+const (
+	batchTableQuery = `
+	create table if not exists %s (
+		batch_id varchar(255) not null,
+		email varchar(255) not null,
+		status varchar(20) not null,
+		error text not null,
+		updated_at timestamp not null default current_timestamp,
+		primary key (batch_id, email)
+	)`
+
+	batchUpsertPostgresQuery = `
+	insert into %s (batch_id, email, status, error) values (?, ?, ?, ?)
+	on conflict (batch_id, email) do update set status = excluded.status,
+	error = excluded.error, updated_at = current_timestamp`
+
+	batchUpsertMysqlQuery = `
+	insert into %s (batch_id, email, status, error) values (?, ?, ?, ?)
+	on duplicate key update status = values(status),
+	error = values(error), updated_at = current_timestamp`
+)
+
+// Recipient is recipient of batch email where Data is what subject and
+// body templates are executed with
+type Recipient struct {
+	Email string
+	Data  interface{}
+}
+
+// RecipientIterator iterates over recipients of a batch so they don't
+// have to be loaded into memory at once eg. by scanning rows of a query
+// Next returns io.EOF once there are no more recipients
+type RecipientIterator interface {
+	Next() (Recipient, error)
+}
+
+// RecipientIteratorFunc is function adapter for RecipientIterator
+type RecipientIteratorFunc func() (Recipient, error)
+
+// Next calls r()
+func (r RecipientIteratorFunc) Next() (Recipient, error) {
+	return r()
+}
+
+// SliceRecipients returns RecipientIterator of recipients
+func SliceRecipients(recipients []Recipient) RecipientIterator {
+	i := 0
+
+	return RecipientIteratorFunc(func() (Recipient, error) {
+		if i >= len(recipients) {
+			return Recipient{}, io.EOF
+		}
+
+		i++
+		return recipients[i-1], nil
+	})
+}
+
+// BatchProgress is number of recipients of a batch handled so far
+type BatchProgress struct {
+	Sent   int
+	Failed int
+
+	// Skipped are recipients that were already sent email by a previous
+	// run of the batch
+	Skipped int
+}
+
+// BatchConfig is config struct used for BatchSender
+type BatchConfig struct {
+	// TableName is table used to store status of recipients
+	// Default is DefaultBatchTable
+	TableName string
+
+	// DBType is the type of database eg. dbutil#Postgres
+	// This is used for placeholder binding and upserts
+	// Default is dbutil#Postgres
+	DBType string
+
+	// From is address emails are sent from
+	From string
+
+	// Subject is text template of subject of emails
+	Subject string
+
+	// Template is html template of body of emails
+	Template string
+
+	// RatePerSecond is max number of emails sent per second across
+	// every worker
+	// Default is 0 which doesn't limit rate
+	RatePerSecond float64
+
+	// Concurrency is number of emails sent at once
+	// Default is 1
+	Concurrency int
+
+	// OnProgress, if set, is called after every recipient is handled
+	// with the progress of the batch and error of recipient, if any
+	// Calls are never concurrent
+	OnProgress func(progress BatchProgress, recipient Recipient, err error)
+}
+
+func (b *BatchConfig) setDefaults() {
+	if b.TableName == "" {
+		b.TableName = DefaultBatchTable
+	}
+	if b.DBType == "" {
+		b.DBType = dbutil.Postgres
+	}
+	if b.Concurrency <= 0 {
+		b.Concurrency = 1
+	}
+}
+
+// CreateBatchTable creates table of recipient status if it does not exist
+func CreateBatchTable(db httputil.XODB, config BatchConfig) error {
+	config.setDefaults()
+
+	_, err := db.Exec(fmt.Sprintf(batchTableQuery, config.TableName))
+	return err
+}
+
+// BatchSender sends templated emails to many recipients, eg. digests or
+// campaigns, throttled to the rate allowed by the mail server
+//
+// Status of every recipient is stored by batch id so batches that were
+// interrupted, eg. by a crash or deploy, can be resumed by sending the
+// same batch id again, which skips recipients that were already sent
+// Emails are sent at least once so a crash between sending and storing
+// status can send a recipient the same email twice
+type BatchSender struct {
+	db        httputil.XODB
+	messenger SendMessage
+	subject   *template.Template
+	body      *htmltemplate.Template
+	config    BatchConfig
+}
+
+// NewBatchSender returns *BatchSender
+// Returns error if subject or body templates of config can't be parsed
+func NewBatchSender(db httputil.XODB, messenger SendMessage, config BatchConfig) (*BatchSender, error) {
+	config.setDefaults()
+
+	subject, err := template.New("batch-subject").Parse(config.Subject)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := htmltemplate.New("batch-body").Parse(config.Template)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatchSender{
+		db:        db,
+		messenger: messenger,
+		subject:   subject,
+		body:      body,
+		config:    config,
+	}, nil
+}
+
+// Statuses returns status of every recipient of batch keyed by email
+func (b *BatchSender) Statuses(batchID string) (map[string]string, error) {
+	rower, err := b.db.Query(
+		b.rebind(fmt.Sprintf("select email, status from %s where batch_id = ?", b.config.TableName)),
+		batchID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string)
+
+	for rower.Next() {
+		var email, status string
+
+		if err = rower.Scan(&email, &status); err != nil {
+			return nil, err
+		}
+
+		statuses[email] = status
+	}
+
+	return statuses, nil
+}
+
+// Send sends email to every recipient of batch that wasn't already sent
+// one by a previous run of batchID and returns the progress of the batch
+//
+// Recipients that fail are stored as RecipientFailed and don't stop the
+// batch, while errors of recipients iterator, storing status or ctx
+// being done stop it after emails being sent finish
+func (b *BatchSender) Send(ctx context.Context, batchID string, recipients RecipientIterator) (BatchProgress, error) {
+	var progress BatchProgress
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var batchErr error
+
+	statuses, err := b.Statuses(batchID)
+
+	if err != nil {
+		return progress, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var limit <-chan time.Time
+
+	if b.config.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / b.config.RatePerSecond))
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+
+	// stop records first error that stops the batch
+	stop := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if batchErr == nil {
+			batchErr = err
+		}
+
+		cancel()
+	}
+
+	queue := make(chan Recipient)
+
+	for i := 0; i < b.config.Concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for recipient := range queue {
+				if limit != nil {
+					select {
+					case <-limit:
+					case <-ctx.Done():
+						continue
+					}
+				}
+
+				sendErr := b.send(recipient)
+				status := RecipientSent
+				errMsg := ""
+
+				if sendErr != nil {
+					status = RecipientFailed
+					errMsg = sendErr.Error()
+				}
+
+				if err := b.record(batchID, recipient.Email, status, errMsg); err != nil {
+					stop(err)
+					continue
+				}
+
+				mu.Lock()
+
+				if sendErr != nil {
+					progress.Failed++
+				} else {
+					progress.Sent++
+				}
+				if b.config.OnProgress != nil {
+					b.config.OnProgress(progress, recipient, sendErr)
+				}
+
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for ctx.Err() == nil {
+		recipient, err := recipients.Next()
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			stop(err)
+			break
+		}
+
+		if statuses[recipient.Email] == RecipientSent {
+			mu.Lock()
+			progress.Skipped++
+
+			if b.config.OnProgress != nil {
+				b.config.OnProgress(progress, recipient, nil)
+			}
+
+			mu.Unlock()
+			continue
+		}
+
+		select {
+		case queue <- recipient:
+		case <-ctx.Done():
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if batchErr == nil {
+		batchErr = ctx.Err()
+	}
+
+	return progress, batchErr
+}
+
+func (b *BatchSender) send(recipient Recipient) error {
+	var subject, body bytes.Buffer
+
+	if err := b.subject.Execute(&subject, recipient.Data); err != nil {
+		return err
+	}
+	if err := b.body.Execute(&body, recipient.Data); err != nil {
+		return err
+	}
+
+	return SendEmail(
+		[]string{recipient.Email},
+		b.config.From,
+		subject.String(),
+		nil,
+		body.Bytes(),
+		b.messenger,
+	)
+}
+
+// record stores status of recipient of batch
+func (b *BatchSender) record(batchID, email, status, errMsg string) error {
+	query := batchUpsertPostgresQuery
+
+	if b.config.DBType == dbutil.Mysql {
+		query = batchUpsertMysqlQuery
+	}
+
+	_, err := b.db.Exec(b.rebind(fmt.Sprintf(query, b.config.TableName)), batchID, email, status, errMsg)
+	return err
+}
+
+func (b *BatchSender) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(b.config.DBType), query)
+}
@@ -0,0 +1,82 @@
+package mailutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+type mockMessenger struct {
+	mu   sync.Mutex
+	sent map[string]string
+}
+
+func (m *mockMessenger) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	to := msg.GetHeaders()["To"][0]
+
+	if to == "bad@example.com" {
+		return errors.New("mailbox unavailable")
+	}
+
+	m.sent[to] = msg.GetHeaders()["Subject"][0] + ": " + msg.GetMessage()
+	return nil
+}
+
+func TestBatchSender(t *testing.T) {
+	var calls int
+
+	messenger := &mockMessenger{sent: make(map[string]string)}
+	db := dbtest.NewExpectDB(t)
+	db.MatchExpectationsInOrder(false)
+
+	sender, err := NewBatchSender(db, messenger, BatchConfig{
+		From:          "noreply@example.com",
+		Subject:       "Digest for {{.Name}}",
+		Template:      "<p>Hi {{.Name}}</p>",
+		RatePerSecond: 1000,
+		Concurrency:   2,
+		OnProgress: func(progress BatchProgress, recipient Recipient, err error) {
+			calls++
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	// a@example.com was sent before crash
+	db.ExpectQuery("select email, status from mail_batch_recipient where batch_id = \\$1").
+		WithArgs("digest-1").
+		WillReturnRows(dbtest.NewRows("email", "status").AddRow("a@example.com", RecipientSent))
+	db.ExpectExec("insert into mail_batch_recipient").
+		WithArgs("digest-1", "b@example.com", RecipientSent, "").
+		WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectExec("insert into mail_batch_recipient").
+		WithArgs("digest-1", "bad@example.com", RecipientFailed, "mailbox unavailable").
+		WillReturnResult(dbtest.NewResult(0, 1))
+
+	progress, err := sender.Send(context.Background(), "digest-1", SliceRecipients([]Recipient{
+		{Email: "a@example.com", Data: map[string]string{"Name": "A"}},
+		{Email: "b@example.com", Data: map[string]string{"Name": "B"}},
+		{Email: "bad@example.com", Data: map[string]string{"Name": "Bad"}},
+	}))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if progress != (BatchProgress{Sent: 1, Failed: 1, Skipped: 1}) || calls != 3 {
+		t.Errorf("got progress %+v with %d calls", progress, calls)
+	}
+	if messenger.sent["b@example.com"] != "Digest for B: <p>Hi B</p>" || len(messenger.sent) != 1 {
+		t.Errorf("got sent %v", messenger.sent)
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+}
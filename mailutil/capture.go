@@ -0,0 +1,212 @@
+package mailutil
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxCaptured is default max number of messages kept by
+// NoopMailer and FileMailer
+const DefaultMaxCaptured = 50
+
+// CapturedMessage is message "sent" through NoopMailer or FileMailer
+type CapturedMessage struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	From    string    `json:"from"`
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Format  string    `json:"format"`
+	Body    string    `json:"-"`
+
+	// File is file message was written to by FileMailer
+	File string `json:"file,omitempty"`
+}
+
+// MessageCapture is implemented by mailers that capture messages
+// instead of sending them, which PreviewHandler lists
+type MessageCapture interface {
+	// Captured returns captured messages, newest first
+	Captured() []CapturedMessage
+}
+
+// messageLog keeps the most recent messages captured by a mailer
+type messageLog struct {
+	mu       sync.Mutex
+	max      int
+	nextID   int
+	messages []CapturedMessage
+}
+
+func newMessageLog(max int) *messageLog {
+	if max <= 0 {
+		max = DefaultMaxCaptured
+	}
+
+	return &messageLog{max: max}
+}
+
+func (l *messageLog) capture(msg *Message, file string) CapturedMessage {
+	headers := msg.GetHeaders()
+
+	if headers == nil {
+		headers = msg.Headers
+	}
+
+	body := msg.GetMessage()
+
+	if body == "" {
+		body = msg.Message
+	}
+
+	format := msg.GetMessageFormat()
+
+	if format == "" {
+		format = msg.MessageFormat
+	}
+
+	first := func(values []string) string {
+		if len(values) > 0 {
+			return values[0]
+		}
+
+		return ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	captured := CapturedMessage{
+		ID:      l.nextID,
+		Time:    time.Now(),
+		From:    first(headers["From"]),
+		To:      headers["To"],
+		Subject: first(headers["Subject"]),
+		Format:  format,
+		Body:    body,
+		File:    file,
+	}
+
+	l.messages = append(l.messages, captured)
+
+	if over := len(l.messages) - l.max; over > 0 {
+		l.messages = append([]CapturedMessage(nil), l.messages[over:]...)
+	}
+
+	return captured
+}
+
+// Captured returns captured messages, newest first
+func (l *messageLog) Captured() []CapturedMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	messages := make([]CapturedMessage, 0, len(l.messages))
+
+	for i := len(l.messages) - 1; i >= 0; i-- {
+		messages = append(messages, l.messages[i])
+	}
+
+	return messages
+}
+
+// NoopMailer doesn't send messages but keeps the most recent ones in
+// memory so they can be previewed with PreviewHandler, which is meant
+// for development and tests where there is no smtp server
+type NoopMailer struct {
+	*messageLog
+}
+
+// NewNoopMailer returns *NoopMailer keeping up to max messages
+// If max is 0, DefaultMaxCaptured is used
+func NewNoopMailer(max int) *NoopMailer {
+	return &NoopMailer{messageLog: newMessageLog(max)}
+}
+
+// Send captures msg without sending it
+func (n *NoopMailer) Send(msg *Message) error {
+	n.capture(msg, "")
+	return nil
+}
+
+// PingContext always returns nil as messages are kept in memory
+// It implements httputil#Pinger
+func (n *NoopMailer) PingContext(ctx context.Context) error {
+	return nil
+}
+
+// FileMailer doesn't send messages but writes their bodies to files
+// within a directory, along with keeping the most recent ones in
+// memory like NoopMailer
+type FileMailer struct {
+	*messageLog
+	dir string
+}
+
+// NewFileMailer returns *FileMailer writing messages to dir, which is
+// created if it doesn't exist, and keeping up to max messages in memory
+func NewFileMailer(dir string, max int) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{messageLog: newMessageLog(max), dir: dir}, nil
+}
+
+// Send writes body of msg to file named after the time it was sent
+// and its subject
+func (f *FileMailer) Send(msg *Message) error {
+	captured := f.capture(msg, "")
+	name := fmt.Sprintf("%s-%d-%s.html", captured.Time.Format("20060102T150405"), captured.ID, fileSafe(captured.Subject))
+	file := filepath.Join(f.dir, name)
+
+	if err := ioutil.WriteFile(file, []byte(captured.Body), 0644); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.messages {
+		if f.messages[i].ID == captured.ID {
+			f.messages[i].File = file
+		}
+	}
+
+	return nil
+}
+
+// PingContext returns error if directory messages are written to
+// doesn't exist or isn't a directory
+// It implements httputil#Pinger
+func (f *FileMailer) PingContext(ctx context.Context) error {
+	info, err := os.Stat(f.dir)
+
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mailutil: '%s' is not a directory", f.dir)
+	}
+
+	return nil
+}
+
+// fileSafe returns s with every character that isn't a letter or
+// digit replaced with "-"
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '-'
+	}, s)
+}
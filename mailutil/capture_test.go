@@ -0,0 +1,92 @@
+package mailutil
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	mailer := NewNoopMailer(2)
+
+	for _, subject := range []string{"Welcome", "Reset password", "Panic alert"} {
+		if err := SendEmail([]string{"foo@example.com"}, "noreply@example.com", subject, nil, []byte("<p>"+subject+"</p>"), mailer); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+	}
+
+	handler := PreviewHandler(mailer)
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/dev/mail", nil))
+
+	var messages []CapturedMessage
+
+	if err := json.NewDecoder(rr.Body).Decode(&messages); err != nil {
+		t.Fatalf("should decode messages; got %s", err.Error())
+	}
+	if len(messages) != 2 || messages[0].Subject != "Panic alert" || messages[1].Subject != "Reset password" {
+		t.Fatalf("should list most recent messages newest first; got %+v", messages)
+	}
+	if messages[0].From != "noreply@example.com" || len(messages[0].To) != 1 {
+		t.Errorf("should capture headers; got %+v", messages[0])
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/dev/mail?id=2", nil))
+
+	if rr.Body.String() != "<p>Reset password</p>" || rr.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Errorf("should render message in sandbox; got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/dev/mail?id=1", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("should not find dropped message; got %d", rr.Code)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailutil")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	defer os.RemoveAll(dir)
+
+	mailer, err := NewFileMailer(dir, 0)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err = SendEmail([]string{"foo@example.com"}, "noreply@example.com", "Reset password", nil, []byte("<p>reset</p>"), mailer); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	messages := mailer.Captured()
+
+	if len(messages) != 1 || messages[0].File == "" {
+		t.Fatalf("should capture message with its file; got %+v", messages)
+	}
+
+	b, err := ioutil.ReadFile(messages[0].File)
+
+	if err != nil || string(b) != "<p>reset</p>" {
+		t.Errorf("should write body to file; got %s and %v", b, err)
+	}
+
+	if err = mailer.PingContext(context.Background()); err != nil {
+		t.Errorf("should not return error; got %s", err.Error())
+	}
+
+	os.RemoveAll(dir)
+
+	if err = mailer.PingContext(context.Background()); err == nil {
+		t.Errorf("should return error once directory is removed")
+	}
+}
@@ -1,57 +1,5 @@
 package mailutil
 
-import (
-	gomail "gopkg.in/gomail.v2"
-)
-
-// MailerConfig is config struct that enables user to set up mailing
-// This config struct is used in the NewMailMessenger function
-type MailerConfig struct {
-	// Host is the host to connect to send message
-	Host string
-	// Port is the port to connect to to send message
-	Port int
-	// User is the user to use as authentication to send message
-	User string
-	// Password is the password to use for authntaication to send message
-	Password string
-}
-
-// MailMessenger sends mails based on mailerconfig
-type MailMessenger struct {
-	mailerConfig MailerConfig
-}
-
-// NewMailMessenger returns *MailMessenger based on the mailerconfig passed
-func NewMailMessenger(mailerConfig MailerConfig) *MailMessenger {
-	return &MailMessenger{
-		mailerConfig: mailerConfig,
-	}
-}
-
-// Send sends email based on msg config passed
-func (m *MailMessenger) Send(msg *Message) error {
-	var d *gomail.Dialer
-
-	d = gomail.NewDialer(
-		m.mailerConfig.Host,
-		m.mailerConfig.Port,
-		m.mailerConfig.User,
-		m.mailerConfig.Password,
-	)
-
-	goMessage := gomail.NewMessage()
-	goMessage.SetHeaders(msg.GetHeaders())
-	goMessage.SetBody("text/html", msg.GetMessage())
-
-	imgUrls := msg.GetImages()
-	for _, imagePath := range imgUrls {
-		goMessage.Embed(imagePath)
-	}
-
-	return d.DialAndSend(goMessage)
-}
-
 // SendMessage is meant to be used to send some type of message
 type SendMessage interface {
 	Send(msg *Message) error
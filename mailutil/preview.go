@@ -0,0 +1,55 @@
+package mailutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// PreviewHandler returns handler listing messages captured by capture,
+// eg. NoopMailer or FileMailer, as json newest first, where
+// "?id=<id>" renders body of message instead so templates like
+// password resets can be checked without an smtp server
+//
+// Bodies are rendered within a sandbox so scripts of templates can't
+// run on the app's origin
+// Handler is meant for development and must not be registered in
+// production as messages contain tokens like password reset links
+func PreviewHandler(capture MessageCapture) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		messages := capture.Captured()
+		w.Header().Set("Cache-Control", "no-store")
+
+		if idParam := r.URL.Query().Get("id"); idParam != "" {
+			id, err := strconv.Atoi(idParam)
+
+			if err != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+
+			for _, msg := range messages {
+				if msg.ID != id {
+					continue
+				}
+
+				contentType := "text/html; charset=utf-8"
+
+				if msg.Format == "text/plain" {
+					contentType = "text/plain; charset=utf-8"
+				}
+
+				w.Header().Set("Content-Type", contentType)
+				w.Header().Set("Content-Security-Policy", "sandbox")
+				w.Write([]byte(msg.Body))
+				return
+			}
+
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(messages)
+	}
+}
@@ -0,0 +1,92 @@
+// Package chiutil extracts metadata of the go-chi/chi route matched by
+// a request, eg. its path pattern, which are the equivalents of package
+// muxutil for chi
+//
+// The functions must be called after chi routed the request, eg. from
+// within middleware added with chi#Router#With or the handler itself,
+// as the pattern is not known before
+package chiutil
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrNoRoute is returned if request did not match a route
+	ErrNoRoute = errors.New("chiutil: request did not match route")
+)
+
+// PathRegex implements httputil#PathRegex with CurrentRouteTemplate
+var PathRegex httputil.PathRegex = CurrentRouteTemplate
+
+// Route is metadata of route matched by request
+type Route struct {
+	// Template is path pattern of route eg. "/api/invoice/{id:[0-9]+}"
+	Template string `json:"template"`
+
+	// Method is http method of request route was matched with
+	Method string `json:"method"`
+
+	// Vars are url parameters of request eg. "id"
+	Vars map[string]string `json:"vars"`
+}
+
+// CurrentRoute returns metadata of route matched by r
+// Returns ErrNoRoute if r did not match a route
+func CurrentRoute(r *http.Request) (Route, error) {
+	rctx := chi.RouteContext(r.Context())
+
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return Route{}, ErrNoRoute
+	}
+
+	vars := make(map[string]string, len(rctx.URLParams.Keys))
+
+	for i, k := range rctx.URLParams.Keys {
+		// Wildcard "*" has no name so it's not a route variable
+		if k != "*" && i < len(rctx.URLParams.Values) {
+			vars[k] = rctx.URLParams.Values[i]
+		}
+	}
+
+	return Route{
+		Template: rctx.RoutePattern(),
+		Method:   rctx.RouteMethod,
+		Vars:     vars,
+	}, nil
+}
+
+// CurrentRouteTemplate returns path pattern of route matched by r
+// eg. "/api/invoice/{id:[0-9]+}"
+// Returns ErrNoRoute if r did not match a route
+func CurrentRouteTemplate(r *http.Request) (string, error) {
+	rctx := chi.RouteContext(r.Context())
+
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return "", ErrNoRoute
+	}
+
+	return rctx.RoutePattern(), nil
+}
+
+// CurrentRouteName returns path pattern of route matched by r, else
+// empty string, as chi routes don't have names
+func CurrentRouteName(r *http.Request) string {
+	template, _ := CurrentRouteTemplate(r)
+	return template
+}
+
+// RouteLabel returns path pattern of route matched by r, else path of r
+// See muxutil#RouteLabel
+func RouteLabel(r *http.Request) string {
+	if template, err := CurrentRouteTemplate(r); err == nil {
+		return template
+	}
+
+	return r.URL.Path
+}
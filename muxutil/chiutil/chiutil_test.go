@@ -0,0 +1,45 @@
+package chiutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestCurrentRoute(t *testing.T) {
+	var route Route
+	var err error
+
+	router := chi.NewRouter()
+	router.Get("/api/invoice/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		route, err = CurrentRoute(r)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/invoice/1", nil))
+
+	if err != nil {
+		t.Fatalf("should not return err; got %s", err.Error())
+	}
+
+	expected := Route{
+		Template: "/api/invoice/{id:[0-9]+}",
+		Method:   http.MethodGet,
+		Vars:     map[string]string{"id": "1"},
+	}
+
+	if !reflect.DeepEqual(route, expected) {
+		t.Errorf("got route %+v; want %+v", route, expected)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/invoice/1", nil)
+
+	if _, err = PathRegex(req); err != ErrNoRoute {
+		t.Errorf("should return ErrNoRoute; got %v", err)
+	}
+	if label := RouteLabel(req); label != "/api/invoice/1" {
+		t.Errorf("should label request with path; got %s", label)
+	}
+}
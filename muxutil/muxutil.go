@@ -0,0 +1,101 @@
+// Package muxutil extracts metadata of the gorilla/mux route matched by
+// a request, eg. its path template, so middleware like
+// apiutil#RoutingHandler doesn't have to be given its own implementation
+// of httputil#PathRegex
+//
+// See package chiutil for the equivalents of go-chi/chi
+package muxutil
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrNoRoute is returned if request did not match a route
+	ErrNoRoute = errors.New("muxutil: request did not match route")
+)
+
+// PathRegex implements httputil#PathRegex with CurrentRouteTemplate
+var PathRegex httputil.PathRegex = CurrentRouteTemplate
+
+// Route is metadata of route matched by request
+type Route struct {
+	// Name is name of route, if any
+	Name string `json:"name"`
+
+	// Template is path template of route eg. "/api/invoice/{id:[0-9]+}"
+	Template string `json:"template"`
+
+	// Methods are http methods of route, if any
+	Methods []string `json:"methods"`
+
+	// Vars are route variables of request eg. "id"
+	Vars map[string]string `json:"vars"`
+}
+
+// CurrentRoute returns metadata of route matched by r
+// Returns ErrNoRoute if r did not match a route
+func CurrentRoute(r *http.Request) (Route, error) {
+	route := mux.CurrentRoute(r)
+
+	if route == nil {
+		return Route{}, ErrNoRoute
+	}
+
+	template, err := route.GetPathTemplate()
+
+	if err != nil {
+		return Route{}, err
+	}
+
+	// Routes without methods return an error which is ignored
+	methods, _ := route.GetMethods()
+
+	return Route{
+		Name:     route.GetName(),
+		Template: template,
+		Methods:  methods,
+		Vars:     mux.Vars(r),
+	}, nil
+}
+
+// CurrentRouteTemplate returns path template of route matched by r
+// eg. "/api/invoice/{id:[0-9]+}"
+// Returns ErrNoRoute if r did not match a route
+func CurrentRouteTemplate(r *http.Request) (string, error) {
+	route := mux.CurrentRoute(r)
+
+	if route == nil {
+		return "", ErrNoRoute
+	}
+
+	return route.GetPathTemplate()
+}
+
+// CurrentRouteName returns name of route matched by r, else empty string
+func CurrentRouteName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName()
+	}
+
+	return ""
+}
+
+// RouteLabel returns name of route matched by r, else its path template,
+// else path of r, which is suitable for labelling requests in logs and
+// metrics without the cardinality of raw paths
+func RouteLabel(r *http.Request) string {
+	if name := CurrentRouteName(r); name != "" {
+		return name
+	}
+	if template, err := CurrentRouteTemplate(r); err == nil {
+		return template
+	}
+
+	return r.URL.Path
+}
@@ -0,0 +1,51 @@
+package muxutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCurrentRoute(t *testing.T) {
+	var route Route
+	var label string
+	var err error
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/invoice/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		route, err = CurrentRoute(r)
+		label = RouteLabel(r)
+	}).Methods(http.MethodGet).Name("invoice")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/invoice/1", nil))
+
+	if err != nil {
+		t.Fatalf("should not return err; got %s", err.Error())
+	}
+
+	expected := Route{
+		Name:     "invoice",
+		Template: "/api/invoice/{id:[0-9]+}",
+		Methods:  []string{http.MethodGet},
+		Vars:     map[string]string{"id": "1"},
+	}
+
+	if !reflect.DeepEqual(route, expected) {
+		t.Errorf("got route %+v; want %+v", route, expected)
+	}
+	if label != "invoice" {
+		t.Errorf("should label request with route name; got %s", label)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/invoice/1", nil)
+
+	if _, err = PathRegex(req); err != ErrNoRoute {
+		t.Errorf("should return ErrNoRoute; got %v", err)
+	}
+	if label = RouteLabel(req); label != "/api/invoice/1" {
+		t.Errorf("should label request with path; got %s", label)
+	}
+}
@@ -0,0 +1,155 @@
+package notifyutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+const (
+	// TwilioBaseURL is default base url of TwilioProvider
+	TwilioBaseURL = "https://api.twilio.com"
+)
+
+// EmailNotifier is Notifier that sends messages as html emails
+// with mailutil
+type EmailNotifier struct {
+	Messenger mailutil.SendMessage
+	From      string
+}
+
+// Notify sends msg to email of recipient
+func (e *EmailNotifier) Notify(ctx context.Context, msg Message) error {
+	return mailutil.SendEmail(
+		[]string{msg.Recipient.Email},
+		e.From,
+		msg.Subject,
+		nil,
+		[]byte(msg.Body),
+		e.Messenger,
+	)
+}
+
+// SMSProvider sends sms messages eg. TwilioProvider
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// SMSNotifier is Notifier that sends message bodies as sms
+// Subjects are not sent
+type SMSNotifier struct {
+	Provider SMSProvider
+}
+
+// Notify sends body of msg to phone of recipient
+func (s *SMSNotifier) Notify(ctx context.Context, msg Message) error {
+	return s.Provider.SendSMS(ctx, msg.Recipient.Phone, msg.Body)
+}
+
+// TwilioProvider is SMSProvider that sends sms messages with the
+// twilio messages api or any api compatible with it
+type TwilioProvider struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+
+	// BaseURL is base url of api
+	// Default is TwilioBaseURL
+	BaseURL string
+
+	// Client is used to send requests
+	// Default is http.DefaultClient
+	Client *http.Client
+}
+
+// SendSMS sends body to phone number to
+func (t *TwilioProvider) SendSMS(ctx context.Context, to, body string) error {
+	baseURL := t.BaseURL
+	client := t.Client
+
+	if baseURL == "" {
+		baseURL = TwilioBaseURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", t.From)
+	form.Set("Body", body)
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(baseURL, "/"), t.AccountSID),
+		strings.NewReader(form.Encode()),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("notifyutil: sms failed with status %d: %s", res.StatusCode, string(resBody))
+	}
+
+	return nil
+}
+
+// PushSender sends encrypted web push payloads to subscriptions eg. with
+// a library implementing RFC 8291 and VAPID
+type PushSender interface {
+	SendPush(ctx context.Context, sub PushSubscription, payload []byte) error
+}
+
+// PushNotifier is Notifier that sends messages to every push
+// subscription of recipient
+// The payload is json with "event", "title" and "body" keys
+type PushNotifier struct {
+	Sender PushSender
+}
+
+// Notify sends msg to every push subscription of recipient
+// Every subscription is tried even if one fails, returning the
+// first error
+func (p *PushNotifier) Notify(ctx context.Context, msg Message) error {
+	var firstErr error
+
+	payload, err := json.Marshal(map[string]string{
+		"event": msg.Event,
+		"title": msg.Subject,
+		"body":  msg.Body,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range msg.Recipient.PushSubscriptions {
+		if err = p.Sender.SendPush(ctx, sub, payload); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,346 @@
+// Package notifyutil sends template driven notifications to users over
+// email, sms and web push based on the channels each user prefers
+package notifyutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// NotificationTopic is outbox topic used by Dispatcher#Enqueue
+	NotificationTopic = "notification"
+)
+
+// Channel is a way of delivering notifications
+type Channel string
+
+const (
+	EmailChannel Channel = "email"
+	SMSChannel   Channel = "sms"
+	PushChannel  Channel = "push"
+)
+
+// PushSubscription is web push subscription of a user's browser
+type PushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// Recipient is user a notification is sent to
+// Channels the recipient has no address for are skipped
+type Recipient struct {
+	UserID            string             `json:"userId"`
+	Email             string             `json:"email"`
+	Phone             string             `json:"phone"`
+	PushSubscriptions []PushSubscription `json:"pushSubscriptions"`
+}
+
+// Message is rendered notification for a single channel
+type Message struct {
+	Event     string
+	Subject   string
+	Body      string
+	Recipient Recipient
+}
+
+// Notifier delivers message over a single channel
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// NotifierFunc is function adapter for Notifier
+type NotifierFunc func(ctx context.Context, msg Message) error
+
+// Notify calls n(ctx, msg)
+func (n NotifierFunc) Notify(ctx context.Context, msg Message) error {
+	return n(ctx, msg)
+}
+
+// QueryPreferences queries preferences of user, in the same style as
+// apiutil#QueryDB, and returns them as json of channel to whether it's
+// enabled eg. {"email": true, "sms": false}
+// sql.ErrNoRows should be returned if user has no preferences
+type QueryPreferences func(db httputil.Querier, userID string) ([]byte, error)
+
+// DispatchError is returned by Dispatcher#Send with the error of every
+// channel that failed
+// Channels that didn't fail were still delivered
+type DispatchError struct {
+	Errors map[Channel]error
+}
+
+func (d *DispatchError) Error() string {
+	channels := make([]string, 0, len(d.Errors))
+
+	for k := range d.Errors {
+		channels = append(channels, string(k))
+	}
+
+	sort.Strings(channels)
+	msgs := make([]string, 0, len(channels))
+
+	for _, c := range channels {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", c, d.Errors[Channel(c)].Error()))
+	}
+
+	return "notifyutil: " + strings.Join(msgs, "; ")
+}
+
+// DispatcherConfig is config struct used for Dispatcher
+type DispatcherConfig struct {
+	// DB is passed to QueryPreferences
+	DB httputil.Querier
+
+	// QueryPreferences is used to load preferred channels of recipients
+	// If nil, DefaultChannels are always used
+	QueryPreferences QueryPreferences
+
+	// DefaultChannels are used for recipients without preferences
+	// Default is every channel with a notifier
+	DefaultChannels []Channel
+
+	// OutboxConfig is config used to write notifications to the
+	// outbox in Dispatcher#Enqueue
+	OutboxConfig dbutil.OutboxConfig
+}
+
+// executor is implemented by both text and html templates
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+type channelTemplate struct {
+	subject executor
+	body    executor
+}
+
+// Dispatcher renders templates of events and delivers them over the
+// preferred channels of recipients
+type Dispatcher struct {
+	notifiers map[Channel]Notifier
+	templates map[string]map[Channel]channelTemplate
+	config    DispatcherConfig
+}
+
+// NewDispatcher returns *Dispatcher
+func NewDispatcher(notifiers map[Channel]Notifier, config DispatcherConfig) *Dispatcher {
+	if config.DefaultChannels == nil {
+		for c := range notifiers {
+			config.DefaultChannels = append(config.DefaultChannels, c)
+		}
+	}
+
+	return &Dispatcher{
+		notifiers: notifiers,
+		templates: make(map[string]map[Channel]channelTemplate),
+		config:    config,
+	}
+}
+
+// RegisterTemplate registers subject and body templates of event for
+// channel, which are executed with data passed to Send
+// Email bodies are html templates, every other template is a text template
+// Events are only sent over channels they have templates for
+func (d *Dispatcher) RegisterTemplate(event string, channel Channel, subject, body string) error {
+	var err error
+	var tmpl channelTemplate
+
+	name := event + "-" + string(channel)
+
+	if tmpl.subject, err = template.New(name + "-subject").Parse(subject); err != nil {
+		return err
+	}
+
+	if channel == EmailChannel {
+		tmpl.body, err = htmltemplate.New(name + "-body").Parse(body)
+	} else {
+		tmpl.body, err = template.New(name + "-body").Parse(body)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if d.templates[event] == nil {
+		d.templates[event] = make(map[Channel]channelTemplate)
+	}
+
+	d.templates[event][channel] = tmpl
+	return nil
+}
+
+// Send renders templates of event with data and delivers them to
+// recipient over every preferred channel that has a template
+// Returns *DispatchError if any channel fails
+func (d *Dispatcher) Send(ctx context.Context, event string, recipient Recipient, data interface{}) error {
+	templates, ok := d.templates[event]
+
+	if !ok {
+		return fmt.Errorf("notifyutil: no templates registered for event '%s'", event)
+	}
+
+	channels, err := d.channels(recipient)
+
+	if err != nil {
+		return err
+	}
+
+	dispatchErr := &DispatchError{Errors: make(map[Channel]error)}
+
+	for _, channel := range channels {
+		tmpl, ok := templates[channel]
+		notifier, hasNotifier := d.notifiers[channel]
+
+		if !ok || !hasNotifier || !hasAddress(recipient, channel) {
+			continue
+		}
+
+		msg := Message{Event: event, Recipient: recipient}
+
+		if msg.Subject, err = execute(tmpl.subject, data); err == nil {
+			msg.Body, err = execute(tmpl.body, data)
+		}
+		if err == nil {
+			err = notifier.Notify(ctx, msg)
+		}
+		if err != nil {
+			dispatchErr.Errors[channel] = err
+		}
+	}
+
+	if len(dispatchErr.Errors) > 0 {
+		return dispatchErr
+	}
+
+	return nil
+}
+
+type queuedNotification struct {
+	Event     string      `json:"event"`
+	Recipient Recipient   `json:"recipient"`
+	Data      interface{} `json:"data"`
+}
+
+// Enqueue writes notification to the outbox within tx so it's only sent
+// if tx commits, and is sent asynchronously by dbutil#OutboxRelay using
+// Dispatcher#Publisher
+//
+// data is stored as json so templates receive it as a
+// map[string]interface{} when sent
+func (d *Dispatcher) Enqueue(tx httputil.XODB, event string, recipient Recipient, data interface{}) error {
+	if _, ok := d.templates[event]; !ok {
+		return fmt.Errorf("notifyutil: no templates registered for event '%s'", event)
+	}
+
+	return dbutil.WriteOutboxWithConfig(tx, d.config.OutboxConfig, NotificationTopic, queuedNotification{
+		Event:     event,
+		Recipient: recipient,
+		Data:      data,
+	})
+}
+
+// Publisher returns dbutil#Publisher that sends notifications written
+// by Enqueue
+// Messages of other topics return error so if the outbox is shared,
+// messages should be routed to this publisher by topic
+func (d *Dispatcher) Publisher() dbutil.Publisher {
+	return dbutil.PublisherFunc(func(ctx context.Context, msg dbutil.OutboxMessage) error {
+		var n queuedNotification
+
+		if msg.Topic != NotificationTopic {
+			return fmt.Errorf("notifyutil: invalid topic '%s'", msg.Topic)
+		}
+
+		if err := json.Unmarshal(msg.Payload, &n); err != nil {
+			return err
+		}
+
+		return d.Send(ctx, n.Event, n.Recipient, n.Data)
+	})
+}
+
+func (d *Dispatcher) channels(recipient Recipient) ([]Channel, error) {
+	if d.config.QueryPreferences == nil || recipient.UserID == "" {
+		return d.config.DefaultChannels, nil
+	}
+
+	prefBytes, err := d.config.QueryPreferences(d.config.DB, recipient.UserID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return d.config.DefaultChannels, nil
+		}
+
+		return nil, err
+	}
+
+	var prefs map[Channel]bool
+
+	if err = json.Unmarshal(prefBytes, &prefs); err != nil {
+		return nil, err
+	}
+
+	channels := make([]Channel, 0, len(prefs))
+
+	for _, c := range d.config.DefaultChannels {
+		if enabled, ok := prefs[c]; !ok || enabled {
+			channels = append(channels, c)
+		}
+	}
+
+	for c, enabled := range prefs {
+		if enabled && !hasChannel(channels, c) {
+			channels = append(channels, c)
+		}
+	}
+
+	return channels, nil
+}
+
+func hasChannel(channels []Channel, channel Channel) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasAddress(recipient Recipient, channel Channel) bool {
+	switch channel {
+	case EmailChannel:
+		return recipient.Email != ""
+	case SMSChannel:
+		return recipient.Phone != ""
+	case PushChannel:
+		return len(recipient.PushSubscriptions) > 0
+	}
+
+	return true
+}
+
+func execute(tmpl executor, data interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,155 @@
+package notifyutil
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestDispatcherSend(t *testing.T) {
+	sent := make(map[Channel]Message)
+	record := func(channel Channel) Notifier {
+		return NotifierFunc(func(ctx context.Context, msg Message) error {
+			sent[channel] = msg
+			return nil
+		})
+	}
+
+	d := NewDispatcher(
+		map[Channel]Notifier{
+			EmailChannel: record(EmailChannel),
+			SMSChannel:   record(SMSChannel),
+			PushChannel: NotifierFunc(func(ctx context.Context, msg Message) error {
+				return errors.New("push failed")
+			}),
+		},
+		DispatcherConfig{
+			QueryPreferences: func(db httputil.Querier, userID string) ([]byte, error) {
+				if userID == "2" {
+					return nil, sql.ErrNoRows
+				}
+
+				return []byte(`{"sms": false}`), nil
+			},
+		},
+	)
+
+	if err := d.RegisterTemplate("orderShipped", EmailChannel, "Order {{.ID}} shipped", "<p>{{.Name}}</p>"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err := d.RegisterTemplate("orderShipped", SMSChannel, "", "Order {{.ID}} shipped"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if err := d.RegisterTemplate("orderShipped", PushChannel, "Shipped", "Order {{.ID}}"); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	data := map[string]interface{}{"ID": 1, "Name": "<b>foo</b>"}
+	recipient := Recipient{UserID: "1", Email: "foo@email.com", Phone: "+15555555555"}
+
+	if err := d.Send(context.Background(), "orderShipped", recipient, data); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if sent[EmailChannel].Subject != "Order 1 shipped" {
+		t.Errorf("got subject %s", sent[EmailChannel].Subject)
+	}
+	if sent[EmailChannel].Body != "<p>&lt;b&gt;foo&lt;/b&gt;</p>" {
+		t.Errorf("email body should be html escaped; got %s", sent[EmailChannel].Body)
+	}
+	if _, ok := sent[SMSChannel]; ok {
+		t.Errorf("sms should not be sent when disabled by preferences")
+	}
+
+	// User without preferences uses default channels, and push
+	// fails as user now has a subscription
+	recipient.UserID = "2"
+	recipient.PushSubscriptions = []PushSubscription{{Endpoint: "https://push.example.com"}}
+	err := d.Send(context.Background(), "orderShipped", recipient, data)
+	dispatchErr, ok := err.(*DispatchError)
+
+	if !ok {
+		t.Fatalf("should return *DispatchError; got %v", err)
+	}
+	if len(dispatchErr.Errors) != 1 || dispatchErr.Errors[PushChannel] == nil {
+		t.Errorf("only push should fail; got %s", err.Error())
+	}
+	if sent[SMSChannel].Body != "Order 1 shipped" {
+		t.Errorf("got sms body %s", sent[SMSChannel].Body)
+	}
+
+	if err = d.Send(context.Background(), "unknown", recipient, data); err == nil {
+		t.Errorf("should return error for unknown event")
+	}
+}
+
+func TestDispatcherPublisher(t *testing.T) {
+	var sent Message
+
+	d := NewDispatcher(
+		map[Channel]Notifier{
+			EmailChannel: NotifierFunc(func(ctx context.Context, msg Message) error {
+				sent = msg
+				return nil
+			}),
+		},
+		DispatcherConfig{},
+	)
+	d.RegisterTemplate("welcome", EmailChannel, "Welcome {{.name}}", "Hi {{.name}}")
+
+	payload, _ := json.Marshal(queuedNotification{
+		Event:     "welcome",
+		Recipient: Recipient{Email: "foo@email.com"},
+		Data:      map[string]string{"name": "foo"},
+	})
+
+	publisher := d.Publisher()
+	err := publisher.Publish(context.Background(), dbutil.OutboxMessage{Topic: NotificationTopic, Payload: payload})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if sent.Subject != "Welcome foo" {
+		t.Errorf("got subject %s", sent.Subject)
+	}
+
+	if err = publisher.Publish(context.Background(), dbutil.OutboxMessage{Topic: "other"}); err == nil {
+		t.Errorf("should return error for other topics")
+	}
+}
+
+func TestTwilioProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+
+		if r.URL.Path != "/2010-04-01/Accounts/sid/Messages.json" || user != "sid" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("To") != "+15555555555" || r.FormValue("Body") != "hello" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	provider := &TwilioProvider{AccountSID: "sid", AuthToken: "token", BaseURL: server.URL}
+
+	if err := provider.SendSMS(context.Background(), "+15555555555", "hello"); err != nil {
+		t.Errorf("should not return error; got %s", err.Error())
+	}
+
+	provider.AuthToken = "invalid"
+
+	if err := provider.SendSMS(context.Background(), "+15555555555", "hello"); err == nil {
+		t.Errorf("should return error for failed status")
+	}
+}
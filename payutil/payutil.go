@@ -0,0 +1,157 @@
+package payutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+const (
+	// StripeBaseURL is default base url of Client
+	StripeBaseURL = "https://api.stripe.com"
+)
+
+var (
+	// ErrNoSecretKey is returned from NewClient if secret key of the
+	// current mode is not set
+	ErrNoSecretKey = errors.New("payutil: stripe secret key not set")
+)
+
+// Error is error returned from stripe api
+type Error struct {
+	// Status is http status of response
+	Status int `json:"-"`
+
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("payutil: stripe %s (%s): %s", e.Type, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("payutil: stripe %s: %s", e.Type, e.Message)
+}
+
+// Client sends requests to the stripe api, or any api compatible with it
+type Client struct {
+	// SecretKey is used to authenticate requests
+	SecretKey string
+
+	// BaseURL is base url of api
+	// Default is StripeBaseURL
+	BaseURL string
+
+	// HTTPClient is used to send requests
+	// Default is http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// NewClient returns *Client using test secret key of conf if
+// conf.TestMode is set, else live secret key
+func NewClient(conf confutil.Stripe) (*Client, error) {
+	key := conf.StripeLiveSecretKey
+
+	if conf.TestMode {
+		key = conf.StripeTestSecretKey
+	}
+	if key == "" {
+		return nil, ErrNoSecretKey
+	}
+
+	return &Client{SecretKey: key}, nil
+}
+
+// WebhookSecret returns webhook secret of conf for its current mode
+func WebhookSecret(conf confutil.Stripe) string {
+	if conf.TestMode {
+		return conf.StripeTestWebhookSecret
+	}
+
+	return conf.StripeLiveWebhookSecret
+}
+
+// Do sends request with form encoded params to path eg. "/v1/customers"
+// and decodes json response into v if v is not nil
+// If api responds with an error, *Error is returned
+func (c *Client) Do(ctx context.Context, method, path string, params url.Values, v interface{}) error {
+	baseURL := c.BaseURL
+	client := c.HTTPClient
+
+	if baseURL == "" {
+		baseURL = StripeBaseURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	endpoint := strings.TrimRight(baseURL, "/") + path
+	body := ""
+
+	if method == http.MethodGet || method == http.MethodDelete {
+		if len(params) > 0 {
+			endpoint += "?" + params.Encode()
+		}
+	} else {
+		body = params.Encode()
+	}
+
+	req, err := http.NewRequest(method, endpoint, strings.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.SecretKey)
+
+	if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var errRes struct {
+			Error *Error `json:"error"`
+		}
+
+		if err = json.Unmarshal(resBody, &errRes); err != nil || errRes.Error == nil {
+			return &Error{
+				Status:  res.StatusCode,
+				Type:    "api_error",
+				Message: string(resBody),
+			}
+		}
+
+		errRes.Error.Status = res.StatusCode
+		return errRes.Error
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.Unmarshal(resBody, v)
+}
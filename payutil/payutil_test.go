@@ -0,0 +1,192 @@
+package payutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+const (
+	testSecret = "whsec_test"
+)
+
+func TestClientDo(t *testing.T) {
+	if _, err := NewClient(confutil.Stripe{TestMode: true}); err != ErrNoSecretKey {
+		t.Errorf("should return ErrNoSecretKey; got %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk_test" {
+			t.Errorf("should send test secret key; got %s", r.Header.Get("Authorization"))
+		}
+
+		r.ParseForm()
+
+		if r.Form.Get("email") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"type": "invalid_request_error", "param": "email", "message": "Missing email"}}`))
+			return
+		}
+
+		w.Write([]byte(`{"id": "cus_1"}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(confutil.Stripe{TestMode: true, StripeTestSecretKey: "sk_test"})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	client.BaseURL = ts.URL
+
+	var customer struct {
+		ID string `json:"id"`
+	}
+
+	if err = client.Do(context.Background(), http.MethodPost, "/v1/customers", url.Values{"email": {"a@b.com"}}, &customer); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if customer.ID != "cus_1" {
+		t.Errorf("should decode customer; got %+v", customer)
+	}
+
+	err = client.Do(context.Background(), http.MethodPost, "/v1/customers", nil, nil)
+
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusBadRequest || e.Param != "email" {
+		t.Errorf("should return *Error; got %v", err)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id": "evt_1"}`)
+	header := SignatureHeaderValue(payload, testSecret, time.Now())
+
+	if err := VerifySignature(payload, header, testSecret, DefaultTolerance); err != nil {
+		t.Errorf("should be valid; got %s", err.Error())
+	}
+	if err := VerifySignature(payload, header, "other", DefaultTolerance); err != ErrInvalidSignature {
+		t.Errorf("should return ErrInvalidSignature; got %v", err)
+	}
+	if err := VerifySignature([]byte(`{}`), header, testSecret, DefaultTolerance); err != ErrInvalidSignature {
+		t.Errorf("should return ErrInvalidSignature; got %v", err)
+	}
+	if err := VerifySignature(payload, "", testSecret, DefaultTolerance); err != ErrInvalidSignature {
+		t.Errorf("should return ErrInvalidSignature; got %v", err)
+	}
+
+	header = SignatureHeaderValue(payload, "", time.Now())
+
+	if err := VerifySignature(payload, header, "", DefaultTolerance); err != ErrInvalidSignature {
+		t.Errorf("should return ErrInvalidSignature for empty secret; got %v", err)
+	}
+
+	header = SignatureHeaderValue(payload, testSecret, time.Now().Add(-time.Hour))
+
+	if err := VerifySignature(payload, header, testSecret, DefaultTolerance); err != ErrExpiredSignature {
+		t.Errorf("should return ErrExpiredSignature; got %v", err)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var handled []string
+
+	db := dbtest.NewExpectDB(t)
+	wh := NewWebhookHandler(db, WebhookConfig{Secret: testSecret})
+	wh.Handle("invoice.paid", func(tx httputil.Tx, r *http.Request, event Event) error {
+		var invoice struct {
+			ID string `json:"id"`
+		}
+
+		if err := event.Decode(&invoice); err != nil {
+			return err
+		}
+		if invoice.ID == "in_fail" {
+			return errors.New("handler failed")
+		}
+
+		handled = append(handled, invoice.ID)
+		return nil
+	})
+
+	serve := func(payload, header string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+
+		if header == "" {
+			header = SignatureHeaderValue([]byte(payload), testSecret, time.Now())
+		}
+
+		req.Header.Set(SignatureHeader, header)
+		rr := httptest.NewRecorder()
+		wh.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	selectQuery := `select 1 from stripe_event where id = \$1`
+	insertQuery := `insert into stripe_event \(id, type, processed_at\) values \(\$1, \$2, \$3\)`
+	paid := `{"id": "evt_1", "type": "invoice.paid", "data": {"object": {"id": "in_1"}}}`
+
+	db.ExpectBegin()
+	db.ExpectQuery(selectQuery).WithArgs("evt_1").WillReturnRows(dbtest.NewRows("found"))
+	db.ExpectExec(insertQuery).WithArgs("evt_1", "invoice.paid", dbtest.AnyArg()).WillReturnResult(dbtest.NewResult(0, 1))
+	db.ExpectCommit()
+
+	if status := serve(paid, ""); status != http.StatusOK {
+		t.Errorf("should have status 200; got %d", status)
+	}
+
+	// Redelivered event is acknowledged without being handled
+	db.ExpectBegin()
+	db.ExpectQuery(selectQuery).WithArgs("evt_1").WillReturnRows(dbtest.NewRows("found").AddRow(1))
+	db.ExpectRollback()
+
+	if status := serve(paid, ""); status != http.StatusOK {
+		t.Errorf("should have status 200; got %d", status)
+	}
+
+	db.ExpectBegin()
+	db.ExpectQuery(selectQuery).WithArgs("evt_2").WillReturnRows(dbtest.NewRows("found"))
+	db.ExpectRollback()
+
+	if status := serve(`{"id": "evt_2", "type": "invoice.paid", "data": {"object": {"id": "in_fail"}}}`, ""); status != http.StatusInternalServerError {
+		t.Errorf("should have status 500; got %d", status)
+	}
+
+	if status := serve(`{"id": "evt_3", "type": "customer.created"}`, ""); status != http.StatusOK {
+		t.Errorf("should have status 200; got %d", status)
+	}
+	if status := serve(paid, "t=1,v1=invalid"); status != http.StatusBadRequest {
+		t.Errorf("should have status 400; got %d", status)
+	}
+	if status := serve(`{"type": "invoice.paid"}`, ""); status != http.StatusBadRequest {
+		t.Errorf("should have status 400; got %d", status)
+	}
+
+	if len(handled) != 1 || handled[0] != "in_1" {
+		t.Errorf("should only handle in_1; got %v", handled)
+	}
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("should meet expectations; got %s", err.Error())
+	}
+
+	// Events signed with an empty key are rejected if secret is not set
+	wh = NewWebhookHandler(db, WebhookConfig{})
+	wh.Handle("invoice.paid", func(tx httputil.Tx, r *http.Request, event Event) error {
+		t.Errorf("should not handle event with empty secret")
+		return nil
+	})
+
+	if status := serve(paid, SignatureHeaderValue([]byte(paid), "", time.Now())); status != http.StatusBadRequest {
+		t.Errorf("should have status 400 with empty secret; got %d", status)
+	}
+}
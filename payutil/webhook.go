@@ -0,0 +1,317 @@
+package payutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// SignatureHeader is header of webhook requests that holds signature
+	// of payload
+	SignatureHeader = "Stripe-Signature"
+
+	// DefaultEventTable is table used to store processed events if
+	// WebhookConfig#TableName is not set
+	DefaultEventTable = "stripe_event"
+
+	// DefaultTolerance is default max age of webhook signatures
+	DefaultTolerance = time.Minute * 5
+
+	invalidSignatureTxt = "Invalid signature"
+	invalidEventTxt     = "Invalid event"
+)
+
+const (
+	eventTableQuery = `
+	create table if not exists %s (
+		id varchar(255) not null primary key,
+		type varchar(255) not null,
+		processed_at timestamp not null default current_timestamp
+	)`
+)
+
+var (
+	// ErrInvalidSignature is returned from VerifySignature if header has
+	// no signature of payload signed with secret
+	ErrInvalidSignature = errors.New("payutil: invalid webhook signature")
+
+	// ErrExpiredSignature is returned from VerifySignature if timestamp
+	// of header is older than tolerance
+	ErrExpiredSignature = errors.New("payutil: webhook signature expired")
+)
+
+// Event is event sent to webhook endpoints
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Created  int64  `json:"created"`
+	Livemode bool   `json:"livemode"`
+	Data     struct {
+		// Object is json of the object of the event eg. the invoice of
+		// an "invoice.paid" event
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Decode decodes object of event into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data.Object, v)
+}
+
+// EventHandler handles an event within tx, which is committed along
+// with the record of the event being processed
+type EventHandler func(tx httputil.Tx, r *http.Request, event Event) error
+
+// WebhookConfig is config struct used for WebhookHandler
+type WebhookConfig struct {
+	// Secret is signing secret of webhook endpoint, see WebhookSecret
+	// Every request is rejected if empty
+	// - Required
+	Secret string
+
+	// Tolerance is max age of signatures
+	// Default is DefaultTolerance
+	Tolerance time.Duration
+
+	// TableName is table used to store ids of processed events
+	// Default is DefaultEventTable
+	TableName string
+
+	// DBType is the type of database eg. Postgres
+	// This is used for placeholder binding
+	// Default is Postgres
+	DBType string
+}
+
+func (w *WebhookConfig) setDefaults() {
+	if w.Tolerance <= 0 {
+		w.Tolerance = DefaultTolerance
+	}
+	if w.TableName == "" {
+		w.TableName = DefaultEventTable
+	}
+	if w.DBType == "" {
+		w.DBType = dbutil.Postgres
+	}
+}
+
+// CreateEventTable creates table of processed events if it does not exist
+func CreateEventTable(db httputil.XODB, config WebhookConfig) error {
+	config.setDefaults()
+	_, err := db.Exec(fmt.Sprintf(eventTableQuery, config.TableName))
+	return err
+}
+
+// VerifySignature returns nil if header, the value of SignatureHeader,
+// has a signature of payload signed with secret that is not older
+// than tolerance, else ErrInvalidSignature or ErrExpiredSignature
+//
+// Every header is invalid if secret is empty, as anyone could sign
+// payloads with an empty key
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected := []byte(computeSignature(payload, timestamp, secret))
+	valid := false
+
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), expected) {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+// SignatureHeaderValue returns value of SignatureHeader for payload
+// signed with secret at t, mainly used for tests
+func SignatureHeaderValue(payload []byte, secret string, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + computeSignature(payload, timestamp, secret)
+}
+
+// WebhookHandler is endpoint for stripe webhooks
+//
+// Signature of every request is verified and its event is dispatched
+// to the EventHandler registered for its type with WebhookHandler#Handle
+// Events are processed at most once, by storing their ids in
+// WebhookConfig#TableName within the transaction of the EventHandler,
+// so redelivered events are acknowledged without being handled again
+//
+// Status is 400 if signature or event is invalid, 500 if EventHandler
+// fails so stripe retries the event, else 200, including events with no
+// EventHandler
+type WebhookHandler struct {
+	db       httputil.DBInterface
+	config   WebhookConfig
+	handlers map[string]EventHandler
+}
+
+// NewWebhookHandler returns *WebhookHandler
+func NewWebhookHandler(db httputil.DBInterface, config WebhookConfig) *WebhookHandler {
+	config.setDefaults()
+
+	return &WebhookHandler{
+		db:       db,
+		config:   config,
+		handlers: make(map[string]EventHandler),
+	}
+}
+
+// Handle registers handler for events of eventType eg. "invoice.paid"
+// Should only be called before serving requests
+func (wh *WebhookHandler) Handle(eventType string, handler EventHandler) {
+	wh.handlers[eventType] = handler
+}
+
+func (wh *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event Event
+
+	if apiutil.HasBodyError(w, r) {
+		return
+	}
+
+	payload, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		apiutil.WriteError(w, err)
+		return
+	}
+
+	if err = VerifySignature(payload, r.Header.Get(SignatureHeader), wh.config.Secret, wh.config.Tolerance); err != nil {
+		apiutil.WriteError(w, badRequest(invalidSignatureTxt))
+		return
+	}
+
+	if err = json.Unmarshal(payload, &event); err != nil || event.ID == "" {
+		apiutil.WriteError(w, badRequest(invalidEventTxt))
+		return
+	}
+
+	handler, ok := wh.handlers[event.Type]
+
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err = wh.process(r, handler, event); err != nil {
+		apiutil.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// process calls handler within transaction unless event was already
+// processed
+func (wh *WebhookHandler) process(r *http.Request, handler EventHandler, event Event) error {
+	var found int
+
+	tx, err := wh.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		wh.rebind("select 1 from %s where id = ?"),
+		event.ID,
+	).Scan(&found)
+
+	if err == nil {
+		tx.Rollback()
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		tx.Rollback()
+		return err
+	}
+
+	if err = handler(tx, r, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err = tx.Exec(
+		wh.rebind("insert into %s (id, type, processed_at) values (?, ?, ?)"),
+		event.ID,
+		event.Type,
+		time.Now().UTC(),
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return wh.db.Commit(tx)
+}
+
+func (wh *WebhookHandler) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(wh.config.DBType), fmt.Sprintf(query, wh.config.TableName))
+}
+
+func computeSignature(payload []byte, timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func badRequest(message string) *httputil.Error {
+	return &httputil.Error{
+		Code:    httputil.CodeInvalid,
+		Message: message,
+		Status:  http.StatusBadRequest,
+	}
+}
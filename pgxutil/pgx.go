@@ -0,0 +1,75 @@
+// Package pgxutil adapts pgx connections and rows into httputil#Querier
+// and httputil#Rower so queryutil, SetRowerResults and the validation
+// rules can be used with pgx without sqlx based dbutil#DB
+package pgxutil
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/TravisS25/httputil"
+)
+
+// Conn is implemented by *pgx.Conn, *pgxpool.Pool and pgx.Tx
+type Conn interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Querier adapts Conn into httputil#Querier and httputil#QuerierContext
+//
+// Queries should use postgres placeholders eg. queryutil#QueryConfig
+// with dbutil#Postgres dialect
+type Querier struct {
+	conn Conn
+}
+
+// NewQuerier returns *Querier
+func NewQuerier(conn Conn) *Querier {
+	return &Querier{conn: conn}
+}
+
+// QueryRow is wrapper for pgx.Conn#QueryRow using context.Background
+func (q *Querier) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	return q.conn.QueryRow(context.Background(), query, args...)
+}
+
+// Query is wrapper for pgx.Conn#Query using context.Background
+func (q *Querier) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	return q.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is wrapper for pgx.Conn#Query
+func (q *Querier) QueryContext(ctx context.Context, query string, args ...interface{}) (httputil.Rower, error) {
+	rows, err := q.conn.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRows(rows), nil
+}
+
+// Rows adapts pgx.Rows into httputil#Rower
+// Like *sql.Rows, rows are closed once Next returns false
+type Rows struct {
+	pgx.Rows
+}
+
+// NewRows returns *Rows
+func NewRows(rows pgx.Rows) *Rows {
+	return &Rows{Rows: rows}
+}
+
+// Columns returns names of columns of rows
+func (r *Rows) Columns() ([]string, error) {
+	fields := r.Rows.FieldDescriptions()
+	columns := make([]string, 0, len(fields))
+
+	for _, v := range fields {
+		columns = append(columns, string(v.Name))
+	}
+
+	return columns, r.Rows.Err()
+}
@@ -0,0 +1,88 @@
+package pgxutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/TravisS25/httputil"
+)
+
+// mockRows is pgx.Rows returning names
+// Methods not used by Rows panic as the embedded interface is nil
+type mockRows struct {
+	pgx.Rows
+	names []string
+	i     int
+}
+
+func (m *mockRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return []pgproto3.FieldDescription{{Name: []byte("name")}}
+}
+
+func (m *mockRows) Next() bool {
+	m.i++
+	return m.i <= len(m.names)
+}
+
+func (m *mockRows) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = m.names[m.i-1]
+	return nil
+}
+
+func (m *mockRows) Err() error {
+	return nil
+}
+
+type mockConn struct {
+	err error
+}
+
+func (m mockConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &mockRows{names: []string{"foo", "bar"}}, nil
+}
+
+func (m mockConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &mockRows{names: []string{"foo"}, i: 1}
+}
+
+func TestQuerier(t *testing.T) {
+	var querier httputil.Querier = NewQuerier(mockConn{})
+	var name string
+
+	if err := querier.QueryRow("select name from foo").Scan(&name); err != nil || name != "foo" {
+		t.Errorf("should scan row; got %s, %v", name, err)
+	}
+
+	rower, err := querier.Query("select name from foo")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if columns, _ := rower.Columns(); len(columns) != 1 || columns[0] != "name" {
+		t.Errorf("should return columns; got %v", columns)
+	}
+
+	var names []string
+
+	for rower.Next() {
+		rower.Scan(&name)
+		names = append(names, name)
+	}
+
+	if len(names) != 2 || names[1] != "bar" {
+		t.Errorf("should scan every row; got %v", names)
+	}
+
+	if rower, err = NewQuerier(mockConn{err: errors.New("err")}).Query("select"); rower != nil || err == nil {
+		t.Errorf("should return nil rower with error")
+	}
+}
@@ -0,0 +1,48 @@
+package httputil
+
+import (
+	"context"
+	"sync"
+)
+
+// Pinger is implemented by stores that can be pinged to determine if
+// they're reachable eg. for health checks
+//
+// It's the same signature as sql.DB#PingContext so *sql.DB, *sqlx.DB
+// and dbutil#DB already implement it
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingerFunc allows an ordinary function to be used as a Pinger
+type PingerFunc func(ctx context.Context) error
+
+// PingContext calls f(ctx)
+func (f PingerFunc) PingContext(ctx context.Context) error {
+	return f(ctx)
+}
+
+// PingAll pings every pinger concurrently and returns error of
+// each, keyed by the same key as pingers, where nil is healthy
+func PingAll(ctx context.Context, pingers map[string]Pinger) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	errs := make(map[string]error, len(pingers))
+
+	for k, v := range pingers {
+		wg.Add(1)
+
+		go func(name string, pinger Pinger) {
+			defer wg.Done()
+			err := pinger.PingContext(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			errs[name] = err
+		}(k, v)
+	}
+
+	wg.Wait()
+	return errs
+}
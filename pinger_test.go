@@ -0,0 +1,29 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPingAll(t *testing.T) {
+	errDown := errors.New("down")
+	errs := PingAll(context.Background(), map[string]Pinger{
+		"up": PingerFunc(func(ctx context.Context) error {
+			return nil
+		}),
+		"down": PingerFunc(func(ctx context.Context) error {
+			return errDown
+		}),
+	})
+
+	if len(errs) != 2 {
+		t.Fatalf("should return error of every pinger; got %v", errs)
+	}
+	if errs["up"] != nil {
+		t.Errorf("should not return error; got %s", errs["up"].Error())
+	}
+	if errs["down"] != errDown {
+		t.Errorf("should return error of pinger; got %v", errs["down"])
+	}
+}
@@ -8,6 +8,7 @@ package httputil
 */
 
 import (
+	"context"
 	"database/sql"
 )
 
@@ -17,6 +18,12 @@ type Querier interface {
 	Query(query string, args ...interface{}) (Rower, error)
 }
 
+// QuerierContext is for querying rows from database with a context
+// so queries can be cancelled or timed out
+type QuerierContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (Rower, error)
+}
+
 // Scanner will scan row returned from database
 type Scanner interface {
 	Scan(dest ...interface{}) error
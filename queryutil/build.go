@@ -0,0 +1,73 @@
+package queryutil
+
+import (
+	"github.com/pkg/errors"
+)
+
+// BuildQuery returns the final query and args that GetQueriedResults
+// would execute for r without executing it, so generated queries can be
+// verified in tests without a database
+func BuildQuery(
+	query string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) (string, []interface{}, error) {
+	replacements, err := GetPreQueryResults(
+		&query,
+		prependVars,
+		fields,
+		r,
+		nil,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "")
+	}
+
+	return query, replacements, nil
+}
+
+// BuildCountQuery returns the final count query and args that
+// GetCountResults would execute for r without executing it
+func BuildCountQuery(
+	countQuery string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) (string, []interface{}, error) {
+	results, err := getReplacementResults(
+		nil,
+		&countQuery,
+		r,
+		&paramConf,
+		&queryConf,
+		fields,
+	)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "")
+	}
+
+	replacements, err := getResults(
+		&countQuery,
+		nil,
+		queryConf,
+		prependVars,
+		results.Replacements,
+		nil,
+	)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "")
+	}
+
+	applyCountMode(&countQuery, queryConf)
+	return countQuery, replacements, nil
+}
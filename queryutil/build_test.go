@@ -0,0 +1,75 @@
+package queryutil
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestBuildQuery(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"foo.name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+		},
+	}
+	r := mapFormRequest{
+		"filters": `[{"field": "foo.name", "operator": "eq", "value": "bar"}]`,
+		"sorts":   `[{"field": "foo.name", "dir": "desc"}]`,
+		"take":    "10",
+		"skip":    "20",
+	}
+	queryConf := QueryConfig{Dialect: dbutil.Postgres}
+
+	query, args, err := BuildQuery(
+		"select foo.id, foo.name from foo where foo.active = ?",
+		[]interface{}{true},
+		fields,
+		r,
+		ParamConfig{},
+		queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(
+		t,
+		`select foo.id, foo.name from foo
+		where foo.active = $1 and foo.name = $2
+		order by foo.name desc
+		limit $3 offset $4`,
+		query,
+		[]interface{}{true, "bar", 10, 20},
+		args,
+	)
+
+	query, args, err = BuildCountQuery(
+		"select count(*) from foo where foo.active = ?",
+		[]interface{}{true},
+		fields,
+		r,
+		ParamConfig{},
+		queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(
+		t,
+		"select count( * ) from foo where foo.active = $1 and foo.name = $2",
+		query,
+		[]interface{}{true, "bar"},
+		args,
+	)
+
+	if _, _, err = BuildQuery("select * from foo", nil, fields, mapFormRequest{
+		"filters": `[{"field": "foo.secret", "operator": "eq", "value": "bar"}]`,
+	}, ParamConfig{}, queryConf); err == nil {
+		t.Errorf("should return error for filter not within fields")
+	}
+}
@@ -0,0 +1,334 @@
+package queryutil
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// ChecksumCacheKey is format of key of page cached by
+	// GetChecksumCachedResults, where %s is hash of query and its args
+	ChecksumCacheKey = "querychecksum:%s"
+)
+
+var (
+	// ErrChecksumCache is returned from GetChecksumCachedResults when
+	// ChecksumCacheConfig#Cache isn't set
+	ErrChecksumCache = errors.New("queryutil: checksum cache is required")
+)
+
+// ChecksumCacheConfig is config struct used for GetChecksumCachedResults
+type ChecksumCacheConfig struct {
+	// Cache stores pages - Required
+	Cache cacheutil.CacheStore
+
+	// TTL is how long pages are cached, which only bounds how long
+	// pages that are no longer requested stay within cache as every
+	// page is validated against its checksum before it's served
+	// Default is 10 minutes
+	TTL time.Duration
+
+	// RowerMapConfig is config rows are converted with
+	RowerMapConfig
+}
+
+// ChecksumResults are results of GetChecksumCachedResults
+type ChecksumResults struct {
+	// Rows are rows of page converted with RowerMapConfig
+	Rows []map[string]interface{}
+
+	// Count is count returned by count query
+	Count int
+
+	// Checksum is hash of count and change markers returned by count
+	// query, which changes whenever rows matching filters change and
+	// can be used as ETag of results
+	Checksum string
+
+	// Cached is whether Rows were served from cache
+	Cached bool
+}
+
+// checksumPage is page as stored in cache
+type checksumPage struct {
+	Checksum string                   `json:"checksum"`
+	Rows     []map[string]interface{} `json:"rows"`
+}
+
+// GetCountAndChecksumResults is the same as GetCountResults but count
+// query also selects change markers after count, eg.
+// "select count(*), max(foo.updated_at) from foo", or for postgres rows
+// without an updated column "max(foo.xmin::text::bigint)", and returns
+// checksum of count and markers along with count
+//
+// Checksum changes whenever rows matching filters of r are inserted,
+// deleted or updated, as long as markers change with every update, so
+// it can validate cached results without executing the query itself
+// If count query returns several rows, counts are summed and markers of
+// every row are part of checksum
+// QueryConfig#CountMode is ignored as markers must be selected from the
+// table itself
+func GetCountAndChecksumResults(
+	countQuery *string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	db httputil.Querier,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) (int, string, error) {
+	var results *resultReplacements
+	var err error
+
+	setDBBindVar(db, &queryConf)
+
+	if results, err = getReplacementResults(
+		nil,
+		countQuery,
+		r,
+		&paramConf,
+		&queryConf,
+		fields,
+	); err != nil {
+		return 0, "", errors.Wrap(err, "")
+	}
+
+	replacements, err := getResults(
+		countQuery,
+		db,
+		queryConf,
+		prependVars,
+		results.Replacements,
+		nil,
+	)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return queryChecksum(db, queryConf, *countQuery, replacements)
+}
+
+// queryChecksum executes count query and returns the sum of counts
+// of every row along with hash of counts and markers of every row
+func queryChecksum(db httputil.Querier, queryConf QueryConfig, query string, replacements []interface{}) (int, string, error) {
+	rower, err := dbutil.QueryWithTimeout(db, queryConf.Timeout, query, replacements...)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return 0, "", errors.Wrap(err, "")
+	}
+	if len(columns) == 0 {
+		return 0, "", errors.New("queryutil: count query must select count")
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	hash := sha1.New()
+	totalCount := 0
+
+	for rower.Next() {
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return 0, "", errors.Wrap(err, "")
+		}
+
+		count, err := checksumCount(values[0])
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		totalCount += count
+
+		for _, v := range values {
+			writeChecksumValue(hash, v)
+		}
+
+		hash.Write([]byte{'\n'})
+	}
+
+	if err = dbutil.RowerErr(rower); err != nil {
+		return 0, "", err
+	}
+
+	return totalCount, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeChecksumValue writes v to hash in a form that doesn't depend on
+// whether driver returned it as bytes or string
+func writeChecksumValue(w io.Writer, v interface{}) {
+	switch t := v.(type) {
+	case []byte:
+		w.Write(t)
+	case time.Time:
+		io.WriteString(w, t.UTC().Format(time.RFC3339Nano))
+	default:
+		fmt.Fprint(w, v)
+	}
+
+	w.Write([]byte{0})
+}
+
+// checksumCount converts count column of count query to int
+func checksumCount(val interface{}) (int, error) {
+	switch v := val.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case []byte:
+		if count, err := strconv.Atoi(string(v)); err == nil {
+			return count, nil
+		}
+	case string:
+		if count, err := strconv.Atoi(v); err == nil {
+			return count, nil
+		}
+	}
+
+	return 0, fmt.Errorf("queryutil: invalid count '%v'", val)
+}
+
+// GetChecksumCachedResults executes queries in two phases so cached
+// pages are always validated before they're served
+//
+// First, count query is executed with GetCountAndChecksumResults, then
+// if page of query for r is cached with the same checksum, it's served
+// from cache, else query is executed and page is cached along with its
+// checksum
+// This gives near real time results for cached grids as any write to
+// rows matching filters changes checksum, without having to invalidate
+// cached pages when writing
+//
+// Pages are cached by hash of query and its args once filters, sorts
+// and limit are applied so every page of every filter is cached apart
+// Rows served from cache are decoded from json, with numbers as
+// json.Number, so they should be written as json
+func GetChecksumCachedResults(
+	query *string,
+	countQuery *string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	db httputil.Querier,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+	config ChecksumCacheConfig,
+) (ChecksumResults, error) {
+	if config.Cache == nil {
+		return ChecksumResults{}, ErrChecksumCache
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute * 10
+	}
+
+	count, checksum, err := GetCountAndChecksumResults(
+		countQuery,
+		prependVars,
+		fields,
+		r,
+		db,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return ChecksumResults{}, errors.Wrap(err, "")
+	}
+
+	replacements, err := GetPreQueryResults(
+		query,
+		prependVars,
+		fields,
+		r,
+		db,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return ChecksumResults{}, errors.Wrap(err, "")
+	}
+
+	results := ChecksumResults{Count: count, Checksum: checksum}
+	key, err := checksumCacheKey(*query, replacements)
+
+	if err != nil {
+		return ChecksumResults{}, err
+	}
+
+	if b, err := config.Cache.Get(key); err == nil {
+		var page checksumPage
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+
+		if err = dec.Decode(&page); err == nil && page.Checksum == checksum {
+			results.Rows = page.Rows
+			results.Cached = true
+			return results, nil
+		}
+	} else if err != cacheutil.ErrCacheNil {
+		// Cache being down shouldn't fail query
+		httputil.Logger.Errorf("queryutil: checksum cache get err: %s", err.Error())
+	}
+
+	rower, err := dbutil.QueryWithTimeout(db, queryConf.Timeout, *query, replacements...)
+
+	if err != nil {
+		return ChecksumResults{}, err
+	}
+
+	if results.Rows, err = RowerToMaps(rower, config.RowerMapConfig); err != nil {
+		return ChecksumResults{}, err
+	}
+
+	payload, err := json.Marshal(checksumPage{Checksum: checksum, Rows: results.Rows})
+
+	if err == nil {
+		err = config.Cache.SetErr(key, payload, config.TTL)
+	}
+	if err != nil {
+		httputil.Logger.Errorf("queryutil: checksum cache set err: %s", err.Error())
+	}
+
+	return results, nil
+}
+
+// checksumCacheKey returns key page of query with args is cached under
+func checksumCacheKey(query string, args []interface{}) (string, error) {
+	b, err := json.Marshal(args)
+
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(query))
+	hash.Write([]byte{0})
+	hash.Write(b)
+
+	return fmt.Sprintf(ChecksumCacheKey, hex.EncodeToString(hash.Sum(nil))), nil
+}
@@ -0,0 +1,71 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestGetChecksumCachedResults(t *testing.T) {
+	const (
+		countQuery = "select count(*), max(foo.updated_at) from foo"
+		pageQuery  = "select foo.id from foo limit $1 offset $2"
+	)
+
+	updated := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := cachetest.NewMemoryCache()
+	db := dbtest.NewExpectDB(t)
+	db.QueryMatcher = dbtest.QueryMatcherEqual
+
+	db.ExpectQuery(countQuery).WillReturnRows(dbtest.NewRows("count", "max").AddRow(int64(1), updated))
+	db.ExpectQuery(pageQuery).WillReturnRows(dbtest.NewRows("id").AddRow(int64(1)))
+	db.ExpectQuery(countQuery).WillReturnRows(dbtest.NewRows("count", "max").AddRow(int64(1), updated))
+	db.ExpectQuery(countQuery).WillReturnRows(dbtest.NewRows("count", "max").AddRow(int64(1), updated.Add(time.Second)))
+	db.ExpectQuery(pageQuery).WillReturnRows(dbtest.NewRows("id").AddRow(int64(2)))
+
+	get := func() ChecksumResults {
+		t.Helper()
+
+		query := "select foo.id from foo"
+		count := countQuery
+		results, err := GetChecksumCachedResults(
+			&query,
+			&count,
+			nil,
+			nil,
+			mapFormRequest{},
+			db,
+			ParamConfig{},
+			QueryConfig{Dialect: dbutil.Postgres},
+			ChecksumCacheConfig{Cache: cache},
+		)
+
+		if err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		return results
+	}
+
+	first := get()
+
+	if first.Cached || first.Count != 1 || first.Rows[0]["id"] != int64(1) {
+		t.Errorf("first results should be queried; got %+v", first)
+	}
+
+	second := get()
+
+	if !second.Cached || second.Checksum != first.Checksum || second.Rows[0]["id"] != json.Number("1") {
+		t.Errorf("results with the same checksum should be cached; got %+v", second)
+	}
+
+	third := get()
+
+	if third.Cached || third.Checksum == first.Checksum || third.Rows[0]["id"] != int64(2) {
+		t.Errorf("results with changed checksum should be queried; got %+v", third)
+	}
+}
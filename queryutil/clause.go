@@ -0,0 +1,218 @@
+package queryutil
+
+import (
+	"strings"
+)
+
+// Markers can be placed within queries, eg. "select * from (select *
+// from foo /*where*/) f", to set where filters, groups and sorts are
+// inserted instead of at the end of query, which is required when the
+// clause belongs to a subquery
+//
+// Whether "where", "group by" or "order by" is inserted before the
+// clause, or "and" and ",", is determined by the query the marker is
+// within, ignoring subqueries
+// Every placeholder of query should come before markers as args of
+// query are bound before args of filters
+const (
+	WhereMarker = "/*where*/"
+	GroupMarker = "/*group*/"
+	OrderMarker = "/*order*/"
+)
+
+// Placeholders can be embedded within queries, eg. ctes and unions, to
+// set where generated filters, groups, sorts and limit are substituted,
+// falling back to appending them to query if query doesn't have them
+// Clauses are added like they are at markers, which take precedence,
+// and placeholders are removed once query is built
+//
+// Args are bound in order of args of query, filters and then limit so
+// FiltersPlaceholder must come before LimitPlaceholder
+const (
+	FiltersPlaceholder = "{{filters}}"
+	GroupsPlaceholder  = "{{groups}}"
+	SortsPlaceholder   = "{{sorts}}"
+	LimitPlaceholder   = "{{limit}}"
+)
+
+// clauseKind is clause of query that filters, groups and sorts are
+// added to
+type clauseKind struct {
+	// keyword is first word of clause and by is second if it has one
+	keyword string
+	by      bool
+
+	// marker, or else placeholder, is where clause is inserted if
+	// query has it
+	marker      string
+	placeholder string
+
+	// start is prepended to sql if query doesn't have clause yet, else
+	// join is
+	start string
+	join  string
+}
+
+var (
+	whereClause = clauseKind{
+		keyword:     "where",
+		marker:      WhereMarker,
+		placeholder: FiltersPlaceholder,
+		start:       " where",
+		join:        " and",
+	}
+	groupClause = clauseKind{
+		keyword:     "group",
+		by:          true,
+		marker:      GroupMarker,
+		placeholder: GroupsPlaceholder,
+		start:       " group by",
+		join:        ",",
+	}
+	orderClause = clauseKind{
+		keyword:     "order",
+		by:          true,
+		marker:      OrderMarker,
+		placeholder: SortsPlaceholder,
+		start:       " order by ",
+		join:        ",",
+	}
+)
+
+// addClause adds sql to clause of query, at marker or placeholder of
+// clause if query has one, else at the end of query
+func addClause(query *string, clause clauseKind, sql string) {
+	pos := strings.Index(*query, clause.marker)
+
+	if pos == -1 {
+		pos = strings.Index(*query, clause.placeholder)
+	}
+	if pos == -1 {
+		pos = len(*query)
+	}
+
+	prefix := clause.start
+
+	if hasClause(*query, pos, clause) {
+		prefix = clause.join
+	}
+	if pos > 0 && (*query)[pos-1] == ' ' {
+		prefix = strings.TrimPrefix(prefix, " ")
+	}
+
+	*query = (*query)[:pos] + prefix + sql + (*query)[pos:]
+}
+
+// addLimit substitutes LimitPlaceholder of query with limit, or
+// appends limit to query if query doesn't have it
+func addLimit(query *string, limit string) {
+	pos := strings.Index(*query, LimitPlaceholder)
+
+	if pos == -1 {
+		*query += limit
+		return
+	}
+	if pos > 0 && (*query)[pos-1] == ' ' {
+		limit = strings.TrimPrefix(limit, " ")
+	}
+
+	*query = (*query)[:pos] + limit + (*query)[pos+len(LimitPlaceholder):]
+}
+
+// removePlaceholders removes placeholders left in query once it's built
+func removePlaceholders(query *string) {
+	placeholders := []string{
+		FiltersPlaceholder, GroupsPlaceholder, SortsPlaceholder, LimitPlaceholder, FieldsPlaceholder,
+	}
+
+	for _, v := range placeholders {
+		*query = strings.Replace(*query, v, "", -1)
+	}
+}
+
+// hasClause returns whether query at pos is within a statement that
+// already has clause
+//
+// Query is scanned as tokens so clauses within strings, comments and
+// quoted identifiers are ignored, along with those of subqueries and
+// ctes, which are within parentheses, and of selects combined with
+// pos by union, intersect or except
+func hasClause(query string, pos int, clause clauseKind) bool {
+	// found is whether clause was found at each depth of parentheses
+	found := []bool{false}
+	prev := ""
+
+	for i := 0; i < pos; {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes within are escaped by doubling them, which scans
+			// as two quoted tokens
+			end := strings.IndexByte(query[i+1:pos], c)
+
+			if end == -1 {
+				return found[len(found)-1]
+			}
+
+			i += end + 2
+			prev = ""
+		case c == '-' && i+1 < pos && query[i+1] == '-':
+			end := strings.IndexByte(query[i:pos], '\n')
+
+			if end == -1 {
+				return found[len(found)-1]
+			}
+
+			i += end
+		case c == '/' && i+1 < pos && query[i+1] == '*':
+			end := strings.Index(query[i+2:pos], "*/")
+
+			if end == -1 {
+				return found[len(found)-1]
+			}
+
+			i += end + 4
+		case c == '(':
+			found = append(found, false)
+			prev = ""
+			i++
+		case c == ')':
+			if len(found) > 1 {
+				found = found[:len(found)-1]
+			}
+
+			prev = ""
+			i++
+		case isClauseWordByte(c):
+			end := i
+
+			for end < pos && isClauseWordByte(query[end]) {
+				end++
+			}
+
+			word := strings.ToLower(query[i:end])
+
+			switch {
+			case word == "union" || word == "intersect" || word == "except":
+				found[len(found)-1] = false
+			case !clause.by && word == clause.keyword:
+				found[len(found)-1] = true
+			case clause.by && word == "by" && prev == clause.keyword:
+				found[len(found)-1] = true
+			}
+
+			prev = word
+			i = end
+		default:
+			i++
+		}
+	}
+
+	return found[len(found)-1]
+}
+
+func isClauseWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,181 @@
+package queryutil
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestAddClause(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		clause   clauseKind
+		expected string
+	}{
+		{
+			"noWhere",
+			"select * from foo",
+			whereClause,
+			"select * from foo where foo.id = ?",
+		},
+		{
+			"where",
+			"select * from foo where foo.active = true",
+			whereClause,
+			"select * from foo where foo.active = true and foo.id = ?",
+		},
+		{
+			"subqueryWhere",
+			"select * from foo where foo.id in (select bar.id from bar where bar.active)",
+			whereClause,
+			"select * from foo where foo.id in (select bar.id from bar where bar.active) and foo.id = ?",
+		},
+		{
+			"subqueryWithoutOuterWhere",
+			"select *, (select count(*) from bar where bar.foo_id = foo.id) from foo",
+			whereClause,
+			"select *, (select count(*) from bar where bar.foo_id = foo.id) from foo where foo.id = ?",
+		},
+		{
+			"cte",
+			"with active as (select * from foo where foo.active) select * from active",
+			whereClause,
+			"with active as (select * from foo where foo.active) select * from active where foo.id = ?",
+		},
+		{
+			"union",
+			"select * from foo where foo.active union select * from bar",
+			whereClause,
+			"select * from foo where foo.active union select * from bar where foo.id = ?",
+		},
+		{
+			"stringsAndComments",
+			"select 'where' as \"where\" from foo -- where\n/* where */",
+			whereClause,
+			"select 'where' as \"where\" from foo -- where\n/* where */ where foo.id = ?",
+		},
+		{
+			"marker",
+			"select * from (select * from foo /*where*/) f where f.total > 0",
+			whereClause,
+			"select * from (select * from foo where foo.id = ?/*where*/) f where f.total > 0",
+		},
+		{
+			"markerWhere",
+			"select * from (select * from foo where foo.active /*where*/) f",
+			whereClause,
+			"select * from (select * from foo where foo.active and foo.id = ?/*where*/) f",
+		},
+		{
+			"windowOrder",
+			"select row_number() over (order by foo.id) from foo",
+			orderClause,
+			"select row_number() over (order by foo.id) from foo order by  foo.id = ?",
+		},
+		{
+			"withinGroup",
+			"select percentile_cont(0.5) within group (order by foo.total) from foo",
+			groupClause,
+			"select percentile_cont(0.5) within group (order by foo.total) from foo group by foo.id = ?",
+		},
+		{
+			"groupBy",
+			"select foo.id from foo group by foo.id",
+			groupClause,
+			"select foo.id from foo group by foo.id, foo.id = ?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := test.query
+			addClause(&query, test.clause, " foo.id = ?")
+
+			if query != test.expected {
+				t.Errorf("should be:\n%s\ngot:\n%s", test.expected, query)
+			}
+		})
+	}
+}
+
+func TestGetFilterReplacementsMarker(t *testing.T) {
+	req := mapFormRequest{"filters": `[{"field": "id", "operator": "eq", "value": 1}]`}
+	fields := map[string]FieldConfig{
+		"id": {DBField: "foo.id", OperationConf: OperationConfig{CanFilterBy: true}},
+	}
+
+	query := "select * from (select * from foo where foo.id in (select bar.id from bar) /*where*/) f order by f.id"
+
+	if _, _, err := GetFilterReplacements(req, &query, "filters", QueryConfig{
+		PrependFilterFields: []Filter{{Field: "id", Operator: "neq", Value: 2.0}},
+	}, fields); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := "select * from (select * from foo where foo.id in (select bar.id from bar) and foo.id != ? and foo.id = ?/*where*/) f order by f.id"
+
+	if query != expected {
+		t.Errorf("should be:\n%s\ngot:\n%s", expected, query)
+	}
+}
+
+func TestBuildQueryPlaceholders(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+		},
+	}
+	r := mapFormRequest{
+		"filters": `[{"field": "name", "operator": "eq", "value": "bar"}]`,
+		"sorts":   `[{"field": "name", "dir": "desc"}]`,
+		"take":    "10",
+		"skip":    "20",
+	}
+	queryConf := QueryConfig{Dialect: dbutil.Postgres}
+
+	query, args, err := BuildQuery(
+		"with page as (select foo.id, foo.name from foo {{filters}} {{sorts}} {{limit}}) "+
+			"select * from page union all select * from archived",
+		nil,
+		fields,
+		r,
+		ParamConfig{},
+		queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(
+		t,
+		`with page as (
+			select foo.id, foo.name from foo
+			where foo.name = $1
+			order by foo.name desc
+			limit $2 offset $3
+		)
+		select * from page union all select * from archived`,
+		query,
+		[]interface{}{"bar", 10, 20},
+		args,
+	)
+
+	query, args, err = BuildCountQuery(
+		"select count(*) from foo {{filters}} {{limit}}",
+		nil,
+		fields,
+		r,
+		ParamConfig{},
+		queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(t, "select count(*) from foo where foo.name = $1", query, []interface{}{"bar"}, args)
+}
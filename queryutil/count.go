@@ -0,0 +1,137 @@
+package queryutil
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// WindowCountColumn is column of WindowCountSelect that holds total
+	// count of rows
+	WindowCountColumn = "window_count"
+)
+
+// CountMode determines how count query of GetCountResults and
+// GetQueriedAndCountResults is executed
+type CountMode int
+
+const (
+	// CountSum executes count query and sums counts of every returned
+	// row, so grouped count queries must select count of each group
+	CountSum CountMode = iota
+
+	// CountSubquery counts every row returned from count query, with
+	// filters and groups applied, by executing it as subquery eg.
+	// "select count(*) from (<count query>) count_query", so grouped
+	// queries return the number of groups
+	// If count query passed to GetQueriedAndCountResults is nil, the
+	// query itself is used before sorts, limit and offset are applied
+	CountSubquery
+)
+
+// applyCountMode wraps count query based on QueryConfig#CountMode
+func applyCountMode(countQuery *string, queryConf QueryConfig) {
+	if queryConf.CountMode == CountSubquery {
+		*countQuery = fmt.Sprintf("select count(*) from (%s) count_query", *countQuery)
+	}
+}
+
+// subqueryCountQuery returns copy of query to be used as count query
+// if countQuery is nil and QueryConfig#CountMode is CountSubquery,
+// else returns countQuery
+// This must be called before query is modified
+func subqueryCountQuery(query, countQuery *string, queryConf QueryConfig) *string {
+	if countQuery != nil || queryConf.CountMode != CountSubquery {
+		return countQuery
+	}
+
+	q := *query
+	return &q
+}
+
+// WindowCountSelect returns select expression that adds total count of
+// rows, before limit and offset are applied, to every row as
+// WindowCountColumn eg. "select foo.id, " + WindowCountSelect() + " from foo"
+// This is used with GetQueriedMapsWithWindowCount
+func WindowCountSelect() string {
+	return "count(*) over() as " + WindowCountColumn
+}
+
+// GetQueriedMapsWithWindowCount returns rows of query converted with
+// RowerToMaps along with total count read from WindowCountColumn, which
+// is removed from rows, so count is queried without a second round trip
+// query must select WindowCountSelect
+//
+// If no rows are returned, eg. offset is past the last row, count is 0
+func GetQueriedMapsWithWindowCount(
+	query *string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	db httputil.Querier,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+	mapConf RowerMapConfig,
+) ([]map[string]interface{}, int, error) {
+	rower, err := GetQueriedResults(
+		query,
+		prependVars,
+		fields,
+		r,
+		db,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "")
+	}
+
+	rows, err := RowerToMaps(rower, mapConf)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key := ColumnName(WindowCountColumn, mapConf.Naming)
+	count := 0
+
+	for i, row := range rows {
+		val, ok := row[key]
+
+		if !ok {
+			return nil, 0, fmt.Errorf("queryutil: query must select %s", WindowCountSelect())
+		}
+
+		if i == 0 {
+			if count, err = windowCount(val); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		delete(row, key)
+	}
+
+	return rows, count, nil
+}
+
+// windowCount converts value of WindowCountColumn to int based on how
+// it was converted by RowerToMaps
+func windowCount(val interface{}) (int, error) {
+	switch v := val.(type) {
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		if count, err := strconv.Atoi(v); err == nil {
+			return count, nil
+		}
+	}
+
+	return 0, fmt.Errorf("queryutil: invalid window count '%v'", val)
+}
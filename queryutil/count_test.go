@@ -0,0 +1,60 @@
+package queryutil
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestCountModes(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"foo.statusID": {
+			DBField:       "foo.status_id",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true, CanGroupBy: true},
+		},
+	}
+	r := mapFormRequest{
+		"groups": `[{"field": "foo.statusID"}]`,
+		"sorts":  `[{"field": "foo.statusID", "dir": "asc"}]`,
+	}
+	queryConf := QueryConfig{Dialect: dbutil.Postgres, CountMode: CountSubquery}
+
+	db := dbtest.NewExpectDB(t)
+	db.QueryMatcher = dbtest.QueryMatcherEqual
+	db.ExpectQuery("select foo.status_id from foo group by foo.status_id order by foo.status_id asc limit $1 offset $2").
+		WillReturnRows(dbtest.NewRows("status_id").AddRow(1).AddRow(2))
+	db.ExpectQuery("select count(*) from (select foo.status_id from foo group by foo.status_id) count_query").
+		WillReturnRows(dbtest.NewRows("count").AddRow(2))
+
+	query := "select foo.status_id from foo"
+	_, count, err := GetQueriedAndCountResults(&query, nil, nil, fields, r, db, ParamConfig{}, queryConf)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if count != 2 {
+		t.Errorf("should count groups; got %d", count)
+	}
+
+	query = "select foo.status_id, " + WindowCountSelect() + " from foo"
+	db.QueryMatcher = dbtest.QueryMatcherRegexp
+	db.ExpectQuery(regexp.QuoteMeta(WindowCountSelect())).
+		WillReturnRows(dbtest.NewRows("status_id", WindowCountColumn).AddRow(1, int64(5)).AddRow(2, int64(5)))
+
+	rows, count, err := GetQueriedMapsWithWindowCount(&query, nil, fields, mapFormRequest{}, db, ParamConfig{}, QueryConfig{}, RowerMapConfig{})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if count != 5 || len(rows) != 2 {
+		t.Errorf("should return 2 rows with count of 5; got %d rows with count %d", len(rows), count)
+	}
+	if _, ok := rows[0]["windowCount"]; ok {
+		t.Errorf("window count should be removed from rows")
+	}
+	if err = db.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
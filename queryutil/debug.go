@@ -0,0 +1,217 @@
+package queryutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// DebugConfig is config struct used to allow authorized users to debug
+// queries generated from url query params
+// This should generally only be enabled in staging
+type DebugConfig struct {
+	// Param is url query param that enables debugging when set to "true"
+	// Default is "debug"
+	Param string
+
+	// Authorize determines if user of request is allowed to debug
+	// queries eg. by checking user is within admin group
+	// Debugging is never enabled if Authorize is nil
+	Authorize func(r FormRequest) bool
+
+	// Explain runs "explain analyze" of generated query, which executes
+	// the query again, and adds the output to QueryDebug
+	Explain bool
+}
+
+// QueryDebug is debug info of queries generated by
+// GetQueriedAndCountResultsWithDebug
+type QueryDebug struct {
+	Query         string        `json:"query"`
+	Args          []interface{} `json:"args"`
+	CountQuery    string        `json:"countQuery"`
+	CountArgs     []interface{} `json:"countArgs"`
+	Duration      string        `json:"duration"`
+	CountDuration string        `json:"countDuration"`
+	Explain       []string      `json:"explain,omitempty"`
+}
+
+// DebugEnabled returns whether queries of r should be debugged based
+// on debug config of queryConf
+func DebugEnabled(r FormRequest, queryConf QueryConfig) bool {
+	debug := queryConf.Debug
+
+	if debug == nil || debug.Authorize == nil {
+		return false
+	}
+
+	param := debug.Param
+
+	if param == "" {
+		param = "debug"
+	}
+
+	if r.FormValue(param) != "true" {
+		return false
+	}
+
+	return debug.Authorize(r)
+}
+
+// GetQueriedAndCountResultsWithDebug is the same as GetQueriedAndCountResults
+// but also returns *QueryDebug with the generated queries, bound args,
+// timing and optionally explain output if debugging is enabled for r,
+// see DebugEnabled, else *QueryDebug is nil
+func GetQueriedAndCountResultsWithDebug(
+	query *string,
+	countQuery *string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	db httputil.Querier,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) (httputil.Rower, int, *QueryDebug, error) {
+	if !DebugEnabled(r, queryConf) {
+		rower, count, err := GetQueriedAndCountResults(
+			query,
+			countQuery,
+			prependVars,
+			fields,
+			r,
+			db,
+			paramConf,
+			queryConf,
+		)
+
+		return rower, count, nil, err
+	}
+
+	debug := &QueryDebug{}
+	countQuery = subqueryCountQuery(query, countQuery, queryConf)
+	replacements, err := GetPreQueryResults(
+		query,
+		prependVars,
+		fields,
+		r,
+		db,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "")
+	}
+
+	start := time.Now()
+	rower, err := dbutil.QueryWithTimeout(db, queryConf.Timeout, *query, replacements...)
+
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "")
+	}
+
+	debug.Duration = time.Since(start).String()
+	debug.Query = *query
+	debug.Args = replacements
+
+	results, err := getReplacementResults(
+		nil,
+		countQuery,
+		r,
+		&paramConf,
+		&queryConf,
+		fields,
+	)
+
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "")
+	}
+
+	countReplacements, err := getResults(
+		countQuery,
+		db,
+		queryConf,
+		prependVars,
+		results.Replacements,
+		nil,
+	)
+
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "")
+	}
+
+	applyCountMode(countQuery, queryConf)
+
+	start = time.Now()
+	count, err := queryCount(db, queryConf, *countQuery, countReplacements)
+
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "")
+	}
+
+	debug.CountDuration = time.Since(start).String()
+	debug.CountQuery = *countQuery
+	debug.CountArgs = countReplacements
+
+	if queryConf.Debug.Explain {
+		if debug.Explain, err = explainQuery(db, queryConf, *query, replacements); err != nil {
+			return nil, 0, nil, errors.Wrap(err, "")
+		}
+	}
+
+	return rower, count, debug, nil
+}
+
+// explainQuery returns every line of output of explaining query
+func explainQuery(db httputil.Querier, queryConf QueryConfig, query string, replacements []interface{}) ([]string, error) {
+	prefix := "explain analyze "
+
+	if queryConf.Dialect == dbutil.Sqlite {
+		prefix = "explain query plan "
+	}
+
+	rower, err := dbutil.QueryWithTimeout(db, queryConf.Timeout, prefix+query, replacements...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+
+	for rower.Next() {
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		parts := make([]string, 0, len(values))
+
+		for _, v := range values {
+			if b, ok := v.([]byte); ok {
+				parts = append(parts, string(b))
+			} else {
+				parts = append(parts, fmt.Sprint(v))
+			}
+		}
+
+		lines = append(lines, strings.Join(parts, "\t"))
+	}
+
+	return lines, dbutil.RowerErr(rower)
+}
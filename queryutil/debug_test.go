@@ -0,0 +1,163 @@
+package queryutil
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+type debugQuerier struct {
+	queries []string
+}
+
+func (d *debugQuerier) QueryRow(query string, args ...interface{}) httputil.Scanner {
+	return nil
+}
+
+func (d *debugQuerier) Query(query string, args ...interface{}) (httputil.Rower, error) {
+	d.queries = append(d.queries, query)
+
+	switch {
+	case strings.HasPrefix(query, "explain"):
+		return dbtest.NewRows("QUERY PLAN").AddRow([]byte("Seq Scan on foo")), nil
+	case strings.Contains(query, "count("):
+		return dbtest.NewRows("count").AddRow(1), nil
+	}
+
+	return dbtest.NewRows("id").AddRow(1), nil
+}
+
+func TestGetQueriedAndCountResultsWithDebug(t *testing.T) {
+	authorized := false
+	fields := map[string]FieldConfig{
+		"id": {DBField: "foo.id", OperationConf: OperationConfig{CanFilterBy: true}},
+	}
+	queryConf := QueryConfig{
+		Debug: &DebugConfig{
+			Authorize: func(r FormRequest) bool { return authorized },
+			Explain:   true,
+		},
+	}
+	r := mapFormRequest{
+		"debug":   "true",
+		"filters": `[{"field": "id", "operator": "eq", "value": 1}]`,
+	}
+
+	query := "select foo.id from foo"
+	countQuery := "select count(*) from foo"
+	db := &debugQuerier{}
+
+	_, _, debug, err := GetQueriedAndCountResultsWithDebug(
+		&query, &countQuery, nil, fields, r, db, ParamConfig{}, queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if debug != nil {
+		t.Fatalf("should not debug for unauthorized user\n")
+	}
+
+	authorized = true
+	query = "select foo.id from foo"
+	countQuery = "select count(*) from foo"
+	db = &debugQuerier{}
+
+	_, count, debug, err := GetQueriedAndCountResultsWithDebug(
+		&query, &countQuery, nil, fields, r, db, ParamConfig{}, queryConf,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if count != 1 {
+		t.Errorf("count should be 1; got %d\n", count)
+	}
+	if debug == nil {
+		t.Fatalf("should return debug for authorized user\n")
+	}
+	if debug.Query != query || len(debug.Args) != 3 || debug.CountQuery != countQuery {
+		t.Errorf("unexpected debug; got %+v\n", debug)
+	}
+	if len(debug.Explain) != 1 || debug.Explain[0] != "Seq Scan on foo" {
+		t.Errorf("unexpected explain; got %v\n", debug.Explain)
+	}
+}
+
+func TestGetFilteredResultsV2Silent(t *testing.T) {
+	var logs bytes.Buffer
+
+	stdout := os.Stdout
+	reader, writer, _ := os.Pipe()
+	os.Stdout = writer
+	httputil.Logger.SetOutput(&logs)
+	httputil.DeprecationWarnings = false
+
+	defer func() {
+		os.Stdout = stdout
+		httputil.Logger.SetOutput(os.Stderr)
+		httputil.DeprecationWarnings = true
+		httputil.Debug = false
+	}()
+
+	db := dbtest.NewExpectDB(t)
+	db.ExpectQuery("").WillReturnError(errors.New("connection refused"))
+	db.ExpectQuery("").WillReturnError(errors.New("connection refused"))
+
+	getResults := func(r FormRequest) error {
+		query := "select id from foo"
+		countQuery := "select count(*) from foo"
+
+		_, _, _, _, err := GetFilteredResultsV2(
+			r,
+			&query,
+			&countQuery,
+			100,
+			sqlx.DOLLAR,
+			nil,
+			map[string]string{"id": "foo.id"},
+			nil,
+			db,
+		)
+
+		return err
+	}
+
+	if err := getResults(mapFormRequest{
+		"filters": `[{"field": "bar", "operator": "eq", "value": 1}]`,
+	}); err == nil {
+		t.Fatalf("should return error for invalid filter")
+	}
+	if err := getResults(httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Fatalf("should return error")
+	}
+
+	writer.Close()
+	output, _ := ioutil.ReadAll(reader)
+
+	if len(output) != 0 {
+		t.Errorf("should not write to stdout unless httputil#Debug is set; got %s", string(output))
+	}
+	if logs.Len() != 0 {
+		t.Errorf("should not log unless httputil#Debug is set; got %s", logs.String())
+	}
+
+	httputil.Debug = true
+
+	if err := getResults(httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Fatalf("should return error")
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("should log error when httputil#Debug is set; got %s", logs.String())
+	}
+}
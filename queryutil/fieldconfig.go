@@ -0,0 +1,165 @@
+package queryutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// OpFilter is value of "ops" tag that allows field to be filtered
+	OpFilter = "filter"
+
+	// OpSort is value of "ops" tag that allows field to be sorted
+	OpSort = "sort"
+
+	// OpGroup is value of "ops" tag that allows field to be grouped
+	OpGroup = "group"
+
+	// OpSelect is value of "ops" tag that allows field to be selected
+	// by ParamConfig#Fields
+	OpSelect = "select"
+)
+
+// FieldConfigOptions is config struct used for FieldConfigFromStruct
+type FieldConfigOptions struct {
+	// Prefix is table name or alias that keys and db fields are
+	// qualified with eg. Prefix "foo" for field with db tag "status_id"
+	// and json tag "statusID" has key "foo.statusID" and db field
+	// "foo.status_id"
+	// db tags that are already qualified are used as is
+	Prefix string
+
+	// DefaultOps are operations allowed for fields without an "ops" tag
+	// Default is no operations
+	DefaultOps OperationConfig
+}
+
+// FieldConfigFromStruct returns fields map derived from tags of model,
+// which must be a struct or pointer to struct, so list endpoints can
+// whitelist fields of the model they already define
+//
+// Only fields with a "db" tag are added where key is "json" tag, or
+// field name if not set, and db field is "db" tag
+// Operations allowed on field are set with "ops" tag as comma separated
+// list of OpFilter, OpSort, OpGroup and OpSelect eg. `ops:"filter,sort"` and
+// `ops:"-"` allows none
+// Fields of embedded structs without a "db" tag are added as well
+//
+// Returns error if model is not a struct, "ops" tag is invalid or a db
+// field is not a valid identifier
+func FieldConfigFromStruct(model interface{}, opts FieldConfigOptions) (map[string]FieldConfig, error) {
+	t := reflect.TypeOf(model)
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("queryutil: model must be struct; got %v", t)
+	}
+
+	fields := make(map[string]FieldConfig)
+
+	if err := addStructFields(fields, t, opts); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func addStructFields(fields map[string]FieldConfig, t reflect.Type, opts FieldConfigOptions) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		dbTag := tagName(sf.Tag.Get("db"))
+
+		if sf.Anonymous && dbTag == "" {
+			ft := sf.Type
+
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addStructFields(fields, ft, opts); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if sf.PkgPath != "" || dbTag == "" || dbTag == "-" {
+			continue
+		}
+
+		key := tagName(sf.Tag.Get("json"))
+
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+		}
+
+		conf := FieldConfig{
+			DBField:       dbTag,
+			OperationConf: opts.DefaultOps,
+		}
+
+		if opts.Prefix != "" {
+			key = opts.Prefix + "." + key
+
+			if !strings.Contains(dbTag, ".") {
+				conf.DBField = opts.Prefix + "." + dbTag
+			}
+		}
+
+		if ops, ok := sf.Tag.Lookup("ops"); ok {
+			opConf, err := parseOps(ops)
+
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("queryutil: field '%s'", sf.Name))
+			}
+
+			conf.OperationConf = opConf
+		}
+
+		if !dbutil.ValidIdentifier(conf.DBField) {
+			return errors.Wrap(ErrInvalidIdentifier, fmt.Sprintf("db field '%s'", conf.DBField))
+		}
+
+		fields[key] = conf
+	}
+
+	return nil
+}
+
+func parseOps(ops string) (OperationConfig, error) {
+	var conf OperationConfig
+
+	if ops == "-" {
+		return conf, nil
+	}
+
+	for _, op := range strings.Split(ops, ",") {
+		switch strings.TrimSpace(op) {
+		case OpFilter:
+			conf.CanFilterBy = true
+		case OpSort:
+			conf.CanSortBy = true
+		case OpGroup:
+			conf.CanGroupBy = true
+		case OpSelect:
+			conf.CanSelect = true
+		case "":
+		default:
+			return conf, fmt.Errorf("invalid op '%s'", op)
+		}
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,70 @@
+package queryutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type fieldConfigBase struct {
+	ID int64 `json:"id" db:"id" ops:"filter,sort"`
+}
+
+type fieldConfigModel struct {
+	fieldConfigBase
+	StatusID    int64  `json:"statusID,omitempty" db:"status_id" ops:"filter,sort,group,select"`
+	Name        string `db:"name"`
+	StatusName  string `json:"statusName" db:"status.name" ops:"-"`
+	Secret      string `json:"-" db:"secret"`
+	Computed    string `json:"computed"`
+	Ignored     string `json:"ignored" db:"-"`
+	unexportedF string `db:"unexported"`
+}
+
+func TestFieldConfigFromStruct(t *testing.T) {
+	fields, err := FieldConfigFromStruct(&fieldConfigModel{}, FieldConfigOptions{
+		Prefix:     "foo",
+		DefaultOps: OperationConfig{CanFilterBy: true},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := map[string]FieldConfig{
+		"foo.id": {
+			DBField:       "foo.id",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+		},
+		"foo.statusID": {
+			DBField:       "foo.status_id",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true, CanGroupBy: true, CanSelect: true},
+		},
+		"foo.Name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true},
+		},
+		"foo.statusName": {
+			DBField: "status.name",
+		},
+	}
+
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("got fields %v; want %v", fields, expected)
+	}
+
+	if _, err = FieldConfigFromStruct(struct {
+		Name string `db:"name" ops:"filter,delete"`
+	}{}, FieldConfigOptions{}); err == nil {
+		t.Errorf("should return error for invalid op")
+	}
+	if _, err = FieldConfigFromStruct(struct {
+		Name string `db:"name; drop table foo"`
+	}{}, FieldConfigOptions{}); errors.Cause(err) != ErrInvalidIdentifier {
+		t.Errorf("should return ErrInvalidIdentifier; got %v", err)
+	}
+	if _, err = FieldConfigFromStruct([]string{}, FieldConfigOptions{}); err == nil {
+		t.Errorf("should return error for non struct")
+	}
+}
@@ -0,0 +1,127 @@
+package queryutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// FieldsPlaceholder can be embedded within select list of queries, eg.
+// "select {{fields}} from foo", to set where fields requested by
+// ParamConfig#Fields are selected so clients only query the columns
+// they need from wide tables
+//
+// Every requested field is selected as its db field aliased with its
+// column name, eg. field "foo.statusID" with db field "foo.status_id"
+// is selected as `foo.status_id as "status_id"`, so rows have the same
+// keys whichever fields are requested
+// If fields aren't requested, every field that can be selected is
+const FieldsPlaceholder = "{{fields}}"
+
+// FieldsError is returned when field requested by ParamConfig#Fields is
+// not within fields or is not allowed to be selected
+type FieldsError struct {
+	field string
+}
+
+func (f *FieldsError) Error() string {
+	return fmt.Sprintf("invalid field: '%s'", f.field)
+}
+
+// DecodeFields returns fields of comma separated paramName of r
+// eg. "fields=id,name", in order requested without duplicates
+// Returns nil if paramName is not set
+func DecodeFields(r FormRequest, paramName string) []string {
+	param := r.FormValue(paramName)
+
+	if param == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+
+	for _, v := range strings.Split(param, ",") {
+		v = strings.TrimSpace(v)
+
+		if v == "" || seen[v] {
+			continue
+		}
+
+		seen[v] = true
+		names = append(names, v)
+	}
+
+	return names
+}
+
+// GetFieldsReplacements decodes fields of paramName from r and validates
+// them against fields, then substitutes FieldsPlaceholder of query, if
+// query has it, with the select list of fields
+// If paramName is not set, every field that can be selected is, sorted
+// by key
+//
+// Returns keys of selected fields which can be set as
+// RowerMapConfig#Fields so only selected keys are emitted
+// Throws FieldsError{} error type if field is not within fields or
+// can't be selected
+func GetFieldsReplacements(
+	r FormRequest,
+	query *string,
+	paramName string,
+	queryConf QueryConfig,
+	fields map[string]FieldConfig,
+) ([]string, error) {
+	names := DecodeFields(r, paramName)
+
+	if names == nil {
+		names = make([]string, 0, len(fields))
+
+		for k, v := range fields {
+			if v.OperationConf.CanSelect {
+				names = append(names, k)
+			}
+		}
+
+		sort.Strings(names)
+	}
+
+	selects := make([]string, 0, len(names))
+	dialect := dbutil.GetDialect(queryConf.Dialect)
+
+	for _, name := range names {
+		conf, ok := fields[name]
+
+		if !ok || !conf.OperationConf.CanSelect {
+			return nil, &FieldsError{field: name}
+		}
+		if err := checkDBField(conf); err != nil {
+			return nil, err
+		}
+
+		selects = append(
+			selects,
+			conf.DBField+" as "+dialect.QuoteIdentifier(fieldAlias(name)),
+		)
+	}
+
+	if pos := strings.Index(*query, FieldsPlaceholder); pos != -1 {
+		*query = (*query)[:pos] + strings.Join(selects, ", ") + (*query)[pos+len(FieldsPlaceholder):]
+	}
+
+	return names, nil
+}
+
+// fieldAlias returns column name of field key without the table it's
+// qualified with eg. "foo.statusID" to "status_id"
+func fieldAlias(key string) string {
+	return FieldColumn(fieldName(key), nil)
+}
+
+// fieldName returns field key without the table it's qualified with
+// eg. "foo.statusID" to "statusID"
+func fieldName(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
@@ -0,0 +1,106 @@
+package queryutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestGetFieldsReplacements(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"foo.id": {
+			DBField:       "foo.id",
+			OperationConf: OperationConfig{CanSelect: true},
+		},
+		"foo.statusID": {
+			DBField:       "foo.status_id",
+			OperationConf: OperationConfig{CanSelect: true},
+		},
+		"foo.secret": {
+			DBField:       "foo.secret",
+			OperationConf: OperationConfig{CanFilterBy: true},
+		},
+	}
+	queryConf := QueryConfig{Dialect: dbutil.Postgres}
+
+	query := "select {{fields}} from foo"
+	selected, err := GetFieldsReplacements(
+		mapFormRequest{"fields": "foo.statusID, foo.id,foo.statusID"}, &query, "fields", queryConf, fields,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !reflect.DeepEqual(selected, []string{"foo.statusID", "foo.id"}) {
+		t.Errorf("got selected %v", selected)
+	}
+	if expected := `select foo.status_id as "status_id", foo.id as "id" from foo`; query != expected {
+		t.Errorf("should be:\n%s\ngot:\n%s", expected, query)
+	}
+
+	query = "select {{fields}} from foo"
+
+	if selected, err = GetFieldsReplacements(mapFormRequest{}, &query, "fields", queryConf, fields); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !reflect.DeepEqual(selected, []string{"foo.id", "foo.statusID"}) {
+		t.Errorf("should select every selectable field; got %v", selected)
+	}
+
+	for _, v := range []string{"foo.secret", "foo.bar"} {
+		query = "select {{fields}} from foo"
+		_, err = GetFieldsReplacements(mapFormRequest{"fields": v}, &query, "fields", queryConf, fields)
+
+		if _, ok := err.(*FieldsError); !ok {
+			t.Errorf("should return FieldsError for '%s'; got %v", v, err)
+		}
+	}
+}
+
+func TestBuildQueryFields(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"id": {
+			DBField:       "foo.id",
+			OperationConf: OperationConfig{CanSelect: true, CanSortBy: true},
+		},
+		"name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanSelect: true},
+		},
+	}
+
+	query, args, err := BuildQuery(
+		"select {{fields}} from foo {{sorts}} {{limit}}",
+		nil,
+		fields,
+		mapFormRequest{"fields": "name", "sorts": `[{"field": "id", "dir": "asc"}]`},
+		ParamConfig{},
+		QueryConfig{Dialect: dbutil.Postgres},
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(
+		t,
+		`select foo.name as "name" from foo order by foo.id asc limit $1 offset $2`,
+		query,
+		[]interface{}{DefaultTakeLimit, 0},
+		args,
+	)
+}
+
+func TestRowerToMapsFields(t *testing.T) {
+	rows := dbtest.NewRows("id", "status_id", "name").AddRow(int64(1), int64(2), []byte("foo"))
+	results, err := RowerToMaps(rows, RowerMapConfig{Fields: []string{"foo.id", "name"}})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if !reflect.DeepEqual(results[0], map[string]interface{}{"id": int64(1), "name": "foo"}) {
+		t.Errorf("should only emit selected fields; got %v\n", results[0])
+	}
+}
@@ -0,0 +1,213 @@
+package queryutil
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geo filter operators, which can only be used on fields with
+// FieldConfig#Geo set
+const (
+	// GeoWithinBBox filters rows within bounding box whose value is
+	// [minX, minY, maxX, maxY] eg. [minLng, minLat, maxLng, maxLat]
+	GeoWithinBBox = "withinbbox"
+
+	// GeoWithinRadius filters rows within radius of point whose value
+	// is {"x": -73.98, "y": 40.75, "radius": 5, "unit": "km"}, where
+	// "lng" and "lat" can be used instead of "x" and "y" and unit
+	// defaults to GeoConfig#Unit
+	GeoWithinRadius = "withinradius"
+)
+
+// DefaultSRID is SRID of geo fields whose GeoConfig#SRID isn't set,
+// which is WGS 84 longitude and latitude
+const DefaultSRID = 4326
+
+// GeoUnits is number of meters per unit that can be used as unit of
+// radius of GeoWithinRadius
+var GeoUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.344,
+	"ft": 0.3048,
+}
+
+// GeoConfig marks field as PostGIS geometry or geography column, so
+// it can be filtered with GeoWithinBBox and GeoWithinRadius and
+// sorted by distance with Sort#Near
+//
+// Geometry columns filtered by radius are cast to geography so
+// distance is in meters, which requires a longitude and latitude SRID
+// and an index on the cast expression to be fast
+type GeoConfig struct {
+	// SRID is SRID of column, which coordinates sent by clients are
+	// also expected to be in
+	// Default is DefaultSRID
+	SRID int
+
+	// Geography should be set if column is geography instead of geometry
+	Geography bool
+
+	// Unit is default unit of radius of GeoWithinRadius, see GeoUnits
+	// Default is "m"
+	Unit string
+}
+
+func (g *GeoConfig) srid() int {
+	if g.SRID == 0 {
+		return DefaultSRID
+	}
+
+	return g.SRID
+}
+
+// point returns expression of point at x and y, which are either
+// placeholders or literals, in SRID of g
+func (g *GeoConfig) point(x, y string) string {
+	p := fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), %d)", x, y, g.srid())
+
+	if g.Geography {
+		p += "::geography"
+	}
+
+	return p
+}
+
+// isGeoOperator returns whether operator is one of the geo operators
+func isGeoOperator(operator string) bool {
+	return operator == GeoWithinBBox || operator == GeoWithinRadius
+}
+
+// geoFilter returns clause and args of geo filter of field with conf
+func geoFilter(filter Filter, conf FieldConfig) (string, []interface{}, error) {
+	invalidValue := func() error {
+		filterErr := &FilterError{}
+		filterErr.setInvalidValueError(filter.Field, filter.Value)
+		return filterErr
+	}
+
+	geo := conf.Geo
+
+	switch filter.Operator {
+	case GeoWithinBBox:
+		list, ok := filter.Value.([]interface{})
+
+		if !ok || len(list) != 4 {
+			return "", nil, invalidValue()
+		}
+
+		args := make([]interface{}, 0, 4)
+
+		for _, v := range list {
+			f, ok := geoNumber(v)
+
+			if !ok {
+				return "", nil, invalidValue()
+			}
+
+			args = append(args, f)
+		}
+
+		envelope := fmt.Sprintf("ST_MakeEnvelope(?, ?, ?, ?, %d)", geo.srid())
+
+		if geo.Geography {
+			envelope += "::geography"
+		}
+
+		return fmt.Sprintf("ST_Intersects(%s, %s)", conf.DBField, envelope), args, nil
+	case GeoWithinRadius:
+		m, ok := filter.Value.(map[string]interface{})
+
+		if !ok {
+			return "", nil, invalidValue()
+		}
+
+		x, xOK := geoCoordinate(m, "x", "lng")
+		y, yOK := geoCoordinate(m, "y", "lat")
+		radius, radiusOK := geoNumber(m["radius"])
+
+		if !xOK || !yOK || !radiusOK || radius < 0 {
+			return "", nil, invalidValue()
+		}
+
+		unit := geo.Unit
+
+		if u, ok := m["unit"].(string); ok && u != "" {
+			unit = u
+		}
+		if unit == "" {
+			unit = "m"
+		}
+
+		meters, ok := GeoUnits[strings.ToLower(unit)]
+
+		if !ok {
+			return "", nil, invalidValue()
+		}
+
+		field := conf.DBField
+		point := geo.point("?", "?")
+
+		if !geo.Geography {
+			field += "::geography"
+			point += "::geography"
+		}
+
+		return fmt.Sprintf("ST_DWithin(%s, %s, ?)", field, point), []interface{}{x, y, radius * meters}, nil
+	}
+
+	filterErr := &FilterError{}
+	filterErr.setInvalidOperationError(filter.Field)
+	return "", nil, filterErr
+}
+
+// geoSortField returns expression of distance between field of conf
+// and point of near, which is [x, y], for sorting by nearest
+// Coordinates are formatted as literals since sorts aren't bound, which
+// is safe as they're parsed numbers
+func geoSortField(sort Sort, conf FieldConfig) (string, error) {
+	if conf.Geo == nil || len(sort.Near) != 2 || !isFinite(sort.Near[0]) || !isFinite(sort.Near[1]) {
+		sortErr := &SortError{}
+		sortErr.setInvalidSortError(sort.Field)
+		return "", sortErr
+	}
+
+	point := conf.Geo.point(
+		strconv.FormatFloat(sort.Near[0], 'f', -1, 64),
+		strconv.FormatFloat(sort.Near[1], 'f', -1, 64),
+	)
+
+	return conf.DBField + " <-> " + point, nil
+}
+
+// geoCoordinate returns number of m at key, else at alt
+func geoCoordinate(m map[string]interface{}, key, alt string) (float64, bool) {
+	if v, ok := m[key]; ok {
+		return geoNumber(v)
+	}
+
+	return geoNumber(m[alt])
+}
+
+func geoNumber(v interface{}) (float64, bool) {
+	var f float64
+
+	switch t := v.(type) {
+	case float64:
+		f = t
+	case int64:
+		f = float64(t)
+	case int:
+		f = float64(t)
+	default:
+		return 0, false
+	}
+
+	return f, isFinite(f)
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
@@ -0,0 +1,91 @@
+package queryutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeoFilters(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"location": {
+			DBField:       "store.location",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+			Geo:           &GeoConfig{},
+		},
+		"area": {
+			DBField:       "store.area",
+			OperationConf: OperationConfig{CanFilterBy: true},
+			Geo:           &GeoConfig{Geography: true, Unit: "km"},
+		},
+		"name": {
+			DBField:       "store.name",
+			OperationConf: OperationConfig{CanFilterBy: true},
+		},
+	}
+
+	q := "select * from store where"
+	replacements, err := ReplaceFilterFields(&q, []Filter{
+		{Field: "location", Operator: GeoWithinBBox, Value: []interface{}{-74.1, 40.6, -73.7, 40.9}},
+		{Field: "area", Operator: GeoWithinRadius, Value: map[string]interface{}{"lng": -73.98, "lat": 40.75, "radius": float64(2)}},
+		{Field: "location", Operator: GeoWithinRadius, Value: map[string]interface{}{"x": -73.98, "y": 40.75, "radius": float64(1), "unit": "mi"}},
+	}, fields)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := " ST_Intersects(store.location, ST_MakeEnvelope(?, ?, ?, ?, 4326)) and" +
+		" ST_DWithin(store.area, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?) and" +
+		" ST_DWithin(store.location::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)"
+
+	if !strings.HasSuffix(q, expected) {
+		t.Errorf("unexpected query; got %s", q)
+	}
+	if len(replacements) != 10 || replacements[6] != float64(2000) || replacements[9] != 1609.344 {
+		t.Errorf("unexpected replacements; got %v", replacements)
+	}
+
+	invalid := []Filter{
+		{Field: "name", Operator: GeoWithinBBox, Value: []interface{}{float64(1), float64(2), float64(3), float64(4)}},
+		{Field: "location", Operator: GeoWithinBBox, Value: []interface{}{float64(1), float64(2)}},
+		{Field: "location", Operator: GeoWithinRadius, Value: map[string]interface{}{"x": float64(1), "y": float64(2), "radius": float64(1), "unit": "parsec"}},
+		{Field: "location", Operator: GeoWithinRadius, Value: map[string]interface{}{"x": float64(1), "radius": float64(1)}},
+	}
+
+	for _, v := range invalid {
+		q = "select * from store where"
+
+		if _, err = ReplaceFilterFields(&q, []Filter{v}, fields); err == nil {
+			t.Errorf("should return error for %v", v)
+		}
+	}
+}
+
+func TestGeoSort(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"location": {
+			DBField:       "store.location",
+			OperationConf: OperationConfig{CanSortBy: true},
+			Geo:           &GeoConfig{SRID: 3857},
+		},
+		"name": {
+			DBField:       "store.name",
+			OperationConf: OperationConfig{CanSortBy: true},
+		},
+	}
+
+	q := "select * from store order by"
+
+	if err := ReplaceSortFields(&q, []Sort{{Field: "location", Dir: "asc", Near: []float64{-8235000.5, 4975000}}}, fields); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !strings.HasSuffix(q, " store.location <-> ST_SetSRID(ST_MakePoint(-8235000.5, 4975000), 3857) asc") {
+		t.Errorf("unexpected query; got %s", q)
+	}
+
+	q = "select * from store order by"
+
+	if err := ReplaceSortFields(&q, []Sort{{Field: "name", Dir: "asc", Near: []float64{1, 2}}}, fields); err == nil {
+		t.Errorf("should return error for sorting non geo field by distance")
+	}
+}
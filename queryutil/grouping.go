@@ -0,0 +1,119 @@
+package queryutil
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// GroupMode determines how groups are applied to group by clause
+type GroupMode string
+
+// Group modes of QueryConfig#GroupMode
+const (
+	// GroupModeNone groups by every group
+	GroupModeNone GroupMode = ""
+
+	// GroupModeRollup groups by "rollup(...)" of groups, which adds
+	// subtotal rows of every prefix of groups and a grand total row
+	GroupModeRollup GroupMode = "rollup"
+
+	// GroupModeCube groups by "cube(...)" of groups, which adds
+	// subtotal rows of every combination of groups and a grand total row
+	GroupModeCube GroupMode = "cube"
+)
+
+// SubtotalColumn is column LabelSubtotals sets on every row
+const SubtotalColumn = "subtotal"
+
+var (
+	// ErrGroupModeNotSupported is returned when QueryConfig#GroupMode
+	// is set for a dialect without dbutil#Dialect#GroupingSets
+	ErrGroupModeNotSupported = errors.New("queryutil: group mode not supported by dialect")
+
+	// ErrInvalidGroupMode is returned when QueryConfig#GroupMode isn't
+	// one of the group modes
+	ErrInvalidGroupMode = errors.New("queryutil: invalid group mode")
+)
+
+// getGroupingSetReplacements is GetGroupReplacements for group modes
+// other than GroupModeNone, where prepended groups and groups of r
+// are applied within a single "rollup(...)" or "cube(...)"
+func getGroupingSetReplacements(
+	r FormRequest,
+	query *string,
+	paramName string,
+	queryConf QueryConfig,
+	fields map[string]FieldConfig,
+) ([]Group, error) {
+	if queryConf.GroupMode != GroupModeRollup && queryConf.GroupMode != GroupModeCube {
+		return nil, ErrInvalidGroupMode
+	}
+	if !dbutil.GetDialect(queryConf.Dialect).GroupingSets {
+		return nil, ErrGroupModeNotSupported
+	}
+
+	groups := make([]Group, 0, len(queryConf.PrependGroupFields))
+	groups = append(groups, queryConf.PrependGroupFields...)
+
+	if !queryConf.ExcludeGroups {
+		groupSlice, err := DecodeGroups(r, paramName)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		groups = append(groups, groupSlice...)
+	}
+
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	var set string
+
+	if err := ReplaceGroupFields(&set, groups, fields); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	addClause(query, groupClause, " "+string(queryConf.GroupMode)+"("+strings.TrimSpace(set)+")")
+	return groups, nil
+}
+
+// LabelSubtotals labels rows of query grouped with GroupModeRollup or
+// GroupModeCube, where columns are result columns of groups in order
+//
+// SubtotalColumn of every row is set to nil for detail rows, else to
+// columns the row is a subtotal over, which are the columns that are
+// null, so every column for the grand total row
+// If label isn't empty, null columns of subtotal rows are set to label
+// eg. "All" for display
+//
+// Rows can't be told apart from subtotals if group columns can be null
+// so such columns should be coalesced within query
+func LabelSubtotals(rows []map[string]interface{}, columns []string, label string) {
+	for _, row := range rows {
+		var rolledUp []string
+
+		for _, c := range columns {
+			if row[c] == nil {
+				rolledUp = append(rolledUp, c)
+			}
+		}
+
+		if len(rolledUp) == 0 {
+			row[SubtotalColumn] = nil
+			continue
+		}
+
+		row[SubtotalColumn] = rolledUp
+
+		if label != "" {
+			for _, c := range rolledUp {
+				row[c] = label
+			}
+		}
+	}
+}
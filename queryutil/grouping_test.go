@@ -0,0 +1,76 @@
+package queryutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestGroupMode(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"region": {
+			DBField:       "sale.region",
+			OperationConf: OperationConfig{CanGroupBy: true},
+		},
+		"product": {
+			DBField:       "sale.product",
+			OperationConf: OperationConfig{CanGroupBy: true},
+		},
+	}
+	req := mapFormRequest{"groups": `[{"field":"product"}]`}
+
+	q := "select sale.region, sale.product, sum(sale.total) from sale"
+	groups, err := GetGroupReplacements(req, &q, "groups", QueryConfig{
+		GroupMode:          GroupModeRollup,
+		PrependGroupFields: []Group{{Field: "region"}},
+	}, fields)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !strings.HasSuffix(q, " group by rollup(sale.region, sale.product)") || len(groups) != 2 {
+		t.Errorf("should group by rollup; got %s", q)
+	}
+
+	q = "select sale.product, sum(sale.total) from sale"
+
+	if _, err = GetGroupReplacements(req, &q, "groups", QueryConfig{GroupMode: GroupModeCube}, fields); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !strings.HasSuffix(q, " cube(sale.product)") {
+		t.Errorf("should group by cube; got %s", q)
+	}
+
+	q = "select sale.product, sum(sale.total) from sale"
+
+	if _, err = GetGroupReplacements(req, &q, "groups", QueryConfig{GroupMode: GroupModeRollup, Dialect: dbutil.Sqlite}, fields); errors.Cause(err) != ErrGroupModeNotSupported {
+		t.Errorf("should not support sqlite; got %v", err)
+	}
+	if _, err = GetGroupReplacements(req, &q, "groups", QueryConfig{GroupMode: "sets"}, fields); errors.Cause(err) != ErrInvalidGroupMode {
+		t.Errorf("should return invalid group mode; got %v", err)
+	}
+}
+
+func TestLabelSubtotals(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"region": "east", "product": "foo", "total": 10},
+		{"region": "east", "product": nil, "total": 10},
+		{"region": nil, "product": nil, "total": 10},
+	}
+
+	LabelSubtotals(rows, []string{"region", "product"}, "All")
+
+	if rows[0][SubtotalColumn] != nil {
+		t.Errorf("detail row should not be subtotal; got %v", rows[0][SubtotalColumn])
+	}
+	if !reflect.DeepEqual(rows[1][SubtotalColumn], []string{"product"}) || rows[1]["product"] != "All" {
+		t.Errorf("should label subtotal row; got %v", rows[1])
+	}
+	if !reflect.DeepEqual(rows[2][SubtotalColumn], []string{"region", "product"}) || rows[2]["region"] != "All" {
+		t.Errorf("should label grand total row; got %v", rows[2])
+	}
+}
@@ -0,0 +1,82 @@
+package queryutil
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+var (
+	// ErrInvalidIdentifier is returned when FieldConfig#DBField is not a
+	// valid identifier and FieldConfig#Expression is not set
+	ErrInvalidIdentifier = errors.New("queryutil: invalid identifier")
+)
+
+// filterOperators are operators that can be applied by filters
+var filterOperators = map[string]bool{
+	"eq":             true,
+	"neq":            true,
+	"startswith":     true,
+	"endswith":       true,
+	"contains":       true,
+	"doesnotcontain": true,
+	"isnull":         true,
+	"isnotnull":      true,
+	"isempty":        true,
+	"isnotempty":     true,
+	"lt":             true,
+	"lte":            true,
+	"gt":             true,
+	"gte":            true,
+}
+
+// valuelessOperators are filter operators whose clause doesn't bind
+// value of filter
+var valuelessOperators = map[string]bool{
+	"isnull":     true,
+	"isnotnull":  true,
+	"isempty":    true,
+	"isnotempty": true,
+}
+
+// bindsValue returns whether clause of built in operator of f has a
+// placeholder for its value, which lists always do as they're applied
+// with "in"
+func bindsValue(f Filter) bool {
+	_, list := f.Value.([]interface{})
+	return list || !valuelessOperators[f.Operator]
+}
+
+// checkDBField returns ErrInvalidIdentifier if DBField of conf is not
+// a valid identifier unless it's marked as an expression or window
+func checkDBField(conf FieldConfig) error {
+	if conf.Expression || conf.Window || dbutil.ValidIdentifier(conf.DBField) {
+		return nil
+	}
+
+	return errors.Wrap(ErrInvalidIdentifier, fmt.Sprintf("db field '%s'", conf.DBField))
+}
+
+// safeIdentifier returns name as is if it's a valid identifier, else
+// quoted with dialect so it's never executed as sql
+func safeIdentifier(name string, dialect dbutil.Dialect) string {
+	if dbutil.ValidIdentifier(name) {
+		return name
+	}
+
+	return dialect.QuoteIdentifier(name)
+}
+
+// bindVarDialect returns dialect identifiers of queries with bindVar are
+// quoted with by functions that take a bind var instead of a dialect,
+// where sqlx.QUESTION is mysql as sqlite accepts its quoting as well
+func bindVarDialect(bindVar int) dbutil.Dialect {
+	if bindVar == sqlx.QUESTION {
+		return dbutil.GetDialect(dbutil.Mysql)
+	}
+
+	return dbutil.GetDialect(dbutil.Postgres)
+}
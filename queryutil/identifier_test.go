@@ -0,0 +1,163 @@
+package queryutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+type mapFormRequest map[string]string
+
+func (m mapFormRequest) FormValue(key string) string {
+	return m[key]
+}
+
+func TestIdentifierSafety(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"name": {
+			DBField:       "foo.name; drop table foo",
+			OperationConf: OperationConfig{CanSortBy: true},
+		},
+		"lowerName": {
+			DBField:       "lower(foo.name)",
+			OperationConf: OperationConfig{CanSortBy: true},
+			Expression:    true,
+		},
+	}
+
+	q := "select * from foo order by"
+
+	if err := ReplaceSortFields(&q, []Sort{{Field: "name", Dir: "asc"}}, fields); errors.Cause(err) != ErrInvalidIdentifier {
+		t.Errorf("should return ErrInvalidIdentifier; got %v\n", err)
+	}
+	if err := ReplaceSortFields(&q, []Sort{{Field: "lowerName", Dir: "asc"}}, fields); err != nil {
+		t.Errorf("should allow expression; got %s\n", err)
+	}
+
+	q = "select * from foo"
+	ApplyOrdering(&q, &Sort{Field: `name" desc; drop table foo; --`, Dir: "asc; drop table foo"})
+
+	if !strings.HasSuffix(q, `order by "name"" desc; drop table foo; --" desc`) {
+		t.Errorf("should quote invalid identifier and dir; got %s\n", q)
+	}
+
+	q = "select * from foo"
+	_, err := ApplyAll(
+		mapFormRequest{"sort": `{"field": "password", "dir": "asc"}`},
+		&q,
+		10,
+		0,
+		nil,
+		[]string{"name"},
+	)
+
+	if _, ok := err.(*SortError); !ok {
+		t.Errorf("should return SortError for sort field not whitelisted; got %v\n", err)
+	}
+
+	if _, err = FilterCheck(Filter{Field: "name", Operator: "drop", Value: "foo"}); err == nil {
+		t.Errorf("should return error for invalid operator\n")
+	}
+}
+
+func TestIdentifierDialect(t *testing.T) {
+	mysql := dbutil.GetDialect(dbutil.Mysql)
+	q := "select * from foo"
+	ApplyOrderingWithDialect(&q, &Sort{Field: "user name", Dir: "asc"}, mysql)
+
+	if !strings.HasSuffix(q, "order by `user name` asc") {
+		t.Errorf("should quote identifier for mysql; got %s\n", q)
+	}
+
+	q = "select * from foo order by"
+	ApplySortWithDialect(&q, Sort{Field: "foo.user name", Dir: "desc"}, false, mysql)
+
+	if !strings.HasSuffix(q, "`foo`.`user name` desc") {
+		t.Errorf("should quote sort field for mysql; got %s\n", q)
+	}
+
+	q = "select * from foo group by"
+	ApplyGroupWithDialect(&q, Group{Field: "foo.user name"}, false, mysql)
+
+	if !strings.HasSuffix(q, "`foo`.`user name`") {
+		t.Errorf("should quote group field for mysql; got %s\n", q)
+	}
+
+	q = "select * from foo"
+	_, err := whereFilter(
+		mapFormRequest{"filters": `[{"field": "name", "operator": "eq", "value": "bar"}]`},
+		&q,
+		sqlx.QUESTION,
+		nil,
+		nil,
+		map[string]string{"name": "foo.user name"},
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if !strings.Contains(q, "`foo`.`user name` = ?") {
+		t.Errorf("should quote filter field for bind var of mysql; got %s\n", q)
+	}
+}
+
+func TestWindowField(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"statusID": {
+			DBField:       "foo.status_id",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true, CanGroupBy: true},
+		},
+		"rank": {
+			DBField:       "row_number() over (order by count(*) desc)",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true, CanGroupBy: true},
+			Window:        true,
+		},
+	}
+
+	q := "select foo.status_id, row_number() over (order by count(*) desc) as rank from foo"
+
+	if _, err := GetPreQueryResults(
+		&q,
+		nil,
+		fields,
+		mapFormRequest{
+			"groups": `[{"field": "statusID"}]`,
+			"sorts":  `[{"field": "rank", "dir": "asc"}]`,
+			"take":   "10",
+			"skip":   "0",
+		},
+		&MockQuerier{},
+		ParamConfig{},
+		QueryConfig{},
+	); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	if strings.Contains(q, "group by foo.status_id, row_number()") {
+		t.Errorf("should not add window field to group by; got %s\n", q)
+	}
+	if !strings.Contains(q, "row_number() over (order by count(*) desc) asc") {
+		t.Errorf("should sort by window field; got %s\n", q)
+	}
+
+	for _, param := range []string{"filters", "groups"} {
+		value := `[{"field": "rank"}]`
+
+		if param == "filters" {
+			value = `[{"field": "rank", "operator": "eq", "value": 1}]`
+		}
+
+		q = "select foo.status_id from foo"
+
+		if _, err := GetPreQueryResults(
+			&q, nil, fields, mapFormRequest{param: value}, &MockQuerier{}, ParamConfig{}, QueryConfig{},
+		); err == nil {
+			t.Errorf("should not allow %s of window field\n", param)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package queryutil
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+const (
+	// DefaultMaxInListSize is default QueryConfig#MaxInListSize
+	DefaultMaxInListSize = 500
+)
+
+// RewriteLargeInLists rewrites "in (?)" clauses of query whose slice
+// argument has more than maxSize values, which sqlx.In would otherwise
+// expand into a placeholder per value and hit the parameter limit of
+// the database
+//
+// On Postgres, "field in (?)" becomes "field = any(?)" and
+// "field not in (?)" becomes "field != all(?)" with the slice passed as
+// a single pq.Array argument
+// On other databases, slices of only numbers are written into query as
+// literals, in chunks of maxSize values joined with "or", so they don't
+// use placeholders at all; other slices are left for sqlx.In to expand
+//
+// Placeholders are counted the same as sqlx.In so query should be
+// called before it with "?" placeholders
+func RewriteLargeInLists(query string, args []interface{}, dialect string, maxSize int) (string, []interface{}) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxInListSize
+	}
+
+	var b strings.Builder
+	newArgs := make([]interface{}, 0, len(args))
+	argIdx := 0
+	last := 0
+
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			continue
+		}
+		if argIdx >= len(args) {
+			break
+		}
+
+		arg := args[argIdx]
+		argIdx++
+
+		values, ok := largeInListValues(arg, maxSize)
+
+		if !ok {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+
+		// Clause has to be exactly "in (?)" to be rewritten
+		start := inListStart(query[:i])
+		end := inListEnd(query, i+1)
+
+		if start < 0 || end < 0 {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+
+		not := false
+		prefix := strings.TrimRight(query[last:start], " \t\n")
+
+		if lower := strings.ToLower(prefix); strings.HasSuffix(lower, " not") {
+			not = true
+			prefix = prefix[:len(prefix)-len(" not")]
+		}
+
+		if dialect == "" || dialect == dbutil.Postgres {
+			b.WriteString(prefix)
+
+			if not {
+				b.WriteString(" != all(?)")
+			} else {
+				b.WriteString(" = any(?)")
+			}
+
+			newArgs = append(newArgs, pq.Array(arg))
+			last = end
+			i = end - 1
+			continue
+		}
+
+		literals, numeric := numericLiterals(values)
+		fieldStart := fieldExprStart(prefix)
+
+		if !numeric || fieldStart < 0 {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+
+		field := prefix[fieldStart:]
+		op := " in ("
+		join := " or "
+
+		if not {
+			op = " not in ("
+			join = " and "
+		}
+
+		b.WriteString(prefix[:fieldStart])
+		b.WriteString("(")
+
+		for c := 0; c < len(literals); c += maxSize {
+			chunkEnd := c + maxSize
+
+			if chunkEnd > len(literals) {
+				chunkEnd = len(literals)
+			}
+			if c > 0 {
+				b.WriteString(join)
+			}
+
+			b.WriteString(field + op + strings.Join(literals[c:chunkEnd], ",") + ")")
+		}
+
+		b.WriteString(")")
+		last = end
+		i = end - 1
+	}
+
+	b.WriteString(query[last:])
+	newArgs = append(newArgs, args[argIdx:]...)
+
+	return b.String(), newArgs
+}
+
+// largeInListValues returns values of arg if it's a slice, other than
+// []byte, with more than maxSize values
+func largeInListValues(arg interface{}, maxSize int) ([]interface{}, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	if _, ok := arg.([]byte); ok {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(arg)
+
+	if v.Kind() != reflect.Slice || v.Len() <= maxSize {
+		return nil, false
+	}
+
+	values := make([]interface{}, v.Len())
+
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+
+	return values, true
+}
+
+// inListStart returns index of "in" of "in (" that before ends with or
+// -1 if before doesn't end with it
+func inListStart(before string) int {
+	trimmed := strings.TrimRight(before, " \t\n")
+
+	if !strings.HasSuffix(trimmed, "(") {
+		return -1
+	}
+
+	trimmed = strings.TrimRight(trimmed[:len(trimmed)-1], " \t\n")
+
+	if len(trimmed) < 3 || !strings.EqualFold(trimmed[len(trimmed)-2:], "in") {
+		return -1
+	}
+	if c := trimmed[len(trimmed)-3]; c != ' ' && c != '\t' && c != '\n' {
+		return -1
+	}
+
+	return len(trimmed) - 2
+}
+
+// inListEnd returns index after ")" that query from start begins with
+// or -1 if it doesn't begin with it
+func inListEnd(query string, start int) int {
+	for i := start; i < len(query); i++ {
+		switch query[i] {
+		case ' ', '\t', '\n':
+			continue
+		case ')':
+			return i + 1
+		default:
+			return -1
+		}
+	}
+
+	return -1
+}
+
+// fieldExprStart returns index of start of the column, quoted or not,
+// that prefix ends with or -1 if it doesn't end with one
+func fieldExprStart(prefix string) int {
+	i := len(prefix)
+
+	for i > 0 {
+		c := prefix[i-1]
+
+		switch {
+		case c == '"' || c == '`':
+			open := strings.LastIndexByte(prefix[:i-1], c)
+
+			if open < 0 {
+				return -1
+			}
+
+			i = open
+		case c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			i--
+		default:
+			if i == len(prefix) {
+				return -1
+			}
+
+			return i
+		}
+	}
+
+	if len(prefix) == 0 {
+		return -1
+	}
+
+	return 0
+}
+
+// numericLiterals returns values formatted as sql literals if every
+// value is a number
+func numericLiterals(values []interface{}) ([]string, bool) {
+	literals := make([]string, len(values))
+
+	for i, v := range values {
+		switch n := v.(type) {
+		case int:
+			literals[i] = strconv.Itoa(n)
+		case int32:
+			literals[i] = strconv.FormatInt(int64(n), 10)
+		case int64:
+			literals[i] = strconv.FormatInt(n, 10)
+		case float64:
+			literals[i] = strconv.FormatFloat(n, 'f', -1, 64)
+		default:
+			return nil, false
+		}
+	}
+
+	return literals, true
+}
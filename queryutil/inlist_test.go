@@ -0,0 +1,50 @@
+package queryutil
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+func TestRewriteLargeInLists(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	query := "select * from foo where foo.name = ? and foo.id in (?) and bar not in (?)"
+	args := []interface{}{"foo", ids, []string{"a", "b", "c"}}
+
+	// Lists within max size are left for sqlx.In
+	q, a := RewriteLargeInLists(query, args, dbutil.Postgres, 3)
+
+	if q != query || len(a) != 3 {
+		t.Errorf("small lists should not be rewritten; got %s", q)
+	}
+
+	q, a = RewriteLargeInLists(query, args, dbutil.Postgres, 2)
+
+	if q != "select * from foo where foo.name = ? and foo.id = any(?) and bar != all(?)" {
+		t.Errorf("should rewrite to any and all; got %s", q)
+	}
+	if len(a) != 3 || a[0] != "foo" {
+		t.Fatalf("should keep arg per placeholder; got %v", a)
+	}
+	if _, ok := a[1].(*pq.Int64Array); !ok {
+		t.Errorf("list should be passed as pq array; got %T", a[1])
+	}
+
+	// Numbers are inlined in chunks elsewhere and strings are left as is
+	q, a = RewriteLargeInLists(query, args, dbutil.Mysql, 2)
+
+	if q != "select * from foo where foo.name = ? and (foo.id in (1,2) or foo.id in (3)) and bar not in (?)" {
+		t.Errorf("should inline numbers in chunks; got %s", q)
+	}
+	if len(a) != 2 || a[0] != "foo" {
+		t.Errorf("inlined list should not have arg; got %v", a)
+	}
+
+	q, _ = RewriteLargeInLists("select * from foo where `id` not in (?)", []interface{}{ids}, dbutil.Mysql, 2)
+
+	if q != "select * from foo where (`id` not in (1,2) and `id` not in (3))" {
+		t.Errorf("should inline quoted column; got %s", q)
+	}
+}
@@ -0,0 +1,155 @@
+package queryutil
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONValue is value of json or jsonb column that preserves objects,
+// arrays and scalars as is, unlike GeneralJSON
+//
+// Data is decoded json where objects are map[string]interface{}, arrays
+// are []interface{} and numbers are json.Number so they don't lose
+// precision
+// Data is nil if column is null
+type JSONValue struct {
+	Data interface{}
+}
+
+// Value returns json of Data as string, which is accepted for both json
+// and jsonb columns, or nil if Data is nil
+func (j JSONValue) Value() (driver.Value, error) {
+	if j.Data == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(j.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan decodes json returned from database as []byte or string
+func (j *JSONValue) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		j.Data = nil
+		return nil
+	case []byte:
+		return j.decode(v)
+	case string:
+		return j.decode([]byte(v))
+	}
+
+	return fmt.Errorf("queryutil: can't scan %T into JSONValue", src)
+}
+
+// MarshalJSON returns json of Data
+func (j JSONValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Data)
+}
+
+// UnmarshalJSON decodes b into Data
+func (j *JSONValue) UnmarshalJSON(b []byte) error {
+	return j.decode(b)
+}
+
+func (j *JSONValue) decode(b []byte) error {
+	var data interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(&data); err != nil {
+		return fmt.Errorf("queryutil: invalid json: %s", err.Error())
+	}
+
+	j.Data = data
+	return nil
+}
+
+// Get returns value at path, where path is dot separated list of object
+// keys and array indexes eg. "items.0.name"
+// Empty path returns Data
+// Returns false if path doesn't exist
+func (j JSONValue) Get(path string) (interface{}, bool) {
+	value := j.Data
+
+	if path == "" {
+		return value, true
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+
+			if value, ok = v[part]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+
+			value = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// GetString returns string at path, see JSONValue#Get
+// Returns false if path doesn't exist or isn't a string
+func (j JSONValue) GetString(path string) (string, bool) {
+	value, _ := j.Get(path)
+	s, ok := value.(string)
+	return s, ok
+}
+
+// GetInt returns integer at path, see JSONValue#Get
+// Returns false if path doesn't exist or isn't an integer
+func (j JSONValue) GetInt(path string) (int64, bool) {
+	value, _ := j.Get(path)
+	num, ok := value.(json.Number)
+
+	if !ok {
+		return 0, false
+	}
+
+	i, err := num.Int64()
+	return i, err == nil
+}
+
+// GetFloat returns number at path, see JSONValue#Get
+// Returns false if path doesn't exist or isn't a number
+func (j JSONValue) GetFloat(path string) (float64, bool) {
+	value, _ := j.Get(path)
+	num, ok := value.(json.Number)
+
+	if !ok {
+		return 0, false
+	}
+
+	f, err := num.Float64()
+	return f, err == nil
+}
+
+// GetBool returns boolean at path, see JSONValue#Get
+// Returns false if path doesn't exist or isn't a boolean
+func (j JSONValue) GetBool(path string) (bool, bool) {
+	value, _ := j.Get(path)
+	b, ok := value.(bool)
+	return b, ok
+}
@@ -0,0 +1,62 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONValue(t *testing.T) {
+	var j JSONValue
+
+	if err := j.Scan([]byte(`{"name": "foo", "id": 9007199254740993, "items": [{"price": 1.5, "active": true}]}`)); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if s, ok := j.GetString("name"); !ok || s != "foo" {
+		t.Errorf("got name %s; want foo", s)
+	}
+	if i, ok := j.GetInt("id"); !ok || i != 9007199254740993 {
+		t.Errorf("should keep precision of id; got %d", i)
+	}
+	if f, ok := j.GetFloat("items.0.price"); !ok || f != 1.5 {
+		t.Errorf("got price %f; want 1.5", f)
+	}
+	if b, ok := j.GetBool("items.0.active"); !ok || !b {
+		t.Errorf("active should be true")
+	}
+	if _, ok := j.Get("items.1.price"); ok {
+		t.Errorf("should not find index out of range")
+	}
+	if _, ok := j.GetInt("name"); ok {
+		t.Errorf("should not return string as int")
+	}
+
+	// Arrays and scalars are preserved
+	for _, v := range []string{`[1,"a",null]`, `"foo"`, `10`, `null`} {
+		if err := j.Scan(v); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		b, _ := json.Marshal(j)
+
+		if string(b) != v {
+			t.Errorf("got %s; want %s", b, v)
+		}
+	}
+
+	if err := j.Scan(nil); err != nil || j.Data != nil {
+		t.Errorf("null should scan to nil data")
+	}
+	if v, _ := j.Value(); v != nil {
+		t.Errorf("nil data should be null; got %v", v)
+	}
+
+	j.Data = []interface{}{"a"}
+
+	if v, _ := j.Value(); v != `["a"]` {
+		t.Errorf("got value %v; want [\"a\"]", v)
+	}
+	if err := j.Scan([]byte("{")); err == nil {
+		t.Errorf("should return error for invalid json")
+	}
+}
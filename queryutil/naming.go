@@ -0,0 +1,165 @@
+package queryutil
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/knq/snaker"
+)
+
+// NamingStrategy converts column names returned from database into
+// json keys of rows and json fields of filters and sorts back into
+// column names
+type NamingStrategy interface {
+	// ToJSON converts column eg. "user_id" to json key eg. "userId"
+	ToJSON(column string) string
+
+	// ToColumn converts json field eg. "userId" to column eg. "user_id"
+	ToColumn(field string) string
+}
+
+var (
+	// CamelCaseNaming converts "user_id" to "userId" and "id" to "id"
+	// using snaker, so common initialisms are upper cased eg.
+	// "user_url" to "userURL"
+	CamelCaseNaming NamingStrategy = snakerNaming{}
+
+	// SnakeCaseNaming converts "userID" to "user_id"
+	SnakeCaseNaming NamingStrategy = snakeCaseNaming{}
+
+	// AsIsNaming leaves column names as returned from database
+	AsIsNaming NamingStrategy = asIsNaming{}
+
+	// LowerCamelNaming converts "user_id" to "userId" and "user_url" to
+	// "userUrl" without any initialisms
+	LowerCamelNaming NamingStrategy = InitialismNaming()
+
+	// DefaultNaming is strategy used when none is set eg. by
+	// RowerMapConfig#Naming, and by functions that aren't configured
+	// per call like SetRowerResults, ApplyOrdering and the filter
+	// and sort fields of GetFilterReplacements and GetSortReplacements
+	// Should only be set on startup
+	// Default is CamelCaseNaming
+	DefaultNaming = CamelCaseNaming
+)
+
+// ColumnName converts column name to json key based on naming
+// If naming is nil, DefaultNaming is used
+func ColumnName(column string, naming NamingStrategy) string {
+	if naming == nil {
+		naming = DefaultNaming
+	}
+
+	return naming.ToJSON(column)
+}
+
+// FieldColumn converts json field back to column name based on naming
+// If naming is nil, DefaultNaming is used
+func FieldColumn(field string, naming NamingStrategy) string {
+	if naming == nil {
+		naming = DefaultNaming
+	}
+
+	return naming.ToColumn(field)
+}
+
+type snakerNaming struct{}
+
+func (snakerNaming) ToJSON(column string) string {
+	if snaker.IsInitialism(column) {
+		return strings.ToLower(column)
+	}
+
+	camelCaseJSON := snaker.SnakeToCamelJSON(column)
+
+	if camelCaseJSON == "" {
+		return column
+	}
+
+	return strings.ToLower(camelCaseJSON[:1]) + camelCaseJSON[1:]
+}
+
+func (snakerNaming) ToColumn(field string) string {
+	return snaker.CamelToSnake(field)
+}
+
+type snakeCaseNaming struct{}
+
+func (snakeCaseNaming) ToJSON(column string) string {
+	return snaker.CamelToSnake(column)
+}
+
+func (snakeCaseNaming) ToColumn(field string) string {
+	return field
+}
+
+type asIsNaming struct{}
+
+func (asIsNaming) ToJSON(column string) string {
+	return column
+}
+
+func (asIsNaming) ToColumn(field string) string {
+	return field
+}
+
+// InitialismNaming returns strict lower camel case NamingStrategy that
+// only upper cases the given initialisms eg. with "id", "user_id" is
+// converted to "userID" and "api_key" to "apiKey"
+// The first word is always lower case so "id" stays "id"
+func InitialismNaming(initialisms ...string) NamingStrategy {
+	n := initialismNaming{initialisms: make(map[string]bool, len(initialisms))}
+
+	for _, v := range initialisms {
+		n.initialisms[strings.ToLower(v)] = true
+	}
+
+	return n
+}
+
+type initialismNaming struct {
+	initialisms map[string]bool
+}
+
+func (n initialismNaming) ToJSON(column string) string {
+	words := strings.Split(strings.ToLower(column), "_")
+	name := ""
+
+	for i, w := range words {
+		if w == "" {
+			continue
+		}
+
+		if i == 0 || name == "" {
+			name += w
+		} else if n.initialisms[w] {
+			name += strings.ToUpper(w)
+		} else {
+			name += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+
+	return name
+}
+
+func (n initialismNaming) ToColumn(field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			// Start of word eg. "Id" of "userId", or end of initialism
+			// eg. "Key" of "APIKey"
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
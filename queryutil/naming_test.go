@@ -0,0 +1,45 @@
+package queryutil
+
+import (
+	"testing"
+)
+
+func TestNamingStrategy(t *testing.T) {
+	tests := []struct {
+		naming NamingStrategy
+		column string
+		json   string
+	}{
+		{nil, "user_id", "userID"},
+		{CamelCaseNaming, "id", "id"},
+		{AsIsNaming, "user_id", "user_id"},
+		{LowerCamelNaming, "user_id", "userId"},
+		{LowerCamelNaming, "api_url", "apiUrl"},
+		{InitialismNaming("url"), "user_id", "userId"},
+		{InitialismNaming("url"), "api_url", "apiURL"},
+		{InitialismNaming("id"), "id", "id"},
+	}
+
+	for _, test := range tests {
+		if name := ColumnName(test.column, test.naming); name != test.json {
+			t.Errorf("should convert %s to %s; got %s\n", test.column, test.json, name)
+		}
+	}
+
+	fields := map[string]string{
+		"userId":   "user_id",
+		"apiURL":   "api_url",
+		"APIKey":   "api_key",
+		"address2": "address2",
+	}
+
+	for field, column := range fields {
+		if c := FieldColumn(field, LowerCamelNaming); c != column {
+			t.Errorf("should convert %s to %s; got %s\n", field, column, c)
+		}
+	}
+
+	if c := FieldColumn("userID", nil); c != "user_id" {
+		t.Errorf("should convert with DefaultNaming; got %s\n", c)
+	}
+}
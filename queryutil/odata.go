@@ -0,0 +1,332 @@
+package queryutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// odataOperators are comparison operators of OData mapped to filter
+// operators
+var odataOperators = map[string]string{
+	"eq": "eq",
+	"ne": "neq",
+	"gt": "gt",
+	"ge": "gte",
+	"lt": "lt",
+	"le": "lte",
+}
+
+// odataFunctions are string functions of OData mapped to filter
+// operators
+var odataFunctions = map[string]string{
+	"contains":   "contains",
+	"startswith": "startswith",
+	"endswith":   "endswith",
+}
+
+type odataTokenKind int
+
+const (
+	odataIdent odataTokenKind = iota
+	odataString
+	odataNumber
+	odataPunct
+)
+
+type odataToken struct {
+	kind  odataTokenKind
+	value string
+}
+
+// ParseODataFilter returns filters of OData "$filter" expression, which
+// supports the subset of OData that maps onto filters:
+//
+// Comparisons "field eq value" with eq, ne, gt, ge, lt and le, where
+// "field eq null" and "field ne null" filter nulls
+// Lists "field in ('a', 'b')"
+// Functions "contains(field, 'a')", "startswith" and "endswith", where
+// "not contains(field, 'a')" filters values that don't contain 'a'
+//
+// Values are strings within single quotes, where quotes are escaped by
+// doubling them, numbers, true and false
+// Conditions can only be joined with "and" as every filter must match
+func ParseODataFilter(filter string) ([]Filter, error) {
+	tokens, err := odataTokens(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &odataParser{tokens: tokens}
+	filters := make([]Filter, 0)
+
+	for len(p.tokens) > 0 {
+		if len(filters) > 0 {
+			switch t := p.next(); {
+			case t.kind == odataIdent && t.value == "and":
+			case t.kind == odataIdent && t.value == "or":
+				return nil, fmt.Errorf("only 'and' is supported")
+			default:
+				return nil, fmt.Errorf("expected 'and'; got '%s'", t.value)
+			}
+		}
+
+		f, err := p.condition()
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, f)
+	}
+
+	return filters, nil
+}
+
+type odataParser struct {
+	tokens []odataToken
+}
+
+func (p *odataParser) next() odataToken {
+	if len(p.tokens) == 0 {
+		return odataToken{kind: odataPunct, value: "end of filter"}
+	}
+
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+func (p *odataParser) expect(punct string) error {
+	if t := p.next(); t.kind != odataPunct || t.value != punct {
+		return fmt.Errorf("expected '%s'; got '%s'", punct, t.value)
+	}
+
+	return nil
+}
+
+func (p *odataParser) ident() (string, error) {
+	t := p.next()
+
+	if t.kind != odataIdent {
+		return "", fmt.Errorf("expected field; got '%s'", t.value)
+	}
+
+	return t.value, nil
+}
+
+func (p *odataParser) value() (interface{}, error) {
+	t := p.next()
+
+	switch t.kind {
+	case odataString:
+		return t.value, nil
+	case odataNumber:
+		return strconv.ParseFloat(t.value, 64)
+	case odataIdent:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("expected value; got '%s'", t.value)
+}
+
+func (p *odataParser) condition() (Filter, error) {
+	name, err := p.ident()
+
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if name == "not" {
+		if name, err = p.ident(); err != nil {
+			return Filter{}, err
+		}
+
+		f, err := p.function(name)
+
+		if err != nil {
+			return Filter{}, err
+		}
+		if f.Operator != "contains" {
+			return Filter{}, fmt.Errorf("'not' is only supported with 'contains'")
+		}
+
+		f.Operator = "doesnotcontain"
+		return f, nil
+	}
+	if _, ok := odataFunctions[name]; ok {
+		return p.function(name)
+	}
+
+	op, err := p.ident()
+
+	if err != nil {
+		return Filter{}, fmt.Errorf("expected operator after '%s'", name)
+	}
+
+	if op == "in" {
+		return p.list(name)
+	}
+
+	operator, ok := odataOperators[op]
+
+	if !ok {
+		return Filter{}, fmt.Errorf("unsupported operator '%s'", op)
+	}
+
+	val, err := p.value()
+
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if val == nil {
+		switch operator {
+		case "eq":
+			return Filter{Field: name, Operator: "isnull", Value: ""}, nil
+		case "neq":
+			return Filter{Field: name, Operator: "isnotnull", Value: ""}, nil
+		default:
+			return Filter{}, fmt.Errorf("null can only be compared with eq and ne")
+		}
+	}
+
+	return Filter{Field: name, Operator: operator, Value: val}, nil
+}
+
+func (p *odataParser) function(name string) (Filter, error) {
+	operator, ok := odataFunctions[name]
+
+	if !ok {
+		return Filter{}, fmt.Errorf("unsupported function '%s'", name)
+	}
+	if err := p.expect("("); err != nil {
+		return Filter{}, err
+	}
+
+	field, err := p.ident()
+
+	if err != nil {
+		return Filter{}, err
+	}
+	if err = p.expect(","); err != nil {
+		return Filter{}, err
+	}
+
+	t := p.next()
+
+	if t.kind != odataString {
+		return Filter{}, fmt.Errorf("expected string; got '%s'", t.value)
+	}
+	if err = p.expect(")"); err != nil {
+		return Filter{}, err
+	}
+
+	return Filter{Field: field, Operator: operator, Value: t.value}, nil
+}
+
+func (p *odataParser) list(field string) (Filter, error) {
+	if err := p.expect("("); err != nil {
+		return Filter{}, err
+	}
+
+	list := make([]interface{}, 0)
+
+	for {
+		val, err := p.value()
+
+		if err != nil {
+			return Filter{}, err
+		}
+		if val == nil {
+			return Filter{}, fmt.Errorf("null can't be within list")
+		}
+
+		list = append(list, val)
+
+		if t := p.next(); t.kind == odataPunct && t.value == ")" {
+			break
+		} else if t.kind != odataPunct || t.value != "," {
+			return Filter{}, fmt.Errorf("expected ',' or ')'; got '%s'", t.value)
+		}
+	}
+
+	return Filter{Field: field, Operator: "eq", Value: list}, nil
+}
+
+// odataTokens splits filter into identifiers, strings, numbers and
+// punctuation
+func odataTokens(filter string) ([]odataToken, error) {
+	tokens := make([]odataToken, 0)
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, odataToken{kind: odataPunct, value: string(c)})
+			i++
+		case c == '\'':
+			var sb strings.Builder
+			i++
+
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+
+					i++
+					break
+				}
+
+				sb.WriteRune(runes[i])
+				i++
+			}
+
+			tokens = append(tokens, odataToken{kind: odataString, value: sb.String()})
+		case c == '-' || unicode.IsDigit(c):
+			start := i
+			i++
+
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, odataToken{kind: odataNumber, value: string(runes[start:i])})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				runes[i] == '_' || runes[i] == '.' || runes[i] == '/') {
+				i++
+			}
+
+			tokens = append(tokens, odataToken{
+				kind:  odataIdent,
+				value: strings.Replace(string(runes[start:i]), "/", ".", -1),
+			})
+		default:
+			return nil, fmt.Errorf("unexpected '%c'", c)
+		}
+	}
+
+	return tokens, nil
+}
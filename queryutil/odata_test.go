@@ -0,0 +1,44 @@
+package queryutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseODataFilter(t *testing.T) {
+	filters, err := ParseODataFilter(
+		"status/id in (1, 2) and name ne null and not contains(name, 'x') and " +
+			"startswith(code,'A') and active eq true and total le -1.5",
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := []Filter{
+		{Field: "status.id", Operator: "eq", Value: []interface{}{1.0, 2.0}},
+		{Field: "name", Operator: "isnotnull", Value: ""},
+		{Field: "name", Operator: "doesnotcontain", Value: "x"},
+		{Field: "code", Operator: "startswith", Value: "A"},
+		{Field: "active", Operator: "eq", Value: true},
+		{Field: "total", Operator: "lte", Value: -1.5},
+	}
+
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("got filters %v; want %v", filters, expected)
+	}
+
+	for _, filter := range []string{
+		"name eq 'a' or name eq 'b'",
+		"name has 'a'",
+		"name eq 'a",
+		"name gt null",
+		"substringof('a', name)",
+		"name eq 'a' name eq 'b'",
+		"(name eq 'a')",
+	} {
+		if _, err = ParseODataFilter(filter); err == nil {
+			t.Errorf("should return error for %q", filter)
+		}
+	}
+}
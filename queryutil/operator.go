@@ -0,0 +1,127 @@
+package queryutil
+
+import (
+	"sort"
+	"sync"
+)
+
+// OperatorBuilder builds where clause of custom filter operator for
+// field, which is db field of filter, and value of filter
+// Clause should use "?" placeholders for args eg. "similarity(name, ?) > 0.3",
+// which are rebound along with the rest of the query
+// Error should be *FilterError for invalid values so it's returned
+// to the client
+type OperatorBuilder func(field string, value interface{}) (clause string, args []interface{}, err error)
+
+// Field types of FieldConfig#Type
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeBoolean = "boolean"
+	FieldTypeDate    = "date"
+)
+
+// typeOperators are built in operators that can be applied to values
+// of each field type
+var typeOperators = map[string][]string{
+	FieldTypeString:  {"eq", "neq", "startswith", "endswith", "contains", "doesnotcontain", "isnull", "isnotnull", "isempty", "isnotempty"},
+	FieldTypeNumber:  {"eq", "neq", "lt", "lte", "gt", "gte", "isnull", "isnotnull"},
+	FieldTypeBoolean: {"eq", "neq", "isnull", "isnotnull"},
+	FieldTypeDate:    {"eq", "neq", "lt", "lte", "gt", "gte", "isnull", "isnotnull"},
+}
+
+var operators = struct {
+	mu       sync.RWMutex
+	builders map[string]OperatorBuilder
+}{builders: make(map[string]OperatorBuilder)}
+
+// RegisterOperator registers builder as filter operator name, eg.
+// "withinradius" for PostGIS or "fuzzy" for trigram similarity, which
+// filters can then use like built in operators
+//
+// Registered operators are consulted before built in ones so they can
+// also replace them, and builder is responsible for validating value
+// Registering nil builder removes operator
+//
+// Operators should be registered at startup
+func RegisterOperator(name string, builder OperatorBuilder) {
+	operators.mu.Lock()
+	defer operators.mu.Unlock()
+
+	if builder == nil {
+		delete(operators.builders, name)
+		return
+	}
+
+	operators.builders[name] = builder
+}
+
+// FilterOperators returns sorted names of every filter operator,
+// built in, geo and registered
+func FilterOperators() []string {
+	operators.mu.RLock()
+	defer operators.mu.RUnlock()
+
+	names := make([]string, 0, len(filterOperators)+len(operators.builders)+2)
+
+	for _, k := range []string{GeoWithinBBox, GeoWithinRadius} {
+		if _, ok := operators.builders[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	for k := range filterOperators {
+		if _, ok := operators.builders[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	for k := range operators.builders {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// lookupOperator returns builder registered as operator name
+func lookupOperator(name string) (OperatorBuilder, bool) {
+	operators.mu.RLock()
+	defer operators.mu.RUnlock()
+
+	builder, ok := operators.builders[name]
+	return builder, ok
+}
+
+// Operators returns sorted filter operators field can be filtered
+// with, which are geo operators for geo fields, else built in
+// operators of its Type, or every built in operator if Type isn't
+// known, along with registered operators
+// Returns nil if field can't be filtered
+func (f FieldConfig) Operators() []string {
+	if !f.OperationConf.CanFilterBy || f.Window {
+		return nil
+	}
+
+	operators.mu.RLock()
+	defer operators.mu.RUnlock()
+
+	var names []string
+
+	if f.Geo != nil {
+		names = []string{GeoWithinBBox, GeoWithinRadius}
+	} else if typed, ok := typeOperators[f.Type]; ok {
+		names = append(names, typed...)
+	} else {
+		for k := range filterOperators {
+			names = append(names, k)
+		}
+	}
+
+	for k := range operators.builders {
+		if !filterOperators[k] && !isGeoOperator(k) {
+			names = append(names, k)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
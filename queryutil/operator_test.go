@@ -0,0 +1,72 @@
+package queryutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterOperator(t *testing.T) {
+	RegisterOperator("fuzzy", func(field string, value interface{}) (string, []interface{}, error) {
+		s, ok := value.(string)
+
+		if !ok {
+			filterErr := &FilterError{}
+			filterErr.setInvalidValueError(field, value)
+			return "", nil, filterErr
+		}
+
+		return "similarity(" + field + ", ?) > ?", []interface{}{s, 0.3}, nil
+	})
+	defer RegisterOperator("fuzzy", nil)
+
+	fields := map[string]FieldConfig{
+		"name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true},
+		},
+		"id": {
+			DBField:       "foo.id",
+			OperationConf: OperationConfig{CanFilterBy: true},
+		},
+	}
+
+	q := "select * from foo where"
+	replacements, err := ReplaceFilterFields(&q, []Filter{
+		{Field: "name", Operator: "fuzzy", Value: "bar"},
+		{Field: "id", Operator: "eq", Value: float64(1)},
+	}, fields)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !strings.HasSuffix(q, " (similarity(foo.name, ?) > ?) and foo.id = ?") {
+		t.Errorf("should apply custom operator; got %s", q)
+	}
+	if len(replacements) != 3 || replacements[0] != "bar" || replacements[1] != 0.3 || replacements[2] != float64(1) {
+		t.Errorf("should return args of custom operator; got %v", replacements)
+	}
+
+	q = "select * from foo where"
+
+	if _, err = ReplaceFilterFields(&q, []Filter{{Field: "name", Operator: "fuzzy", Value: float64(1)}}, fields); err == nil {
+		t.Errorf("should return error of builder")
+	}
+
+	found := false
+
+	for _, v := range FilterOperators() {
+		if v == "fuzzy" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("FilterOperators should contain registered operator")
+	}
+
+	RegisterOperator("fuzzy", nil)
+
+	if _, err = FilterCheck(Filter{Field: "name", Operator: "fuzzy", Value: "bar"}); err == nil {
+		t.Errorf("should return error for removed operator")
+	}
+}
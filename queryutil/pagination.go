@@ -0,0 +1,55 @@
+package queryutil
+
+// Pagination is take and skip applied to a list query, which should be
+// sent back to the client, eg. within apiutil#ListResponse, so it knows
+// when its take or skip was capped instead of silently getting fewer
+// results than it asked for
+type Pagination struct {
+	// Take and Skip are the values applied to query
+	Take int `json:"take"`
+	Skip int `json:"skip"`
+
+	// RequestedTake and RequestedSkip are values requested by client,
+	// which are only set if they were capped by QueryConfig#TakeLimit
+	// or QueryConfig#SkipLimit
+	RequestedTake *int `json:"requestedTake,omitempty"`
+	RequestedSkip *int `json:"requestedSkip,omitempty"`
+
+	// Total is total number of results, which is set once counted
+	Total int `json:"total"`
+}
+
+// Capped returns whether take or skip requested by client was capped
+func (p Pagination) Capped() bool {
+	return p.RequestedTake != nil || p.RequestedSkip != nil
+}
+
+// ParsePagination is the same as ParseTakeAndSkip but returns take and
+// skip as Pagination along with values requested by client if they
+// were capped
+func ParsePagination(r FormRequest, paramConf ParamConfig, queryConf QueryConfig) (Pagination, error) {
+	r, err := TranslateRequest(r, paramConf, nil)
+
+	if err != nil {
+		return Pagination{}, err
+	}
+
+	take, skip, err := ParseTakeAndSkip(r, paramConf, queryConf)
+
+	if err != nil {
+		return Pagination{}, err
+	}
+
+	p := Pagination{Take: take, Skip: skip}
+	takeParam, skipParam := paginationParams(paramConf)
+
+	// Params were already validated so errors can't be returned
+	if requested, _ := parsePaginationParam(r, takeParam, take); requested != take {
+		p.RequestedTake = &requested
+	}
+	if requested, _ := parsePaginationParam(r, skipParam, skip); requested != skip {
+		p.RequestedSkip = &requested
+	}
+
+	return p, nil
+}
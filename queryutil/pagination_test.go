@@ -0,0 +1,144 @@
+package queryutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseTakeAndSkip(t *testing.T) {
+	takeLimit := 50
+	zero := 0
+
+	tests := []struct {
+		name      string
+		req       mapFormRequest
+		queryConf QueryConfig
+		take      int
+		skip      int
+		reason    error
+	}{
+		{"defaults", mapFormRequest{}, QueryConfig{TakeLimit: &takeLimit}, 50, 0, nil},
+		{"capTake", mapFormRequest{"take": "500", "skip": "10"}, QueryConfig{TakeLimit: &takeLimit}, 50, 10, nil},
+		{"negativeSkip", mapFormRequest{"skip": "-5"}, QueryConfig{}, 0, 0, ErrPaginationNegative},
+		{"negativeTake", mapFormRequest{"take": "-1"}, QueryConfig{}, 0, 0, ErrPaginationNegative},
+		{"notNumber", mapFormRequest{"take": "1.5"}, QueryConfig{}, 0, 0, ErrPaginationNotNumber},
+		{"overflow", mapFormRequest{"skip": "99999999999999999999"}, QueryConfig{}, 0, 0, ErrPaginationNotNumber},
+		{"skipTooLarge", mapFormRequest{"skip": "1001"}, QueryConfig{SkipLimit: 1000}, 0, 0, ErrSkipTooLarge},
+		{"capSkip", mapFormRequest{"skip": "1001"}, QueryConfig{SkipLimit: 1000, CapSkip: true}, 100, 1000, nil},
+		{"unlimited", mapFormRequest{"take": "5000"}, QueryConfig{TakeLimit: &zero, AllowUnlimited: true}, 5000, 0, nil},
+		{"unlimitedDefault", mapFormRequest{}, QueryConfig{TakeLimit: &zero, AllowUnlimited: true}, 0, 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			take, skip, err := ParseTakeAndSkip(test.req, ParamConfig{}, test.queryConf)
+
+			if test.reason != nil {
+				pagErr, ok := errors.Cause(err).(*PaginationError)
+
+				if !ok || pagErr.Reason != test.reason {
+					t.Fatalf("should return pagination error with %v; got %v", test.reason, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not return error; got %s", err.Error())
+			}
+			if take != test.take || skip != test.skip {
+				t.Errorf("got take %d and skip %d; want %d and %d", take, skip, test.take, test.skip)
+			}
+		})
+	}
+}
+
+func TestTakeLimitSemantics(t *testing.T) {
+	zero := 0
+	negative := -1
+
+	tests := []struct {
+		name      string
+		queryConf QueryConfig
+		reason    error
+	}{
+		{"negative", QueryConfig{TakeLimit: &negative, AllowUnlimited: true}, ErrTakeLimitNegative},
+		{"unlimitedNotAllowed", QueryConfig{TakeLimit: &zero}, ErrUnlimitedNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := ParseTakeAndSkip(mapFormRequest{}, ParamConfig{}, test.queryConf); !isTakeLimitError(err, test.reason) {
+				t.Errorf("ParseTakeAndSkip should return take limit error with %v; got %v", test.reason, err)
+			}
+
+			q := "select * from foo"
+
+			if _, err := GetLimitWithOffsetReplacementsV2(mapFormRequest{}, &q, ParamConfig{}, test.queryConf); !isTakeLimitError(err, test.reason) {
+				t.Errorf("GetLimitWithOffsetReplacementsV2 should return take limit error with %v; got %v", test.reason, err)
+			}
+		})
+	}
+
+	q := "select * from foo"
+
+	if _, err := GetLimitWithOffsetReplacements(mapFormRequest{}, &q, "take", "skip", 0); !isTakeLimitError(err, ErrUnlimitedNotAllowed) {
+		t.Errorf("GetLimitWithOffsetReplacements should not allow unlimited; got %v", err)
+	}
+
+	q = "select * from foo"
+
+	if _, err := ApplyAll(mapFormRequest{}, &q, 0, 0, nil, nil); !isTakeLimitError(err, ErrUnlimitedNotAllowed) {
+		t.Errorf("ApplyAll should not allow unlimited; got %v", err)
+	}
+
+	q = "select * from foo"
+	replacements, err := ApplyAllV2(mapFormRequest{"skip": "10"}, &q, 0, 0, nil, nil, &ApplyConfig{ApplyLimit: true, AllowUnlimited: true})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if len(replacements) != 2 || replacements[0] != int64(math.MaxInt64) || replacements[1] != 10 {
+		t.Errorf("should not limit take; got %v", replacements)
+	}
+
+	q = "select * from foo"
+	replacements, err = ApplyAll(mapFormRequest{}, &q, 20, 0, nil, nil)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if len(replacements) != 2 || replacements[0] != 20 || replacements[1] != 0 {
+		t.Errorf("should default take to take limit; got %v", replacements)
+	}
+}
+
+func isTakeLimitError(err error, reason error) bool {
+	takeErr, ok := errors.Cause(err).(*TakeLimitError)
+	return ok && takeErr.Reason == reason
+}
+
+func TestParsePagination(t *testing.T) {
+	takeLimit := 50
+	queryConf := QueryConfig{TakeLimit: &takeLimit, SkipLimit: 1000, CapSkip: true}
+
+	p, err := ParsePagination(mapFormRequest{"take": "20", "skip": "40"}, ParamConfig{}, queryConf)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if p.Take != 20 || p.Skip != 40 || p.Capped() {
+		t.Errorf("should not cap pagination; got %+v", p)
+	}
+
+	if p, err = ParsePagination(mapFormRequest{"take": "500", "skip": "5000"}, ParamConfig{}, queryConf); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if p.Take != 50 || p.Skip != 1000 || !p.Capped() {
+		t.Fatalf("should cap pagination; got %+v", p)
+	}
+	if *p.RequestedTake != 500 || *p.RequestedSkip != 5000 {
+		t.Errorf("should return requested take and skip; got %d and %d", *p.RequestedTake, *p.RequestedSkip)
+	}
+}
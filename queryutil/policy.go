@@ -0,0 +1,97 @@
+package queryutil
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// FieldPolicy restricts fields of payloads to users within groups where
+// key is field name and value is groups allowed to see it eg.
+// FieldPolicy{"salary": {"Admin"}}
+// Fields not within policy are visible to everyone
+//
+// Field names are matched against keys of objects at every depth of
+// payload, so for rows from RowerToMaps they should be the converted
+// column names
+type FieldPolicy map[string][]string
+
+// Allowed returns whether field is visible to user within groups
+func (p FieldPolicy) Allowed(field string, groups []string) bool {
+	allowed, ok := p[field]
+
+	if !ok {
+		return true
+	}
+
+	for _, a := range allowed {
+		for _, g := range groups {
+			if a == g {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Filter returns payload with every field not visible to groups removed
+// Maps and slices of maps are filtered in place while any other payload,
+// eg. structs, is converted through json so fields are matched against
+// json names
+func (p FieldPolicy) Filter(payload interface{}, groups []string) (interface{}, error) {
+	if len(p) == 0 {
+		return payload, nil
+	}
+
+	switch v := payload.(type) {
+	case map[string]interface{}:
+		p.filterValue(v, groups)
+		return v, nil
+	case []map[string]interface{}:
+		for _, row := range v {
+			p.filterValue(row, groups)
+		}
+
+		return v, nil
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(payloadBytes))
+	dec.UseNumber()
+
+	if err = dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	p.filterValue(value, groups)
+	return value, nil
+}
+
+func (p FieldPolicy) filterValue(value interface{}, groups []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if !p.Allowed(k, groups) {
+				delete(v, k)
+				continue
+			}
+
+			p.filterValue(field, groups)
+		}
+	case []interface{}:
+		for _, item := range v {
+			p.filterValue(item, groups)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			p.filterValue(item, groups)
+		}
+	}
+}
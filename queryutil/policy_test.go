@@ -0,0 +1,63 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestFieldPolicy(t *testing.T) {
+	policy := FieldPolicy{"salary": {"Admin"}}
+
+	type employee struct {
+		Name    string     `json:"name"`
+		Salary  int        `json:"salary"`
+		Manager *employee  `json:"manager,omitempty"`
+		Reports []employee `json:"reports,omitempty"`
+	}
+
+	payload := employee{
+		Name:    "foo",
+		Salary:  10,
+		Manager: &employee{Name: "bar", Salary: 20},
+		Reports: []employee{{Name: "baz", Salary: 5}},
+	}
+
+	filtered, err := policy.Filter(payload, []string{"User"})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	filteredBytes, _ := json.Marshal(filtered)
+	expected := `{"manager":{"name":"bar"},"name":"foo","reports":[{"name":"baz"}]}`
+
+	if string(filteredBytes) != expected {
+		t.Errorf("got %s; want %s", filteredBytes, expected)
+	}
+
+	filtered, _ = policy.Filter(payload, []string{"User", "Admin"})
+	filteredBytes, _ = json.Marshal(filtered)
+
+	if string(filteredBytes) != `{"manager":{"name":"bar","salary":20},"name":"foo","reports":[{"name":"baz","salary":5}],"salary":10}` {
+		t.Errorf("admin should see salary; got %s", filteredBytes)
+	}
+
+	rows := []map[string]interface{}{{"name": "foo", "salary": 10}}
+
+	if filtered, _ = policy.Filter(rows, nil); !reflect.DeepEqual(filtered, []map[string]interface{}{{"name": "foo"}}) {
+		t.Errorf("should filter rows in place; got %v", filtered)
+	}
+
+	rower := dbtest.NewRows("name", "salary").AddRow("foo", int64(10))
+	mapped, err := RowerToMaps(rower, RowerMapConfig{FieldPolicy: policy})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !reflect.DeepEqual(mapped, []map[string]interface{}{{"name": "foo"}}) {
+		t.Errorf("should leave out salary column; got %v", mapped)
+	}
+}
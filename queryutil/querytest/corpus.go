@@ -0,0 +1,110 @@
+package querytest
+
+import (
+	"sort"
+
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+// Corpus is known sql injection payloads, which are used as fields,
+// operators, values and dirs of hostile filters, sorts and groups
+var Corpus = []string{
+	"",
+	" ",
+	"'",
+	"\"",
+	"`",
+	";",
+	"--",
+	"/*",
+	"' or '1'='1",
+	"' or 1=1 --",
+	"\" or \"\"=\"",
+	"1 or 1=1",
+	"1; drop table users",
+	"id; drop table users --",
+	"id desc, (select 1)",
+	"id) or (1=1",
+	"(select password from users limit 1)",
+	"id union select password from users",
+	"case when 1=1 then id else name end",
+	"pg_sleep(10)",
+	"id\" or \"1\"=\"1",
+	"id` or `1`=`1",
+	"id/**/or/**/1=1",
+	"id\x00",
+	"id\n--",
+	"foo.id asc, 1",
+	"asc; delete from foo",
+	"desc nulls first",
+	"eq or 1=1",
+	"in",
+	"isnull or 1=1",
+	"%' or '%'='",
+	"\\' or 1=1 --",
+	"ıd",
+	"ＩＤ",
+}
+
+// HostileFilters returns filters of every payload of Corpus as field of
+// filter, and as operator and value of every field of fields
+func HostileFilters(fields map[string]queryutil.FieldConfig) []queryutil.Filter {
+	names := fieldNames(fields)
+	filters := make([]queryutil.Filter, 0, len(Corpus)*(1+len(names)*3))
+
+	for _, v := range Corpus {
+		filters = append(filters, queryutil.Filter{Field: v, Operator: "eq", Value: "foo"})
+
+		for _, name := range names {
+			filters = append(
+				filters,
+				queryutil.Filter{Field: name, Operator: v, Value: "foo"},
+				queryutil.Filter{Field: name, Operator: "eq", Value: v},
+				queryutil.Filter{Field: name, Operator: "eq", Value: []interface{}{v, "foo"}},
+			)
+		}
+	}
+
+	return filters
+}
+
+// HostileSorts returns sorts of every payload of Corpus as field of
+// sort, and as dir of every field of fields
+func HostileSorts(fields map[string]queryutil.FieldConfig) []queryutil.Sort {
+	names := fieldNames(fields)
+	sorts := make([]queryutil.Sort, 0, len(Corpus)*(1+len(names)))
+
+	for _, v := range Corpus {
+		sorts = append(sorts, queryutil.Sort{Field: v, Dir: "asc"})
+
+		for _, name := range names {
+			sorts = append(sorts, queryutil.Sort{Field: name, Dir: v})
+		}
+	}
+
+	return sorts
+}
+
+// HostileGroups returns groups of every payload of Corpus as field
+func HostileGroups(fields map[string]queryutil.FieldConfig) []queryutil.Group {
+	groups := make([]queryutil.Group, 0, len(Corpus))
+
+	for _, v := range Corpus {
+		groups = append(groups, queryutil.Group{Field: v})
+	}
+
+	return groups
+}
+
+// fieldNames returns sorted names of fields so payloads are generated
+// in the same order every run
+func fieldNames(fields map[string]queryutil.FieldConfig) []string {
+	names := make([]string, 0, len(fields))
+
+	for k := range fields {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+	return names
+}
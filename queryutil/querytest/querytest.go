@@ -0,0 +1,372 @@
+package querytest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// baseQuery is query filters, sorts and groups are appended to
+	baseQuery = "select foo.id from foo where"
+)
+
+var (
+	// keywords are words queryutil generates within filter, sort and
+	// group clauses of every dialect
+	keywords = map[string]bool{
+		"and":    true,
+		"in":     true,
+		"is":     true,
+		"not":    true,
+		"null":   true,
+		"like":   true,
+		"ilike":  true,
+		"asc":    true,
+		"desc":   true,
+		"concat": true,
+	}
+
+	// literals are the only string literals queryutil generates
+	literals = map[string]bool{
+		"'%'": true,
+		"''":  true,
+	}
+
+	// operators are comparison and concat operators queryutil generates
+	operators = map[string]bool{
+		"=":  true,
+		"!=": true,
+		"<":  true,
+		"<=": true,
+		">":  true,
+		">=": true,
+		"||": true,
+	}
+)
+
+// Checker checks sql queryutil appends to queries for filters, sorts
+// and groups only contains db fields of Fields, placeholders and the
+// keywords and operators queryutil generates, so anything from a
+// request other than values bound as args is caught
+//
+// Clauses of FieldConfig#Geo fields and of operators registered with
+// queryutil#RegisterOperator contain functions eg. "ST_DWithin", which
+// should be added to Keywords if such fields are checked
+type Checker struct {
+	// Fields are fields of resource being checked, whose db fields are
+	// the only identifiers allowed
+	Fields map[string]queryutil.FieldConfig
+
+	// Dialect is dialect filters are applied with
+	// Default is postgres
+	Dialect dbutil.Dialect
+
+	// Keywords are words allowed besides identifiers of Fields and the
+	// keywords of queryutil, matched case insensitive
+	Keywords []string
+}
+
+// NewChecker returns *Checker of fields with postgres dialect
+func NewChecker(fields map[string]queryutil.FieldConfig) *Checker {
+	return &Checker{
+		Fields:  fields,
+		Dialect: dbutil.GetDialect(dbutil.Postgres),
+	}
+}
+
+// CheckSQL returns error if sql, which should only be what queryutil
+// appended to a query, contains anything other than db fields of
+// Checker#Fields, extra identifiers, placeholders and keywords
+//
+// If args is not negative, it's the number of args bound to sql which
+// must match the number of placeholders
+// Extra are identifiers, or expressions, allowed besides db fields eg.
+// field applied by Apply functions
+func (c *Checker) CheckSQL(sql string, args int, extra ...string) error {
+	idents := make(map[string]bool)
+	exprs := make([]string, 0)
+
+	for _, conf := range c.Fields {
+		if dbutil.ValidIdentifier(conf.DBField) {
+			idents[conf.DBField] = true
+		} else if conf.DBField != "" {
+			exprs = append(exprs, conf.DBField)
+		}
+	}
+	for _, v := range extra {
+		if dbutil.ValidIdentifier(v) {
+			idents[v] = true
+		} else if v != "" {
+			exprs = append(exprs, v)
+		}
+	}
+
+	// Expressions and quoted identifiers are removed before sql is
+	// tokenized, longest first so expressions within others are kept
+	sort.Slice(exprs, func(i, j int) bool {
+		return len(exprs[i]) > len(exprs[j])
+	})
+
+	for _, v := range exprs {
+		sql = strings.Replace(sql, v, " ", -1)
+	}
+
+	extraKeywords := make(map[string]bool, len(c.Keywords))
+
+	for _, v := range c.Keywords {
+		extraKeywords[strings.ToLower(v)] = true
+	}
+
+	placeholders := 0
+	runes := []rune(sql)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '?':
+			placeholders++
+			i++
+		case r == '(' || r == ')' || r == ',':
+			i++
+		case r == '\'':
+			end := i + 1
+
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end < len(runes) {
+				end++
+			}
+
+			token := string(runes[i:end])
+
+			if !literals[token] {
+				return fmt.Errorf("querytest: unexpected literal %s in sql %q", token, sql)
+			}
+
+			i = end
+		case strings.ContainsRune("=!<>|", r):
+			end := i
+
+			for end < len(runes) && strings.ContainsRune("=!<>|", runes[end]) {
+				end++
+			}
+
+			if token := string(runes[i:end]); !operators[token] {
+				return fmt.Errorf("querytest: unexpected operator %s in sql %q", token, sql)
+			}
+
+			i = end
+		case isWordRune(r):
+			end := i
+
+			for end < len(runes) && (isWordRune(runes[end]) || runes[end] == '.') {
+				end++
+			}
+
+			token := string(runes[i:end])
+			lower := strings.ToLower(token)
+
+			if !idents[token] && !keywords[lower] && !extraKeywords[lower] && !isNumber(token) {
+				return fmt.Errorf("querytest: unexpected word %s in sql %q", token, sql)
+			}
+
+			i = end
+		default:
+			return fmt.Errorf("querytest: unexpected character %q in sql %q", r, sql)
+		}
+	}
+
+	if args >= 0 && placeholders != args {
+		return fmt.Errorf(
+			"querytest: sql %q has %d placeholders but %d args",
+			sql, placeholders, args,
+		)
+	}
+
+	return nil
+}
+
+// CheckFilters fails t if sql of any filter applied with
+// queryutil#ReplaceFilterFieldsWithDialect doesn't pass CheckSQL
+// Filters are checked one at a time and then all together, where
+// filters rejected with error pass as they're never applied
+//
+// Every filter is also applied with queryutil#ApplyFilterWithDialect,
+// which doesn't whitelist fields, and must only add its field as a
+// single identifier
+func (c *Checker) CheckFilters(t testing.TB, filters []queryutil.Filter) {
+	t.Helper()
+
+	check := func(filters []queryutil.Filter) {
+		query := baseQuery
+		args, err := queryutil.ReplaceFilterFieldsWithDialect(&query, filters, c.Fields, c.Dialect)
+
+		if err != nil {
+			return
+		}
+		if err = c.CheckSQL(strings.TrimPrefix(query, baseQuery), len(args)); err != nil {
+			t.Errorf("filters %+v: %s", filters, err.Error())
+		}
+	}
+
+	for _, v := range filters {
+		check([]queryutil.Filter{v})
+
+		query := baseQuery
+		queryutil.ApplyFilterWithDialect(&query, v, false, c.Dialect)
+
+		if err := c.CheckSQL(
+			strings.TrimPrefix(query, baseQuery), -1, safeIdentifier(v.Field, c.Dialect),
+		); err != nil {
+			t.Errorf("apply filter %+v: %s", v, err.Error())
+		}
+	}
+
+	if len(filters) > 1 {
+		check(filters)
+	}
+}
+
+// CheckSorts is the same as CheckFilters but for sorts applied with
+// queryutil#ReplaceSortFields and queryutil#ApplySortWithDialect
+func (c *Checker) CheckSorts(t testing.TB, sorts []queryutil.Sort) {
+	t.Helper()
+
+	check := func(sorts []queryutil.Sort) {
+		query := baseQuery
+
+		if err := queryutil.ReplaceSortFields(&query, sorts, c.Fields); err != nil {
+			return
+		}
+		if err := c.CheckSQL(strings.TrimPrefix(query, baseQuery), 0); err != nil {
+			t.Errorf("sorts %+v: %s", sorts, err.Error())
+		}
+	}
+
+	for _, v := range sorts {
+		check([]queryutil.Sort{v})
+
+		query := baseQuery
+		queryutil.ApplySortWithDialect(&query, v, false, c.Dialect)
+
+		if err := c.CheckSQL(
+			strings.TrimPrefix(query, baseQuery), 0,
+			safeIdentifier(v.Field, c.Dialect),
+		); err != nil {
+			t.Errorf("apply sort %+v: %s", v, err.Error())
+		}
+	}
+
+	if len(sorts) > 1 {
+		check(sorts)
+	}
+}
+
+// CheckGroups is the same as CheckFilters but for groups applied with
+// queryutil#ReplaceGroupFields and queryutil#ApplyGroupWithDialect
+func (c *Checker) CheckGroups(t testing.TB, groups []queryutil.Group) {
+	t.Helper()
+
+	check := func(groups []queryutil.Group) {
+		query := baseQuery
+
+		if err := queryutil.ReplaceGroupFields(&query, groups, c.Fields); err != nil {
+			return
+		}
+		if err := c.CheckSQL(strings.TrimPrefix(query, baseQuery), 0); err != nil {
+			t.Errorf("groups %+v: %s", groups, err.Error())
+		}
+	}
+
+	for _, v := range groups {
+		check([]queryutil.Group{v})
+
+		query := baseQuery
+		queryutil.ApplyGroupWithDialect(&query, v, false, c.Dialect)
+
+		if err := c.CheckSQL(
+			strings.TrimPrefix(query, baseQuery), 0,
+			safeIdentifier(v.Field, c.Dialect),
+		); err != nil {
+			t.Errorf("apply group %+v: %s", v, err.Error())
+		}
+	}
+
+	if len(groups) > 1 {
+		check(groups)
+	}
+}
+
+// CheckHostile fails t if any payload of HostileFilters, HostileSorts
+// or HostileGroups of Checker#Fields doesn't pass CheckFilters,
+// CheckSorts or CheckGroups
+func (c *Checker) CheckHostile(t testing.TB) {
+	t.Helper()
+	c.CheckFilters(t, HostileFilters(c.Fields))
+	c.CheckSorts(t, HostileSorts(c.Fields))
+	c.CheckGroups(t, HostileGroups(c.Fields))
+}
+
+// Fuzz fuzzes filters, sorts and groups of Checker#Fields, seeded with
+// Corpus, which fails if any doesn't pass CheckFilters, CheckSorts or
+// CheckGroups
+// It's meant to be called from fuzz test of fields eg.
+//
+//	func FuzzInvoiceFields(f *testing.F) {
+//		querytest.NewChecker(invoiceFields).Fuzz(f)
+//	}
+func (c *Checker) Fuzz(f *testing.F) {
+	names := fieldNames(c.Fields)
+
+	for _, v := range Corpus {
+		f.Add(v, "eq", "foo", "asc")
+
+		for _, name := range names {
+			f.Add(name, v, "foo", v)
+			f.Add(name, "eq", v, "desc")
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, field, operator, value, dir string) {
+		c.CheckFilters(t, []queryutil.Filter{
+			{Field: field, Operator: operator, Value: value},
+			{Field: field, Operator: operator, Value: []interface{}{value}},
+		})
+		c.CheckSorts(t, []queryutil.Sort{{Field: field, Dir: dir}})
+		c.CheckGroups(t, []queryutil.Group{{Field: field}})
+	})
+}
+
+// safeIdentifier returns identifier field is applied as by Apply
+// functions, which is quoted if it's not a valid identifier
+func safeIdentifier(field string, dialect dbutil.Dialect) string {
+	if dbutil.ValidIdentifier(field) {
+		return field
+	}
+
+	return dialect.QuoteIdentifier(field)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,79 @@
+package querytest
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+var (
+	// This should be used for read only
+	testFields = map[string]queryutil.FieldConfig{
+		"id": {
+			DBField: "foo.id",
+			OperationConf: queryutil.OperationConfig{
+				CanFilterBy: true,
+				CanSortBy:   true,
+				CanGroupBy:  true,
+			},
+		},
+		"name": {
+			DBField:    "lower(foo.name)",
+			Expression: true,
+			OperationConf: queryutil.OperationConfig{
+				CanFilterBy: true,
+				CanSortBy:   true,
+			},
+		},
+	}
+)
+
+func TestCheckSQL(t *testing.T) {
+	c := NewChecker(testFields)
+
+	valid := []struct {
+		sql  string
+		args int
+	}{
+		{" foo.id = ? and lower(foo.name) ilike '%' || ? || '%'", 2},
+		{" foo.id in (?) and foo.id is not null", 1},
+		{" foo.id asc, lower(foo.name) desc", 0},
+	}
+
+	for _, v := range valid {
+		if err := c.CheckSQL(v.sql, v.args); err != nil {
+			t.Errorf("should pass sql %q; got %s", v.sql, err.Error())
+		}
+	}
+
+	invalid := []struct {
+		sql  string
+		args int
+	}{
+		{" foo.id = ? or 1=1", 1},
+		{" foo.id = ?; drop table foo", 1},
+		{" foo.id = ? --", 1},
+		{" foo.password = ?", 1},
+		{" foo.id = 'admin'", 0},
+		{" foo.id = ?", 2},
+	}
+
+	for _, v := range invalid {
+		if err := c.CheckSQL(v.sql, v.args); err == nil {
+			t.Errorf("should fail sql %q", v.sql)
+		}
+	}
+}
+
+func TestCheckHostile(t *testing.T) {
+	NewChecker(testFields).CheckHostile(t)
+
+	c := NewChecker(testFields)
+	c.Dialect = dbutil.GetDialect(dbutil.Mysql)
+	c.CheckHostile(t)
+}
+
+func FuzzQueryutil(f *testing.F) {
+	NewChecker(testFields).Fuzz(f)
+}
@@ -5,18 +5,19 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/TravisS25/httputil/cacheutil"
 	"github.com/TravisS25/httputil/confutil"
 
 	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/dbutil"
-	"github.com/knq/snaker"
 
 	"github.com/jmoiron/sqlx"
 
@@ -28,6 +29,10 @@ import (
 const (
 	// Select string for queries
 	Select = "select "
+
+	// DefaultTakeLimit is take limit of queries whose
+	// QueryConfig#TakeLimit is nil
+	DefaultTakeLimit = 100
 )
 
 // Aggregate Types
@@ -43,6 +48,37 @@ var (
 	ErrInvalidSort  = errors.New("invalid sort")
 	ErrInvalidArray = errors.New("invalid array for field")
 	ErrInvalidValue = errors.New("invalid field value")
+
+	// ErrPaginationNotNumber is reason of PaginationError for take or
+	// skip values that aren't whole numbers or are too large to be one
+	ErrPaginationNotNumber = errors.New("must be a whole number")
+
+	// ErrPaginationNegative is reason of PaginationError for negative
+	// take or skip values
+	ErrPaginationNegative = errors.New("must not be negative")
+
+	// ErrSkipTooLarge is reason of PaginationError for skip values over
+	// QueryConfig#SkipLimit
+	ErrSkipTooLarge = errors.New("exceeds max skip")
+
+	// ErrTakeLimitNegative is reason of TakeLimitError for negative
+	// take limits
+	ErrTakeLimitNegative = errors.New("take limit must not be negative")
+
+	// ErrUnlimitedNotAllowed is reason of TakeLimitError for take limit
+	// of 0 without QueryConfig#AllowUnlimited
+	ErrUnlimitedNotAllowed = errors.New("take limit of 0 requires AllowUnlimited")
+
+	// MaxBindParameters is max number of parameters of query per sqlx
+	// bind type used by InQueryRebind
+	// Bind types that aren't set aren't limited
+	// Sqlite shares sqlx.QUESTION with mysql so InQueryRebindWithDialect
+	// should be used for its lower limit
+	MaxBindParameters = map[int]int{
+		sqlx.QUESTION: 65535,
+		sqlx.DOLLAR:   65535,
+		sqlx.AT:       2100,
+	}
 )
 
 ////////////////////////////////////////////////////////////
@@ -206,6 +242,58 @@ func (s *SliceError) setInvalidSliceError(field, fieldType string) {
 	s.invalidSlice = true
 }
 
+// PaginationError is returned when take or skip query params are invalid
+type PaginationError struct {
+	// Param is name of query param eg. "skip"
+	Param string
+
+	// Value is value of query param
+	Value string
+
+	// Reason is why value is invalid which is ErrPaginationNotNumber,
+	// ErrPaginationNegative or ErrSkipTooLarge
+	Reason error
+}
+
+func (p *PaginationError) Error() string {
+	return fmt.Sprintf("invalid value '%s' for '%s': %s", p.Value, p.Param, p.Reason.Error())
+}
+
+// TakeLimitError is returned when take limit configured for a query is
+// invalid, which is a server error unlike PaginationError
+type TakeLimitError struct {
+	// TakeLimit is the configured take limit
+	TakeLimit int
+
+	// Reason is why take limit is invalid which is ErrTakeLimitNegative
+	// or ErrUnlimitedNotAllowed
+	Reason error
+}
+
+func (t *TakeLimitError) Error() string {
+	return fmt.Sprintf("queryutil: invalid take limit %d: %s", t.TakeLimit, t.Reason.Error())
+}
+
+// TooManyParametersError is returned by InQueryRebind when query has
+// more bind parameters than the database allows, which is usually from
+// a filter or arg with a list of thousands of values
+type TooManyParametersError struct {
+	// Count is number of parameters of query after lists are expanded
+	Count int
+
+	// Limit is max number of parameters allowed
+	Limit int
+}
+
+func (t *TooManyParametersError) Error() string {
+	return fmt.Sprintf(
+		"query has %d parameters but database allows %d; pass large lists as one "+
+			"array parameter, eg. QueryConfig#MaxInListSize, or split them across queries",
+		t.Count,
+		t.Limit,
+	)
+}
+
 ////////////////////////////////////////////////////////////
 // CONFIG STRUCTS
 ////////////////////////////////////////////////////////////
@@ -229,6 +317,7 @@ type resultReplacements struct {
 	Filters      []Filter
 	Sorts        []Sort
 	Groups       []Group
+	Fields       []string
 	Replacements []interface{}
 }
 
@@ -244,6 +333,10 @@ type OperationConfig struct {
 
 	// CanGroupBy determines whether field can be grouped
 	CanGroupBy bool
+
+	// CanSelect determines whether field can be requested by
+	// ParamConfig#Fields
+	CanSelect bool
 }
 
 // FieldConfig is meant to be a per database field config
@@ -257,6 +350,40 @@ type FieldConfig struct {
 	// OperationConf is config to set to determine which sql
 	// operations can be performed on DBField
 	OperationConf OperationConfig
+
+	// Expression should be set if DBField is a sql expression, eg.
+	// "lower(foo.name)", instead of a column
+	// If not set, DBField must be a valid identifier, see
+	// dbutil#ValidIdentifier, else ErrInvalidIdentifier is returned
+	Expression bool
+
+	// Window should be set if DBField is a window function, eg.
+	// "row_number() over (order by foo.total desc)", so ranked lists
+	// can be sorted through the same query params as other fields
+	// Window fields are treated as expressions, can't be filtered or
+	// grouped as window functions aren't allowed within "where" and
+	// "group by" clauses, and are never added to group by when sorts
+	// are added to group by, see QueryConfig#DisableGroupMod
+	Window bool
+
+	// ValueTransform, if set, is applied to filter values of field
+	// before they are bound to query so values match how they're
+	// stored eg. LowercaseTransform for emails
+	// For "in" filters, it is applied to every value of list
+	// Returned error is sent to client as invalid filter value
+	ValueTransform func(interface{}) (interface{}, error)
+
+	// Geo marks DBField as PostGIS geometry or geography column so it
+	// can be filtered with GeoWithinBBox and GeoWithinRadius and
+	// sorted by distance with Sort#Near
+	Geo *GeoConfig
+
+	// Label is human readable name of field and Type is type of its
+	// values, see FieldTypeString, which describe field to clients
+	// eg. for filter builders, where Type also determines operators
+	// reported by Operators
+	Label string
+	Type  string
 }
 
 // ParamConfig is for extracting expected query params from url
@@ -281,6 +408,16 @@ type ParamConfig struct {
 	// Group is for query param that will be applied
 	// to "group by" clause of query
 	Group *string
+
+	// Fields is for query param of comma separated fields, eg.
+	// "fields=id,name", that will be applied to FieldsPlaceholder
+	// of query, see GetFieldsReplacements
+	Fields *string
+
+	// Syntax, if set, translates params of JSON:API or OData into the
+	// params above, see TranslateRequest
+	// Default is SyntaxDefault
+	Syntax Syntax
 }
 
 // QueryConfig is config for how the overall execution of the query
@@ -289,12 +426,41 @@ type QueryConfig struct {
 	// SQLBindVar is used to determines what query placeholder parameters
 	// will be converted to depending on what database being used
 	// This is based off of the sqlx library
+	// Default is bind var of db if it implements dbutil#BindVarProvider,
+	// else bind var of Dialect if set, else sqlx.QUESTION
 	SQLBindVar *int
 
+	// Dialect is type of database being queried eg. dbutil#Mysql
+	// which is used to generate filters and limit clause through
+	// dbutil#GetDialect
+	// Default is dbutil#Postgres
+	Dialect string
+
 	// TakeLimit is used to set max limit on number of
 	// records that are returned from query
+	// Take defaults to TakeLimit when not set by request
+	//
+	// Nil uses DefaultTakeLimit, 0 doesn't limit take which requires
+	// AllowUnlimited, and negative returns *TakeLimitError
 	TakeLimit *int
 
+	// AllowUnlimited must be set for TakeLimit of 0 so queries that
+	// return every row are always opted into explicitly
+	// Without it, TakeLimit of 0 returns *TakeLimitError with
+	// ErrUnlimitedNotAllowed instead of querying the whole table
+	AllowUnlimited bool
+
+	// SkipLimit is max number of records that can be skipped, which
+	// keeps clients from making the database scan deep offsets
+	// Skip over the limit returns PaginationError with ErrSkipTooLarge
+	// unless CapSkip is set
+	// Default is 0 which doesn't limit skip
+	SkipLimit int
+
+	// CapSkip determines whether skip over SkipLimit is lowered to
+	// SkipLimit instead of returning an error
+	CapSkip bool
+
 	// PrependFilterFields prepends filters to query before
 	// ones passed by url query params
 	PrependFilterFields []Filter
@@ -307,6 +473,19 @@ type QueryConfig struct {
 	// ones passed by url query params
 	PrependSortFields []Sort
 
+	// DefaultSorts are applied to query when no sorts are passed
+	// by url query params
+	// Like sorts of url query params, these must be within fields
+	// and be allowed to be sorted by
+	DefaultSorts []Sort
+
+	// TiebreakerColumn is unique database column, eg. "id", that is
+	// always appended to order by clause, if not already sorted by, so
+	// rows with the same sort values are returned in the same order
+	// and paginated results don't duplicate or skip rows between pages
+	// If the query is grouped, the column must be within group by
+	TiebreakerColumn string
+
 	// ExcludeFilters determines whether to exclude applying
 	// filters from url query params
 	// The PrependFilterFields property is NOT effected by this
@@ -337,6 +516,43 @@ type QueryConfig struct {
 	// automatically add the order by fields to the group by clause if they are
 	// needed unless DisableGroupMod is set true
 	DisableGroupMod bool
+
+	// GroupMode, if set, groups by rollup or cube of groups, which adds
+	// subtotal rows that can be labeled with LabelSubtotals eg. for
+	// financial summaries
+	// Fields added to group by for sorts, see DisableGroupMod, are
+	// added outside of rollup or cube
+	// Dialect must support grouping sets, see
+	// dbutil#Dialect#GroupingSets, else ErrGroupModeNotSupported
+	// is returned
+	GroupMode GroupMode
+
+	// Timeout is max duration generated queries are allowed to run
+	// before being cancelled, in which case dbutil#ErrQueryTimeout
+	// is returned
+	// Default is 0 which uses the query timeout of db if it implements
+	// dbutil#QueryTimeouter, else no timeout
+	Timeout time.Duration
+
+	// Debug, if set, allows authorized users to retrieve generated
+	// queries and their explain output through
+	// GetQueriedAndCountResultsWithDebug
+	Debug *DebugConfig
+
+	// CountMode determines how count query is executed
+	// Default is CountSum
+	CountMode CountMode
+
+	// MaxInListSize is max number of values of a list filter before its
+	// "in" clause is rewritten to not use a placeholder per value
+	// See RewriteLargeInLists
+	// Default is DefaultMaxInListSize
+	MaxInListSize int
+
+	// MaxParameters is max number of bind parameters of generated
+	// queries, over which TooManyParametersError is returned
+	// Default is dbutil#Dialect#MaxParams of Dialect
+	MaxParameters int
 }
 
 type ApplyConfig struct {
@@ -345,6 +561,10 @@ type ApplyConfig struct {
 	ExecuteQuery      bool
 	ExecuteCountQuery bool
 	ExclusionFields   []string
+
+	// AllowUnlimited allows takeLimit of 0 which doesn't limit take
+	// See QueryConfig#AllowUnlimited
+	AllowUnlimited bool
 }
 
 ////////////////////////////////////////////////////////////
@@ -362,6 +582,10 @@ type Filter struct {
 type Sort struct {
 	Dir   string `json:"dir"`
 	Field string `json:"field"`
+
+	// Near is point, as [x, y], field is sorted by distance from
+	// which requires field to have FieldConfig#Geo
+	Near []float64 `json:"near,omitempty"`
 }
 
 // Aggregate is config struct to be used in conjunction with Group
@@ -392,6 +616,7 @@ func getResults(
 	var replacements []interface{}
 	var err error
 
+	removePlaceholders(query)
 	totalReplacements := len(filterReplacements)
 
 	if prependVars != nil {
@@ -424,8 +649,18 @@ func getResults(
 	// fmt.Printf("query at this point: %s\n", *query)
 	// fmt.Printf("replacements overall: %v\n", replacements)
 
-	if *query, replacements, err = InQueryRebind(
-		*queryConf.SQLBindVar, *query, replacements...,
+	*query, replacements = RewriteLargeInLists(
+		*query, replacements, queryConf.Dialect, queryConf.MaxInListSize,
+	)
+
+	maxParams := queryConf.MaxParameters
+
+	if maxParams == 0 {
+		maxParams = dbutil.GetDialect(queryConf.Dialect).MaxParams
+	}
+
+	if *query, replacements, err = inQueryRebind(
+		*queryConf.SQLBindVar, maxParams, *query, replacements...,
 	); err != nil {
 		return nil, errors.Wrap(err, "\n-------------------\n")
 	}
@@ -433,6 +668,18 @@ func getResults(
 	return replacements, nil
 }
 
+// setDBBindVar sets SQLBindVar of queryConf to bind var of db if it's
+// not set and db implements dbutil#BindVarProvider
+func setDBBindVar(db httputil.Querier, queryConf *QueryConfig) {
+	if queryConf.SQLBindVar != nil {
+		return
+	}
+
+	if bindVar := dbutil.BindVarOf(db); bindVar != sqlx.UNKNOWN {
+		queryConf.SQLBindVar = &bindVar
+	}
+}
+
 func getCountResults(
 	query *string,
 	db httputil.Querier,
@@ -454,7 +701,14 @@ func getCountResults(
 		return 0, err
 	}
 
-	rower, err := db.Query(*query, replacements...)
+	applyCountMode(query, queryConf)
+	return queryCount(db, queryConf, *query, replacements)
+}
+
+// queryCount executes count query and returns the sum of counts
+// of every row
+func queryCount(db httputil.Querier, queryConf QueryConfig, query string, replacements []interface{}) (int, error) {
+	rower, err := dbutil.QueryWithTimeout(db, queryConf.Timeout, query, replacements...)
 
 	if err != nil {
 		return 0, err
@@ -477,6 +731,10 @@ func getCountResults(
 		totalCount += count
 	}
 
+	if err = dbutil.RowerErr(rower); err != nil {
+		return 0, err
+	}
+
 	return totalCount, nil
 }
 
@@ -493,6 +751,7 @@ func getReplacementResults(
 	var filters []Filter
 	var sorts []Sort
 	var groups []Group
+	var selected []string
 	var err error
 
 	f := "filters"
@@ -500,9 +759,10 @@ func getReplacementResults(
 	so := "sorts"
 	t := "take"
 	g := "groups"
+	fs := "fields"
 
 	sql := sqlx.QUESTION
-	limit := 100
+	limit := DefaultTakeLimit
 
 	if paramConf.Filter == nil {
 		paramConf.Filter = &f
@@ -519,8 +779,19 @@ func getReplacementResults(
 	if paramConf.Group == nil {
 		paramConf.Group = &g
 	}
+	if paramConf.Fields == nil {
+		paramConf.Fields = &fs
+	}
+
+	if r, err = TranslateRequest(r, *paramConf, fields); err != nil {
+		return nil, err
+	}
 
 	if queryConf.SQLBindVar == nil {
+		if queryConf.Dialect != "" {
+			sql = dbutil.GetDialect(queryConf.Dialect).BindVar
+		}
+
 		queryConf.SQLBindVar = &sql
 	}
 	if queryConf.TakeLimit == nil {
@@ -533,6 +804,10 @@ func getReplacementResults(
 		q = countQuery
 	}
 
+	if err = Registry.Check(*q, fields); err != nil {
+		return nil, err
+	}
+
 	if filters, filterReplacements, err = GetFilterReplacements(
 		r,
 		q,
@@ -557,6 +832,16 @@ func getReplacementResults(
 	}
 
 	if query != nil {
+		if selected, err = GetFieldsReplacements(
+			r,
+			q,
+			*paramConf.Fields,
+			*queryConf,
+			fields,
+		); err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
 		if sorts, err = DecodeSorts(r, *paramConf.Sort); err != nil {
 			return nil, errors.Wrap(err, "")
 		}
@@ -570,6 +855,12 @@ func getReplacementResults(
 
 				if ok {
 					if conf.OperationConf.CanSortBy {
+						// Window functions are computed after grouping
+						// so they're never part of group by
+						if conf.Window {
+							continue
+						}
+
 						for _, k := range groups {
 							if v.Field == k.Field {
 								hasGroupInSort = true
@@ -591,16 +882,8 @@ func getReplacementResults(
 				}
 			}
 
-			for i, v := range groupFields {
-				if i == 0 {
-					*q += ","
-				}
-
-				*q += " " + v
-
-				if i != len(groupFields)-1 {
-					*q += ","
-				}
+			if len(groupFields) > 0 {
+				addClause(q, groupClause, " "+strings.Join(groupFields, ", "))
 			}
 
 		}
@@ -620,6 +903,7 @@ func getReplacementResults(
 		Filters:      filters,
 		Groups:       groups,
 		Sorts:        sorts,
+		Fields:       selected,
 		Replacements: filterReplacements,
 	}, nil
 }
@@ -778,6 +1062,7 @@ func GetQueriedAndCountResults(
 	paramConf ParamConfig,
 	queryConf QueryConfig,
 ) (httputil.Rower, int, error) {
+	countQuery = subqueryCountQuery(query, countQuery, queryConf)
 	rower, err := GetQueriedResults(
 		query,
 		prependVars,
@@ -826,6 +1111,8 @@ func GetCountResults(
 	var results *resultReplacements
 	var err error
 
+	setDBBindVar(db, &queryConf)
+
 	if results, err = getReplacementResults(
 		nil,
 		countQuery,
@@ -861,6 +1148,8 @@ func GetPreQueryResults(
 	var limitOffsetReplacements []interface{}
 	var err error
 
+	setDBBindVar(db, &queryConf)
+
 	if results, err = getReplacementResults(
 		query,
 		nil,
@@ -873,12 +1162,11 @@ func GetPreQueryResults(
 	}
 
 	if !queryConf.ExcludeLimitWithOffset {
-		if limitOffsetReplacements, err = GetLimitWithOffsetReplacements(
+		if limitOffsetReplacements, err = getLimitWithOffsetReplacements(
 			r,
 			query,
-			*paramConf.Take,
-			*paramConf.Skip,
-			*queryConf.TakeLimit,
+			paramConf,
+			queryConf,
 		); err != nil {
 			return nil, errors.Wrap(err, "")
 		}
@@ -923,7 +1211,7 @@ func GetQueriedResults(
 		return nil, errors.Wrap(err, "")
 	}
 
-	return db.Query(*query, replacements...)
+	return dbutil.QueryWithTimeout(db, queryConf.Timeout, *query, replacements...)
 }
 
 ////////////////////////////////////////////////////////////
@@ -947,23 +1235,20 @@ func GetFilterReplacements(
 	var allFilters, filters []Filter
 	var replacements, prependReplacements, allReplacements []interface{}
 
-	filterExp := regexp.MustCompile(`(?i)(\n|\t|\s)where(\n|\t|\s)`)
-
 	if queryConf.PrependFilterFields != nil {
 		if len(queryConf.PrependFilterFields) > 0 {
-			if f := filterExp.FindString(*query); f == "" {
-				*query += " where"
-			} else {
-				*query += " and"
-			}
+			var clause string
 
-			if prependReplacements, err = ReplaceFilterFields(
-				query,
+			if prependReplacements, err = ReplaceFilterFieldsWithDialect(
+				&clause,
 				queryConf.PrependFilterFields,
 				fields,
+				dbutil.GetDialect(queryConf.Dialect),
 			); err != nil {
 				return nil, nil, errors.Wrap(err, "")
 			}
+
+			addClause(query, whereClause, clause)
 		}
 	} else {
 		queryConf.PrependFilterFields = make([]Filter, 0)
@@ -975,15 +1260,18 @@ func GetFilterReplacements(
 		}
 
 		if len(filters) > 0 {
-			if f := filterExp.FindString(*query); f == "" {
-				*query += " where"
-			} else {
-				*query += " and"
-			}
+			var clause string
 
-			if replacements, err = ReplaceFilterFields(query, filters, fields); err != nil {
+			if replacements, err = ReplaceFilterFieldsWithDialect(
+				&clause,
+				filters,
+				fields,
+				dbutil.GetDialect(queryConf.Dialect),
+			); err != nil {
 				return nil, nil, errors.Wrap(err, "")
 			}
+
+			addClause(query, whereClause, clause)
 		}
 	} else {
 		filters = make([]Filter, 0)
@@ -1073,23 +1361,19 @@ func GetSortReplacements(
 	//var replacements, prependReplacements []interface{}
 	var err error
 
-	orderExp := regexp.MustCompile(`(?i)(\n|\t|\s)order(\n|\t|\s)`)
-
 	if queryConf.PrependSortFields != nil {
 		if len(queryConf.PrependSortFields) > 0 {
-			if s := orderExp.FindString(*query); s == "" {
-				*query += " order by "
-			} else {
-				*query += ","
-			}
+			var clause string
 
 			if err = ReplaceSortFields(
-				query,
+				&clause,
 				queryConf.PrependSortFields,
 				fields,
 			); err != nil {
 				return nil, errors.Wrap(err, "")
 			}
+
+			addClause(query, orderClause, clause)
 		}
 	} else {
 		queryConf.PrependSortFields = make([]Sort, 0)
@@ -1099,18 +1383,20 @@ func GetSortReplacements(
 		if sortSlice, err = DecodeSorts(r, paramName); err != nil {
 			return nil, errors.Wrap(err, "")
 		}
+	}
 
-		if len(sortSlice) > 0 {
-			if s := orderExp.FindString(*query); s == "" {
-				*query += " order by "
-			} else {
-				*query += ","
-			}
+	if len(sortSlice) == 0 {
+		sortSlice = queryConf.DefaultSorts
+	}
 
-			if err = ReplaceSortFields(query, sortSlice, fields); err != nil {
-				return nil, errors.Wrap(err, "")
-			}
+	if len(sortSlice) > 0 {
+		var clause string
+
+		if err = ReplaceSortFields(&clause, sortSlice, fields); err != nil {
+			return nil, errors.Wrap(err, "")
 		}
+
+		addClause(query, orderClause, clause)
 	}
 
 	allSorts = make([]Sort, 0, len(queryConf.PrependSortFields)+len(sortSlice))
@@ -1123,6 +1409,10 @@ func GetSortReplacements(
 		allSorts = append(allSorts, v)
 	}
 
+	if queryConf.TiebreakerColumn != "" {
+		applyTiebreaker(query, queryConf.TiebreakerColumn, allSorts, fields, dbutil.GetDialect(queryConf.Dialect))
+	}
+
 	// for _, v := range prependReplacements {
 	// 	allReplacements = append(allReplacements, v)
 	// }
@@ -1198,23 +1488,23 @@ func GetGroupReplacements(
 	//var replacements, prependReplacements []interface{}
 	var err error
 
-	groupExp := regexp.MustCompile(`(?i)(\n|\t|\s)group(\n|\t|\s)`)
+	if queryConf.GroupMode != GroupModeNone {
+		return getGroupingSetReplacements(r, query, paramName, queryConf, fields)
+	}
 
 	if queryConf.PrependGroupFields != nil {
 		if len(queryConf.PrependGroupFields) > 0 {
-			if g := groupExp.FindString(*query); g == "" {
-				*query += " group by "
-			} else {
-				*query += ","
-			}
+			var clause string
 
 			if err = ReplaceGroupFields(
-				query,
+				&clause,
 				queryConf.PrependGroupFields,
 				fields,
 			); err != nil {
 				return nil, errors.Wrap(err, "")
 			}
+
+			addClause(query, groupClause, clause)
 		}
 	} else {
 		queryConf.PrependGroupFields = make([]Group, 0)
@@ -1226,15 +1516,13 @@ func GetGroupReplacements(
 		}
 
 		if len(groupSlice) > 0 {
-			if g := groupExp.FindString(*query); g == "" {
-				*query += " group by "
-			} else {
-				*query += ","
-			}
+			var clause string
 
-			if err = ReplaceGroupFields(query, groupSlice, fields); err != nil {
+			if err = ReplaceGroupFields(&clause, groupSlice, fields); err != nil {
 				return nil, errors.Wrap(err, "")
 			}
+
+			addClause(query, groupClause, clause)
 		}
 	} else {
 		groupSlice = make([]Group, 0)
@@ -1298,6 +1586,15 @@ func GetGroupReplacements(
 // 	return nil, replacements, nil
 // }
 
+// GetLimitWithOffsetReplacements applies limit and offset to query and
+// returns take and skip values from r as replacements
+//
+// takeLimit follows QueryConfig#TakeLimit without AllowUnlimited, so
+// 0 or negative takeLimit returns *TakeLimitError
+// Use GetLimitWithOffsetReplacementsV2 for unlimited queries
+//
+// Deprecated: Use GetLimitWithOffsetReplacementsV2, which uses ParamConfig
+// and QueryConfig
 func GetLimitWithOffsetReplacements(
 	r FormRequest,
 	query *string,
@@ -1305,35 +1602,162 @@ func GetLimitWithOffsetReplacements(
 	skipParam string,
 	takeLimit int,
 ) ([]interface{}, error) {
-	var err error
-	var takeInt, skipInt int
+	httputil.Deprecated("queryutil.GetLimitWithOffsetReplacements", "queryutil.GetLimitWithOffsetReplacementsV2")
+	return getLimitWithOffsetReplacements(
+		r,
+		query,
+		ParamConfig{Take: &takeParam, Skip: &skipParam},
+		QueryConfig{TakeLimit: &takeLimit, Dialect: dbutil.Postgres},
+	)
+}
 
-	take := r.FormValue(takeParam)
-	skip := r.FormValue(skipParam)
+// GetLimitWithOffsetReplacementsV2 is the same as
+// GetLimitWithOffsetReplacements but uses param names of paramConf and
+// take limit, AllowUnlimited and dialect of queryConf
+func GetLimitWithOffsetReplacementsV2(
+	r FormRequest,
+	query *string,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) ([]interface{}, error) {
+	return getLimitWithOffsetReplacements(r, query, paramConf, queryConf)
+}
 
-	if take == "" {
-		takeInt = takeLimit
-	} else {
-		if takeInt, err = strconv.Atoi(take); err != nil {
-			return nil, errors.Wrap(err, "")
-		}
+func getLimitWithOffsetReplacements(
+	r FormRequest,
+	query *string,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+) ([]interface{}, error) {
+	take, skip, err := ParseTakeAndSkip(r, paramConf, queryConf)
 
-		if takeInt > takeLimit {
-			takeInt = takeLimit
-		}
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	if skip == "" {
-		skipInt = 0
-	} else {
-		if skipInt, err = strconv.Atoi(skip); err != nil {
-			return nil, errors.Wrap(err, "")
+	addLimit(query, dbutil.GetDialect(queryConf.Dialect).LimitOffset)
+	return limitReplacements(take, skip), nil
+}
+
+// limitReplacements returns take and skip as replacements of limit
+// clause, where take of 0 from ParseTakeAndSkip, which only happens
+// when unlimited, is replaced by the max limit every dialect supports
+// so the query doesn't change shape
+func limitReplacements(take, skip int) []interface{} {
+	if take == 0 {
+		return []interface{}{int64(math.MaxInt64), skip}
+	}
+
+	return []interface{}{take, skip}
+}
+
+// ParseTakeAndSkip returns take and skip values of r from the take and
+// skip params of paramConf, where take defaults to and is capped at
+// TakeLimit of queryConf and skip defaults to 0
+//
+// Values must be non negative whole numbers that fit within int32, and
+// skip must not be over SkipLimit of queryConf unless CapSkip is set,
+// else *PaginationError is returned
+//
+// If TakeLimit is 0 and AllowUnlimited is set, take isn't capped and
+// 0 is returned as take when param isn't set or is 0, meaning no limit
+// Invalid TakeLimit returns *TakeLimitError
+func ParseTakeAndSkip(r FormRequest, paramConf ParamConfig, queryConf QueryConfig) (int, int, error) {
+	r, err := TranslateRequest(r, paramConf, nil)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	takeParam, skipParam := paginationParams(paramConf)
+	takeLimit := DefaultTakeLimit
+
+	if queryConf.TakeLimit != nil {
+		takeLimit = *queryConf.TakeLimit
+	}
+
+	if err = checkTakeLimit(takeLimit, queryConf.AllowUnlimited); err != nil {
+		return 0, 0, err
+	}
+
+	take, err := parsePaginationParam(r, takeParam, takeLimit)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	skip, err := parsePaginationParam(r, skipParam, 0)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if takeLimit > 0 && take > takeLimit {
+		take = takeLimit
+	}
+
+	if queryConf.SkipLimit > 0 && skip > queryConf.SkipLimit {
+		if !queryConf.CapSkip {
+			return 0, 0, &PaginationError{
+				Param:  skipParam,
+				Value:  r.FormValue(skipParam),
+				Reason: ErrSkipTooLarge,
+			}
 		}
+
+		skip = queryConf.SkipLimit
 	}
 
-	replacements := []interface{}{takeInt, skipInt}
-	ApplyLimit(query)
-	return replacements, nil
+	return take, skip, nil
+}
+
+// paginationParams returns names of take and skip params of paramConf
+func paginationParams(paramConf ParamConfig) (string, string) {
+	takeParam := "take"
+	skipParam := "skip"
+
+	if paramConf.Take != nil {
+		takeParam = *paramConf.Take
+	}
+	if paramConf.Skip != nil {
+		skipParam = *paramConf.Skip
+	}
+
+	return takeParam, skipParam
+}
+
+// checkTakeLimit returns *TakeLimitError if takeLimit is negative, or
+// is 0 without allowUnlimited
+func checkTakeLimit(takeLimit int, allowUnlimited bool) error {
+	if takeLimit < 0 {
+		return &TakeLimitError{TakeLimit: takeLimit, Reason: ErrTakeLimitNegative}
+	}
+	if takeLimit == 0 && !allowUnlimited {
+		return &TakeLimitError{TakeLimit: takeLimit, Reason: ErrUnlimitedNotAllowed}
+	}
+
+	return nil
+}
+
+// parsePaginationParam parses value of param from r as non negative
+// int32, returning defaultVal if param isn't set
+func parsePaginationParam(r FormRequest, param string, defaultVal int) (int, error) {
+	value := r.FormValue(param)
+
+	if value == "" {
+		return defaultVal, nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 32)
+
+	if err != nil {
+		return 0, &PaginationError{Param: param, Value: value, Reason: ErrPaginationNotNumber}
+	}
+	if i < 0 {
+		return 0, &PaginationError{Param: param, Value: value, Reason: ErrPaginationNegative}
+	}
+
+	return int(i), nil
 }
 
 ////////////////////////////////////////////////////////////
@@ -1410,6 +1834,17 @@ func DecodeGroups(r FormRequest, paramName string) ([]Group, error) {
 // This function does not apply "where" string for query so one must do it before
 // passing query
 func ReplaceFilterFields(query *string, filters []Filter, fields map[string]FieldConfig) ([]interface{}, error) {
+	return ReplaceFilterFieldsWithDialect(query, filters, fields, dbutil.GetDialect(dbutil.Postgres))
+}
+
+// ReplaceFilterFieldsWithDialect is the same as ReplaceFilterFields but
+// filters are applied with the syntax of dialect
+func ReplaceFilterFieldsWithDialect(
+	query *string,
+	filters []Filter,
+	fields map[string]FieldConfig,
+	dialect dbutil.Dialect,
+) ([]interface{}, error) {
 	var err error
 	replacements := make([]interface{}, 0, len(filters))
 
@@ -1423,7 +1858,7 @@ func ReplaceFilterFields(query *string, filters []Filter, fields map[string]Fiel
 		// If valid, apply filter to query
 		// Else throw error
 		if conf, ok := fields[v.Field]; ok {
-			if !conf.OperationConf.CanFilterBy {
+			if !conf.OperationConf.CanFilterBy || conf.Window {
 				filterErr := &FilterError{}
 				filterErr.setInvalidFilterError(conf.DBField)
 				return nil, errors.Wrap(filterErr, "")
@@ -1432,11 +1867,38 @@ func ReplaceFilterFields(query *string, filters []Filter, fields map[string]Fiel
 			//replacements = append(replacements, conf.DBField)
 			containsField = true
 
+			if conf.Geo != nil && isGeoOperator(v.Operator) {
+				if err = checkDBField(conf); err != nil {
+					return nil, err
+				}
+
+				clause, args, err := geoFilter(v, conf)
+
+				if err != nil {
+					return nil, errors.Wrap(err, "")
+				}
+
+				*query += " " + clause
+
+				if i != len(filters)-1 {
+					*query += " and"
+				}
+
+				replacements = append(replacements, args...)
+				continue
+			}
+
 			if r, err = FilterCheck(v); err != nil {
 				return nil, errors.Wrap(err, "")
 			}
 
-			replacements = append(replacements, r)
+			if conf.ValueTransform != nil && r != nil {
+				if r, err = transformValue(r, conf.ValueTransform); err != nil {
+					filterErr := &FilterError{}
+					filterErr.setInvalidValueError(v.Field, v.Value)
+					return nil, errors.Wrap(filterErr, err.Error())
+				}
+			}
 
 			applyAnd := true
 
@@ -1444,8 +1906,28 @@ func ReplaceFilterFields(query *string, filters []Filter, fields map[string]Fiel
 				applyAnd = false
 			}
 
+			if err = checkDBField(conf); err != nil {
+				return nil, err
+			}
+
+			_, custom := lookupOperator(v.Operator)
 			v.Field = conf.DBField
-			ApplyFilter(query, v, applyAnd)
+
+			if custom {
+				v.Value = r
+			}
+
+			args, err := applyFilter(query, v, applyAnd, dialect)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "")
+			}
+
+			if custom {
+				replacements = append(replacements, args...)
+			} else if bindsValue(v) {
+				replacements = append(replacements, r)
+			}
 		}
 
 		if !containsField {
@@ -1491,8 +1973,19 @@ func ReplaceSortFields(query *string, sorts []Sort, fields map[string]FieldConfi
 				addComma = false
 			}
 
+			if err = checkDBField(conf); err != nil {
+				return err
+			}
+
 			v.Field = conf.DBField
-			ApplySort(query, v, addComma)
+
+			if len(v.Near) > 0 {
+				if v.Field, err = geoSortField(v, conf); err != nil {
+					return errors.Wrap(err, "")
+				}
+			}
+
+			applySort(query, v, addComma)
 			containsField = true
 		}
 
@@ -1518,7 +2011,7 @@ func ReplaceGroupFields(query *string, groups []Group, fields map[string]FieldCo
 		// If valid, apply sort to query
 		// Else throw error
 		if conf, ok := fields[v.Field]; ok {
-			if !conf.OperationConf.CanGroupBy {
+			if !conf.OperationConf.CanGroupBy || conf.Window {
 				groupErr := &GroupError{}
 				groupErr.setInvalidGroupError(v.Field)
 				return errors.Wrap(groupErr, "")
@@ -1530,8 +2023,12 @@ func ReplaceGroupFields(query *string, groups []Group, fields map[string]FieldCo
 				addComma = false
 			}
 
+			if err := checkDBField(conf); err != nil {
+				return err
+			}
+
 			v.Field = conf.DBField
-			ApplyGroup(query, v, addComma)
+			applyGroup(query, v, addComma)
 			containsField = true
 		}
 
@@ -1552,7 +2049,44 @@ func ReplaceGroupFields(query *string, groups []Group, fields map[string]FieldCo
 // ApplyFilter applies the filter passed to the query passed
 // The applyAnd paramter is used to determine if the query should have
 // an "and" added to the end
+//
+// Operators registered with RegisterOperator are applied before built
+// in ones, but their args and errors are only returned through
+// ReplaceFilterFields so that should be used for them instead
 func ApplyFilter(query *string, filter Filter, applyAnd bool) {
+	ApplyFilterWithDialect(query, filter, applyAnd, dbutil.GetDialect(dbutil.Postgres))
+}
+
+// ApplyFilterWithDialect is the same as ApplyFilter but uses the like
+// operator, concatenation and identifier quoting of dialect
+//
+// If filter field is not a valid identifier, it's quoted so it can't
+// be used for sql injection
+func ApplyFilterWithDialect(query *string, filter Filter, applyAnd bool, dialect dbutil.Dialect) {
+	filter.Field = safeIdentifier(filter.Field, dialect)
+	applyFilter(query, filter, applyAnd, dialect)
+}
+
+// applyFilter applies filter to query and returns args of clause if its
+// operator is registered with RegisterOperator, else nil
+func applyFilter(query *string, filter Filter, applyAnd bool, dialect dbutil.Dialect) ([]interface{}, error) {
+	if builder, ok := lookupOperator(filter.Operator); ok {
+		clause, builderArgs, err := builder(filter.Field, filter.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Clause is wrapped so "or" within it can't escape the filter
+		*query += " (" + clause + ")"
+
+		if applyAnd {
+			*query += " and"
+		}
+
+		return builderArgs, nil
+	}
+
 	_, ok := filter.Value.([]interface{})
 
 	if ok {
@@ -1564,13 +2098,13 @@ func ApplyFilter(query *string, filter Filter, applyAnd bool) {
 		case "neq":
 			*query += " " + filter.Field + " != ?"
 		case "startswith":
-			*query += " " + filter.Field + " ilike ? || '%'"
+			*query += " " + filter.Field + " " + dialect.Like + " " + dialect.Concat("?", "'%'")
 		case "endswith":
-			*query += " " + filter.Field + " ilike '%' || ?"
+			*query += " " + filter.Field + " " + dialect.Like + " " + dialect.Concat("'%'", "?")
 		case "contains":
-			*query += " " + filter.Field + " ilike '%' || ? || '%'"
+			*query += " " + filter.Field + " " + dialect.Like + " " + dialect.Concat("'%'", "?", "'%'")
 		case "doesnotcontain":
-			*query += " " + filter.Field + " not ilike '%' || ? || '%'"
+			*query += " " + filter.Field + " not " + dialect.Like + " " + dialect.Concat("'%'", "?", "'%'")
 		case "isnull":
 			*query += " " + filter.Field + " is null"
 		case "isnotnull":
@@ -1594,12 +2128,44 @@ func ApplyFilter(query *string, filter Filter, applyAnd bool) {
 	if applyAnd {
 		*query += " and"
 	}
+
+	return nil, nil
+}
+
+// applyTiebreaker appends column to order by clause of query
+// unless one of sorts is already by column
+func applyTiebreaker(query *string, column string, sorts []Sort, fields map[string]FieldConfig, dialect dbutil.Dialect) {
+	for _, v := range sorts {
+		if conf, ok := fields[v.Field]; ok && conf.DBField == column {
+			return
+		}
+	}
+
+	var clause string
+
+	ApplySortWithDialect(&clause, Sort{Field: column, Dir: "asc"}, false, dialect)
+	addClause(query, orderClause, clause)
 }
 
 // ApplySort applies the sort passed to the query passed
 // The addComma paramter is used to determine if the query should have
 // ","(comma) appended to the query
+//
+// If sort field is not a valid identifier, it's quoted for postgres so
+// it can't be used for sql injection
+// Use ApplySortWithDialect for other databases
 func ApplySort(query *string, sort Sort, addComma bool) {
+	ApplySortWithDialect(query, sort, addComma, dbutil.GetDialect(dbutil.Postgres))
+}
+
+// ApplySortWithDialect is the same as ApplySort but sort field is
+// quoted with identifier quoting of dialect
+func ApplySortWithDialect(query *string, sort Sort, addComma bool, dialect dbutil.Dialect) {
+	sort.Field = safeIdentifier(sort.Field, dialect)
+	applySort(query, sort, addComma)
+}
+
+func applySort(query *string, sort Sort, addComma bool) {
 	*query += " " + sort.Field
 
 	if sort.Dir == "asc" {
@@ -1613,7 +2179,25 @@ func ApplySort(query *string, sort Sort, addComma bool) {
 	}
 }
 
+// ApplyGroup applies the group passed to the query passed
+// The addComma paramter is used to determine if the query should have
+// ","(comma) appended to the query
+//
+// If group field is not a valid identifier, it's quoted for postgres so
+// it can't be used for sql injection
+// Use ApplyGroupWithDialect for other databases
 func ApplyGroup(query *string, group Group, addComma bool) {
+	ApplyGroupWithDialect(query, group, addComma, dbutil.GetDialect(dbutil.Postgres))
+}
+
+// ApplyGroupWithDialect is the same as ApplyGroup but group field is
+// quoted with identifier quoting of dialect
+func ApplyGroupWithDialect(query *string, group Group, addComma bool, dialect dbutil.Dialect) {
+	group.Field = safeIdentifier(group.Field, dialect)
+	applyGroup(query, group, addComma)
+}
+
+func applyGroup(query *string, group Group, addComma bool) {
 	*query += " " + group.Field
 
 	if addComma {
@@ -1650,6 +2234,17 @@ func FilterCheck(f Filter) (interface{}, error) {
 	validTypes := []string{"string", "float64", "int64"}
 	hasValidType := false
 
+	// Values of registered operators are validated by their builders
+	if _, ok := lookupOperator(f.Operator); ok {
+		return f.Value, nil
+	}
+
+	if !filterOperators[f.Operator] {
+		filterErr := &FilterError{}
+		filterErr.setInvalidOperationError(f.Field)
+		return nil, filterErr
+	}
+
 	if f.Value != "" && f.Operator != "isnull" && f.Operator != "isnotnull" {
 		// First check if value sent is slice
 		list, ok := f.Value.([]interface{})
@@ -1754,7 +2349,7 @@ func DecodeFilter(filterEncoding string) ([]*Filter, error) {
 // FILTER LOGIC
 /////////////////////////////////////////////
 
-func applyFilters(query *string, filters []*Filter) {
+func applyFilters(query *string, filters []*Filter, dialect dbutil.Dialect) {
 	if len(filters) > 0 {
 		var selectCount int
 		var whereCount int
@@ -1783,6 +2378,7 @@ func applyFilters(query *string, filters []*Filter) {
 		// Loop through given filters and apply search criteria to query
 		// based off of filter operator
 		for i := 0; i < len(filters); i++ {
+			filters[i].Field = safeIdentifier(filters[i].Field, dialect)
 			_, ok := filters[i].Value.([]interface{})
 
 			if ok {
@@ -1829,6 +2425,10 @@ func applyFilters(query *string, filters []*Filter) {
 }
 
 func ApplyFilterV2(query *string, filters []*Filter, exclusionFields []string) {
+	applyFilterV2(query, filters, exclusionFields, dbutil.GetDialect(dbutil.Postgres))
+}
+
+func applyFilterV2(query *string, filters []*Filter, exclusionFields []string, dialect dbutil.Dialect) {
 	for i, v := range filters {
 		for _, t := range exclusionFields {
 			if v.Field == t {
@@ -1837,13 +2437,14 @@ func ApplyFilterV2(query *string, filters []*Filter, exclusionFields []string) {
 		}
 	}
 
-	applyFilters(query, filters)
+	applyFilters(query, filters, dialect)
 }
 
 // ApplyFilters takes a query string with a slice of Filter structs
 // and applies where filtering to the query
+// Filter fields that aren't valid identifiers are quoted for postgres
 func ApplyFilters(query *string, filters []*Filter) {
-	applyFilters(query, filters)
+	applyFilters(query, filters, dbutil.GetDialect(dbutil.Postgres))
 }
 
 // -------------------------------------------------------------------------------
@@ -1854,8 +2455,25 @@ func ApplyLimit(query *string) {
 }
 
 // ApplyOrdering takes given query and applies the given sort criteria
+// The sort field is converted to a column with DefaultNaming and quoted
+// for postgres if it's not a valid identifier and any dir other than
+// "asc" is applied as "desc"
+// Use ApplyOrderingWithDialect for other databases
 func ApplyOrdering(query *string, sort *Sort) {
-	*query += " order by " + snaker.CamelToSnake(sort.Field) + " " + sort.Dir
+	ApplyOrderingWithDialect(query, sort, dbutil.GetDialect(dbutil.Postgres))
+}
+
+// ApplyOrderingWithDialect is the same as ApplyOrdering but sort field
+// is quoted with identifier quoting of dialect
+func ApplyOrderingWithDialect(query *string, sort *Sort, dialect dbutil.Dialect) {
+	dir := "desc"
+
+	if sort.Dir == "asc" {
+		dir = "asc"
+	}
+
+	field := safeIdentifier(FieldColumn(sort.Field, nil), dialect)
+	*query += " order by " + field + " " + dir
 }
 
 /////////////////////////////////////////////
@@ -1899,9 +2517,9 @@ func whereFilter(
 		}
 
 		if exclusionFields == nil {
-			ApplyFilters(query, filters)
+			applyFilters(query, filters, bindVarDialect(bindVar))
 		} else {
-			ApplyFilterV2(query, filters, exclusionFields)
+			applyFilterV2(query, filters, exclusionFields, bindVarDialect(bindVar))
 		}
 		varReplacements = append(varReplacements, replacements...)
 	}
@@ -1949,6 +2567,9 @@ func whereFilter(
 // 		can't be used a placeholders like values can in a query
 // 		so if any given filter name does not match any of the field
 // 		names in the slice, then an error will be thrown
+//
+// Deprecated: Use GetFilterReplacements, which validates filters with
+// FieldConfig
 func WhereFilter(
 	r FormRequest,
 	query *string,
@@ -1956,6 +2577,7 @@ func WhereFilter(
 	prependVars []interface{},
 	fieldNames []string,
 ) ([]interface{}, error) {
+	httputil.Deprecated("queryutil.WhereFilter", "queryutil.GetFilterReplacements")
 	return whereFilter(r, query, bindVar, prependVars, fieldNames, nil, nil)
 }
 
@@ -1989,6 +2611,8 @@ func WhereFilter(
 // exclusionFields:
 //		Fields to exclude from form filters
 //
+// Deprecated: Use GetFilterReplacements, which validates filters with
+// FieldConfig
 func WhereFilterV2(
 	r FormRequest,
 	query *string,
@@ -1997,6 +2621,7 @@ func WhereFilterV2(
 	fieldNames map[string]string,
 	exclusionFields []string,
 ) ([]interface{}, error) {
+	httputil.Deprecated("queryutil.WhereFilterV2", "queryutil.GetFilterReplacements")
 	return whereFilter(r, query, bindVar, prependVars, nil, fieldNames, exclusionFields)
 }
 
@@ -2015,32 +2640,26 @@ func applyAll(
 	applyConfig *ApplyConfig,
 ) ([]interface{}, error) {
 	var err error
-	var intTake uint64
 
 	filters := make([]*Filter, 0)
 	varReplacements := make([]interface{}, 0)
-	take := r.FormValue("take")
-	skip := r.FormValue("skip")
 	filtersEncoded := r.FormValue("filters")
 	sortEncoded := r.FormValue("sort")
+	queryConf := QueryConfig{TakeLimit: new(int)}
 
-	if take == "" {
-		take = "0"
-		intTake = uint64(0)
+	if takeLimit > math.MaxInt32 {
+		*queryConf.TakeLimit = math.MaxInt32
 	} else {
-		intTake, err = strconv.ParseUint(take, 10, 32)
-
-		if err != nil {
-			return nil, errors.New(err.Error())
-		}
+		*queryConf.TakeLimit = int(takeLimit)
 	}
-
-	if skip == "" {
-		skip = "0"
+	if applyConfig != nil {
+		queryConf.AllowUnlimited = applyConfig.AllowUnlimited
 	}
 
-	if intTake > takeLimit && takeLimit > 0 {
-		take = strconv.FormatUint(takeLimit, 10)
+	take, skip, err := ParseTakeAndSkip(r, ParamConfig{}, queryConf)
+
+	if err != nil {
+		return nil, err
 	}
 
 	if prependVars != nil {
@@ -2068,12 +2687,12 @@ func applyAll(
 
 		if applyConfig != nil {
 			if applyConfig.ExclusionFields == nil {
-				ApplyFilters(query, filters)
+				applyFilters(query, filters, bindVarDialect(bindVar))
 			} else {
-				ApplyFilterV2(query, filters, applyConfig.ExclusionFields)
+				applyFilterV2(query, filters, applyConfig.ExclusionFields, bindVarDialect(bindVar))
 			}
 		} else {
-			ApplyFilters(query, filters)
+			applyFilters(query, filters, bindVarDialect(bindVar))
 		}
 
 		varReplacements = append(varReplacements, replacements...)
@@ -2090,33 +2709,49 @@ func applyAll(
 			return nil, ErrInvalidSort
 		}
 
+		// Sort field is placed within query so it must be whitelisted
 		if fieldNamesV2 != nil {
 			if _, ok := fieldNamesV2[sort.Field]; !ok {
-				fmt.Printf("sort field: %s\n", sort.Field)
-				filterErr := &FilterError{}
-				filterErr.setInvalidFilterError(sort.Field)
-				return nil, filterErr
+				sortErr := &SortError{}
+				sortErr.setInvalidSortError(sort.Field)
+				return nil, sortErr
 			}
 
 			sort.Field = fieldNamesV2[sort.Field]
+		} else {
+			sort.Field = FieldColumn(sort.Field, nil)
+			containsField := false
+
+			for _, v := range fieldNames {
+				if sort.Field == v {
+					containsField = true
+					break
+				}
+			}
+
+			if !containsField {
+				sortErr := &SortError{}
+				sortErr.setInvalidSortError(sort.Field)
+				return nil, sortErr
+			}
 		}
 
 		if applyConfig != nil {
 			if applyConfig.ApplyOrdering {
-				ApplyOrdering(query, sort)
+				ApplyOrderingWithDialect(query, sort, bindVarDialect(bindVar))
 			}
 		} else {
-			ApplyOrdering(query, sort)
+			ApplyOrderingWithDialect(query, sort, bindVarDialect(bindVar))
 		}
 	}
 
 	if applyConfig != nil {
 		if applyConfig.ApplyLimit {
-			varReplacements = append(varReplacements, take, skip)
+			varReplacements = append(varReplacements, limitReplacements(take, skip)...)
 			ApplyLimit(query)
 		}
 	} else {
-		varReplacements = append(varReplacements, take, skip)
+		varReplacements = append(varReplacements, limitReplacements(take, skip)...)
 		ApplyLimit(query)
 	}
 
@@ -2139,8 +2774,11 @@ func applyAll(
 // query:
 // 		The query to be modified
 // takeLimit:
-// 		Applies limit to the number of returned rows
-// 		If 0, no limit is set
+// 		Applies limit to the number of returned rows, which is also
+// 		the default take
+// 		0 isn't allowed since ApplyAll can't opt into unlimited
+// 		queries, so *TakeLimitError is returned; use ApplyAllV2 with
+// 		ApplyConfig#AllowUnlimited instead
 // bindVar:
 // 		The binding var used for query eg. sql.DOLLAR
 // prependVars:
@@ -2153,6 +2791,9 @@ func applyAll(
 // 		can't be used as placeholders like values can in a query
 // 		so if any given filter name does not match any of the field
 // 		names in the slice, then an error will be thrown
+//
+// Deprecated: Use GetQueriedResults, which validates filters and sorts
+// with FieldConfig
 func ApplyAll(
 	r FormRequest,
 	query *string,
@@ -2161,6 +2802,7 @@ func ApplyAll(
 	prependVars []interface{},
 	fieldNames []string,
 ) ([]interface{}, error) {
+	httputil.Deprecated("queryutil.ApplyAll", "queryutil.GetQueriedResults")
 	return applyAll(r, query, takeLimit, bindVar, prependVars, fieldNames, nil, nil)
 }
 
@@ -2174,8 +2816,9 @@ func ApplyAll(
 // query:
 // 		The query to be modified
 // takeLimit:
-// 		Applies limit to the number of returned rows
-// 		If 0, no limit is set
+// 		Applies limit to the number of returned rows, which is also
+// 		the default take
+// 		If 0, no limit is set which requires ApplyConfig#AllowUnlimited
 // bindVar:
 // 		The binding var used for query eg. sql.DOLLAR
 // prependVars:
@@ -2191,6 +2834,8 @@ func ApplyAll(
 // applyConfig:
 //		Configuration to determine whether to apply certain filters
 //
+// Deprecated: Use GetQueriedResults, which validates filters and sorts
+// with FieldConfig
 func ApplyAllV2(
 	r FormRequest,
 	query *string,
@@ -2200,6 +2845,7 @@ func ApplyAllV2(
 	fieldNames map[string]string,
 	applyconfig *ApplyConfig,
 ) ([]interface{}, error) {
+	httputil.Deprecated("queryutil.ApplyAllV2", "queryutil.GetQueriedResults")
 	return applyAll(r, query, takeLimit, bindVar, prependVars, nil, fieldNames, applyconfig)
 }
 
@@ -2209,6 +2855,9 @@ func ApplyAllV2(
 
 // GetFilteredResults is a wrapper function for getting a filtered query from
 // ApplyAll function along with getting a count
+//
+// Deprecated: Use GetQueriedAndCountResults, which validates filters and
+// sorts with FieldConfig
 func GetFilteredResults(
 	r FormRequest,
 	query *string,
@@ -2219,13 +2868,21 @@ func GetFilteredResults(
 	fieldNames []string,
 	db httputil.DBInterface,
 ) (httputil.Rower, int, error) {
-	replacements, err := ApplyAll(
+	httputil.Deprecated("queryutil.GetFilteredResults", "queryutil.GetQueriedAndCountResults")
+
+	if err := checkRegistered(query, countQuery); err != nil {
+		return nil, 0, err
+	}
+
+	replacements, err := applyAll(
 		r,
 		query,
 		takeLimit,
 		bindVar,
 		prependVars,
 		fieldNames,
+		nil,
+		nil,
 	)
 
 	if err != nil {
@@ -2242,12 +2899,14 @@ func GetFilteredResults(
 		return nil, 0, err
 	}
 
-	countReplacements, err := WhereFilter(
+	countReplacements, err := whereFilter(
 		r,
 		countQuery,
 		bindVar,
 		prependVars,
 		fieldNames,
+		nil,
+		nil,
 	)
 
 	if err != nil {
@@ -2268,6 +2927,12 @@ func GetFilteredResults(
 	return results, countResults.Total, nil
 }
 
+// GetFilteredResultsV2 is the same as GetFilteredResults but uses
+// ApplyAllV2 and WhereFilterV2 logic and also returns replacements of
+// query and count query
+//
+// Deprecated: Use GetQueriedAndCountResults, which validates filters and
+// sorts with FieldConfig
 func GetFilteredResultsV2(
 	r FormRequest,
 	query *string,
@@ -2279,22 +2944,28 @@ func GetFilteredResultsV2(
 	applyConfig *ApplyConfig,
 	db httputil.DBInterface,
 ) (httputil.Rower, int, []interface{}, []interface{}, error) {
+	httputil.Deprecated("queryutil.GetFilteredResultsV2", "queryutil.GetQueriedAndCountResults")
+
 	var rower httputil.Rower
 	var count int
 
-	replacements, err := ApplyAllV2(
+	if err := checkRegistered(query, countQuery); err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	replacements, err := applyAll(
 		r,
 		query,
 		takeLimit,
 		bindVar,
 		prependVars,
+		nil,
 		fieldNames,
 		applyConfig,
 	)
 
 	if err != nil {
-		confutil.CheckError(err, "")
-		fmt.Printf("error 1")
+		httputil.Debugf("queryutil: filtered results step 1: %s", err.Error())
 		return nil, 0, nil, nil, err
 	}
 
@@ -2306,7 +2977,7 @@ func GetFilteredResultsV2(
 		)
 
 		if err != nil {
-			fmt.Printf("error 2")
+			httputil.Debugf("queryutil: filtered results step 2: %s", err.Error())
 			return err
 		}
 
@@ -2320,7 +2991,7 @@ func GetFilteredResultsV2(
 			err = executeQuery()
 
 			if err != nil {
-				fmt.Printf("error 3")
+				httputil.Debugf("queryutil: filtered results step 3: %s", err.Error())
 				return nil, 0, nil, nil, err
 			}
 		}
@@ -2328,7 +2999,7 @@ func GetFilteredResultsV2(
 		err = executeQuery()
 
 		if err != nil {
-			fmt.Printf("error 4")
+			httputil.Debugf("queryutil: filtered results step 4: %s", err.Error())
 			return nil, 0, nil, nil, err
 		}
 	}
@@ -2339,17 +3010,18 @@ func GetFilteredResultsV2(
 		exclusionFields = applyConfig.ExclusionFields
 	}
 
-	countReplacements, err := WhereFilterV2(
+	countReplacements, err := whereFilter(
 		r,
 		countQuery,
 		bindVar,
 		prependVars,
+		nil,
 		fieldNames,
 		exclusionFields,
 	)
 
 	if err != nil {
-		fmt.Printf("error 5")
+		httputil.Debugf("queryutil: filtered results step 5: %s", err.Error())
 		return nil, 0, nil, nil, err
 	}
 
@@ -2364,7 +3036,7 @@ func GetFilteredResultsV2(
 		)
 
 		if err != nil {
-			fmt.Printf("error 6")
+			httputil.Debugf("queryutil: filtered results step 6: %s", err.Error())
 			return err
 		}
 
@@ -2378,7 +3050,7 @@ func GetFilteredResultsV2(
 			err = executeCountQuery()
 
 			if err != nil {
-				fmt.Printf("error 7")
+				httputil.Debugf("queryutil: filtered results step 7: %s", err.Error())
 				return nil, 0, nil, nil, err
 			}
 		}
@@ -2386,7 +3058,7 @@ func GetFilteredResultsV2(
 		err = executeCountQuery()
 
 		if err != nil {
-			fmt.Printf("error 8")
+			httputil.Debugf("queryutil: filtered results step 8: %s", err.Error())
 			return nil, 0, nil, nil, err
 		}
 	}
@@ -2399,6 +3071,11 @@ func CountSelect(column string) string {
 	return fmt.Sprintf("count(%s) as total", column)
 }
 
+// GeneralJSON is json column value decoded as object
+// Top level arrays are wrapped within "array" key and scalars can't be
+// scanned
+//
+// Deprecated: Use JSONValue, which preserves arrays and scalars
 type GeneralJSON map[string]interface{}
 
 func (g GeneralJSON) Value() (driver.Value, error) {
@@ -2434,22 +3111,57 @@ func (g *GeneralJSON) Scan(src interface{}) error {
 	return nil
 }
 
+// InQueryRebind expands slice args of query with sqlx.In and rebinds
+// it to bindType
+//
+// Returns *TooManyParametersError if expanded query has more parameters
+// than MaxBindParameters of bindType, so it's not sent to the database
+// only to be rejected
 func InQueryRebind(bindType int, query string, args ...interface{}) (string, []interface{}, error) {
+	return inQueryRebind(bindType, MaxBindParameters[bindType], query, args...)
+}
+
+// InQueryRebindWithDialect is the same as InQueryRebind but uses bind
+// type and max parameters of dialect
+func InQueryRebindWithDialect(dialect dbutil.Dialect, query string, args ...interface{}) (string, []interface{}, error) {
+	return inQueryRebind(dialect.BindVar, dialect.MaxParams, query, args...)
+}
+
+func inQueryRebind(bindType, maxParams int, query string, args ...interface{}) (string, []interface{}, error) {
 	query, args, err := sqlx.In(query, args...)
 
 	if err != nil {
 		return query, nil, err
 	}
+	if maxParams > 0 && len(args) > maxParams {
+		return query, nil, &TooManyParametersError{Count: len(args), Limit: maxParams}
+	}
 
 	query = sqlx.Rebind(bindType, query)
 	return query, args, nil
 }
 
+// SetRowerResults caches every row of rower, along with the whole list
+// and form selections, converting column names to camel case
+//
+// Panics if a []byte column is not numeric so SetRowerResultsV2
+// should be used for configurable conversion
+// The "id" column is the exception, which is kept as string if it's
+// not numeric so uuid primary keys can be used
+// Every value is written to cache in one round trip with CacheStore#MSet
+// WarmCacheSetup should be used instead if several instances can cache
+// the same table at once
+// Returns error if values could not be written to cache
+//
+// Deprecated: Use SetRowerResultsV2, which converts values with
+// RowerMapConfig
 func SetRowerResults(
 	rower httputil.Rower,
 	cache cacheutil.CacheStore,
 	cacheSetup cacheutil.CacheSetup,
 ) error {
+	httputil.Deprecated("queryutil.SetRowerResults", "queryutil.SetRowerResultsV2")
+
 	var err error
 	columns, err := rower.Columns()
 
@@ -2462,6 +3174,7 @@ func SetRowerResults(
 	valuePtrs := make([]interface{}, count)
 	rows := make([]interface{}, 0)
 	forms := make([]httputil.FormSelection, 0)
+	cacheValues := make(map[string]interface{})
 
 	for rower.Next() {
 		form := httputil.FormSelection{}
@@ -2501,21 +3214,18 @@ func SetRowerResults(
 				t := val.([]byte)
 				v, err = strconv.ParseFloat(string(t), confutil.IntBitSize)
 				if err != nil {
-					panic(err)
+					// Non numeric ids eg. uuids are kept as string
+					if k != "id" {
+						panic(err)
+					}
+
+					v = string(t)
 				}
 			default:
 				v = val
 			}
 
-			var columnName string
-
-			if snaker.IsInitialism(columns[i]) {
-				columnName = strings.ToLower(columns[i])
-			} else {
-				camelCaseJSON := snaker.SnakeToCamelJSON(columns[i])
-				firstLetter := strings.ToLower(string(camelCaseJSON[0]))
-				columnName = firstLetter + camelCaseJSON[1:]
-			}
+			columnName := ColumnName(columns[i], nil)
 
 			row[columnName] = v
 
@@ -2541,15 +3251,15 @@ func SetRowerResults(
 			cacheID = strconv.FormatInt(idVal.(int64), confutil.IntBase)
 		case int:
 			cacheID = strconv.Itoa(idVal.(int))
+		case string:
+			cacheID = idVal.(string)
+		case []byte:
+			cacheID = string(idVal.([]byte))
 		default:
 			return errors.New("Invalid id type")
 		}
 
-		cache.Set(
-			fmt.Sprintf(cacheSetup.CacheIDKey, cacheID),
-			rowBytes,
-			0,
-		)
+		cacheValues[fmt.Sprintf(cacheSetup.CacheIDKey, cacheID)] = rowBytes
 
 		rows = append(rows, row)
 		forms = append(forms, form)
@@ -2567,19 +3277,28 @@ func SetRowerResults(
 		return err
 	}
 
-	cache.Set(cacheSetup.CacheListKey, rowsBytes, 0)
-	cache.Set(cacheSetup.FormSelectionConf.FormSelectionKey, formBytes, 0)
-	return nil
+	cacheValues[cacheSetup.CacheListKey] = rowsBytes
+	cacheValues[cacheSetup.FormSelectionConf.FormSelectionKey] = formBytes
+	return cache.MSet(cacheValues, 0)
 }
 
+// HasFilterError writes 406 status if err is filter, sort, group,
+// fields or syntax error and 504 status if err is dbutil#ErrQueryTimeout, returning true
+// Else returns false
 func HasFilterError(w http.ResponseWriter, err error) bool {
 	switch err.(type) {
-	case *FilterError, *SortError, *GroupError:
+	case *FilterError, *SortError, *GroupError, *FieldsError, *SyntaxError:
 		w.WriteHeader(http.StatusNotAcceptable)
 		w.Write([]byte(err.Error()))
 		return true
 	}
 
+	if errors.Cause(err) == dbutil.ErrQueryTimeout {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(dbutil.ErrQueryTimeout.Error()))
+		return true
+	}
+
 	return false
 }
 
@@ -2587,11 +3306,17 @@ func replaceFields(filters []*Filter, fieldNames []string) ([]interface{}, error
 	replacements := make([]interface{}, 0)
 	for i, v := range filters {
 		containsField := false
-		filters[i].Field = snaker.CamelToSnake(filters[i].Field)
+		filters[i].Field = FieldColumn(filters[i].Field, nil)
 		for _, k := range fieldNames {
 			if v.Field == k {
 				containsField = true
 
+				if !filterOperators[v.Operator] {
+					filterErr := &FilterError{}
+					filterErr.setInvalidOperationError(v.Field)
+					return nil, filterErr
+				}
+
 				if v.Value != "" && v.Operator != "isnull" && v.Operator != "isnotnull" {
 					list, ok := v.Value.([]interface{})
 
@@ -2658,6 +3383,12 @@ func replaceFieldsV2(filters []*Filter, fieldNames map[string]string) ([]interfa
 }
 
 func filterCheckV1(f *Filter, replacements []interface{}) ([]interface{}, error) {
+	if !filterOperators[f.Operator] {
+		filterErr := &FilterError{}
+		filterErr.setInvalidOperationError(f.Field)
+		return nil, filterErr
+	}
+
 	if f.Value != "" && f.Operator != "isnull" && f.Operator != "isnotnull" {
 		// First check if value sent is slice
 		list, ok := f.Value.([]interface{})
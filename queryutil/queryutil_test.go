@@ -8,6 +8,7 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
 )
 
 type MockRower struct {
@@ -41,6 +42,15 @@ func (m *MockQuerier) QueryRow(query string, args ...interface{}) httputil.Scann
 	return m.getQueryRow(query, args...)
 }
 
+type MockBindVarQuerier struct {
+	MockQuerier
+	bindVar int
+}
+
+func (m *MockBindVarQuerier) BindVar() int {
+	return m.bindVar
+}
+
 type MockFormRequest struct{}
 
 func (m *MockFormRequest) FormValue(key string) string {
@@ -107,7 +117,7 @@ func TestDecodeFilters(t *testing.T) {
 	var err error
 
 	if filters, err = DecodeFilters(testMockRequest, "filters"); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	if len(filters) != 1 {
@@ -128,7 +138,7 @@ func TestReplaceFilterFields(t *testing.T) {
 	)
 
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	if len(r) != 1 {
@@ -145,10 +155,10 @@ func TestGetFilterReplacements(t *testing.T) {
 		testMockRequest,
 		&q,
 		"filters",
-		nil,
+		QueryConfig{},
 		testFields,
 	); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	if len(r) != 1 {
@@ -161,7 +171,7 @@ func TestDecodeGroups(t *testing.T) {
 	var err error
 
 	if groups, err = DecodeGroups(testMockRequest, "groups"); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	if len(groups) != 1 {
@@ -180,16 +190,16 @@ func TestGetGroupReplacements(t *testing.T) {
 		foo.bar
 	`
 
-	if _, err = GetGroupReplacements(testMockRequest, &q, "groups", nil, testFields); err != nil {
-		t.Fatalf(err.Error())
+	if _, err = GetGroupReplacements(testMockRequest, &q, "groups", QueryConfig{}, testFields); err != nil {
+		t.Fatal(err.Error())
 	}
 
 	if val := strings.Contains(q, "group by"); !val {
 		t.Fatalf("Query should contain 'group by' clause\n  query: %s", q)
 	}
 
-	if _, err = GetGroupReplacements(testMockRequest, &f, "groups", nil, testFields); err != nil {
-		t.Fatalf(err.Error())
+	if _, err = GetGroupReplacements(testMockRequest, &f, "groups", QueryConfig{}, testFields); err != nil {
+		t.Fatal(err.Error())
 	}
 
 	allStrings := groupExp.FindAllString(f, -1)
@@ -204,14 +214,14 @@ func TestDecodeSorts(t *testing.T) {
 	var err error
 
 	if sorts, err = DecodeSorts(testMockRequest, "sorts"); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	if len(sorts) != 1 {
 		t.Fatalf("Should have one replacement variable\n")
 	}
 
-	if sorts[0].Field != "foo.number" || sorts[0].Dir != "desc" {
+	if sorts[0].Field != "foo.dateExpired" || sorts[0].Dir != "desc" {
 		t.Fatalf("sort not properly decoded\n")
 	}
 }
@@ -227,16 +237,16 @@ func TestGetSortReplacements(t *testing.T) {
 		foo.bar desc
 	`
 
-	if _, err = GetSortReplacements(testMockRequest, &q, "sorts", nil, testFields); err != nil {
-		t.Fatalf(err.Error())
+	if _, err = GetSortReplacements(testMockRequest, &q, "sorts", QueryConfig{}, testFields); err != nil {
+		t.Fatal(err.Error())
 	}
 
 	if val := strings.Contains(q, "order by"); !val {
 		t.Fatalf("Query should contain 'order by' clause\n  query: %s", q)
 	}
 
-	if _, err = GetSortReplacements(testMockRequest, &f, "sorts", nil, testFields); err != nil {
-		t.Fatalf(err.Error())
+	if _, err = GetSortReplacements(testMockRequest, &f, "sorts", QueryConfig{}, testFields); err != nil {
+		t.Fatal(err.Error())
 	}
 
 	allStrings := sortExp.FindAllString(f, -1)
@@ -286,10 +296,12 @@ func TestGetQueriedResults(t *testing.T) {
 			// },
 		},
 	); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
-	t.Fatalf("query: %s\n", q)
+	if !groupExp.MatchString(q) || !sortExp.MatchString(q) {
+		t.Errorf("query should be grouped and sorted; got %s\n", q)
+	}
 }
 
 func TestGetLimitWithOffsetReplacements(t *testing.T) {
@@ -313,7 +325,7 @@ func TestGetLimitWithOffsetReplacements(t *testing.T) {
 	)
 
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err.Error())
 	}
 
 	_, err = GetLimitWithOffsetReplacements(
@@ -329,3 +341,72 @@ func TestGetLimitWithOffsetReplacements(t *testing.T) {
 	}
 	err = nil
 }
+
+func TestGetSortReplacementsDefaultsAndTiebreaker(t *testing.T) {
+	q := testQuery
+	queryConf := QueryConfig{
+		DefaultSorts:     []Sort{{Field: "foo.number", Dir: "asc"}},
+		TiebreakerColumn: "foo.id",
+	}
+
+	if _, err := GetSortReplacements(testMockRequest, &q, "none", queryConf, testFields); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasSuffix(q, "order by  foo.number asc, foo.id asc") {
+		t.Errorf("should apply default sort and tiebreaker\n  query: %s", q)
+	}
+
+	q = testQuery
+	queryConf.TiebreakerColumn = "foo.date_expired"
+
+	if _, err := GetSortReplacements(testMockRequest, &q, "sorts", queryConf, testFields); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasSuffix(q, "order by  foo.date_expired desc") {
+		t.Errorf("should not apply default sort or tiebreaker already sorted by\n  query: %s", q)
+	}
+}
+
+func TestInQueryRebindMaxParameters(t *testing.T) {
+	ids := make([]int, 1000)
+	query := "select * from foo where foo.id in (?)"
+
+	if _, args, err := InQueryRebind(sqlx.DOLLAR, query, ids); err != nil || len(args) != 1000 {
+		t.Fatalf("postgres should allow 1000 parameters; got %d, %v", len(args), err)
+	}
+
+	_, _, err := InQueryRebindWithDialect(dbutil.GetDialect(dbutil.Sqlite), query, ids)
+
+	tooMany, ok := err.(*TooManyParametersError)
+
+	if !ok {
+		t.Fatalf("sqlite should return TooManyParametersError; got %v", err)
+	}
+	if tooMany.Count != 1000 || tooMany.Limit != 999 {
+		t.Errorf("should have count 1000 and limit 999; got %d and %d", tooMany.Count, tooMany.Limit)
+	}
+}
+
+func TestGetCountResultsBindVarFromDB(t *testing.T) {
+	var query string
+
+	db := &MockBindVarQuerier{bindVar: sqlx.DOLLAR}
+	db.getQuery = func(q string, args ...interface{}) (httputil.Rower, error) {
+		query = q
+		return &MockRower{getNext: func() bool { return false }}, nil
+	}
+
+	q := "select count(*) from foo"
+
+	if _, err := GetCountResults(
+		&q, nil, testFields, testMockRequest, db, ParamConfig{}, QueryConfig{Dialect: dbutil.Mysql},
+	); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.Contains(query, "$1") {
+		t.Errorf("should use bind var of db over dialect\n  query: %s", query)
+	}
+}
@@ -0,0 +1,127 @@
+package queryutil
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrQueryNotRegistered is returned in strict mode of Registry when
+	// base query isn't registered
+	ErrQueryNotRegistered = errors.New("queryutil: query not registered")
+
+	// ErrFieldNotRegistered is returned in strict mode of Registry when
+	// field config passed with base query isn't the one registered
+	// with it, or allows more operations
+	ErrFieldNotRegistered = errors.New("queryutil: field not registered")
+)
+
+// Registry is registry every base query, and its fields, is checked
+// against before it's modified by url query params and executed
+// It's empty and not strict by default so nothing is refused
+var Registry = NewQueryRegistry()
+
+// QueryRegistry is allowlist of base queries along with fields that
+// can be used to filter, sort and group them
+//
+// Endpoints should register their queries at startup, eg.
+// queryutil.Registry.Register(fields, listQuery, countQuery), and
+// production should enable strict mode so any query not registered,
+// eg. one concatenated with untrusted input elsewhere in app code,
+// is refused before it reaches the database
+type QueryRegistry struct {
+	mu      sync.RWMutex
+	strict  bool
+	queries map[string]map[string]FieldConfig
+}
+
+// NewQueryRegistry returns empty registry that isn't strict
+func NewQueryRegistry() *QueryRegistry {
+	return &QueryRegistry{queries: make(map[string]map[string]FieldConfig)}
+}
+
+// Register registers queries as base queries that can be filtered,
+// sorted and grouped by fields
+// Whitespace of queries is not significant
+func (q *QueryRegistry) Register(fields map[string]FieldConfig, queries ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, query := range queries {
+		q.queries[normalizeQuery(query)] = fields
+	}
+}
+
+// SetStrict sets whether unregistered queries are refused
+func (q *QueryRegistry) SetStrict(strict bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.strict = strict
+}
+
+// Strict returns whether unregistered queries are refused
+func (q *QueryRegistry) Strict() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.strict
+}
+
+// Check returns error if query isn't registered or any of fields don't
+// match the config registered with query, where operations of fields
+// can be a subset of the registered ones
+// Always returns nil if registry isn't strict
+func (q *QueryRegistry) Check(query string, fields map[string]FieldConfig) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if !q.strict {
+		return nil
+	}
+
+	registered, ok := q.queries[normalizeQuery(query)]
+
+	if !ok {
+		return errors.Wrapf(ErrQueryNotRegistered, "%q", query)
+	}
+
+	for name, field := range fields {
+		conf, ok := registered[name]
+
+		if !ok ||
+			conf.DBField != field.DBField ||
+			conf.Expression != field.Expression ||
+			conf.Window != field.Window ||
+			(field.OperationConf.CanFilterBy && !conf.OperationConf.CanFilterBy) ||
+			(field.OperationConf.CanSortBy && !conf.OperationConf.CanSortBy) ||
+			(field.OperationConf.CanGroupBy && !conf.OperationConf.CanGroupBy) ||
+			(field.OperationConf.CanSelect && !conf.OperationConf.CanSelect) {
+			return errors.Wrapf(ErrFieldNotRegistered, "'%s' for %q", name, query)
+		}
+	}
+
+	return nil
+}
+
+// checkRegistered checks base queries of functions that don't take
+// field configs against Registry
+func checkRegistered(queries ...*string) error {
+	for _, query := range queries {
+		if query == nil {
+			continue
+		}
+		if err := Registry.Check(*query, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeQuery collapses whitespace of query
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
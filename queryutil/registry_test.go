@@ -0,0 +1,52 @@
+package queryutil
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestQueryRegistry(t *testing.T) {
+	defer func(registry *QueryRegistry) { Registry = registry }(Registry)
+
+	Registry = NewQueryRegistry()
+
+	fields := map[string]FieldConfig{
+		"foo.name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+		},
+	}
+	query := "select foo.id, foo.name\n\tfrom foo"
+	r := mapFormRequest{"sorts": `[{"field": "foo.name", "dir": "desc"}]`}
+
+	build := func(query string, fields map[string]FieldConfig) error {
+		_, _, err := BuildQuery(query, nil, fields, r, ParamConfig{}, QueryConfig{})
+		return err
+	}
+
+	if err := build("select * from foo where name = 'bar'", fields); err != nil {
+		t.Fatalf("should not refuse queries unless strict; got %s", err.Error())
+	}
+
+	Registry.Register(fields, query)
+	Registry.SetStrict(true)
+
+	if err := build("select foo.id, foo.name from foo", fields); err != nil {
+		t.Fatalf("should allow registered query; got %s", err.Error())
+	}
+	if err := build(query+" where name = 'bar'", fields); errors.Cause(err) != ErrQueryNotRegistered {
+		t.Errorf("should refuse unregistered query; got %v", err)
+	}
+
+	grouped := map[string]FieldConfig{
+		"foo.name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanSortBy: true, CanGroupBy: true},
+		},
+	}
+
+	if err := build(query, grouped); errors.Cause(err) != ErrFieldNotRegistered {
+		t.Errorf("should refuse operations not registered; got %v", err)
+	}
+}
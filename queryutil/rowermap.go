@@ -0,0 +1,415 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/idutil"
+)
+
+// ColumnType is type hint for column used to convert values returned
+// from database
+type ColumnType int
+
+const (
+	// ColumnDefault converts value based on ByteHandling and
+	// RowerMapConfig#Int64AsString
+	ColumnDefault ColumnType = iota
+	ColumnString
+	ColumnInt
+	ColumnFloat
+	ColumnBool
+
+	// ColumnJSON leaves value as raw json so it's not encoded as string
+	ColumnJSON
+)
+
+// ByteHandling determines how []byte values, which is how some drivers
+// return numeric and text columns, are converted when there is no
+// column type hint
+type ByteHandling int
+
+const (
+	// BytesAsNumberOrString converts to float64 if numeric, else string
+	BytesAsNumberOrString ByteHandling = iota
+
+	// BytesAsString always converts to string
+	BytesAsString
+
+	// BytesAsNumber converts to float64 and returns error if not numeric
+	// This is the behaviour of SetRowerResults
+	BytesAsNumber
+)
+
+// ColumnConverter converts value of column returned from database
+type ColumnConverter func(val interface{}) (interface{}, error)
+
+// RowerMapConfig is config struct used in conjunction with RowerToMaps
+// and SetRowerResultsV2
+type RowerMapConfig struct {
+	// Naming is strategy used to convert column names
+	// Default is DefaultNaming
+	Naming NamingStrategy
+
+	// ColumnTypes are type hints of values keyed by column name
+	// as returned from database
+	ColumnTypes map[string]ColumnType
+
+	// Bytes determines how []byte values are converted for columns
+	// without type hint
+	// Default is BytesAsNumberOrString
+	Bytes ByteHandling
+
+	// Int64AsString converts int64 values to strings, so they don't
+	// lose precision in javascript, for columns without type hint
+	Int64AsString bool
+
+	// Converters are custom converters keyed by column name as
+	// returned from database
+	// Converters take precedence over ColumnTypes
+	Converters map[string]ColumnConverter
+
+	// FieldPolicy, if set, leaves out columns not visible to Groups
+	// where fields of policy are converted column names
+	FieldPolicy FieldPolicy
+
+	// Groups are groups of user rows are returned to, used with
+	// FieldPolicy
+	Groups []string
+
+	// Fields, if set, are the only keys of rows returned, as converted
+	// column names, eg. fields returned by GetFieldsReplacements
+	// Fields qualified with table, eg. "foo.statusID", match by name
+	// after the table
+	Fields []string
+
+	// IDCodec, if set, encodes values of IDColumns so database ids
+	// aren't exposed
+	// Cache keys of SetRowerResultsV2 use the encoded ids
+	IDCodec idutil.Codec
+
+	// IDColumns are columns, as returned from database, encoded with
+	// IDCodec eg. "id" and foreign keys like "user_id"
+	// Default is "id"
+	IDColumns []string
+}
+
+func (r RowerMapConfig) isIDColumn(column string) bool {
+	if r.IDCodec == nil {
+		return false
+	}
+	if len(r.IDColumns) == 0 {
+		return column == "id"
+	}
+
+	for _, c := range r.IDColumns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selected returns set of Fields by name, or nil if Fields is not set
+func (r RowerMapConfig) selected() map[string]bool {
+	if len(r.Fields) == 0 {
+		return nil
+	}
+
+	selected := make(map[string]bool, len(r.Fields))
+
+	for _, f := range r.Fields {
+		selected[fieldName(f)] = true
+	}
+
+	return selected
+}
+
+// RowerToMaps scans every row of rower into map of converted column
+// name to value based on config
+// Returns error if value can't be converted
+func RowerToMaps(rower httputil.Rower, config RowerMapConfig) ([]map[string]interface{}, error) {
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	names := make([]string, len(columns))
+	selected := config.selected()
+
+	for i, c := range columns {
+		names[i] = ColumnName(c, config.Naming)
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	rows := make([]map[string]interface{}, 0)
+
+	for rower.Next() {
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err = rower.Scan(valuePtrs...); err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		row := make(map[string]interface{}, len(columns))
+
+		for i, c := range columns {
+			if !config.FieldPolicy.Allowed(names[i], config.Groups) {
+				continue
+			}
+			if selected != nil && !selected[names[i]] {
+				continue
+			}
+			if row[names[i]], err = convertColumn(c, values[i], config); err != nil {
+				return nil, err
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func convertColumn(column string, val interface{}, config RowerMapConfig) (interface{}, error) {
+	if converter, ok := config.Converters[column]; ok {
+		v, err := converter(val)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "queryutil: converting column '%s'", column)
+		}
+
+		return v, nil
+	}
+
+	if config.isIDColumn(column) {
+		v, err := idutil.EncodeValue(config.IDCodec, val)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "queryutil: converting column '%s'", column)
+		}
+
+		return v, nil
+	}
+
+	if val == nil {
+		return nil, nil
+	}
+
+	b, isBytes := val.([]byte)
+	columnErr := func(err error) error {
+		return fmt.Errorf("queryutil: converting column '%s': %s", column, err.Error())
+	}
+
+	switch config.ColumnTypes[column] {
+	case ColumnString:
+		if isBytes {
+			return string(b), nil
+		}
+
+		return fmt.Sprint(val), nil
+	case ColumnInt:
+		switch v := val.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		}
+
+		i, err := strconv.ParseInt(bytesOrString(val), confutil.IntBase, confutil.IntBitSize)
+
+		if err != nil {
+			return nil, columnErr(err)
+		}
+
+		return i, nil
+	case ColumnFloat:
+		switch v := val.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+
+		f, err := strconv.ParseFloat(bytesOrString(val), confutil.IntBitSize)
+
+		if err != nil {
+			return nil, columnErr(err)
+		}
+
+		return f, nil
+	case ColumnBool:
+		if v, ok := val.(bool); ok {
+			return v, nil
+		}
+
+		bo, err := strconv.ParseBool(bytesOrString(val))
+
+		if err != nil {
+			return nil, columnErr(err)
+		}
+
+		return bo, nil
+	case ColumnJSON:
+		raw := []byte(bytesOrString(val))
+
+		if !json.Valid(raw) {
+			return nil, columnErr(errors.New("invalid json"))
+		}
+
+		return json.RawMessage(raw), nil
+	}
+
+	switch v := val.(type) {
+	case int64:
+		if config.Int64AsString {
+			return strconv.FormatInt(v, confutil.IntBase), nil
+		}
+	case []byte:
+		if config.Bytes == BytesAsString {
+			return string(v), nil
+		}
+
+		f, err := strconv.ParseFloat(string(v), confutil.IntBitSize)
+
+		if err != nil {
+			if config.Bytes == BytesAsNumber {
+				return nil, columnErr(err)
+			}
+
+			return string(v), nil
+		}
+
+		return f, nil
+	}
+
+	return val, nil
+}
+
+func bytesOrString(val interface{}) string {
+	if b, ok := val.([]byte); ok {
+		return string(b)
+	}
+
+	return fmt.Sprint(val)
+}
+
+// SetRowerResultsV2 is the same as SetRowerResults but converts rows
+// based on config and returns errors instead of panicking
+// Every value is written to cache in one round trip with CacheStore#MSet
+// WarmCacheSetup should be used instead if several instances can cache
+// the same table at once
+// Returns error if values could not be written to cache
+//
+// Rows should have an "id" column which is used for the cache key of
+// each row
+// If cacheSetup#FormSelectionConf is nil, form selections are not cached
+func SetRowerResultsV2(
+	rower httputil.Rower,
+	cache cacheutil.CacheStore,
+	cacheSetup cacheutil.CacheSetup,
+	config RowerMapConfig,
+) error {
+	rows, err := RowerToMaps(rower, config)
+
+	if err != nil {
+		return err
+	}
+
+	cacheValues, _, err := rowsCacheValues(rows, cacheSetup, config)
+
+	if err != nil {
+		return err
+	}
+
+	if err = cache.MSet(cacheValues, 0); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+// rowsCacheValues returns values of rows to cache keyed by their keys of
+// cacheSetup, along with the per id keys of rows
+func rowsCacheValues(
+	rows []map[string]interface{},
+	cacheSetup cacheutil.CacheSetup,
+	config RowerMapConfig,
+) (map[string]interface{}, []string, error) {
+	idColumn := ColumnName("id", config.Naming)
+	forms := make([]httputil.FormSelection, 0, len(rows))
+	cacheValues := make(map[string]interface{}, len(rows)+2)
+	idKeys := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		cacheID, err := rowCacheID(row[idColumn])
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rowBytes, err := json.Marshal(row)
+
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "")
+		}
+
+		key := fmt.Sprintf(cacheSetup.CacheIDKey, cacheID)
+		cacheValues[key] = rowBytes
+		idKeys = append(idKeys, key)
+
+		if cacheSetup.FormSelectionConf != nil {
+			forms = append(forms, httputil.FormSelection{
+				Text:  row[cacheSetup.FormSelectionConf.TextColumn],
+				Value: row[cacheSetup.FormSelectionConf.ValueColumn],
+			})
+		}
+	}
+
+	rowsBytes, err := json.Marshal(rows)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+
+	cacheValues[cacheSetup.CacheListKey] = rowsBytes
+
+	if cacheSetup.FormSelectionConf != nil {
+		formBytes, err := json.Marshal(forms)
+
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "")
+		}
+
+		cacheValues[cacheSetup.FormSelectionConf.FormSelectionKey] = formBytes
+	}
+
+	return cacheValues, idKeys, nil
+}
+
+// rowCacheID returns id of row used in its cache key
+func rowCacheID(id interface{}) (string, error) {
+	switch v := id.(type) {
+	case int64:
+		return strconv.FormatInt(v, confutil.IntBase), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, confutil.IntBitSize), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("queryutil: invalid id type %T", id)
+	}
+}
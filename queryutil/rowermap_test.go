@@ -0,0 +1,140 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestRowerToMaps(t *testing.T) {
+	rows := dbtest.NewRows("id", "user_name", "total", "active", "meta").
+		AddRow(int64(1), []byte("foo"), []byte("1.5"), []byte("t"), []byte(`{"a":1}`))
+
+	results, err := RowerToMaps(rows, RowerMapConfig{
+		ColumnTypes: map[string]ColumnType{
+			"active": ColumnBool,
+			"meta":   ColumnJSON,
+		},
+		Int64AsString: true,
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	row := results[0]
+
+	if row["id"] != "1" || row["userName"] != "foo" || row["total"] != 1.5 || row["active"] != true {
+		t.Errorf("unexpected row; got %v\n", row)
+	}
+	if string(row["meta"].(json.RawMessage)) != `{"a":1}` {
+		t.Errorf("should leave json as is; got %v\n", row["meta"])
+	}
+
+	rows = dbtest.NewRows("user_name").AddRow([]byte("foo"))
+
+	if _, err = RowerToMaps(rows, RowerMapConfig{Bytes: BytesAsNumber}); err == nil {
+		t.Errorf("should return error for non numeric bytes\n")
+	}
+
+	rows = dbtest.NewRows("user_name").AddRow([]byte("foo"))
+
+	if results, err = RowerToMaps(rows, RowerMapConfig{Naming: AsIsNaming}); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if results[0]["user_name"] != "foo" {
+		t.Errorf("should keep column name; got %v\n", results[0])
+	}
+
+	rows = dbtest.NewRows("id", "user_id").AddRow(int64(1), int64(2))
+	results, err = RowerToMaps(rows, RowerMapConfig{
+		IDCodec:   prefixCodec{},
+		IDColumns: []string{"id", "user_id"},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if results[0]["id"] != "x1" || results[0]["userID"] != "x2" {
+		t.Errorf("should encode id columns; got %v\n", results[0])
+	}
+}
+
+// prefixCodec is idutil#Codec that prefixes ids with "x"
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(id int64) (string, error) {
+	return "x" + strconv.FormatInt(id, 10), nil
+}
+
+func (prefixCodec) Decode(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(s, "x"), 10, 64)
+}
+
+func TestSetRowerResultsV2(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	rows := dbtest.NewRows("id", "name").
+		AddRow(int64(1), []byte("foo")).
+		AddRow(int64(2), []byte("bar"))
+
+	err := SetRowerResultsV2(rows, cache, cacheutil.CacheSetup{
+		CacheIDKey:   "item-%s",
+		CacheListKey: "items",
+		FormSelectionConf: &cacheutil.FormSelectionConfig{
+			TextColumn:       "name",
+			ValueColumn:      "id",
+			FormSelectionKey: "items-selection",
+		},
+	}, RowerMapConfig{})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	for _, key := range []string{"item-1", "item-2", "items", "items-selection"} {
+		if ok, _ := cache.HasKey(key); !ok {
+			t.Errorf("should have cached key %s\n", key)
+		}
+	}
+
+	rows = dbtest.NewRows("name").AddRow([]byte("foo"))
+
+	if err = SetRowerResultsV2(rows, cache, cacheutil.CacheSetup{}, RowerMapConfig{}); err == nil {
+		t.Errorf("should return error without id column\n")
+	}
+}
+
+func TestSetRowerResultsUUID(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	rows := dbtest.NewRows("id", "name").
+		AddRow([]byte("0f8fad5b-d9cb-469f-a165-70867728950e"), "foo").
+		AddRow("7c9e6679-7425-40de-944b-e07fc1f90ae7", "bar")
+
+	err := SetRowerResults(rows, cache, cacheutil.CacheSetup{
+		CacheIDKey:   "item-%s",
+		CacheListKey: "items",
+		FormSelectionConf: &cacheutil.FormSelectionConfig{
+			TextColumn:       "name",
+			ValueColumn:      "id",
+			FormSelectionKey: "items-selection",
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	for _, key := range []string{
+		"item-0f8fad5b-d9cb-469f-a165-70867728950e",
+		"item-7c9e6679-7425-40de-944b-e07fc1f90ae7",
+	} {
+		if ok, _ := cache.HasKey(key); !ok {
+			t.Errorf("should have cached key %s\n", key)
+		}
+	}
+}
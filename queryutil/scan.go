@@ -0,0 +1,198 @@
+package queryutil
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+var (
+	// ErrInvalidScanDest is returned from ScanRowerInto if dest is not
+	// pointer to struct or pointer to slice of structs
+	ErrInvalidScanDest = errors.New("queryutil: dest must be pointer to struct or pointer to slice of structs")
+)
+
+// ScanRowerInto scans rower into dest, which can be pointer to struct
+// or pointer to slice of structs or struct pointers
+//
+// If dest is pointer to struct, only the next row is scanned and
+// sql.ErrNoRows is returned if there are no rows
+// If dest is pointer to slice, every row is appended to the slice
+//
+// Columns are matched to fields by the "db" tag, the same tag used by
+// sqlx, else the "json" tag, else the lowercased field name
+// Fields of embedded structs are matched as if they belonged to the
+// outer struct and columns without a matching field are discarded
+func ScanRowerInto(dest interface{}, rower httputil.Rower) error {
+	destVal := reflect.ValueOf(dest)
+
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return ErrInvalidScanDest
+	}
+
+	destVal = destVal.Elem()
+
+	columns, err := rower.Columns()
+
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	switch destVal.Kind() {
+	case reflect.Struct:
+		if !rower.Next() {
+			if err = dbutil.RowerErr(rower); err != nil {
+				return err
+			}
+
+			return sql.ErrNoRows
+		}
+
+		return scanStruct(destVal, columns, rower)
+	case reflect.Slice:
+		elemType := destVal.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+
+		if isPtr {
+			elemType = elemType.Elem()
+		}
+
+		if elemType.Kind() != reflect.Struct {
+			return ErrInvalidScanDest
+		}
+
+		for rower.Next() {
+			elem := reflect.New(elemType)
+
+			if err = scanStruct(elem.Elem(), columns, rower); err != nil {
+				return err
+			}
+
+			if isPtr {
+				destVal.Set(reflect.Append(destVal, elem))
+			} else {
+				destVal.Set(reflect.Append(destVal, elem.Elem()))
+			}
+		}
+
+		return dbutil.RowerErr(rower)
+	}
+
+	return ErrInvalidScanDest
+}
+
+// CollectRows scans every row of rower into a slice of T, which
+// should be a struct, using the same rules as ScanRowerInto
+func CollectRows[T any](rower httputil.Rower) ([]T, error) {
+	dest := make([]T, 0)
+
+	if err := ScanRowerInto(&dest, rower); err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// scanStruct scans current row of rower into fields of structVal
+// that match columns
+func scanStruct(structVal reflect.Value, columns []string, rower httputil.Rower) error {
+	fields := structFields(structVal.Type())
+	dests := make([]interface{}, len(columns))
+
+	for i, c := range columns {
+		idx, ok := fields[c]
+
+		if !ok {
+			idx, ok = fields[strings.ToLower(c)]
+		}
+
+		if !ok {
+			var discard interface{}
+			dests[i] = &discard
+			continue
+		}
+
+		dests[i] = fieldByIndex(structVal, idx).Addr().Interface()
+	}
+
+	return rower.Scan(dests...)
+}
+
+// structFields returns map of column name to field index of t
+func structFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIdx := append(append([]int{}, index...), i)
+			name := tagName(field.Tag.Get("db"))
+
+			if name == "" {
+				name = tagName(field.Tag.Get("json"))
+			}
+			if name == "-" {
+				continue
+			}
+
+			fieldType := field.Type
+
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+				walk(fieldType, fieldIdx)
+				continue
+			}
+
+			if field.PkgPath != "" {
+				continue
+			}
+
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+
+			// Fields of outer struct take precedence over embedded ones
+			if existing, ok := fields[name]; !ok || len(fieldIdx) < len(existing) {
+				fields[name] = fieldIdx
+			}
+		}
+	}
+
+	walk(t, nil)
+	return fields
+}
+
+// fieldByIndex is the same as reflect.Value#FieldByIndex but allocates
+// nil embedded struct pointers
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(idx)
+	}
+
+	return v
+}
+
+func tagName(tag string) string {
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		return tag[:idx]
+	}
+
+	return tag
+}
@@ -0,0 +1,55 @@
+package queryutil
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+type scanBase struct {
+	ID int `db:"id"`
+}
+
+type scanUser struct {
+	scanBase
+	Name  string `json:"name"`
+	Email string
+	Skip  string `db:"-"`
+}
+
+func TestScanRowerInto(t *testing.T) {
+	var users []*scanUser
+
+	rows := dbtest.NewRows("id", "name", "EMAIL", "unknown").
+		AddRow(1, "foo", "foo@email.com", "x").
+		AddRow(2, "bar", "bar@email.com", "y")
+
+	if err := ScanRowerInto(&users, rows); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("should have 2 users; got %d\n", len(users))
+	}
+	if users[1].ID != 2 || users[1].Name != "bar" || users[1].Email != "bar@email.com" {
+		t.Errorf("unexpected user; got %+v\n", users[1])
+	}
+
+	var user scanUser
+
+	if err := ScanRowerInto(&user, dbtest.NewRows("id")); err != sql.ErrNoRows {
+		t.Errorf("should return sql.ErrNoRows; got %v\n", err)
+	}
+	if err := ScanRowerInto(user, dbtest.NewRows("id")); err != ErrInvalidScanDest {
+		t.Errorf("should return ErrInvalidScanDest; got %v\n", err)
+	}
+
+	collected, err := CollectRows[scanUser](dbtest.NewRows("id", "name").AddRow(3, "baz"))
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if len(collected) != 1 || collected[0].ID != 3 || collected[0].Name != "baz" {
+		t.Errorf("unexpected rows; got %+v\n", collected)
+	}
+}
@@ -0,0 +1,320 @@
+package queryutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+// Bucket is size of time buckets of Series
+type Bucket string
+
+const (
+	BucketHour  Bucket = "hour"
+	BucketDay   Bucket = "day"
+	BucketWeek  Bucket = "week"
+	BucketMonth Bucket = "month"
+
+	// SeriesBucketColumn is column of series query that holds start of
+	// each bucket
+	SeriesBucketColumn = "bucket"
+)
+
+var (
+	// ErrInvalidBucket is returned if bucket of SeriesConfig is not one
+	// of the Bucket constants
+	ErrInvalidBucket = errors.New("queryutil: invalid bucket")
+
+	// ErrInvalidSeriesRange is returned if end of SeriesConfig is not
+	// after start
+	ErrInvalidSeriesRange = errors.New("queryutil: series end must be after start")
+)
+
+// SeriesAggregate is aggregate computed for every bucket of Series
+type SeriesAggregate struct {
+	// Name is key of value within SeriesPoint#Values
+	Name string
+
+	// Expression is aggregate sql expression eg. "count(*)" or
+	// "sum(total)" where columns are columns of SeriesConfig#Query
+	Expression string
+}
+
+// SeriesConfig is config used with SeriesQuery and GetSeries
+type SeriesConfig struct {
+	// Query is query of rows to aggregate which is used as subquery
+	// eg. "select created_at, total from orders where status = ?"
+	Query string
+
+	// Args are args of placeholders within Query
+	Args []interface{}
+
+	// DateColumn is column of Query that rows are bucketed by
+	DateColumn string
+
+	// Bucket is size of buckets
+	// Default is BucketDay
+	Bucket Bucket
+
+	// Timezone is location buckets are computed in, so days start at
+	// midnight of users eg. "America/New_York"
+	// Default is UTC
+	Timezone string
+
+	// Aggregates are values computed for every bucket
+	// Default is count of rows as "count"
+	Aggregates []SeriesAggregate
+
+	// Start and End is range of series where Start is truncated to its
+	// bucket and End is exclusive
+	Start time.Time
+	End   time.Time
+
+	// SQLBindVar is bind var of returned query
+	// Default is bind var of db if it implements dbutil#BindVarProvider,
+	// else sqlx.DOLLAR
+	SQLBindVar *int
+}
+
+// SeriesPoint is single bucket of Series
+type SeriesPoint struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// Series is buckets, without gaps, in ascending order
+type Series []SeriesPoint
+
+// Labels returns time of every point formatted with layout
+func (s Series) Labels(layout string) []string {
+	labels := make([]string, 0, len(s))
+
+	for _, p := range s {
+		labels = append(labels, p.Time.Format(layout))
+	}
+
+	return labels
+}
+
+// Values returns value of aggregate name of every point
+func (s Series) Values(name string) []float64 {
+	values := make([]float64, 0, len(s))
+
+	for _, p := range s {
+		values = append(values, p.Values[name])
+	}
+
+	return values
+}
+
+func (s *SeriesConfig) setDefaults() {
+	if s.Bucket == "" {
+		s.Bucket = BucketDay
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if len(s.Aggregates) == 0 {
+		s.Aggregates = []SeriesAggregate{{Name: "count", Expression: "count(*)"}}
+	}
+}
+
+// SeriesQuery returns postgres query, and its args, that groups rows
+// of config by date_trunc of bucket in timezone between start and end
+// Only buckets with rows are returned so GetSeries should be used to
+// fill gaps
+func SeriesQuery(config SeriesConfig) (string, []interface{}, error) {
+	config.setDefaults()
+
+	switch config.Bucket {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		return "", nil, ErrInvalidBucket
+	}
+
+	if !config.End.After(config.Start) {
+		return "", nil, ErrInvalidSeriesRange
+	}
+	if !dbutil.ValidIdentifier(config.DateColumn) {
+		return "", nil, errors.Wrap(ErrInvalidIdentifier, fmt.Sprintf("date column '%s'", config.DateColumn))
+	}
+
+	selects := make([]string, 0, len(config.Aggregates))
+
+	for i, a := range config.Aggregates {
+		selects = append(selects, fmt.Sprintf("%s as agg_%d", a.Expression, i))
+	}
+
+	query := fmt.Sprintf(
+		"select date_trunc('%s', series_query.%s at time zone ?) as %s, %s "+
+			"from (%s) series_query "+
+			"where series_query.%s >= ? and series_query.%s < ? "+
+			"group by 1 order by 1",
+		config.Bucket,
+		config.DateColumn,
+		SeriesBucketColumn,
+		strings.Join(selects, ", "),
+		config.Query,
+		config.DateColumn,
+		config.DateColumn,
+	)
+
+	args := make([]interface{}, 0, len(config.Args)+3)
+	args = append(args, config.Timezone)
+	args = append(args, config.Args...)
+	args = append(args, config.Start, config.End)
+
+	bindVar := sqlx.DOLLAR
+
+	if config.SQLBindVar != nil {
+		bindVar = *config.SQLBindVar
+	}
+
+	return sqlx.Rebind(bindVar, query), args, nil
+}
+
+// GetSeries executes SeriesQuery of config against db and returns every
+// bucket between start and end, where buckets without rows have values
+// of 0, so results can be charted as is
+func GetSeries(db httputil.Querier, config SeriesConfig) (Series, error) {
+	config.setDefaults()
+
+	if config.SQLBindVar == nil {
+		if bindVar := dbutil.BindVarOf(db); bindVar != sqlx.UNKNOWN {
+			config.SQLBindVar = &bindVar
+		}
+	}
+
+	loc, err := time.LoadLocation(config.Timezone)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	query, args, err := SeriesQuery(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rower, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	values := make(map[int64]map[string]float64)
+
+	err = EachRow(rower, func(scan httputil.Scanner) error {
+		var bucket time.Time
+		dest := make([]interface{}, len(config.Aggregates)+1)
+		aggs := make([]interface{}, len(config.Aggregates))
+		dest[0] = &bucket
+
+		for i := range aggs {
+			dest[i+1] = &aggs[i]
+		}
+
+		if err := scan.Scan(dest...); err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		// Bucket is timestamp without time zone of local wall clock
+		local := time.Date(
+			bucket.Year(), bucket.Month(), bucket.Day(),
+			bucket.Hour(), 0, 0, 0, loc,
+		)
+		point := make(map[string]float64, len(aggs))
+
+		for i, a := range config.Aggregates {
+			f, err := seriesFloat(aggs[i])
+
+			if err != nil {
+				return errors.Wrapf(err, "queryutil: aggregate '%s'", a.Name)
+			}
+
+			point[a.Name] = f
+		}
+
+		values[local.Unix()] = point
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(Series, 0)
+	end := config.End.In(loc)
+
+	for t := truncateBucket(config.Start.In(loc), config.Bucket); t.Before(end); t = nextBucket(t, config.Bucket) {
+		point, ok := values[t.Unix()]
+
+		if !ok {
+			point = make(map[string]float64, len(config.Aggregates))
+
+			for _, a := range config.Aggregates {
+				point[a.Name] = 0
+			}
+		}
+
+		series = append(series, SeriesPoint{Time: t, Values: point})
+	}
+
+	return series, nil
+}
+
+// truncateBucket returns start of bucket of t the same way as postgres
+// date_trunc where weeks start on monday
+func truncateBucket(t time.Time, bucket Bucket) time.Time {
+	switch bucket {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func nextBucket(t time.Time, bucket Bucket) time.Time {
+	switch bucket {
+	case BucketHour:
+		return truncateBucket(t.Add(time.Hour), bucket)
+	case BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case BucketMonth:
+		return t.AddDate(0, 1, 0)
+	}
+
+	return t.AddDate(0, 0, 1)
+}
+
+func seriesFloat(val interface{}) (float64, error) {
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+
+	return 0, fmt.Errorf("queryutil: invalid aggregate type %T", val)
+}
@@ -0,0 +1,91 @@
+package queryutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestGetSeries(t *testing.T) {
+	var query string
+	var args []interface{}
+
+	db := &MockQuerier{
+		getQuery: func(q string, a ...interface{}) (httputil.Rower, error) {
+			query = q
+			args = a
+			return dbtest.NewRows("bucket", "agg_0", "agg_1").
+				AddRow(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), int64(3), []byte("2.5")), nil
+		},
+	}
+
+	series, err := GetSeries(db, SeriesConfig{
+		Query:      "select created_at, total from orders where status = ?",
+		Args:       []interface{}{"paid"},
+		DateColumn: "created_at",
+		Aggregates: []SeriesAggregate{
+			{Name: "count", Expression: "count(*)"},
+			{Name: "total", Expression: "sum(total)"},
+		},
+		Start: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+
+	expectedQuery := "select date_trunc('day', series_query.created_at at time zone $1) as bucket, " +
+		"count(*) as agg_0, sum(total) as agg_1 " +
+		"from (select created_at, total from orders where status = $2) series_query " +
+		"where series_query.created_at >= $3 and series_query.created_at < $4 group by 1 order by 1"
+
+	if query != expectedQuery {
+		t.Errorf("unexpected query\n  got: %s\n  want: %s\n", query, expectedQuery)
+	}
+	if len(args) != 4 || args[0] != "UTC" || args[1] != "paid" {
+		t.Errorf("unexpected args; got %v\n", args)
+	}
+
+	if len(series) != 3 {
+		t.Fatalf("should have 3 buckets; got %d\n", len(series))
+	}
+	if !series[0].Time.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("should truncate start to bucket; got %s\n", series[0].Time)
+	}
+	if counts := series.Values("count"); counts[0] != 0 || counts[1] != 3 || counts[2] != 0 {
+		t.Errorf("should fill gaps; got %v\n", counts)
+	}
+	if series[1].Values["total"] != 2.5 {
+		t.Errorf("should have total of 2.5; got %v\n", series[1].Values["total"])
+	}
+	if labels := series.Labels("01-02"); labels[2] != "01-03" {
+		t.Errorf("should have labels; got %v\n", labels)
+	}
+
+	if _, _, err = SeriesQuery(SeriesConfig{
+		DateColumn: "created_at",
+		Bucket:     "year",
+		Start:      time.Now(),
+		End:        time.Now().Add(time.Hour),
+	}); err != ErrInvalidBucket {
+		t.Errorf("should return ErrInvalidBucket; got %v\n", err)
+	}
+}
+
+func TestTruncateBucket(t *testing.T) {
+	// Thursday
+	day := time.Date(2026, 1, 15, 13, 30, 0, 0, time.UTC)
+
+	if w := truncateBucket(day, BucketWeek); !w.Equal(time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("should truncate to monday; got %s\n", w)
+	}
+	if m := truncateBucket(day, BucketMonth); !m.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("should truncate to first of month; got %s\n", m)
+	}
+	if h := truncateBucket(day, BucketHour); !h.Equal(time.Date(2026, 1, 15, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("should truncate to hour; got %s\n", h)
+	}
+}
@@ -0,0 +1,70 @@
+package queryutil
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil"
+)
+
+var (
+	// ErrStopRows can be returned from callback of GetQueriedResultsFunc
+	// or EachRow to stop iterating rows without returning an error
+	ErrStopRows = errors.New("queryutil: stop rows")
+)
+
+// RowFunc is called for every row with scanner of the current row
+type RowFunc func(scan httputil.Scanner) error
+
+// EachRow calls fn for every row of rower, closing rower once done
+// if it can be closed
+// If fn returns ErrStopRows, iteration stops and nil is returned, while
+// other errors stop iteration and are returned as is
+func EachRow(rower httputil.Rower, fn RowFunc) error {
+	if c, ok := rower.(interface{ Close() error }); ok {
+		defer c.Close()
+	}
+
+	for rower.Next() {
+		if err := fn(rower); err != nil {
+			if err == ErrStopRows {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return dbutil.RowerErr(rower)
+}
+
+// GetQueriedResultsFunc is the same as GetQueriedResults but calls fn
+// for every row instead of returning rower, so large results eg. of
+// reports and exports can be processed one row at a time
+// See EachRow for how fn can stop iteration
+func GetQueriedResultsFunc(
+	query *string,
+	prependVars []interface{},
+	fields map[string]FieldConfig,
+	r FormRequest,
+	db httputil.Querier,
+	paramConf ParamConfig,
+	queryConf QueryConfig,
+	fn RowFunc,
+) error {
+	rower, err := GetQueriedResults(
+		query,
+		prependVars,
+		fields,
+		r,
+		db,
+		paramConf,
+		queryConf,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return EachRow(rower, fn)
+}
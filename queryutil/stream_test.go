@@ -0,0 +1,54 @@
+package queryutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestGetQueriedResultsFunc(t *testing.T) {
+	db := &MockQuerier{
+		getQuery: func(query string, args ...interface{}) (httputil.Rower, error) {
+			return dbtest.NewRows("id").AddRow(1).AddRow(2).AddRow(3), nil
+		},
+	}
+
+	var ids []int
+
+	q := "select id from foo"
+
+	if err := GetQueriedResultsFunc(
+		&q, nil, nil, mapFormRequest{}, db, ParamConfig{}, QueryConfig{},
+		func(scan httputil.Scanner) error {
+			var id int
+
+			if err := scan.Scan(&id); err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+
+			if id == 2 {
+				return ErrStopRows
+			}
+
+			return nil
+		},
+	); err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("should stop after 2 rows; got %v\n", ids)
+	}
+
+	fnErr := errors.New("fn error")
+	err := EachRow(dbtest.NewRows("id").AddRow(1), func(scan httputil.Scanner) error {
+		return fnErr
+	})
+
+	if err != fnErr {
+		t.Errorf("should return error of fn; got %v\n", err)
+	}
+}
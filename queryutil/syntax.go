@@ -0,0 +1,328 @@
+package queryutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Syntax determines which conventions query params of list queries
+// follow, see ParamConfig#Syntax
+type Syntax string
+
+// Syntaxes of ParamConfig#Syntax
+const (
+	// SyntaxDefault reads filters, sorts and groups as json arrays and
+	// take and skip as numbers from the params of ParamConfig
+	SyntaxDefault Syntax = ""
+
+	// SyntaxJSONAPI reads the filtering, sorting and pagination
+	// conventions of JSON:API which are
+	//
+	// "filter[name]=foo" filters name equal to foo, where comma
+	// separated values eg. "filter[id]=1,2" filter id within list, and
+	// "filter[name][contains]=foo" filters with any filter operator
+	// "sort=-created,name" sorts by created descending and then name
+	// "page[size]" and "page[number]", starting at 1, or "page[limit]"
+	// and "page[offset]" paginate
+	//
+	// Filter values are passed as strings
+	SyntaxJSONAPI Syntax = "jsonapi"
+
+	// SyntaxOData reads the subset of OData of "$filter", "$orderby",
+	// "$top", "$skip" and "$select", see ParseODataFilter for what
+	// "$filter" supports
+	SyntaxOData Syntax = "odata"
+)
+
+var (
+	// ErrInvalidSyntax is returned when ParamConfig#Syntax isn't one
+	// of the syntaxes
+	ErrInvalidSyntax = errors.New("queryutil: invalid syntax")
+)
+
+// SyntaxError is returned when query param of JSON:API or OData syntax
+// can't be parsed
+type SyntaxError struct {
+	// Param is name of query param eg. "$filter"
+	Param string
+
+	// Value is value of query param
+	Value string
+
+	// Reason is why value can't be parsed
+	Reason string
+}
+
+func (s *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid value '%s' for '%s': %s", s.Value, s.Param, s.Reason)
+}
+
+// syntaxRequest is FormRequest whose filter, sort, take, skip and
+// fields params are translated from another syntax
+type syntaxRequest struct {
+	FormRequest
+	values map[string]string
+}
+
+func (s *syntaxRequest) FormValue(key string) string {
+	if v, ok := s.values[key]; ok {
+		return v
+	}
+
+	return s.FormRequest.FormValue(key)
+}
+
+// TranslateRequest returns r with params of ParamConfig#Syntax of
+// paramConf translated into the filters, sorts, take, skip and fields
+// params of paramConf, so they're applied like any other list query
+// Returns r as is for SyntaxDefault
+//
+// If r is not *http.Request, JSON:API filters are only read for keys
+// of fields as other params of r can't be listed
+// Returns *SyntaxError if params can't be parsed
+func TranslateRequest(r FormRequest, paramConf ParamConfig, fields map[string]FieldConfig) (FormRequest, error) {
+	if _, ok := r.(*syntaxRequest); ok {
+		return r, nil
+	}
+
+	var filters []Filter
+	var sorts []Sort
+	var take, skip, selected string
+	var err error
+
+	switch paramConf.Syntax {
+	case SyntaxDefault:
+		return r, nil
+	case SyntaxJSONAPI:
+		if filters, err = jsonAPIFilters(r, fields); err != nil {
+			return nil, err
+		}
+		if take, skip, err = jsonAPIPage(r); err != nil {
+			return nil, err
+		}
+
+		sorts = jsonAPISorts(r.FormValue("sort"))
+		selected = r.FormValue(paramName(paramConf.Fields, "fields"))
+	case SyntaxOData:
+		if filters, err = ParseODataFilter(r.FormValue("$filter")); err != nil {
+			return nil, &SyntaxError{Param: "$filter", Value: r.FormValue("$filter"), Reason: err.Error()}
+		}
+		if sorts, err = odataOrderBy(r.FormValue("$orderby")); err != nil {
+			return nil, err
+		}
+
+		take = r.FormValue("$top")
+		skip = r.FormValue("$skip")
+		selected = r.FormValue("$select")
+	default:
+		return nil, errors.Wrapf(ErrInvalidSyntax, "'%s'", paramConf.Syntax)
+	}
+
+	takeParam, skipParam := paginationParams(paramConf)
+	values := map[string]string{
+		paramName(paramConf.Filter, "filters"): "",
+		paramName(paramConf.Sort, "sorts"):     "",
+		paramName(paramConf.Fields, "fields"):  selected,
+		takeParam:                              take,
+		skipParam:                              skip,
+	}
+
+	if len(filters) > 0 {
+		if values[paramName(paramConf.Filter, "filters")], err = encodeQueryParam(filters); err != nil {
+			return nil, err
+		}
+	}
+	if len(sorts) > 0 {
+		if values[paramName(paramConf.Sort, "sorts")], err = encodeQueryParam(sorts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &syntaxRequest{FormRequest: r, values: values}, nil
+}
+
+// encodeQueryParam encodes v the way decodeQueryParams decodes it
+func encodeQueryParam(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	return url.QueryEscape(string(b)), nil
+}
+
+// paramName returns name if set, else defaultName
+func paramName(name *string, defaultName string) string {
+	if name != nil {
+		return *name
+	}
+
+	return defaultName
+}
+
+// jsonAPIFilters returns filters of "filter[...]" params of r sorted by
+// param
+func jsonAPIFilters(r FormRequest, fields map[string]FieldConfig) ([]Filter, error) {
+	var params []string
+
+	if req, ok := r.(*http.Request); ok {
+		if err := req.ParseForm(); err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		for k := range req.Form {
+			if strings.HasPrefix(k, "filter[") {
+				params = append(params, k)
+			}
+		}
+	} else {
+		operators := FilterOperators()
+
+		for k := range fields {
+			params = append(params, "filter["+k+"]")
+
+			for _, op := range operators {
+				params = append(params, "filter["+k+"]["+op+"]")
+			}
+		}
+	}
+
+	sort.Strings(params)
+	filters := make([]Filter, 0)
+
+	for _, param := range params {
+		value := r.FormValue(param)
+
+		if value == "" {
+			continue
+		}
+
+		field, op, ok := parseJSONAPIFilter(param)
+
+		if !ok {
+			return nil, &SyntaxError{Param: param, Value: value, Reason: "expected filter[field] or filter[field][operator]"}
+		}
+
+		f := Filter{Field: field, Operator: op, Value: value}
+
+		if op == "" {
+			f.Operator = "eq"
+
+			if strings.Contains(value, ",") {
+				list := make([]interface{}, 0)
+
+				for _, v := range strings.Split(value, ",") {
+					list = append(list, v)
+				}
+
+				f.Value = list
+			}
+		}
+
+		filters = append(filters, f)
+	}
+
+	return filters, nil
+}
+
+// parseJSONAPIFilter returns field and operator of param
+// "filter[field]" or "filter[field][operator]"
+func parseJSONAPIFilter(param string) (string, string, bool) {
+	rest := strings.TrimPrefix(param, "filter[")
+	end := strings.Index(rest, "]")
+
+	if end < 1 {
+		return "", "", false
+	}
+
+	field, rest := rest[:end], rest[end+1:]
+
+	if rest == "" {
+		return field, "", true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+
+	return field, rest[1 : len(rest)-1], true
+}
+
+// jsonAPISorts returns sorts of comma separated fields, where fields
+// prefixed with "-" are sorted descending
+func jsonAPISorts(param string) []Sort {
+	sorts := make([]Sort, 0)
+
+	for _, v := range strings.Split(param, ",") {
+		v = strings.TrimSpace(v)
+
+		switch {
+		case v == "":
+		case strings.HasPrefix(v, "-"):
+			sorts = append(sorts, Sort{Field: v[1:], Dir: "desc"})
+		default:
+			sorts = append(sorts, Sort{Field: v, Dir: "asc"})
+		}
+	}
+
+	return sorts
+}
+
+// jsonAPIPage returns take and skip of "page[...]" params of r
+func jsonAPIPage(r FormRequest) (string, string, error) {
+	if size := r.FormValue("page[size]"); size != "" {
+		number := r.FormValue("page[number]")
+
+		if number == "" {
+			return size, "", nil
+		}
+
+		s, err := strconv.Atoi(size)
+
+		if err != nil || s < 0 {
+			return "", "", &SyntaxError{Param: "page[size]", Value: size, Reason: "expected non negative number"}
+		}
+
+		n, err := strconv.Atoi(number)
+
+		if err != nil || n < 1 {
+			return "", "", &SyntaxError{Param: "page[number]", Value: number, Reason: "expected number starting at 1"}
+		}
+
+		return size, strconv.Itoa((n - 1) * s), nil
+	}
+
+	if number := r.FormValue("page[number]"); number != "" {
+		return "", "", &SyntaxError{Param: "page[number]", Value: number, Reason: "page[size] is required"}
+	}
+
+	return r.FormValue("page[limit]"), r.FormValue("page[offset]"), nil
+}
+
+// odataOrderBy returns sorts of "$orderby" eg. "name desc, id"
+func odataOrderBy(param string) ([]Sort, error) {
+	sorts := make([]Sort, 0)
+
+	for _, v := range strings.Split(param, ",") {
+		parts := strings.Fields(v)
+
+		switch {
+		case len(parts) == 0:
+		case len(parts) == 1:
+			sorts = append(sorts, Sort{Field: parts[0], Dir: "asc"})
+		case len(parts) == 2 && (parts[1] == "asc" || parts[1] == "desc"):
+			sorts = append(sorts, Sort{Field: parts[0], Dir: parts[1]})
+		default:
+			return nil, &SyntaxError{Param: "$orderby", Value: param, Reason: "expected field [asc|desc]"}
+		}
+	}
+
+	return sorts, nil
+}
@@ -0,0 +1,149 @@
+package queryutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestTranslateRequest(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"id":   {DBField: "foo.id"},
+		"name": {DBField: "foo.name"},
+	}
+
+	for _, test := range []struct {
+		name    string
+		req     FormRequest
+		syntax  Syntax
+		filters []Filter
+		sorts   []Sort
+		take    string
+		skip    string
+	}{
+		{
+			name: "jsonapi",
+			req: httptest.NewRequest(
+				http.MethodGet,
+				"/foo?filter[id]=1,2&filter[name][contains]=a%2Bb&sort=-name,id&page[size]=10&page[number]=3",
+				nil,
+			),
+			syntax: SyntaxJSONAPI,
+			filters: []Filter{
+				{Field: "id", Operator: "eq", Value: []interface{}{"1", "2"}},
+				{Field: "name", Operator: "contains", Value: "a+b"},
+			},
+			sorts: []Sort{{Field: "name", Dir: "desc"}, {Field: "id", Dir: "asc"}},
+			take:  "10",
+			skip:  "20",
+		},
+		{
+			name: "jsonapi without http request",
+			req: mapFormRequest{
+				"filter[name]": "foo",
+				"filter[bar]":  "ignored",
+				"page[limit]":  "5",
+				"page[offset]": "15",
+			},
+			syntax:  SyntaxJSONAPI,
+			filters: []Filter{{Field: "name", Operator: "eq", Value: "foo"}},
+			take:    "5",
+			skip:    "15",
+		},
+		{
+			name: "odata",
+			req: mapFormRequest{
+				"$filter":  "id gt 1 and contains(name, 'o''b')",
+				"$orderby": "name desc, id",
+				"$top":     "10",
+				"$skip":    "5",
+			},
+			syntax: SyntaxOData,
+			filters: []Filter{
+				{Field: "id", Operator: "gt", Value: 1.0},
+				{Field: "name", Operator: "contains", Value: "o'b"},
+			},
+			sorts: []Sort{{Field: "name", Dir: "desc"}, {Field: "id", Dir: "asc"}},
+			take:  "10",
+			skip:  "5",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := TranslateRequest(test.req, ParamConfig{Syntax: test.syntax}, fields)
+
+			if err != nil {
+				t.Fatalf("should not return error; got %s", err.Error())
+			}
+
+			filters, err := DecodeFilters(r, "filters")
+
+			if err != nil {
+				t.Fatalf("should decode filters; got %s", err.Error())
+			}
+			if !reflect.DeepEqual(filters, test.filters) {
+				t.Errorf("got filters %v; want %v", filters, test.filters)
+			}
+
+			sorts, err := DecodeSorts(r, "sorts")
+
+			if err != nil {
+				t.Fatalf("should decode sorts; got %s", err.Error())
+			}
+			if !reflect.DeepEqual(sorts, test.sorts) {
+				t.Errorf("got sorts %v; want %v", sorts, test.sorts)
+			}
+
+			if r.FormValue("take") != test.take || r.FormValue("skip") != test.skip {
+				t.Errorf("got take %q and skip %q", r.FormValue("take"), r.FormValue("skip"))
+			}
+		})
+	}
+
+	for _, req := range []FormRequest{
+		mapFormRequest{"page[number]": "2"},
+		httptest.NewRequest(http.MethodGet, "/foo?filter[name]]=foo", nil),
+	} {
+		if _, err := TranslateRequest(req, ParamConfig{Syntax: SyntaxJSONAPI}, fields); err == nil {
+			t.Errorf("should return error for %v", req)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("should return SyntaxError; got %v", err)
+		}
+	}
+}
+
+func TestBuildQuerySyntax(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"name": {
+			DBField:       "foo.name",
+			OperationConf: OperationConfig{CanFilterBy: true, CanSortBy: true},
+		},
+	}
+
+	query, args, err := BuildQuery(
+		"select foo.id, foo.name from foo",
+		nil,
+		fields,
+		mapFormRequest{"$filter": "name eq 'bar'", "$orderby": "name desc", "$top": "10"},
+		ParamConfig{Syntax: SyntaxOData},
+		QueryConfig{Dialect: dbutil.Postgres},
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	dbtest.AssertQuery(
+		t,
+		`select foo.id, foo.name from foo
+		where foo.name = $1
+		order by foo.name desc
+		limit $2 offset $3`,
+		query,
+		[]interface{}{"bar", 10, 0},
+		args,
+	)
+}
@@ -0,0 +1,74 @@
+package queryutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// transformValue applies transform to value, or every value within
+// value if it's a list
+func transformValue(value interface{}, transform func(interface{}) (interface{}, error)) (interface{}, error) {
+	list, ok := value.([]interface{})
+
+	if !ok {
+		return transform(value)
+	}
+
+	transformed := make([]interface{}, 0, len(list))
+
+	for _, v := range list {
+		t, err := transform(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transformed = append(transformed, t)
+	}
+
+	return transformed, nil
+}
+
+// LowercaseTransform is FieldConfig#ValueTransform that lowercases
+// string values eg. for emails
+func LowercaseTransform(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s), nil
+	}
+
+	return value, nil
+}
+
+// DigitsTransform is FieldConfig#ValueTransform that strips everything
+// but digits from string values eg. for phone numbers
+func DigitsTransform(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+
+	if !ok {
+		return value, nil
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, s), nil
+}
+
+// TimeTransform returns FieldConfig#ValueTransform that parses string
+// values with layout, eg. confutil#FormDateLayout, into time.Time
+func TimeTransform(layout string) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("queryutil: expected string for layout '%s'", layout)
+		}
+
+		return time.Parse(layout, s)
+	}
+}
@@ -0,0 +1,56 @@
+package queryutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestValueTransform(t *testing.T) {
+	fields := map[string]FieldConfig{
+		"email": {
+			DBField:        "user.email",
+			OperationConf:  OperationConfig{CanFilterBy: true},
+			ValueTransform: LowercaseTransform,
+		},
+		"phone": {
+			DBField:        "user.phone",
+			OperationConf:  OperationConfig{CanFilterBy: true},
+			ValueTransform: DigitsTransform,
+		},
+		"dateCreated": {
+			DBField:        "user.date_created",
+			OperationConf:  OperationConfig{CanFilterBy: true},
+			ValueTransform: TimeTransform("01/02/2006"),
+		},
+	}
+
+	q := "select * from user where"
+	replacements, err := ReplaceFilterFields(&q, []Filter{
+		{Field: "email", Operator: "eq", Value: "Foo@Email.com"},
+		{Field: "phone", Operator: "eq", Value: []interface{}{"(555) 555-1234"}},
+		{Field: "dateCreated", Operator: "gte", Value: "01/02/2020"},
+	}, fields)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s\n", err)
+	}
+	if replacements[0] != "foo@email.com" {
+		t.Errorf("should lowercase email; got %v\n", replacements[0])
+	}
+	if list := replacements[1].([]interface{}); list[0] != "5555551234" {
+		t.Errorf("should strip phone punctuation; got %v\n", list[0])
+	}
+	if !replacements[2].(time.Time).Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("should parse date; got %v\n", replacements[2])
+	}
+
+	_, err = ReplaceFilterFields(&q, []Filter{
+		{Field: "dateCreated", Operator: "gte", Value: "invalid"},
+	}, fields)
+
+	if _, ok := errors.Cause(err).(*FilterError); !ok {
+		t.Errorf("should return FilterError; got %v\n", err)
+	}
+}
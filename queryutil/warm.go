@@ -0,0 +1,308 @@
+package queryutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// WarmConfig is config struct used for WarmCacheSetup
+type WarmConfig struct {
+	RowerMapConfig
+
+	// Lock is config of lock of table held while warming
+	Lock cacheutil.LockConfig
+}
+
+// WarmCacheSetup queries rows of table of cacheSetup with query and
+// caches them the same as SetRowerResultsV2 while holding the lock of
+// the table, cacheutil#LockKey, so instances warming the same table at
+// once can't interleave writes of different snapshots of it
+//
+// Rows are sorted by CacheSetup#OrderByColumn, if set, then by id so the
+// cached list doesn't depend on the order rows are returned in
+// Per id keys of rows in the previously cached list that are no longer
+// returned by query are deleted after the new rows are cached
+//
+// Returns cacheutil#ErrLockNotAcquired if table is being warmed by
+// someone else for longer than WarmConfig#Lock#Wait
+func WarmCacheSetup(
+	ctx context.Context,
+	db httputil.Querier,
+	cache cacheutil.CacheStore,
+	cacheSetup cacheutil.CacheSetup,
+	config WarmConfig,
+	query string,
+	args ...interface{},
+) error {
+	return cacheutil.WithLock(ctx, cache, cacheutil.LockKey(cacheSetup), config.Lock, func() error {
+		previous, err := cachedIDKeys(cache, cacheSetup, config.RowerMapConfig)
+
+		if err != nil {
+			return err
+		}
+
+		rower, err := db.Query(query, args...)
+
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		rows, err := RowerToMaps(rower, config.RowerMapConfig)
+
+		if err != nil {
+			return err
+		}
+
+		sortCacheRows(rows, cacheSetup, config.RowerMapConfig)
+		cacheValues, idKeys, err := rowsCacheValues(rows, cacheSetup, config.RowerMapConfig)
+
+		if err != nil {
+			return err
+		}
+		if err = cache.MSet(cacheValues, 0); err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		current := make(map[string]bool, len(idKeys))
+
+		for _, key := range idKeys {
+			current[key] = true
+		}
+
+		stale := make([]string, 0)
+
+		for _, key := range previous {
+			if !current[key] {
+				stale = append(stale, key)
+			}
+		}
+
+		if len(stale) > 0 {
+			cache.Del(stale...)
+		}
+
+		return nil
+	})
+}
+
+// CacheInconsistency is key of table cached by SetRowerResultsV2 or
+// WarmCacheSetup whose value doesn't match the cached list of the table
+type CacheInconsistency struct {
+	Key    string
+	Reason string
+}
+
+// CheckCacheConsistency compares cached list of table of cacheSetup with
+// its per id keys and form selections and returns every inconsistency
+// found eg. from writes of different instances interleaving
+// config should be the same as the one table was cached with
+//
+// Tables that aren't cached have no inconsistencies
+func CheckCacheConsistency(
+	cache cacheutil.CacheStore,
+	cacheSetup cacheutil.CacheSetup,
+	config RowerMapConfig,
+) ([]CacheInconsistency, error) {
+	rows, err := cachedRows(cache, cacheSetup)
+
+	if err != nil || rows == nil {
+		return nil, err
+	}
+
+	idColumn := ColumnName("id", config.Naming)
+	inconsistencies := make([]CacheInconsistency, 0)
+	keys := make([]string, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+
+	for _, row := range rows {
+		cacheID, err := rowCacheID(row[idColumn])
+
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf(cacheSetup.CacheIDKey, cacheID)
+
+		if seen[key] {
+			inconsistencies = append(inconsistencies, CacheInconsistency{
+				Key:    cacheSetup.CacheListKey,
+				Reason: fmt.Sprintf("id %s is listed more than once", cacheID),
+			})
+		}
+
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	values, err := cache.MGet(keys...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	for i, value := range values {
+		if value == nil {
+			inconsistencies = append(inconsistencies, CacheInconsistency{
+				Key:    keys[i],
+				Reason: "row is listed but not cached",
+			})
+			continue
+		}
+
+		var row map[string]interface{}
+
+		if err = json.Unmarshal(value, &row); err != nil || !reflect.DeepEqual(row, rows[i]) {
+			inconsistencies = append(inconsistencies, CacheInconsistency{
+				Key:    keys[i],
+				Reason: "row is different from the listed row",
+			})
+		}
+	}
+
+	if cacheSetup.FormSelectionConf != nil {
+		value, err := cache.Get(cacheSetup.FormSelectionConf.FormSelectionKey)
+
+		if err != nil && err != cacheutil.ErrCacheNil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		var forms []httputil.FormSelection
+
+		if value != nil {
+			if err = json.Unmarshal(value, &forms); err != nil {
+				return nil, errors.Wrap(err, "")
+			}
+		}
+		if len(forms) != len(rows) {
+			inconsistencies = append(inconsistencies, CacheInconsistency{
+				Key: cacheSetup.FormSelectionConf.FormSelectionKey,
+				Reason: fmt.Sprintf(
+					"form selections have %d items but list has %d",
+					len(forms),
+					len(rows),
+				),
+			})
+		}
+	}
+
+	return inconsistencies, nil
+}
+
+// cachedRows returns rows of cached list of cacheSetup or nil if list
+// isn't cached
+func cachedRows(cache cacheutil.CacheStore, cacheSetup cacheutil.CacheSetup) ([]map[string]interface{}, error) {
+	value, err := cache.Get(cacheSetup.CacheListKey)
+
+	if err == cacheutil.ErrCacheNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var rows []map[string]interface{}
+
+	if err = json.Unmarshal(value, &rows); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return rows, nil
+}
+
+// cachedIDKeys returns per id keys of rows of cached list of cacheSetup
+func cachedIDKeys(
+	cache cacheutil.CacheStore,
+	cacheSetup cacheutil.CacheSetup,
+	config RowerMapConfig,
+) ([]string, error) {
+	rows, err := cachedRows(cache, cacheSetup)
+
+	if err != nil {
+		return nil, err
+	}
+
+	idColumn := ColumnName("id", config.Naming)
+	keys := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		// Rows that can't be keyed weren't cached by id so are skipped
+		if cacheID, err := rowCacheID(row[idColumn]); err == nil {
+			keys = append(keys, fmt.Sprintf(cacheSetup.CacheIDKey, cacheID))
+		}
+	}
+
+	return keys, nil
+}
+
+// sortCacheRows sorts rows by CacheSetup#OrderByColumn, if set, then id
+func sortCacheRows(rows []map[string]interface{}, cacheSetup cacheutil.CacheSetup, config RowerMapConfig) {
+	idColumn := ColumnName("id", config.Naming)
+	orderColumn := ""
+
+	if cacheSetup.OrderByColumn != "" {
+		orderColumn = ColumnName(cacheSetup.OrderByColumn, config.Naming)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if orderColumn != "" {
+			if c := compareCacheValues(rows[i][orderColumn], rows[j][orderColumn]); c != 0 {
+				return c < 0
+			}
+		}
+
+		return compareCacheValues(rows[i][idColumn], rows[j][idColumn]) < 0
+	})
+}
+
+// compareCacheValues compares values of rows returned by RowerToMaps
+// where nil is first and numbers are compared numerically
+func compareCacheValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	af, aNum := cacheNumber(a)
+	bf, bNum := cacheNumber(b)
+
+	if aNum && bNum {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func cacheNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
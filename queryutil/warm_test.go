@@ -0,0 +1,130 @@
+package queryutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+)
+
+func TestWarmCacheSetup(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	db := dbtest.NewExpectDB(t)
+	setup := cacheutil.CacheSetup{
+		StringVal:     "item",
+		CacheIDKey:    "item-%s",
+		CacheListKey:  "items",
+		OrderByColumn: "name",
+		FormSelectionConf: &cacheutil.FormSelectionConfig{
+			TextColumn:       "name",
+			ValueColumn:      "id",
+			FormSelectionKey: "items-selection",
+		},
+	}
+
+	db.ExpectQuery("select id, name from item").WillReturnRows(
+		dbtest.NewRows("id", "name").
+			AddRow(int64(1), "foo").
+			AddRow(int64(2), "bar").
+			AddRow(int64(3), "bar"),
+	)
+	db.ExpectQuery("select id, name from item").WillReturnRows(
+		dbtest.NewRows("id", "name").AddRow(int64(1), "foo"),
+	)
+
+	warm := func() {
+		t.Helper()
+
+		if err := WarmCacheSetup(context.Background(), db, cache, setup, WarmConfig{}, "select id, name from item"); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+	}
+
+	warm()
+
+	list, _ := cache.Get("items")
+
+	if string(list) != `[{"id":2,"name":"bar"},{"id":3,"name":"bar"},{"id":1,"name":"foo"}]` {
+		t.Errorf("list should be sorted by name then id; got %s", list)
+	}
+
+	// Rows no longer returned are removed
+	warm()
+
+	for key, cached := range map[string]bool{"item-1": true, "item-2": false, "item-3": false} {
+		if ok, _ := cache.HasKey(key); ok != cached {
+			t.Errorf("key %s should be cached: %t", key, cached)
+		}
+	}
+
+	if ok, _ := cache.HasKey(cacheutil.LockKey(setup)); ok {
+		t.Errorf("lock should be released")
+	}
+
+	// Warming while someone else holds the lock fails without waiting
+	lock, err := cacheutil.AcquireLock(cache, cacheutil.LockKey(setup), 0)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	err = WarmCacheSetup(context.Background(), db, cache, setup, WarmConfig{}, "select id, name from item")
+
+	if err != cacheutil.ErrLockNotAcquired {
+		t.Errorf("should return ErrLockNotAcquired; got %v", err)
+	}
+
+	lock.Release()
+}
+
+func TestCheckCacheConsistency(t *testing.T) {
+	cache := cachetest.NewMemoryCache()
+	setup := cacheutil.CacheSetup{
+		CacheIDKey:   "item-%s",
+		CacheListKey: "items",
+		FormSelectionConf: &cacheutil.FormSelectionConfig{
+			TextColumn:       "name",
+			ValueColumn:      "id",
+			FormSelectionKey: "items-selection",
+		},
+	}
+
+	if results, err := CheckCacheConsistency(cache, setup, RowerMapConfig{}); err != nil || len(results) != 0 {
+		t.Fatalf("uncached table should be consistent; got %v, %v", results, err)
+	}
+
+	rows := dbtest.NewRows("id", "name").
+		AddRow(int64(1), "foo").
+		AddRow(int64(2), "bar")
+
+	if err := SetRowerResultsV2(rows, cache, setup, RowerMapConfig{}); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if results, err := CheckCacheConsistency(cache, setup, RowerMapConfig{}); err != nil || len(results) != 0 {
+		t.Fatalf("should be consistent; got %v, %v", results, err)
+	}
+
+	// Interleaved writes of another snapshot
+	cache.Set("item-1", `{"id":1,"name":"baz"}`, 0)
+	cache.Del("item-2")
+	cache.Set("items-selection", `[]`, 0)
+
+	results, err := CheckCacheConsistency(cache, setup, RowerMapConfig{})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	keys := map[string]bool{}
+
+	for _, r := range results {
+		keys[r.Key] = true
+	}
+
+	if len(results) != 3 || !keys["item-1"] || !keys["item-2"] || !keys["items-selection"] {
+		t.Errorf("should find 3 inconsistencies; got %v", results)
+	}
+}
@@ -0,0 +1,60 @@
+package httputil
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrorReporter sends errors to an error aggregator eg. Sentry so
+// production incidents aren't only written to stdout
+// Implementations should not block for long as errors are captured
+// within requests
+type ErrorReporter interface {
+	CaptureError(ctx context.Context, err error, tags map[string]string)
+}
+
+// NopReporter is ErrorReporter that discards every error
+type NopReporter struct{}
+
+// CaptureError does nothing
+func (NopReporter) CaptureError(ctx context.Context, err error, tags map[string]string) {}
+
+var (
+	// Reporter is ErrorReporter that fatal errors throughout this library
+	// are sent to eg. server errors, panics and failed database failovers
+	// Default is NopReporter
+	// Should only be set on startup
+	Reporter ErrorReporter = NopReporter{}
+)
+
+// CaptureError sends err with tags to Reporter
+// Does nothing if err is nil
+func CaptureError(ctx context.Context, err error, tags map[string]string) {
+	if err == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	Reporter.CaptureError(ctx, err, tags)
+}
+
+// PanicError is error captured when a panic is recovered
+type PanicError struct {
+	// Value is value passed to panic
+	Value interface{}
+
+	// Stack is stack trace of goroutine that panicked
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns Value if it's an error
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
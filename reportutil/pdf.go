@@ -0,0 +1,196 @@
+package reportutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/storageutil"
+)
+
+const (
+	pdfFont         = "Helvetica"
+	pdfMargin       = 10.0
+	pdfFooterOffset = -12.0
+	pdfLineHeight   = 1.6
+	pdfEllipsis     = "..."
+)
+
+// RenderPDF renders rows as pdf report to w
+// A page is added whenever rows no longer fit within the current page,
+// repeating title, header and column headers
+func RenderPDF(w io.Writer, def Definition, rows []map[string]interface{}) error {
+	pdf, err := buildPDF(def, rows)
+
+	if err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
+
+// WritePDF renders rows as pdf report and writes it to w with
+// ContentTypePDF
+// If filename is set, the report is sent as an attachment
+// Nothing is written to w if the report fails to render
+func WritePDF(w http.ResponseWriter, filename string, def Definition, rows []map[string]interface{}) error {
+	pdf, err := buildPDF(def, rows)
+
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", httputil.ContentTypePDF)
+
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+
+	return pdf.Output(w)
+}
+
+// StorePDF renders rows as pdf report and stores it as objectName
+// within bucketName
+func StorePDF(
+	storage storageutil.StorageReaderWriter,
+	bucketName string,
+	objectName string,
+	def Definition,
+	rows []map[string]interface{},
+) error {
+	var buf bytes.Buffer
+
+	if err := RenderPDF(&buf, def, rows); err != nil {
+		return err
+	}
+
+	_, err := storage.PutObject(
+		bucketName,
+		objectName,
+		&buf,
+		int64(buf.Len()),
+		minio.PutObjectOptions{ContentType: httputil.ContentTypePDF},
+	)
+
+	return err
+}
+
+func buildPDF(def Definition, rows []map[string]interface{}) (*gofpdf.Fpdf, error) {
+	def.setDefaults()
+
+	pdf := gofpdf.New(string(def.Orientation), "mm", def.PageSize, "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin*2)
+	pdf.AliasNbPages(PagesToken)
+	pdf.SetTitle(def.Title, true)
+
+	// Core fonts are cp1252 so text is translated from utf-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	widths := columnWidths(pdf, def.Columns)
+	lineHeight := def.FontSize * pdfLineHeight * 25.4 / 72
+
+	pdf.SetHeaderFunc(func() {
+		if def.Title != "" {
+			pdf.SetFont(pdfFont, "B", def.FontSize+4)
+			pdf.CellFormat(0, lineHeight*1.5, tr(def.Title), "", 1, "L", false, 0, "")
+		}
+		if def.Header != "" {
+			pdf.SetFont(pdfFont, "", def.FontSize)
+			pdf.CellFormat(0, lineHeight, tr(strings.Replace(def.Header, PageToken, fmt.Sprint(pdf.PageNo()), -1)), "", 1, "L", false, 0, "")
+		}
+
+		pdf.Ln(lineHeight / 2)
+		pdf.SetFont(pdfFont, "B", def.FontSize)
+		pdf.SetFillColor(242, 242, 242)
+
+		for i, col := range def.Columns {
+			pdf.CellFormat(widths[i], lineHeight, fitText(pdf, tr(col.Header), widths[i]), "B", 0, string(alignOrLeft(col.Align)), true, 0, "")
+		}
+
+		pdf.Ln(-1)
+		pdf.SetFont(pdfFont, "", def.FontSize)
+	})
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(pdfFooterOffset)
+		pdf.SetFont(pdfFont, "", def.FontSize-1)
+		pdf.CellFormat(0, lineHeight, tr(strings.Replace(def.Footer, PageToken, fmt.Sprint(pdf.PageNo()), -1)), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	for _, row := range rows {
+		for i, col := range def.Columns {
+			pdf.CellFormat(widths[i], lineHeight, fitText(pdf, tr(cellText(col, row)), widths[i]), "B", 0, string(alignOrLeft(col.Align)), false, 0, "")
+		}
+
+		pdf.Ln(-1)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("reportutil: %s", err.Error())
+	}
+
+	return pdf, nil
+}
+
+// columnWidths returns width of every column where columns without
+// width share the remaining width of the page
+func columnWidths(pdf *gofpdf.Fpdf, columns []Column) []float64 {
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	remaining := pageWidth - left - right
+	unset := 0
+	widths := make([]float64, len(columns))
+
+	for i, col := range columns {
+		if col.Width > 0 {
+			widths[i] = col.Width
+			remaining -= col.Width
+		} else {
+			unset++
+		}
+	}
+
+	if unset > 0 && remaining > 0 {
+		for i := range widths {
+			if widths[i] == 0 {
+				widths[i] = remaining / float64(unset)
+			}
+		}
+	}
+
+	return widths
+}
+
+// fitText truncates text with ellipsis so it fits within width
+// text should already be translated to cp1252, which is single byte,
+// so it's truncated by byte
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	// Leave room for cell padding
+	width -= 2
+
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+
+	for len(text) > 0 && pdf.GetStringWidth(text+pdfEllipsis) > width {
+		text = text[:len(text)-1]
+	}
+
+	return text + pdfEllipsis
+}
+
+func alignOrLeft(a Align) Align {
+	if a == "" {
+		return AlignLeft
+	}
+
+	return a
+}
@@ -0,0 +1,302 @@
+// Package reportutil renders rows from the database or structs as
+// paginated PDF and HTML reports eg. invoices and grid exports
+package reportutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/queryutil"
+)
+
+const (
+	// PageToken is replaced with the current page number in
+	// Definition#Header and Definition#Footer
+	PageToken = "{page}"
+
+	// PagesToken is replaced with the total number of pages in
+	// Definition#Header and Definition#Footer
+	PagesToken = "{pages}"
+
+	// DefaultFooter is footer used if Definition#Footer is empty
+	DefaultFooter = "Page " + PageToken + " of " + PagesToken
+)
+
+// Align is horizontal alignment of column values
+type Align string
+
+const (
+	AlignLeft   Align = "L"
+	AlignCenter Align = "C"
+	AlignRight  Align = "R"
+)
+
+// Orientation is page orientation of PDF reports
+type Orientation string
+
+const (
+	Portrait  Orientation = "P"
+	Landscape Orientation = "L"
+)
+
+// Column is column of report table
+type Column struct {
+	// Header is text of column header
+	Header string
+
+	// Field is key of value within rows
+	// For rows from RowsFromRower, this is the column name returned
+	// from database and for rows from RowsFromStructs, this is the json
+	// name of the field
+	Field string
+
+	// Width is width of column in millimeters for PDF reports
+	// Columns without width share the remaining width of the page equally
+	Width float64
+
+	// Align is alignment of column values
+	// Default is AlignLeft
+	Align Align
+
+	// Format converts value of column to text
+	// Default is FormatValue
+	Format func(val interface{}) string
+}
+
+// Definition defines layout of a report
+type Definition struct {
+	// Title is shown at the top of every page
+	Title string
+
+	// Header is text shown under title of every page
+	// PageToken and PagesToken are replaced with page numbers
+	Header string
+
+	// Footer is text shown at the bottom of every page
+	// PageToken and PagesToken are replaced with page numbers
+	// Default is DefaultFooter
+	Footer string
+
+	Columns []Column
+
+	// Orientation is page orientation of PDF reports
+	// Default is Portrait
+	Orientation Orientation
+
+	// PageSize is page size of PDF reports eg. "A4", "Letter"
+	// Default is "A4"
+	PageSize string
+
+	// FontSize is font size of PDF reports
+	// Default is 9
+	FontSize float64
+
+	// PageRows is number of rows per page of HTML reports, where each
+	// page is separated by a css page break when printed
+	// Default is 0 which renders every row on a single page
+	// PDF reports are paginated based on page size
+	PageRows int
+}
+
+func (d *Definition) setDefaults() {
+	if d.Footer == "" {
+		d.Footer = DefaultFooter
+	}
+	if d.Orientation == "" {
+		d.Orientation = Portrait
+	}
+	if d.PageSize == "" {
+		d.PageSize = "A4"
+	}
+	if d.FontSize <= 0 {
+		d.FontSize = 9
+	}
+}
+
+// RowsFromRower returns every row of rower keyed by column name as
+// returned from database
+func RowsFromRower(rower httputil.Rower) ([]map[string]interface{}, error) {
+	return queryutil.RowerToMaps(rower, queryutil.RowerMapConfig{
+		Naming: queryutil.AsIsNaming,
+		Bytes:  queryutil.BytesAsNumberOrString,
+	})
+}
+
+// RowsFromStructs returns every element of slice, which must be a slice
+// of structs or pointers to structs, keyed by the json name of fields
+func RowsFromStructs(slice interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+
+	sliceBytes, err := json.Marshal(slice)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(sliceBytes, &rows); err != nil {
+		return nil, fmt.Errorf("reportutil: slice must be slice of structs: %s", err.Error())
+	}
+
+	return rows, nil
+}
+
+// FormatValue is default format of column values
+// nil is empty, times are formatted as RFC3339 and whole floats are
+// formatted without decimals
+func FormatValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(val)
+}
+
+func cellText(col Column, row map[string]interface{}) string {
+	if col.Format != nil {
+		return col.Format(row[col.Field])
+	}
+
+	return FormatValue(row[col.Field])
+}
+
+func replacePageTokens(text string, page, pages int) string {
+	return strings.NewReplacer(
+		PageToken, strconv.Itoa(page),
+		PagesToken, strconv.Itoa(pages),
+	).Replace(text)
+}
+
+type htmlPage struct {
+	Header string
+	Footer string
+	Rows   [][]string
+	Last   bool
+}
+
+type htmlReport struct {
+	Title   string
+	Columns []Column
+	Pages   []htmlPage
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"align": func(a Align) string {
+		switch a {
+		case AlignCenter:
+			return "center"
+		case AlignRight:
+			return "right"
+		}
+
+		return "left"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 12px; }
+table { width: 100%; border-collapse: collapse; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px; }
+th { background: #f2f2f2; }
+.page { page-break-after: always; }
+.page.last { page-break-after: auto; }
+.footer { margin-top: 8px; color: #666; text-align: center; }
+</style>
+</head>
+<body>
+{{- $columns := .Columns}}
+{{- range .Pages}}
+<div class="page{{if .Last}} last{{end}}">
+<h1>{{$.Title}}</h1>
+{{- if .Header}}
+<p class="header">{{.Header}}</p>
+{{- end}}
+<table>
+<thead><tr>{{range $columns}}<th style="text-align: {{align .Align}}">{{.Header}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range $i, $cell := .}}<td style="text-align: {{align (index $columns $i).Align}}">{{$cell}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+<p class="footer">{{.Footer}}</p>
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders rows as html report to w
+func RenderHTML(w io.Writer, def Definition, rows []map[string]interface{}) error {
+	def.setDefaults()
+
+	pageRows := def.PageRows
+
+	if pageRows <= 0 || pageRows > len(rows) {
+		pageRows = len(rows)
+	}
+
+	pages := 1
+
+	if pageRows > 0 {
+		pages = (len(rows) + pageRows - 1) / pageRows
+	}
+
+	report := htmlReport{
+		Title:   def.Title,
+		Columns: def.Columns,
+		Pages:   make([]htmlPage, 0, pages),
+	}
+
+	for p := 0; p < pages; p++ {
+		page := htmlPage{
+			Header: replacePageTokens(def.Header, p+1, pages),
+			Footer: replacePageTokens(def.Footer, p+1, pages),
+			Last:   p == pages-1,
+		}
+
+		end := (p + 1) * pageRows
+
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		for _, row := range rows[p*pageRows : end] {
+			cells := make([]string, 0, len(def.Columns))
+
+			for _, col := range def.Columns {
+				cells = append(cells, cellText(col, row))
+			}
+
+			page.Rows = append(page.Rows, cells)
+		}
+
+		report.Pages = append(report.Pages, page)
+	}
+
+	return htmlReportTemplate.Execute(w, report)
+}
+
+// WriteHTML renders rows as html report to w with ContentTypeHTML
+func WriteHTML(w http.ResponseWriter, def Definition, rows []map[string]interface{}) error {
+	w.Header().Set("Content-Type", httputil.ContentTypeHTML)
+	return RenderHTML(w, def, rows)
+}
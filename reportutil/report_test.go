@@ -0,0 +1,122 @@
+package reportutil
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/storageutil/storagetest"
+)
+
+type invoiceLine struct {
+	Item     string  `json:"item"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+var reportDef = Definition{
+	Title:  "Invoice",
+	Header: "Customer: Fooé",
+	Columns: []Column{
+		{Header: "Item", Field: "item"},
+		{Header: "Qty", Field: "quantity", Width: 20, Align: AlignRight},
+		{Header: "Price", Field: "price", Width: 30, Align: AlignRight},
+	},
+	PageRows: 2,
+}
+
+func invoiceRows(t *testing.T, count int) []map[string]interface{} {
+	lines := make([]invoiceLine, 0, count)
+
+	for i := 0; i < count; i++ {
+		lines = append(lines, invoiceLine{Item: "<widget>", Quantity: i + 1, Price: 9.5})
+	}
+
+	rows, err := RowsFromStructs(lines)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	return rows
+}
+
+func TestRenderHTML(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := RenderHTML(&buf, reportDef, invoiceRows(t, 3)); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	html := buf.String()
+
+	if strings.Count(html, `class="page`) != 2 {
+		t.Errorf("should have 2 pages")
+	}
+	if !strings.Contains(html, "Page 2 of 2") {
+		t.Errorf("should have page numbers in footer")
+	}
+	if !strings.Contains(html, "&lt;widget&gt;") || !strings.Contains(html, "9.5") {
+		t.Errorf("should have escaped row values")
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := RenderPDF(&buf, reportDef, invoiceRows(t, 200)); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Errorf("should render pdf")
+	}
+	if bytes.Count(buf.Bytes(), []byte("/Type /Page\n")) < 2 {
+		t.Errorf("should add pages when rows don't fit")
+	}
+
+	rr := httptest.NewRecorder()
+
+	if err := WritePDF(rr, "invoice.pdf", reportDef, invoiceRows(t, 1)); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if rr.Header().Get("Content-Type") != httputil.ContentTypePDF {
+		t.Errorf("got content type %s", rr.Header().Get("Content-Type"))
+	}
+	if rr.Header().Get("Content-Disposition") != `attachment; filename="invoice.pdf"` {
+		t.Errorf("got content disposition %s", rr.Header().Get("Content-Disposition"))
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d", rr.Code)
+	}
+}
+
+func TestStorePDF(t *testing.T) {
+	var stored []byte
+
+	storage := &storagetest.MockStorageReaderWriter{
+		PutObjectFunc: func(bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (int64, error) {
+			stored, _ = ioutil.ReadAll(reader)
+
+			if opts.ContentType != httputil.ContentTypePDF {
+				t.Errorf("got content type %s", opts.ContentType)
+			}
+
+			return objectSize, nil
+		},
+	}
+
+	if err := StorePDF(storage, "reports", "invoice.pdf", reportDef, invoiceRows(t, 1)); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if !bytes.HasPrefix(stored, []byte("%PDF")) {
+		t.Errorf("should store pdf")
+	}
+}
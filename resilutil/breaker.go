@@ -0,0 +1,245 @@
+// Package resilutil keeps outages of external dependencies, eg. redis,
+// smtp and s3, from stalling requests by failing calls fast with
+// circuit breakers once the dependency keeps failing
+package resilutil
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State is state of Breaker
+type State int
+
+const (
+	// StateClosed lets every call through while counting failures
+	StateClosed State = iota
+
+	// StateOpen rejects every call with ErrOpen until
+	// BreakerConfig#OpenTimeout has passed
+	StateOpen
+
+	// StateHalfOpen lets a limited number of trial calls through to
+	// determine if the dependency has recovered
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+// ErrOpen is returned from calls rejected by Breaker
+var ErrOpen = errors.New("resilutil: circuit breaker is open")
+
+// BreakerConfig is config struct used for Breaker
+type BreakerConfig struct {
+	// Name is name of dependency passed to hooks eg. "redis"
+	Name string
+
+	// FailureThreshold is number of consecutive failures after which
+	// breaker opens
+	// Default is 5
+	FailureThreshold int
+
+	// OpenTimeout is how long breaker stays open before letting trial
+	// calls through
+	// Default is 30 seconds
+	OpenTimeout time.Duration
+
+	// HalfOpenMaxCalls is max number of trial calls let through at once
+	// while half open
+	// Default is 1
+	HalfOpenMaxCalls int
+
+	// HalfOpenSuccesses is number of successful trial calls after which
+	// breaker closes
+	// Default is 1
+	HalfOpenSuccesses int
+
+	// IsFailure determines if error of call counts as failure, eg. so
+	// "not found" errors don't open breaker
+	// Default counts every error
+	IsFailure func(err error) bool
+
+	// OnStateChange, if set, is called every time breaker changes state
+	OnStateChange func(name string, from, to State)
+
+	// OnResult, if set, is called with the result of every call let
+	// through, along with how long it took, eg. to record metrics
+	OnResult func(name string, err error, duration time.Duration)
+
+	// OnReject, if set, is called every time a call is rejected
+	OnReject func(name string)
+}
+
+func (b *BreakerConfig) setDefaults() {
+	if b.FailureThreshold <= 0 {
+		b.FailureThreshold = 5
+	}
+	if b.OpenTimeout <= 0 {
+		b.OpenTimeout = time.Second * 30
+	}
+	if b.HalfOpenMaxCalls <= 0 {
+		b.HalfOpenMaxCalls = 1
+	}
+	if b.HalfOpenSuccesses <= 0 {
+		b.HalfOpenSuccesses = 1
+	}
+	if b.IsFailure == nil {
+		b.IsFailure = func(err error) bool { return err != nil }
+	}
+}
+
+// Breaker is circuit breaker that opens after consecutive failures of
+// calls, rejecting every call until OpenTimeout has passed, then lets
+// trial calls through and closes again once they succeed
+//
+// Breaker is safe for concurrent use
+type Breaker struct {
+	config BreakerConfig
+	now    func() time.Time
+
+	mu            sync.Mutex
+	state         State
+	failures      int
+	successes     int
+	halfOpenCalls int
+	openedAt      time.Time
+}
+
+// NewBreaker returns closed *Breaker
+func NewBreaker(config BreakerConfig) *Breaker {
+	config.setDefaults()
+
+	return &Breaker{config: config, now: time.Now}
+}
+
+// State returns current state of breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state, change := b.currentState()
+	b.mu.Unlock()
+
+	b.notify(change)
+	return state
+}
+
+// Do calls fn if breaker allows it and records its result
+// Returns ErrOpen without calling fn if breaker is open, else returns
+// error of fn
+func (b *Breaker) Do(fn func() error) error {
+	state, err := b.allow()
+
+	if err != nil {
+		return err
+	}
+
+	start := b.now()
+	err = fn()
+
+	if b.config.OnResult != nil {
+		b.config.OnResult(b.config.Name, err, b.now().Sub(start))
+	}
+
+	b.done(state, err)
+	return err
+}
+
+type stateChange struct {
+	from, to State
+}
+
+// currentState returns state, moving open breaker to half open once
+// OpenTimeout has passed
+// Must be called with mu locked
+func (b *Breaker) currentState() (State, *stateChange) {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen, b.setState(StateHalfOpen)
+	}
+
+	return b.state, nil
+}
+
+func (b *Breaker) allow() (State, error) {
+	b.mu.Lock()
+	state, change := b.currentState()
+	rejected := state == StateOpen ||
+		(state == StateHalfOpen && b.halfOpenCalls >= b.config.HalfOpenMaxCalls)
+
+	if !rejected && state == StateHalfOpen {
+		b.halfOpenCalls++
+	}
+
+	b.mu.Unlock()
+	b.notify(change)
+
+	if rejected {
+		if b.config.OnReject != nil {
+			b.config.OnReject(b.config.Name)
+		}
+
+		return state, ErrOpen
+	}
+
+	return state, nil
+}
+
+// done records result of call that was allowed while breaker was state
+func (b *Breaker) done(state State, err error) {
+	var change *stateChange
+
+	failed := b.config.IsFailure(err)
+
+	b.mu.Lock()
+
+	switch {
+	case state == StateHalfOpen && b.state == StateHalfOpen:
+		b.halfOpenCalls--
+
+		if failed {
+			change = b.setState(StateOpen)
+		} else if b.successes++; b.successes >= b.config.HalfOpenSuccesses {
+			change = b.setState(StateClosed)
+		}
+	case state == StateClosed && b.state == StateClosed:
+		if !failed {
+			b.failures = 0
+		} else if b.failures++; b.failures >= b.config.FailureThreshold {
+			change = b.setState(StateOpen)
+		}
+	}
+
+	b.mu.Unlock()
+	b.notify(change)
+}
+
+// setState moves breaker to state and resets its counts
+// Must be called with mu locked
+func (b *Breaker) setState(state State) *stateChange {
+	change := &stateChange{from: b.state, to: state}
+
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.halfOpenCalls = 0
+
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+
+	return change
+}
+
+func (b *Breaker) notify(change *stateChange) {
+	if change != nil && b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.config.Name, change.from, change.to)
+	}
+}
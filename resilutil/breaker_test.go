@@ -0,0 +1,82 @@
+package resilutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestBreaker(t *testing.T) {
+	var changes []string
+
+	now := time.Now()
+	failure := errors.New("connection refused")
+	breaker := NewBreaker(BreakerConfig{
+		Name:             "redis",
+		FailureThreshold: 2,
+		OpenTimeout:      time.Second * 10,
+		OnStateChange: func(name string, from, to State) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	})
+	breaker.now = func() time.Time { return now }
+
+	fail := func() error { return failure }
+	succeed := func() error { return nil }
+
+	breaker.Do(fail)
+	breaker.Do(succeed)
+	breaker.Do(fail)
+
+	if breaker.State() != StateClosed {
+		t.Fatalf("should reset failures on success")
+	}
+
+	breaker.Do(fail)
+
+	if breaker.State() != StateOpen {
+		t.Fatalf("should open after consecutive failures")
+	}
+	if err := breaker.Do(succeed); err != ErrOpen {
+		t.Errorf("should reject calls while open; got %v", err)
+	}
+
+	now = now.Add(time.Second * 10)
+
+	if err := breaker.Do(fail); err != failure {
+		t.Errorf("should let trial call through; got %v", err)
+	}
+	if breaker.State() != StateOpen {
+		t.Fatalf("should open again after failed trial call")
+	}
+
+	now = now.Add(time.Second * 10)
+
+	if err := breaker.Do(succeed); err != nil || breaker.State() != StateClosed {
+		t.Errorf("should close after successful trial call; got %v", err)
+	}
+
+	want := "closed>open open>half-open half-open>open open>half-open half-open>closed"
+
+	if got := strings.Join(changes, " "); got != want {
+		t.Errorf("got changes %s; want %s", got, want)
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	cache := NewCacheStore(cachetest.NewMemoryCache(), NewBreaker(BreakerConfig{FailureThreshold: 1}))
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get("missing"); err != cacheutil.ErrCacheNil {
+			t.Fatalf("should return cache miss; got %v", err)
+		}
+	}
+
+	if cache.Breaker().State() != StateClosed {
+		t.Errorf("should not count misses as failures")
+	}
+}
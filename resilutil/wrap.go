@@ -0,0 +1,218 @@
+package resilutil
+
+import (
+	"io"
+	"net/url"
+	"time"
+
+	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/mailutil"
+	"github.com/TravisS25/httputil/storageutil"
+)
+
+// CacheStore is cacheutil#CacheStore that calls wrapped store through
+// breaker so cache outages fail fast
+//
+// cacheutil#ErrCacheNil is not counted as failure
+// Set and Del are skipped while breaker is open as they don't return
+// errors
+type CacheStore struct {
+	store   cacheutil.CacheStore
+	breaker *Breaker
+}
+
+// NewCacheStore returns *CacheStore
+func NewCacheStore(store cacheutil.CacheStore, breaker *Breaker) *CacheStore {
+	return &CacheStore{store: store, breaker: breaker}
+}
+
+// Breaker returns breaker of c
+func (c *CacheStore) Breaker() *Breaker {
+	return c.breaker
+}
+
+func (c *CacheStore) Get(key string) ([]byte, error) {
+	var value []byte
+	var getErr error
+
+	err := c.breaker.Do(func() error {
+		value, getErr = c.store.Get(key)
+		return cacheErr(getErr)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, getErr
+}
+
+func (c *CacheStore) MGet(keys ...string) ([][]byte, error) {
+	var values [][]byte
+
+	err := c.breaker.Do(func() (err error) {
+		values, err = c.store.MGet(keys...)
+		return err
+	})
+
+	return values, err
+}
+
+func (c *CacheStore) Set(key string, value interface{}, expiration time.Duration) {
+	c.SetErr(key, value, expiration)
+}
+
+func (c *CacheStore) SetErr(key string, value interface{}, expiration time.Duration) error {
+	return c.breaker.Do(func() error {
+		return c.store.SetErr(key, value, expiration)
+	})
+}
+
+func (c *CacheStore) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	var set bool
+
+	err := c.breaker.Do(func() (err error) {
+		set, err = c.store.SetNX(key, value, expiration)
+		return err
+	})
+
+	return set, err
+}
+
+func (c *CacheStore) MSet(values map[string]interface{}, expiration time.Duration) error {
+	return c.breaker.Do(func() error {
+		return c.store.MSet(values, expiration)
+	})
+}
+
+func (c *CacheStore) Del(keys ...string) {
+	c.breaker.Do(func() error {
+		c.store.Del(keys...)
+		return nil
+	})
+}
+
+func (c *CacheStore) HasKey(key string) (bool, error) {
+	var has bool
+	var hasErr error
+
+	err := c.breaker.Do(func() error {
+		has, hasErr = c.store.HasKey(key)
+		return cacheErr(hasErr)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return has, hasErr
+}
+
+func (c *CacheStore) TTL(key string) (time.Duration, error) {
+	var ttl time.Duration
+	var ttlErr error
+
+	err := c.breaker.Do(func() error {
+		ttl, ttlErr = c.store.TTL(key)
+		return cacheErr(ttlErr)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return ttl, ttlErr
+}
+
+// cacheErr returns nil for misses so they're not counted as failures
+func cacheErr(err error) error {
+	if err == cacheutil.ErrCacheNil {
+		return nil
+	}
+
+	return err
+}
+
+// Messenger is mailutil#SendMessage that sends through breaker so smtp
+// outages fail fast
+type Messenger struct {
+	messenger mailutil.SendMessage
+	breaker   *Breaker
+}
+
+// NewMessenger returns *Messenger
+func NewMessenger(messenger mailutil.SendMessage, breaker *Breaker) *Messenger {
+	return &Messenger{messenger: messenger, breaker: breaker}
+}
+
+// Breaker returns breaker of m
+func (m *Messenger) Breaker() *Breaker {
+	return m.breaker
+}
+
+func (m *Messenger) Send(msg *mailutil.Message) error {
+	return m.breaker.Do(func() error {
+		return m.messenger.Send(msg)
+	})
+}
+
+// Storage is storageutil#StorageReaderWriter that calls wrapped storage
+// through breaker so s3 outages fail fast
+//
+// Objects returned from GetObject are read lazily so only errors of
+// the call itself are counted
+type Storage struct {
+	storage storageutil.StorageReaderWriter
+	breaker *Breaker
+}
+
+// NewStorage returns *Storage
+func NewStorage(storage storageutil.StorageReaderWriter, breaker *Breaker) *Storage {
+	return &Storage{storage: storage, breaker: breaker}
+}
+
+// Breaker returns breaker of s
+func (s *Storage) Breaker() *Breaker {
+	return s.breaker
+}
+
+func (s *Storage) GetObject(bucketName, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	var obj *minio.Object
+
+	err := s.breaker.Do(func() (err error) {
+		obj, err = s.storage.GetObject(bucketName, objectName, opts)
+		return err
+	})
+
+	return obj, err
+}
+
+func (s *Storage) PresignedGetObject(bucketName, objectName string, expiry time.Duration, reqParams url.Values) (*url.URL, error) {
+	var u *url.URL
+
+	err := s.breaker.Do(func() (err error) {
+		u, err = s.storage.PresignedGetObject(bucketName, objectName, expiry, reqParams)
+		return err
+	})
+
+	return u, err
+}
+
+func (s *Storage) PutObject(bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (int64, error) {
+	var n int64
+
+	err := s.breaker.Do(func() (err error) {
+		n, err = s.storage.PutObject(bucketName, objectName, reader, objectSize, opts)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *Storage) RemoveObject(bucketName, objectName string) error {
+	return s.breaker.Do(func() error {
+		return s.storage.RemoveObject(bucketName, objectName)
+	})
+}
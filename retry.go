@@ -0,0 +1,182 @@
+package httputil
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy determines how Retry retries failed calls
+type RetryPolicy struct {
+	// MaxAttempts is max number of calls including the first one
+	// Default is 3
+	MaxAttempts int
+
+	// InitialBackoff is wait before the first retry
+	// Default is 100 milliseconds
+	InitialBackoff time.Duration
+
+	// MaxBackoff is max wait between retries
+	// Default is 5 seconds
+	MaxBackoff time.Duration
+
+	// Multiplier is what backoff is multiplied by after every retry
+	// Default is 2
+	Multiplier float64
+
+	// Jitter is fraction of backoff, between 0 and 1, that's randomized
+	// so callers failing at the same time don't retry at the same time
+	// eg. 0.2 waits between 80% and 100% of backoff
+	// Default is 0.2, set to a negative number to disable
+	Jitter float64
+
+	// Retryable determines if error should be retried
+	// Default is IsRetryable
+	Retryable func(err error) bool
+}
+
+func (r *RetryPolicy) setDefaults() {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 3
+	}
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = time.Millisecond * 100
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = time.Second * 5
+	}
+	if r.Multiplier < 1 {
+		r.Multiplier = 2
+	}
+	if r.Jitter == 0 {
+		r.Jitter = 0.2
+	}
+	if r.Jitter > 1 {
+		r.Jitter = 1
+	}
+	if r.Retryable == nil {
+		r.Retryable = IsRetryable
+	}
+}
+
+// backoff returns wait before retry after attempt, starting at 1
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := float64(r.InitialBackoff)
+
+	for i := 1; i < attempt && wait < float64(r.MaxBackoff); i++ {
+		wait *= r.Multiplier
+	}
+	if wait > float64(r.MaxBackoff) {
+		wait = float64(r.MaxBackoff)
+	}
+	if r.Jitter > 0 {
+		wait -= wait * r.Jitter * rand.Float64()
+	}
+
+	return time.Duration(wait)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent wraps err so Retry returns it without retrying, regardless
+// of RetryPolicy#Retryable
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Retry calls fn until it succeeds, returns error that's not retryable
+// by policy or policy.MaxAttempts is reached, waiting with jittered
+// exponential backoff between calls
+//
+// The last error of fn is returned, unwrapped if wrapped by Permanent
+// If ctx is done while waiting, the last error of fn is returned
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	policy.setDefaults()
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+
+		if err == nil {
+			return nil
+		}
+		if p, ok := err.(*permanentError); ok {
+			return p.err
+		}
+		if attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryable returns whether err, or any error it wraps, is transient
+// and the call that returned it can be retried, which is:
+//   - serialization failures (sql state 40001) including CockroachDB
+//     "restart transaction" errors
+//   - network timeouts, refused and reset connections and unexpected EOF
+//   - driver.ErrBadConn
+func IsRetryable(err error) bool {
+	for err != nil {
+		if isRetryable(err) {
+			return true
+		}
+
+		switch v := err.(type) {
+		case interface{ Unwrap() error }:
+			err = v.Unwrap()
+		case interface{ Cause() error }:
+			err = v.Cause()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+func isRetryable(err error) bool {
+	switch err {
+	case driver.ErrBadConn, io.ErrUnexpectedEOF, syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.EPIPE:
+		return true
+	}
+
+	// Implemented by errors of pq and pgx
+	if v, ok := err.(interface{ SQLState() string }); ok && v.SQLState() == "40001" {
+		return true
+	}
+	if v, ok := err.(net.Error); ok && v.Timeout() {
+		return true
+	}
+	if _, ok := err.(*net.OpError); ok {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "restart transaction")
+}
@@ -0,0 +1,142 @@
+package httputil
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+type sqlStateError string
+
+func (s sqlStateError) Error() string {
+	return "pq: " + string(s)
+}
+
+func (s sqlStateError) SQLState() string {
+	return string(s)
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond * 2,
+	}
+	calls := 0
+
+	err := Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+
+		if calls < 3 {
+			return driver.ErrBadConn
+		}
+
+		return nil
+	})
+
+	if err != nil || calls != 3 {
+		t.Fatalf("should succeed on third call; got %d calls, err %v", calls, err)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return driver.ErrBadConn
+	})
+
+	if err != driver.ErrBadConn || calls != 4 {
+		t.Fatalf("should stop after max attempts; got %d calls, err %v", calls, err)
+	}
+
+	calls = 0
+	fatal := errors.New("invalid input")
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return fatal
+	})
+
+	if err != fatal || calls != 1 {
+		t.Fatalf("should not retry non retryable error; got %d calls, err %v", calls, err)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return Permanent(driver.ErrBadConn)
+	})
+
+	if err != driver.ErrBadConn || calls != 1 {
+		t.Fatalf("should not retry permanent error; got %d calls, err %v", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	policy.InitialBackoff = time.Hour
+	policy.MaxBackoff = time.Hour
+	err = Retry(ctx, policy, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return driver.ErrBadConn
+	})
+
+	if err != driver.ErrBadConn || calls != 1 {
+		t.Fatalf("should stop when ctx is done; got %d calls, err %v", calls, err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Millisecond * 100,
+		MaxBackoff:     time.Millisecond * 300,
+		Jitter:         -1,
+	}
+	policy.setDefaults()
+
+	for attempt, expected := range []time.Duration{
+		time.Millisecond * 100,
+		time.Millisecond * 200,
+		time.Millisecond * 300,
+		time.Millisecond * 300,
+	} {
+		if wait := policy.backoff(attempt + 1); wait != expected {
+			t.Errorf("attempt %d should wait %s; got %s", attempt+1, expected, wait)
+		}
+	}
+
+	policy.Jitter = 0.5
+
+	for i := 0; i < 50; i++ {
+		if wait := policy.backoff(1); wait < time.Millisecond*50 || wait > time.Millisecond*100 {
+			t.Fatalf("jittered wait should be between 50ms and 100ms; got %s", wait)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{driver.ErrBadConn, true},
+		{sqlStateError("40001"), true},
+		{sqlStateError("23505"), false},
+		{errors.New("restart transaction: TransactionRetryWithProtoRefreshError"), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{pkgerrors.Wrap(sqlStateError("40001"), "update"), true},
+		{errors.New("invalid input"), false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if IsRetryable(test.err) != test.retryable {
+			t.Errorf("retryable of %v should be %t", test.err, test.retryable)
+		}
+	}
+}
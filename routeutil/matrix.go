@@ -0,0 +1,175 @@
+package routeutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Access of AccessEntry
+const (
+	// AccessAnon is access of routes that can be accessed without being
+	// logged in
+	AccessAnon = "anonymous"
+
+	// AccessUser is access of routes every logged in user can access
+	AccessUser = "any user"
+
+	// AccessRestricted is access of routes only users with one of their
+	// groups or permissions can access
+	AccessRestricted = "restricted"
+)
+
+// Formats of Registry#WriteAccessMatrix
+const (
+	MatrixMarkdown = "markdown"
+	MatrixJSON     = "json"
+)
+
+// AccessEntry is access control of route, which is row of access matrix
+type AccessEntry struct {
+	Name        string   `json:"name,omitempty"`
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	Description string   `json:"description,omitempty"`
+
+	// Access is AccessAnon, AccessUser or AccessRestricted
+	Access      string   `json:"access"`
+	Groups      []string `json:"groups,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+
+	// RateLimit is rate limit of route eg. "100 per 1m0s by ip"
+	RateLimit string `json:"rateLimit,omitempty"`
+
+	// Cache is cache policy of route eg. "1m0s by groups; tables: invoice"
+	Cache string `json:"cache,omitempty"`
+}
+
+// AccessMatrix returns access control of every route sorted by path
+// and then methods, so reviews of who can access what are generated
+// from the same routes that are registered and authorized instead of
+// being maintained by hand
+func (reg *Registry) AccessMatrix() []AccessEntry {
+	entries := make([]AccessEntry, 0, len(reg.routes))
+
+	for _, route := range reg.routes {
+		entry := AccessEntry{
+			Name:        route.Name,
+			Path:        route.Path,
+			Methods:     route.Methods,
+			Description: route.Description,
+			Access:      AccessRestricted,
+			Groups:      sortedCopy(route.Groups),
+			Permissions: sortedCopy(route.Permissions),
+		}
+
+		switch {
+		case route.Anon:
+			entry.Access = AccessAnon
+		case len(route.Groups) == 0 && len(route.Permissions) == 0:
+			entry.Access = AccessUser
+		}
+
+		if len(entry.Methods) == 0 {
+			entry.Methods = []string{"*"}
+		}
+
+		if limit := route.RateLimit; limit != nil {
+			entry.RateLimit = fmt.Sprintf("%d per %s", limit.Requests, limit.Per)
+
+			if limit.By != "" {
+				entry.RateLimit += " by " + limit.By
+			}
+		}
+
+		if route.Cache != nil {
+			config := route.Cache.Config()
+			scope := "groups"
+
+			if config.VaryByUser {
+				scope = "user"
+			}
+
+			entry.Cache = fmt.Sprintf("%s by %s", config.TTL, scope)
+			tables := make([]string, 0, len(config.Tags))
+
+			for _, tag := range config.Tags {
+				tables = append(tables, tag.StringVal)
+			}
+
+			if len(tables) > 0 {
+				sort.Strings(tables)
+				entry.Cache += "; tables: " + strings.Join(tables, ", ")
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+
+		return strings.Join(entries[i].Methods, ",") < strings.Join(entries[j].Methods, ",")
+	})
+
+	return entries
+}
+
+// WriteAccessMatrix writes AccessMatrix to w as MatrixMarkdown table or
+// indented MatrixJSON
+// Returns error if format is neither
+func (reg *Registry) WriteAccessMatrix(w io.Writer, format string) error {
+	entries := reg.AccessMatrix()
+
+	switch format {
+	case MatrixJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case MatrixMarkdown:
+		var sb strings.Builder
+
+		sb.WriteString("| Methods | Path | Access | Groups | Permissions | Rate limit | Cache | Description |\n")
+		sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+
+		for _, e := range entries {
+			cells := []string{
+				strings.Join(e.Methods, ", "),
+				"`" + e.Path + "`",
+				e.Access,
+				strings.Join(e.Groups, ", "),
+				strings.Join(e.Permissions, ", "),
+				e.RateLimit,
+				e.Cache,
+				e.Description,
+			}
+
+			for i, c := range cells {
+				cells[i] = strings.Replace(c, "|", `\|`, -1)
+			}
+
+			sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+
+		_, err := io.WriteString(w, sb.String())
+		return err
+	default:
+		return errors.Errorf("routeutil: invalid access matrix format '%s'", format)
+	}
+}
+
+func sortedCopy(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	c := append([]string(nil), values...)
+	sort.Strings(c)
+	return c
+}
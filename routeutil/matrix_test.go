@@ -0,0 +1,127 @@
+package routeutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+)
+
+func TestAccessMatrix(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	cache, err := apiutil.NewCacheHandler(apiutil.CacheHandlerConfig{
+		CacheStore: cachetest.NewMemoryCache(),
+		Tags:       []cacheutil.CacheSetup{{StringVal: "invoice"}},
+	})
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	limited := 0
+	reg := NewRegistry()
+
+	if err = reg.Add(
+		Route{
+			Path:        "/api/login",
+			Methods:     []string{http.MethodPost},
+			Handler:     handler,
+			Anon:        true,
+			Description: "Logs in | out",
+			RateLimit: &RateLimit{Requests: 5, Per: time.Minute, By: "ip", Handler: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					limited++
+					next.ServeHTTP(w, r)
+				})
+			}},
+		},
+		Route{
+			Path:        "/api/invoice",
+			Methods:     []string{http.MethodGet},
+			Handler:     handler,
+			Groups:      []string{"User", "Admin"},
+			Permissions: []string{"invoice:read"},
+			Cache:       cache,
+		},
+		Route{Path: "/api/account", Handler: handler},
+	); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := []AccessEntry{
+		{Path: "/api/account", Methods: []string{"*"}, Access: AccessUser},
+		{
+			Path:        "/api/invoice",
+			Methods:     []string{http.MethodGet},
+			Access:      AccessRestricted,
+			Groups:      []string{"Admin", "User"},
+			Permissions: []string{"invoice:read"},
+			Cache:       "1m0s by groups; tables: invoice",
+		},
+		{
+			Path:        "/api/login",
+			Methods:     []string{http.MethodPost},
+			Description: "Logs in | out",
+			Access:      AccessAnon,
+			RateLimit:   "5 per 1m0s by ip",
+		},
+	}
+
+	if matrix := reg.AccessMatrix(); !reflect.DeepEqual(matrix, expected) {
+		t.Errorf("got matrix %+v; want %+v", matrix, expected)
+	}
+
+	var buf bytes.Buffer
+
+	if err = reg.WriteAccessMatrix(&buf, MatrixJSON); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	var entries []AccessEntry
+
+	if err = json.Unmarshal(buf.Bytes(), &entries); err != nil || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("json should decode to matrix; got %s and %v", buf.String(), err)
+	}
+
+	buf.Reset()
+
+	if err = reg.WriteAccessMatrix(&buf, MatrixMarkdown); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	row := "| POST | `/api/login` | anonymous |  |  | 5 per 1m0s by ip |  | Logs in \\| out |"
+
+	if !strings.Contains(buf.String(), row) {
+		t.Errorf("markdown should contain row %s; got\n%s", row, buf.String())
+	}
+	if err = reg.WriteAccessMatrix(&buf, "csv"); err == nil {
+		t.Errorf("should return error for invalid format")
+	}
+
+	router := mux.NewRouter()
+	reg.Register(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/login", nil))
+
+	if limited != 1 {
+		t.Errorf("route should be wrapped with rate limit handler")
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/invoice", nil))
+
+	if rr.Header().Get(apiutil.ResponseCacheHeader) != "MISS" {
+		t.Errorf("route should be wrapped with cache handler; got header %v", rr.Header())
+	}
+}
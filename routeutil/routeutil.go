@@ -0,0 +1,282 @@
+package routeutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/muxutil"
+)
+
+var (
+	// ErrNoRoute is returned from Registry#PathRegex if request did not
+	// match a route registered with mux
+	ErrNoRoute = muxutil.ErrNoRoute
+)
+
+// Route is a route along with the authorization needed to access it
+type Route struct {
+	// Name is optional name of route used for mux#Route#Name
+	Name string
+
+	// Path is mux path template of route eg. "/api/invoice/{id:[0-9]+}"
+	// - Required
+	Path string
+
+	// Methods are http methods of route
+	// If empty, route matches every method
+	Methods []string
+
+	// Handler handles requests of route - Required
+	Handler http.Handler
+
+	// Groups are groups allowed to access route, where user only needs
+	// one of them
+	Groups []string
+
+	// Permissions are permissions allowed to access route, where user
+	// only needs one of them
+	// This requires apiutil#RoleHandler
+	Permissions []string
+
+	// Anon is whether route can be accessed without being logged in
+	Anon bool
+
+	// Description is what route does, used by the access matrix
+	Description string
+
+	// RateLimit, if set, is rate limit of route
+	RateLimit *RateLimit
+
+	// Cache, if set, caches responses of route, where route is wrapped
+	// with its MiddlewareFunc when registered
+	Cache *apiutil.CacheHandler
+}
+
+// RateLimit is rate limit of route
+type RateLimit struct {
+	// Requests is number of requests allowed Per duration
+	Requests int
+	Per      time.Duration
+
+	// By is what requests are counted by eg. "ip" or "user"
+	By string
+
+	// Handler, if set, enforces rate limit and route is wrapped with it
+	// when registered
+	// If not set, rate limit is only documented eg. because it's
+	// enforced by a proxy
+	Handler mux.MiddlewareFunc
+}
+
+// RouteSeed is Route without its handler, used to seed the tables the
+// QueryDB of apiutil#RoutingHandler reads from
+type RouteSeed struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	Groups      []string `json:"groups"`
+	Permissions []string `json:"permissions"`
+	Anon        bool     `json:"anon"`
+}
+
+// Registry is set of routes declared with their authorization so they
+// can be registered on mux and used to generate the nonUserURLs and urls
+// of users for apiutil#RoutingHandler from the same place
+//
+// apiutil#RoutingHandler authorizes by path only so when routes share a
+// path, their groups and permissions are combined
+type Registry struct {
+	routes []Route
+	keys   map[string]bool
+}
+
+// NewRegistry returns *Registry without routes
+func NewRegistry() *Registry {
+	return &Registry{keys: make(map[string]bool)}
+}
+
+// Add adds routes to registry
+// Returns error if route has no path or handler or if path and method of
+// route was already added
+func (reg *Registry) Add(routes ...Route) error {
+	for _, route := range routes {
+		if route.Path == "" {
+			return errors.New("routeutil: route path is required")
+		}
+		if route.Handler == nil {
+			return fmt.Errorf("routeutil: handler of route %s is required", route.Path)
+		}
+
+		methods := route.Methods
+
+		if len(methods) == 0 {
+			methods = []string{"*"}
+		}
+
+		for _, method := range methods {
+			key := method + " " + route.Path
+
+			if reg.keys[key] {
+				return fmt.Errorf("routeutil: route %s already added", key)
+			}
+
+			reg.keys[key] = true
+		}
+
+		reg.routes = append(reg.routes, route)
+	}
+
+	return nil
+}
+
+// Routes returns routes of registry in the order they were added
+func (reg *Registry) Routes() []Route {
+	return append([]Route(nil), reg.routes...)
+}
+
+// Register registers every route of registry on router, where handler of
+// route is wrapped by rate limit and then cache of route, if set
+func (reg *Registry) Register(router *mux.Router) {
+	for _, route := range reg.routes {
+		handler := route.Handler
+
+		if route.Cache != nil {
+			handler = route.Cache.MiddlewareFunc(handler)
+		}
+		if route.RateLimit != nil && route.RateLimit.Handler != nil {
+			handler = route.RateLimit.Handler(handler)
+		}
+
+		muxRoute := router.Handle(route.Path, handler)
+
+		if len(route.Methods) > 0 {
+			muxRoute.Methods(route.Methods...)
+		}
+		if route.Name != "" {
+			muxRoute.Name(route.Name)
+		}
+	}
+}
+
+// NonUserURLs returns paths of Anon routes, used as nonUserURLs of
+// apiutil#NewRoutingHandler
+func (reg *Registry) NonUserURLs() map[string]bool {
+	urls := make(map[string]bool)
+
+	for _, route := range reg.routes {
+		if route.Anon {
+			urls[route.Path] = true
+		}
+	}
+
+	return urls
+}
+
+// URLGroups returns groups allowed to access each path
+// Paths without groups are not included
+func (reg *Registry) URLGroups() map[string][]string {
+	return reg.pathMap(func(route Route) []string { return route.Groups })
+}
+
+// URLPermissions returns permissions allowed to access each path
+// Paths without permissions are not included
+func (reg *Registry) URLPermissions() map[string][]string {
+	return reg.pathMap(func(route Route) []string { return route.Permissions })
+}
+
+// UserURLs returns paths user of request is allowed to access based on
+// groups set by apiutil#GroupHandler and permissions set by
+// apiutil#RoleHandler
+// Routes without groups and permissions can be accessed by every user
+func (reg *Registry) UserURLs(r *http.Request) map[string]bool {
+	urls := make(map[string]bool)
+	groups := apiutil.GetUserGroups(r)
+
+	for _, route := range reg.routes {
+		if route.Anon || (len(route.Groups) == 0 && len(route.Permissions) == 0) {
+			urls[route.Path] = true
+			continue
+		}
+
+		for _, g := range route.Groups {
+			if groups[g] {
+				urls[route.Path] = true
+				break
+			}
+		}
+
+		if apiutil.HasPermission(r, route.Permissions...) {
+			urls[route.Path] = true
+		}
+	}
+
+	return urls
+}
+
+// QueryURLs implements apiutil#QueryDB by returning json of UserURLs so
+// apiutil#RoutingHandler can authorize users from registry instead of
+// database
+// RoutingHandler must be placed after GroupHandler to use this
+func (reg *Registry) QueryURLs(w http.ResponseWriter, r *http.Request, db httputil.Querier) ([]byte, error) {
+	return json.Marshal(reg.UserURLs(r))
+}
+
+// PathRegex implements httputil#PathRegex by returning path template of
+// route matched by mux, see muxutil#CurrentRouteTemplate
+func (reg *Registry) PathRegex(r *http.Request) (string, error) {
+	return muxutil.CurrentRouteTemplate(r)
+}
+
+// Seeds returns every route of registry without its handler, which can
+// be marshalled to json or inserted into the tables the QueryDB of
+// apiutil#RoutingHandler reads from
+func (reg *Registry) Seeds() []RouteSeed {
+	seeds := make([]RouteSeed, 0, len(reg.routes))
+
+	for _, route := range reg.routes {
+		seeds = append(seeds, RouteSeed{
+			Name:        route.Name,
+			Path:        route.Path,
+			Methods:     route.Methods,
+			Groups:      route.Groups,
+			Permissions: route.Permissions,
+			Anon:        route.Anon,
+		})
+	}
+
+	return seeds
+}
+
+func (reg *Registry) pathMap(values func(route Route) []string) map[string][]string {
+	sets := make(map[string]map[string]bool)
+
+	for _, route := range reg.routes {
+		for _, v := range values(route) {
+			if sets[route.Path] == nil {
+				sets[route.Path] = make(map[string]bool)
+			}
+
+			sets[route.Path][v] = true
+		}
+	}
+
+	paths := make(map[string][]string, len(sets))
+
+	for path, set := range sets {
+		for v := range set {
+			paths[path] = append(paths[path], v)
+		}
+
+		sort.Strings(paths[path])
+	}
+
+	return paths
+}
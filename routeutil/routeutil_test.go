@@ -0,0 +1,99 @@
+package routeutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TravisS25/httputil/apiutil"
+)
+
+func TestRegistry(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	reg := NewRegistry()
+
+	err := reg.Add(
+		Route{Path: "/api/login", Methods: []string{http.MethodPost}, Handler: handler, Anon: true},
+		Route{Path: "/api/invoice/{id}", Methods: []string{http.MethodGet}, Handler: handler, Groups: []string{"User"}},
+		Route{Path: "/api/invoice/{id}", Methods: []string{http.MethodPut}, Handler: handler, Groups: []string{"Admin"}, Permissions: []string{"invoice:write"}},
+		Route{Path: "/api/account", Handler: handler},
+		Route{Path: "/api/user", Methods: []string{http.MethodGet}, Handler: handler, Groups: []string{"Admin"}},
+	)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if err = reg.Add(Route{Path: "/api/login", Methods: []string{http.MethodPost}, Handler: handler}); err == nil {
+		t.Errorf("should return error for duplicate route")
+	}
+	if err = reg.Add(Route{Path: "/api/foo"}); err == nil {
+		t.Errorf("should return error for route without handler")
+	}
+
+	if urls := reg.NonUserURLs(); !reflect.DeepEqual(urls, map[string]bool{"/api/login": true}) {
+		t.Errorf("should only have login as non user url; got %v", urls)
+	}
+	if groups := reg.URLGroups()["/api/invoice/{id}"]; !reflect.DeepEqual(groups, []string{"Admin", "User"}) {
+		t.Errorf("should combine groups of path; got %v", groups)
+	}
+	if permissions := reg.URLPermissions(); !reflect.DeepEqual(permissions, map[string][]string{"/api/invoice/{id}": {"invoice:write"}}) {
+		t.Errorf("should have invoice permissions; got %v", permissions)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req = req.WithContext(context.WithValue(req.Context(), apiutil.GroupCtxKey, map[string]bool{"User": true}))
+
+	urlBytes, err := reg.QueryURLs(nil, req, nil)
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	var urls map[string]bool
+
+	if err = json.Unmarshal(urlBytes, &urls); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := map[string]bool{"/api/login": true, "/api/invoice/{id}": true, "/api/account": true}
+
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("should return %v; got %v", expected, urls)
+	}
+
+	if seeds := reg.Seeds(); len(seeds) != 5 || seeds[2].Permissions[0] != "invoice:write" {
+		t.Errorf("should return seed of every route; got %+v", seeds)
+	}
+
+	router := mux.NewRouter()
+	reg.Register(router)
+
+	var path string
+
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, err = reg.PathRegex(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/invoice/1", nil))
+
+	if rr.Code != http.StatusOK || err != nil || path != "/api/invoice/{id}" {
+		t.Errorf("should match invoice route; got %d, %s and %v", rr.Code, path, err)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/invoice/1", nil))
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status should be %d; got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
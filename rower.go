@@ -0,0 +1,62 @@
+package httputil
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SQLQueryer is implemented by *sql.DB and *sql.Tx
+type SQLQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// SQLQuerier adapts *sql.DB or *sql.Tx of database/sql into XODB and
+// QuerierContext so queryutil, SetRowerResults etc. can be used
+// without sqlx based dbutil#DB
+//
+// *sql.Rows already implements Rower so rows returned are as is
+type SQLQuerier struct {
+	db SQLQueryer
+}
+
+// NewSQLQuerier returns *SQLQuerier
+func NewSQLQuerier(db SQLQueryer) *SQLQuerier {
+	return &SQLQuerier{db: db}
+}
+
+// QueryRow is wrapper for sql.DB#QueryRow
+func (s *SQLQuerier) QueryRow(query string, args ...interface{}) Scanner {
+	return s.db.QueryRow(query, args...)
+}
+
+// Query is wrapper for sql.DB#Query
+func (s *SQLQuerier) Query(query string, args ...interface{}) (Rower, error) {
+	return SQLRows(s.db.Query(query, args...))
+}
+
+// QueryContext is wrapper for sql.DB#QueryContext
+func (s *SQLQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (Rower, error) {
+	return SQLRows(s.db.QueryContext(ctx, query, args...))
+}
+
+// Exec is wrapper for sql.DB#Exec
+func (s *SQLQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(query, args...)
+}
+
+// SQLRows returns rows as Rower, or nil if err is not nil so the result
+// is never a non nil Rower holding nil *sql.Rows
+//
+// Example:
+//
+//	rower, err := httputil.SQLRows(db.Query("select id from foo"))
+func SQLRows(rows *sql.Rows, err error) (Rower, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
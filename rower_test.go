@@ -0,0 +1,105 @@
+package httputil
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+func init() {
+	sql.Register("rowertest", rowerDriver{})
+}
+
+// rowerDriver is database driver where every query returns rows of
+// rowerValues
+type rowerDriver struct{}
+
+var rowerValues = [][]driver.Value{{int64(1), "foo"}, {int64(2), "bar"}}
+
+func (rowerDriver) Open(name string) (driver.Conn, error) {
+	return rowerConn{}, nil
+}
+
+type rowerConn struct{}
+
+func (rowerConn) Prepare(query string) (driver.Stmt, error) { return rowerStmt{}, nil }
+func (rowerConn) Close() error                              { return nil }
+func (rowerConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type rowerStmt struct{}
+
+func (rowerStmt) Close() error  { return nil }
+func (rowerStmt) NumInput() int { return -1 }
+func (rowerStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (rowerStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &rowerRows{}, nil
+}
+
+type rowerRows struct {
+	i int
+}
+
+func (r *rowerRows) Columns() []string { return []string{"id", "name"} }
+func (r *rowerRows) Close() error      { return nil }
+func (r *rowerRows) Next(dest []driver.Value) error {
+	if r.i == len(rowerValues) {
+		return io.EOF
+	}
+
+	copy(dest, rowerValues[r.i])
+	r.i++
+	return nil
+}
+
+func TestSQLQuerier(t *testing.T) {
+	db, err := sql.Open("rowertest", "")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	var querier XODB = NewSQLQuerier(db)
+	var id int64
+	var name string
+
+	if err = querier.QueryRow("select id, name from foo").Scan(&id, &name); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+	if id != 1 || name != "foo" {
+		t.Errorf("should scan first row; got %d, %s", id, name)
+	}
+
+	rower, err := querier.Query("select id, name from foo")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	columns, _ := rower.Columns()
+
+	if len(columns) != 2 || columns[1] != "name" {
+		t.Errorf("should return columns; got %v", columns)
+	}
+
+	var names []string
+
+	for rower.Next() {
+		if err = rower.Scan(&id, &name); err != nil {
+			t.Fatalf("should not return error; got %s", err.Error())
+		}
+
+		names = append(names, name)
+	}
+
+	if len(names) != 2 || names[1] != "bar" {
+		t.Errorf("should scan every row; got %v", names)
+	}
+
+	if rower, err = SQLRows(nil, errors.New("err")); rower != nil || err == nil {
+		t.Errorf("should return nil rower with error")
+	}
+}
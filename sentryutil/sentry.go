@@ -0,0 +1,277 @@
+// Package sentryutil implements httputil#ErrorReporter that sends
+// errors to Sentry, or any service compatible with its store api
+package sentryutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TravisS25/httputil"
+)
+
+const (
+	// DefaultTimeout is default timeout of sending an event
+	DefaultTimeout = time.Second * 5
+
+	sentryVersion = "7"
+	sentryClient  = "httputil-sentry/1.0"
+)
+
+// Config is config struct used for Reporter
+type Config struct {
+	// DSN is data source name of project eg.
+	// "https://public@o0.ingest.sentry.io/1" - Required
+	DSN string
+
+	// Environment is environment of events eg. "production"
+	Environment string
+
+	// Release is release of events eg. git sha of build
+	Release string
+
+	// Client is used to send events
+	// Default is http.Client with DefaultTimeout
+	Client *http.Client
+}
+
+// Reporter is httputil#ErrorReporter that sends errors as Sentry events
+// Events are sent in the background so capturing doesn't block
+// requests; call Flush before exiting so pending events are sent
+type Reporter struct {
+	config   Config
+	storeURL string
+	auth     string
+	wg       sync.WaitGroup
+}
+
+// NewReporter returns *Reporter
+// Returns error if Config#DSN is invalid
+func NewReporter(config Config) (*Reporter, error) {
+	dsn, err := url.Parse(config.DSN)
+
+	if err != nil {
+		return nil, fmt.Errorf("sentryutil: invalid dsn: %s", err.Error())
+	}
+
+	if dsn.User == nil || dsn.User.Username() == "" || dsn.Host == "" {
+		return nil, fmt.Errorf("sentryutil: dsn must have key and host")
+	}
+
+	path := strings.TrimSuffix(dsn.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+
+	if projectID == "" {
+		return nil, fmt.Errorf("sentryutil: dsn must have project id")
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	auth := fmt.Sprintf(
+		"Sentry sentry_version=%s, sentry_client=%s, sentry_key=%s",
+		sentryVersion,
+		sentryClient,
+		dsn.User.Username(),
+	)
+
+	if secret, ok := dsn.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	return &Reporter{
+		config:   config,
+		storeURL: fmt.Sprintf("%s://%s%s/api/%s/store/", dsn.Scheme, dsn.Host, path[:idx], projectID),
+		auth:     auth,
+	}, nil
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     string            `json:"message"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+}
+
+// CaptureError sends err with tags as event in the background
+// Panics, captured as *httputil.PanicError, are sent with level "fatal"
+// and their stack trace
+func (r *Reporter) CaptureError(ctx context.Context, err error, tags map[string]string) {
+	ev, evErr := r.newEvent(err, tags)
+
+	if evErr != nil {
+		httputil.Logger.Errorf("sentryutil: %s", evErr.Error())
+		return
+	}
+
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		if sendErr := r.send(ev); sendErr != nil {
+			httputil.Logger.Errorf("sentryutil: %s", sendErr.Error())
+		}
+	}()
+}
+
+// Flush waits until every pending event is sent or timeout passes
+// Returns whether every event was sent
+func (r *Reporter) Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (r *Reporter) newEvent(err error, tags map[string]string) (*event, error) {
+	id := make([]byte, 16)
+
+	if _, randErr := rand.Read(id); randErr != nil {
+		return nil, randErr
+	}
+
+	hostname, _ := os.Hostname()
+	ev := &event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Environment: r.config.Environment,
+		Release:     r.config.Release,
+		ServerName:  hostname,
+		Message:     err.Error(),
+		Tags:        tags,
+	}
+
+	exc := exception{Type: reflect.TypeOf(err).String(), Value: err.Error()}
+
+	if p, ok := err.(*httputil.PanicError); ok {
+		ev.Level = "fatal"
+		exc.Stacktrace = parseStack(p.Stack)
+	}
+
+	ev.Exception.Values = []exception{exc}
+	return ev, nil
+}
+
+func (r *Reporter) send(ev *event) error {
+	body, err := json.Marshal(ev)
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.storeURL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", httputil.ContentTypeJSON)
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	res, err := r.config.Client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("event failed with status %d: %s", res.StatusCode, string(resBody))
+	}
+
+	return nil
+}
+
+// parseStack returns frames of stack from runtime/debug#Stack, where
+// every frame is a function line followed by a tab indented
+// "file:line +0x0" line
+// Sentry expects frames with the oldest call first
+func parseStack(stack []byte) *stacktrace {
+	var frames []frame
+
+	lines := strings.Split(string(stack), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+
+		f := frame{Function: line}
+
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			location := strings.Fields(lines[i+1])
+
+			if len(location) > 0 {
+				if idx := strings.LastIndex(location[0], ":"); idx > 0 {
+					f.Filename = location[0][:idx]
+					f.Lineno, _ = strconv.Atoi(location[0][idx+1:])
+				}
+			}
+
+			i++
+		}
+
+		frames = append([]frame{f}, frames...)
+	}
+
+	if len(frames) == 0 {
+		return nil
+	}
+
+	return &stacktrace{Frames: frames}
+}
@@ -0,0 +1,426 @@
+package startutil
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/formutil"
+	"github.com/TravisS25/httputil/mailutil"
+	"github.com/TravisS25/httputil/sentryutil"
+	"github.com/TravisS25/httputil/storageutil"
+)
+
+var (
+	// ErrAppNotStarted is returned by App#Stop if App#Start was not
+	// called or did not succeed
+	ErrAppNotStarted = errors.New("startutil: app not started")
+)
+
+// dbDrainer is implemented by databases that can wait for queries in
+// progress before closing like *dbutil.DB
+type dbDrainer interface {
+	Drain(ctx context.Context) error
+}
+
+// AppOption substitutes a component of App instead of it being
+// constructed from settings, mainly used for tests
+type AppOption func(a *App)
+
+// WithDB substitutes database of App
+// App#Stop will not close a substituted database
+func WithDB(db httputil.DBInterfaceV2) AppOption {
+	return func(a *App) {
+		a.db = db
+	}
+}
+
+// WithCache substitutes cache of App
+func WithCache(cache cacheutil.CacheStore) AppOption {
+	return func(a *App) {
+		a.cache = cache
+	}
+}
+
+// WithMailer substitutes mailer of App
+func WithMailer(mailer mailutil.SendMessage) AppOption {
+	return func(a *App) {
+		a.mailer = mailer
+	}
+}
+
+// WithStorage substitutes storage of App
+func WithStorage(storage storageutil.StorageReaderWriter) AppOption {
+	return func(a *App) {
+		a.storage = storage
+	}
+}
+
+// WithLogger substitutes logger of App
+func WithLogger(logger *logrus.Logger) AppOption {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+// AppConfig is config struct used for App
+type AppConfig struct {
+	// DBType is the type of database eg. Postgres
+	// Default is Postgres
+	DBType string
+
+	// DBName is key of confutil#Settings#Databases used to construct
+	// database of App, see GetDB
+	// If not set, confutil#Settings#DatabaseConfig is used
+	DBName string
+
+	// StorageName is key of confutil#Settings#S3Config used to construct
+	// storage of App with GetBucket
+	// If not set, App#Storage is nil unless substituted with WithStorage
+	StorageName string
+
+	// ShutdownTimeout is how long App#Stop and App#Serve wait for
+	// requests and queries in progress to finish
+	// Default is confutil#Settings#Server#ShutdownTimeout, else 30 seconds
+	ShutdownTimeout time.Duration
+
+	// FlushTimeout is how long App#Stop waits for error reporter to send
+	// queued errors
+	// Default is 5 seconds
+	FlushTimeout time.Duration
+
+	// Banner, if set, is config LogBanner is called with, using logger
+	// of App, once App#Start succeeds
+	Banner *BannerConfig
+}
+
+// App owns the components of an application, constructed from settings
+// on App#Start, and exposes them to handler factories through its
+// accessor methods
+//
+// Any component can be substituted with an AppOption, in which case
+// it is not constructed from settings
+type App struct {
+	settings *confutil.Settings
+	config   AppConfig
+
+	db      httputil.DBInterfaceV2
+	cache   cacheutil.CacheStore
+	mailer  mailutil.SendMessage
+	storage storageutil.StorageReaderWriter
+	logger  *logrus.Logger
+	appSessions
+
+	ownsDB   bool
+	reporter *sentryutil.Reporter
+	started  bool
+}
+
+// NewApp returns *App
+// Components are not constructed until App#Start is called
+func NewApp(settings *confutil.Settings, config AppConfig, options ...AppOption) *App {
+	if config.DBType == "" {
+		config.DBType = dbutil.Postgres
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = settings.Server.ShutdownTimeout.Duration
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = time.Second * 30
+	}
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = time.Second * 5
+	}
+
+	a := &App{
+		settings: settings,
+		config:   config,
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	return a
+}
+
+// Start constructs every component that was not substituted, runs
+// migrations and sets error reporter
+//
+// The database is constructed with GetDB, and the cache, session store
+// and mailer are only constructed if they are configured in settings
+// If any step fails after the database is constructed, it's closed
+func (a *App) Start() error {
+	if a.logger == nil {
+		a.logger = httputil.Logger
+	}
+
+	if err := a.start(); err != nil {
+		if closeErr := a.closeDB(); closeErr != nil {
+			a.Logger().Warnf("startutil: db close: %s", closeErr.Error())
+		}
+
+		return err
+	}
+
+	if a.config.Banner != nil {
+		banner := *a.config.Banner
+
+		if banner.Logger == nil {
+			banner.Logger = a.logger
+		}
+
+		LogBanner(a.settings, banner)
+	}
+
+	a.started = true
+	return nil
+}
+
+func (a *App) start() error {
+	var err error
+
+	if a.db == nil {
+		if a.db, err = GetDB(a.settings, a.config.DBName, a.config.DBType); err != nil {
+			return errors.Wrap(err, "startutil: db")
+		}
+
+		a.ownsDB = true
+	}
+
+	if a.cache == nil {
+		if a.cache, err = GetEncryptedCacheSettings(a.settings); err != nil {
+			return errors.Wrap(err, "startutil: cache")
+		}
+	}
+
+	if err = a.startSessionStore(); err != nil {
+		return errors.Wrap(err, "startutil: session store")
+	}
+
+	if a.mailer == nil && a.hasEmail() {
+		a.mailer = GetMessenger(a.settings)
+	}
+
+	if a.storage == nil && a.config.StorageName != "" {
+		var bucket *storageutil.Bucket
+
+		bucket, err = GetBucket(a.settings, a.config.StorageName)
+
+		if err != nil {
+			return errors.Wrap(err, "startutil: storage")
+		}
+
+		a.storage = bucket
+	}
+
+	if _, err = RunMigrations(a.settings, a.db, a.config.DBType); err != nil {
+		return errors.Wrap(err, "startutil: migrations")
+	}
+
+	if a.reporter, err = SetErrorReporter(a.settings); err != nil {
+		return errors.Wrap(err, "startutil: error reporter")
+	}
+
+	return nil
+}
+
+// closeDB closes database if it was constructed by App#Start
+func (a *App) closeDB() error {
+	if !a.ownsDB {
+		return nil
+	}
+
+	var err error
+
+	if closer, ok := a.db.(io.Closer); ok {
+		err = closer.Close()
+	}
+
+	a.ownsDB = false
+	a.db = nil
+	return err
+}
+
+// Stop is the same as App#Shutdown with a context that times out after
+// AppConfig#ShutdownTimeout
+func (a *App) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	return a.Shutdown(ctx)
+}
+
+// Shutdown drains database, if it was constructed by App#Start, so
+// queries and transactions in progress can finish before ctx is done,
+// then flushes error reporter
+// See dbutil#DB#Drain
+func (a *App) Shutdown(ctx context.Context) error {
+	var err error
+
+	if !a.started {
+		return ErrAppNotStarted
+	}
+
+	a.started = false
+
+	if a.ownsDB {
+		if drainer, ok := a.db.(dbDrainer); ok {
+			err = drainer.Drain(ctx)
+		} else if closer, ok := a.db.(io.Closer); ok {
+			err = closer.Close()
+		}
+
+		a.ownsDB = false
+		a.db = nil
+	}
+
+	if a.reporter != nil && !a.reporter.Flush(a.config.FlushTimeout) {
+		a.Logger().Warn("startutil: error reporter not flushed before timeout")
+	}
+
+	return err
+}
+
+// Serve serves handler with server returned from NewServer until
+// SIGINT or SIGTERM is received, then shuts down server, so requests
+// in progress can finish, and app within AppConfig#ShutdownTimeout
+//
+// App#Start should be called before Serve
+func (a *App) Serve(handler http.Handler) error {
+	server := NewServer(a.settings, handler)
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErr:
+		if stopErr := a.Stop(); stopErr != nil {
+			a.Logger().Warnf("startutil: app stop: %s", stopErr.Error())
+		}
+
+		return err
+	case <-signals:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		a.Logger().Warnf("startutil: server shutdown: %s", err.Error())
+	}
+
+	return a.Shutdown(ctx)
+}
+
+// Settings returns settings App was constructed with
+func (a *App) Settings() *confutil.Settings {
+	return a.settings
+}
+
+// DBType returns type of database of App
+func (a *App) DBType() string {
+	return a.config.DBType
+}
+
+// DB returns database of App
+func (a *App) DB() httputil.DBInterfaceV2 {
+	return a.db
+}
+
+// Cache returns cache of App, which is nil if cache is not configured
+func (a *App) Cache() cacheutil.CacheStore {
+	return a.cache
+}
+
+// Mailer returns mailer of App, which is nil if email is not configured
+func (a *App) Mailer() mailutil.SendMessage {
+	return a.mailer
+}
+
+// Storage returns storage of App, which is nil if AppConfig#StorageName
+// is not set
+// If constructed from settings, it is *storageutil.Bucket
+func (a *App) Storage() storageutil.StorageReaderWriter {
+	return a.storage
+}
+
+// Pingers returns every component of App that can be pinged keyed by
+// "db", "cache", "sessions", "mailer" and "storage" so they can be
+// health checked with apiutil#HealthHandler
+// Caches are pinged with cacheutil#CachePinger and session stores with
+// cacheutil#SessionPinger if they don't implement httputil#Pinger
+func (a *App) Pingers() map[string]httputil.Pinger {
+	pingers := make(map[string]httputil.Pinger)
+
+	if pinger, ok := a.db.(httputil.Pinger); ok {
+		pingers["db"] = pinger
+	}
+	if a.cache != nil {
+		pingers["cache"] = cacheutil.CachePinger(a.cache)
+	}
+
+	if pinger := a.sessionPinger(); pinger != nil {
+		pingers["sessions"] = pinger
+	}
+
+	if pinger, ok := a.mailer.(httputil.Pinger); ok {
+		pingers["mailer"] = pinger
+	}
+	if pinger, ok := a.storage.(httputil.Pinger); ok {
+		pingers["storage"] = pinger
+	}
+
+	return pingers
+}
+
+// Logger returns logger of App
+// Default is httputil#Logger
+func (a *App) Logger() *logrus.Logger {
+	if a.logger == nil {
+		return httputil.Logger
+	}
+
+	return a.logger
+}
+
+// FormValidator returns *formutil.FormValidation using database and
+// cache of App
+func (a *App) FormValidator() *formutil.FormValidation {
+	return GetFormValidator(a.db, a.cache)
+}
+
+// Template returns templates of confutil#Settings#TemplatesDir
+func (a *App) Template() *template.Template {
+	return GetTemplate(a.settings)
+}
+
+// CSRF returns csrf middleware using settings of App
+func (a *App) CSRF(cookieName string) func(http.Handler) http.Handler {
+	return GetCSRF(a.settings, cookieName)
+}
+
+func (a *App) hasEmail() bool {
+	if a.settings.EmailConfig.TestMode {
+		return a.settings.EmailConfig.TestEmail != nil
+	}
+
+	return a.settings.EmailConfig.LiveEmail != nil
+}
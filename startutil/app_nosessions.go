@@ -0,0 +1,20 @@
+//go:build nosessions
+// +build nosessions
+
+package startutil
+
+import (
+	"github.com/TravisS25/httputil"
+)
+
+// appSessions is empty as session store of App is excluded by the
+// nosessions build tag
+type appSessions struct{}
+
+func (a *App) startSessionStore() error {
+	return nil
+}
+
+func (a *App) sessionPinger() httputil.Pinger {
+	return nil
+}
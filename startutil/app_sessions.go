@@ -0,0 +1,49 @@
+//go:build !nosessions
+// +build !nosessions
+
+package startutil
+
+import (
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/cacheutil"
+)
+
+// appSessions is session store of App
+type appSessions struct {
+	sessionStore sessions.Store
+}
+
+// WithSessionStore substitutes session store of App
+func WithSessionStore(store sessions.Store) AppOption {
+	return func(a *App) {
+		a.sessionStore = store
+	}
+}
+
+// SessionStore returns session store of App
+func (a *App) SessionStore() sessions.Store {
+	return a.sessionStore
+}
+
+func (a *App) startSessionStore() error {
+	if a.sessionStore != nil {
+		return nil
+	}
+
+	var err error
+	a.sessionStore, err = GetStoreSettings(a.settings)
+	return err
+}
+
+func (a *App) sessionPinger() httputil.Pinger {
+	switch store := a.sessionStore.(type) {
+	case httputil.Pinger:
+		return store
+	case cacheutil.SessionStore:
+		return cacheutil.SessionPinger(store)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build !nosessions
+// +build !nosessions
+
+package startutil_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil/cacheutil/cachetest"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/dbutil/dbtest"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+// pingDB is database that can be pinged and counts how many times
+// it's closed
+type pingDB struct {
+	*dbtest.ExpectDB
+	closed int
+}
+
+func (p *pingDB) PingContext(ctx context.Context) error {
+	return nil
+}
+
+func (p *pingDB) Close() error {
+	p.closed++
+	return nil
+}
+
+func TestApp(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+
+	db := &pingDB{ExpectDB: dbtest.NewExpectDB(t)}
+	app := startutil.NewApp(
+		&confutil.Settings{},
+		startutil.AppConfig{Banner: &startutil.BannerConfig{Name: "test"}},
+		startutil.WithDB(db),
+		startutil.WithCache(cachetest.NewMemoryCache()),
+		startutil.WithSessionStore(cachetest.NewMemorySessionStore()),
+		startutil.WithLogger(logger),
+	)
+
+	if err := app.Stop(); err != startutil.ErrAppNotStarted {
+		t.Errorf("should return ErrAppNotStarted before start; got %v", err)
+	}
+
+	if err := app.Start(); err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	if !strings.Contains(logs.String(), "starting test") {
+		t.Errorf("should log banner; got %s", logs.String())
+	}
+	if app.DB() != db || app.Cache() == nil || app.SessionStore() == nil {
+		t.Errorf("should use substituted components")
+	}
+	if app.DBType() != dbutil.Postgres {
+		t.Errorf("db type should default to %s; got %s", dbutil.Postgres, app.DBType())
+	}
+	if app.Mailer() != nil || app.Storage() != nil {
+		t.Errorf("mailer and storage should be nil when not configured")
+	}
+
+	pingers := app.Pingers()
+
+	for _, name := range []string{"db", "cache", "sessions"} {
+		pinger, ok := pingers[name]
+
+		if !ok {
+			t.Errorf("should have pinger %s; got %v", name, pingers)
+			continue
+		}
+		if err := pinger.PingContext(context.Background()); err != nil {
+			t.Errorf("pinger %s should not return error; got %s", name, err.Error())
+		}
+	}
+
+	if len(pingers) != 3 {
+		t.Errorf("should have 3 pingers; got %v", pingers)
+	}
+
+	if err := app.Stop(); err != nil {
+		t.Errorf("should not return error; got %s", err.Error())
+	}
+	if db.closed != 0 {
+		t.Errorf("should not close substituted db")
+	}
+	if err := app.Stop(); err != startutil.ErrAppNotStarted {
+		t.Errorf("should return ErrAppNotStarted once stopped; got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package startutil_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+// fakeCloses counts connections of fakeDriver that were closed
+var fakeCloses int32
+
+func init() {
+	// The sqlite driver is not linked into these tests so GetDB can open
+	// connections to fakeDriver through dbutil#Sqlite
+	sql.Register(dbutil.Sqlite, fakeDriver{})
+}
+
+// fakeDriver is database driver where every query succeeds with no rows
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeConn) Close() error {
+	atomic.AddInt32(&fakeCloses, 1)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestAppStartClosesDB(t *testing.T) {
+	settings := &confutil.Settings{
+		DatabaseConfig: confutil.DatabaseConfig{
+			Prod: &confutil.Database{DBName: "app.db"},
+		},
+		Cache: confutil.CacheConfig{
+			Redis:      &confutil.RedisCache{Address: "localhost:6379"},
+			EncryptKey: "tooshort",
+		},
+	}
+
+	closes := atomic.LoadInt32(&fakeCloses)
+	app := startutil.NewApp(settings, startutil.AppConfig{DBType: dbutil.Sqlite})
+	err := app.Start()
+
+	if err == nil || !strings.HasPrefix(err.Error(), "startutil: cache") {
+		t.Fatalf("should return cache error; got %v", err)
+	}
+	if atomic.LoadInt32(&fakeCloses) == closes {
+		t.Errorf("should close db when start fails")
+	}
+	if app.DB() != nil {
+		t.Errorf("db should be nil when start fails")
+	}
+	if err = app.Stop(); err != startutil.ErrAppNotStarted {
+		t.Errorf("should return ErrAppNotStarted; got %v", err)
+	}
+}
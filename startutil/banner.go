@@ -0,0 +1,148 @@
+package startutil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+)
+
+// BannerConfig is config struct used for LogBanner
+type BannerConfig struct {
+	// Name and Version are name and version of app logged with banner
+	Name    string
+	Version string
+
+	// Features are feature toggles logged with banner eg. which flags
+	// of flagutil are on
+	Features map[string]bool
+
+	// Logger is logger banner is logged with
+	// Default is httputil#Logger
+	Logger *logrus.Logger
+}
+
+// LogBanner logs the effective configuration of settings at info level,
+// which is the resolved profile, server address, database hosts, cache
+// and session store endpoints, storage endpoints and feature toggles,
+// so deploys can be verified from logs
+//
+// The whole of settings, with secrets masked by confutil#Masked, is
+// logged at debug level
+func LogBanner(settings *confutil.Settings, config BannerConfig) {
+	if config.Logger == nil {
+		config.Logger = httputil.Logger
+	}
+
+	fields := logrus.Fields{
+		"profile":    settings.ActiveProfile,
+		"prod":       settings.Prod,
+		"domain":     settings.Domain,
+		"address":    settings.Server.Address,
+		"databases":  bannerDatabases(settings),
+		"caches":     bannerCaches(settings),
+		"sessions":   bannerSessionStore(settings.Store),
+		"storage":    bannerStorage(settings.S3Config),
+		"migrations": settings.Migrations.RunOnBoot,
+	}
+
+	if config.Version != "" {
+		fields["version"] = config.Version
+	}
+	if len(config.Features) > 0 {
+		fields["features"] = config.Features
+	}
+
+	name := config.Name
+
+	if name == "" {
+		name = "app"
+	}
+
+	config.Logger.WithFields(fields).Infof("starting %s", name)
+	config.Logger.WithField("config", confutil.Masked(settings)).Debug("effective configuration")
+}
+
+func bannerDatabases(settings *confutil.Settings) map[string][]string {
+	databases := make(map[string][]string)
+	hosts := func(list ...*confutil.Database) []string {
+		h := make([]string, 0, len(list))
+
+		for _, db := range list {
+			if db != nil {
+				h = append(h, fmt.Sprintf("%s:%s/%s", db.Host, db.Port, db.DBName))
+			}
+		}
+
+		return h
+	}
+
+	dbConfig := settings.DatabaseConfig.Prod
+
+	if settings.DatabaseConfig.TestMode {
+		dbConfig = settings.DatabaseConfig.Test
+	}
+	if dbConfig != nil {
+		databases["default"] = hosts(dbConfig)
+	}
+
+	for name, list := range settings.Databases {
+		ptrs := make([]*confutil.Database, 0, len(list))
+
+		for i := range list {
+			ptrs = append(ptrs, &list[i])
+		}
+
+		databases[name] = hosts(ptrs...)
+	}
+
+	return databases
+}
+
+func bannerCaches(settings *confutil.Settings) map[string]string {
+	caches := make(map[string]string)
+
+	if settings.Cache.Redis != nil {
+		caches["default"] = settings.Cache.Redis.Address
+	}
+
+	for name, cache := range settings.Caches {
+		if cache.Redis != nil {
+			caches[name] = cache.Redis.Address
+		}
+	}
+
+	return caches
+}
+
+// bannerSessionStore returns which store GetStoreSettings constructs
+func bannerSessionStore(store confutil.StoreConfig) string {
+	switch {
+	case store.Redis != nil:
+		return "redis " + store.Redis.Address
+	case store.FileSystemStore != nil:
+		return "filesystem"
+	default:
+		return "cookie"
+	}
+}
+
+func bannerStorage(s3 confutil.S3Config) []string {
+	storage := make([]string, 0, len(s3))
+
+	for name, v := range s3 {
+		bucket := v.Bucket
+
+		if bucket == "" {
+			bucket = name
+		}
+
+		storage = append(storage, fmt.Sprintf("%s=%s/%s", name, v.EndPoint, bucket))
+	}
+
+	sort.Strings(storage)
+	return storage
+}
@@ -0,0 +1,26 @@
+//go:build !noredis
+// +build !noredis
+
+package startutil
+
+import (
+	"github.com/go-redis/redis"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+)
+
+// newClientCache returns cache of cacheConf, which is nil if redis is
+// not configured
+func newClientCache(cacheConf confutil.CacheConfig) (cacheutil.CacheStore, error) {
+	if cacheConf.Redis == nil {
+		return nil, nil
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cacheConf.Redis.Address,
+		Password: cacheConf.Redis.Password,
+		DB:       cacheConf.Redis.DB,
+	})
+	return cacheutil.NewClientCache(redisClient), nil
+}
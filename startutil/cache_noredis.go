@@ -0,0 +1,18 @@
+//go:build noredis
+// +build noredis
+
+package startutil
+
+import (
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+)
+
+// newClientCache returns ErrRedisExcluded if redis is configured
+func newClientCache(cacheConf confutil.CacheConfig) (cacheutil.CacheStore, error) {
+	if cacheConf.Redis == nil {
+		return nil, nil
+	}
+
+	return nil, ErrRedisExcluded
+}
@@ -0,0 +1,32 @@
+//go:build noredis
+// +build noredis
+
+package startutil_test
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+func TestGetCache(t *testing.T) {
+	settings := &confutil.Settings{
+		Cache: confutil.CacheConfig{
+			Redis: &confutil.RedisCache{Address: "localhost:6379"},
+		},
+		Caches: map[string]confutil.CacheConfig{
+			"sessions": {Redis: &confutil.RedisCache{Address: "localhost:6379"}},
+		},
+	}
+
+	if _, err := startutil.GetCache(settings, "sessions"); err != startutil.ErrRedisExcluded {
+		t.Errorf("should return ErrRedisExcluded; got %v", err)
+	}
+	if _, err := startutil.GetEncryptedCacheSettings(settings); err != startutil.ErrRedisExcluded {
+		t.Errorf("should return ErrRedisExcluded; got %v", err)
+	}
+	if cache := startutil.GetCacheSettings(settings); cache != nil {
+		t.Errorf("cache should be nil; got %T", cache)
+	}
+}
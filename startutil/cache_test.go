@@ -0,0 +1,45 @@
+//go:build !noredis
+// +build !noredis
+
+package startutil_test
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/cacheutil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+func TestGetCache(t *testing.T) {
+	settings := &confutil.Settings{
+		Caches: map[string]confutil.CacheConfig{
+			"sessions":  {Redis: &confutil.RedisCache{Address: "localhost:6379"}},
+			"encrypted": {Redis: &confutil.RedisCache{Address: "localhost:6379"}, EncryptKey: "01234567890123456789012345678901"},
+			"invalid":   {Redis: &confutil.RedisCache{Address: "localhost:6379"}, EncryptKey: "tooshort"},
+			"noredis":   {},
+		},
+	}
+
+	if _, err := startutil.GetCache(settings, "missing"); err == nil {
+		t.Errorf("should return error for cache not configured")
+	}
+	if _, err := startutil.GetCache(settings, "noredis"); err == nil {
+		t.Errorf("should return error for cache without redis")
+	}
+	if _, err := startutil.GetCache(settings, "invalid"); err == nil {
+		t.Errorf("should return error for invalid encrypt key")
+	}
+
+	cache, err := startutil.GetCache(settings, "sessions")
+
+	if _, ok := cache.(*cacheutil.ClientCache); err != nil || !ok {
+		t.Errorf("should return *cacheutil.ClientCache; got %T, %v", cache, err)
+	}
+
+	cache, err = startutil.GetCache(settings, "encrypted")
+
+	if _, ok := cache.(*cacheutil.EncryptedCache); err != nil || !ok {
+		t.Errorf("should return *cacheutil.EncryptedCache; got %T, %v", cache, err)
+	}
+}
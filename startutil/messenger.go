@@ -0,0 +1,33 @@
+//go:build !nogomail
+// +build !nogomail
+
+package startutil
+
+import (
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+// GetMessenger returns mailutil#MailMessenger of test email of conf if
+// it's in test mode, else of live email
+func GetMessenger(conf *confutil.Settings) mailutil.SendMessage {
+	var mailer mailutil.SendMessage
+
+	if conf.EmailConfig.TestMode {
+		mailer = mailutil.NewMailMessenger(mailutil.MailerConfig{
+			Host:     conf.EmailConfig.TestEmail.Host,
+			Port:     conf.EmailConfig.TestEmail.Port,
+			User:     conf.EmailConfig.TestEmail.User,
+			Password: conf.EmailConfig.TestEmail.Password,
+		})
+	} else {
+		mailer = mailutil.NewMailMessenger(mailutil.MailerConfig{
+			Host:     conf.EmailConfig.LiveEmail.Host,
+			Port:     conf.EmailConfig.LiveEmail.Port,
+			User:     conf.EmailConfig.LiveEmail.User,
+			Password: conf.EmailConfig.LiveEmail.Password,
+		})
+	}
+
+	return mailer
+}
@@ -0,0 +1,15 @@
+//go:build nogomail
+// +build nogomail
+
+package startutil
+
+import (
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/mailutil"
+)
+
+// GetMessenger returns nil as mailutil#MailMessenger is excluded by the
+// nogomail build tag so mailer of App must be set with WithMailer
+func GetMessenger(conf *confutil.Settings) mailutil.SendMessage {
+	return nil
+}
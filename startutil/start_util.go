@@ -1,19 +1,35 @@
 package startutil
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/TravisS25/httputil/formutil"
 
 	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/cacheutil"
 	"github.com/TravisS25/httputil/confutil"
-	"github.com/TravisS25/httputil/mailutil"
-	"github.com/go-redis/redis"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/sentryutil"
+	"github.com/TravisS25/httputil/storageutil"
+	"github.com/TravisS25/httputil/templateutil"
 	"github.com/gorilla/csrf"
-	"github.com/gorilla/sessions"
-	redistore "gopkg.in/boj/redistore.v1"
+	minio "github.com/minio/minio-go"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoDatabase is returned by GetDB if the database of
+	// confutil#Settings#DatabaseConfig for current mode is not set
+	ErrNoDatabase = errors.New("startutil: database not configured")
+
+	// ErrRedisExcluded is returned when a cache or session store is
+	// configured to use redis but the package was built with noredis
+	ErrRedisExcluded = errors.New("startutil: redis excluded by noredis build tag")
 )
 
 func GetFormValidator(db httputil.Querier, cache cacheutil.CacheStore) *formutil.FormValidation {
@@ -23,122 +39,226 @@ func GetFormValidator(db httputil.Querier, cache cacheutil.CacheStore) *formutil
 	return formValidation
 }
 
+// SelectProfile returns settings with the profile named by the value of
+// the given environment variable applied
+// If the environment variable is not set, a copy of the base settings is returned
+//
+// The returned settings are what should be passed to the rest of
+// the startutil helpers
+func SelectProfile(conf *confutil.Settings, envVar string) (*confutil.Settings, error) {
+	return conf.Select(os.Getenv(envVar))
+}
+
 // func SetConfigSettings(conf *confutil.Settings, envVar string) {
 // 	conf = confutil.ConfigSettings(envVar)
 // 	fmt.Println(conf.Cache.Redis.Address)
 // }
 
-func getCacheSettings(conf *confutil.Settings) *cacheutil.ClientCache {
-	if conf.Cache.Redis != nil {
-		redisClient := redis.NewClient(&redis.Options{
-			Addr:     conf.Cache.Redis.Address,
-			Password: conf.Cache.Redis.Password,
-			DB:       conf.Cache.Redis.DB,
-		})
-		return cacheutil.NewClientCache(redisClient)
+// GetCacheSettings returns cache of conf.Cache, which is nil if redis
+// is not configured or if built with noredis
+func GetCacheSettings(conf *confutil.Settings) cacheutil.CacheStore {
+	cache, _ := newClientCache(conf.Cache)
+	return cache
+}
+
+// GetEncryptedCacheSettings is the same as GetCacheSettings but wraps
+// cache with cacheutil#EncryptedCache if conf.Cache.EncryptKey is set
+func GetEncryptedCacheSettings(conf *confutil.Settings) (cacheutil.CacheStore, error) {
+	return newCache(conf.Cache)
+}
+
+// GetCache returns cache of conf.Caches with given name, wrapped with
+// cacheutil#EncryptedCache if its EncryptKey is set
+func GetCache(conf *confutil.Settings, name string) (cacheutil.CacheStore, error) {
+	cacheConf, ok := conf.Caches[name]
+
+	if !ok {
+		return nil, fmt.Errorf("startutil: cache '%s' not configured", name)
+	}
+
+	cache, err := newCache(cacheConf)
+
+	if err != nil {
+		return nil, err
+	}
+	if cache == nil {
+		return nil, fmt.Errorf("startutil: cache '%s' has no redis settings", name)
 	}
 
-	return nil
+	return cache, nil
 }
 
-func GetCacheSettings(conf *confutil.Settings) cacheutil.CacheStore {
-	return getCacheSettings(conf)
+// GetBucket returns storage client of conf.S3Config with given name
+// along with its bucket
+// Name of the bucket is the name of the storage if S3Storage#Bucket
+// is not set
+func GetBucket(conf *confutil.Settings, name string) (*storageutil.Bucket, error) {
+	s3, ok := conf.S3Config[name]
+
+	if !ok {
+		return nil, fmt.Errorf("startutil: storage '%s' not configured", name)
+	}
+
+	client, err := minio.New(s3.EndPoint, s3.AccessKeyID, s3.SecretAccessKey, s3.UseSSL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := &storageutil.Bucket{
+		StorageReaderWriter: storageutil.NewMinioStorage(client),
+		Name:                s3.Bucket,
+	}
+
+	if bucket.Name == "" {
+		bucket.Name = name
+	}
+
+	return bucket, nil
+}
+
+func newCache(cacheConf confutil.CacheConfig) (cacheutil.CacheStore, error) {
+	cache, err := newClientCache(cacheConf)
+
+	if cache == nil || err != nil {
+		return nil, err
+	}
+
+	if cacheConf.EncryptKey == "" {
+		return cache, nil
+	}
+
+	encryptedCache, err := cacheutil.NewEncryptedCache(cache, []byte(cacheConf.EncryptKey))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedCache, nil
+}
+
+// SetErrorReporter sets httputil#Reporter to sentryutil#Reporter if
+// conf.Sentry.DSN is set, and returns it so it can be flushed before
+// exiting
+// Returns nil if conf.Sentry.DSN is not set
+func SetErrorReporter(conf *confutil.Settings) (*sentryutil.Reporter, error) {
+	if conf.Sentry.DSN == "" {
+		return nil, nil
+	}
+
+	reporter, err := sentryutil.NewReporter(sentryutil.Config{
+		DSN:         conf.Sentry.DSN,
+		Environment: conf.Sentry.Environment,
+		Release:     conf.Sentry.Release,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	httputil.Reporter = reporter
+	return reporter, nil
 }
 
 // func GetCacheSettingsV2(conf *confutil.Settings) cacheutil.CacheStore {
 // 	return getCacheSettings(conf)
 // }
 
-// func GetDB(conf *confutil.Settings, isProd bool) (*dbutil.DB, error) {
-// 	var err error
-// 	var db *dbutil.DB
-
-// 	if isProd {
-// 		db, err = dbutil.NewDB(dbutil.DBConfig{
-// 			Host:     conf.DatabaseConfig.Prod.Host,
-// 			User:     conf.DatabaseConfig.Prod.User,
-// 			Password: conf.DatabaseConfig.Prod.Password,
-// 			DBName:   conf.DatabaseConfig.Prod.DBName,
-// 			Port:     conf.DatabaseConfig.Prod.Port,
-// 			SSLMode:  conf.DatabaseConfig.Prod.SSlMode,
-// 		}, dbutil.Postgres)
-// 	} else {
-// 		db, err = dbutil.NewDB(dbutil.DBConfig{
-// 			Host:     conf.DatabaseConfig.Test.Host,
-// 			User:     conf.DatabaseConfig.Test.User,
-// 			Password: conf.DatabaseConfig.Test.Password,
-// 			DBName:   conf.DatabaseConfig.Test.DBName,
-// 			Port:     conf.DatabaseConfig.Test.Port,
-// 			SSLMode:  conf.DatabaseConfig.Test.SSlMode,
-// 		}, dbutil.Postgres)
-// 	}
-
-// 	if err != nil {
-// 		panic(err)
-// 	}
-
-// 	return db, err
-// }
+// GetDB returns database connection of conf
+//
+// If name is set, the list of conf.Databases with that name is used,
+// where each database is tried in order until one connects and the
+// rest are used to fail over with DB#RecoverError
+// Else conf.DatabaseConfig.Test is used if conf.DatabaseConfig.TestMode
+// is set, and conf.DatabaseConfig.Prod if not
+//
+// Pool settings of each confutil#Database are applied to the connection
+func GetDB(conf *confutil.Settings, name, dbType string) (httputil.DBInterfaceV2, error) {
+	var dbConfigList []confutil.Database
+
+	if name != "" {
+		list, ok := conf.Databases[name]
+
+		if !ok {
+			return nil, fmt.Errorf("startutil: databases '%s' not configured", name)
+		}
 
-func GetStoreSettings(conf *confutil.Settings) (sessions.Store, error) {
-	var err error
-	var store sessions.Store
-
-	if conf.Store.Redis != nil {
-		store, err = redistore.NewRediStore(
-			conf.Store.Redis.Size,
-			conf.Store.Redis.Network,
-			conf.Store.Redis.Address,
-			conf.Store.Redis.Password,
-			[]byte(conf.Store.Redis.AuthKey),
-			[]byte(conf.Store.Redis.EncryptKey),
-		)
-	} else if conf.Store.FileSystemStore != nil {
-		store = sessions.NewFilesystemStore(
-			"/tmp",
-			[]byte(conf.Store.FileSystemStore.AuthKey),
-			[]byte(conf.Store.FileSystemStore.EncryptKey),
-		)
+		dbConfigList = list
 	} else {
-		store = sessions.NewCookieStore(
-			[]byte(conf.Store.CookieStore.AuthKey),
-			[]byte(conf.Store.CookieStore.EncryptKey),
-		)
+		dbConfig := conf.DatabaseConfig.Prod
+
+		if conf.DatabaseConfig.TestMode {
+			dbConfig = conf.DatabaseConfig.Test
+		}
+		if dbConfig == nil {
+			return nil, ErrNoDatabase
+		}
+
+		dbConfigList = []confutil.Database{*dbConfig}
 	}
 
+	db, err := dbutil.NewDBWithList(dbConfigList, dbType)
+
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return store, err
+	return db, nil
 }
 
-func GetMessenger(conf *confutil.Settings) mailutil.SendMessage {
-	var mailer mailutil.SendMessage
-
-	if conf.EmailConfig.TestMode {
-		mailer = mailutil.NewMailMessenger(mailutil.MailerConfig{
-			Host:     conf.EmailConfig.TestEmail.Host,
-			Port:     conf.EmailConfig.TestEmail.Port,
-			User:     conf.EmailConfig.TestEmail.User,
-			Password: conf.EmailConfig.TestEmail.Password,
-		})
-	} else {
-		mailer = mailutil.NewMailMessenger(mailutil.MailerConfig{
-			Host:     conf.EmailConfig.LiveEmail.Host,
-			Port:     conf.EmailConfig.LiveEmail.Port,
-			User:     conf.EmailConfig.LiveEmail.User,
-			Password: conf.EmailConfig.LiveEmail.Password,
-		})
+// RunMigrations applies pending migrations from conf.Migrations.Dir
+// if conf.Migrations.RunOnBoot is set and returns the number applied
+func RunMigrations(conf *confutil.Settings, db httputil.DBInterface, dbType string) (int, error) {
+	if !conf.Migrations.RunOnBoot {
+		return 0, nil
 	}
 
-	return mailer
+	return dbutil.Migrate(db, dbutil.MigrateConfig{
+		Dir:       conf.Migrations.Dir,
+		TableName: conf.Migrations.Table,
+		DBType:    dbType,
+	})
 }
 
 func GetTemplate(conf *confutil.Settings) *template.Template {
 	return template.Must(template.ParseGlob(conf.TemplatesDir))
 }
 
+// GetRenderer returns *templateutil.Renderer of templates within
+// conf.TemplatesDir, which may also be a glob like GetTemplate uses in
+// which case its directory is used
+// Templates are reloaded on every render if conf is not Prod
+func GetRenderer(conf *confutil.Settings, config templateutil.Config) (*templateutil.Renderer, error) {
+	dir := conf.TemplatesDir
+
+	if strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+
+	config.Dir = dir
+	config.Reload = !conf.Prod
+	return templateutil.New(config)
+}
+
+// NewServer returns *http.Server that serves handler with address and
+// timeouts of conf.Server
+// Address defaults to ":8080"
+func NewServer(conf *confutil.Settings, handler http.Handler) *http.Server {
+	address := conf.Server.Address
+
+	if address == "" {
+		address = ":8080"
+	}
+
+	return &http.Server{
+		Addr:         address,
+		Handler:      handler,
+		ReadTimeout:  conf.Server.ReadTimeout.Duration,
+		WriteTimeout: conf.Server.WriteTimeout.Duration,
+		IdleTimeout:  conf.Server.IdleTimeout.Duration,
+	}
+}
+
 func GetCSRF(conf *confutil.Settings, cookieName string) func(http.Handler) http.Handler {
 	return csrf.Protect([]byte(conf.CSRF), csrf.Secure(conf.HTTPS), csrf.CookieName(cookieName))
 }
@@ -0,0 +1,79 @@
+package startutil_test
+
+import (
+	"testing"
+
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/dbutil"
+	"github.com/TravisS25/httputil/startutil"
+)
+
+func TestGetDB(t *testing.T) {
+	settings := &confutil.Settings{}
+
+	if _, err := startutil.GetDB(settings, "", dbutil.Sqlite); err != startutil.ErrNoDatabase {
+		t.Errorf("should return ErrNoDatabase; got %v", err)
+	}
+	if _, err := startutil.GetDB(settings, "replicas", dbutil.Sqlite); err == nil {
+		t.Errorf("should return error for databases not configured")
+	}
+
+	settings.DatabaseConfig = confutil.DatabaseConfig{
+		TestMode: true,
+		Prod:     &confutil.Database{DBName: "prod.db"},
+	}
+
+	if _, err := startutil.GetDB(settings, "", dbutil.Sqlite); err != startutil.ErrNoDatabase {
+		t.Errorf("should return ErrNoDatabase without test database in test mode; got %v", err)
+	}
+
+	settings.DatabaseConfig.TestMode = false
+	settings.Databases = map[string][]confutil.Database{
+		"replicas": {{DBName: "replica.db"}},
+	}
+
+	for _, name := range []string{"", "replicas"} {
+		db, err := startutil.GetDB(settings, name, dbutil.Sqlite)
+
+		if err != nil {
+			t.Fatalf("should not return error for '%s'; got %s", name, err.Error())
+		}
+
+		dbutilDB, ok := db.(*dbutil.DB)
+
+		if !ok {
+			t.Fatalf("should return *dbutil.DB; got %T", db)
+		}
+
+		dbutilDB.Close()
+	}
+}
+
+func TestGetBucket(t *testing.T) {
+	settings := &confutil.Settings{
+		S3Config: confutil.S3Config{
+			"uploads": {EndPoint: "localhost:9000"},
+			"exports": {EndPoint: "localhost:9000", Bucket: "files"},
+		},
+	}
+
+	if _, err := startutil.GetBucket(settings, "missing"); err == nil {
+		t.Errorf("should return error for storage not configured")
+	}
+
+	tests := map[string]string{
+		"uploads": "uploads",
+		"exports": "files",
+	}
+
+	for name, bucketName := range tests {
+		bucket, err := startutil.GetBucket(settings, name)
+
+		if err != nil {
+			t.Fatalf("should not return error for %s; got %s", name, err.Error())
+		}
+		if bucket.Name != bucketName {
+			t.Errorf("bucket of %s should be %s; got %s", name, bucketName, bucket.Name)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !nosessions
+// +build !nosessions
+
+package startutil
+
+import (
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+// GetStoreSettings returns session store of conf.Store
+// A redis store can't be constructed if built with noredis
+func GetStoreSettings(conf *confutil.Settings) (sessions.Store, error) {
+	var err error
+	var store sessions.Store
+
+	if conf.Store.Redis != nil {
+		store, err = newRedisStore(conf.Store.Redis)
+	} else if conf.Store.FileSystemStore != nil {
+		store = sessions.NewFilesystemStore(
+			"/tmp",
+			[]byte(conf.Store.FileSystemStore.AuthKey),
+			[]byte(conf.Store.FileSystemStore.EncryptKey),
+		)
+	} else {
+		store = sessions.NewCookieStore(
+			[]byte(conf.Store.CookieStore.AuthKey),
+			[]byte(conf.Store.CookieStore.EncryptKey),
+		)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return store, err
+}
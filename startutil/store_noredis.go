@@ -0,0 +1,14 @@
+//go:build noredis && !nosessions
+// +build noredis,!nosessions
+
+package startutil
+
+import (
+	"github.com/gorilla/sessions"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+func newRedisStore(conf *confutil.RedisSession) (sessions.Store, error) {
+	return nil, ErrRedisExcluded
+}
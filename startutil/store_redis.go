@@ -0,0 +1,22 @@
+//go:build !noredis && !nosessions
+// +build !noredis,!nosessions
+
+package startutil
+
+import (
+	"github.com/gorilla/sessions"
+	redistore "gopkg.in/boj/redistore.v1"
+
+	"github.com/TravisS25/httputil/confutil"
+)
+
+func newRedisStore(conf *confutil.RedisSession) (sessions.Store, error) {
+	return redistore.NewRediStore(
+		conf.Size,
+		conf.Network,
+		conf.Address,
+		conf.Password,
+		[]byte(conf.AuthKey),
+		[]byte(conf.EncryptKey),
+	)
+}
@@ -1,11 +1,22 @@
 package storageutil
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/url"
 	"time"
 
 	minio "github.com/minio/minio-go"
+
+	"github.com/TravisS25/httputil"
+)
+
+var (
+	// ErrBucketNotFound is returned from Bucket#PingContext if storage
+	// is reachable but bucket doesn't exist
+	ErrBucketNotFound = errors.New("storageutil: bucket not found")
 )
 
 type StorageReaderWriter interface {
@@ -14,3 +25,58 @@ type StorageReaderWriter interface {
 	PutObject(bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (n int64, err error)
 	RemoveObject(bucketName, objectName string) error
 }
+
+// Bucket is StorageReaderWriter along with the name of the bucket
+// it should be used with
+type Bucket struct {
+	StorageReaderWriter
+	Name string
+}
+
+// PingContext returns error if storage of bucket is unreachable or, if
+// storage can check for buckets like *minio.Client, bucket doesn't exist
+// Storage that can do neither is assumed to be reachable
+// It implements httputil#Pinger
+func (b *Bucket) PingContext(ctx context.Context) error {
+	if exister, ok := b.StorageReaderWriter.(BucketExister); ok {
+		exists, err := exister.BucketExists(b.Name)
+
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w '%s'", ErrBucketNotFound, b.Name)
+		}
+
+		return nil
+	}
+
+	if pinger, ok := b.StorageReaderWriter.(httputil.Pinger); ok {
+		return pinger.PingContext(ctx)
+	}
+
+	return nil
+}
+
+// BucketExister is implemented by storages that can check if a bucket
+// exists like *minio.Client
+type BucketExister interface {
+	BucketExists(bucketName string) (bool, error)
+}
+
+// MinioStorage is *minio.Client that can be pinged
+type MinioStorage struct {
+	*minio.Client
+}
+
+// NewMinioStorage returns *MinioStorage
+func NewMinioStorage(client *minio.Client) *MinioStorage {
+	return &MinioStorage{Client: client}
+}
+
+// PingContext lists buckets to determine if storage is reachable
+// It implements httputil#Pinger
+func (m *MinioStorage) PingContext(ctx context.Context) error {
+	_, err := m.Client.ListBuckets()
+	return err
+}
@@ -0,0 +1,380 @@
+// Package templateutil renders html templates composed of layouts,
+// partials and pages with a standard set of funcs and per-request data
+// like the csrf token and user
+package templateutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/csrf"
+
+	"github.com/TravisS25/httputil/apiutil"
+	"github.com/TravisS25/httputil/confutil"
+	"github.com/TravisS25/httputil/timeutil"
+)
+
+const (
+	// DefaultLayoutsDir is default Config#LayoutsDir
+	DefaultLayoutsDir = "layouts"
+
+	// DefaultPartialsDir is default Config#PartialsDir
+	DefaultPartialsDir = "partials"
+
+	// DefaultExtension is default Config#Extension
+	DefaultExtension = ".html"
+)
+
+var (
+	// ErrEmptyDir is returned from New when Config#Dir is not set
+	ErrEmptyDir = errors.New("templateutil: templates dir can't be empty")
+)
+
+// TemplateNotFoundError is returned when rendering page that doesn't exist
+type TemplateNotFoundError struct {
+	Name string
+}
+
+func (t *TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("templateutil: template '%s' not found", t.Name)
+}
+
+// Config is config struct used for Renderer
+type Config struct {
+	// Dir is root directory of templates - Required
+	//
+	// Every file with Extension under Dir, outside of LayoutsDir and
+	// PartialsDir, is a page named by its path relative to Dir without
+	// extension eg. "users/list"
+	Dir string
+
+	// LayoutsDir is directory, relative to Dir, of layouts which pages
+	// are rendered within by defining the blocks the layout uses eg.
+	// {{define "content"}}...{{end}}
+	// Layouts are named by their file name without extension
+	// Default value is DefaultLayoutsDir
+	LayoutsDir string
+
+	// PartialsDir is directory, relative to Dir, of templates that are
+	// available to every page and layout
+	// Default value is DefaultPartialsDir
+	PartialsDir string
+
+	// Extension is extension of template files
+	// Default value is DefaultExtension
+	Extension string
+
+	// Layout is layout pages are rendered within
+	// If empty, pages are rendered on their own
+	Layout string
+
+	// Funcs are added to DefaultFuncs, replacing funcs of the same name
+	Funcs template.FuncMap
+
+	// Reload parses templates again on every render so changes show up
+	// without restarting, which should only be used in development
+	Reload bool
+
+	// RequestData, if set, returns extra data of request that is added
+	// to PageData#Request eg. decoded user
+	RequestData func(r *http.Request) map[string]interface{}
+}
+
+// PageData is data pages are rendered with by RenderRequest
+type PageData struct {
+	// Data is data passed to RenderRequest
+	Data interface{}
+
+	// CSRFToken is csrf token of request if handler is wrapped with
+	// csrf.Protect
+	CSRFToken string
+
+	// CSRFField is hidden input of csrf token to place within forms
+	CSRFField template.HTML
+
+	// UserID is id of user of request set by apiutil#AuthHandler
+	UserID string
+
+	// Lang is language of request, see apiutil#LanguageFromRequest
+	Lang string
+
+	// Request is data returned from Config#RequestData
+	Request map[string]interface{}
+}
+
+// Renderer renders pages of Config#Dir
+type Renderer struct {
+	config Config
+	mu     sync.RWMutex
+	pages  map[string]*template.Template
+}
+
+// New returns *Renderer with every page of config parsed
+func New(config Config) (*Renderer, error) {
+	if config.Dir == "" {
+		return nil, ErrEmptyDir
+	}
+	if config.LayoutsDir == "" {
+		config.LayoutsDir = DefaultLayoutsDir
+	}
+	if config.PartialsDir == "" {
+		config.PartialsDir = DefaultPartialsDir
+	}
+	if config.Extension == "" {
+		config.Extension = DefaultExtension
+	}
+
+	r := &Renderer{config: config}
+
+	if err := r.Parse(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Parse parses every page of Config#Dir again
+func (r *Renderer) Parse() error {
+	var layouts, partials []string
+
+	pages := make(map[string]string)
+	layoutsDir := filepath.Join(r.config.Dir, r.config.LayoutsDir)
+	partialsDir := filepath.Join(r.config.Dir, r.config.PartialsDir)
+
+	err := filepath.Walk(r.config.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != r.config.Extension {
+			return nil
+		}
+
+		switch {
+		case isWithin(layoutsDir, path):
+			layouts = append(layouts, path)
+		case isWithin(partialsDir, path):
+			partials = append(partials, path)
+		default:
+			rel, err := filepath.Rel(r.config.Dir, path)
+
+			if err != nil {
+				return err
+			}
+
+			pages[filepath.ToSlash(strings.TrimSuffix(rel, r.config.Extension))] = path
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	funcs := DefaultFuncs()
+
+	for k, v := range r.config.Funcs {
+		funcs[k] = v
+	}
+
+	parsed := make(map[string]*template.Template, len(pages))
+
+	for name, path := range pages {
+		tmpl := template.New(name).Funcs(funcs)
+
+		// Layouts and partials are parsed first so pages can redefine
+		// their blocks
+		for _, file := range append(append([]string{}, layouts...), partials...) {
+			if tmpl, err = parseFile(tmpl, file, r.config.Extension); err != nil {
+				return err
+			}
+		}
+
+		if tmpl, err = parseFile(tmpl, path, ""); err != nil {
+			return err
+		}
+
+		parsed[name] = tmpl.Lookup(name)
+	}
+
+	r.mu.Lock()
+	r.pages = parsed
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Render writes page with name, within Config#Layout if set, to w
+// Returns *TemplateNotFoundError if page doesn't exist
+func (r *Renderer) Render(w io.Writer, name string, data interface{}) error {
+	if r.config.Reload {
+		if err := r.Parse(); err != nil {
+			return err
+		}
+	}
+
+	r.mu.RLock()
+	tmpl, ok := r.pages[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return &TemplateNotFoundError{Name: name}
+	}
+
+	if r.config.Layout != "" {
+		return tmpl.ExecuteTemplate(w, r.config.Layout, data)
+	}
+
+	return tmpl.Execute(w, data)
+}
+
+// RenderHTML renders page with name to memory first and writes it as
+// html response, else writes server error if rendering fails
+func (r *Renderer) RenderHTML(w http.ResponseWriter, name string, data interface{}) {
+	var buf bytes.Buffer
+
+	if err := r.Render(&buf, name, data); err != nil {
+		apiutil.ServerError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// RenderRequest is the same as RenderHTML but page is rendered with
+// PageData of request that has data under PageData#Data
+func (r *Renderer) RenderRequest(w http.ResponseWriter, req *http.Request, name string, data interface{}) {
+	r.RenderHTML(w, name, r.PageData(req, data))
+}
+
+// PageData returns PageData of request with data
+func (r *Renderer) PageData(req *http.Request, data interface{}) PageData {
+	page := PageData{
+		Data:      data,
+		CSRFToken: csrf.Token(req),
+		CSRFField: csrf.TemplateField(req),
+		UserID:    apiutil.GetUserID(req),
+		Lang:      apiutil.LanguageFromRequest(req),
+	}
+
+	if r.config.RequestData != nil {
+		page.Request = r.config.RequestData(req)
+	}
+
+	return page
+}
+
+// DefaultFuncs returns funcs every template is parsed with
+//
+//	date       formats time with layout, default confutil#DateLayout
+//	localDate  formats date of time within time zone, see timeutil#LocalDateInUTC
+//	currency   formats amount with symbol and thousands separators eg. $1,234.50
+//	json       encodes value as json for use within scripts
+func DefaultFuncs() template.FuncMap {
+	return template.FuncMap{
+		"date":      formatDate,
+		"localDate": formatLocalDate,
+		"currency":  FormatCurrency,
+		"json":      toJSON,
+	}
+}
+
+// FormatCurrency formats amount with symbol, thousands separators and
+// two decimals eg. FormatCurrency("$", 1234.5) returns "$1,234.50"
+func FormatCurrency(symbol string, amount float64) string {
+	sign := ""
+
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var parts []string
+
+	for len(whole) > 3 {
+		parts = append([]string{whole[len(whole)-3:]}, parts...)
+		whole = whole[:len(whole)-3]
+	}
+
+	parts = append([]string{whole}, parts...)
+	return fmt.Sprintf("%s%s%s.%02d", sign, symbol, strings.Join(parts, ","), cents%100)
+}
+
+func formatDate(t time.Time, layout ...string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if len(layout) > 0 {
+		return t.Format(layout[0])
+	}
+
+	return t.Format(confutil.DateLayout)
+}
+
+func formatLocalDate(t time.Time, timeZone string) (string, error) {
+	if t.IsZero() {
+		return "", nil
+	}
+
+	date, err := timeutil.LocalDateInUTC(t, timeZone)
+
+	if err != nil {
+		return "", err
+	}
+
+	return date.Format(confutil.DateLayout), nil
+}
+
+func toJSON(v interface{}) (template.JS, error) {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	return template.JS(b), nil
+}
+
+// parseFile parses file into tmpl as template named by its file name
+// without extension, or as tmpl itself if extension is empty
+func parseFile(tmpl *template.Template, file, extension string) (*template.Template, error) {
+	source, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := tmpl
+
+	if extension != "" {
+		t = tmpl.New(strings.TrimSuffix(filepath.Base(file), extension))
+	}
+
+	if _, err = t.Parse(string(source)); err != nil {
+		return nil, fmt.Errorf("templateutil: %s: %s", file, err.Error())
+	}
+
+	return tmpl, nil
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
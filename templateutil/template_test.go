@@ -0,0 +1,92 @@
+package templateutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "templateutil")
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err = ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatalf("should not have error; got %s", err.Error())
+		}
+	}
+
+	return dir
+}
+
+func TestRenderer(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/base.html":   `<title>{{block "title" .}}App{{end}}</title>{{template "content" .}}`,
+		"partials/price.html": `{{currency "$" .}}`,
+		"users/list.html":     `{{define "title"}}Users{{end}}{{define "content"}}{{template "price" .Data.Price}} {{date .Data.Date}}{{end}}`,
+	})
+
+	renderer, err := New(Config{Dir: dir, Layout: "base"})
+
+	if err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+
+	data := PageData{Data: map[string]interface{}{
+		"Price": 1234.5,
+		"Date":  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+	}}
+
+	if err = renderer.Render(&buf, "users/list", data); err != nil {
+		t.Fatalf("should not have error; got %s", err.Error())
+	}
+	if buf.String() != "<title>Users</title>$1,234.50 2020-01-02" {
+		t.Errorf("should render page within layout; got %s", buf.String())
+	}
+
+	if err = renderer.Render(&buf, "missing", nil); err == nil {
+		t.Errorf("should return error for missing page")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rr := httptest.NewRecorder()
+	renderer.RenderRequest(rr, req, "users/list", map[string]interface{}{"Price": 1.0, "Date": time.Now()})
+
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("should write html; got %s", rr.Header().Get("Content-Type"))
+	}
+	if renderer.PageData(req, nil).Lang != "fr" {
+		t.Errorf("should set language of request")
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := map[float64]string{
+		0:          "$0.00",
+		999.999:    "$1,000.00",
+		-1234567.8: "-$1,234,567.80",
+	}
+
+	for amount, expected := range tests {
+		if got := FormatCurrency("$", amount); got != expected {
+			t.Errorf("got %s; want %s", got, expected)
+		}
+	}
+}
@@ -1,13 +1,75 @@
 package timeutil
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/TravisS25/httputil"
 	"github.com/TravisS25/httputil/confutil"
 )
 
+const (
+	// ISO8601Layout is ISO 8601 date time format without timezone offset
+	ISO8601Layout = "2006-01-02T15:04:05"
+)
+
+var (
+	// Clock is what the current time of GetCurrent functions is taken from
+	// Tests can set it to httputil#MockClock for deterministic dates
+	// Default is httputil#RealClock
+	Clock = httputil.RealClock
+
+	// ErrInvalidDateFormat is returned from ParseFlexible when given value
+	// does not match any of the layouts tried
+	ErrInvalidDateFormat = errors.New("timeutil: value does not match any date layout")
+
+	// FlexibleLayouts is the default list of layouts ParseFlexible will try,
+	// in order, if no layouts are passed to it
+	FlexibleLayouts = []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		ISO8601Layout,
+		confutil.DateTimeMilliLayout,
+		confutil.DateTimeLayout,
+		confutil.DateLayout,
+		confutil.FormDateTimeLayout,
+		confutil.FormDateLayout,
+	}
+)
+
+// ParseFlexible tries to parse given value against each layout passed, in order,
+// and returns the first successful result converted to UTC
+// If no layouts are passed, FlexibleLayouts is used
+//
+// Form layouts using lower case "pm" will also accept upper case "AM/PM" as
+// FormDateTimeExp is case insensitive
+//
+// Returns ErrInvalidDateFormat if value does not match any layout
+func ParseFlexible(value string, layouts ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if len(layouts) == 0 {
+		layouts = FlexibleLayouts
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+
+		if strings.Contains(layout, "pm") {
+			if t, err := time.Parse(layout, strings.ToLower(value)); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+
+	return time.Time{}, ErrInvalidDateFormat
+}
+
 func ConvertTimeToLocalDateTime(dateString, timezone string) (time.Time, error) {
 	location, err := time.LoadLocation(timezone)
 
@@ -25,7 +87,7 @@ func ConvertTimeToLocalDateTime(dateString, timezone string) (time.Time, error)
 }
 
 func GetCurrentDateTimeInUTC() *time.Time {
-	currentDate := time.Now()
+	currentDate := Clock.Now()
 	year := strconv.Itoa(currentDate.Year())
 	month := fmt.Sprintf("%02d", currentDate.Month())
 	day := fmt.Sprintf("%02d", currentDate.Day())
@@ -47,7 +109,7 @@ func GetCurrentLocalDateTimeInUTC(timezone string) (*time.Time, error) {
 		return nil, err
 	}
 
-	localTime := time.Now().In(location)
+	localTime := Clock.Now().In(location)
 	utcTime := time.Date(
 		localTime.Year(),
 		localTime.Month(),
@@ -63,19 +125,19 @@ func GetCurrentLocalDateTimeInUTC(timezone string) (*time.Time, error) {
 }
 
 func GetCurrentLocalDateInUTC(timeZone string) (*time.Time, error) {
-	location, err := time.LoadLocation(timeZone)
-
-	if err != nil {
-		return nil, err
-	}
+	return LocalDateInUTC(Clock.Now(), timeZone)
+}
 
-	utc, err := time.LoadLocation("UTC")
+// LocalDateInUTC returns date of t in timeZone as midnight UTC of
+// that date eg. to compare dates of users with their local today
+func LocalDateInUTC(t time.Time, timeZone string) (*time.Time, error) {
+	location, err := time.LoadLocation(timeZone)
 
 	if err != nil {
 		return nil, err
 	}
 
-	localTime := time.Now().In(location)
+	localTime := t.In(location)
 	utcTime := time.Date(
 		localTime.Year(),
 		localTime.Month(),
@@ -84,7 +146,7 @@ func GetCurrentLocalDateInUTC(timeZone string) (*time.Time, error) {
 		0,
 		0,
 		0,
-		utc,
+		time.UTC,
 	)
 
 	return &utcTime, nil
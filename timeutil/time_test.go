@@ -1,8 +1,63 @@
 package timeutil
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/TravisS25/httputil"
+	"github.com/TravisS25/httputil/confutil"
+)
 
 func TestGetCurrentLocalDateInUTC(t *testing.T) {
-	time, _ := GetCurrentLocalDateInUTC("America/New_York")
-	t.Errorf("time: %s", time.String())
+	// 2am UTC is still the previous day in New York
+	Clock = httputil.NewMockClock(time.Date(2019, time.March, 5, 2, 0, 0, 0, time.UTC))
+	defer func() { Clock = httputil.RealClock }()
+
+	result, err := GetCurrentLocalDateInUTC("America/New_York")
+
+	if err != nil {
+		t.Fatalf("should not return error; got %s", err.Error())
+	}
+
+	expected := time.Date(2019, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	if !result.Equal(expected) {
+		t.Errorf("got %s; want %s", result, expected)
+	}
+	if _, err = GetCurrentLocalDateInUTC("Not/AZone"); err == nil {
+		t.Errorf("invalid timezone should return error")
+	}
+}
+
+func TestParseFlexible(t *testing.T) {
+	expected := time.Date(2019, time.March, 4, 13, 30, 0, 0, time.UTC)
+	values := []string{
+		"2019-03-04T13:30:00Z",
+		"2019-03-04T08:30:00-05:00",
+		"2019-03-04T13:30:00",
+		"2019-03-04 13:30:00",
+		"03/04/2019 1:30 pm",
+		"03/04/2019 1:30 PM",
+	}
+
+	for _, v := range values {
+		result, err := ParseFlexible(v)
+
+		if err != nil {
+			t.Errorf("value %s err: %s\n", v, err.Error())
+			continue
+		}
+
+		if !result.Equal(expected) || result.Location() != time.UTC {
+			t.Errorf("value %s: got %s; want %s\n", v, result, expected)
+		}
+	}
+
+	if _, err := ParseFlexible("2019-03-04", confutil.FormDateLayout); err != ErrInvalidDateFormat {
+		t.Errorf("should have returned ErrInvalidDateFormat\n")
+	}
+
+	if _, err := ParseFlexible("not a date"); err != ErrInvalidDateFormat {
+		t.Errorf("should have returned ErrInvalidDateFormat\n")
+	}
 }
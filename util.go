@@ -3,7 +3,6 @@ package httputil
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"os"
 
@@ -20,10 +19,19 @@ const (
 	ContentTypeText   = "text/plain; charset=utf-8"
 	ContenTypeJPG     = "image/jpeg"
 	ContentTypePNG    = "image/png"
+	ContentTypeCSV    = "text/csv; charset=utf-8"
+	ContentTypeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 )
 
 var (
 	Logger = logrus.New()
+
+	// Debug turns on debug output of library code paths eg. the steps
+	// of middleware and response bodies of apitest
+	// Nothing is written when false, which should be the case in
+	// production
+	// Should only be set on startup
+	Debug = false
 )
 
 func init() {
@@ -31,10 +39,17 @@ func init() {
 	logrus.SetOutput(os.Stdout)
 }
 
-// CheckError simply prints given error in verbose to stdout
+// CheckError logs given error in verbose with Logger
 func CheckError(err error, customMessage string) {
 	err = errors.Wrap(err, customMessage)
-	fmt.Printf("%+v\n", err)
+	Logger.Errorf("%+v", err)
+}
+
+// Debugf writes debug message with Logger if Debug is set
+func Debugf(format string, args ...interface{}) {
+	if Debug {
+		Logger.Printf(format, args...)
+	}
 }
 
 func InsertAt(slice []interface{}, val interface{}, idx int) []interface{} {
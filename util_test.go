@@ -0,0 +1,52 @@
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebugOutput(t *testing.T) {
+	var logs bytes.Buffer
+
+	stdout := os.Stdout
+	reader, writer, _ := os.Pipe()
+	os.Stdout = writer
+	Logger.SetOutput(&logs)
+
+	defer func() {
+		os.Stdout = stdout
+		Logger.SetOutput(os.Stderr)
+		Debug = false
+	}()
+
+	Debugf("session id: %s", "foo")
+
+	if logs.Len() != 0 {
+		t.Errorf("should not log unless Debug is set; got %s", logs.String())
+	}
+
+	CheckError(errors.New("connection refused"), "query")
+
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("should log error; got %s", logs.String())
+	}
+
+	logs.Reset()
+	Debug = true
+	Debugf("session id: %s", "foo")
+
+	if !strings.Contains(logs.String(), "session id: foo") {
+		t.Errorf("should log when Debug is set; got %s", logs.String())
+	}
+
+	writer.Close()
+	output, _ := ioutil.ReadAll(reader)
+
+	if len(output) != 0 {
+		t.Errorf("should never write to stdout; got %s", string(output))
+	}
+}